@@ -12,15 +12,25 @@ import (
 	zlog "github.com/rs/zerolog/log"
 
 	"github.com/ksred/klear-api/internal/auth"
+	"github.com/ksred/klear-api/internal/breaks"
 	"github.com/ksred/klear-api/internal/clearing"
+	"github.com/ksred/klear-api/internal/config"
 	"github.com/ksred/klear-api/internal/database"
+	"github.com/ksred/klear-api/internal/events"
+	"github.com/ksred/klear-api/internal/exchange"
+	"github.com/ksred/klear-api/internal/market"
+	"github.com/ksred/klear-api/internal/pricefeed"
 	"github.com/ksred/klear-api/internal/settlement"
 	"github.com/ksred/klear-api/internal/trading"
+	"github.com/ksred/klear-api/internal/types"
 	"github.com/ksred/klear-api/pkg/middleware"
 
 	"github.com/gin-gonic/gin"
 )
 
+// operatorClientID is the client the operator API key's tokens are issued for
+const operatorClientID = "operator"
+
 // init configures the application logging based on environment settings
 // In development mode, it enables pretty printing with timestamps
 // Debug logging can be enabled via DEBUG environment variable
@@ -44,54 +54,117 @@ func init() {
 // main initializes and runs the trading API server with graceful shutdown support
 // It sets up all required services, database connections, and API routes
 func main() {
+	// Load and validate configuration before initializing any service
+	cfg, err := config.Load()
+	if err != nil {
+		zlog.Fatal().Err(err).Msg("Failed to load configuration")
+	}
+
+	// Route across the configured venues instead of the built-in set
+	if cfg.Exchanges != nil {
+		exchange.SetExchanges(cfg.Exchanges)
+		zlog.Info().Int("exchanges", len(cfg.Exchanges)).Msg("Loaded exchange config")
+	}
+	if cfg.InstantFills {
+		exchange.SetInstantFills(true)
+		zlog.Warn().Msg("Exchange instant-fill mode enabled; executions are not realistically simulated")
+	}
+
 	// Initialize database
-	db, err := database.NewDatabase()
+	db, err := database.NewDatabase(cfg.DatabaseDSN)
 	if err != nil {
 		zlog.Fatal().Err(err).Msg("Failed to initialize database")
 	}
 
-	// Initialize router
-	router := gin.Default()
+	// Initialize router, recovering panics with a JSON error envelope rather than gin's plain text
+	router := gin.New()
+	router.Use(gin.Logger(), middleware.RequestID(), middleware.Recovery())
+	// Only believe X-Forwarded-For from configured proxies, so rate limits key on the real client IP
+	// and clients cannot pick their own key by sending the header
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		zlog.Fatal().Err(err).Msg("Failed to set trusted proxies")
+	}
 
 	// Initialize services and handlers
-	authService := auth.NewService("klear-secret-key")
+	authService := auth.NewService(cfg.JWTSecret)
 	authHandlers := auth.NewGinHandlers(authService)
 	// Register test credentials
 	authService.RegisterAPICredentials(auth.TestAPIKey, auth.TestAPISecret)
+	// The operator key is the only key whose tokens may use the admin routes
+	if cfg.OperatorAPIKey != "" {
+		if err := authService.RegisterAPIKey(cfg.OperatorAPIKey, cfg.OperatorAPISecret, operatorClientID,
+			[]string{auth.PermissionRead, auth.PermissionAdmin}); err != nil {
+			zlog.Fatal().Err(err).Msg("Failed to register operator API key")
+		}
+	}
 
-	tradingService := trading.NewService(db)
+	tradingService := trading.NewService(db, cfg.Trading)
 	tradingHandlers := trading.NewGinHandlers(tradingService)
+	// Register test client
+	if err := tradingService.RegisterClient(&types.Client{
+		ClientID: auth.TestAPIKey,
+		Name:     "Test Client",
+		Active:   true,
+	}); err != nil {
+		zlog.Fatal().Err(err).Msg("Failed to register test client")
+	}
+
+	// Mock market data until a vendor feed is integrated
+	priceFeed := pricefeed.NewMockFeed()
+
+	marketHandlers := market.NewGinHandlers(market.NewService(priceFeed))
 
-	clearingService := clearing.NewService(db)
+	// Limit order prices are banded around the feed's last price
+	tradingService.SetPriceFeed(priceFeed)
+
+	clearingService := clearing.NewService(db, priceFeed, cfg.Clearing)
 	clearingHandlers := clearing.NewGinHandlers(clearingService)
 
-	settlementService := settlement.NewService(db)
+	tradingService.SetClearer(func(tradeID string) error {
+		_, err := clearingService.ClearTrade(tradeID)
+		return err
+	})
+
+	settlementService := settlement.NewService(db, cfg.Settlement)
 	settlementHandlers := settlement.NewGinHandlers(settlementService)
 
+	breaksHandlers := breaks.NewGinHandlers(breaks.NewService(db))
+
+	eventsHandlers := events.NewGinHandlers(events.NewService(db, cfg.PageSize))
+
+	// Watch the database so writes fail fast with 503 while it is unreachable
+	dbHealth := database.NewHealthMonitor(db, cfg.DBHealthCheckInterval)
+
 	// Create and start settlement processor
-	settlementProcessor := settlement.NewProcessor(settlementService.GetDB())
+	settlementProcessor := settlement.NewProcessor(settlementService.GetDB(), cfg.SettlementProcessInterval)
+	settlementProcessor.SetWebhookRetry(cfg.Settlement.WebhookRetry)
 	processorCtx, processorCancel := context.WithCancel(context.Background())
 	defer processorCancel()
 
 	go settlementProcessor.Start(processorCtx)
 
+	// Create and start end-of-day netting processor
+	eodProcessor, err := clearing.NewEODProcessor(clearingService, cfg.Clearing.EODNettingTime)
+	if err != nil {
+		zlog.Fatal().Err(err).Msg("Failed to create end-of-day netting processor")
+	}
+	go eodProcessor.Start(processorCtx)
+
+	// Create and start GTD order expirer
+	orderExpirer := trading.NewOrderExpirer(tradingService, cfg.OrderExpiryInterval)
+	go orderExpirer.Start(processorCtx)
+
+	go dbHealth.Start(processorCtx)
+
 	// Setup middleware
-	router.Use(middleware.RateLimit())
+	rateLimiter := middleware.NewRateLimiter(cfg.RateLimits)
+	router.Use(rateLimiter.Middleware())
 
 	// Setup API routes
-	setupRoutes(router, authHandlers, tradingHandlers, clearingHandlers, settlementHandlers)
-
-	// Get port from env otherwise it's 8080
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
-	}
+	setupRoutes(router, cfg, rateLimiter, dbHealth, authService, authHandlers, tradingHandlers, clearingHandlers, settlementHandlers, breaksHandlers, eventsHandlers, marketHandlers)
 
 	// Create server
-	srv := &http.Server{
-		Addr:    ":" + port,
-		Handler: router,
-	}
+	srv := newServer(cfg, router)
 
 	// Graceful shutdown setup
 	go func() {
@@ -116,47 +189,178 @@ func main() {
 	zlog.Info().Msg("Server exiting")
 }
 
+// newServer builds the HTTP server for the router, applying the configured connection timeouts
+// so slow or hung clients cannot hold connections open indefinitely
+func newServer(cfg *config.Config, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              ":" + cfg.Port,
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}
+}
+
 // setupRoutes configures all API endpoints and their handlers
 // It groups routes by functionality and applies appropriate middleware:
-// - Auth routes: Public endpoints for authentication
-// - Order routes: Protected by JWT authentication
+// - Readiness route: Public, reports whether the database is reachable
+// - All API routes: Bounded by the configured request timeout
+// - Order, execution, settlement and internal writes: Rejected with 503 in degraded mode while the database is down
+// - Auth routes: Public endpoints for authentication; revoking a token needs a valid token
+// - Order routes: Protected by JWT authentication; changes need the trade permission
+// - Execution routes: Protected by JWT authentication; allocations need the trade permission
+// - Market data routes: Protected by JWT authentication
 // - Internal routes: Protected by internal network authentication
+// - Admin routes: Operator tooling, protected by JWT authentication with the admin permission
 // Parameters:
 //   - router: The main Gin router instance
+//   - cfg: The application configuration
+//   - rateLimiter: The rate limiter applied to all routes, inspected by the admin routes
+//   - dbHealth: The database health monitor, backing the readiness route and degraded mode
+//   - authService: The authentication service, consulted for revoked tokens
 //   - authHandlers: Handlers for authentication endpoints
 //   - tradingHandlers: Handlers for order management
 //   - clearingHandlers: Handlers for trade clearing
 //   - settlementHandlers: Handlers for trade settlement
+//   - breaksHandlers: Handlers for trade break tracking
+//   - eventsHandlers: Handlers for reading the domain event log
+//   - marketHandlers: Handlers for market data
 func setupRoutes(
 	router *gin.Engine,
+	cfg *config.Config,
+	rateLimiter *middleware.RateLimiter,
+	dbHealth *database.HealthMonitor,
+	authService *auth.Service,
 	authHandlers *auth.GinHandlers,
 	tradingHandlers *trading.GinHandlers,
 	clearingHandlers *clearing.GinHandlers,
 	settlementHandlers *settlement.GinHandlers,
+	breaksHandlers *breaks.GinHandlers,
+	eventsHandlers *events.GinHandlers,
+	marketHandlers *market.GinHandlers,
 ) {
+	// Revoked tokens are rejected on every authenticated route
+	jwtAuth := middleware.JWTAuth(cfg.JWTSecret, authService)
+	internalAuth := middleware.InternalAuth(cfg.JWTSecret, authService)
+
+	// Any valid token may read; placing, changing or cancelling orders and changing account settings needs trade
+	requireTrade := middleware.RequirePermission(auth.PermissionTrade)
+	// Only operator tokens carry the admin permission; client tokens are turned away with 403
+	requireAdmin := middleware.RequirePermission(auth.PermissionAdmin)
+
+	// In degraded mode writes that need the database are turned away while it is down
+	requireDatabase := func(c *gin.Context) { c.Next() }
+	if cfg.DegradedMode {
+		requireDatabase = middleware.RequireDatabase(dbHealth)
+	}
+
+	router.GET("/ready", dbHealth.ReadinessHandler())
+
 	v1 := router.Group("/api/v1")
+	v1.Use(middleware.Timeout(cfg.RequestTimeout))
 	{
 		// Auth routes
 		auth := v1.Group("/auth")
 		{
 			auth.POST("/token", authHandlers.GenerateTokenHandler())
+			auth.POST("/revoke", jwtAuth, authHandlers.RevokeTokenHandler())
 		}
 
+		// Authenticated client profile
+		v1.GET("/me", jwtAuth, authHandlers.MeHandler())
+
 		// Order routes
 		orders := v1.Group("/orders")
-		orders.Use(middleware.JWTAuth())
+		orders.Use(jwtAuth, requireDatabase)
 		{
-			orders.POST("", tradingHandlers.CreateOrderHandler())
+			orders.POST("", requireTrade, tradingHandlers.CreateOrderHandler())
+			orders.GET("", tradingHandlers.ListOrdersHandler())
+			orders.POST("/cancel-all", requireTrade, tradingHandlers.CancelAllOrdersHandler())
+			orders.POST("/validate", tradingHandlers.ValidateOrderHandler())
+			orders.GET("/by-idempotency-key/:key", tradingHandlers.GetOrderByIdempotencyKeyHandler())
 			orders.GET("/:order_id", tradingHandlers.GetOrderStatusHandler())
+			orders.GET("/:order_id/history", tradingHandlers.GetOrderHistoryHandler())
+			orders.POST("/:order_id/replace", requireTrade, tradingHandlers.ReplaceOrderHandler())
+		}
+
+		// Execution routes
+		executions := v1.Group("/executions")
+		executions.Use(jwtAuth, requireDatabase)
+		{
+			executions.POST("/:execution_id/allocations", requireTrade, tradingHandlers.AllocateExecutionHandler())
+		}
+
+		// Settlement routes
+		settlements := v1.Group("/settlements")
+		settlements.Use(jwtAuth, requireDatabase)
+		{
+			settlements.GET("", settlementHandlers.ListSettlementsHandler())
+			settlements.POST("/status", settlementHandlers.GetSettlementStatusesHandler())
+			settlements.PUT("/webhook", requireTrade, settlementHandlers.SetWebhookHandler())
+			settlements.GET("/webhook", settlementHandlers.GetWebhookHandler())
+			settlements.DELETE("/webhook", requireTrade, settlementHandlers.DeleteWebhookHandler())
+		}
+
+		// Client account routes; clients may only read their own
+		clients := v1.Group("/clients")
+		clients.Use(jwtAuth)
+		{
+			clients.GET("/:client_id/fee-statement", settlementHandlers.GetFeeStatementHandler())
+		}
+
+		// Market data routes
+		marketData := v1.Group("/market")
+		marketData.Use(jwtAuth)
+		{
+			marketData.GET("/quote/:symbol", marketHandlers.GetQuoteHandler())
 		}
 
 		// Internal routes (should be protected by internal network)
 		internal := v1.Group("/internal")
-		internal.Use(middleware.InternalAuth())
+		internal.Use(internalAuth, requireDatabase)
 		{
+			internal.POST("/execution/batch", tradingHandlers.ExecuteOrdersHandler())
 			internal.POST("/execution/:order_id", tradingHandlers.ExecuteOrderHandler())
+			internal.POST("/clearing/netting", clearingHandlers.ClearNettingWindowHandler())
 			internal.POST("/clearing/:trade_id", clearingHandlers.ClearTradeHandler())
+			internal.POST("/clearing/:trade_id/retry", clearingHandlers.RetryClearingHandler())
+			internal.GET("/netting/preview", clearingHandlers.PreviewNettingHandler())
+			internal.GET("/netting/history", clearingHandlers.GetNettingHistoryHandler())
 			internal.POST("/settlement/:trade_id", settlementHandlers.SettleTradeHandler())
+			internal.PUT("/settlement/:settlement_id/status", settlementHandlers.UpdateSettlementStatusHandler())
+			// The wildcard must share its name with POST /settlement/:trade_id; it holds the settlement ID
+			internal.POST("/settlement/:trade_id/cancel", settlementHandlers.CancelSettlementHandler())
+			internal.GET("/settlement/batches/:batch_id", settlementHandlers.GetSettlementBatchHandler())
+			internal.GET("/clients/:client_id/daily-stats", clearingHandlers.GetDailyStatsHandler())
+			internal.GET("/risk/house-exposure", tradingHandlers.GetHouseExposureHandler())
+			internal.GET("/clients/:client_id/deferred-settlements", settlementHandlers.GetDeferredBalancesHandler())
+			internal.GET("/clients/:client_id/currency-netting", settlementHandlers.GetCurrencyNettingHandler())
+			internal.PUT("/clients/:client_id/counterparty-agreement", settlementHandlers.SetCounterpartyAgreementHandler())
+			internal.GET("/trades/:execution_id/lifecycle", settlementHandlers.GetTradeLifecycleHandler())
+			internal.POST("/trades/:execution_id/bust", settlementHandlers.BustTradeHandler())
+			internal.GET("/metrics/settlement-latency", settlementHandlers.GetSettlementLatencyHandler())
+			internal.GET("/breaks", breaksHandlers.ListOpenBreaksHandler())
+			internal.POST("/breaks/:break_id/resolve", breaksHandlers.ResolveBreakHandler())
+			internal.GET("/events", eventsHandlers.ListEventsHandler())
+			internal.GET("/fills", tradingHandlers.GetFillsHandler())
+			internal.GET("/integrity/orphaned-executions", tradingHandlers.GetOrphanedExecutionsHandler())
+			internal.GET("/venues/stats", tradingHandlers.GetVenueStatsHandler())
+			internal.GET("/symbols/halted", tradingHandlers.ListHaltedSymbolsHandler())
+		}
+
+		// Admin routes, for operators only
+		admin := v1.Group("/admin")
+		admin.Use(jwtAuth, requireAdmin)
+		{
+			admin.POST("/api-keys", authHandlers.RegisterAPIKeyHandler())
+			admin.GET("/ratelimit", rateLimiter.ListVisitorsHandler())
+			admin.DELETE("/ratelimit", rateLimiter.PurgeVisitorsHandler())
+			admin.GET("/webhooks/dead-letters", settlementHandlers.ListDeadLettersHandler())
+			admin.POST("/webhooks/dead-letters/:dead_letter_id/redrive", requireDatabase, settlementHandlers.RedriveDeadLetterHandler())
+			// Halts stop every client trading the symbol, so only operators may toggle them
+			admin.POST("/symbols/:symbol/halt", tradingHandlers.HaltSymbolHandler())
+			admin.DELETE("/symbols/:symbol/halt", tradingHandlers.ResumeSymbolHandler())
 		}
 	}
 }
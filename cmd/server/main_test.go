@@ -0,0 +1,96 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/klear-api/internal/auth"
+	"github.com/ksred/klear-api/internal/breaks"
+	"github.com/ksred/klear-api/internal/clearing"
+	"github.com/ksred/klear-api/internal/config"
+	"github.com/ksred/klear-api/internal/events"
+	"github.com/ksred/klear-api/internal/market"
+	"github.com/ksred/klear-api/internal/settlement"
+	"github.com/ksred/klear-api/internal/trading"
+)
+
+func TestNewServerAppliesConfiguredTimeouts(t *testing.T) {
+	t.Setenv("PORT", "9090")
+	t.Setenv("HTTP_READ_HEADER_TIMEOUT", "2s")
+	t.Setenv("HTTP_READ_TIMEOUT", "10s")
+	t.Setenv("HTTP_WRITE_TIMEOUT", "45s")
+	t.Setenv("HTTP_IDLE_TIMEOUT", "90s")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("config.Load: %v", err)
+	}
+
+	server := newServer(cfg, http.NotFoundHandler())
+	if server.Addr != ":9090" {
+		t.Errorf("address = %q, want :9090", server.Addr)
+	}
+	if server.ReadHeaderTimeout != 2*time.Second || server.ReadTimeout != 10*time.Second ||
+		server.WriteTimeout != 45*time.Second || server.IdleTimeout != 90*time.Second {
+		t.Errorf("timeouts = header %s read %s write %s idle %s, want 2s, 10s, 45s and 90s",
+			server.ReadHeaderTimeout, server.ReadTimeout, server.WriteTimeout, server.IdleTimeout)
+	}
+}
+
+func TestNewServerDefaultsBoundEveryTimeout(t *testing.T) {
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("config.Load: %v", err)
+	}
+
+	server := newServer(cfg, http.NotFoundHandler())
+	for name, timeout := range map[string]time.Duration{
+		"read header": server.ReadHeaderTimeout,
+		"read":        server.ReadTimeout,
+		"write":       server.WriteTimeout,
+		"idle":        server.IdleTimeout,
+	} {
+		if timeout <= 0 {
+			t.Errorf("%s timeout = %s, want a bound by default", name, timeout)
+		}
+	}
+	// The write timeout leaves room to send the 504 of a request that hit its timeout
+	if server.WriteTimeout <= cfg.RequestTimeout {
+		t.Errorf("write timeout = %s, want it above the request timeout of %s", server.WriteTimeout, cfg.RequestTimeout)
+	}
+}
+
+func TestSymbolHaltTogglesAreAdminRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("config.Load: %v", err)
+	}
+	router := gin.New()
+	setupRoutes(router, cfg, nil, nil, nil,
+		auth.NewGinHandlers(nil), trading.NewGinHandlers(nil), clearing.NewGinHandlers(nil),
+		settlement.NewGinHandlers(nil), breaks.NewGinHandlers(nil), events.NewGinHandlers(nil), market.NewGinHandlers(nil))
+
+	registered := make(map[string]bool)
+	for _, route := range router.Routes() {
+		registered[route.Method+" "+route.Path] = true
+	}
+	// Client tokens pass internal authentication, so a halt there would let any client stop a symbol
+	for _, route := range []string{
+		"POST /api/v1/admin/symbols/:symbol/halt",
+		"DELETE /api/v1/admin/symbols/:symbol/halt",
+	} {
+		if !registered[route] {
+			t.Errorf("%s is not registered", route)
+		}
+	}
+	for _, route := range []string{
+		"POST /api/v1/internal/symbols/:symbol/halt",
+		"DELETE /api/v1/internal/symbols/:symbol/halt",
+	} {
+		if registered[route] {
+			t.Errorf("%s is registered behind internal authentication", route)
+		}
+	}
+}
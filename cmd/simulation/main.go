@@ -3,6 +3,7 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"io"
 	"math"
@@ -19,6 +20,7 @@ import (
 	"github.com/ksred/klear-api/internal/auth"
 	"github.com/ksred/klear-api/internal/clearing"
 	"github.com/ksred/klear-api/internal/database"
+	"github.com/ksred/klear-api/internal/pricefeed"
 	"github.com/ksred/klear-api/internal/settlement"
 	"github.com/ksred/klear-api/internal/trading"
 	"github.com/ksred/klear-api/internal/types"
@@ -32,11 +34,14 @@ const (
 	maxOrders     = 150
 	numWorkers    = 5
 	serverAddress = "http://localhost:8080"
+	jwtSecret     = "klear-secret-key" // Signs and verifies the simulation server's tokens
 )
 
 var (
 	symbols = []string{"AAPL", "GOOGL", "MSFT", "AMZN", "META"}
 	sides   = []string{"BUY", "SELL"}
+
+	reportPath = flag.String("report", "", "write per-endpoint performance stats as JSON to this file")
 )
 
 // init configures the logger for the simulation with pretty printing and timestamp
@@ -89,14 +94,60 @@ func (rs *routeStats) calculate() (min, max, mean, median, p95, p99 time.Duratio
 	median = rs.durations[len(rs.durations)/2]
 
 	// Calculate percentiles
-	p95idx := int(math.Ceil(float64(len(rs.durations))*0.95)) - 1
-	p99idx := int(math.Ceil(float64(len(rs.durations))*0.99)) - 1
-	p95 = rs.durations[p95idx]
-	p99 = rs.durations[p99idx]
+	p95 = rs.durations[percentileIndex(len(rs.durations), 0.95)]
+	p99 = rs.durations[percentileIndex(len(rs.durations), 0.99)]
 
 	return
 }
 
+// percentileIndex returns the nearest-rank index of percentile p in a sorted
+// sample of size n, clamped to the bounds of the sample
+func percentileIndex(n int, p float64) int {
+	idx := int(math.Ceil(float64(n)*p)) - 1
+	if idx < 0 {
+		return 0
+	}
+	if idx >= n {
+		return n - 1
+	}
+	return idx
+}
+
+// endpointReport is the JSON form of an endpoint's performance statistics
+// Durations are reported in milliseconds
+type endpointReport struct {
+	Endpoint string  `json:"endpoint"`
+	Calls    int     `json:"calls"`
+	Errors   int     `json:"errors"`
+	MinMs    float64 `json:"min_ms"`
+	MaxMs    float64 `json:"max_ms"`
+	MeanMs   float64 `json:"mean_ms"`
+	MedianMs float64 `json:"median_ms"`
+	P95Ms    float64 `json:"p95_ms"`
+	P99Ms    float64 `json:"p99_ms"`
+}
+
+// report builds the JSON form of the route's statistics
+func (rs *routeStats) report() endpointReport {
+	min, max, mean, median, p95, p99 := rs.calculate()
+	return endpointReport{
+		Endpoint: rs.name,
+		Calls:    rs.totalCalls,
+		Errors:   rs.failures,
+		MinMs:    toMillis(min),
+		MaxMs:    toMillis(max),
+		MeanMs:   toMillis(mean),
+		MedianMs: toMillis(median),
+		P95Ms:    toMillis(p95),
+		P99Ms:    toMillis(p99),
+	}
+}
+
+// toMillis converts a duration to fractional milliseconds
+func toMillis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
 // simulationClient handles HTTP communication with the trading API
 type simulationClient struct {
 	baseURL   string
@@ -483,9 +534,35 @@ func (sc *simulationClient) printPerformanceStats() {
 	fmt.Println(strings.Repeat("-", 100))
 }
 
+// writePerformanceReport writes the performance statistics for all API endpoints as JSON
+func (sc *simulationClient) writePerformanceReport(path string) error {
+	// Sort by key so reports diff cleanly between runs
+	keys := make([]string, 0, len(sc.stats))
+	for key := range sc.stats {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	reports := make([]endpointReport, 0, len(keys))
+	for _, key := range keys {
+		reports = append(reports, sc.stats[key].report())
+	}
+
+	data, err := json.MarshalIndent(reports, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal performance report: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write performance report: %w", err)
+	}
+	return nil
+}
+
 // main runs the trading simulation
 // It starts a local API server and simulates multiple concurrent trading clients
 func main() {
+	flag.Parse()
+
 	// Start the server in a goroutine
 	go func() {
 		if err := startServer(); err != nil {
@@ -684,6 +761,13 @@ Duration:         %v
 
 	// Add this before the final success rate calculation
 	simClient.printPerformanceStats()
+
+	if *reportPath != "" {
+		if err := simClient.writePerformanceReport(*reportPath); err != nil {
+			log.Fatal().Err(err).Msg("Failed to write performance report")
+		}
+		log.Info().Str("path", *reportPath).Msg("Performance report written")
+	}
 }
 
 // createOrdersHTTP generates and submits random orders to the API
@@ -728,19 +812,26 @@ func createOrdersHTTP(workerID, numOrders int, simClient *simulationClient, orde
 // Sets up all required services, handlers and routes
 func startServer() error {
 	// Initialize database
-	db, err := database.NewDatabase()
+	db, err := database.NewDatabase(database.DefaultDSN)
 	if err != nil {
 		return fmt.Errorf("failed to initialize database: %w", err)
 	}
 
 	// Initialize services
-	authService := auth.NewService("klear-secret-key")
-	tradingService := trading.NewService(db)
-	clearingService := clearing.NewService(db)
-	settlementService := settlement.NewService(db)
+	authService := auth.NewService(jwtSecret)
+	tradingService := trading.NewService(db, trading.DefaultConfig())
+	clearingService := clearing.NewService(db, pricefeed.NewMockFeed(), clearing.DefaultConfig())
+	settlementService := settlement.NewService(db, settlement.DefaultConfig())
 
 	// Register test credentials
 	authService.RegisterAPICredentials(auth.TestAPIKey, auth.TestAPISecret)
+	if err := tradingService.RegisterClient(&types.Client{
+		ClientID: auth.TestAPIKey,
+		Name:     "Test Client",
+		Active:   true,
+	}); err != nil {
+		return fmt.Errorf("failed to register test client: %w", err)
+	}
 
 	// Initialize router
 	router := gin.Default()
@@ -775,7 +866,7 @@ func setupRoutes(
 
 		// Order routes
 		orders := v1.Group("/orders")
-		orders.Use(middleware.JWTAuth())
+		orders.Use(middleware.JWTAuth(jwtSecret, nil))
 		{
 			orders.POST("", tradingHandlers.CreateOrderHandler())
 			orders.GET("/:order_id", tradingHandlers.GetOrderStatusHandler())
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// statsOf returns route stats holding durations of 1ms to n ms, recorded in reverse order
+func statsOf(n int) *routeStats {
+	rs := &routeStats{name: "Create Order"}
+	for i := n; i >= 1; i-- {
+		rs.addDuration(time.Duration(i) * time.Millisecond)
+	}
+	return rs
+}
+
+func TestRouteStatsCalculate(t *testing.T) {
+	ms := time.Millisecond
+	tests := []struct {
+		samples                          int
+		min, max, mean, median, p95, p99 time.Duration
+	}{
+		{1, ms, ms, ms, ms, ms, ms},
+		{2, ms, 2 * ms, 1500 * time.Microsecond, 2 * ms, 2 * ms, 2 * ms},
+		{100, ms, 100 * ms, 50500 * time.Microsecond, 51 * ms, 95 * ms, 99 * ms},
+	}
+	for _, tt := range tests {
+		min, max, mean, median, p95, p99 := statsOf(tt.samples).calculate()
+		got := []time.Duration{min, max, mean, median, p95, p99}
+		want := []time.Duration{tt.min, tt.max, tt.mean, tt.median, tt.p95, tt.p99}
+		for i, name := range []string{"min", "max", "mean", "median", "p95", "p99"} {
+			if got[i] != want[i] {
+				t.Errorf("%d samples: %s = %s, want %s", tt.samples, name, got[i], want[i])
+			}
+		}
+	}
+}
+
+func TestRouteStatsCalculateEmpty(t *testing.T) {
+	min, max, mean, median, p95, p99 := (&routeStats{}).calculate()
+	for _, d := range []time.Duration{min, max, mean, median, p95, p99} {
+		if d != 0 {
+			t.Fatalf("empty stats = %v, want all zero", []time.Duration{min, max, mean, median, p95, p99})
+		}
+	}
+}
+
+func TestWritePerformanceReport(t *testing.T) {
+	create := statsOf(100)
+	create.failures = 3
+	sc := &simulationClient{stats: map[string]*routeStats{
+		"create": create,
+		"auth":   {name: "Authentication"},
+	}}
+
+	path := filepath.Join(t.TempDir(), "report.json")
+	if err := sc.writePerformanceReport(path); err != nil {
+		t.Fatalf("writePerformanceReport: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read report: %v", err)
+	}
+	var reports []endpointReport
+	if err := json.Unmarshal(data, &reports); err != nil {
+		t.Fatalf("failed to decode report %s: %v", data, err)
+	}
+
+	if len(reports) != 2 || reports[0].Endpoint != "Authentication" || reports[1].Endpoint != "Create Order" {
+		t.Fatalf("reports = %+v, want Authentication then Create Order", reports)
+	}
+	want := endpointReport{
+		Endpoint: "Create Order", Calls: 100, Errors: 3,
+		MinMs: 1, MaxMs: 100, MeanMs: 50.5, MedianMs: 51, P95Ms: 95, P99Ms: 99,
+	}
+	if reports[1] != want {
+		t.Errorf("report = %+v, want %+v", reports[1], want)
+	}
+}
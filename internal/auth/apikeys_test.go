@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/klear-api/pkg/middleware"
+)
+
+// newPermissionRouter serves a read route and a route requiring the trade permission
+func newPermissionRouter(service *Service) *gin.Engine {
+	router := gin.New()
+	router.Use(middleware.JWTAuth(testSecret, service))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	router.GET("/orders", ok)
+	router.GET("/trade", middleware.RequirePermission(PermissionTrade), ok)
+	return router
+}
+
+func TestReadOnlyKeyIsForbiddenFromTrading(t *testing.T) {
+	service := NewService(testSecret)
+	registerKey(t, service, "reporting-key", "client-1", PermissionRead)
+	registerKey(t, service, "trading-key", "client-1", PermissionRead, PermissionTrade)
+	router := newPermissionRouter(service)
+
+	readOnly := issueToken(t, service, "reporting-key").Token
+	if recorder := get(router, "/orders", readOnly); recorder.Code != http.StatusOK {
+		t.Errorf("read-only key reading: status = %d, want 200", recorder.Code)
+	}
+	if recorder := get(router, "/trade", readOnly); recorder.Code != http.StatusForbidden {
+		t.Errorf("read-only key trading: status = %d, want 403", recorder.Code)
+	}
+
+	trading := issueToken(t, service, "trading-key").Token
+	if recorder := get(router, "/trade", trading); recorder.Code != http.StatusOK {
+		t.Errorf("trading key trading: status = %d, want 200", recorder.Code)
+	}
+}
+
+// postAPIKey posts body to the register API key handler
+func postAPIKey(router http.Handler, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/admin/api-keys", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestRegisterAPIKeyHandler(t *testing.T) {
+	service := NewService(testSecret)
+	router := gin.New()
+	router.POST("/admin/api-keys", NewGinHandlers(service).RegisterAPIKeyHandler())
+
+	recorder := postAPIKey(router, `{"api_key":"reporting-key","api_secret":"reporting-key-secret","client_id":"client-1","permissions":["read"]}`)
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want 201: %s", recorder.Code, recorder.Body.String())
+	}
+	if strings.Contains(recorder.Body.String(), "reporting-key-secret") {
+		t.Error("response contains the API secret")
+	}
+	var envelope struct {
+		Data APIKey `json:"data"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if envelope.Data.ClientID != "client-1" || len(envelope.Data.Permissions) != 1 {
+		t.Errorf("registered key = %+v, want client-1 with [read]", envelope.Data)
+	}
+
+	// The registered key issues tokens for its client, which cannot trade
+	token := issueToken(t, service, "reporting-key")
+	claims, err := service.ValidateToken(token.Token)
+	if err != nil {
+		t.Fatalf("ValidateToken: %v", err)
+	}
+	if claims.ClientID != "client-1" || len(claims.Permissions) != 1 || claims.Permissions[0] != PermissionRead {
+		t.Errorf("token claims = %s %v, want client-1 [read]", claims.ClientID, claims.Permissions)
+	}
+	if recorder := get(newPermissionRouter(service), "/trade", token.Token); recorder.Code != http.StatusForbidden {
+		t.Errorf("registered read-only key trading: status = %d, want 403", recorder.Code)
+	}
+
+	for name, body := range map[string]string{
+		"unknown permission": `{"api_key":"k","api_secret":"s","client_id":"client-1","permissions":["withdraw"]}`,
+		"no permissions":     `{"api_key":"k","api_secret":"s","client_id":"client-1","permissions":[]}`,
+		"missing client":     `{"api_key":"k","api_secret":"s","permissions":["read"]}`,
+	} {
+		if recorder := postAPIKey(router, body); recorder.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", name, recorder.Code)
+		}
+	}
+}
+
+func TestRegisterAPIKeyConcurrentWithTokenRequests(t *testing.T) {
+	service := NewService(testSecret)
+	registerKey(t, service, "trading-key", "client-1", PermissionTrade)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			if err := service.RegisterAPIKey(fmt.Sprintf("key-%d", i), "secret", "client-1", []string{PermissionRead}); err != nil {
+				t.Errorf("RegisterAPIKey: %v", err)
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			if _, err := service.GetOrCreateToken(Credentials{APIKey: "trading-key", APISecret: "trading-key-secret"}); err != nil {
+				t.Errorf("GetOrCreateToken: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+}
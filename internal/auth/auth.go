@@ -2,16 +2,38 @@ package auth
 
 import (
 	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"github.com/ksred/klear-api/pkg/response"
 )
 
 var (
 	ErrInvalidCredentials = errors.New("invalid API credentials")
 	ErrTokenGeneration    = errors.New("failed to generate token")
+	ErrInvalidAPIKey      = errors.New("API key, secret and client ID are required")
+	ErrUnknownPermission  = errors.New("unknown permission")
+	ErrNoPermissions      = errors.New("at least one permission is required")
+	ErrInvalidToken       = errors.New("invalid token")
+	ErrTokenRevoked       = errors.New("token has been revoked")
+	ErrTokenNotRevocable  = errors.New("token has no ID and cannot be revoked")
+	ErrTokenNotOwned      = errors.New("token was issued to another client")
+)
+
+// Permissions granted to API keys
+// Any valid token may read; PermissionTrade is required to place, change or cancel orders
+// and to change account settings. PermissionAdmin is held only by operator keys and is
+// required by the admin routes
+const (
+	PermissionRead  = "read"
+	PermissionTrade = "trade"
+	PermissionAdmin = "admin"
 )
 
 // Test credentials
@@ -39,11 +61,61 @@ type Claims struct {
 	Permissions []string `json:"permissions"`
 }
 
+// RevokeTokenRequest names the token to revoke
+type RevokeTokenRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// RegisterAPIKeyRequest registers an API key for a client
+type RegisterAPIKeyRequest struct {
+	APIKey      string   `json:"api_key" binding:"required"`
+	APISecret   string   `json:"api_secret" binding:"required"`
+	ClientID    string   `json:"client_id" binding:"required"`
+	Permissions []string `json:"permissions" binding:"required"`
+}
+
+// APIKey describes a registered API key; its secret is never returned
+type APIKey struct {
+	APIKey      string   `json:"api_key"`
+	ClientID    string   `json:"client_id"`
+	Permissions []string `json:"permissions"`
+}
+
+// RevokedToken identifies a revoked token and when its revocation lapses along with the token itself
+type RevokedToken struct {
+	TokenID   string    `json:"token_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Profile describes the authenticated client as seen from its token
+type Profile struct {
+	ClientID       string    `json:"client_id"`
+	Permissions    []string  `json:"permissions"`
+	TokenExpiresAt time.Time `json:"token_expires_at"`
+}
+
+// registeredKey is a registered API key: its secret, the client it acts for and what it may do
+type registeredKey struct {
+	secret      string
+	clientID    string
+	permissions []string
+}
+
+// tokenReuseMargin is the minimum validity a cached token must have left to be handed out again
+const tokenReuseMargin = time.Minute
+
 // Service handles authentication and authorization operations
 type Service struct {
 	jwtSecret []byte
 	// In a real implementation, this would be replaced with a database
-	apiCredentials map[string]string // map[APIKey]APISecret
+	credentialsMu  sync.RWMutex
+	apiCredentials map[string]registeredKey // map[APIKey]registered key
+
+	tokenMu    sync.Mutex
+	tokenCache map[string]*TokenResponse // map[APIKey]last token issued by GetOrCreateToken
+
+	revokedMu sync.Mutex
+	revoked   map[string]time.Time // map[token ID]token expiry
 }
 
 // NewService creates a new authentication service with the given JWT secret
@@ -51,15 +123,19 @@ func NewService(jwtSecret string) *Service {
 	return &Service{
 		jwtSecret: []byte(jwtSecret),
 		// This is just for demonstration - in production, use a proper database
-		apiCredentials: make(map[string]string),
+		apiCredentials: make(map[string]registeredKey),
+		tokenCache:     make(map[string]*TokenResponse),
+		revoked:        make(map[string]time.Time),
 	}
 }
 
 // GenerateToken generates a JWT token for valid API credentials
-// The token includes client ID and permissions with 24-hour expiration
+// The token includes the client ID and permissions registered for the key, with 24-hour expiration,
+// and a unique ID (jti) by which it can be revoked
 func (s *Service) GenerateToken(creds Credentials) (*TokenResponse, error) {
 	// Verify API credentials
-	if !s.validateCredentials(creds) {
+	key, ok := s.validateCredentials(creds)
+	if !ok {
 		return nil, ErrInvalidCredentials
 	}
 
@@ -69,12 +145,13 @@ func (s *Service) GenerateToken(creds Credentials) (*TokenResponse, error) {
 	// Create the claims
 	claims := Claims{
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
 			ExpiresAt: jwt.NewNumericDate(expiration),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 		},
-		ClientID:    creds.APIKey,      // Using API key as client ID for simplicity
-		Permissions: []string{"trade"}, // Default permission
+		ClientID:    key.clientID,
+		Permissions: key.permissions,
 	}
 
 	// Create the token
@@ -92,9 +169,101 @@ func (s *Service) GenerateToken(creds Credentials) (*TokenResponse, error) {
 	}, nil
 }
 
+// GetOrCreateToken returns the token previously issued for the API key while it remains valid,
+// generating and caching a new one otherwise. Credentials are verified on every call
+func (s *Service) GetOrCreateToken(creds Credentials) (*TokenResponse, error) {
+	if _, ok := s.validateCredentials(creds); !ok {
+		return nil, ErrInvalidCredentials
+	}
+
+	s.tokenMu.Lock()
+	defer s.tokenMu.Unlock()
+
+	if cached, ok := s.tokenCache[creds.APIKey]; ok && time.Until(cached.Expiration) > tokenReuseMargin {
+		return cached, nil
+	}
+
+	token, err := s.GenerateToken(creds)
+	if err != nil {
+		return nil, err
+	}
+	s.tokenCache[creds.APIKey] = token
+	return token, nil
+}
+
 // ValidateToken validates a JWT token and returns the claims
-// Verifies token signature and expiration
+// Verifies token signature and expiration, and that the token has not been revoked
 func (s *Service) ValidateToken(tokenString string) (*Claims, error) {
+	claims, err := s.parseToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if s.IsRevoked(claims.ID) {
+		return nil, ErrTokenRevoked
+	}
+	return claims, nil
+}
+
+// RevokeToken revokes a token the client was issued, so it is rejected before it expires
+// Revoking a token that is already revoked succeeds. The revocation is kept only until the
+// token would have expired anyway, which bounds the revocation list
+// Parameters:
+//   - tokenString: The token to revoke
+//   - clientID: ID of the client asking for the revocation, which must own the token
+func (s *Service) RevokeToken(tokenString, clientID string) (*RevokedToken, error) {
+	claims, err := s.parseToken(tokenString)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	if claims.ClientID != clientID {
+		return nil, ErrTokenNotOwned
+	}
+	if claims.ID == "" {
+		return nil, ErrTokenNotRevocable
+	}
+
+	expiresAt := claims.ExpiresAt.Time
+	now := time.Now()
+
+	s.revokedMu.Lock()
+	// Entries for tokens that have since expired are no longer needed
+	for id, expiry := range s.revoked {
+		if !now.Before(expiry) {
+			delete(s.revoked, id)
+		}
+	}
+	s.revoked[claims.ID] = expiresAt
+	s.revokedMu.Unlock()
+
+	// A revoked token must not be handed out again by GetOrCreateToken
+	s.tokenMu.Lock()
+	for apiKey, cached := range s.tokenCache {
+		if cached.Token == tokenString {
+			delete(s.tokenCache, apiKey)
+		}
+	}
+	s.tokenMu.Unlock()
+
+	return &RevokedToken{
+		TokenID:   claims.ID,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// IsRevoked reports whether the token with the given ID (jti) has been revoked
+// Tokens issued without an ID are never revoked
+func (s *Service) IsRevoked(tokenID string) bool {
+	if tokenID == "" {
+		return false
+	}
+	s.revokedMu.Lock()
+	defer s.revokedMu.Unlock()
+	_, revoked := s.revoked[tokenID]
+	return revoked
+}
+
+// parseToken verifies a token's signature and expiration and returns its claims
+func (s *Service) parseToken(tokenString string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, errors.New("unexpected signing method")
@@ -113,15 +282,60 @@ func (s *Service) ValidateToken(tokenString string) (*Claims, error) {
 	return nil, errors.New("invalid token")
 }
 
-// validateCredentials checks if the API credentials are valid
-func (s *Service) validateCredentials(creds Credentials) bool {
-	secret, exists := s.apiCredentials[creds.APIKey]
-	return exists && secret == creds.APISecret
+// validateCredentials checks if the API credentials are valid and returns the registered key
+func (s *Service) validateCredentials(creds Credentials) (registeredKey, bool) {
+	s.credentialsMu.RLock()
+	defer s.credentialsMu.RUnlock()
+	key, exists := s.apiCredentials[creds.APIKey]
+	return key, exists && key.secret == creds.APISecret
 }
 
 // RegisterAPICredentials registers new API credentials (for testing/demo purposes)
+// The API key doubles as the client ID and may trade
 func (s *Service) RegisterAPICredentials(apiKey, apiSecret string) {
-	s.apiCredentials[apiKey] = apiSecret
+	s.credentialsMu.Lock()
+	defer s.credentialsMu.Unlock()
+	s.apiCredentials[apiKey] = registeredKey{
+		secret:      apiSecret,
+		clientID:    apiKey,
+		permissions: []string{PermissionTrade},
+	}
+}
+
+// RegisterAPIKey registers an API key acting for a client with the given permissions, so one
+// client can hold several keys, e.g. a read-only key for reporting and a trading key
+// Re-registering a key replaces its client and permissions; tokens already issued keep theirs until they expire
+// Parameters:
+//   - key: The API key
+//   - secret: The API secret
+//   - clientID: ID of the client the key acts for
+//   - permissions: What the key may do (PermissionRead, PermissionTrade, PermissionAdmin)
+func (s *Service) RegisterAPIKey(key, secret, clientID string, permissions []string) error {
+	if key == "" || secret == "" || clientID == "" {
+		return ErrInvalidAPIKey
+	}
+	if len(permissions) == 0 {
+		return ErrNoPermissions
+	}
+	for _, permission := range permissions {
+		if permission != PermissionRead && permission != PermissionTrade && permission != PermissionAdmin {
+			return fmt.Errorf("%w: %s", ErrUnknownPermission, permission)
+		}
+	}
+
+	s.credentialsMu.Lock()
+	s.apiCredentials[key] = registeredKey{
+		secret:      secret,
+		clientID:    clientID,
+		permissions: append([]string(nil), permissions...),
+	}
+	s.credentialsMu.Unlock()
+
+	// A token cached for the key's previous registration must not be handed out again
+	s.tokenMu.Lock()
+	delete(s.tokenCache, key)
+	s.tokenMu.Unlock()
+	return nil
 }
 
 // GinHandlers contains HTTP handlers for authentication endpoints
@@ -138,21 +352,137 @@ func NewGinHandlers(service *Service) *GinHandlers {
 
 // GenerateTokenHandler handles POST requests to generate JWT tokens
 // Request body should contain API credentials
+// Query parameter: reuse (optional, true returns the client's unexpired token instead of minting a new one)
 func (h *GinHandlers) GenerateTokenHandler() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var creds Credentials
 		if err := c.ShouldBindJSON(&creds); err != nil {
-			response.BadRequest(c, "Invalid request body")
+			response.BindError(c, err)
 			return
 		}
 
-		token, err := h.service.GenerateToken(creds)
+		reuse := false
+		if reuseParam := c.Query("reuse"); reuseParam != "" {
+			parsed, err := strconv.ParseBool(reuseParam)
+			if err != nil {
+				response.BadRequest(c, "Invalid reuse value, expected true or false")
+				return
+			}
+			reuse = parsed
+		}
+
+		var token *TokenResponse
+		var err error
+		if reuse {
+			token, err = h.service.GetOrCreateToken(creds)
+		} else {
+			token, err = h.service.GenerateToken(creds)
+		}
 		if err == ErrInvalidCredentials {
 			response.Unauthorized(c, err.Error())
 			return
 		}
-		response.Handle(c, token, err)
+		response.HandleWithStatus(c, token, err, http.StatusOK)
+	}
+}
+
+// RevokeTokenHandler handles POST requests to revoke a token issued to the authenticated client
+// Requires a valid JWT token; a client may revoke the token it authenticates with
+// Request body: token
+func (h *GinHandlers) RevokeTokenHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req RevokeTokenRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BindError(c, err)
+			return
+		}
+
+		revoked, err := h.service.RevokeToken(req.Token, c.GetString("clientID"))
+		switch {
+		case errors.Is(err, ErrInvalidToken), errors.Is(err, ErrTokenNotRevocable):
+			response.BadRequest(c, err.Error())
+			return
+		case errors.Is(err, ErrTokenNotOwned):
+			response.Forbidden(c, err.Error())
+			return
+		}
+		response.HandleWithStatus(c, revoked, err, http.StatusOK)
+	}
+}
+
+// RegisterAPIKeyHandler handles POST requests to register an API key for a client
+// Admin only. Re-registering an existing key replaces its secret, client and permissions
+// Request body: api_key, api_secret, client_id, permissions
+func (h *GinHandlers) RegisterAPIKeyHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req RegisterAPIKeyRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BindError(c, err)
+			return
+		}
+
+		err := h.service.RegisterAPIKey(req.APIKey, req.APISecret, req.ClientID, req.Permissions)
+		if errors.Is(err, ErrInvalidAPIKey) || errors.Is(err, ErrNoPermissions) || errors.Is(err, ErrUnknownPermission) {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		if err != nil {
+			response.InternalError(c, "An unexpected error occurred")
+			return
+		}
+
+		response.Success(c, APIKey{
+			APIKey:      req.APIKey,
+			ClientID:    req.ClientID,
+			Permissions: req.Permissions,
+		})
+	}
+}
+
+// MeHandler handles GET requests for the authenticated client's profile
+// Requires a valid JWT token
+func (h *GinHandlers) MeHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, exists := c.Get("claims")
+		if !exists {
+			response.Unauthorized(c, "Missing authentication claims")
+			return
+		}
+
+		clientID := GetClientID(claims)
+		if clientID == "" {
+			response.Unauthorized(c, "Invalid client ID in token")
+			return
+		}
+
+		profile := Profile{
+			ClientID:    clientID,
+			Permissions: GetPermissions(claims),
+		}
+		if jwtClaims, ok := claims.(jwt.MapClaims); ok {
+			if exp, err := jwtClaims.GetExpirationTime(); err == nil && exp != nil {
+				profile.TokenExpiresAt = exp.Time
+			}
+		}
+
+		response.Success(c, profile)
+	}
+}
+
+// GetPermissions extracts the permissions from a JWT token
+// Returns an empty list if no permissions are present
+func GetPermissions(claims interface{}) []string {
+	permissions := []string{}
+	if jwtClaims, ok := claims.(jwt.MapClaims); ok {
+		if values, ok := jwtClaims["permissions"].([]interface{}); ok {
+			for _, value := range values {
+				if permission, ok := value.(string); ok {
+					permissions = append(permissions, permission)
+				}
+			}
+		}
 	}
+	return permissions
 }
 
 // GetClientID extracts the client ID from a JWT token
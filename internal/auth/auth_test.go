@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/klear-api/pkg/middleware"
+)
+
+// testSecret signs every token issued in the tests
+const testSecret = "test-secret"
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	os.Exit(m.Run())
+}
+
+// registerKey registers an API key for the client, failing the test on error
+func registerKey(t *testing.T, service *Service, key, clientID string, permissions ...string) {
+	t.Helper()
+	if err := service.RegisterAPIKey(key, key+"-secret", clientID, permissions); err != nil {
+		t.Fatalf("RegisterAPIKey: %v", err)
+	}
+}
+
+// issueToken generates a token for a key registered by registerKey, failing the test on error
+func issueToken(t *testing.T, service *Service, key string) *TokenResponse {
+	t.Helper()
+	token, err := service.GenerateToken(Credentials{APIKey: key, APISecret: key + "-secret"})
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	return token
+}
+
+// get serves a GET request authenticated with the bearer token
+func get(router http.Handler, path, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestMeHandlerReturnsTokenProfile(t *testing.T) {
+	service := NewService(testSecret)
+	registerKey(t, service, "reporting-key", "client-1", PermissionRead)
+	token := issueToken(t, service, "reporting-key")
+
+	router := gin.New()
+	router.GET("/me", middleware.JWTAuth(testSecret, service), NewGinHandlers(service).MeHandler())
+
+	recorder := get(router, "/me", token.Token)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", recorder.Code, recorder.Body.String())
+	}
+	var envelope struct {
+		Data Profile `json:"data"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	profile := envelope.Data
+	if profile.ClientID != "client-1" {
+		t.Errorf("client ID = %q, want the token's client-1", profile.ClientID)
+	}
+	if len(profile.Permissions) != 1 || profile.Permissions[0] != PermissionRead {
+		t.Errorf("permissions = %v, want [%s]", profile.Permissions, PermissionRead)
+	}
+	// Token expiry is carried in whole seconds
+	if diff := profile.TokenExpiresAt.Sub(token.Expiration); diff > time.Second || diff < -time.Second {
+		t.Errorf("token expires at %s, want %s", profile.TokenExpiresAt, token.Expiration)
+	}
+}
+
+func TestMeHandlerRejectsMissingToken(t *testing.T) {
+	service := NewService(testSecret)
+	router := gin.New()
+	router.GET("/me", middleware.JWTAuth(testSecret, service), NewGinHandlers(service).MeHandler())
+
+	if recorder := get(router, "/me", "not-a-token"); recorder.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want 401", recorder.Code)
+	}
+}
+
+// requestToken posts the key's credentials to the token handler at path and returns the issued token
+func requestToken(t *testing.T, router http.Handler, path, key string) string {
+	t.Helper()
+	body := strings.NewReader(`{"api_key":"` + key + `","api_secret":"` + key + `-secret"}`)
+	req := httptest.NewRequest(http.MethodPost, path, body)
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	if recorder.Code != http.StatusCreated && recorder.Code != http.StatusOK {
+		t.Fatalf("token status = %d: %s", recorder.Code, recorder.Body.String())
+	}
+	var envelope struct {
+		Data TokenResponse `json:"data"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return envelope.Data.Token
+}
+
+func TestGenerateTokenHandlerReusesUnexpiredToken(t *testing.T) {
+	service := NewService(testSecret)
+	registerKey(t, service, "trading-key", "client-1", PermissionRead)
+	router := gin.New()
+	router.POST("/token", NewGinHandlers(service).GenerateTokenHandler())
+
+	first := requestToken(t, router, "/token?reuse=true", "trading-key")
+	if second := requestToken(t, router, "/token?reuse=true", "trading-key"); second != first {
+		t.Error("second reuse request minted a new token, want the unexpired token returned again")
+	}
+	if fresh := requestToken(t, router, "/token", "trading-key"); fresh == first {
+		t.Error("request without reuse returned the cached token, want a new one")
+	}
+
+	// A revoked token is never handed out again
+	if _, err := service.RevokeToken(first, "client-1"); err != nil {
+		t.Fatalf("RevokeToken: %v", err)
+	}
+	if after := requestToken(t, router, "/token?reuse=true", "trading-key"); after == first {
+		t.Error("reuse request returned a revoked token")
+	}
+}
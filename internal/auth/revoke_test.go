@@ -0,0 +1,119 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/klear-api/pkg/middleware"
+)
+
+// newRevokeRouter serves the revoke and profile endpoints behind the JWT middleware
+func newRevokeRouter(service *Service) *gin.Engine {
+	handlers := NewGinHandlers(service)
+	jwtAuth := middleware.JWTAuth(testSecret, service)
+	router := gin.New()
+	router.POST("/auth/revoke", jwtAuth, handlers.RevokeTokenHandler())
+	router.GET("/me", jwtAuth, handlers.MeHandler())
+	return router
+}
+
+// revoke posts a revocation of token, authenticated with bearer
+func revoke(router http.Handler, bearer, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/auth/revoke", strings.NewReader(`{"token":"`+token+`"}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+bearer)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestRevokedTokenRejectedWhileOthersStillWork(t *testing.T) {
+	service := NewService(testSecret)
+	registerKey(t, service, "trading-key", "client-1", PermissionTrade)
+	revoked := issueToken(t, service, "trading-key").Token
+	other := issueToken(t, service, "trading-key").Token
+	router := newRevokeRouter(service)
+
+	if recorder := revoke(router, revoked, revoked); recorder.Code != http.StatusOK {
+		t.Fatalf("revoke status = %d, want 200: %s", recorder.Code, recorder.Body.String())
+	}
+
+	if recorder := get(router, "/me", revoked); recorder.Code != http.StatusUnauthorized {
+		t.Errorf("revoked token status = %d, want 401", recorder.Code)
+	}
+	if _, err := service.ValidateToken(revoked); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("ValidateToken(revoked) err = %v, want %v", err, ErrTokenRevoked)
+	}
+	if recorder := get(router, "/me", other); recorder.Code != http.StatusOK {
+		t.Errorf("other token status = %d, want 200", recorder.Code)
+	}
+
+	// Revoking again succeeds
+	if recorder := revoke(router, other, revoked); recorder.Code != http.StatusOK {
+		t.Errorf("repeated revoke status = %d, want 200", recorder.Code)
+	}
+}
+
+func TestRevokeTokenRejected(t *testing.T) {
+	service := NewService(testSecret)
+	registerKey(t, service, "key-1", "client-1", PermissionTrade)
+	registerKey(t, service, "key-2", "client-2", PermissionTrade)
+	own := issueToken(t, service, "key-1").Token
+	others := issueToken(t, service, "key-2").Token
+	router := newRevokeRouter(service)
+
+	if recorder := revoke(router, own, others); recorder.Code != http.StatusForbidden {
+		t.Errorf("revoking another client's token: status = %d, want 403", recorder.Code)
+	}
+	if recorder := get(router, "/me", others); recorder.Code != http.StatusOK {
+		t.Errorf("another client's token after a refused revoke: status = %d, want 200", recorder.Code)
+	}
+	if recorder := revoke(router, own, "not-a-token"); recorder.Code != http.StatusBadRequest {
+		t.Errorf("revoking an invalid token: status = %d, want 400", recorder.Code)
+	}
+}
+
+func TestTokensCarryUniqueIDs(t *testing.T) {
+	service := NewService(testSecret)
+	registerKey(t, service, "trading-key", "client-1", PermissionTrade)
+
+	first, err := service.ValidateToken(issueToken(t, service, "trading-key").Token)
+	if err != nil {
+		t.Fatalf("ValidateToken: %v", err)
+	}
+	second, err := service.ValidateToken(issueToken(t, service, "trading-key").Token)
+	if err != nil {
+		t.Fatalf("ValidateToken: %v", err)
+	}
+	if first.ID == "" || first.ID == second.ID {
+		t.Errorf("token IDs = %q and %q, want distinct non-empty IDs", first.ID, second.ID)
+	}
+}
+
+func TestRevocationsLapseWithTheToken(t *testing.T) {
+	service := NewService(testSecret)
+	registerKey(t, service, "trading-key", "client-1", PermissionTrade)
+	// A revocation of a token that has since expired
+	service.revoked["expired-token"] = time.Now().Add(-time.Minute)
+
+	token := issueToken(t, service, "trading-key")
+	revoked, err := service.RevokeToken(token.Token, "client-1")
+	if err != nil {
+		t.Fatalf("RevokeToken: %v", err)
+	}
+	// Token expiry is carried in whole seconds
+	if diff := revoked.ExpiresAt.Sub(token.Expiration); diff > time.Second || diff < -time.Second {
+		t.Errorf("revocation expires at %s, want the token's expiry %s", revoked.ExpiresAt, token.Expiration)
+	}
+	if service.IsRevoked("expired-token") {
+		t.Error("revocation of an expired token was kept, want it dropped")
+	}
+	if len(service.revoked) != 1 {
+		t.Errorf("revocation list holds %d entries, want only the live token's", len(service.revoked))
+	}
+}
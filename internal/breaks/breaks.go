@@ -0,0 +1,206 @@
+package breaks
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ksred/klear-api/pkg/common"
+	"github.com/ksred/klear-api/pkg/response"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// Stages a trade can break at
+const (
+	StageClearing   = "CLEARING"
+	StageSettlement = "SETTLEMENT"
+)
+
+// Break statuses
+const (
+	StatusOpen     = "OPEN"
+	StatusResolved = "RESOLVED"
+)
+
+var (
+	ErrBreakNotFound        = errors.New("trade break not found")
+	ErrBreakAlreadyResolved = errors.New("trade break is already resolved")
+	ErrInvalidStage         = errors.New("stage must be CLEARING or SETTLEMENT")
+)
+
+// Service manages trade breaks raised by clearing and settlement failures
+type Service struct {
+	db    *Database
+	clock common.Clock
+}
+
+// NewService creates a new trade break service with the given database connection
+func NewService(gormDB *gorm.DB) *Service {
+	return &Service{
+		db:    NewDatabase(gormDB),
+		clock: common.RealClock{},
+	}
+}
+
+// SetClock replaces the clock used to stamp when breaks are opened, updated and resolved
+func (s *Service) SetClock(clock common.Clock) {
+	s.clock = clock
+}
+
+// OpenBreak records that a trade failed at a stage
+// If the trade already has an open break at that stage, e.g. after a failed retry,
+// its reason is updated instead of opening a second break
+// Parameters:
+//   - tradeID: ID of the trade that failed
+//   - stage: Stage the trade failed at (CLEARING or SETTLEMENT)
+//   - reason: Why the trade failed
+func (s *Service) OpenBreak(tradeID, stage, reason string) (*TradeBreak, error) {
+	existing, err := s.db.GetOpenBreak(tradeID, stage)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		existing.Reason = reason
+		existing.UpdatedAt = s.clock.Now()
+		if err := s.db.UpdateBreak(existing); err != nil {
+			return nil, err
+		}
+		return existing, nil
+	}
+
+	tradeBreak := &TradeBreak{
+		BreakID:   "BRK_" + uuid.New().String(),
+		TradeID:   tradeID,
+		Stage:     stage,
+		Reason:    reason,
+		Status:    StatusOpen,
+		CreatedAt: s.clock.Now(),
+		UpdatedAt: s.clock.Now(),
+	}
+	if err := s.db.CreateBreak(tradeBreak); err != nil {
+		return nil, err
+	}
+
+	log.Warn().
+		Str("break_id", tradeBreak.BreakID).
+		Str("trade_id", tradeID).
+		Str("stage", stage).
+		Str("reason", reason).
+		Msg("opened trade break")
+
+	return tradeBreak, nil
+}
+
+// Record opens a break for a failed trade, logging rather than returning any error
+// so the failure that caused the break is what the caller reports
+func (s *Service) Record(tradeID, stage, reason string) {
+	if _, err := s.OpenBreak(tradeID, stage, reason); err != nil {
+		log.Error().
+			Err(err).
+			Str("trade_id", tradeID).
+			Str("stage", stage).
+			Msg("failed to open trade break")
+	}
+}
+
+// ListOpenBreaks lists unresolved breaks, oldest first
+// Parameters:
+//   - stage: Optional stage to filter by; empty lists breaks at every stage
+func (s *Service) ListOpenBreaks(stage string) ([]TradeBreak, error) {
+	stage = strings.ToUpper(strings.TrimSpace(stage))
+	if stage != "" && stage != StageClearing && stage != StageSettlement {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidStage, stage)
+	}
+	return s.db.ListOpenBreaks(stage)
+}
+
+// ResolveBreak marks an open break resolved with the resolving assignee and notes
+// Parameters:
+//   - breakID: ID of the break to resolve
+//   - assignee: Who resolved the break
+//   - notes: How the break was resolved
+func (s *Service) ResolveBreak(breakID, assignee, notes string) (*TradeBreak, error) {
+	resolved, err := s.db.ResolveBreak(breakID, strings.TrimSpace(assignee), strings.TrimSpace(notes), s.clock.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	tradeBreak, err := s.db.GetBreak(breakID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBreakNotFound
+		}
+		return nil, err
+	}
+	if !resolved {
+		return nil, ErrBreakAlreadyResolved
+	}
+
+	log.Info().
+		Str("break_id", breakID).
+		Str("trade_id", tradeBreak.TradeID).
+		Str("assignee", tradeBreak.Assignee).
+		Msg("resolved trade break")
+
+	return tradeBreak, nil
+}
+
+// GinHandlers wraps the trade break service with HTTP handlers
+type GinHandlers struct {
+	service *Service
+}
+
+// NewGinHandlers creates a new set of HTTP handlers for trade break endpoints
+func NewGinHandlers(service *Service) *GinHandlers {
+	return &GinHandlers{
+		service: service,
+	}
+}
+
+// ListOpenBreaksHandler handles GET requests for unresolved trade breaks
+// Requires internal authentication
+// Query parameter: stage (optional, CLEARING or SETTLEMENT)
+func (h *GinHandlers) ListOpenBreaksHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tradeBreaks, err := h.service.ListOpenBreaks(c.Query("stage"))
+		if errors.Is(err, ErrInvalidStage) {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		response.Handle(c, tradeBreaks, err)
+	}
+}
+
+// ResolveBreakHandler handles POST requests to resolve a trade break
+// Requires internal authentication
+// URL parameter: break_id
+// Request body should contain resolution notes and an optional assignee
+func (h *GinHandlers) ResolveBreakHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		breakID := common.NormalizeID(c.Param("break_id"))
+		if breakID == "" {
+			response.BadRequest(c, "Break ID is required")
+			return
+		}
+
+		var req ResolveBreakRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BindError(c, err)
+			return
+		}
+
+		tradeBreak, err := h.service.ResolveBreak(breakID, req.Assignee, req.Notes)
+		switch {
+		case errors.Is(err, ErrBreakNotFound):
+			response.NotFound(c, err.Error())
+		case errors.Is(err, ErrBreakAlreadyResolved):
+			response.Conflict(c, err.Error())
+		default:
+			response.HandleWithStatus(c, tradeBreak, err, http.StatusOK)
+		}
+	}
+}
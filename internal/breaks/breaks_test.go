@@ -0,0 +1,154 @@
+package breaks
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/klear-api/internal/database/dbtest"
+	"github.com/ksred/klear-api/pkg/common"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	os.Exit(m.Run())
+}
+
+// newTestService creates a trade break service over a fresh database
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+	return NewService(dbtest.Open(t, &TradeBreak{}))
+}
+
+// resolve posts a resolution of the break to the handler
+func resolve(router http.Handler, breakID string, req ResolveBreakRequest) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(req)
+	httpReq := httptest.NewRequest(http.MethodPost, "/breaks/"+breakID+"/resolve", bytes.NewReader(body))
+	httpReq.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httpReq)
+	return recorder
+}
+
+func TestOpenBreakUpdatesExistingOpenBreak(t *testing.T) {
+	service := newTestService(t)
+
+	first, err := service.OpenBreak("trade-1", StageClearing, "margin exceeded")
+	if err != nil {
+		t.Fatalf("OpenBreak: %v", err)
+	}
+	retried, err := service.OpenBreak("trade-1", StageClearing, "risk score exceeded")
+	if err != nil {
+		t.Fatalf("OpenBreak: %v", err)
+	}
+	if retried.BreakID != first.BreakID || retried.Reason != "risk score exceeded" {
+		t.Errorf("retried break = %+v, want break %s updated with the latest reason", retried, first.BreakID)
+	}
+	service.Record("trade-1", StageSettlement, "insufficient funds")
+
+	open, err := service.ListOpenBreaks("")
+	if err != nil {
+		t.Fatalf("ListOpenBreaks: %v", err)
+	}
+	if len(open) != 2 {
+		t.Errorf("open breaks = %d, want one per stage", len(open))
+	}
+	settlementBreaks, err := service.ListOpenBreaks("settlement")
+	if err != nil {
+		t.Fatalf("ListOpenBreaks: %v", err)
+	}
+	if len(settlementBreaks) != 1 || settlementBreaks[0].Stage != StageSettlement {
+		t.Errorf("settlement breaks = %+v, want the settlement break only", settlementBreaks)
+	}
+	if _, err := service.ListOpenBreaks("booking"); !errors.Is(err, ErrInvalidStage) {
+		t.Errorf("ListOpenBreaks error = %v, want ErrInvalidStage", err)
+	}
+}
+
+func TestResolveBreakHandler(t *testing.T) {
+	service := newTestService(t)
+	tradeBreak, err := service.OpenBreak("trade-1", StageClearing, "margin exceeded")
+	if err != nil {
+		t.Fatalf("OpenBreak: %v", err)
+	}
+	router := gin.New()
+	router.POST("/breaks/:break_id/resolve", NewGinHandlers(service).ResolveBreakHandler())
+
+	recorder := resolve(router, tradeBreak.BreakID, ResolveBreakRequest{Assignee: "ops-1", Notes: "margin topped up"})
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", recorder.Code, recorder.Body.String())
+	}
+	var envelope struct {
+		Data TradeBreak `json:"data"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	resolved := envelope.Data
+	if resolved.Status != StatusResolved || resolved.Assignee != "ops-1" || resolved.Notes != "margin topped up" || resolved.ResolvedAt == nil {
+		t.Errorf("resolved break = %+v, want RESOLVED by ops-1 with notes", resolved)
+	}
+
+	open, err := service.ListOpenBreaks("")
+	if err != nil {
+		t.Fatalf("ListOpenBreaks: %v", err)
+	}
+	if len(open) != 0 {
+		t.Errorf("open breaks = %+v, want none", open)
+	}
+
+	tests := []struct {
+		name    string
+		breakID string
+		req     ResolveBreakRequest
+		want    int
+	}{
+		{"already resolved", tradeBreak.BreakID, ResolveBreakRequest{Notes: "again"}, http.StatusConflict},
+		{"unknown break", "BRK_missing", ResolveBreakRequest{Notes: "n/a"}, http.StatusNotFound},
+		{"missing notes", tradeBreak.BreakID, ResolveBreakRequest{}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if recorder := resolve(router, tt.breakID, tt.req); recorder.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, recorder.Code, tt.want)
+		}
+	}
+}
+
+func TestBreakTimestampsFollowClock(t *testing.T) {
+	service := newTestService(t)
+	openedAt := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	clock := common.NewFakeClock(openedAt)
+	service.SetClock(clock)
+
+	opened, err := service.OpenBreak("trade-1", StageClearing, "margin exceeded")
+	if err != nil {
+		t.Fatalf("OpenBreak: %v", err)
+	}
+	if !opened.CreatedAt.Equal(openedAt) || !opened.UpdatedAt.Equal(openedAt) {
+		t.Errorf("break opened at %s and updated at %s, want the clock's %s", opened.CreatedAt, opened.UpdatedAt, openedAt)
+	}
+
+	clock.Advance(time.Hour)
+	retried, err := service.OpenBreak("trade-1", StageClearing, "risk score exceeded")
+	if err != nil {
+		t.Fatalf("OpenBreak: %v", err)
+	}
+	if want := openedAt.Add(time.Hour); !retried.UpdatedAt.Equal(want) {
+		t.Errorf("retried break updated at %s, want the clock's %s", retried.UpdatedAt, want)
+	}
+
+	clock.Advance(time.Hour)
+	resolved, err := service.ResolveBreak(opened.BreakID, "ops", "re-cleared")
+	if err != nil {
+		t.Fatalf("ResolveBreak: %v", err)
+	}
+	if want := openedAt.Add(2 * time.Hour); resolved.ResolvedAt == nil || !resolved.ResolvedAt.Equal(want) {
+		t.Errorf("break resolved at %v, want the clock's %s", resolved.ResolvedAt, want)
+	}
+}
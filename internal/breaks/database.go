@@ -0,0 +1,86 @@
+package breaks
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Database struct {
+	db *gorm.DB
+}
+
+func NewDatabase(db *gorm.DB) *Database {
+	return &Database{db: db}
+}
+
+// CreateBreak creates a new trade break record
+func (d *Database) CreateBreak(tradeBreak *TradeBreak) error {
+	return d.db.Create(tradeBreak).Error
+}
+
+// GetBreak retrieves a trade break by its break ID
+func (d *Database) GetBreak(breakID string) (*TradeBreak, error) {
+	var tradeBreak TradeBreak
+	if err := d.db.Where("break_id = ?", breakID).First(&tradeBreak).Error; err != nil {
+		return nil, err
+	}
+	return &tradeBreak, nil
+}
+
+// GetOpenBreak retrieves the open break for a trade at a stage, or nil if there is none
+func (d *Database) GetOpenBreak(tradeID, stage string) (*TradeBreak, error) {
+	var tradeBreak TradeBreak
+	err := d.db.Where("trade_id = ? AND stage = ? AND status = ?", tradeID, stage, StatusOpen).
+		First(&tradeBreak).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tradeBreak, nil
+}
+
+// UpdateBreak saves the reason of an existing trade break along with its update time
+// The update time is written as given rather than stamped by gorm, so it follows the service clock
+func (d *Database) UpdateBreak(tradeBreak *TradeBreak) error {
+	return d.db.Model(&TradeBreak{}).
+		Where("break_id = ?", tradeBreak.BreakID).
+		Updates(map[string]interface{}{
+			"reason":     tradeBreak.Reason,
+			"updated_at": tradeBreak.UpdatedAt,
+		}).Error
+}
+
+// ListOpenBreaks retrieves open breaks, oldest first, optionally filtered by stage
+func (d *Database) ListOpenBreaks(stage string) ([]TradeBreak, error) {
+	query := d.db.Where("status = ?", StatusOpen)
+	if stage != "" {
+		query = query.Where("stage = ?", stage)
+	}
+	var tradeBreaks []TradeBreak
+	if err := query.Order("created_at ASC").Find(&tradeBreaks).Error; err != nil {
+		return nil, err
+	}
+	return tradeBreaks, nil
+}
+
+// ResolveBreak marks an open break resolved. It reports false if the break was not open,
+// so two concurrent resolutions cannot both succeed
+func (d *Database) ResolveBreak(breakID, assignee, notes string, resolvedAt time.Time) (resolved bool, err error) {
+	result := d.db.Model(&TradeBreak{}).
+		Where("break_id = ? AND status = ?", breakID, StatusOpen).
+		Updates(map[string]interface{}{
+			"status":      StatusResolved,
+			"assignee":    assignee,
+			"notes":       notes,
+			"resolved_at": resolvedAt,
+			"updated_at":  resolvedAt,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
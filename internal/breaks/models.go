@@ -0,0 +1,28 @@
+package breaks
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TradeBreak records a trade that failed clearing or settlement and needs operations follow-up
+type TradeBreak struct {
+	gorm.Model `json:"-"`
+	BreakID    string     `gorm:"uniqueIndex" json:"break_id"`
+	TradeID    string     `gorm:"index" json:"trade_id"`
+	Stage      string     `json:"stage"` // CLEARING or SETTLEMENT
+	Reason     string     `json:"reason"`
+	Status     string     `gorm:"index" json:"status"` // OPEN or RESOLVED
+	Assignee   string     `json:"assignee,omitempty"`
+	Notes      string     `json:"notes,omitempty"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// ResolveBreakRequest is the body accepted when resolving a trade break
+type ResolveBreakRequest struct {
+	Assignee string `json:"assignee"`
+	Notes    string `json:"notes" binding:"required"`
+}
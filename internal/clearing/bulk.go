@@ -0,0 +1,240 @@
+package clearing
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ksred/klear-api/internal/breaks"
+	"github.com/ksred/klear-api/pkg/common"
+	"github.com/ksred/klear-api/pkg/money"
+	"github.com/ksred/klear-api/pkg/response"
+	"github.com/rs/zerolog/log"
+)
+
+// ErrNoTradesInWindow is returned when a bulk clear finds no executions to net
+var ErrNoTradesInWindow = errors.New("no trades in netting window")
+
+// StatusSkipped marks a trade left out of a bulk clear because it was already cleared
+const StatusSkipped = "SKIPPED"
+
+// BulkClearingRequest selects the netting window to clear
+type BulkClearingRequest struct {
+	Symbol string `json:"symbol" binding:"required"`
+	Window string `json:"window"` // Lookback duration such as "4h"; defaults to 24h
+}
+
+// BulkClearingResult reports the outcome of clearing one trade in a bulk clear
+type BulkClearingResult struct {
+	TradeID        string   `json:"trade_id"`
+	ClearingID     string   `json:"clearing_id,omitempty"`
+	ClearingStatus string   `json:"clearing_status"` // CLEARED, FAILED or SKIPPED
+	MarginRequired float64  `json:"margin_required"`
+	Error          string   `json:"error,omitempty"`
+	Warnings       []string `json:"warnings,omitempty"`
+}
+
+// BulkClearingResponse reports a bulk clear of a symbol's netting window
+type BulkClearingResponse struct {
+	NettingID string               `json:"netting_id,omitempty"` // Omitted when every trade was skipped
+	Symbol    string               `json:"symbol"`
+	Netting   *NettingSummary      `json:"netting,omitempty"`
+	Cleared   int                  `json:"cleared"`
+	Failed    int                  `json:"failed"`
+	Skipped   int                  `json:"skipped"`
+	Results   []BulkClearingResult `json:"results"`
+	Timestamp time.Time            `json:"timestamp"`
+}
+
+// ClearNettingWindow nets every execution in a symbol's window once and clears each of them
+// against the shared netting. The netting margin is allocated across trades by notional.
+// A client's margin accumulates across its trades in the window, so the utilization limit applies
+// to everything the client clears in the pass rather than to each trade alone.
+// Trades that are already cleared are skipped
+// Parameters:
+//   - symbol: Symbol to clear
+//   - window: Lookback from now; zero uses the clearing netting window
+func (s *Service) ClearNettingWindow(symbol string, window time.Duration) (*BulkClearingResponse, error) {
+	symbol = common.NormalizeSymbol(symbol)
+	if symbol == "" {
+		return nil, ErrSymbolRequired
+	}
+	if window == 0 {
+		window = defaultNettingWindow
+	}
+	if window < 0 {
+		return nil, ErrInvalidNettingWindow
+	}
+
+	logger := log.With().
+		Str("symbol", symbol).
+		Dur("window", window).
+		Str("service", "clearing").
+		Logger()
+
+	logger.Info().Msg("starting bulk clearing of netting window")
+
+	windowEnd := s.clock.Now()
+	windowStart := windowEnd.Add(-window)
+
+	// Every trade in the window is fetched so already-cleared ones can be reported as skipped
+	executions, err := s.db.GetTradesForNetting(symbol, windowStart, windowEnd, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(executions) == 0 {
+		return nil, ErrNoTradesInWindow
+	}
+	orderMap, err := s.db.GetOrdersForExecutions(executions)
+	if err != nil {
+		return nil, err
+	}
+
+	netting, err := s.calculateSymbolNetting(symbol, windowStart, windowEnd, 0, s.config.ExcludeClearedFromNetting)
+	if err != nil {
+		return nil, err
+	}
+	netting.NettingType = NettingTypeClearing
+	roundNettingAmounts(netting, money.DefaultCurrency)
+
+	// Margin is allocated over the same trades that were netted
+	netted := executions
+	if s.config.ExcludeClearedFromNetting {
+		netted, err = s.db.GetTradesForNetting(symbol, windowStart, windowEnd, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+	grossNotional := 0.0
+	for _, exec := range netted {
+		grossNotional = money.Sum(money.DefaultCurrency, grossNotional,
+			money.Notional(exec.AveragePrice, exec.TotalQuantity, money.DefaultCurrency))
+	}
+
+	resp := &BulkClearingResponse{
+		NettingID: netting.NettingID,
+		Symbol:    symbol,
+		Results:   make([]BulkClearingResult, 0, len(executions)),
+	}
+	clearings := make([]*Clearing, 0, len(executions))
+	// Margin taken by each client's trades cleared so far in this pass
+	committedMargin := make(map[string]float64)
+
+	for i := range executions {
+		exec := &executions[i]
+		order := orderMap[exec.OrderID]
+		result := BulkClearingResult{TradeID: exec.ExecutionID}
+
+		cleared, err := s.db.HasClearingWithStatus(exec.ExecutionID, StatusCleared)
+		if err != nil {
+			return nil, err
+		}
+		if cleared {
+			result.ClearingStatus = StatusSkipped
+			result.Error = ErrAlreadyCleared.Error()
+			resp.Skipped++
+			resp.Results = append(resp.Results, result)
+			continue
+		}
+
+		clearing := &Clearing{
+			ClearingID:     "CLR_" + uuid.New().String(),
+			TradeID:        exec.ExecutionID,
+			NettingID:      netting.NettingID,
+			ClearingHouse:  s.config.ClearingHouseFor(symbol),
+			ClearingStatus: StatusPending,
+			CreatedAt:      s.clock.Now(),
+			UpdatedAt:      s.clock.Now(),
+		}
+		if grossNotional > 0 {
+			tradeNotional := money.Notional(exec.AveragePrice, exec.TotalQuantity, money.DefaultCurrency)
+			clearing.MarginRequired = netting.NetMargin * tradeNotional / grossNotional
+		}
+
+		warnings, err := s.processClearingCalculations(clearing, exec, &order, committedMargin[order.ClientID])
+		if err != nil {
+			logger.Warn().Err(err).Str("trade_id", exec.ExecutionID).Msg("trade failed bulk clearing")
+			clearing.ClearingStatus = StatusFailed
+			result.Error = err.Error()
+			resp.Failed++
+		} else {
+			clearing.ClearingStatus = StatusCleared
+			result.Warnings = warnings
+			resp.Cleared++
+			committedMargin[order.ClientID] += clearing.MarginRequired
+		}
+		roundMonetaryAmounts(clearing, order.Currency)
+
+		result.ClearingID = clearing.ClearingID
+		result.ClearingStatus = clearing.ClearingStatus
+		result.MarginRequired = clearing.MarginRequired
+		resp.Results = append(resp.Results, result)
+		clearings = append(clearings, clearing)
+	}
+
+	// Nothing new was cleared, so there is no netting run worth recording
+	if len(clearings) == 0 {
+		resp.NettingID = ""
+		resp.Timestamp = s.clock.Now()
+		logger.Info().Int("skipped", resp.Skipped).Msg("all trades in netting window already cleared")
+		return resp, nil
+	}
+
+	if err := s.db.SaveNettingResult(netting, clearings...); err != nil {
+		logger.Error().Err(err).Msg("failed to save bulk clearing results")
+		return nil, err
+	}
+
+	for _, result := range resp.Results {
+		if result.ClearingStatus == StatusFailed {
+			s.breaks.Record(result.TradeID, breaks.StageClearing, result.Error)
+		}
+	}
+
+	resp.Netting = newNettingSummary(netting)
+	resp.Timestamp = s.clock.Now()
+
+	logger.Info().
+		Str("netting_id", netting.NettingID).
+		Int("cleared", resp.Cleared).
+		Int("failed", resp.Failed).
+		Int("skipped", resp.Skipped).
+		Msg("completed bulk clearing of netting window")
+
+	return resp, nil
+}
+
+// ClearNettingWindowHandler handles POST requests to clear every trade in a symbol's netting window
+// Requires internal authentication
+// Request body should contain the symbol and an optional window
+func (h *GinHandlers) ClearNettingWindowHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req BulkClearingRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BindError(c, err)
+			return
+		}
+
+		var window time.Duration
+		if req.Window != "" {
+			parsed, err := time.ParseDuration(req.Window)
+			if err != nil {
+				response.BadRequest(c, "Invalid window format, expected a duration such as 24h")
+				return
+			}
+			window = parsed
+		}
+
+		result, err := h.service.ClearNettingWindow(req.Symbol, window)
+		switch {
+		case errors.Is(err, ErrSymbolRequired) || errors.Is(err, ErrInvalidNettingWindow):
+			response.BadRequest(c, err.Error())
+		case errors.Is(err, ErrNoTradesInWindow):
+			response.NotFound(c, err.Error())
+		default:
+			response.HandleWithStatus(c, result, err, http.StatusOK)
+		}
+	}
+}
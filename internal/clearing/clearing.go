@@ -5,36 +5,73 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/ksred/klear-api/internal/breaks"
+	"github.com/ksred/klear-api/internal/pricefeed"
 	"github.com/ksred/klear-api/internal/types"
+	"github.com/ksred/klear-api/pkg/common"
+	"github.com/ksred/klear-api/pkg/money"
 	"github.com/ksred/klear-api/pkg/response"
 	"github.com/rs/zerolog/log"
 	"gorm.io/gorm"
 )
 
+var (
+	ErrAlreadyCleared   = errors.New("trade has already been cleared")
+	ErrNoFailedClearing = errors.New("trade has no failed clearing to retry")
+	ErrUnknownSide      = errors.New("unknown order side")
+	ErrNoTradesToNet    = errors.New("no trades to net in the netting window")
+	// ErrExecutionNotCompleted is returned when the trade's execution is PENDING or FAILED
+	ErrExecutionNotCompleted = errors.New("execution is not completed")
+)
+
 // Service handles trade clearing operations
 type Service struct {
-	db *Database
+	db     *Database
+	breaks *breaks.Service
+	feed   pricefeed.PriceFeed
+	config Config
+	clock  common.Clock
 }
 
-// NewService creates a new clearing service with the given database connection
-func NewService(gormDB *gorm.DB) *Service {
+// NewService creates a new clearing service with the given database connection, price feed and configuration
+func NewService(gormDB *gorm.DB, feed pricefeed.PriceFeed, config Config) *Service {
 	return &Service{
-		db: NewDatabase(gormDB),
+		db:     NewDatabase(gormDB),
+		breaks: breaks.NewService(gormDB),
+		feed:   feed,
+		config: config,
+		clock:  common.RealClock{},
 	}
 }
 
+// SetClock replaces the clock used for market-hours checks, netting windows and record timestamps
+func (s *Service) SetClock(clock common.Clock) {
+	s.clock = clock
+	s.breaks.SetClock(clock)
+}
+
 const (
 	StatusPending = "PENDING"
 	StatusCleared = "CLEARED"
 	StatusFailed  = "FAILED"
+	// StatusReversed marks the clearing of a busted trade
+	StatusReversed = "REVERSED"
 )
 
+// executionStatusCompleted is the only execution status that can be cleared
+const executionStatusCompleted = "COMPLETED"
+
+// defaultVolatility is used when the price feed has no volatility for a symbol
+const defaultVolatility = 0.15 // 15% base market volatility
+
 // ClearTrade handles the clearing process for a trade
 // It performs trade netting, calculates margins, and validates clearing rules
+// A trade that is already cleared returns its existing clearing, so retries never clear it twice
 // Parameters:
 //   - tradeID: ID of the trade to clear
 func (s *Service) ClearTrade(tradeID string) (*ClearingResponse, error) {
@@ -45,13 +82,26 @@ func (s *Service) ClearTrade(tradeID string) (*ClearingResponse, error) {
 
 	logger.Info().Msg("starting clearing process for trade")
 
+	// A retried clear→settle chain returns the existing clearing rather than clearing the trade twice
+	existing, err := s.db.GetClearedClearingByTradeID(tradeID)
+	if err == nil {
+		logger.Info().
+			Str("clearing_id", existing.ClearingID).
+			Msg("trade already cleared, returning existing clearing")
+		return s.newClearingResponse(existing)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		logger.Error().Err(err).Msg("failed to check for an existing clearing")
+		return nil, fmt.Errorf("failed to check for an existing clearing: %w", err)
+	}
+
 	// Create initial clearing record with PENDING status
 	clearing := &Clearing{
 		ClearingID:     "CLR_" + uuid.New().String(),
 		TradeID:        tradeID,
 		ClearingStatus: StatusPending,
-		CreatedAt:      time.Now(),
-		UpdatedAt:      time.Now(),
+		CreatedAt:      s.clock.Now(),
+		UpdatedAt:      s.clock.Now(),
 	}
 
 	logger.Debug().
@@ -73,6 +123,12 @@ func (s *Service) ClearTrade(tradeID string) (*ClearingResponse, error) {
 		Float64("average_price", execution.AveragePrice).
 		Msg("fetched execution details")
 
+	// A pending or failed execution has nothing to clear, so no clearing record is made for it
+	if execution.Status != executionStatusCompleted {
+		logger.Warn().Str("execution_status", execution.Status).Msg("clearing rejected, execution is not completed")
+		return nil, fmt.Errorf("%w: status is %s", ErrExecutionNotCompleted, execution.Status)
+	}
+
 	// Get order details
 	order, err := s.db.GetOrderByID(execution.OrderID)
 	if err != nil {
@@ -88,6 +144,8 @@ func (s *Service) ClearTrade(tradeID string) (*ClearingResponse, error) {
 		Float64("quantity", order.Quantity).
 		Msg("fetched order details")
 
+	clearing.ClearingHouse = s.config.ClearingHouseFor(order.Symbol)
+
 	// Perform trade netting
 	nettingResult, err := s.calculateTradeNetting(execution, order)
 	if err != nil {
@@ -97,7 +155,9 @@ func (s *Service) ClearTrade(tradeID string) (*ClearingResponse, error) {
 			logger.Error().Err(err).Msg("failed to save failed clearing record")
 			return nil, err
 		}
-		return nil, fmt.Errorf("netting calculation failed: %w", err)
+		err = fmt.Errorf("netting calculation failed: %w", err)
+		s.breaks.Record(tradeID, breaks.StageClearing, err.Error())
+		return nil, err
 	}
 
 	logger.Info().
@@ -109,22 +169,28 @@ func (s *Service) ClearTrade(tradeID string) (*ClearingResponse, error) {
 		Msg("completed trade netting calculation")
 
 	// Update clearing with netted values
+	clearing.NettingID = nettingResult.NettingID
 	clearing.NetPositions = nettingResult.NetQuantity
 	clearing.SettlementAmount = nettingResult.NetSettlement
 	clearing.MarginRequired = nettingResult.NetMargin
 
 	// Process clearing calculations and validation
-	if err := s.processClearingCalculations(clearing, execution, order); err != nil {
+	warnings, err := s.processClearingCalculations(clearing, execution, order, 0)
+	if err != nil {
 		logger.Error().Err(err).Msg("clearing calculations failed")
 		clearing.ClearingStatus = StatusFailed
 		if err := s.db.CreateClearing(clearing); err != nil {
 			logger.Error().Err(err).Msg("failed to save failed clearing record")
 			return nil, err
 		}
+		s.breaks.Record(tradeID, breaks.StageClearing, err.Error())
 		return nil, err
 	}
 
 	clearing.ClearingStatus = StatusCleared
+	roundMonetaryAmounts(clearing, order.Currency)
+	// A netting spans every trade in the symbol, whatever their currencies
+	roundNettingAmounts(nettingResult, money.DefaultCurrency)
 
 	// Save both netting result and clearing in a transaction
 	if err := s.db.SaveNettingResult(nettingResult, clearing); err != nil {
@@ -143,27 +209,116 @@ func (s *Service) ClearTrade(tradeID string) (*ClearingResponse, error) {
 	return &ClearingResponse{
 		ClearingID:       clearing.ClearingID,
 		ClearingStatus:   clearing.ClearingStatus,
+		ClearingHouse:    clearing.ClearingHouse,
 		MarginRequired:   clearing.MarginRequired,
 		NetPositions:     clearing.NetPositions,
 		SettlementAmount: clearing.SettlementAmount,
-		Timestamp:        time.Now(),
+		NettingID:        nettingResult.NettingID,
+		Netting:          newNettingSummary(nettingResult),
+		Fills:            newFillSummaries(execution.Fills),
+		Warnings:         warnings,
+		Timestamp:        s.clock.Now(),
 	}, nil
 }
 
+// RetryClearing re-attempts clearing for a trade whose latest clearing attempt failed
+// A fresh clearing record is created for the new attempt; the failed record is kept for audit
+// Parameters:
+//   - tradeID: ID of the trade to re-clear
+func (s *Service) RetryClearing(tradeID string) (*ClearingResponse, error) {
+	logger := log.With().
+		Str("trade_id", tradeID).
+		Str("service", "clearing").
+		Logger()
+
+	cleared, err := s.db.HasClearingWithStatus(tradeID, StatusCleared)
+	if err != nil {
+		return nil, err
+	}
+	if cleared {
+		logger.Warn().Msg("retry rejected, trade already cleared")
+		return nil, ErrAlreadyCleared
+	}
+
+	latest, err := s.db.GetLatestClearingByTradeID(tradeID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNoFailedClearing
+		}
+		return nil, fmt.Errorf("failed to fetch latest clearing: %w", err)
+	}
+	if latest.ClearingStatus != StatusFailed {
+		logger.Warn().
+			Str("clearing_id", latest.ClearingID).
+			Str("status", latest.ClearingStatus).
+			Msg("retry rejected, latest clearing is not failed")
+		return nil, ErrNoFailedClearing
+	}
+
+	logger.Info().
+		Str("failed_clearing_id", latest.ClearingID).
+		Msg("retrying failed clearing")
+
+	return s.ClearTrade(tradeID)
+}
+
+// roundMonetaryAmounts rounds the clearing amounts to the precision of the trade's currency before
+// they are persisted, as settlement rounds them
+func roundMonetaryAmounts(clearing *Clearing, currency string) {
+	clearing.MarginRequired = money.Round(clearing.MarginRequired, currency)
+	clearing.SettlementAmount = money.Round(clearing.SettlementAmount, currency)
+}
+
+// roundNettingAmounts rounds the netting amounts to currency precision before they are persisted
+func roundNettingAmounts(netting *TradeNetting, currency string) {
+	netting.NetAmount = money.Round(netting.NetAmount, currency)
+	netting.NetSettlement = money.Round(netting.NetSettlement, currency)
+	netting.NetMargin = money.Round(netting.NetMargin, currency)
+}
+
 // calculateTradeNetting performs multilateral netting for trades
 // Groups trades by symbol within the netting window and calculates net positions
+// Returns ErrNoTradesToNet if the window holds no trades to net, e.g. when clock skew puts the
+// trade being cleared outside it
 func (s *Service) calculateTradeNetting(execution *types.Execution, order *types.Order) (*TradeNetting, error) {
+	now := s.clock.Now()
+	windowStart := now.Add(-defaultNettingWindow)
+	netting, err := s.calculateSymbolNetting(order.Symbol, windowStart, now, execution.AveragePrice,
+		s.config.ExcludeClearedFromNetting)
+	if err != nil {
+		return nil, err
+	}
+	if newNettingSummary(netting).TradesNetted == 0 {
+		log.Warn().
+			Str("execution_id", execution.ExecutionID).
+			Str("symbol", order.Symbol).
+			Time("executed_at", execution.CreatedAt).
+			Time("window_start", windowStart).
+			Time("window_end", now).
+			Msg("netting window holds no trades to net")
+		return nil, fmt.Errorf("%w for %s (execution %s executed at %s, window %s to %s)", ErrNoTradesToNet,
+			order.Symbol, execution.ExecutionID, execution.CreatedAt.Format(time.RFC3339),
+			windowStart.Format(time.RFC3339), now.Format(time.RFC3339))
+	}
+	netting.NettingType = NettingTypeClearing
+	return netting, nil
+}
+
+// calculateSymbolNetting nets all trades in a symbol executed within the window
+// fallbackPrice values the net position when the price feed has no mark; if it is
+// zero the window's volume-weighted average price is used instead
+// excludeCleared leaves trades that were already cleared out of the netting
+func (s *Service) calculateSymbolNetting(symbol string, windowStart, windowEnd time.Time, fallbackPrice float64, excludeCleared bool) (*TradeNetting, error) {
 	logger := log.With().
-		Str("execution_id", execution.ExecutionID).
-		Str("symbol", order.Symbol).
+		Str("symbol", symbol).
 		Str("service", "clearing").
 		Logger()
 
 	logger.Info().Msg("starting trade netting calculation")
 
 	// Get all trades for the same symbol within the netting window
-	nettingWindowStart := time.Now().Add(-24 * time.Hour)
-	executions, err := s.db.GetTradesForNetting(order.Symbol, nettingWindowStart)
+	nettingWindowStart := windowStart
+	executions, err := s.db.GetTradesForNetting(symbol, nettingWindowStart, windowEnd, excludeCleared)
 	if err != nil {
 		logger.Error().Err(err).Msg("failed to fetch trades for netting")
 		return nil, err
@@ -184,12 +339,12 @@ func (s *Service) calculateTradeNetting(execution *types.Execution, order *types
 	// Initialize netting result
 	netting := &TradeNetting{
 		NettingID:      "NET_" + uuid.New().String(),
-		Symbol:         order.Symbol,
+		Symbol:         symbol,
 		WindowStart:    nettingWindowStart,
-		WindowEnd:      time.Now(),
+		WindowEnd:      windowEnd,
 		Status:         "PENDING",
-		CreatedAt:      time.Now(),
-		UpdatedAt:      time.Now(),
+		CreatedAt:      s.clock.Now(),
+		UpdatedAt:      s.clock.Now(),
 		OriginalTrades: "[]", // Will be updated with JSON array
 	}
 
@@ -201,6 +356,8 @@ func (s *Service) calculateTradeNetting(execution *types.Execution, order *types
 
 	// Process all trades for multilateral netting
 	tradeIDs := make([]string, 0, len(executions))
+	grossNotional := 0.0
+	grossQuantity := 0.0
 	for _, exec := range executions {
 		ord, exists := orderMap[exec.OrderID]
 		if !exists {
@@ -212,22 +369,34 @@ func (s *Service) calculateTradeNetting(execution *types.Execution, order *types
 		}
 
 		tradeIDs = append(tradeIDs, exec.ExecutionID)
-		if ord.Side == "BUY" {
+		// Notionals are computed and summed exactly so netted amounts carry no binary rounding drift
+		amount := money.Notional(exec.AveragePrice, exec.TotalQuantity, money.DefaultCurrency)
+		grossNotional = money.Sum(money.DefaultCurrency, grossNotional, amount)
+		grossQuantity += exec.TotalQuantity
+		switch ord.Side {
+		case "BUY":
 			netting.NetQuantity += exec.TotalQuantity
-			netting.NetAmount += exec.TotalQuantity * exec.AveragePrice
+			netting.NetAmount = money.Sum(money.DefaultCurrency, netting.NetAmount, amount)
 			logger.Debug().
 				Str("execution_id", exec.ExecutionID).
 				Float64("quantity", exec.TotalQuantity).
-				Float64("amount", exec.TotalQuantity*exec.AveragePrice).
+				Float64("amount", amount).
 				Msg("added buy trade to netting")
-		} else {
+		case "SELL":
 			netting.NetQuantity -= exec.TotalQuantity
-			netting.NetAmount -= exec.TotalQuantity * exec.AveragePrice
+			netting.NetAmount = money.Sum(money.DefaultCurrency, netting.NetAmount, -amount)
 			logger.Debug().
 				Str("execution_id", exec.ExecutionID).
 				Float64("quantity", -exec.TotalQuantity).
-				Float64("amount", -exec.TotalQuantity*exec.AveragePrice).
+				Float64("amount", -amount).
 				Msg("added sell trade to netting")
+		default:
+			logger.Error().
+				Str("execution_id", exec.ExecutionID).
+				Str("order_id", exec.OrderID).
+				Str("side", ord.Side).
+				Msg("unknown order side in netting window")
+			return nil, fmt.Errorf("%w %q on order %s", ErrUnknownSide, ord.Side, exec.OrderID)
 		}
 	}
 
@@ -250,9 +419,22 @@ func (s *Service) calculateTradeNetting(execution *types.Execution, order *types
 		concentrationMultiplier    = 1.15 // 15% extra for concentrated positions
 	)
 
+	// Value the net position at the live mark, falling back to the execution price
+	markPrice, err := s.feed.LastPrice(symbol)
+	if err != nil {
+		logger.Warn().Err(err).Msg("no mark price available, valuing at execution price")
+		markPrice = fallbackPrice
+		if markPrice <= 0 && grossQuantity > 0 {
+			markPrice = grossNotional / grossQuantity
+		}
+	}
+	markedExposure := math.Abs(netting.NetQuantity) * markPrice
+
 	// Start with base margin
-	netting.NetMargin = netting.NetSettlement * baseMarginRate
+	netting.NetMargin = markedExposure * baseMarginRate
 	logger.Debug().
+		Float64("mark_price", markPrice).
+		Float64("marked_exposure", markedExposure).
 		Float64("base_margin", netting.NetMargin).
 		Float64("base_rate", baseMarginRate).
 		Msg("calculated base margin")
@@ -273,6 +455,32 @@ func (s *Service) calculateTradeNetting(execution *types.Execution, order *types
 			Msg("applied concentration multiplier")
 	}
 
+	// Apply the margin floor so fully offsetting positions still post margin
+	// against their gross risk, then the cap
+	marginFloor := math.Max(s.config.MarginFloor, grossNotional*s.config.GrossMarginFloorRate)
+	if netting.NetMargin < marginFloor {
+		netting.NetMargin = marginFloor
+		logger.Debug().
+			Float64("gross_notional", grossNotional).
+			Float64("margin_floor", marginFloor).
+			Msg("applied margin floor")
+	}
+	if s.config.MarginCap > 0 && netting.NetMargin > s.config.MarginCap {
+		netting.NetMargin = s.config.MarginCap
+		logger.Debug().
+			Float64("margin_cap", s.config.MarginCap).
+			Msg("applied margin cap")
+	}
+
+	if err := errors.Join(
+		money.CheckFinite("net quantity", netting.NetQuantity),
+		money.CheckFinite("net amount", netting.NetAmount),
+		money.CheckFinite("net margin", netting.NetMargin),
+	); err != nil {
+		logger.Error().Err(err).Float64("mark_price", markPrice).Msg("netting produced non-finite values")
+		return nil, err
+	}
+
 	netting.Status = "COMPLETED"
 	logger.Info().
 		Float64("net_quantity", netting.NetQuantity).
@@ -286,9 +494,12 @@ func (s *Service) calculateTradeNetting(execution *types.Execution, order *types
 }
 
 // processClearingCalculations performs the core clearing calculations
-func (s *Service) processClearingCalculations(clearing *Clearing, execution *types.Execution, order *types.Order) error {
+// committedMargin is margin the client already holds against trades cleared earlier in the same pass,
+// counted towards the utilization limit with the clearing's own margin
+// Returns any non-fatal warnings raised while validating the clearing
+func (s *Service) processClearingCalculations(clearing *Clearing, execution *types.Execution, order *types.Order, committedMargin float64) ([]string, error) {
 	// Calculate settlement amount based on actual execution price and quantity
-	clearing.SettlementAmount = execution.AveragePrice * execution.TotalQuantity
+	clearing.SettlementAmount = money.Notional(execution.AveragePrice, execution.TotalQuantity, order.Currency)
 
 	// Calculate net positions
 	positionMultiplier := 1.0
@@ -297,17 +508,44 @@ func (s *Service) processClearingCalculations(clearing *Clearing, execution *typ
 	}
 	clearing.NetPositions = execution.TotalQuantity * positionMultiplier
 
+	// NaN passes every range check in validateClearing, so reject it before validating
+	if err := checkFiniteAmounts(clearing); err != nil {
+		return nil, fmt.Errorf("clearing validation failed: %w", err)
+	}
+
 	// Validate the clearing
-	if err := s.validateClearing(clearing, order); err != nil {
-		return fmt.Errorf("clearing validation failed: %w", err)
+	warnings, err := s.validateClearing(clearing, order, committedMargin)
+	if err != nil {
+		return nil, fmt.Errorf("clearing validation failed: %w", err)
 	}
 
-	return nil
+	return warnings, nil
+}
+
+// checkFiniteAmounts returns an error if any monetary field of the clearing is NaN or infinite
+// The offending fields are zeroed so the failed clearing can still be stored
+func checkFiniteAmounts(clearing *Clearing) error {
+	var errs []error
+	for _, field := range []struct {
+		name  string
+		value *float64
+	}{
+		{"settlement amount", &clearing.SettlementAmount},
+		{"net positions", &clearing.NetPositions},
+		{"margin required", &clearing.MarginRequired},
+	} {
+		if err := money.CheckFinite(field.name, *field.value); err != nil {
+			errs = append(errs, err)
+			*field.value = 0
+		}
+	}
+	return errors.Join(errs...)
 }
 
 // validateClearing performs validation checks on the clearing
 // Verifies position limits, margin requirements, and risk thresholds
-func (s *Service) validateClearing(clearing *Clearing, order *types.Order) error {
+// Margin utilization includes committedMargin, margin already taken by the client in the same pass
+func (s *Service) validateClearing(clearing *Clearing, order *types.Order, committedMargin float64) ([]string, error) {
 	logger := log.With().
 		Str("clearing_id", clearing.ClearingID).
 		Str("order_id", order.OrderID).
@@ -339,14 +577,14 @@ func (s *Service) validateClearing(clearing *Clearing, order *types.Order) error
 		logger.Error().
 			Float64("settlement_amount", clearing.SettlementAmount).
 			Msg("invalid settlement amount")
-		return errors.New("invalid settlement amount")
+		return nil, errors.New("invalid settlement amount")
 	}
 	if clearing.SettlementAmount > positionLimit {
 		logger.Error().
 			Float64("settlement_amount", clearing.SettlementAmount).
 			Float64("position_limit", positionLimit).
 			Msg("settlement amount exceeds position limit")
-		return fmt.Errorf("settlement amount %f exceeds position limit of %f",
+		return nil, fmt.Errorf("settlement amount %f exceeds position limit of %f",
 			clearing.SettlementAmount, positionLimit)
 	}
 
@@ -359,30 +597,42 @@ func (s *Service) validateClearing(clearing *Clearing, order *types.Order) error
 		logger.Error().
 			Float64("margin_required", clearing.MarginRequired).
 			Msg("invalid margin requirement")
-		return errors.New("invalid margin requirement")
+		return nil, errors.New("invalid margin requirement")
 	}
-	marginUtilization := clearing.MarginRequired / availableMargin
+	marginUtilization := (committedMargin + clearing.MarginRequired) / availableMargin
 	if marginUtilization > maxMarginUtilization {
 		logger.Error().
 			Float64("margin_utilization", marginUtilization).
 			Float64("max_margin_utilization", maxMarginUtilization).
 			Float64("margin_required", clearing.MarginRequired).
+			Float64("committed_margin", committedMargin).
 			Float64("available_margin", availableMargin).
 			Msg("margin utilization exceeds maximum allowed")
-		return fmt.Errorf("margin utilization %f exceeds maximum allowed %f",
+		return nil, fmt.Errorf("margin utilization %f exceeds maximum allowed %f",
 			marginUtilization, maxMarginUtilization)
 	}
 
+	var warnings []string
+	if warnThreshold := s.config.MarginWarnThreshold; warnThreshold > 0 && marginUtilization > warnThreshold {
+		logger.Warn().
+			Float64("margin_utilization", marginUtilization).
+			Float64("warn_threshold", warnThreshold).
+			Float64("max_margin_utilization", maxMarginUtilization).
+			Msg("margin utilization exceeds warning threshold")
+		warnings = append(warnings, fmt.Sprintf("margin utilization %.2f%% exceeds warning threshold of %.2f%%",
+			marginUtilization*100, warnThreshold*100))
+	}
+
 	logger.Debug().
 		Float64("margin_required", clearing.MarginRequired).
 		Float64("margin_utilization", marginUtilization).
 		Msg("margin validation passed")
 
 	// Get current day's net position
-	currentDayNetPosition, err := s.db.GetDailyNetPosition(order.ClientID)
+	currentDayNetPosition, err := s.db.GetDailyNetPosition(order.ClientID, s.clock.Now())
 	if err != nil {
 		logger.Error().Err(err).Msg("failed to get daily net position")
-		return fmt.Errorf("failed to get daily net position: %w", err)
+		return nil, fmt.Errorf("failed to get daily net position: %w", err)
 	}
 
 	projectedNetPosition := math.Abs(currentDayNetPosition + clearing.NetPositions)
@@ -393,7 +643,7 @@ func (s *Service) validateClearing(clearing *Clearing, order *types.Order) error
 			Float64("current_net_position", currentDayNetPosition).
 			Float64("new_position", clearing.NetPositions).
 			Msg("projected net position would exceed daily limit")
-		return fmt.Errorf("projected net position %f would exceed daily limit of %f",
+		return nil, fmt.Errorf("projected net position %f would exceed daily limit of %f",
 			projectedNetPosition, maxDailyNetPosition)
 	}
 
@@ -403,10 +653,10 @@ func (s *Service) validateClearing(clearing *Clearing, order *types.Order) error
 		Msg("position limit validation passed")
 
 	// Get current day's trading volume
-	currentDayVolume, err := s.db.GetDailyTradingVolume(order.ClientID)
+	currentDayVolume, err := s.db.GetDailyTradingVolume(order.ClientID, s.clock.Now())
 	if err != nil {
 		logger.Error().Err(err).Msg("failed to get daily trading volume")
-		return fmt.Errorf("failed to get daily trading volume: %w", err)
+		return nil, fmt.Errorf("failed to get daily trading volume: %w", err)
 	}
 
 	projectedDailyVolume := currentDayVolume + clearing.SettlementAmount
@@ -417,7 +667,7 @@ func (s *Service) validateClearing(clearing *Clearing, order *types.Order) error
 			Float64("current_volume", currentDayVolume).
 			Float64("new_volume", clearing.SettlementAmount).
 			Msg("projected daily volume would exceed limit")
-		return fmt.Errorf("projected daily volume %f would exceed limit of %f",
+		return nil, fmt.Errorf("projected daily volume %f would exceed limit of %f",
 			projectedDailyVolume, dailyTradingLimit)
 	}
 
@@ -427,7 +677,7 @@ func (s *Service) validateClearing(clearing *Clearing, order *types.Order) error
 		Msg("volume limit validation passed")
 
 	// Validate trade timing (mock market hours check). Using large values for testing
-	now := time.Now()
+	now := s.clock.Now()
 	marketOpen := time.Date(now.Year(), now.Month(), now.Day(), 1, 30, 0, 0, time.Local)  // 9:30 AM
 	marketClose := time.Date(now.Year(), now.Month(), now.Day(), 23, 0, 0, 0, time.Local) // 4:00 PM
 
@@ -443,35 +693,40 @@ func (s *Service) validateClearing(clearing *Clearing, order *types.Order) error
 			Time("market_open", marketOpen).
 			Time("market_close", marketClose).
 			Msg("clearing attempted outside market hours")
-		return errors.New("clearing can only be processed during market hours")
+		return nil, errors.New("clearing can only be processed during market hours")
 	}
 
 	// Mock risk scoring
-	riskScore := s.calculateMockRiskScore(clearing, order)
+	riskScore, err := s.calculateMockRiskScore(clearing, order)
+	if err != nil {
+		logger.Error().Err(err).Msg("risk score calculation failed")
+		return nil, err
+	}
 	if riskScore > 0.8 { // 80% risk threshold
 		logger.Error().
 			Float64("risk_score", riskScore).
 			Float64("risk_threshold", 0.8).
 			Msg("risk score exceeds acceptable threshold")
-		return fmt.Errorf("risk score %f exceeds acceptable threshold", riskScore)
+		return nil, fmt.Errorf("risk score %f exceeds acceptable threshold", riskScore)
 	}
 
 	logger.Debug().
 		Float64("risk_score", riskScore).
 		Msg("risk score validation passed")
 
-	logger.Info().Msg("clearing validation completed successfully")
-	return nil
+	logger.Info().Int("warnings", len(warnings)).Msg("clearing validation completed successfully")
+	return warnings, nil
 }
 
 // calculateMockRiskScore calculates a simple mock risk score between 0 and 1
-func (s *Service) calculateMockRiskScore(clearing *Clearing, order *types.Order) float64 {
+// Market volatility is sourced from the price feed for the order's symbol
+// A missing, negative or non-finite volatility falls back to defaultVolatility
+func (s *Service) calculateMockRiskScore(clearing *Clearing, order *types.Order) (float64, error) {
 	// Mock factors for risk calculation
 	const (
-		positionFactor   = 0.4  // 40% weight for position size
-		marginFactor     = 0.3  // 30% weight for margin utilization
-		volatilityFactor = 0.3  // 30% weight for market volatility
-		baseVolatility   = 0.15 // 15% base market volatility
+		positionFactor   = 0.4 // 40% weight for position size
+		marginFactor     = 0.3 // 30% weight for margin utilization
+		volatilityFactor = 0.3 // 30% weight for market volatility
 	)
 
 	// Position size risk (larger positions = higher risk)
@@ -480,18 +735,34 @@ func (s *Service) calculateMockRiskScore(clearing *Clearing, order *types.Order)
 	// Margin utilization risk
 	marginRisk := clearing.MarginRequired / 1000000.0 // Normalized to 1M
 
-	// Mock volatility risk (in reality, this would come from market data)
-	mockVolatility := baseVolatility
+	// Volatility risk from market data
+	volatility, err := s.feed.Volatility(order.Symbol)
+	if err != nil {
+		log.Warn().
+			Err(err).
+			Str("symbol", order.Symbol).
+			Msg("no volatility available, using default")
+		volatility = defaultVolatility
+	} else if money.CheckFinite("volatility", volatility) != nil || volatility < 0 {
+		log.Warn().
+			Float64("volatility", volatility).
+			Str("symbol", order.Symbol).
+			Msg("unusable volatility from price feed, using default")
+		volatility = defaultVolatility
+	}
 	if order.OrderType == "MARKET" {
-		mockVolatility *= 1.2 // 20% higher risk for market orders
+		volatility *= 1.2 // 20% higher risk for market orders
 	}
 
 	// Calculate weighted risk score
 	riskScore := (positionRisk * positionFactor) +
 		(marginRisk * marginFactor) +
-		(mockVolatility * volatilityFactor)
+		(volatility * volatilityFactor)
 
-	return math.Min(riskScore, 1.0) // Ensure score is between 0 and 1
+	if err := money.CheckFinite("risk score", riskScore); err != nil {
+		return 0, err
+	}
+	return math.Min(riskScore, 1.0), nil // Ensure score is between 0 and 1
 }
 
 // GetClearingStatus retrieves the current status of a clearing
@@ -500,15 +771,73 @@ func (s *Service) GetClearingStatus(clearingID string) (*ClearingResponse, error
 	if err != nil {
 		return nil, err
 	}
+	return s.newClearingResponse(clearing)
+}
 
-	return &ClearingResponse{
+// newClearingResponse builds the response for a stored clearing, with its netting and fills
+func (s *Service) newClearingResponse(clearing *Clearing) (*ClearingResponse, error) {
+	resp := &ClearingResponse{
 		ClearingID:       clearing.ClearingID,
 		ClearingStatus:   clearing.ClearingStatus,
+		ClearingHouse:    clearing.ClearingHouse,
 		MarginRequired:   clearing.MarginRequired,
 		NetPositions:     clearing.NetPositions,
 		SettlementAmount: clearing.SettlementAmount,
+		NettingID:        clearing.NettingID,
 		Timestamp:        clearing.UpdatedAt,
-	}, nil
+	}
+
+	// Failed clearings never persisted a netting record
+	if clearing.NettingID != "" {
+		netting, err := s.db.GetTradeNetting(clearing.NettingID)
+		if err != nil {
+			return nil, err
+		}
+		resp.Netting = newNettingSummary(netting)
+	}
+
+	execution, err := s.db.GetExecutionByID(clearing.TradeID)
+	if err != nil {
+		return nil, err
+	}
+	resp.Fills = newFillSummaries(execution.Fills)
+
+	return resp, nil
+}
+
+// newFillSummaries lists the venue fills that make up an execution
+func newFillSummaries(fills []types.ExchangeFill) []FillSummary {
+	summaries := make([]FillSummary, 0, len(fills))
+	for _, fill := range fills {
+		summaries = append(summaries, FillSummary{
+			FillID:     fill.FillID,
+			ExchangeID: fill.ExchangeID,
+			Quantity:   fill.Quantity,
+			Price:      fill.Price,
+			FeeRate:    fill.FeeRate,
+			FeeAmount:  fill.FeeAmount,
+		})
+	}
+	return summaries
+}
+
+// newNettingSummary builds the response summary for a netting record
+func newNettingSummary(netting *TradeNetting) *NettingSummary {
+	var tradeIDs []string
+	if err := json.Unmarshal([]byte(netting.OriginalTrades), &tradeIDs); err != nil {
+		log.Warn().Err(err).Str("netting_id", netting.NettingID).Msg("failed to parse netted trade IDs")
+	}
+
+	return &NettingSummary{
+		TradesNetted: len(tradeIDs),
+		WindowStart:  netting.WindowStart,
+		WindowEnd:    netting.WindowEnd,
+	}
+}
+
+// GetDailyStats retrieves a client's trading statistics for the given day
+func (s *Service) GetDailyStats(clientID string, date time.Time) (*DailyTradingStats, error) {
+	return s.db.GetDailyTradingStats(clientID, date)
 }
 
 // GinHandlers contains HTTP handlers for clearing endpoints
@@ -528,10 +857,48 @@ func NewGinHandlers(service *Service) *GinHandlers {
 // URL parameter: trade_id
 func (h *GinHandlers) ClearTradeHandler() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		tradeID := c.Param("trade_id")
+		tradeID := common.NormalizeID(c.Param("trade_id"))
+		if tradeID == "" {
+			response.BadRequest(c, "Trade ID is required")
+			return
+		}
+		if !common.IsValidTradeID(tradeID) {
+			response.BadRequest(c, "Trade ID must be a UUID or an EXEC- execution ID")
+			return
+		}
 
 		clearingResponse, err := h.service.ClearTrade(tradeID)
-		response.Handle(c, clearingResponse, err)
+		if errors.Is(err, types.ErrOrderDeleted) || errors.Is(err, types.ErrExecutionDeleted) || errors.Is(err, ErrNoTradesToNet) ||
+			errors.Is(err, ErrExecutionNotCompleted) {
+			response.Conflict(c, err.Error())
+			return
+		}
+		response.HandleWithStatus(c, clearingResponse, err, http.StatusOK)
+	}
+}
+
+// RetryClearingHandler handles POST requests to retry a failed clearing
+// Requires internal authentication
+// URL parameter: trade_id
+func (h *GinHandlers) RetryClearingHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tradeID := common.NormalizeID(c.Param("trade_id"))
+		if tradeID == "" {
+			response.BadRequest(c, "Trade ID is required")
+			return
+		}
+		if !common.IsValidTradeID(tradeID) {
+			response.BadRequest(c, "Trade ID must be a UUID or an EXEC- execution ID")
+			return
+		}
+
+		clearingResponse, err := h.service.RetryClearing(tradeID)
+		if errors.Is(err, ErrAlreadyCleared) || errors.Is(err, ErrNoFailedClearing) || errors.Is(err, ErrNoTradesToNet) ||
+			errors.Is(err, types.ErrOrderDeleted) || errors.Is(err, types.ErrExecutionDeleted) || errors.Is(err, ErrExecutionNotCompleted) {
+			response.Conflict(c, err.Error())
+			return
+		}
+		response.HandleWithStatus(c, clearingResponse, err, http.StatusOK)
 	}
 }
 
@@ -543,3 +910,30 @@ func (h *GinHandlers) GetClearingStatusHandler() gin.HandlerFunc {
 		response.Handle(c, clearingResponse, err)
 	}
 }
+
+// GetDailyStatsHandler handles GET requests for a client's daily trading stats
+// Requires internal authentication
+// URL parameter: client_id
+// Query parameter: date (YYYY-MM-DD, defaults to today)
+func (h *GinHandlers) GetDailyStatsHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clientID := common.NormalizeID(c.Param("client_id"))
+		if clientID == "" {
+			response.BadRequest(c, "Client ID is required")
+			return
+		}
+
+		date := h.service.clock.Now().UTC()
+		if dateParam := c.Query("date"); dateParam != "" {
+			parsed, err := time.Parse("2006-01-02", dateParam)
+			if err != nil {
+				response.BadRequest(c, "Invalid date format, expected YYYY-MM-DD")
+				return
+			}
+			date = parsed
+		}
+
+		stats, err := h.service.GetDailyStats(clientID, date)
+		response.Handle(c, stats, err)
+	}
+}
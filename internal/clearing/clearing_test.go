@@ -0,0 +1,930 @@
+package clearing
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ksred/klear-api/internal/breaks"
+	"github.com/ksred/klear-api/internal/database/dbtest"
+	"github.com/ksred/klear-api/internal/events"
+	"github.com/ksred/klear-api/internal/pricefeed"
+	"github.com/ksred/klear-api/internal/types"
+	"github.com/ksred/klear-api/pkg/common"
+	"github.com/ksred/klear-api/pkg/money"
+)
+
+// testNow is the time every test clock starts at, during market hours
+var testNow = time.Date(2026, 10, 14, 12, 0, 0, 0, time.Local)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	os.Exit(m.Run())
+}
+
+// newTestService creates a clearing service with the default configuration over a fresh database,
+// with its clock stopped at testNow
+func newTestService(t *testing.T) (*Service, *common.FakeClock) {
+	t.Helper()
+	return newTestServiceWithConfig(t, DefaultConfig())
+}
+
+// newTestServiceWithConfig creates a clearing service over a fresh database, with its clock stopped at testNow
+func newTestServiceWithConfig(t *testing.T, config Config) (*Service, *common.FakeClock) {
+	t.Helper()
+	db := dbtest.Open(t,
+		&types.Order{},
+		&types.Execution{},
+		&types.ExchangeFill{},
+		&Clearing{},
+		&TradeNetting{},
+		&events.DomainEvent{},
+		&breaks.TradeBreak{},
+	)
+	clock := common.NewFakeClock(testNow)
+	service := NewService(db, pricefeed.NewMockFeed(), config)
+	service.SetClock(clock)
+	return service, clock
+}
+
+// mockFeed returns the mock price feed a test service was created with
+func mockFeed(service *Service) *pricefeed.MockFeed {
+	return service.feed.(*pricefeed.MockFeed)
+}
+
+// seedTrade records a client's order and its completed execution, filled on one venue at the given time
+func seedTrade(t *testing.T, service *Service, clientID, symbol, side string, quantity, price float64, at time.Time) *types.Execution {
+	t.Helper()
+	order := &types.Order{
+		OrderID:   uuid.New().String(),
+		ClientID:  clientID,
+		Symbol:    symbol,
+		Side:      side,
+		OrderType: "LIMIT",
+		Quantity:  quantity,
+		Price:     price,
+		Currency:  "USD",
+		Status:    "FILLED",
+		CreatedAt: at,
+		UpdatedAt: at,
+	}
+	executionID := uuid.New().String()
+	execution := &types.Execution{
+		ExecutionID:   executionID,
+		OrderID:       order.OrderID,
+		TotalQuantity: quantity,
+		AveragePrice:  price,
+		Side:          side,
+		Status:        executionStatusCompleted,
+		Fills: []types.ExchangeFill{{
+			FillID:       "FILL-" + executionID,
+			ExchangeID:   "EXCH1",
+			ExchangeName: "Primary Exchange",
+			Price:        price,
+			Quantity:     quantity,
+			FeeRate:      0.001,
+			FeeAmount:    price * quantity * 0.001,
+			CreatedAt:    at,
+		}},
+		CreatedAt: at,
+		UpdatedAt: at,
+	}
+	if err := service.db.db.Create(order).Error; err != nil {
+		t.Fatalf("failed to seed order: %v", err)
+	}
+	if err := service.db.db.Create(execution).Error; err != nil {
+		t.Fatalf("failed to seed execution: %v", err)
+	}
+	return execution
+}
+
+// decodeData decodes the data of a success response envelope into v
+func decodeData(t *testing.T, recorder *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	var envelope struct {
+		Success bool            `json:"success"`
+		Data    json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("failed to decode response %s: %v", recorder.Body.String(), err)
+	}
+	if !envelope.Success {
+		t.Fatalf("response was not successful: %s", recorder.Body.String())
+	}
+	if err := json.Unmarshal(envelope.Data, v); err != nil {
+		t.Fatalf("failed to decode response data %s: %v", envelope.Data, err)
+	}
+}
+
+func TestGetDailyStatsHandler(t *testing.T) {
+	service, _ := newTestService(t)
+	day := testNow.Add(-2 * time.Hour)
+
+	seedTrade(t, service, "client-1", "AAPL", "BUY", 100, 150, day)
+	seedTrade(t, service, "client-1", "AAPL", "SELL", 40, 155, day.Add(time.Hour))
+	seedTrade(t, service, "client-1", "MSFT", "BUY", 10, 400, day.Add(90*time.Minute))
+	// Neither another client's trade nor the previous day's is counted
+	seedTrade(t, service, "client-2", "AAPL", "BUY", 500, 150, day)
+	seedTrade(t, service, "client-1", "AAPL", "BUY", 700, 150, day.Add(-24*time.Hour))
+
+	router := gin.New()
+	router.GET("/clients/:client_id/daily-stats", NewGinHandlers(service).GetDailyStatsHandler())
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/clients/client-1/daily-stats?date=2026-10-14", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", recorder.Code, recorder.Body.String())
+	}
+
+	var stats DailyTradingStats
+	decodeData(t, recorder, &stats)
+	want := DailyTradingStats{
+		ClientID:      "client-1",
+		Date:          "2026-10-14",
+		NetPosition:   70,                        // 100 - 40 + 10
+		TradingVolume: 100*150 + 40*155 + 10*400, // Both sides count towards volume
+		OrderCount:    3,
+		FillCount:     3,
+	}
+	if stats != want {
+		t.Errorf("stats = %+v, want %+v", stats, want)
+	}
+}
+
+func TestGetDailyStatsHandlerDefaultsToToday(t *testing.T) {
+	service, _ := newTestService(t)
+	seedTrade(t, service, "client-1", "AAPL", "BUY", 100, 150, testNow.Add(-time.Hour))
+
+	router := gin.New()
+	router.GET("/clients/:client_id/daily-stats", NewGinHandlers(service).GetDailyStatsHandler())
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/clients/client-1/daily-stats", nil))
+
+	var stats DailyTradingStats
+	decodeData(t, recorder, &stats)
+	if stats.Date != "2026-10-14" || stats.OrderCount != 1 {
+		t.Errorf("stats = %+v, want today's single order", stats)
+	}
+}
+
+func TestGetDailyStatsHandlerRejectsInvalidDate(t *testing.T) {
+	service, _ := newTestService(t)
+
+	router := gin.New()
+	router.GET("/clients/:client_id/daily-stats", NewGinHandlers(service).GetDailyStatsHandler())
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/clients/client-1/daily-stats?date=14/10/2026", nil))
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", recorder.Code)
+	}
+}
+
+func TestCalculateMockRiskScoreUsesFeedVolatility(t *testing.T) {
+	service, _ := newTestService(t)
+	mockFeed(service).SetQuote("CALM", 100, 0.05)
+	mockFeed(service).SetQuote("WILD", 100, 0.90)
+
+	clearing := &Clearing{NetPositions: 100, MarginRequired: 1000}
+	scoreFor := func(symbol string) float64 {
+		t.Helper()
+		score, err := service.calculateMockRiskScore(clearing, &types.Order{Symbol: symbol, OrderType: "LIMIT"})
+		if err != nil {
+			t.Fatalf("calculateMockRiskScore(%s): %v", symbol, err)
+		}
+		return score
+	}
+
+	// Position and margin risk are identical, so only the volatility weighting differs
+	base := 100/1000000.0*0.4 + 1000/1000000.0*0.3
+	tests := []struct {
+		symbol string
+		want   float64
+	}{
+		{"CALM", base + 0.05*0.3},
+		{"WILD", base + 0.90*0.3},
+		{"UNKNOWN", base + defaultVolatility*0.3},
+	}
+	for _, tt := range tests {
+		if got := scoreFor(tt.symbol); math.Abs(got-tt.want) > 1e-12 {
+			t.Errorf("risk score for %s = %v, want %v", tt.symbol, got, tt.want)
+		}
+	}
+	if scoreFor("WILD") <= scoreFor("CALM") {
+		t.Error("a volatile symbol should score riskier than a calm one")
+	}
+}
+
+func TestClearTradeValuesMarginAtLiveMark(t *testing.T) {
+	service, _ := newTestService(t)
+	execution := seedTrade(t, service, "client-1", "AAPL", "BUY", 100, 150, testNow.Add(-time.Minute))
+	mockFeed(service).SetQuote("AAPL", 200, 0.22)
+
+	response, err := service.ClearTrade(execution.ExecutionID)
+	if err != nil {
+		t.Fatalf("ClearTrade: %v", err)
+	}
+	// 100 shares marked at 200, not the 150 execution price, at the 10% base rate with the 1.2 volatility multiplier
+	if want := 100 * 200 * 0.10 * 1.2; math.Abs(response.MarginRequired-want) > 0.005 {
+		t.Errorf("margin required = %v, want %v", response.MarginRequired, want)
+	}
+	if want := 100.0 * 150; response.SettlementAmount != want {
+		t.Errorf("settlement amount = %v, want %v at the execution price", response.SettlementAmount, want)
+	}
+}
+
+func TestClearTradeRoundsToOrderCurrencyPrecision(t *testing.T) {
+	tests := []struct {
+		currency         string
+		settlementAmount float64
+		marginRequired   float64
+	}{
+		{"USD", 15012.35, 2400.01},
+		{"JPY", 15012, 2400},
+		{"BHD", 15012.345, 2400.015},
+	}
+	for _, tt := range tests {
+		t.Run(tt.currency, func(t *testing.T) {
+			service, _ := newTestService(t)
+			execution := seedTrade(t, service, "client-1", "AAPL", "BUY", 100, 150.12345, testNow.Add(-time.Minute))
+			if err := service.db.db.Model(&types.Order{}).Where("order_id = ?", execution.OrderID).
+				Update("currency", tt.currency).Error; err != nil {
+				t.Fatalf("failed to set order currency: %v", err)
+			}
+			// 100 marked at 200.00123 at the 10% base rate with the 1.2 volatility multiplier is 2400.01476
+			mockFeed(service).SetQuote("AAPL", 200.00123, 0.22)
+
+			response, err := service.ClearTrade(execution.ExecutionID)
+			if err != nil {
+				t.Fatalf("ClearTrade: %v", err)
+			}
+			if response.SettlementAmount != tt.settlementAmount || response.MarginRequired != tt.marginRequired {
+				t.Errorf("settlement amount %v, margin %v; want %v and %v",
+					response.SettlementAmount, response.MarginRequired, tt.settlementAmount, tt.marginRequired)
+			}
+			stored, err := service.db.GetClearedClearingByTradeID(execution.ExecutionID)
+			if err != nil {
+				t.Fatalf("GetClearedClearingByTradeID: %v", err)
+			}
+			if stored.SettlementAmount != tt.settlementAmount || stored.MarginRequired != tt.marginRequired {
+				t.Errorf("stored settlement amount %v, margin %v; want %v and %v",
+					stored.SettlementAmount, stored.MarginRequired, tt.settlementAmount, tt.marginRequired)
+			}
+		})
+	}
+}
+
+func TestClearingResponseListsContributingFills(t *testing.T) {
+	service, _ := newTestService(t)
+	execution := seedTrade(t, service, "client-1", "AAPL", "BUY", 60, 150, testNow.Add(-time.Minute))
+	// A second venue filled the rest of the 100 shares
+	execution.TotalQuantity = 100
+	second := types.ExchangeFill{
+		FillID:       "FILL-2-" + execution.ExecutionID,
+		ExecutionID:  execution.ExecutionID,
+		ExchangeID:   "EXCH2",
+		ExchangeName: "Secondary Exchange",
+		Price:        150,
+		Quantity:     40,
+		FeeRate:      0.002,
+		FeeAmount:    12,
+		CreatedAt:    testNow.Add(-time.Minute),
+	}
+	if err := service.db.db.Create(&second).Error; err != nil {
+		t.Fatalf("failed to seed fill: %v", err)
+	}
+	if err := service.db.db.Save(execution).Error; err != nil {
+		t.Fatalf("failed to update execution: %v", err)
+	}
+
+	cleared, err := service.ClearTrade(execution.ExecutionID)
+	if err != nil {
+		t.Fatalf("ClearTrade: %v", err)
+	}
+	status, err := service.GetClearingStatus(cleared.ClearingID)
+	if err != nil {
+		t.Fatalf("GetClearingStatus: %v", err)
+	}
+
+	want := map[string]FillSummary{
+		"EXCH1": {FillID: execution.Fills[0].FillID, ExchangeID: "EXCH1", Quantity: 60, Price: 150, FeeRate: 0.001, FeeAmount: 9},
+		"EXCH2": {FillID: second.FillID, ExchangeID: "EXCH2", Quantity: 40, Price: 150, FeeRate: 0.002, FeeAmount: 12},
+	}
+	for name, fills := range map[string][]FillSummary{"clearing": cleared.Fills, "status": status.Fills} {
+		if len(fills) != len(want) {
+			t.Errorf("%s response lists %d fills, want %d", name, len(fills), len(want))
+			continue
+		}
+		for _, fill := range fills {
+			if fill != want[fill.ExchangeID] {
+				t.Errorf("%s response fill = %+v, want %+v", name, fill, want[fill.ExchangeID])
+			}
+		}
+	}
+}
+
+func TestRetryClearingAfterFailure(t *testing.T) {
+	service, clock := newTestService(t)
+	beforeOpen := time.Date(testNow.Year(), testNow.Month(), testNow.Day(), 0, 30, 0, 0, time.Local)
+	clock.Set(beforeOpen)
+	execution := seedTrade(t, service, "client-1", "AAPL", "BUY", 100, 150, beforeOpen.Add(-10*time.Minute))
+
+	// Clearing outside market hours fails and is kept as FAILED
+	if _, err := service.ClearTrade(execution.ExecutionID); err == nil {
+		t.Fatal("ClearTrade succeeded outside market hours")
+	}
+	failed, err := service.db.GetLatestClearingByTradeID(execution.ExecutionID)
+	if err != nil {
+		t.Fatalf("GetLatestClearingByTradeID: %v", err)
+	}
+	if failed.ClearingStatus != StatusFailed {
+		t.Fatalf("clearing status = %s, want %s", failed.ClearingStatus, StatusFailed)
+	}
+
+	router := gin.New()
+	router.POST("/clearing/:trade_id/retry", NewGinHandlers(service).RetryClearingHandler())
+	retry := func() *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/clearing/"+execution.ExecutionID+"/retry", nil))
+		return recorder
+	}
+
+	// Once the market opens the retry clears the trade as a fresh attempt
+	clock.Set(testNow)
+	recorder := retry()
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("retry status = %d, want 200: %s", recorder.Code, recorder.Body.String())
+	}
+	var cleared ClearingResponse
+	decodeData(t, recorder, &cleared)
+	if cleared.ClearingStatus != StatusCleared {
+		t.Errorf("retried clearing status = %s, want %s", cleared.ClearingStatus, StatusCleared)
+	}
+	if cleared.ClearingID == failed.ClearingID {
+		t.Error("retry reused the failed clearing instead of creating a fresh attempt")
+	}
+
+	// A cleared trade cannot be retried
+	if recorder := retry(); recorder.Code != http.StatusConflict {
+		t.Errorf("second retry status = %d, want 409", recorder.Code)
+	}
+}
+
+func TestRetryClearingRequiresFailedClearing(t *testing.T) {
+	service, _ := newTestService(t)
+	execution := seedTrade(t, service, "client-1", "AAPL", "BUY", 100, 150, testNow.Add(-time.Minute))
+
+	if _, err := service.RetryClearing(execution.ExecutionID); !errors.Is(err, ErrNoFailedClearing) {
+		t.Errorf("RetryClearing error = %v, want ErrNoFailedClearing", err)
+	}
+}
+
+func TestSymbolNettingMatchesUnnormalizedSymbol(t *testing.T) {
+	service, _ := newTestService(t)
+	seedTrade(t, service, "client-1", "AAPL", "BUY", 100, 150, testNow.Add(-time.Hour))
+	seedTrade(t, service, "client-2", "AAPL", "SELL", 40, 150, testNow.Add(-time.Hour))
+
+	netting, err := service.calculateSymbolNetting(" aapl ", testNow.Add(-defaultNettingWindow), testNow, 0, false)
+	if err != nil {
+		t.Fatalf("calculateSymbolNetting: %v", err)
+	}
+	if netting.NetQuantity != 60 {
+		t.Errorf("net quantity = %v, want 60", netting.NetQuantity)
+	}
+}
+
+func TestClearingResponseReferencesNetting(t *testing.T) {
+	service, _ := newTestService(t)
+	seedTrade(t, service, "client-2", "AAPL", "SELL", 40, 150, testNow.Add(-2*time.Hour))
+	execution := seedTrade(t, service, "client-1", "AAPL", "BUY", 100, 150, testNow.Add(-time.Hour))
+
+	response, err := service.ClearTrade(execution.ExecutionID)
+	if err != nil {
+		t.Fatalf("ClearTrade: %v", err)
+	}
+
+	var netting TradeNetting
+	if err := service.db.db.Where("netting_id = ?", response.NettingID).First(&netting).Error; err != nil {
+		t.Fatalf("clearing response references netting %q that was not stored: %v", response.NettingID, err)
+	}
+	if netting.Symbol != "AAPL" || netting.NetQuantity != 60 {
+		t.Errorf("netting = %s %v, want AAPL netted to 60", netting.Symbol, netting.NetQuantity)
+	}
+	if response.Netting == nil || response.Netting.TradesNetted != 2 {
+		t.Errorf("netting summary = %+v, want 2 trades netted", response.Netting)
+	}
+	if !response.Netting.WindowEnd.Equal(testNow) || !response.Netting.WindowStart.Equal(testNow.Add(-defaultNettingWindow)) {
+		t.Errorf("netting window = %s to %s, want the %s before %s", response.Netting.WindowStart,
+			response.Netting.WindowEnd, defaultNettingWindow, testNow)
+	}
+
+	status, err := service.GetClearingStatus(response.ClearingID)
+	if err != nil {
+		t.Fatalf("GetClearingStatus: %v", err)
+	}
+	if status.NettingID != response.NettingID || status.Netting == nil || status.Netting.TradesNetted != 2 {
+		t.Errorf("clearing status netting = %s %+v, want %s with 2 trades", status.NettingID, status.Netting, response.NettingID)
+	}
+}
+
+func TestClearTradeEnforcesMarketHours(t *testing.T) {
+	midnight := time.Date(testNow.Year(), testNow.Month(), testNow.Day(), 0, 0, 0, 0, time.Local)
+	tests := []struct {
+		name    string
+		now     time.Time
+		wantErr bool
+	}{
+		{"midnight", midnight, true},
+		{"mid-session", testNow, false},
+		{"after close", midnight.Add(23*time.Hour + time.Minute), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _ := newTestService(t)
+			service.SetClock(common.FixedClock{Time: tt.now})
+			execution := seedTrade(t, service, "client-1", "AAPL", "BUY", 100, 150, tt.now.Add(-time.Minute))
+
+			_, err := service.ClearTrade(execution.ExecutionID)
+			if tt.wantErr && (err == nil || !strings.Contains(err.Error(), "market hours")) {
+				t.Errorf("ClearTrade error = %v, want a market hours rejection", err)
+			}
+			if !tt.wantErr && err != nil {
+				t.Errorf("ClearTrade: %v", err)
+			}
+		})
+	}
+}
+
+func TestNettingMarginFloorAndCap(t *testing.T) {
+	tests := []struct {
+		name       string
+		floor      float64
+		grossRate  float64
+		cap        float64
+		buy, sell  float64
+		wantMargin float64
+	}{
+		// Fully offsetting trades have no net exposure but still post margin on their gross
+		{"offsetting at absolute floor", 1000, 0.02, 0, 100, 100, 1000},
+		{"offsetting at gross floor", 100, 0.02, 0, 100, 100, 0.02 * 200 * 150},
+		// 1000 net at 190 marks to 190,000 of exposure, needing 22,800 before the cap
+		{"capped", 100, 0.02, 5000, 1000, 0, 5000},
+		{"uncapped", 100, 0.02, 0, 1000, 0, 1000 * 190 * 0.10 * 1.2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			config.MarginFloor = tt.floor
+			config.GrossMarginFloorRate = tt.grossRate
+			config.MarginCap = tt.cap
+			service, _ := newTestServiceWithConfig(t, config)
+			if tt.buy > 0 {
+				seedTrade(t, service, "client-1", "AAPL", "BUY", tt.buy, 150, testNow.Add(-time.Hour))
+			}
+			if tt.sell > 0 {
+				seedTrade(t, service, "client-2", "AAPL", "SELL", tt.sell, 150, testNow.Add(-time.Hour))
+			}
+
+			netting, err := service.calculateSymbolNetting("AAPL", testNow.Add(-defaultNettingWindow), testNow, 0, false)
+			if err != nil {
+				t.Fatalf("calculateSymbolNetting: %v", err)
+			}
+			if math.Abs(netting.NetMargin-tt.wantMargin) > 1e-6 {
+				t.Errorf("net margin = %v, want %v", netting.NetMargin, tt.wantMargin)
+			}
+		})
+	}
+}
+
+func TestClearTradeHandlerRejectsInvalidTradeIDs(t *testing.T) {
+	service, _ := newTestService(t)
+	router := gin.New()
+	router.POST("/clearing/:trade_id", NewGinHandlers(service).ClearTradeHandler())
+
+	for _, tradeID := range []string{"%20", "not-a-trade", "EXEC-12a"} {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/clearing/"+tradeID, nil))
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("trade ID %q: status = %d, want 400", tradeID, recorder.Code)
+		}
+	}
+}
+
+func TestClearTradeWarnsAboveSoftMarginThreshold(t *testing.T) {
+	config := DefaultConfig()
+	config.MarginCap = 0
+	tests := []struct {
+		name         string
+		mark         float64
+		wantErr      bool
+		wantWarnings int
+	}{
+		// 2,000 concentrated shares at the 10% base rate and 1.2 volatility multiplier
+		// against $1M of available margin
+		{"below soft threshold", 1300, false, 0}, // 35.9% utilization
+		{"above soft threshold", 1700, false, 1}, // 46.9% utilization
+		{"above hard maximum", 3000, true, 0},    // 82.8% utilization
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, _ := newTestServiceWithConfig(t, config)
+			execution := seedTrade(t, service, "client-1", "AAPL", "BUY", 2000, 150, testNow.Add(-time.Minute))
+			mockFeed(service).SetQuote("AAPL", tt.mark, 0.22)
+
+			response, err := service.ClearTrade(execution.ExecutionID)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ClearTrade succeeded with margin %v, want a margin utilization error", response.MarginRequired)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ClearTrade: %v", err)
+			}
+			if response.ClearingStatus != StatusCleared {
+				t.Errorf("clearing status = %s, want %s", response.ClearingStatus, StatusCleared)
+			}
+			if len(response.Warnings) != tt.wantWarnings {
+				t.Errorf("warnings = %v, want %d", response.Warnings, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestClearTradeRecordsRoutedClearingHouse(t *testing.T) {
+	config := DefaultConfig()
+	config.ClearingHouseRoutes = map[string]string{"MSFT": "LCH"}
+	service, _ := newTestServiceWithConfig(t, config)
+	routed := seedTrade(t, service, "client-1", "MSFT", "BUY", 100, 300, testNow.Add(-time.Minute))
+	defaulted := seedTrade(t, service, "client-1", "AAPL", "BUY", 100, 150, testNow.Add(-time.Minute))
+
+	tests := []struct {
+		execution *types.Execution
+		want      string
+	}{
+		{routed, "LCH"},
+		{defaulted, config.ClearingHouse},
+	}
+	for _, tt := range tests {
+		response, err := service.ClearTrade(tt.execution.ExecutionID)
+		if err != nil {
+			t.Fatalf("ClearTrade: %v", err)
+		}
+		if response.ClearingHouse != tt.want {
+			t.Errorf("clearing house = %s, want %s", response.ClearingHouse, tt.want)
+		}
+		stored, err := service.GetClearingStatus(response.ClearingID)
+		if err != nil {
+			t.Fatalf("GetClearingStatus: %v", err)
+		}
+		if stored.ClearingHouse != tt.want {
+			t.Errorf("stored clearing house = %s, want %s", stored.ClearingHouse, tt.want)
+		}
+	}
+}
+
+func TestPreviewNettingHandlerListsOnlyInWindowTrades(t *testing.T) {
+	service, _ := newTestService(t)
+	buy := seedTrade(t, service, "client-1", "AAPL", "BUY", 100, 150, testNow.Add(-time.Hour))
+	sell := seedTrade(t, service, "client-2", "AAPL", "SELL", 40, 155, testNow.Add(-3*time.Hour))
+	// Neither a trade before the window nor another symbol's trade is a candidate
+	seedTrade(t, service, "client-1", "AAPL", "BUY", 500, 150, testNow.Add(-5*time.Hour))
+	seedTrade(t, service, "client-1", "MSFT", "BUY", 10, 400, testNow.Add(-time.Hour))
+
+	router := gin.New()
+	router.GET("/netting/preview", NewGinHandlers(service).PreviewNettingHandler())
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/netting/preview?symbol=aapl&window=4h", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", recorder.Code, recorder.Body.String())
+	}
+	var preview NettingPreview
+	decodeData(t, recorder, &preview)
+
+	ids := map[string]bool{}
+	for _, trade := range preview.Trades {
+		ids[trade.ExecutionID] = true
+	}
+	if len(preview.Trades) != 2 || !ids[buy.ExecutionID] || !ids[sell.ExecutionID] {
+		t.Fatalf("trades = %+v, want only the two AAPL trades in the last 4h", preview.Trades)
+	}
+	if preview.NetQuantity != 60 || preview.NetAmount != 15000-6200 {
+		t.Errorf("net = %v @ %v, want 60 and 8800", preview.NetQuantity, preview.NetAmount)
+	}
+
+	// Previewing creates no netting record
+	var nettings int64
+	if err := service.db.db.Model(&TradeNetting{}).Count(&nettings).Error; err != nil {
+		t.Fatalf("failed to count nettings: %v", err)
+	}
+	if nettings != 0 {
+		t.Errorf("%d netting records created, want none", nettings)
+	}
+
+	for _, query := range []string{"window=4h", "symbol=AAPL&window=-1h", "symbol=AAPL&window=soon"} {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/netting/preview?"+query, nil))
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", query, recorder.Code)
+		}
+	}
+}
+
+func TestClearNettingWindowClearsEveryTradeUnderOneNetting(t *testing.T) {
+	service, _ := newTestService(t)
+	executions := []*types.Execution{
+		seedTrade(t, service, "client-1", "AAPL", "BUY", 100, 150, testNow.Add(-time.Hour)),
+		seedTrade(t, service, "client-2", "AAPL", "SELL", 40, 151, testNow.Add(-2*time.Hour)),
+		seedTrade(t, service, "client-1", "AAPL", "BUY", 60, 149, testNow.Add(-3*time.Hour)),
+	}
+	// Trades of other symbols are left alone
+	seedTrade(t, service, "client-1", "MSFT", "BUY", 10, 400, testNow.Add(-time.Hour))
+
+	result, err := service.ClearNettingWindow("aapl", 0)
+	if err != nil {
+		t.Fatalf("ClearNettingWindow: %v", err)
+	}
+	if result.NettingID == "" || result.Cleared != len(executions) || result.Failed != 0 || result.Skipped != 0 {
+		t.Fatalf("result = %+v, want all %d trades cleared under a netting", result, len(executions))
+	}
+
+	for _, execution := range executions {
+		var clearing Clearing
+		if err := service.db.db.Where("trade_id = ?", execution.ExecutionID).First(&clearing).Error; err != nil {
+			t.Fatalf("no clearing for trade %s: %v", execution.ExecutionID, err)
+		}
+		if clearing.ClearingStatus != StatusCleared || clearing.NettingID != result.NettingID {
+			t.Errorf("trade %s clearing = %s under netting %q, want %s under %s",
+				execution.ExecutionID, clearing.ClearingStatus, clearing.NettingID, StatusCleared, result.NettingID)
+		}
+	}
+	var nettings int64
+	if err := service.db.db.Model(&TradeNetting{}).Count(&nettings).Error; err != nil {
+		t.Fatalf("failed to count nettings: %v", err)
+	}
+	if nettings != 1 {
+		t.Errorf("%d netting records, want one shared netting", nettings)
+	}
+
+	// Clearing the window again skips every trade
+	again, err := service.ClearNettingWindow("AAPL", 0)
+	if err != nil {
+		t.Fatalf("ClearNettingWindow: %v", err)
+	}
+	if again.Skipped != len(executions) || again.Cleared != 0 || again.NettingID != "" {
+		t.Errorf("second result = %+v, want every trade skipped", again)
+	}
+}
+
+func TestClearNettingWindowAccumulatesClientMargin(t *testing.T) {
+	config := DefaultConfig()
+	config.MarginCap = 0
+	service, _ := newTestServiceWithConfig(t, config)
+	for i := 0; i < 4; i++ {
+		seedTrade(t, service, "client-1", "AAPL", "BUY", 500, 150, testNow.Add(-time.Duration(i+1)*time.Minute))
+	}
+	// 2,000 concentrated shares take 82.8% of the client's margin, 20.7% for each trade,
+	// so the client's last trade takes it over the 80% limit
+	mockFeed(service).SetQuote("AAPL", 3000, 0.22)
+
+	result, err := service.ClearNettingWindow("AAPL", time.Hour)
+	if err != nil {
+		t.Fatalf("ClearNettingWindow: %v", err)
+	}
+	if result.Cleared != 3 || result.Failed != 1 {
+		t.Fatalf("cleared %d and failed %d, want the fourth trade to fail the utilization limit: %+v",
+			result.Cleared, result.Failed, result.Results)
+	}
+	if failed := result.Results[3]; failed.ClearingStatus != StatusFailed || !strings.Contains(failed.Error, "margin utilization") {
+		t.Errorf("last result = %+v, want a margin utilization failure", failed)
+	}
+}
+
+func TestNettingRejectsUnknownSide(t *testing.T) {
+	service, _ := newTestService(t)
+	seedTrade(t, service, "client-1", "AAPL", "BUY", 100, 150, testNow.Add(-2*time.Hour))
+	// An execution whose order has no side must not be netted as a sell
+	unsided := seedTrade(t, service, "client-1", "AAPL", "", 40, 150, testNow.Add(-time.Hour))
+
+	if _, err := service.ClearTrade(unsided.ExecutionID); !errors.Is(err, ErrUnknownSide) {
+		t.Errorf("ClearTrade error = %v, want ErrUnknownSide", err)
+	}
+	if _, err := service.PreviewNetting("AAPL", 0); !errors.Is(err, ErrUnknownSide) {
+		t.Errorf("PreviewNetting error = %v, want ErrUnknownSide", err)
+	}
+}
+
+func TestFailedClearingOpensTradeBreak(t *testing.T) {
+	service, _ := newTestService(t)
+	// 4,000 @ 150 is above the $500K per-trade position limit
+	execution := seedTrade(t, service, "client-1", "AAPL", "BUY", 4000, 150, testNow.Add(-time.Minute))
+
+	if _, err := service.ClearTrade(execution.ExecutionID); err == nil {
+		t.Fatal("ClearTrade succeeded, want the position limit to fail it")
+	}
+
+	open, err := breaks.NewService(service.db.db).ListOpenBreaks(breaks.StageClearing)
+	if err != nil {
+		t.Fatalf("ListOpenBreaks: %v", err)
+	}
+	if len(open) != 1 || open[0].TradeID != execution.ExecutionID || !strings.Contains(open[0].Reason, "position limit") {
+		t.Errorf("open breaks = %+v, want one clearing break for the trade", open)
+	}
+}
+
+func TestClearTradeHandlerRejectsDeletedReferences(t *testing.T) {
+	service, _ := newTestService(t)
+	router := gin.New()
+	router.POST("/clearing/:trade_id", NewGinHandlers(service).ClearTradeHandler())
+
+	orderDeleted := seedTrade(t, service, "client-1", "AAPL", "BUY", 100, 150, testNow.Add(-time.Minute))
+	if err := service.db.db.Where("order_id = ?", orderDeleted.OrderID).Delete(&types.Order{}).Error; err != nil {
+		t.Fatalf("failed to delete order: %v", err)
+	}
+	executionDeleted := seedTrade(t, service, "client-1", "AAPL", "BUY", 100, 150, testNow.Add(-time.Minute))
+	if err := service.db.db.Where("execution_id = ?", executionDeleted.ExecutionID).Delete(&types.Execution{}).Error; err != nil {
+		t.Fatalf("failed to delete execution: %v", err)
+	}
+
+	tests := []struct {
+		tradeID string
+		want    error
+	}{
+		{orderDeleted.ExecutionID, types.ErrOrderDeleted},
+		{executionDeleted.ExecutionID, types.ErrExecutionDeleted},
+	}
+	for _, tt := range tests {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/clearing/"+tt.tradeID, nil))
+		if recorder.Code != http.StatusConflict || !strings.Contains(recorder.Body.String(), tt.want.Error()) {
+			t.Errorf("status = %d with %s, want 409 reporting %q", recorder.Code, recorder.Body.String(), tt.want)
+		}
+	}
+
+	// A trade that never existed is not reported as deleted
+	if _, err := service.ClearTrade(uuid.New().String()); err == nil || errors.Is(err, types.ErrExecutionDeleted) {
+		t.Errorf("ClearTrade of an unknown trade error = %v, want a lookup failure", err)
+	}
+}
+
+func TestClearTradeExcludesAlreadyClearedTradesFromNetting(t *testing.T) {
+	for _, exclude := range []bool{true, false} {
+		config := DefaultConfig()
+		config.ExcludeClearedFromNetting = exclude
+		service, _ := newTestServiceWithConfig(t, config)
+		first := seedTrade(t, service, "client-1", "AAPL", "BUY", 100, 150, testNow.Add(-2*time.Minute))
+		if _, err := service.ClearTrade(first.ExecutionID); err != nil {
+			t.Fatalf("ClearTrade first: %v", err)
+		}
+
+		second := seedTrade(t, service, "client-1", "AAPL", "BUY", 40, 150, testNow.Add(-time.Minute))
+		cleared, err := service.ClearTrade(second.ExecutionID)
+		if err != nil {
+			t.Fatalf("ClearTrade second: %v", err)
+		}
+
+		// With the exclusion the first trade, already cleared, is not netted a second time
+		wantTrades := 1
+		if !exclude {
+			wantTrades = 2
+		}
+		if cleared.Netting == nil || cleared.Netting.TradesNetted != wantTrades {
+			t.Errorf("exclude %v: netting = %+v, want %d trades netted", exclude, cleared.Netting, wantTrades)
+		}
+	}
+}
+
+func TestClearTradeRejectsNonFiniteMark(t *testing.T) {
+	service, _ := newTestService(t)
+	execution := seedTrade(t, service, "client-1", "AAPL", "BUY", 100, 150, testNow.Add(-time.Minute))
+	mockFeed(service).SetQuote("AAPL", math.NaN(), 0.22)
+
+	if _, err := service.ClearTrade(execution.ExecutionID); !errors.Is(err, money.ErrNonFinite) {
+		t.Fatalf("ClearTrade error = %v, want ErrNonFinite", err)
+	}
+
+	// Nothing non-finite reaches the database
+	var clearings []Clearing
+	if err := service.db.db.Find(&clearings).Error; err != nil {
+		t.Fatalf("failed to load clearings: %v", err)
+	}
+	for _, clearing := range clearings {
+		for _, value := range []float64{clearing.SettlementAmount, clearing.NetPositions, clearing.MarginRequired} {
+			if math.IsNaN(value) || math.IsInf(value, 0) {
+				t.Errorf("clearing %s stored with non-finite amounts: %+v", clearing.ClearingID, clearing)
+			}
+		}
+	}
+	var nettings []TradeNetting
+	if err := service.db.db.Find(&nettings).Error; err != nil {
+		t.Fatalf("failed to load nettings: %v", err)
+	}
+	for _, netting := range nettings {
+		if math.IsNaN(netting.NetMargin) || math.IsInf(netting.NetMargin, 0) {
+			t.Errorf("netting %s stored with margin %v", netting.NettingID, netting.NetMargin)
+		}
+	}
+}
+
+func TestRiskScoreFallsBackOnUnusableVolatility(t *testing.T) {
+	service, _ := newTestService(t)
+	for _, volatility := range []float64{math.NaN(), math.Inf(1), -0.5} {
+		mockFeed(service).SetQuote("AAPL", 150, volatility)
+		clearing := &Clearing{NetPositions: 100, MarginRequired: 1500}
+		order := &types.Order{Symbol: "AAPL", OrderType: "LIMIT", Quantity: 100, Price: 150}
+
+		score, err := service.calculateMockRiskScore(clearing, order)
+		if err != nil {
+			t.Fatalf("volatility %v: calculateMockRiskScore: %v", volatility, err)
+		}
+		if math.IsNaN(score) || score < 0 || score > 1 {
+			t.Errorf("volatility %v: risk score = %v, want a score between 0 and 1", volatility, score)
+		}
+	}
+}
+
+func TestClearTradeOutsideNettingWindowReportsNoTradesToNet(t *testing.T) {
+	service, _ := newTestService(t)
+	// Clock skew puts the execution after the end of the netting window
+	execution := seedTrade(t, service, "client-1", "AAPL", "BUY", 100, 150, testNow.Add(time.Hour))
+
+	_, err := service.ClearTrade(execution.ExecutionID)
+	if !errors.Is(err, ErrNoTradesToNet) {
+		t.Fatalf("ClearTrade error = %v, want ErrNoTradesToNet", err)
+	}
+	if !strings.Contains(err.Error(), execution.ExecutionID) {
+		t.Errorf("error = %q, want it to name the execution", err)
+	}
+
+	var nettings int64
+	service.db.db.Model(&TradeNetting{}).Count(&nettings)
+	if nettings != 0 {
+		t.Errorf("stored %d nettings, want none for an empty window", nettings)
+	}
+}
+
+func TestClearTradeRetryReturnsExistingClearing(t *testing.T) {
+	service, _ := newTestService(t)
+	execution := seedTrade(t, service, "client-1", "AAPL", "BUY", 100, 150, testNow.Add(-time.Minute))
+
+	first, err := service.ClearTrade(execution.ExecutionID)
+	if err != nil {
+		t.Fatalf("ClearTrade: %v", err)
+	}
+	retry, err := service.ClearTrade(execution.ExecutionID)
+	if err != nil {
+		t.Fatalf("retried ClearTrade: %v", err)
+	}
+	if retry.ClearingID != first.ClearingID {
+		t.Errorf("retry returned clearing %s, want the existing %s", retry.ClearingID, first.ClearingID)
+	}
+
+	var count int64
+	if err := service.db.db.Model(&Clearing{}).Where("trade_id = ?", execution.ExecutionID).Count(&count).Error; err != nil {
+		t.Fatalf("failed to count clearings: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("trade has %d clearings after a retry, want 1", count)
+	}
+}
+
+func TestClearTradeRejectsExecutionsNotCompleted(t *testing.T) {
+	service, _ := newTestService(t)
+	router := gin.New()
+	router.POST("/clearing/:trade_id", NewGinHandlers(service).ClearTradeHandler())
+
+	for _, status := range []string{"FAILED", "PENDING"} {
+		trade := seedTrade(t, service, "client-1", "AAPL", "BUY", 100, 150, testNow.Add(-time.Minute))
+		if err := service.db.db.Model(&types.Execution{}).Where("execution_id = ?", trade.ExecutionID).
+			Update("status", status).Error; err != nil {
+			t.Fatalf("failed to mark execution %s: %v", status, err)
+		}
+
+		if _, err := service.ClearTrade(trade.ExecutionID); !errors.Is(err, ErrExecutionNotCompleted) {
+			t.Errorf("ClearTrade of a %s execution error = %v, want ErrExecutionNotCompleted", status, err)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/clearing/"+trade.ExecutionID, nil))
+		if recorder.Code != http.StatusConflict {
+			t.Errorf("clearing a %s execution status = %d, want 409", status, recorder.Code)
+		}
+
+		var clearings int64
+		if err := service.db.db.Model(&Clearing{}).Where("trade_id = ?", trade.ExecutionID).Count(&clearings).Error; err != nil {
+			t.Fatalf("failed to count clearings: %v", err)
+		}
+		if clearings != 0 {
+			t.Errorf("%s execution has %d clearings, want none", status, clearings)
+		}
+	}
+}
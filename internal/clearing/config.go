@@ -0,0 +1,38 @@
+package clearing
+
+// Config holds the tunable margin limits applied by the clearing service
+type Config struct {
+	MarginFloor          float64 // Minimum absolute margin required for any netting
+	GrossMarginFloorRate float64 // Minimum margin as a fraction of gross notional, so offsetting positions still post margin
+	MarginCap            float64 // Maximum margin required for any netting (0 disables the cap)
+	MarginWarnThreshold  float64 // Soft margin utilization above which a clearing succeeds with a warning (0 disables)
+	EODNettingTime       string  // Local time (HH:MM) at which the end-of-day netting runs
+	ClearingHouse        string  // CCP that clears symbols without a specific route
+	// ClearingHouseRoutes maps symbols to the CCP that clears them in multi-CCP setups
+	ClearingHouseRoutes map[string]string
+	// ExcludeClearedFromNetting leaves trades that already have a CLEARED clearing out of
+	// clearing nettings, so each clearing nets only trades that are not yet cleared
+	ExcludeClearedFromNetting bool
+}
+
+// ClearingHouseFor returns the CCP that clears the given symbol
+func (c Config) ClearingHouseFor(symbol string) string {
+	if house, ok := c.ClearingHouseRoutes[symbol]; ok {
+		return house
+	}
+	return c.ClearingHouse
+}
+
+// DefaultConfig returns the clearing configuration used when nothing is overridden
+func DefaultConfig() Config {
+	return Config{
+		MarginFloor:          100,    // $100 minimum margin
+		GrossMarginFloorRate: 0.02,   // 2% of gross notional
+		MarginCap:            500000, // $500K maximum margin
+		MarginWarnThreshold:  0.40,   // Warn at 40%, well below the 80% hard maximum
+		EODNettingTime:       "23:30",
+		ClearingHouse:        "KLEAR-CCP",
+		// Re-netting cleared trades would count their positions again
+		ExcludeClearedFromNetting: true,
+	}
+}
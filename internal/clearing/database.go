@@ -4,7 +4,10 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/ksred/klear-api/internal/database/retry"
+	"github.com/ksred/klear-api/internal/events"
 	"github.com/ksred/klear-api/internal/types"
+	"github.com/ksred/klear-api/pkg/common"
 	"gorm.io/gorm"
 )
 
@@ -29,6 +32,39 @@ func (d *Database) GetClearing(clearingID string) (*Clearing, error) {
 	return &clearing, nil
 }
 
+// GetLatestClearingByTradeID retrieves the most recent clearing attempt for a trade
+func (d *Database) GetLatestClearingByTradeID(tradeID string) (*Clearing, error) {
+	var clearing Clearing
+	if err := d.db.Where("trade_id = ?", tradeID).
+		Order("created_at DESC").
+		First(&clearing).Error; err != nil {
+		return nil, err
+	}
+	return &clearing, nil
+}
+
+// GetClearedClearingByTradeID retrieves the successful clearing for a trade, if it has one
+func (d *Database) GetClearedClearingByTradeID(tradeID string) (*Clearing, error) {
+	var clearing Clearing
+	if err := d.db.Where("trade_id = ? AND clearing_status = ?", tradeID, StatusCleared).
+		Order("created_at DESC").
+		First(&clearing).Error; err != nil {
+		return nil, err
+	}
+	return &clearing, nil
+}
+
+// HasClearingWithStatus reports whether a trade has any clearing in the given status
+func (d *Database) HasClearingWithStatus(tradeID, status string) (bool, error) {
+	var count int64
+	if err := d.db.Model(&Clearing{}).
+		Where("trade_id = ? AND clearing_status = ?", tradeID, status).
+		Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to check clearing status: %w", err)
+	}
+	return count > 0, nil
+}
+
 func (d *Database) UpdateClearing(clearing *Clearing) error {
 	return d.db.Save(clearing).Error
 }
@@ -55,7 +91,7 @@ func (d *Database) UpdateTradeNetting(netting *TradeNetting) error {
 // GetLatestNettingBySymbol retrieves the latest netting record for a symbol
 func (d *Database) GetLatestNettingBySymbol(symbol string) (*TradeNetting, error) {
 	var netting TradeNetting
-	if err := d.db.Where("symbol = ?", symbol).
+	if err := d.db.Where("symbol = ?", common.NormalizeSymbol(symbol)).
 		Order("created_at DESC").
 		First(&netting).Error; err != nil {
 		return nil, fmt.Errorf("failed to fetch latest netting for symbol: %w", err)
@@ -63,19 +99,33 @@ func (d *Database) GetLatestNettingBySymbol(symbol string) (*TradeNetting, error
 	return &netting, nil
 }
 
-// GetNettingsByTimeWindow retrieves all netting records within a time window
-func (d *Database) GetNettingsByTimeWindow(start, end time.Time) ([]TradeNetting, error) {
-	var nettings []TradeNetting
-	if err := d.db.Where("window_start >= ? AND window_end <= ?", start, end).
-		Order("created_at DESC").
+// GetNettingsByTimeWindow retrieves a symbol's netting records whose window ended in [start, end),
+// oldest first, restricted to one netting type when nettingType is not empty
+func (d *Database) GetNettingsByTimeWindow(symbol, nettingType string, start, end time.Time) ([]TradeNetting, error) {
+	nettings := []TradeNetting{}
+	query := d.db.Where("symbol = ? AND window_end >= ? AND window_end < ?", symbol, start, end)
+	if nettingType != "" {
+		query = query.Where("netting_type = ?", nettingType)
+	}
+	if err := query.
+		Order("window_end ASC").
+		Order("created_at ASC").
 		Find(&nettings).Error; err != nil {
 		return nil, fmt.Errorf("failed to fetch nettings for time window: %w", err)
 	}
 	return nettings, nil
 }
 
-// SaveNettingResult saves the netting result in a transaction
-func (d *Database) SaveNettingResult(netting *TradeNetting, clearing *Clearing) error {
+// SaveNettingResult saves the netting result and the clearings it produced in a transaction,
+// recording each cleared trade in the event log
+// The transaction is retried on transient database errors such as lock contention
+func (d *Database) SaveNettingResult(netting *TradeNetting, clearings ...*Clearing) error {
+	return retry.Do("save_netting_result", func() error {
+		return d.saveNettingResult(netting, clearings)
+	})
+}
+
+func (d *Database) saveNettingResult(netting *TradeNetting, clearings []*Clearing) error {
 	// Start transaction
 	tx := d.db.Begin()
 	if err := tx.Error; err != nil {
@@ -93,19 +143,37 @@ func (d *Database) SaveNettingResult(netting *TradeNetting, clearing *Clearing)
 		return fmt.Errorf("failed to save netting record: %w", err)
 	}
 
-	// Update clearing record
-	if err := tx.Save(clearing).Error; err != nil {
-		tx.Rollback()
-		return fmt.Errorf("failed to update clearing record: %w", err)
+	// Update clearing records
+	for _, clearing := range clearings {
+		if err := tx.Save(clearing).Error; err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to update clearing record: %w", err)
+		}
+		if clearing.ClearingStatus != StatusCleared {
+			continue
+		}
+		if err := events.Append(tx, events.EventTradeCleared, clearing.ClearingID, clearing, clearing.UpdatedAt); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to record clearing event: %w", err)
+		}
 	}
 
-	return tx.Commit().Error
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	return nil
 }
 
 // GetExecutionByID retrieves an execution by its ID
 func (d *Database) GetExecutionByID(executionID string) (*types.Execution, error) {
 	var execution types.Execution
-	if err := d.db.Where("execution_id = ?", executionID).First(&execution).Error; err != nil {
+	if err := d.db.Preload("Fills").
+		Where("execution_id = ?", executionID).
+		First(&execution).Error; err != nil {
+		if types.IsSoftDeleted(d.db, &types.Execution{}, "execution_id", executionID, err) {
+			return nil, fmt.Errorf("%w: %s", types.ErrExecutionDeleted, executionID)
+		}
 		return nil, fmt.Errorf("failed to fetch execution: %w", err)
 	}
 	return &execution, nil
@@ -115,23 +183,58 @@ func (d *Database) GetExecutionByID(executionID string) (*types.Execution, error
 func (d *Database) GetOrderByID(orderID string) (*types.Order, error) {
 	var order types.Order
 	if err := d.db.Where("order_id = ?", orderID).First(&order).Error; err != nil {
+		if types.IsSoftDeleted(d.db, &types.Order{}, "order_id", orderID, err) {
+			return nil, fmt.Errorf("%w: %s", types.ErrOrderDeleted, orderID)
+		}
 		return nil, fmt.Errorf("failed to fetch order: %w", err)
 	}
 	return &order, nil
 }
 
-// GetTradesForNetting retrieves all trades within the netting window for a given symbol
-func (d *Database) GetTradesForNetting(symbol string, windowStart time.Time) ([]types.Execution, error) {
+// GetTradesForNetting retrieves all completed trades within the netting window for a given symbol
+// When excludeCleared is set, trades that already have a CLEARED clearing are left out
+func (d *Database) GetTradesForNetting(symbol string, windowStart, windowEnd time.Time, excludeCleared bool) ([]types.Execution, error) {
 	var executions []types.Execution
-	if err := d.db.
+	query := d.db.
 		Joins("JOIN orders ON orders.order_id = executions.order_id").
-		Where("orders.symbol = ? AND executions.created_at > ?", symbol, windowStart).
-		Find(&executions).Error; err != nil {
+		Where("orders.symbol = ? AND executions.created_at > ? AND executions.created_at <= ?",
+			common.NormalizeSymbol(symbol), windowStart, windowEnd).
+		Where("executions.status = ?", executionStatusCompleted)
+	if excludeCleared {
+		query = query.Where("NOT EXISTS (SELECT 1 FROM clearings WHERE clearings.trade_id = executions.execution_id "+
+			"AND clearings.clearing_status = ? AND clearings.deleted_at IS NULL)", StatusCleared)
+	}
+	if err := query.Find(&executions).Error; err != nil {
 		return nil, fmt.Errorf("failed to fetch trades for netting: %w", err)
 	}
 	return executions, nil
 }
 
+// GetTradedSymbols retrieves the distinct symbols with executions in the window
+func (d *Database) GetTradedSymbols(windowStart, windowEnd time.Time) ([]string, error) {
+	var symbols []string
+	if err := d.db.Model(&types.Execution{}).
+		Joins("JOIN orders ON orders.order_id = executions.order_id").
+		Where("executions.created_at > ? AND executions.created_at <= ?", windowStart, windowEnd).
+		Distinct().
+		Order("orders.symbol").
+		Pluck("orders.symbol", &symbols).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch traded symbols: %w", err)
+	}
+	return symbols, nil
+}
+
+// HasNettingForWindow reports whether a netting of the given type already exists for the symbol and window
+func (d *Database) HasNettingForWindow(symbol, nettingType string, windowStart time.Time) (bool, error) {
+	var count int64
+	if err := d.db.Model(&TradeNetting{}).
+		Where("symbol = ? AND netting_type = ? AND window_start = ?", symbol, nettingType, windowStart).
+		Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to check existing netting: %w", err)
+	}
+	return count > 0, nil
+}
+
 // GetOrdersForExecutions retrieves orders for a list of executions
 func (d *Database) GetOrdersForExecutions(executions []types.Execution) (map[string]types.Order, error) {
 	orderMap := make(map[string]types.Order)
@@ -156,12 +259,12 @@ func (d *Database) GetOrdersForExecutions(executions []types.Execution) (map[str
 	return orderMap, nil
 }
 
-// GetDailyNetPosition retrieves the current day's net position for a client
-func (d *Database) GetDailyNetPosition(clientID string) (float64, error) {
+// GetDailyNetPosition retrieves a client's net position for the UTC day containing now
+func (d *Database) GetDailyNetPosition(clientID string, now time.Time) (float64, error) {
 	var netPosition float64
 
 	// Get start of day in UTC
-	now := time.Now().UTC()
+	now = now.UTC()
 	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
 	endOfDay := startOfDay.Add(24 * time.Hour)
 
@@ -188,12 +291,12 @@ func (d *Database) GetDailyNetPosition(clientID string) (float64, error) {
 	return netPosition, nil
 }
 
-// GetDailyTradingVolume retrieves the current day's trading volume for a client
-func (d *Database) GetDailyTradingVolume(clientID string) (float64, error) {
+// GetDailyTradingVolume retrieves a client's trading volume for the UTC day containing now
+func (d *Database) GetDailyTradingVolume(clientID string, now time.Time) (float64, error) {
 	var totalVolume float64
 
 	// Get start of day in UTC
-	now := time.Now().UTC()
+	now = now.UTC()
 	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
 	endOfDay := startOfDay.Add(24 * time.Hour)
 
@@ -214,20 +317,16 @@ func (d *Database) GetDailyTradingVolume(clientID string) (float64, error) {
 	return totalVolume, nil
 }
 
-// GetDailyTradingStats retrieves both net position and volume in a single query for efficiency
-func (d *Database) GetDailyTradingStats(clientID string) (netPosition, tradingVolume float64, err error) {
-	type Result struct {
-		NetPosition   float64
-		TradingVolume float64
-	}
-	var result Result
+// GetDailyTradingStats retrieves net position, volume, order count and fill count for a client on a given day
+func (d *Database) GetDailyTradingStats(clientID string, date time.Time) (*DailyTradingStats, error) {
+	var stats DailyTradingStats
 
-	// Get start of day in UTC
-	now := time.Now().UTC()
-	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	// Get start of the requested day in UTC
+	date = date.UTC()
+	startOfDay := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
 	endOfDay := startOfDay.Add(24 * time.Hour)
 
-	// Combined query to get both stats in one go
+	// Combined query to get position and volume in one go
 	query := `
 		SELECT 
 			COALESCE(SUM(
@@ -245,9 +344,29 @@ func (d *Database) GetDailyTradingStats(clientID string) (netPosition, tradingVo
 		AND executions.created_at < ?
 		AND executions.status = 'COMPLETED'`
 
-	if err := d.db.Raw(query, clientID, startOfDay, endOfDay).Scan(&result).Error; err != nil {
-		return 0, 0, fmt.Errorf("failed to calculate daily trading stats: %w", err)
+	if err := d.db.Raw(query, clientID, startOfDay, endOfDay).Scan(&stats).Error; err != nil {
+		return nil, fmt.Errorf("failed to calculate daily trading stats: %w", err)
 	}
 
-	return result.NetPosition, result.TradingVolume, nil
+	// Count orders placed by the client during the day
+	if err := d.db.Model(&types.Order{}).
+		Where("client_id = ? AND created_at >= ? AND created_at < ?", clientID, startOfDay, endOfDay).
+		Count(&stats.OrderCount).Error; err != nil {
+		return nil, fmt.Errorf("failed to count daily orders: %w", err)
+	}
+
+	// Count exchange fills for the client's executions during the day
+	if err := d.db.Model(&types.ExchangeFill{}).
+		Joins("JOIN executions ON executions.execution_id = exchange_fills.execution_id").
+		Joins("JOIN orders ON orders.order_id = executions.order_id").
+		Where("orders.client_id = ? AND exchange_fills.created_at >= ? AND exchange_fills.created_at < ?",
+			clientID, startOfDay, endOfDay).
+		Count(&stats.FillCount).Error; err != nil {
+		return nil, fmt.Errorf("failed to count daily fills: %w", err)
+	}
+
+	stats.ClientID = clientID
+	stats.Date = startOfDay.Format("2006-01-02")
+
+	return &stats, nil
 }
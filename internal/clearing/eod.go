@@ -0,0 +1,124 @@
+package clearing
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ksred/klear-api/pkg/money"
+	"github.com/rs/zerolog/log"
+)
+
+// Netting types distinguish nettings produced while clearing from end-of-day snapshots
+const (
+	NettingTypeClearing = "CLEARING"
+	NettingTypeEOD      = "EOD"
+)
+
+// RunEODNetting computes and persists an end-of-day netting per symbol traded on the given day
+// Symbols that already have an EOD netting for the day are skipped, so the run can be repeated safely
+// Parameters:
+//   - date: Any time on the trading day to net
+func (s *Service) RunEODNetting(date time.Time) ([]TradeNetting, error) {
+	windowStart := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	windowEnd := windowStart.AddDate(0, 0, 1)
+
+	logger := log.With().
+		Str("service", "clearing").
+		Time("window_start", windowStart).
+		Time("window_end", windowEnd).
+		Logger()
+
+	logger.Info().Msg("starting end-of-day netting")
+
+	symbols, err := s.db.GetTradedSymbols(windowStart, windowEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	nettings := make([]TradeNetting, 0, len(symbols))
+	for _, symbol := range symbols {
+		exists, err := s.db.HasNettingForWindow(symbol, NettingTypeEOD, windowStart)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			logger.Debug().Str("symbol", symbol).Msg("end-of-day netting already recorded, skipping")
+			continue
+		}
+
+		// The end-of-day netting reports the whole day's position, cleared trades included
+		netting, err := s.calculateSymbolNetting(symbol, windowStart, windowEnd, 0, false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to net %s: %w", symbol, err)
+		}
+		netting.NettingType = NettingTypeEOD
+		roundNettingAmounts(netting, money.DefaultCurrency)
+
+		if err := s.db.CreateTradeNetting(netting); err != nil {
+			return nil, fmt.Errorf("failed to save end-of-day netting for %s: %w", symbol, err)
+		}
+		nettings = append(nettings, *netting)
+	}
+
+	logger.Info().
+		Int("symbols_traded", len(symbols)).
+		Int("nettings_created", len(nettings)).
+		Msg("completed end-of-day netting")
+
+	return nettings, nil
+}
+
+// EODProcessor runs the end-of-day netting once a day at a configured time
+type EODProcessor struct {
+	service *Service
+	runAt   time.Duration // Offset from local midnight at which the run is triggered
+}
+
+// NewEODProcessor creates an end-of-day netting processor
+// Parameters:
+//   - service: Clearing service used to compute the nettings
+//   - runAt: Local trigger time in HH:MM format
+func NewEODProcessor(service *Service, runAt string) (*EODProcessor, error) {
+	t, err := time.Parse("15:04", runAt)
+	if err != nil {
+		return nil, fmt.Errorf("invalid end-of-day netting time %q: %w", runAt, err)
+	}
+	return &EODProcessor{
+		service: service,
+		runAt:   time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute,
+	}, nil
+}
+
+// Start waits for each day's trigger time and runs the end-of-day netting until ctx is cancelled
+func (p *EODProcessor) Start(ctx context.Context) {
+	logger := log.With().Str("component", "eod_netting_processor").Logger()
+	logger.Info().Dur("run_at", p.runAt).Msg("starting end-of-day netting processor")
+
+	for {
+		now := p.service.clock.Now()
+		next := p.nextRun(now)
+		timer := time.NewTimer(next.Sub(now))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			logger.Info().Msg("shutting down end-of-day netting processor")
+			return
+		case <-timer.C:
+			if _, err := p.service.RunEODNetting(next); err != nil {
+				logger.Error().Err(err).Msg("end-of-day netting failed")
+			}
+		}
+	}
+}
+
+// nextRun returns the next trigger time after now
+func (p *EODProcessor) nextRun(now time.Time) time.Time {
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	next := midnight.Add(p.runAt)
+	if !next.After(now) {
+		next = midnight.AddDate(0, 0, 1).Add(p.runAt)
+	}
+	return next
+}
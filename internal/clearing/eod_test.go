@@ -0,0 +1,114 @@
+package clearing
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRunEODNettingRecordsOneNettingPerSymbol(t *testing.T) {
+	service, _ := newTestService(t)
+	seedTrade(t, service, "client-1", "AAPL", "BUY", 100, 150, testNow.Add(-2*time.Hour))
+	seedTrade(t, service, "client-2", "AAPL", "SELL", 40, 151, testNow.Add(-time.Hour))
+	seedTrade(t, service, "client-1", "MSFT", "BUY", 10, 300, testNow.Add(-time.Hour))
+	// Yesterday's trade belongs to yesterday's snapshot
+	seedTrade(t, service, "client-1", "GOOGL", "BUY", 5, 100, testNow.AddDate(0, 0, -1))
+
+	nettings, err := service.RunEODNetting(testNow)
+	if err != nil {
+		t.Fatalf("RunEODNetting: %v", err)
+	}
+	bySymbol := make(map[string]TradeNetting)
+	for _, netting := range nettings {
+		if netting.NettingType != NettingTypeEOD {
+			t.Errorf("%s netting type = %s, want %s", netting.Symbol, netting.NettingType, NettingTypeEOD)
+		}
+		bySymbol[netting.Symbol] = netting
+	}
+	if len(nettings) != 2 || len(bySymbol) != 2 {
+		t.Fatalf("nettings = %d for %v, want one each for AAPL and MSFT", len(nettings), bySymbol)
+	}
+	if aapl, ok := bySymbol["AAPL"]; !ok || aapl.NetQuantity != 60 {
+		t.Errorf("AAPL netting = %+v, want a net quantity of 60", aapl)
+	}
+	if _, ok := bySymbol["MSFT"]; !ok {
+		t.Error("no MSFT netting recorded")
+	}
+
+	// Repeating the run for the day adds nothing
+	again, err := service.RunEODNetting(testNow.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("repeated RunEODNetting: %v", err)
+	}
+	if len(again) != 0 {
+		t.Errorf("repeated run created %d nettings, want none", len(again))
+	}
+	var stored int64
+	service.db.db.Model(&TradeNetting{}).Where("netting_type = ?", NettingTypeEOD).Count(&stored)
+	if stored != 2 {
+		t.Errorf("stored %d end-of-day nettings, want 2", stored)
+	}
+}
+
+func TestEODProcessorNextRun(t *testing.T) {
+	service, _ := newTestService(t)
+	processor, err := NewEODProcessor(service, "22:30")
+	if err != nil {
+		t.Fatalf("NewEODProcessor: %v", err)
+	}
+	midnight := time.Date(2026, 10, 14, 0, 0, 0, 0, time.Local)
+
+	tests := []struct {
+		now  time.Time
+		want time.Time
+	}{
+		{midnight.Add(12 * time.Hour), midnight.Add(22*time.Hour + 30*time.Minute)},
+		{midnight.Add(22*time.Hour + 30*time.Minute), midnight.AddDate(0, 0, 1).Add(22*time.Hour + 30*time.Minute)},
+		{midnight.Add(23 * time.Hour), midnight.AddDate(0, 0, 1).Add(22*time.Hour + 30*time.Minute)},
+	}
+	for _, tt := range tests {
+		if got := processor.nextRun(tt.now); !got.Equal(tt.want) {
+			t.Errorf("nextRun(%s) = %s, want %s", tt.now, got, tt.want)
+		}
+	}
+
+	if _, err := NewEODProcessor(service, "25:00"); err == nil {
+		t.Error("NewEODProcessor accepted an invalid time")
+	}
+}
+
+func TestEODProcessorWaitsOnServiceClock(t *testing.T) {
+	service, clock := newTestService(t)
+	seedTrade(t, service, "client-1", "AAPL", "BUY", 100, 150, testNow.Add(-time.Hour))
+	processor, err := NewEODProcessor(service, "22:30")
+	if err != nil {
+		t.Fatalf("NewEODProcessor: %v", err)
+	}
+	// The trigger is moments away on the service clock, however far it is on the wall clock
+	midnight := time.Date(testNow.Year(), testNow.Month(), testNow.Day(), 0, 0, 0, 0, testNow.Location())
+	clock.Set(midnight.Add(22*time.Hour + 30*time.Minute - 20*time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		processor.Start(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		var stored int64
+		service.db.db.Model(&TradeNetting{}).Where("netting_type = ?", NettingTypeEOD).Count(&stored)
+		if stored == 1 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("stored %d end-of-day nettings, want the run triggered on the service clock", stored)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
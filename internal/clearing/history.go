@@ -0,0 +1,120 @@
+package clearing
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/klear-api/pkg/common"
+	"github.com/ksred/klear-api/pkg/money"
+	"github.com/ksred/klear-api/pkg/response"
+)
+
+var (
+	ErrInvalidHistoryWindow = errors.New("end must be after start")
+	ErrInvalidNettingType   = errors.New("netting type must be CLEARING or EOD")
+)
+
+// NettingHistory is the series of a symbol's netting records over a period, oldest first,
+// with aggregates describing how its net position and margin moved
+type NettingHistory struct {
+	Symbol           string         `json:"symbol"`
+	NettingType      string         `json:"netting_type,omitempty"` // Empty when both types are included
+	Start            time.Time      `json:"start"`
+	End              time.Time      `json:"end"`
+	Count            int            `json:"count"`
+	MinNetQuantity   float64        `json:"min_net_quantity"`
+	MaxNetQuantity   float64        `json:"max_net_quantity"`
+	AverageNetMargin float64        `json:"average_net_margin"`
+	PeakNetMargin    float64        `json:"peak_net_margin"`
+	Nettings         []TradeNetting `json:"nettings"`
+}
+
+// GetNettingHistory returns the nettings of a symbol whose window ended in [start, end), in
+// chronological order, with the range of net quantities and the average and peak margin
+// Parameters:
+//   - symbol: Symbol to report on
+//   - nettingType: CLEARING or EOD to include only that type; empty includes both
+//   - start: Start of the period, inclusive
+//   - end: End of the period, exclusive
+func (s *Service) GetNettingHistory(symbol, nettingType string, start, end time.Time) (*NettingHistory, error) {
+	symbol = common.NormalizeSymbol(symbol)
+	if symbol == "" {
+		return nil, ErrSymbolRequired
+	}
+	nettingType = strings.ToUpper(strings.TrimSpace(nettingType))
+	if nettingType != "" && nettingType != NettingTypeClearing && nettingType != NettingTypeEOD {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidNettingType, nettingType)
+	}
+	if !end.After(start) {
+		return nil, ErrInvalidHistoryWindow
+	}
+
+	nettings, err := s.db.GetNettingsByTimeWindow(symbol, nettingType, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	history := &NettingHistory{
+		Symbol:      symbol,
+		NettingType: nettingType,
+		Start:       start,
+		End:         end,
+		Count:       len(nettings),
+		Nettings:    nettings,
+	}
+	if len(nettings) == 0 {
+		return history, nil
+	}
+
+	history.MinNetQuantity = math.Inf(1)
+	history.MaxNetQuantity = math.Inf(-1)
+	var totalMargin float64
+	for _, netting := range nettings {
+		history.MinNetQuantity = math.Min(history.MinNetQuantity, netting.NetQuantity)
+		history.MaxNetQuantity = math.Max(history.MaxNetQuantity, netting.NetQuantity)
+		history.PeakNetMargin = math.Max(history.PeakNetMargin, netting.NetMargin)
+		totalMargin += netting.NetMargin
+	}
+	history.AverageNetMargin = money.Round(totalMargin/float64(len(nettings)), money.DefaultCurrency)
+	return history, nil
+}
+
+// GetNettingHistoryHandler handles GET requests for a symbol's netting history
+// Requires internal authentication
+// Query parameters: symbol (required), start and end (RFC 3339, default to the current UTC day),
+// netting_type (CLEARING or EOD, optional)
+func (h *GinHandlers) GetNettingHistoryHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		now := h.service.clock.Now().UTC()
+		start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		if startParam := c.Query("start"); startParam != "" {
+			parsed, err := time.Parse(time.RFC3339, startParam)
+			if err != nil {
+				response.BadRequest(c, "Invalid start format, expected RFC 3339")
+				return
+			}
+			start = parsed
+		}
+
+		end := start.Add(24 * time.Hour)
+		if endParam := c.Query("end"); endParam != "" {
+			parsed, err := time.Parse(time.RFC3339, endParam)
+			if err != nil {
+				response.BadRequest(c, "Invalid end format, expected RFC 3339")
+				return
+			}
+			end = parsed
+		}
+
+		history, err := h.service.GetNettingHistory(c.Query("symbol"), c.Query("netting_type"), start, end)
+		if errors.Is(err, ErrSymbolRequired) || errors.Is(err, ErrInvalidNettingType) || errors.Is(err, ErrInvalidHistoryWindow) {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		response.Handle(c, history, err)
+	}
+}
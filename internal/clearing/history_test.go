@@ -0,0 +1,96 @@
+package clearing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// seedNetting records a completed netting of the symbol for the window ending at windowEnd
+func seedNetting(t *testing.T, service *Service, symbol, nettingType string, windowEnd time.Time, netQuantity, netMargin float64) *TradeNetting {
+	t.Helper()
+	netting := &TradeNetting{
+		NettingID:      "NET_" + uuid.New().String(),
+		Symbol:         symbol,
+		WindowStart:    windowEnd.Add(-time.Hour),
+		WindowEnd:      windowEnd,
+		NetQuantity:    netQuantity,
+		NetAmount:      netQuantity * 150,
+		NetMargin:      netMargin,
+		Status:         "COMPLETED",
+		NettingType:    nettingType,
+		OriginalTrades: "[]",
+		CreatedAt:      windowEnd,
+		UpdatedAt:      windowEnd,
+	}
+	if err := service.db.db.Create(netting).Error; err != nil {
+		t.Fatalf("failed to seed netting: %v", err)
+	}
+	return netting
+}
+
+func TestNettingHistoryListsSymbolNettingsInOrder(t *testing.T) {
+	service, _ := newTestService(t)
+	day := time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC)
+
+	// Seeded out of order, so the ordering comes from the query
+	third := seedNetting(t, service, "AAPL", NettingTypeEOD, day.Add(17*time.Hour), 40, 600)
+	first := seedNetting(t, service, "AAPL", NettingTypeClearing, day.Add(10*time.Hour), 100, 1500)
+	second := seedNetting(t, service, "AAPL", NettingTypeClearing, day.Add(13*time.Hour), -20, 300)
+	// Another symbol's netting and ones outside the period are left out
+	seedNetting(t, service, "MSFT", NettingTypeClearing, day.Add(11*time.Hour), 500, 9000)
+	seedNetting(t, service, "AAPL", NettingTypeClearing, day.Add(-time.Hour), 900, 9000)
+	seedNetting(t, service, "AAPL", NettingTypeClearing, day.Add(24*time.Hour), 900, 9000)
+
+	router := gin.New()
+	router.GET("/netting/history", NewGinHandlers(service).GetNettingHistoryHandler())
+	get := func(query string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/netting/history?"+query, nil))
+		return recorder
+	}
+
+	recorder := get("symbol=aapl&start=2026-10-14T00:00:00Z&end=2026-10-15T00:00:00Z")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", recorder.Code, recorder.Body.String())
+	}
+	var history NettingHistory
+	decodeData(t, recorder, &history)
+
+	want := []*TradeNetting{first, second, third}
+	if history.Symbol != "AAPL" || history.Count != len(want) || len(history.Nettings) != len(want) {
+		t.Fatalf("history = %s with %d nettings, want AAPL with %d", history.Symbol, len(history.Nettings), len(want))
+	}
+	for i, netting := range history.Nettings {
+		if netting.NettingID != want[i].NettingID {
+			t.Errorf("netting %d ended %v, want the one ending %v", i, netting.WindowEnd, want[i].WindowEnd)
+		}
+	}
+	if history.MinNetQuantity != -20 || history.MaxNetQuantity != 100 {
+		t.Errorf("net quantity range = %v to %v, want -20 to 100", history.MinNetQuantity, history.MaxNetQuantity)
+	}
+	if history.AverageNetMargin != 800 || history.PeakNetMargin != 1500 {
+		t.Errorf("net margin = %v average, %v peak; want 800 and 1500", history.AverageNetMargin, history.PeakNetMargin)
+	}
+
+	// Restricting to one netting type drops the end-of-day snapshot
+	decodeData(t, get("symbol=AAPL&netting_type=clearing&start=2026-10-14T00:00:00Z&end=2026-10-15T00:00:00Z"), &history)
+	if history.Count != 2 || history.NettingType != NettingTypeClearing {
+		t.Errorf("clearing history = %d %s nettings, want 2 CLEARING", history.Count, history.NettingType)
+	}
+
+	for _, query := range []string{
+		"start=2026-10-14T00:00:00Z",
+		"symbol=AAPL&netting_type=INTRADAY",
+		"symbol=AAPL&start=2026-10-15T00:00:00Z&end=2026-10-14T00:00:00Z",
+		"symbol=AAPL&start=yesterday",
+	} {
+		if recorder := get(query); recorder.Code != http.StatusBadRequest {
+			t.Errorf("%s status = %d, want 400", query, recorder.Code)
+		}
+	}
+}
@@ -3,42 +3,95 @@ package clearing
 import (
 	"time"
 
+	"github.com/ksred/klear-api/pkg/money"
 	"gorm.io/gorm"
 )
 
 type Clearing struct {
-	gorm.Model       `json:"-"`
-	ClearingID       string    `gorm:"uniqueIndex" json:"clearing_id"`
-	TradeID          string    `json:"trade_id"`
-	ClearingStatus   string    `json:"clearing_status"` // PENDING, CLEARED, FAILED
-	MarginRequired   float64   `json:"margin_required"`
-	NetPositions     float64   `json:"net_positions"`
-	SettlementAmount float64   `json:"settlement_amount"`
-	CreatedAt        time.Time `json:"created_at"`
-	UpdatedAt        time.Time `json:"updated_at"`
+	gorm.Model            `json:"-"`
+	ClearingID            string    `gorm:"uniqueIndex" json:"clearing_id"`
+	TradeID               string    `json:"trade_id"`
+	NettingID             string    `gorm:"index" json:"netting_id,omitempty"`
+	ClearingHouse         string    `gorm:"index" json:"clearing_house"` // CCP that processed the clearing
+	ClearingStatus        string    `json:"clearing_status"`             // PENDING, CLEARED, FAILED, REVERSED
+	MarginRequired        float64   `json:"margin_required"`
+	NetPositions          float64   `json:"net_positions"`
+	SettlementAmount      float64   `json:"settlement_amount"`
+	SettlementAmountUnits int64     `json:"-"` // Settlement amount as exact integer units of 10^-8
+	CreatedAt             time.Time `json:"created_at"`
+	UpdatedAt             time.Time `json:"updated_at"`
+}
+
+// BeforeSave stores the settlement amount as exact scaled integers alongside the float column
+func (c *Clearing) BeforeSave(tx *gorm.DB) error {
+	c.SettlementAmountUnits = money.ToUnits(c.SettlementAmount)
+	return nil
+}
+
+// AfterFind restores the settlement amount from its exact scaled integers
+// Rows saved before the integer column existed keep their float value
+func (c *Clearing) AfterFind(tx *gorm.DB) error {
+	if c.SettlementAmountUnits != 0 {
+		c.SettlementAmount = money.FromUnits(c.SettlementAmountUnits)
+	}
+	return nil
 }
 
 type ClearingResponse struct {
-	ClearingID       string    `json:"clearing_id"`
-	ClearingStatus   string    `json:"clearing_status"`
-	MarginRequired   float64   `json:"margin_required"`
-	NetPositions     float64   `json:"net_positions"`
-	SettlementAmount float64   `json:"settlement_amount"`
-	Timestamp        time.Time `json:"timestamp"`
+	ClearingID       string          `json:"clearing_id"`
+	ClearingStatus   string          `json:"clearing_status"`
+	ClearingHouse    string          `json:"clearing_house"`
+	MarginRequired   float64         `json:"margin_required"`
+	NetPositions     float64         `json:"net_positions"`
+	SettlementAmount float64         `json:"settlement_amount"`
+	NettingID        string          `json:"netting_id,omitempty"`
+	Netting          *NettingSummary `json:"netting,omitempty"`
+	Fills            []FillSummary   `json:"fills"`
+	Warnings         []string        `json:"warnings,omitempty"` // Non-fatal risk alerts, e.g. margin utilization above the soft threshold
+	Timestamp        time.Time       `json:"timestamp"`
+}
+
+// FillSummary describes a venue fill that contributed to the cleared execution
+// Fills crossed internally against another client's order are on the INTERNAL venue
+type FillSummary struct {
+	FillID     string  `json:"fill_id"`
+	ExchangeID string  `json:"exchange_id"`
+	Quantity   float64 `json:"quantity"`
+	Price      float64 `json:"price"`
+	FeeRate    float64 `json:"fee_rate"`
+	FeeAmount  float64 `json:"fee_amount"`
+}
+
+// NettingSummary describes the netting run that produced a clearing's margin
+type NettingSummary struct {
+	TradesNetted int       `json:"trades_netted"`
+	WindowStart  time.Time `json:"window_start"`
+	WindowEnd    time.Time `json:"window_end"`
 }
 
 type TradeNetting struct {
-	gorm.Model      `json:"-"`
-	NettingID       string    `gorm:"uniqueIndex" json:"netting_id"`
-	Symbol          string    `json:"symbol"`
-	WindowStart     time.Time `json:"window_start"`
-	WindowEnd       time.Time `json:"window_end"`
-	NetQuantity     float64   `json:"net_quantity"`
-	NetAmount       float64   `json:"net_amount"`
-	NetSettlement   float64   `json:"net_settlement"`
-	NetMargin       float64   `json:"net_margin"`
-	Status          string    `json:"status"` // PENDING, COMPLETED, FAILED
-	OriginalTrades  string    `json:"original_trades"` // JSON array of trade IDs
-	CreatedAt       time.Time `json:"created_at"`
-	UpdatedAt       time.Time `json:"updated_at"`
+	gorm.Model     `json:"-"`
+	NettingID      string    `gorm:"uniqueIndex" json:"netting_id"`
+	Symbol         string    `json:"symbol"`
+	WindowStart    time.Time `json:"window_start"`
+	WindowEnd      time.Time `json:"window_end"`
+	NetQuantity    float64   `json:"net_quantity"`
+	NetAmount      float64   `json:"net_amount"`
+	NetSettlement  float64   `json:"net_settlement"`
+	NetMargin      float64   `json:"net_margin"`
+	Status         string    `json:"status"`                    // PENDING, COMPLETED, FAILED
+	NettingType    string    `gorm:"index" json:"netting_type"` // CLEARING or EOD
+	OriginalTrades string    `json:"original_trades"`           // JSON array of trade IDs
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// DailyTradingStats summarises a client's trading activity for a single day
+type DailyTradingStats struct {
+	ClientID      string  `json:"client_id"`
+	Date          string  `json:"date"`
+	NetPosition   float64 `json:"net_position"`
+	TradingVolume float64 `json:"trading_volume"`
+	OrderCount    int64   `json:"order_count"`
+	FillCount     int64   `json:"fill_count"`
 }
@@ -0,0 +1,138 @@
+package clearing
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/klear-api/pkg/common"
+	"github.com/ksred/klear-api/pkg/money"
+	"github.com/ksred/klear-api/pkg/response"
+)
+
+// defaultNettingWindow is the lookback used by trade clearing, and by previews without an explicit window
+const defaultNettingWindow = 24 * time.Hour
+
+var (
+	ErrSymbolRequired       = errors.New("symbol is required")
+	ErrInvalidNettingWindow = errors.New("netting window must be positive")
+)
+
+// NettingCandidate is an execution that would be included in a netting run
+type NettingCandidate struct {
+	ExecutionID string    `json:"execution_id"`
+	OrderID     string    `json:"order_id"`
+	ClientID    string    `json:"client_id"`
+	Side        string    `json:"side"`
+	Quantity    float64   `json:"quantity"`
+	Price       float64   `json:"price"`
+	NetQuantity float64   `json:"net_quantity"` // Signed contribution to the net position: positive for buys
+	NetAmount   float64   `json:"net_amount"`   // Signed contribution to the net amount
+	ExecutedAt  time.Time `json:"executed_at"`
+}
+
+// NettingPreview lists the trades a netting run for a symbol would include, without running it
+type NettingPreview struct {
+	Symbol      string             `json:"symbol"`
+	WindowStart time.Time          `json:"window_start"`
+	WindowEnd   time.Time          `json:"window_end"`
+	NetQuantity float64            `json:"net_quantity"`
+	NetAmount   float64            `json:"net_amount"`
+	Trades      []NettingCandidate `json:"trades"`
+}
+
+// PreviewNetting returns the executions in a symbol's netting window with their net contributions
+// No netting record is created
+// Parameters:
+//   - symbol: Symbol to preview
+//   - window: Lookback from now; zero uses the clearing netting window
+func (s *Service) PreviewNetting(symbol string, window time.Duration) (*NettingPreview, error) {
+	symbol = common.NormalizeSymbol(symbol)
+	if symbol == "" {
+		return nil, ErrSymbolRequired
+	}
+	if window == 0 {
+		window = defaultNettingWindow
+	}
+	if window < 0 {
+		return nil, ErrInvalidNettingWindow
+	}
+
+	windowEnd := s.clock.Now()
+	windowStart := windowEnd.Add(-window)
+
+	executions, err := s.db.GetTradesForNetting(symbol, windowStart, windowEnd, s.config.ExcludeClearedFromNetting)
+	if err != nil {
+		return nil, err
+	}
+	orderMap, err := s.db.GetOrdersForExecutions(executions)
+	if err != nil {
+		return nil, err
+	}
+
+	preview := &NettingPreview{
+		Symbol:      symbol,
+		WindowStart: windowStart,
+		WindowEnd:   windowEnd,
+		Trades:      make([]NettingCandidate, 0, len(executions)),
+	}
+	for _, exec := range executions {
+		ord, exists := orderMap[exec.OrderID]
+		if !exists {
+			return nil, fmt.Errorf("order not found for execution %s", exec.ExecutionID)
+		}
+
+		netQuantity := exec.TotalQuantity
+		netAmount := money.Notional(exec.AveragePrice, exec.TotalQuantity, money.DefaultCurrency)
+		switch ord.Side {
+		case "BUY":
+			// Buys add to the net position as they are
+		case "SELL":
+			netQuantity = -netQuantity
+			netAmount = -netAmount
+		default:
+			return nil, fmt.Errorf("%w %q on order %s", ErrUnknownSide, ord.Side, exec.OrderID)
+		}
+
+		preview.NetQuantity += netQuantity
+		preview.NetAmount = money.Sum(money.DefaultCurrency, preview.NetAmount, netAmount)
+		preview.Trades = append(preview.Trades, NettingCandidate{
+			ExecutionID: exec.ExecutionID,
+			OrderID:     exec.OrderID,
+			ClientID:    ord.ClientID,
+			Side:        ord.Side,
+			Quantity:    exec.TotalQuantity,
+			Price:       exec.AveragePrice,
+			NetQuantity: netQuantity,
+			NetAmount:   netAmount,
+			ExecutedAt:  exec.CreatedAt,
+		})
+	}
+
+	return preview, nil
+}
+
+// PreviewNettingHandler handles GET requests previewing the trades in a symbol's netting window
+// Requires internal authentication
+// Query parameters: symbol (required), window (duration such as "4h", defaults to 24h)
+func (h *GinHandlers) PreviewNettingHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var window time.Duration
+		if windowParam := c.Query("window"); windowParam != "" {
+			parsed, err := time.ParseDuration(windowParam)
+			if err != nil {
+				response.BadRequest(c, "Invalid window format, expected a duration such as 24h")
+				return
+			}
+			window = parsed
+		}
+
+		preview, err := h.service.PreviewNetting(c.Query("symbol"), window)
+		if errors.Is(err, ErrSymbolRequired) || errors.Is(err, ErrInvalidNettingWindow) {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		response.Handle(c, preview, err)
+	}
+}
@@ -0,0 +1,508 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ksred/klear-api/internal/clearing"
+	"github.com/ksred/klear-api/internal/database"
+	"github.com/ksred/klear-api/internal/exchange"
+	"github.com/ksred/klear-api/internal/settlement"
+	"github.com/ksred/klear-api/internal/trading"
+	"github.com/ksred/klear-api/pkg/common"
+	"github.com/ksred/klear-api/pkg/middleware"
+	"github.com/ksred/klear-api/pkg/money"
+	"golang.org/x/time/rate"
+)
+
+// Config holds the application configuration loaded from the environment
+type Config struct {
+	Env            string          // Deployment environment; "production" enables the strictest checks
+	Port           string          // Port the HTTP server listens on
+	JWTSecret      string          // Key used to sign client JWTs
+	DatabaseDSN    string          // Data source of the application database
+	RequestTimeout time.Duration   // Maximum time allowed to serve an API request
+	PageSize       common.PageSize // Page bounds shared by every listing: orders, fills, settlements and events
+	Trading        trading.Config
+	Clearing       clearing.Config
+	Settlement     settlement.Config
+	RateLimits     middleware.RateLimitConfig
+	// TrustedProxies lists the proxy IPs and CIDRs whose X-Forwarded-For header is believed when
+	// resolving the client IP; requests from any other peer are keyed on the peer address
+	TrustedProxies []string
+	Exchanges      []*exchange.Exchange // Venue set loaded from EXCHANGES_FILE; nil keeps the built-in venues
+	// InstantFills makes every venue fill immediately and in full at the order price, with no
+	// simulated latency or failures, so load tests measure the API rather than the simulation
+	InstantFills bool
+	// SettlementProcessInterval is the time between runs of the pending settlement processor
+	SettlementProcessInterval time.Duration
+	// OrderExpiryInterval is the time between sweeps for expired GTD orders
+	OrderExpiryInterval time.Duration
+	// DegradedMode turns writes away with 503 while the database health check finds it unreachable
+	DegradedMode bool
+	// DBHealthCheckInterval is the time between database health checks
+	DBHealthCheckInterval time.Duration
+	// HTTP server connection timeouts, bounding slow or idle clients
+	ReadHeaderTimeout time.Duration // Time allowed to read request headers
+	ReadTimeout       time.Duration // Time allowed to read the whole request, body included
+	WriteTimeout      time.Duration // Time allowed to write the response; must exceed RequestTimeout
+	IdleTimeout       time.Duration // Time a keep-alive connection may wait for its next request
+	// OperatorAPIKey and OperatorAPISecret are the credentials of the operator API key, whose
+	// tokens carry the admin permission the admin routes require. Unset, no token can reach them
+	OperatorAPIKey    string
+	OperatorAPISecret string
+}
+
+// developmentJWTSecret signs tokens outside production when JWT_SECRET is unset
+// It is public, so production deployments must configure their own secret
+const developmentJWTSecret = "klear-secret-key"
+
+// ValidationError lists every problem found while loading the configuration,
+// so they can all be fixed before the next start
+type ValidationError struct {
+	Problems []error
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Problems))
+	for i, problem := range e.Problems {
+		messages[i] = problem.Error()
+	}
+	return "invalid configuration: " + strings.Join(messages, "; ")
+}
+
+// Unwrap returns the individual problems so callers can match them with errors.Is
+func (e *ValidationError) Unwrap() []error {
+	return e.Problems
+}
+
+// Load reads the application configuration from environment variables,
+// falling back to the defaults for any value that is not set
+// Every setting is read and validated before returning, and all problems found are reported
+// together in a *ValidationError
+func Load() (*Config, error) {
+	cfg := &Config{
+		Env:            "development",
+		Port:           "8080",
+		DatabaseDSN:    database.DefaultDSN,
+		RequestTimeout: 30 * time.Second,
+		PageSize:       common.DefaultPageSize(),
+		Trading:        trading.DefaultConfig(),
+		Clearing:       clearing.DefaultConfig(),
+		Settlement:     settlement.DefaultConfig(),
+		RateLimits:     middleware.DefaultRateLimitConfig(),
+		// Settlements are processed in batches; expired orders should leave the book promptly
+		SettlementProcessInterval: 5 * time.Minute,
+		OrderExpiryInterval:       time.Minute,
+		DegradedMode:              true,
+		DBHealthCheckInterval:     10 * time.Second,
+		ReadHeaderTimeout:         5 * time.Second,
+		ReadTimeout:               15 * time.Second,
+		WriteTimeout:              35 * time.Second, // Leaves room to write the 504 of a timed-out request
+		IdleTimeout:               2 * time.Minute,
+	}
+
+	var problems []error
+	var err error
+	if env := strings.TrimSpace(os.Getenv("ENV")); env != "" {
+		cfg.Env = env
+	}
+	if port := strings.TrimSpace(os.Getenv("PORT")); port != "" {
+		cfg.Port = port
+	}
+	cfg.JWTSecret = os.Getenv("JWT_SECRET")
+	cfg.OperatorAPIKey = strings.TrimSpace(os.Getenv("OPERATOR_API_KEY"))
+	cfg.OperatorAPISecret = os.Getenv("OPERATOR_API_SECRET")
+	if dsn, ok := os.LookupEnv("DATABASE_DSN"); ok {
+		cfg.DatabaseDSN = strings.TrimSpace(dsn)
+	}
+	if cfg.SettlementProcessInterval, err = getEnvDuration("SETTLEMENT_PROCESS_INTERVAL", cfg.SettlementProcessInterval); err != nil {
+		problems = append(problems, err)
+	}
+	if cfg.OrderExpiryInterval, err = getEnvDuration("ORDER_EXPIRY_INTERVAL", cfg.OrderExpiryInterval); err != nil {
+		problems = append(problems, err)
+	}
+	if cfg.DegradedMode, err = getEnvBool("DB_DEGRADED_MODE", cfg.DegradedMode); err != nil {
+		problems = append(problems, err)
+	}
+	if cfg.DBHealthCheckInterval, err = getEnvDuration("DB_HEALTH_CHECK_INTERVAL", cfg.DBHealthCheckInterval); err != nil {
+		problems = append(problems, err)
+	}
+	if cfg.ReadHeaderTimeout, err = getEnvDuration("HTTP_READ_HEADER_TIMEOUT", cfg.ReadHeaderTimeout); err != nil {
+		problems = append(problems, err)
+	}
+	if cfg.ReadTimeout, err = getEnvDuration("HTTP_READ_TIMEOUT", cfg.ReadTimeout); err != nil {
+		problems = append(problems, err)
+	}
+	if cfg.WriteTimeout, err = getEnvDuration("HTTP_WRITE_TIMEOUT", cfg.WriteTimeout); err != nil {
+		problems = append(problems, err)
+	}
+	if cfg.IdleTimeout, err = getEnvDuration("HTTP_IDLE_TIMEOUT", cfg.IdleTimeout); err != nil {
+		problems = append(problems, err)
+	}
+	if cfg.RequestTimeout, err = getEnvDuration("REQUEST_TIMEOUT", cfg.RequestTimeout); err != nil {
+		problems = append(problems, err)
+	}
+	if cfg.Trading.MaxOrderQuantity, err = getEnvFloat("MAX_ORDER_QUANTITY", cfg.Trading.MaxOrderQuantity); err != nil {
+		problems = append(problems, err)
+	}
+	if cfg.Trading.MaxOrderPrice, err = getEnvFloat("MAX_ORDER_PRICE", cfg.Trading.MaxOrderPrice); err != nil {
+		problems = append(problems, err)
+	}
+	if cfg.Trading.MaxOrderNotional, err = getEnvFloat("MAX_ORDER_NOTIONAL", cfg.Trading.MaxOrderNotional); err != nil {
+		problems = append(problems, err)
+	}
+	if cfg.Trading.MaxOpenOrders, err = getEnvInt("MAX_OPEN_ORDERS", cfg.Trading.MaxOpenOrders); err != nil {
+		problems = append(problems, err)
+	}
+	if cfg.Trading.MaxGTDHorizon, err = getEnvDuration("MAX_GTD_HORIZON", cfg.Trading.MaxGTDHorizon); err != nil {
+		problems = append(problems, err)
+	}
+	if cfg.Trading.MaxMarketSlippage, err = getEnvFloat("MAX_MARKET_SLIPPAGE", cfg.Trading.MaxMarketSlippage); err != nil {
+		problems = append(problems, err)
+	}
+	if cfg.Trading.PriceBand, err = getEnvFloat("PRICE_BAND", cfg.Trading.PriceBand); err != nil {
+		problems = append(problems, err)
+	}
+
+	if cfg.Clearing.MarginFloor, err = getEnvFloat("MARGIN_FLOOR", cfg.Clearing.MarginFloor); err != nil {
+		problems = append(problems, err)
+	}
+	if cfg.Clearing.GrossMarginFloorRate, err = getEnvFloat("GROSS_MARGIN_FLOOR_RATE", cfg.Clearing.GrossMarginFloorRate); err != nil {
+		problems = append(problems, err)
+	}
+	if cfg.Clearing.MarginCap, err = getEnvFloat("MARGIN_CAP", cfg.Clearing.MarginCap); err != nil {
+		problems = append(problems, err)
+	}
+	if cfg.Clearing.MarginWarnThreshold, err = getEnvFloat("MARGIN_UTILIZATION_WARN_THRESHOLD", cfg.Clearing.MarginWarnThreshold); err != nil {
+		problems = append(problems, err)
+	}
+	if house := strings.TrimSpace(os.Getenv("CLEARING_HOUSE")); house != "" {
+		cfg.Clearing.ClearingHouse = house
+	}
+	if cfg.Clearing.ClearingHouseRoutes, err = getEnvRoutes("CLEARING_HOUSE_ROUTES"); err != nil {
+		problems = append(problems, err)
+	}
+	if eodTime := os.Getenv("EOD_NETTING_TIME"); eodTime != "" {
+		if _, err := time.Parse("15:04", eodTime); err != nil {
+			problems = append(problems, fmt.Errorf("invalid value for EOD_NETTING_TIME: %w", err))
+		} else {
+			cfg.Clearing.EODNettingTime = eodTime
+		}
+	}
+	if cfg.Clearing.ExcludeClearedFromNetting, err = getEnvBool("NETTING_EXCLUDE_CLEARED", cfg.Clearing.ExcludeClearedFromNetting); err != nil {
+		problems = append(problems, err)
+	}
+	if currency := os.Getenv("DEFAULT_CURRENCY"); currency != "" {
+		currency = money.NormalizeCurrency(currency)
+		if !money.IsValidCurrency(currency) {
+			problems = append(problems, fmt.Errorf("invalid value for DEFAULT_CURRENCY: %s is not an ISO 4217 code", currency))
+		} else {
+			cfg.Trading.DefaultCurrency = currency
+			cfg.Settlement.DefaultCurrency = currency
+		}
+	}
+	if cfg.Settlement.MinSettlementAmount, err = getEnvFloat("MIN_SETTLEMENT_AMOUNT", cfg.Settlement.MinSettlementAmount); err != nil {
+		problems = append(problems, err)
+	}
+	if cfg.Settlement.BatchSettlements, err = getEnvBool("SETTLEMENT_BATCHING", cfg.Settlement.BatchSettlements); err != nil {
+		problems = append(problems, err)
+	}
+	if cfg.Settlement.AllowPrivateWebhooks, err = getEnvBool("WEBHOOK_ALLOW_PRIVATE_TARGETS", cfg.Settlement.AllowPrivateWebhooks); err != nil {
+		problems = append(problems, err)
+	}
+	if cfg.Settlement.WebhookRetry.MaxAttempts, err = getEnvInt("WEBHOOK_MAX_ATTEMPTS", cfg.Settlement.WebhookRetry.MaxAttempts); err != nil {
+		problems = append(problems, err)
+	}
+	if cfg.Settlement.WebhookRetry.BaseBackoff, err = getEnvDuration("WEBHOOK_RETRY_BACKOFF", cfg.Settlement.WebhookRetry.BaseBackoff); err != nil {
+		problems = append(problems, err)
+	}
+	if cfg.Settlement.WebhookRetry.MaxBackoff, err = getEnvDuration("WEBHOOK_MAX_BACKOFF", cfg.Settlement.WebhookRetry.MaxBackoff); err != nil {
+		problems = append(problems, err)
+	}
+	if cfg.Trading.EnforceExecutionOwnership, err = getEnvBool("ENFORCE_EXECUTION_OWNERSHIP", cfg.Trading.EnforceExecutionOwnership); err != nil {
+		problems = append(problems, err)
+	}
+	if cfg.Trading.DefaultLotSize, err = getEnvFloat("DEFAULT_LOT_SIZE", cfg.Trading.DefaultLotSize); err != nil {
+		problems = append(problems, err)
+	}
+	if cfg.Trading.LotSizes, err = getEnvSymbolSizes("LOT_SIZES", "lot size"); err != nil {
+		problems = append(problems, err)
+	}
+	if cfg.Trading.DefaultTickSize, err = getEnvFloat("DEFAULT_TICK_SIZE", cfg.Trading.DefaultTickSize); err != nil {
+		problems = append(problems, err)
+	}
+	if cfg.Trading.TickSizes, err = getEnvSymbolSizes("TICK_SIZES", "tick size"); err != nil {
+		problems = append(problems, err)
+	}
+	if cfg.Trading.RecordFailedAttempts, err = getEnvBool("RECORD_FAILED_VENUE_ATTEMPTS", cfg.Trading.RecordFailedAttempts); err != nil {
+		problems = append(problems, err)
+	}
+	if cfg.Trading.AutoClear, err = getEnvBool("AUTO_CLEAR_ON_EXECUTION", cfg.Trading.AutoClear); err != nil {
+		problems = append(problems, err)
+	}
+	if cfg.Trading.InternalCrossing, err = getEnvBool("INTERNAL_CROSSING", cfg.Trading.InternalCrossing); err != nil {
+		problems = append(problems, err)
+	}
+	if cfg.Trading.InternalCrossFeeRate, err = getEnvFloat("INTERNAL_CROSS_FEE_RATE", cfg.Trading.InternalCrossFeeRate); err != nil {
+		problems = append(problems, err)
+	}
+	if backend := strings.ToLower(strings.TrimSpace(os.Getenv("IDEMPOTENCY_BACKEND"))); backend != "" {
+		if backend != trading.IdempotencyBackendDB && backend != trading.IdempotencyBackendRedis {
+			problems = append(problems, fmt.Errorf("invalid value for IDEMPOTENCY_BACKEND: %s is not db or redis", backend))
+		} else {
+			cfg.Trading.IdempotencyBackend = backend
+		}
+	}
+	if addr := strings.TrimSpace(os.Getenv("REDIS_ADDR")); addr != "" {
+		cfg.Trading.RedisAddr = addr
+	}
+	cfg.Trading.RedisPassword = os.Getenv("REDIS_PASSWORD")
+	if cfg.Trading.RedisDB, err = getEnvInt("REDIS_DB", cfg.Trading.RedisDB); err != nil {
+		problems = append(problems, err)
+	}
+	if cfg.RateLimits.Auth, err = getEnvRateLimit("RATE_LIMIT_AUTH", cfg.RateLimits.Auth); err != nil {
+		problems = append(problems, err)
+	}
+	if cfg.RateLimits.Trading, err = getEnvRateLimit("RATE_LIMIT_TRADING", cfg.RateLimits.Trading); err != nil {
+		problems = append(problems, err)
+	}
+	if cfg.RateLimits.Status, err = getEnvRateLimit("RATE_LIMIT_STATUS", cfg.RateLimits.Status); err != nil {
+		problems = append(problems, err)
+	}
+	if cfg.TrustedProxies, err = getEnvProxies("TRUSTED_PROXIES"); err != nil {
+		problems = append(problems, err)
+	}
+	if cfg.PageSize.Default, err = getEnvInt("PAGE_SIZE_DEFAULT", cfg.PageSize.Default); err != nil {
+		problems = append(problems, err)
+	}
+	if cfg.PageSize.Max, err = getEnvInt("PAGE_SIZE_MAX", cfg.PageSize.Max); err != nil {
+		problems = append(problems, err)
+	}
+	// Every listing pages the same way
+	cfg.Trading.PageSize = cfg.PageSize
+	cfg.Settlement.PageSize = cfg.PageSize
+	if cfg.InstantFills, err = getEnvBool("EXCHANGE_INSTANT_FILLS", cfg.InstantFills); err != nil {
+		problems = append(problems, err)
+	}
+	if path := os.Getenv("EXCHANGES_FILE"); path != "" {
+		if cfg.Exchanges, err = exchange.LoadExchanges(path); err != nil {
+			problems = append(problems, fmt.Errorf("invalid value for EXCHANGES_FILE: %w", err))
+		}
+	}
+
+	problems = append(problems, cfg.validate()...)
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
+	}
+	return cfg, nil
+}
+
+// IsProduction reports whether the configuration is for a production deployment
+func (c *Config) IsProduction() bool {
+	return c.Env == "production"
+}
+
+// validate checks settings whose values parsed but are unusable, and fills the development
+// JWT secret when one is allowed
+func (c *Config) validate() []error {
+	var problems []error
+
+	if strings.TrimSpace(c.JWTSecret) == "" {
+		if c.IsProduction() {
+			problems = append(problems, errors.New("JWT_SECRET must be set in production"))
+		} else {
+			c.JWTSecret = developmentJWTSecret
+		}
+	}
+	if (c.OperatorAPIKey == "") != (c.OperatorAPISecret == "") {
+		problems = append(problems, errors.New("OPERATOR_API_KEY and OPERATOR_API_SECRET must be set together"))
+	}
+	if port, err := strconv.Atoi(c.Port); err != nil || port < 1 || port > 65535 {
+		problems = append(problems, fmt.Errorf("invalid value for PORT: %q is not a port between 1 and 65535", c.Port))
+	}
+	if c.DatabaseDSN == "" {
+		problems = append(problems, errors.New("invalid value for DATABASE_DSN: must not be empty"))
+	}
+
+	for _, interval := range []struct {
+		key   string
+		value time.Duration
+	}{
+		{"REQUEST_TIMEOUT", c.RequestTimeout},
+		{"SETTLEMENT_PROCESS_INTERVAL", c.SettlementProcessInterval},
+		{"ORDER_EXPIRY_INTERVAL", c.OrderExpiryInterval},
+		{"DB_HEALTH_CHECK_INTERVAL", c.DBHealthCheckInterval},
+		{"MAX_GTD_HORIZON", c.Trading.MaxGTDHorizon},
+		{"HTTP_READ_HEADER_TIMEOUT", c.ReadHeaderTimeout},
+		{"HTTP_READ_TIMEOUT", c.ReadTimeout},
+		{"HTTP_WRITE_TIMEOUT", c.WriteTimeout},
+		{"HTTP_IDLE_TIMEOUT", c.IdleTimeout},
+		{"WEBHOOK_RETRY_BACKOFF", c.Settlement.WebhookRetry.BaseBackoff},
+	} {
+		if interval.value <= 0 {
+			problems = append(problems, fmt.Errorf("invalid value for %s: %s is not positive", interval.key, interval.value))
+		}
+	}
+	if c.PageSize.Default < 1 {
+		problems = append(problems, fmt.Errorf("invalid value for PAGE_SIZE_DEFAULT: %d is not positive", c.PageSize.Default))
+	} else if c.PageSize.Max < c.PageSize.Default {
+		problems = append(problems, fmt.Errorf("invalid value for PAGE_SIZE_MAX: %d is below PAGE_SIZE_DEFAULT of %d",
+			c.PageSize.Max, c.PageSize.Default))
+	}
+	if c.Trading.MaxOpenOrders < 0 {
+		problems = append(problems, fmt.Errorf("invalid value for MAX_OPEN_ORDERS: %d is negative", c.Trading.MaxOpenOrders))
+	}
+	if c.Trading.PriceBand < 0 || math.IsNaN(c.Trading.PriceBand) {
+		problems = append(problems, fmt.Errorf("invalid value for PRICE_BAND: %v is not a non-negative fraction", c.Trading.PriceBand))
+	}
+	if c.Trading.InternalCrossFeeRate < 0 || math.IsNaN(c.Trading.InternalCrossFeeRate) {
+		problems = append(problems, fmt.Errorf("invalid value for INTERNAL_CROSS_FEE_RATE: %v is not a non-negative fraction", c.Trading.InternalCrossFeeRate))
+	}
+	if c.Settlement.WebhookRetry.MaxAttempts < 1 {
+		problems = append(problems, fmt.Errorf("invalid value for WEBHOOK_MAX_ATTEMPTS: %d is not positive", c.Settlement.WebhookRetry.MaxAttempts))
+	}
+	if c.Settlement.WebhookRetry.MaxBackoff < c.Settlement.WebhookRetry.BaseBackoff {
+		problems = append(problems, fmt.Errorf("invalid value for WEBHOOK_MAX_BACKOFF: %s is below WEBHOOK_RETRY_BACKOFF of %s",
+			c.Settlement.WebhookRetry.MaxBackoff, c.Settlement.WebhookRetry.BaseBackoff))
+	}
+	// A write timeout at or below the request timeout would cut off the 504 response
+	if c.RequestTimeout > 0 && c.WriteTimeout > 0 && c.WriteTimeout <= c.RequestTimeout {
+		problems = append(problems, fmt.Errorf("invalid value for HTTP_WRITE_TIMEOUT: %s must exceed REQUEST_TIMEOUT of %s",
+			c.WriteTimeout, c.RequestTimeout))
+	}
+	return problems
+}
+
+// getEnvBool parses a boolean environment variable, returning the default if unset
+func getEnvBool(key string, defaultValue bool) (bool, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+	return parsed, nil
+}
+
+// getEnvInt parses an integer environment variable, returning the default if unset
+func getEnvInt(key string, defaultValue int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+	return parsed, nil
+}
+
+// getEnvFloat parses a float environment variable, returning the default if unset
+func getEnvFloat(key string, defaultValue float64) (float64, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+	return parsed, nil
+}
+
+// getEnvRoutes parses a comma-separated list of SYMBOL=VALUE pairs (e.g. "AAPL=LCH,MSFT=DTCC"),
+// returning nil if unset. Symbols are normalized to match order symbols
+func getEnvRoutes(key string) (map[string]string, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil, nil
+	}
+	routes := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		symbol, target, ok := strings.Cut(pair, "=")
+		symbol = common.NormalizeSymbol(symbol)
+		target = strings.TrimSpace(target)
+		if !ok || symbol == "" || target == "" {
+			return nil, fmt.Errorf("invalid value for %s: expected SYMBOL=VALUE pairs, got %q", key, pair)
+		}
+		routes[symbol] = target
+	}
+	return routes, nil
+}
+
+// getEnvSymbolSizes parses a comma-separated list of SYMBOL=SIZE pairs (e.g. "AAPL=1,BTC=0.001")
+// of the named size, such as a lot size, returning nil if unset
+func getEnvSymbolSizes(key, name string) (map[string]float64, error) {
+	pairs, err := getEnvRoutes(key)
+	if err != nil || pairs == nil {
+		return nil, err
+	}
+	sizes := make(map[string]float64, len(pairs))
+	for symbol, value := range pairs {
+		size, err := strconv.ParseFloat(value, 64)
+		if err != nil || size <= 0 {
+			return nil, fmt.Errorf("invalid value for %s: %s of %s must be a positive number, got %q", key, name, symbol, value)
+		}
+		sizes[symbol] = size
+	}
+	return sizes, nil
+}
+
+// getEnvProxies parses a comma-separated list of IP addresses and CIDR ranges (e.g. "10.0.0.0/8,192.168.1.5"),
+// returning nil if unset
+func getEnvProxies(key string) ([]string, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil, nil
+	}
+	var proxies []string
+	for _, proxy := range strings.Split(value, ",") {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				return nil, fmt.Errorf("invalid value for %s: %q is not an IP address or CIDR range", key, proxy)
+			}
+		}
+		proxies = append(proxies, proxy)
+	}
+	return proxies, nil
+}
+
+// getEnvRateLimit parses a requests/seconds rate limit environment variable (e.g. "100/60"),
+// returning the default if unset
+func getEnvRateLimit(key string, defaultValue rate.Limit) (rate.Limit, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	parsed, err := middleware.ParseRateLimit(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+	return parsed, nil
+}
+
+// getEnvDuration parses a duration environment variable (e.g. "30s"), returning the default if unset
+func getEnvDuration(key string, defaultValue time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value for %s: %w", key, err)
+	}
+	return parsed, nil
+}
@@ -0,0 +1,157 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// loadProblems loads the configuration and returns the problems it reported
+func loadProblems(t *testing.T) []error {
+	t.Helper()
+	_, err := Load()
+	if err == nil {
+		return nil
+	}
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Load error = %v, want a *ValidationError", err)
+	}
+	return validationErr.Problems
+}
+
+// hasProblem reports whether any of the problems mentions the setting
+func hasProblem(problems []error, setting string) bool {
+	for _, problem := range problems {
+		if strings.Contains(problem.Error(), setting) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestLoadDefaultsInDevelopment(t *testing.T) {
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.Port != "8080" || cfg.IsProduction() {
+		t.Errorf("config = port %s production %v, want port 8080 in development", cfg.Port, cfg.IsProduction())
+	}
+	// Outside production a missing secret falls back to the development secret
+	if cfg.JWTSecret != developmentJWTSecret {
+		t.Errorf("JWT secret = %q, want the development secret", cfg.JWTSecret)
+	}
+}
+
+func TestLoadRejectsMissingSecretInProduction(t *testing.T) {
+	t.Setenv("ENV", "production")
+	t.Setenv("JWT_SECRET", "  ")
+
+	if problems := loadProblems(t); !hasProblem(problems, "JWT_SECRET") {
+		t.Errorf("problems = %v, want a missing JWT_SECRET reported", problems)
+	}
+
+	t.Setenv("JWT_SECRET", "production-secret")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load with a secret: %v", err)
+	}
+	if cfg.JWTSecret != "production-secret" {
+		t.Errorf("JWT secret = %q, want the configured secret", cfg.JWTSecret)
+	}
+}
+
+func TestLoadRejectsInvalidPort(t *testing.T) {
+	for _, port := range []string{"http", "0", "65536", "-1"} {
+		t.Run(port, func(t *testing.T) {
+			t.Setenv("PORT", port)
+			if problems := loadProblems(t); !hasProblem(problems, "PORT") {
+				t.Errorf("problems = %v, want port %q reported", problems, port)
+			}
+		})
+	}
+}
+
+func TestLoadRequiresOperatorKeyAndSecretTogether(t *testing.T) {
+	tests := []struct {
+		name        string
+		key, secret string
+		wantProblem bool
+	}{
+		{"neither", "", "", false},
+		{"both", "operator-key", "operator-secret", false},
+		{"key only", "operator-key", "", true},
+		{"secret only", "", "operator-secret", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("OPERATOR_API_KEY", tt.key)
+			t.Setenv("OPERATOR_API_SECRET", tt.secret)
+			if problems := loadProblems(t); hasProblem(problems, "OPERATOR_API_KEY") != tt.wantProblem {
+				t.Errorf("problems = %v, want operator credentials reported: %v", problems, tt.wantProblem)
+			}
+		})
+	}
+}
+
+func TestLoadReportsEveryProblem(t *testing.T) {
+	t.Setenv("ENV", "production")
+	t.Setenv("JWT_SECRET", "")
+	t.Setenv("PORT", "not-a-port")
+	t.Setenv("DATABASE_DSN", " ")
+	t.Setenv("ORDER_EXPIRY_INTERVAL", "0s")
+	t.Setenv("SETTLEMENT_PROCESS_INTERVAL", "soon")
+
+	problems := loadProblems(t)
+	for _, setting := range []string{"JWT_SECRET", "PORT", "DATABASE_DSN", "ORDER_EXPIRY_INTERVAL", "SETTLEMENT_PROCESS_INTERVAL"} {
+		if !hasProblem(problems, setting) {
+			t.Errorf("problems = %v, want %s reported", problems, setting)
+		}
+	}
+}
+
+func TestLoadSharesPageSizeAcrossListings(t *testing.T) {
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.PageSize.Default != 50 || cfg.PageSize.Max != 500 {
+		t.Errorf("default page size = %+v, want 50 capped at 500", cfg.PageSize)
+	}
+
+	t.Setenv("PAGE_SIZE_DEFAULT", "20")
+	t.Setenv("PAGE_SIZE_MAX", "200")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if cfg.PageSize.Default != 20 || cfg.PageSize.Max != 200 {
+		t.Errorf("page size = %+v, want 20 capped at 200", cfg.PageSize)
+	}
+	if cfg.Trading.PageSize != cfg.PageSize || cfg.Settlement.PageSize != cfg.PageSize {
+		t.Errorf("trading pages by %+v and settlement by %+v, want both the shared %+v",
+			cfg.Trading.PageSize, cfg.Settlement.PageSize, cfg.PageSize)
+	}
+
+	t.Setenv("PAGE_SIZE_MAX", "10")
+	if problems := loadProblems(t); !hasProblem(problems, "PAGE_SIZE_MAX") {
+		t.Errorf("problems = %v, want a maximum below the default reported", problems)
+	}
+}
+
+func TestLoadParsesTrustedProxies(t *testing.T) {
+	t.Setenv("TRUSTED_PROXIES", " 10.0.0.0/8, 192.168.1.5 ,")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(cfg.TrustedProxies) != 2 || cfg.TrustedProxies[0] != "10.0.0.0/8" || cfg.TrustedProxies[1] != "192.168.1.5" {
+		t.Errorf("trusted proxies = %v, want [10.0.0.0/8 192.168.1.5]", cfg.TrustedProxies)
+	}
+
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8,load-balancer")
+	if problems := loadProblems(t); !hasProblem(problems, "TRUSTED_PROXIES") {
+		t.Errorf("problems = %v, want the hostname reported", problems)
+	}
+}
@@ -3,17 +3,26 @@ package database
 import (
 	"fmt"
 
+	"github.com/ksred/klear-api/internal/breaks"
 	"github.com/ksred/klear-api/internal/clearing"
 	"github.com/ksred/klear-api/internal/database/migrations"
+	"github.com/ksred/klear-api/internal/events"
 	"github.com/ksred/klear-api/internal/settlement"
 	"github.com/ksred/klear-api/internal/trading"
+	"github.com/ksred/klear-api/internal/types"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
-// NewDatabase initializes and returns a new GORM DB connection
-func NewDatabase() (*gorm.DB, error) {
-	db, err := gorm.Open(sqlite.Open("test.db"), &gorm.Config{})
+// DefaultDSN is the SQLite database file used when no data source is configured
+const DefaultDSN = "test.db"
+
+// NewDatabase opens the SQLite database at dsn, runs migrations and returns the GORM DB connection
+func NewDatabase(dsn string) (*gorm.DB, error) {
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		// Translate driver errors (e.g. unique violations) into gorm errors
+		TranslateError: true,
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -27,16 +36,53 @@ func NewDatabase() (*gorm.DB, error) {
 		return nil, fmt.Errorf("failed to run migrations: %w", err)
 	}
 
+	if err := migrations.ScopeIdempotencyKeys(db); err != nil {
+		return nil, fmt.Errorf("failed to run migrations: %w", err)
+	}
+
 	// Auto-migrate other schemas
 	err = db.AutoMigrate(
 		&trading.Order{},
 		&trading.IdempotencyRecord{},
+		&types.Client{},
+		&types.Allocation{},
+		&types.VenueAttempt{},
 		&clearing.Clearing{},
 		&settlement.Settlement{},
+		&settlement.SettlementBatch{},
+		&settlement.CounterpartyAgreement{},
+		&settlement.ClientWebhook{},
+		&settlement.WebhookDeadLetter{},
+		&breaks.TradeBreak{},
+		&events.DomainEvent{},
 	)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := migrations.NormalizeOrderSymbols(db); err != nil {
+		return nil, fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	if err := migrations.BackfillOrderUnits(db); err != nil {
+		return nil, fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	if err := migrations.AddDailyStatsIndexes(db); err != nil {
+		return nil, fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	if err := migrations.BackfillSettledAt(db); err != nil {
+		return nil, fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	if err := migrations.AddOpenOrdersIndex(db); err != nil {
+		return nil, fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	if err := migrations.BackfillAmountUnits(db); err != nil {
+		return nil, fmt.Errorf("failed to run migrations: %w", err)
+	}
+
 	return db, nil
 }
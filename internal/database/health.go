@@ -0,0 +1,104 @@
+package database
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/klear-api/pkg/response"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// healthCheckTimeout bounds a single ping so a hung connection is reported as down rather than blocking
+const healthCheckTimeout = 2 * time.Second
+
+// HealthMonitor tracks whether the database is reachable, so requests that need it can be
+// turned away with a clear 503 while it is down instead of failing with opaque 500s
+type HealthMonitor struct {
+	db       *gorm.DB
+	interval time.Duration // Time between background checks
+
+	mu      sync.RWMutex
+	lastErr error // Error from the most recent check, nil while the database is up
+}
+
+// NewHealthMonitor creates a monitor for db that checks it at the given interval
+// The database is assumed up until the first check says otherwise
+func NewHealthMonitor(db *gorm.DB, interval time.Duration) *HealthMonitor {
+	return &HealthMonitor{
+		db:       db,
+		interval: interval,
+	}
+}
+
+// Check pings the database, records the result and returns the error if it is unreachable
+// A ping abandoned because ctx was cancelled, such as by a client disconnecting, is not recorded
+func (m *HealthMonitor) Check(ctx context.Context) error {
+	err := m.ping(ctx)
+	if err != nil && ctx.Err() != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	wasHealthy := m.lastErr == nil
+	m.lastErr = err
+	m.mu.Unlock()
+
+	if err != nil && wasHealthy {
+		log.Error().Err(err).Msg("database is unavailable; entering degraded mode")
+	} else if err == nil && !wasHealthy {
+		log.Info().Msg("database is available again; leaving degraded mode")
+	}
+	return err
+}
+
+func (m *HealthMonitor) ping(ctx context.Context) error {
+	sqlDB, err := m.db.DB()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+	return sqlDB.PingContext(ctx)
+}
+
+// Healthy reports whether the database was reachable at the most recent check
+func (m *HealthMonitor) Healthy() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.lastErr == nil
+}
+
+// Start checks the database at the configured interval until ctx is cancelled
+func (m *HealthMonitor) Start(ctx context.Context) {
+	logger := log.With().Str("component", "db_health_monitor").Logger()
+	logger.Info().Dur("interval", m.interval).Msg("starting database health monitor")
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info().Msg("shutting down database health monitor")
+			return
+		case <-ticker.C:
+			_ = m.Check(ctx)
+		}
+	}
+}
+
+// ReadinessHandler reports whether the server can serve requests from the most recent check,
+// so frequent load balancer probes never reach the database
+// It returns 503 while the database is unreachable so load balancers stop routing to the instance
+func (m *HealthMonitor) ReadinessHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !m.Healthy() {
+			response.ServiceUnavailable(c, "Database is unavailable")
+			return
+		}
+		response.Success(c, gin.H{"status": "ready", "database": "up"})
+	}
+}
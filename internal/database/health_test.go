@@ -0,0 +1,120 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/ksred/klear-api/internal/breaks"
+	"github.com/ksred/klear-api/internal/database/dbtest"
+	"github.com/ksred/klear-api/internal/events"
+	"github.com/ksred/klear-api/internal/trading"
+	"github.com/ksred/klear-api/internal/types"
+	"github.com/ksred/klear-api/pkg/middleware"
+	"gorm.io/gorm"
+)
+
+// newDegradedRouter serves order creation and readiness as the server does, with degraded mode on
+func newDegradedRouter(t *testing.T, db *gorm.DB, monitor *HealthMonitor) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	handlers := trading.NewGinHandlers(trading.NewService(db, trading.DefaultConfig()))
+	router := gin.New()
+	router.GET("/ready", monitor.ReadinessHandler())
+	router.POST("/api/v1/orders", func(c *gin.Context) {
+		c.Set("claims", jwt.MapClaims{"client_id": "client-1", "permissions": []interface{}{"trade"}})
+		c.Set("clientID", "client-1")
+		c.Next()
+	}, middleware.RequireDatabase(monitor), handlers.CreateOrderHandler())
+	return router
+}
+
+// serve sends a request with an optional JSON body and returns the status and error code
+func serve(router http.Handler, method, path, body string) (int, string) {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", uuid.New().String())
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	var envelope struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	json.Unmarshal(recorder.Body.Bytes(), &envelope)
+	return recorder.Code, envelope.Error.Code
+}
+
+const orderBody = `{"client_id":"client-1","symbol":"AAPL","side":"BUY","order_type":"LIMIT","quantity":100,"price":150}`
+
+func TestOrderCreationReturns503WhenDatabaseIsClosed(t *testing.T) {
+	db := dbtest.Open(t,
+		&types.Order{},
+		&types.Client{},
+		&types.Execution{},
+		&types.ExchangeFill{},
+		&types.Allocation{},
+		&types.VenueAttempt{},
+		&trading.IdempotencyRecord{},
+		&events.DomainEvent{},
+		&breaks.TradeBreak{},
+	)
+	if err := db.Create(&types.Client{ClientID: "client-1", Name: "client-1", Active: true}).Error; err != nil {
+		t.Fatalf("failed to seed client: %v", err)
+	}
+	// The background check never runs, so only the write's own check can find the outage
+	monitor := NewHealthMonitor(db, time.Hour)
+	router := newDegradedRouter(t, db, monitor)
+
+	if status, _ := serve(router, http.MethodPost, "/api/v1/orders", orderBody); status != http.StatusCreated {
+		t.Fatalf("order creation with the database up status = %d, want 201", status)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("DB: %v", err)
+	}
+	sqlDB.Close()
+
+	// Readiness answers from the last check, which still found the database up
+	if status, _ := serve(router, http.MethodGet, "/ready", ""); status != http.StatusOK {
+		t.Errorf("readiness before any failed check status = %d, want the cached 200", status)
+	}
+
+	status, code := serve(router, http.MethodPost, "/api/v1/orders", orderBody)
+	if status != http.StatusServiceUnavailable || code != "SERVICE_UNAVAILABLE" {
+		t.Errorf("order creation with the database closed = %d %s, want 503 SERVICE_UNAVAILABLE", status, code)
+	}
+	if monitor.Healthy() {
+		t.Error("monitor still healthy after the write found the database closed")
+	}
+	if status, code := serve(router, http.MethodGet, "/ready", ""); status != http.StatusServiceUnavailable || code != "SERVICE_UNAVAILABLE" {
+		t.Errorf("readiness after the failed check = %d %s, want 503 SERVICE_UNAVAILABLE", status, code)
+	}
+}
+
+func TestCancelledCheckIsNotRecorded(t *testing.T) {
+	db := dbtest.Open(t)
+	monitor := NewHealthMonitor(db, time.Hour)
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("DB: %v", err)
+	}
+	sqlDB.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := monitor.Check(ctx); err == nil {
+		t.Fatal("Check of a closed database succeeded")
+	}
+	if !monitor.Healthy() {
+		t.Error("a check abandoned by its caller marked the database down")
+	}
+}
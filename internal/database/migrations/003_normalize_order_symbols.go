@@ -0,0 +1,11 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+// NormalizeOrderSymbols trims and upper-cases symbols on existing orders so that
+// they net together with orders created after symbol normalization was introduced
+func NormalizeOrderSymbols(db *gorm.DB) error {
+	return db.Exec(`UPDATE orders SET symbol = UPPER(TRIM(symbol)) WHERE symbol != UPPER(TRIM(symbol))`).Error
+}
@@ -0,0 +1,14 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+// BackfillOrderUnits populates the scaled integer price and quantity columns on orders
+// created before they were introduced
+func BackfillOrderUnits(db *gorm.DB) error {
+	if err := db.Exec(`UPDATE orders SET price_units = CAST(ROUND(price * 100000000) AS INTEGER) WHERE price_units = 0 AND price != 0`).Error; err != nil {
+		return err
+	}
+	return db.Exec(`UPDATE orders SET quantity_units = CAST(ROUND(quantity * 100000000) AS INTEGER) WHERE quantity_units = 0 AND quantity != 0`).Error
+}
@@ -0,0 +1,12 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+// ScopeIdempotencyKeys drops the original key-only unique index on idempotency records
+// so the same key can be used once per resource type. AutoMigrate then creates the
+// composite (idempotency_key, resource_type) index in its place
+func ScopeIdempotencyKeys(db *gorm.DB) error {
+	return db.Exec(`DROP INDEX IF EXISTS idx_idempotency_records_idempotency_key`).Error
+}
@@ -0,0 +1,21 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+// AddDailyStatsIndexes indexes the join behind the daily net position, volume and trading stats
+// queries, which select a client's orders and then their executions within a day. Without them
+// both tables are scanned in full as volume grows
+func AddDailyStatsIndexes(db *gorm.DB) error {
+	statements := []string{
+		`CREATE INDEX IF NOT EXISTS idx_orders_client_id ON orders (client_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_executions_order_created_status ON executions (order_id, created_at, status)`,
+	}
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package migrations
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ksred/klear-api/internal/clearing"
+	"github.com/ksred/klear-api/internal/database/dbtest"
+	"github.com/ksred/klear-api/internal/types"
+	"gorm.io/gorm"
+)
+
+// dailyStatsQuery is the join behind the daily net position, volume and trading stats queries
+const dailyStatsQuery = `
+	SELECT COALESCE(SUM(executions.total_quantity), 0)
+	FROM executions
+	JOIN orders ON orders.order_id = executions.order_id
+	WHERE orders.client_id = ?
+	AND executions.created_at >= ?
+	AND executions.created_at < ?
+	AND executions.status = 'COMPLETED'`
+
+// statsDay is the day the daily stats tests query
+var statsDay = time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC)
+
+// seedDailyTrades records an executed buy for each of the clients on each of the days before and
+// including statsDay
+func seedDailyTrades(tb testing.TB, db *gorm.DB, clients, days int) {
+	tb.Helper()
+	var orders []types.Order
+	var executions []types.Execution
+	for day := 0; day < days; day++ {
+		at := statsDay.AddDate(0, 0, -day).Add(12 * time.Hour)
+		for client := 0; client < clients; client++ {
+			orderID := uuid.New().String()
+			orders = append(orders, types.Order{
+				OrderID: orderID, ClientID: fmt.Sprintf("client-%d", client), Symbol: "AAPL", Side: "BUY",
+				OrderType: "LIMIT", Quantity: 10, Price: 100, Status: "FILLED", CreatedAt: at, UpdatedAt: at,
+			})
+			executions = append(executions, types.Execution{
+				ExecutionID: uuid.New().String(), OrderID: orderID, TotalQuantity: 10, AveragePrice: 100,
+				Side: "BUY", Status: "COMPLETED", CreatedAt: at, UpdatedAt: at,
+			})
+		}
+	}
+	if err := db.CreateInBatches(orders, 500).Error; err != nil {
+		tb.Fatalf("failed to seed orders: %v", err)
+	}
+	if err := db.CreateInBatches(executions, 500).Error; err != nil {
+		tb.Fatalf("failed to seed executions: %v", err)
+	}
+}
+
+// queryPlan returns the SQLite query plan of the daily stats query
+func queryPlan(t *testing.T, db *gorm.DB) string {
+	t.Helper()
+	rows, err := db.Raw("EXPLAIN QUERY PLAN "+dailyStatsQuery, "client-1", statsDay, statsDay.AddDate(0, 0, 1)).Rows()
+	if err != nil {
+		t.Fatalf("failed to explain query: %v", err)
+	}
+	defer rows.Close()
+	var steps []string
+	for rows.Next() {
+		var id, parent, unused int
+		var detail string
+		if err := rows.Scan(&id, &parent, &unused, &detail); err != nil {
+			t.Fatalf("failed to read query plan: %v", err)
+		}
+		steps = append(steps, detail)
+	}
+	return strings.Join(steps, "\n")
+}
+
+func TestDailyStatsQueryUsesIndexes(t *testing.T) {
+	db := dbtest.Open(t, &types.Order{}, &types.Execution{})
+	seedDailyTrades(t, db, 5, 3)
+
+	if err := AddDailyStatsIndexes(db); err != nil {
+		t.Fatalf("AddDailyStatsIndexes: %v", err)
+	}
+	plan := queryPlan(t, db)
+	for _, index := range []string{"idx_orders_client_id", "idx_executions_order_created_status"} {
+		if !strings.Contains(plan, index) {
+			t.Errorf("query plan does not use %s:\n%s", index, plan)
+		}
+	}
+}
+
+func TestDailyStatsUnchangedByIndexes(t *testing.T) {
+	db := dbtest.Open(t, &types.Order{}, &types.Execution{}, &types.ExchangeFill{})
+	seedDailyTrades(t, db, 5, 3)
+	stats := clearing.NewDatabase(db)
+
+	before, err := stats.GetDailyTradingStats("client-1", statsDay)
+	if err != nil {
+		t.Fatalf("GetDailyTradingStats: %v", err)
+	}
+	if err := AddDailyStatsIndexes(db); err != nil {
+		t.Fatalf("AddDailyStatsIndexes: %v", err)
+	}
+	// Running the migration again is harmless
+	if err := AddDailyStatsIndexes(db); err != nil {
+		t.Fatalf("AddDailyStatsIndexes again: %v", err)
+	}
+	after, err := stats.GetDailyTradingStats("client-1", statsDay)
+	if err != nil {
+		t.Fatalf("GetDailyTradingStats: %v", err)
+	}
+
+	if *before != *after {
+		t.Errorf("daily stats = %+v after indexing, want %+v", after, before)
+	}
+	if after.NetPosition != 10 || after.TradingVolume != 1000 || after.OrderCount != 1 {
+		t.Errorf("daily stats = %+v, want the client's one buy of 10 at 100", after)
+	}
+}
+
+func BenchmarkDailyStats(b *testing.B) {
+	for _, indexed := range []bool{false, true} {
+		b.Run(fmt.Sprintf("indexed=%v", indexed), func(b *testing.B) {
+			db := dbtest.Open(b, &types.Order{}, &types.Execution{}, &types.ExchangeFill{})
+			seedDailyTrades(b, db, 200, 30)
+			if indexed {
+				if err := AddDailyStatsIndexes(db); err != nil {
+					b.Fatalf("AddDailyStatsIndexes: %v", err)
+				}
+			}
+			stats := clearing.NewDatabase(db)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := stats.GetDailyTradingStats("client-1", statsDay); err != nil {
+					b.Fatalf("GetDailyTradingStats: %v", err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,11 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+// BackfillSettledAt records when settlements that settled before settled_at was introduced
+// reached SETTLED. SETTLED is terminal, so their last update is when they settled
+func BackfillSettledAt(db *gorm.DB) error {
+	return db.Exec(`UPDATE settlements SET settled_at = updated_at WHERE settlement_status = 'SETTLED' AND settled_at IS NULL`).Error
+}
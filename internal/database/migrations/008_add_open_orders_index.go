@@ -0,0 +1,11 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+// AddOpenOrdersIndex indexes orders by client and status, so the open order count checked on
+// every new order is answered from the index instead of scanning the client's order history
+func AddOpenOrdersIndex(db *gorm.DB) error {
+	return db.Exec(`CREATE INDEX IF NOT EXISTS idx_orders_client_status ON orders (client_id, status)`).Error
+}
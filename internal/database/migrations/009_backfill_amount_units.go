@@ -0,0 +1,26 @@
+package migrations
+
+import (
+	"gorm.io/gorm"
+)
+
+// BackfillAmountUnits populates the scaled integer amount columns on executions, fills,
+// clearings and settlements saved before they were introduced
+func BackfillAmountUnits(db *gorm.DB) error {
+	statements := []string{
+		`UPDATE executions SET total_quantity_units = CAST(ROUND(total_quantity * 100000000) AS INTEGER) WHERE total_quantity_units = 0 AND total_quantity != 0`,
+		`UPDATE executions SET average_price_units = CAST(ROUND(average_price * 100000000) AS INTEGER) WHERE average_price_units = 0 AND average_price != 0`,
+		`UPDATE exchange_fills SET price_units = CAST(ROUND(price * 100000000) AS INTEGER) WHERE price_units = 0 AND price != 0`,
+		`UPDATE exchange_fills SET quantity_units = CAST(ROUND(quantity * 100000000) AS INTEGER) WHERE quantity_units = 0 AND quantity != 0`,
+		`UPDATE exchange_fills SET fee_amount_units = CAST(ROUND(fee_amount * 100000000) AS INTEGER) WHERE fee_amount_units = 0 AND fee_amount != 0`,
+		`UPDATE clearings SET settlement_amount_units = CAST(ROUND(settlement_amount * 100000000) AS INTEGER) WHERE settlement_amount_units = 0 AND settlement_amount != 0`,
+		`UPDATE settlements SET final_amount_units = CAST(ROUND(final_amount * 100000000) AS INTEGER) WHERE final_amount_units = 0 AND final_amount != 0`,
+		`UPDATE settlements SET settlement_fees_units = CAST(ROUND(settlement_fees * 100000000) AS INTEGER) WHERE settlement_fees_units = 0 AND settlement_fees != 0`,
+	}
+	for _, statement := range statements {
+		if err := db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/ksred/klear-api/internal/database/dbtest"
+	"github.com/ksred/klear-api/internal/types"
+)
+
+func TestNormalizeOrderSymbols(t *testing.T) {
+	db := dbtest.Open(t, &types.Order{})
+	for _, symbol := range []string{" aapl ", "Aapl", "AAPL", "msft "} {
+		order := types.Order{OrderID: uuid.New().String(), ClientID: "client-1", Symbol: symbol, Side: "BUY"}
+		if err := db.Create(&order).Error; err != nil {
+			t.Fatalf("failed to seed order: %v", err)
+		}
+	}
+
+	if err := NormalizeOrderSymbols(db); err != nil {
+		t.Fatalf("NormalizeOrderSymbols: %v", err)
+	}
+
+	var counts []struct {
+		Symbol string
+		Count  int
+	}
+	if err := db.Model(&types.Order{}).Select("symbol, COUNT(*) AS count").Group("symbol").Order("symbol").
+		Scan(&counts).Error; err != nil {
+		t.Fatalf("failed to count symbols: %v", err)
+	}
+	if len(counts) != 2 || counts[0].Symbol != "AAPL" || counts[0].Count != 3 || counts[1].Symbol != "MSFT" || counts[1].Count != 1 {
+		t.Errorf("symbols = %+v, want 3 AAPL and 1 MSFT", counts)
+	}
+}
@@ -0,0 +1,65 @@
+package retry
+
+import (
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// maxAttempts bounds the number of times an operation is tried
+	maxAttempts = 4
+	// baseDelay is the backoff before the first retry, doubled on each subsequent retry
+	baseDelay = 20 * time.Millisecond
+)
+
+// transientMessages identify database errors that are likely to succeed when retried
+var transientMessages = []string{
+	"database is locked",         // SQLite SQLITE_BUSY
+	"database table is locked",   // SQLite SQLITE_LOCKED
+	"could not serialize access", // Postgres serialization failure (40001)
+	"deadlock detected",          // Postgres deadlock (40P01)
+	"sqlstate 40001",
+	"sqlstate 40p01",
+}
+
+// IsTransient reports whether err is a transient database error worth retrying
+func IsTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	for _, transient := range transientMessages {
+		if strings.Contains(msg, transient) {
+			return true
+		}
+	}
+	return false
+}
+
+// Do runs an idempotent database operation, retrying with jittered exponential
+// backoff while it fails with a transient error. Any other error is returned immediately
+func Do(operation string, fn func() error) error {
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		if err = fn(); err == nil || !IsTransient(err) {
+			return err
+		}
+		if attempt == maxAttempts {
+			break
+		}
+
+		delay := baseDelay << (attempt - 1)
+		delay += time.Duration(rand.Int63n(int64(delay)))
+		log.Warn().
+			Err(err).
+			Str("operation", operation).
+			Int("attempt", attempt).
+			Dur("backoff", delay).
+			Msg("transient database error, retrying")
+		time.Sleep(delay)
+	}
+	return err
+}
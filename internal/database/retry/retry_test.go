@@ -0,0 +1,52 @@
+package retry
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDoRetriesTransientErrorUntilSuccess(t *testing.T) {
+	attempts := 0
+	err := Do("test", func() error {
+		attempts++
+		if attempts < 3 {
+			return errors.New("database is locked")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("attempts = %d, want 3", attempts)
+	}
+}
+
+func TestDoReturnsPermanentErrorImmediately(t *testing.T) {
+	permanent := errors.New("UNIQUE constraint failed: orders.order_id")
+	attempts := 0
+	err := Do("test", func() error {
+		attempts++
+		return permanent
+	})
+	if !errors.Is(err, permanent) {
+		t.Errorf("err = %v, want %v", err, permanent)
+	}
+	if attempts != 1 {
+		t.Errorf("attempts = %d, want 1", attempts)
+	}
+}
+
+func TestDoGivesUpAfterMaxAttempts(t *testing.T) {
+	attempts := 0
+	err := Do("test", func() error {
+		attempts++
+		return errors.New("ERROR: could not serialize access due to concurrent update (SQLSTATE 40001)")
+	})
+	if !IsTransient(err) {
+		t.Errorf("err = %v, want the last transient error", err)
+	}
+	if attempts != maxAttempts {
+		t.Errorf("attempts = %d, want %d", attempts, maxAttempts)
+	}
+}
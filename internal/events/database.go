@@ -0,0 +1,42 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Database struct {
+	db *gorm.DB
+}
+
+func NewDatabase(db *gorm.DB) *Database {
+	return &Database{db: db}
+}
+
+// Append records an event using tx, so the event commits or rolls back with the state change it describes
+// payload is stored as JSON
+func Append(tx *gorm.DB, eventType, aggregateID string, payload interface{}, occurredAt time.Time) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event payload: %w", eventType, err)
+	}
+	return tx.Create(&DomainEvent{
+		EventType:   eventType,
+		AggregateID: aggregateID,
+		Payload:     data,
+		OccurredAt:  occurredAt,
+	}).Error
+}
+
+// ListEventsAfter retrieves up to limit events with a sequence greater than after, in sequence order
+func (d *Database) ListEventsAfter(after uint64, limit int) ([]DomainEvent, error) {
+	events := []DomainEvent{}
+	err := d.db.Where("sequence > ?", after).
+		Order("sequence ASC").
+		Limit(limit).
+		Find(&events).Error
+	return events, err
+}
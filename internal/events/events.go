@@ -0,0 +1,90 @@
+package events
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/klear-api/pkg/common"
+	"github.com/ksred/klear-api/pkg/response"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidAfter is returned when the after sequence is not a non-negative integer
+var ErrInvalidAfter = errors.New("after must be a non-negative integer")
+
+// Service reads the domain event log written by trading, clearing and settlement
+type Service struct {
+	db       *Database
+	pageSize common.PageSize
+}
+
+// NewService creates a new event log service with the given database connection and page size bounds
+func NewService(gormDB *gorm.DB, pageSize common.PageSize) *Service {
+	return &Service{
+		db:       NewDatabase(gormDB),
+		pageSize: pageSize,
+	}
+}
+
+// ListEvents retrieves the events that follow the after sequence, in the order they were appended
+// Parameters:
+//   - after: Sequence of the last event already consumed; 0 reads from the start of the log
+//   - limit: Maximum number of events to return
+func (s *Service) ListEvents(after uint64, limit int) (*EventPage, error) {
+	// Fetch one extra event to tell whether another page follows
+	events, err := s.db.ListEventsAfter(after, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &EventPage{Limit: limit, NextAfter: after}
+	if len(events) > limit {
+		events = events[:limit]
+		page.HasMore = true
+	}
+	if len(events) > 0 {
+		page.NextAfter = events[len(events)-1].Sequence
+	}
+	page.Events = events
+	return page, nil
+}
+
+// GinHandlers wraps the event log service with HTTP handlers
+type GinHandlers struct {
+	service *Service
+}
+
+// NewGinHandlers creates a new set of HTTP handlers for event log endpoints
+func NewGinHandlers(service *Service) *GinHandlers {
+	return &GinHandlers{
+		service: service,
+	}
+}
+
+// ListEventsHandler handles GET requests to read the event log
+// Requires internal authentication
+// Query parameters: after (optional, defaults to 0), limit (optional)
+func (h *GinHandlers) ListEventsHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var after uint64
+		if raw := c.Query("after"); raw != "" {
+			n, err := strconv.ParseUint(raw, 10, 64)
+			if err != nil {
+				response.BadRequest(c, ErrInvalidAfter.Error())
+				return
+			}
+			after = n
+		}
+
+		// Events are read by sequence, so only the limit of the usual paging parameters applies
+		page, err := common.ParsePage(c.Query("limit"), "", "", h.service.pageSize)
+		if err != nil {
+			response.BadRequest(c, err.Error())
+			return
+		}
+
+		events, err := h.service.ListEvents(after, page.Limit)
+		response.Handle(c, events, err)
+	}
+}
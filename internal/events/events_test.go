@@ -0,0 +1,63 @@
+package events
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/klear-api/internal/database/dbtest"
+	"github.com/ksred/klear-api/pkg/common"
+)
+
+// getEvents serves GET path on the events endpoint and decodes the page, failing the test on error
+func getEvents(t *testing.T, router http.Handler, path string) EventPage {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("GET %s status = %d, want 200: %s", path, recorder.Code, recorder.Body.String())
+	}
+	var envelope struct {
+		Data EventPage `json:"data"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return envelope.Data
+}
+
+func TestListEventsResumesAfterSequence(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := dbtest.Open(t, &DomainEvent{})
+	at := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	for _, id := range []string{"ORDER-1", "ORDER-2", "ORDER-3"} {
+		if err := Append(db, EventOrderCreated, id, map[string]string{"order_id": id}, at); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+	router := gin.New()
+	router.GET("/events", NewGinHandlers(NewService(db, common.DefaultPageSize())).ListEventsHandler())
+
+	first := getEvents(t, router, "/events?limit=2")
+	if len(first.Events) != 2 || !first.HasMore {
+		t.Fatalf("first page = %d events, has_more %v; want 2 with more to follow", len(first.Events), first.HasMore)
+	}
+	if first.Events[0].AggregateID != "ORDER-1" || first.Events[1].AggregateID != "ORDER-2" {
+		t.Errorf("first page = %s, %s; want ORDER-1, ORDER-2", first.Events[0].AggregateID, first.Events[1].AggregateID)
+	}
+
+	rest := getEvents(t, router, "/events?after="+strconv.FormatUint(first.NextAfter, 10))
+	if len(rest.Events) != 1 || rest.Events[0].AggregateID != "ORDER-3" || rest.HasMore {
+		t.Errorf("page after %d = %+v, want only ORDER-3", first.NextAfter, rest.Events)
+	}
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/events?after=-1", nil))
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("after=-1 status = %d, want 400", recorder.Code)
+	}
+}
@@ -0,0 +1,42 @@
+package events
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Event types recorded in the domain event log
+const (
+	EventOrderCreated            = "ORDER_CREATED"
+	EventOrderExecuted           = "ORDER_EXECUTED"
+	EventOrderStatusChanged      = "ORDER_STATUS_CHANGED"
+	EventTradeCleared            = "TRADE_CLEARED"
+	EventClearingStatusChanged   = "CLEARING_STATUS_CHANGED"
+	EventTradeBusted             = "TRADE_BUSTED"
+	EventSettlementCreated       = "SETTLEMENT_CREATED"
+	EventSettlementStatusChanged = "SETTLEMENT_STATUS_CHANGED"
+)
+
+// DomainEvent is one entry in the append-only event log
+// Sequence increases with every event appended, so consumers can resume after the last sequence they saw
+type DomainEvent struct {
+	Sequence    uint64          `gorm:"primaryKey;autoIncrement" json:"sequence"`
+	EventType   string          `gorm:"index" json:"event_type"`
+	AggregateID string          `gorm:"index" json:"aggregate_id"` // Order, execution, clearing or settlement ID the event is about
+	Payload     json.RawMessage `gorm:"type:text" json:"payload"`
+	OccurredAt  time.Time       `json:"occurred_at"`
+}
+
+// StatusChange is the payload of a status change event
+type StatusChange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// EventPage is one page of the event log, in sequence order
+type EventPage struct {
+	Events    []DomainEvent `json:"events"`
+	Limit     int           `json:"limit"`      // Page size applied, after defaulting and clamping
+	NextAfter uint64        `json:"next_after"` // Sequence to pass as after to read the events that follow
+	HasMore   bool          `json:"has_more"`
+}
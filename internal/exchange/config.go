@@ -0,0 +1,129 @@
+package exchange
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ksred/klear-api/pkg/common"
+)
+
+var ErrNoExchanges = errors.New("exchange config defines no exchanges")
+
+var (
+	exchangesMu sync.RWMutex
+	exchanges   = defaultExchanges
+)
+
+// instantFills switches the venues from realistic simulation to deterministic instant fills
+var instantFills atomic.Bool
+
+var (
+	clockMu sync.RWMutex
+	clock   common.Clock = common.RealClock{}
+)
+
+// now returns the current time on the venues' clock
+func now() time.Time {
+	clockMu.RLock()
+	defer clockMu.RUnlock()
+	return clock.Now()
+}
+
+// activeExchanges returns the venue set orders are currently routed across
+func activeExchanges() []*Exchange {
+	exchangesMu.RLock()
+	defer exchangesMu.RUnlock()
+	return exchanges
+}
+
+// Exchanges returns the venue set orders are currently routed across, so it can be restored after SetExchanges
+func Exchanges() []*Exchange {
+	return activeExchanges()
+}
+
+// SetExchanges replaces the venue set orders are routed across
+func SetExchanges(set []*Exchange) {
+	exchangesMu.Lock()
+	defer exchangesMu.Unlock()
+	exchanges = set
+}
+
+// SetInstantFills switches every venue into or out of instant-fill mode, meant for load testing
+// In instant-fill mode an attempt fills immediately, never fails and fills at exactly the order price,
+// so benchmarks measure the API and database rather than the simulated latency and failures
+func SetInstantFills(enabled bool) {
+	instantFills.Store(enabled)
+}
+
+// SetClock replaces the clock used to stamp fills, venue attempts, executions and quotes and to
+// roll the venue stats over each day. Venue latency is still measured on the wall clock
+func SetClock(c common.Clock) {
+	clockMu.Lock()
+	defer clockMu.Unlock()
+	clock = c
+}
+
+// LoadExchanges reads a venue set from a JSON file holding an array of exchange definitions
+// Parameters:
+//   - path: Path of the JSON file
+func LoadExchanges(path string) ([]*Exchange, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read exchange config: %w", err)
+	}
+
+	var set []*Exchange
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse exchange config %s: %w", path, err)
+	}
+	if len(set) == 0 {
+		return nil, ErrNoExchanges
+	}
+
+	seen := make(map[string]bool, len(set))
+	for i, ex := range set {
+		if ex == nil {
+			return nil, fmt.Errorf("exchange %d is empty", i)
+		}
+		if err := ex.validate(); err != nil {
+			return nil, fmt.Errorf("invalid exchange %q: %w", ex.ID, err)
+		}
+		for j, symbol := range ex.SupportedSymbols {
+			ex.SupportedSymbols[j] = common.NormalizeSymbol(symbol)
+			if ex.SupportedSymbols[j] == "" {
+				return nil, fmt.Errorf("invalid exchange %q: supported_symbols must not contain empty symbols", ex.ID)
+			}
+		}
+		if seen[ex.ID] {
+			return nil, fmt.Errorf("duplicate exchange ID %q", ex.ID)
+		}
+		seen[ex.ID] = true
+	}
+	return set, nil
+}
+
+// validate checks that an exchange definition can be simulated
+func (e *Exchange) validate() error {
+	switch {
+	case e.ID == "":
+		return errors.New("id is required")
+	case common.NormalizeSymbol(e.ID) == InternalVenueID:
+		return fmt.Errorf("id %s is reserved for internal crosses", InternalVenueID)
+	case e.MinLatency < 0 || e.MaxLatency < e.MinLatency:
+		return errors.New("latency range must satisfy 0 <= min_latency_ms <= max_latency_ms")
+	case e.LiquidityFactor <= 0 || e.LiquidityFactor > 1:
+		return errors.New("liquidity_factor must be in (0, 1]")
+	case e.SuccessRate <= 0 || e.SuccessRate > 1:
+		return errors.New("success_rate must be in (0, 1]")
+	case e.MaxFillQuantity <= 0:
+		return errors.New("max_fill_quantity must be positive")
+	case e.FeeRate < 0:
+		return errors.New("fee_rate must not be negative")
+	}
+	return nil
+}
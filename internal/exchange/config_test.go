@@ -0,0 +1,74 @@
+package exchange
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeConfig writes an exchange config file in the test's temporary directory
+func writeConfig(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "exchanges.json")
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatalf("failed to write exchange config: %v", err)
+	}
+	return path
+}
+
+func TestLoadedExchangesAreTheOnlyVenuesRouted(t *testing.T) {
+	path := writeConfig(t, `[
+		{"id": "ALPHA", "name": "Alpha", "min_latency_ms": 0, "max_latency_ms": 0, "liquidity_factor": 1,
+		 "max_fill_quantity": 60, "success_rate": 1, "fee_rate": 0.001},
+		{"id": "BETA", "name": "Beta", "min_latency_ms": 0, "max_latency_ms": 0, "liquidity_factor": 1,
+		 "max_fill_quantity": 60, "success_rate": 1, "fee_rate": 0.002, "supported_symbols": [" aapl "]}
+	]`)
+	set, err := LoadExchanges(path)
+	if err != nil {
+		t.Fatalf("LoadExchanges: %v", err)
+	}
+	if len(set) != 2 || set[1].SupportedSymbols[0] != "AAPL" {
+		t.Fatalf("loaded %+v, want both venues with normalized symbols", set)
+	}
+	useExchanges(t, set)
+
+	// Neither venue can fill 100 alone, so both are used
+	execution, _, err := ExecuteOrderAcrossExchanges(newLimitOrder(100), 0, 0)
+	if err != nil {
+		t.Fatalf("ExecuteOrderAcrossExchanges: %v", err)
+	}
+	venues := map[string]bool{}
+	for _, fill := range execution.Fills {
+		if fill.ExchangeID != "ALPHA" && fill.ExchangeID != "BETA" {
+			t.Errorf("filled on %s, want only the configured venues", fill.ExchangeID)
+		}
+		venues[fill.ExchangeID] = true
+	}
+	if !venues["ALPHA"] || !venues["BETA"] {
+		t.Errorf("filled on %v, want both configured venues", venues)
+	}
+}
+
+func TestLoadExchangesRejectsInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+	}{
+		{"empty", `[]`},
+		{"malformed", `{"id": "ALPHA"`},
+		{"missing id", `[{"name": "Alpha", "liquidity_factor": 1, "max_fill_quantity": 10, "success_rate": 1}]`},
+		{"bad success rate", `[{"id": "ALPHA", "liquidity_factor": 1, "max_fill_quantity": 10, "success_rate": 2}]`},
+		{"reserved id", `[{"id": "internal", "liquidity_factor": 1, "max_fill_quantity": 10, "success_rate": 1}]`},
+		{"duplicate id", `[{"id": "A", "liquidity_factor": 1, "max_fill_quantity": 10, "success_rate": 1},
+			{"id": "A", "liquidity_factor": 1, "max_fill_quantity": 10, "success_rate": 1}]`},
+	}
+	for _, tt := range tests {
+		if _, err := LoadExchanges(writeConfig(t, tt.contents)); err == nil {
+			t.Errorf("%s: LoadExchanges succeeded, want an error", tt.name)
+		}
+	}
+	if _, err := LoadExchanges(writeConfig(t, `[]`)); !errors.Is(err, ErrNoExchanges) {
+		t.Errorf("LoadExchanges error = %v, want ErrNoExchanges", err)
+	}
+}
@@ -1,32 +1,69 @@
 package exchange
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"math/rand"
 	"time"
 
 	"github.com/rs/zerolog/log"
 	"github.com/ksred/klear-api/internal/types"
+	"github.com/ksred/klear-api/pkg/money"
 )
 
 // Exchange represents a mock trading exchange
 type Exchange struct {
-	ID              string
-	Name            string
-	MinLatency      int // in milliseconds
-	MaxLatency      int
-	LiquidityFactor float64 // 0-1, represents available liquidity
-	SuccessRate     float64 // 0-1, probability of successful execution
-	FeeRate         float64 // percentage of transaction value
+	ID               string   `json:"id"`
+	Name             string   `json:"name"`
+	MinLatency       int      `json:"min_latency_ms"` // in milliseconds
+	MaxLatency       int      `json:"max_latency_ms"`
+	LiquidityFactor  float64  `json:"liquidity_factor"`            // 0-1, represents available liquidity
+	MaxFillQuantity  float64  `json:"max_fill_quantity"`           // maximum quantity available at the top of the book per order
+	SuccessRate      float64  `json:"success_rate"`                // 0-1, probability of successful execution
+	FeeRate          float64  `json:"fee_rate"`                    // percentage of transaction value
+	SupportedSymbols []string `json:"supported_symbols,omitempty"` // symbols the venue trades; empty means every symbol
 }
 
-var mockExchanges = []*Exchange{
+// Supports reports whether the venue trades the symbol
+func (e *Exchange) Supports(symbol string) bool {
+	if len(e.SupportedSymbols) == 0 {
+		return true
+	}
+	for _, supported := range e.SupportedSymbols {
+		if supported == symbol {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	// ErrWouldCross is returned when a post-only order would take liquidity
+	ErrWouldCross = errors.New("post-only order would cross the market")
+	// ErrSlippageExceeded is returned when a market order fill would be worse than the slippage cap allows
+	ErrSlippageExceeded = errors.New("fill price exceeds maximum slippage")
+	// ErrBelowLotSize is returned when an order is too small to fill a single lot
+	ErrBelowLotSize = errors.New("order quantity is below the lot size")
+	// ErrNoQuantityExecuted is returned when the fills for an order add up to no quantity,
+	// so there is no execution to record and no average price to compute
+	ErrNoQuantityExecuted = errors.New("no quantity executed")
+	// ErrSymbolNotSupported is returned when no venue trades the order's symbol
+	ErrSymbolNotSupported = errors.New("no exchange supports the symbol")
+)
+
+// maxRoutingAttempts bounds the number of venue attempts made for a single order
+const maxRoutingAttempts = 6
+
+// defaultExchanges is the built-in venue set used unless an exchange config file is loaded
+var defaultExchanges = []*Exchange{
 	{
 		ID:              "EXCH1",
 		Name:            "Primary Exchange",
 		MinLatency:      5,
 		MaxLatency:      30,
 		LiquidityFactor: 0.9,
+		MaxFillQuantity: 5000,
 		SuccessRate:     0.95,
 		FeeRate:         0.001, // 0.1%
 	},
@@ -36,6 +73,7 @@ var mockExchanges = []*Exchange{
 		MinLatency:      10,
 		MaxLatency:      50,
 		LiquidityFactor: 0.7,
+		MaxFillQuantity: 2500,
 		SuccessRate:     0.90,
 		FeeRate:         0.0008, // 0.08%
 	},
@@ -45,6 +83,7 @@ var mockExchanges = []*Exchange{
 		MinLatency:      15,
 		MaxLatency:      70,
 		LiquidityFactor: 0.5,
+		MaxFillQuantity: 1000,
 		SuccessRate:     0.85,
 		FeeRate:         0.0005, // 0.05%
 	},
@@ -54,13 +93,17 @@ var mockExchanges = []*Exchange{
 		MinLatency:      20,
 		MaxLatency:      100,
 		LiquidityFactor: 0.3,
+		MaxFillQuantity: 500,
 		SuccessRate:     0.75,
 		FeeRate:         0.0003, // 0.03%
 	},
 }
 
 // ExecuteOrder simulates order execution on a specific exchange
-func (e *Exchange) ExecuteOrder(order *types.Order) (*types.ExchangeFill, error) {
+// maxSlippage caps the adverse price move accepted on market orders as a fraction of the
+// order price; 0 disables the cap
+// lotSize rounds the filled quantity down to whole lots; 0 allows any quantity
+func (e *Exchange) ExecuteOrder(order *types.Order, maxSlippage, lotSize float64) (*types.ExchangeFill, error) {
 	logger := log.With().
 		Str("exchange_id", e.ID).
 		Str("order_id", order.OrderID).
@@ -71,6 +114,10 @@ func (e *Exchange) ExecuteOrder(order *types.Order) (*types.ExchangeFill, error)
 
 	logger.Info().Msg("attempting to execute order")
 
+	if instantFills.Load() {
+		return e.instantFill(order, lotSize)
+	}
+
 	// Simulate random latency
 	latency := rand.Intn(e.MaxLatency-e.MinLatency+1) + e.MinLatency
 	logger.Debug().Int("latency_ms", latency).Msg("simulated network latency")
@@ -91,24 +138,63 @@ func (e *Exchange) ExecuteOrder(order *types.Order) (*types.ExchangeFill, error)
 		Float64("executed_price", priceVariance).
 		Msg("price variance applied")
 
-	// Adjust quantity based on liquidity
-	executedQty := order.Quantity
+	// Reject market order fills that move against the order by more than the cap
+	if maxSlippage > 0 && order.OrderType == "MARKET" && order.Price > 0 {
+		slippage := adverseSlippage(string(order.Side), order.Price, priceVariance)
+		if slippage > maxSlippage {
+			logger.Warn().
+				Float64("slippage", slippage).
+				Float64("max_slippage", maxSlippage).
+				Msg("fill rejected, adverse slippage exceeds cap")
+			return nil, fmt.Errorf("%w on exchange %s: %.4f > %.4f", ErrSlippageExceeded, e.ID, slippage, maxSlippage)
+		}
+	}
+
+	// Cap the fill at the depth available on the book. When liquidity is thin
+	// only a fraction of the usual depth is available
+	availableDepth := e.MaxFillQuantity
 	if rand.Float64() > e.LiquidityFactor {
-		executedQty = order.Quantity * e.LiquidityFactor
+		availableDepth = e.MaxFillQuantity * e.LiquidityFactor
 		logger.Debug().
 			Float64("liquidity_factor", e.LiquidityFactor).
+			Float64("available_depth", availableDepth).
+			Msg("book depth reduced due to thin liquidity")
+	}
+
+	executedQty := math.Min(order.Quantity, availableDepth)
+	if executedQty < order.Quantity {
+		logger.Debug().
 			Float64("original_quantity", order.Quantity).
 			Float64("executed_quantity", executedQty).
-			Msg("quantity adjusted due to liquidity")
-		
-		if executedQty == 0 {
-			logger.Error().Msg("insufficient liquidity for execution")
-			return nil, fmt.Errorf("insufficient liquidity on exchange %s", e.ID)
-		}
+			Float64("available_depth", availableDepth).
+			Msg("quantity capped at available depth")
+	}
+
+	// Fills are made in whole lots, so depth short of a lot cannot be used
+	if lotQty := RoundDownToLot(executedQty, lotSize); lotQty < executedQty {
+		logger.Debug().
+			Float64("lot_size", lotSize).
+			Float64("unrounded_quantity", executedQty).
+			Float64("executed_quantity", lotQty).
+			Msg("quantity rounded down to whole lots")
+		executedQty = lotQty
+	}
+
+	if executedQty <= 0 {
+		logger.Error().Msg("insufficient liquidity for execution")
+		return nil, fmt.Errorf("insufficient liquidity on exchange %s", e.ID)
 	}
 
 	// Calculate fee amount
 	feeAmount := priceVariance * executedQty * e.FeeRate
+	if err := errors.Join(
+		money.CheckFinite("fill price", priceVariance),
+		money.CheckFinite("fill quantity", executedQty),
+		money.CheckFinite("fee amount", feeAmount),
+	); err != nil {
+		logger.Error().Err(err).Msg("rejecting fill with non-finite values")
+		return nil, fmt.Errorf("fill rejected on exchange %s: %w", e.ID, err)
+	}
 
 	fill := &types.ExchangeFill{
 		FillID:       fmt.Sprintf("FILL-%s-%d", e.ID, rand.Int63()),
@@ -118,7 +204,7 @@ func (e *Exchange) ExecuteOrder(order *types.Order) (*types.ExchangeFill, error)
 		Quantity:     executedQty,
 		FeeRate:      e.FeeRate,
 		FeeAmount:    feeAmount,
-		CreatedAt:    time.Now(),
+		CreatedAt:    now(),
 	}
 
 	logger.Info().
@@ -131,12 +217,60 @@ func (e *Exchange) ExecuteOrder(order *types.Order) (*types.ExchangeFill, error)
 	return fill, nil
 }
 
-// GetBestExchange selects the best exchange based on liquidity and success rate
-func GetBestExchange() *Exchange {
-	logger := log.With().Str("component", "exchange_selection").Logger()
-	
+// instantFill fills the order's whole quantity, in whole lots, at exactly the order price
+// with no latency, failures or depth limits. Fees are charged as usual
+func (e *Exchange) instantFill(order *types.Order, lotSize float64) (*types.ExchangeFill, error) {
+	executedQty := RoundDownToLot(order.Quantity, lotSize)
+	if executedQty <= 0 {
+		return nil, fmt.Errorf("%w: quantity %v is smaller than the lot size %v", ErrBelowLotSize, order.Quantity, lotSize)
+	}
+
+	fill := &types.ExchangeFill{
+		FillID:       fmt.Sprintf("FILL-%s-%d", e.ID, rand.Int63()),
+		ExchangeID:   e.ID,
+		ExchangeName: e.Name,
+		Price:        order.Price,
+		Quantity:     executedQty,
+		FeeRate:      e.FeeRate,
+		FeeAmount:    order.Price * executedQty * e.FeeRate,
+		CreatedAt:    now(),
+	}
+
+	log.Debug().
+		Str("exchange_id", e.ID).
+		Str("order_id", order.OrderID).
+		Str("fill_id", fill.FillID).
+		Float64("executed_quantity", fill.Quantity).
+		Msg("order filled instantly")
+
+	return fill, nil
+}
+
+// GetBestExchange selects the best exchange trading the symbol based on liquidity and success rate
+// Returns nil if no exchange trades the symbol
+func GetBestExchange(symbol string) *Exchange {
+	return selectExchange(symbol, nil)
+}
+
+// selectExchange picks an exchange trading the symbol weighted by liquidity and success rate,
+// skipping any exchange in excluded. Returns nil if every such exchange is excluded
+func selectExchange(symbol string, excluded map[string]bool) *Exchange {
+	logger := log.With().Str("component", "exchange_selection").Str("symbol", symbol).Logger()
+
+	exchanges := activeExchanges()
+	candidates := make([]*Exchange, 0, len(exchanges))
+	for _, ex := range exchanges {
+		if !excluded[ex.ID] && ex.Supports(symbol) {
+			candidates = append(candidates, ex)
+		}
+	}
+	if len(candidates) == 0 {
+		logger.Warn().Msg("no exchanges available for routing")
+		return nil
+	}
+
 	totalWeight := 0.0
-	for _, ex := range mockExchanges {
+	for _, ex := range candidates {
 		totalWeight += ex.LiquidityFactor * ex.SuccessRate
 	}
 
@@ -146,9 +280,10 @@ func GetBestExchange() *Exchange {
 	logger.Debug().
 		Float64("total_weight", totalWeight).
 		Float64("random_choice", choice).
+		Int("candidates", len(candidates)).
 		Msg("calculating best exchange")
 
-	for _, ex := range mockExchanges {
+	for _, ex := range candidates {
 		currentWeight += ex.LiquidityFactor * ex.SuccessRate
 		if currentWeight >= choice {
 			logger.Info().
@@ -160,12 +295,26 @@ func GetBestExchange() *Exchange {
 		}
 	}
 
-	logger.Warn().Msg("falling back to primary exchange")
-	return mockExchanges[0]
+	logger.Warn().Msg("falling back to first available exchange")
+	return candidates[0]
+}
+
+// supportedAnywhere reports whether any venue trades the symbol
+func supportedAnywhere(symbol string) bool {
+	for _, ex := range activeExchanges() {
+		if ex.Supports(symbol) {
+			return true
+		}
+	}
+	return false
 }
 
 // ExecuteOrderAcrossExchanges attempts to execute an order across multiple exchanges
-func ExecuteOrderAcrossExchanges(order *types.Order) (*types.Execution, error) {
+// Venue attempts whose market order fill exceeds maxSlippage are rejected and routed elsewhere
+// Every fill is a whole number of lots of lotSize (0 allows any quantity), so any quantity
+// beyond the last whole lot of the order is left unfilled
+// The venue attempts that failed are returned whether or not the order was executed
+func ExecuteOrderAcrossExchanges(order *types.Order, maxSlippage, lotSize float64) (*types.Execution, []types.VenueAttempt, error) {
 	logger := log.With().
 		Str("order_id", order.OrderID).
 		Float64("total_quantity", order.Quantity).
@@ -174,24 +323,72 @@ func ExecuteOrderAcrossExchanges(order *types.Order) (*types.Execution, error) {
 
 	logger.Info().Msg("starting cross-exchange execution")
 
-	remainingQty := order.Quantity
+	// Exchange fills always take liquidity, so a post-only order can never be
+	// filled immediately and is left resting instead
+	if order.PostOnly {
+		logger.Warn().Msg("rejecting immediate execution of post-only order")
+		return nil, nil, ErrWouldCross
+	}
+
+	if !supportedAnywhere(order.Symbol) {
+		logger.Warn().Str("symbol", order.Symbol).Msg("no exchange supports the symbol")
+		return nil, nil, fmt.Errorf("%w: %s", ErrSymbolNotSupported, order.Symbol)
+	}
+
+	remainingQty := RoundDownToLot(order.Quantity, lotSize)
+	if remainingQty <= 0 {
+		logger.Warn().Float64("lot_size", lotSize).Msg("order quantity is smaller than one lot")
+		return nil, nil, fmt.Errorf("%w: quantity %v is smaller than the lot size %v", ErrBelowLotSize, order.Quantity, lotSize)
+	}
 	var fills []*types.ExchangeFill
+	var failedAttempts []types.VenueAttempt
 	totalExecutedQty := 0.0
 	weightedPrice := 0.0
 
-	for i := 0; i < 3 && remainingQty > 0; i++ {
+	// Venues whose depth has been consumed are excluded so the remainder sweeps to the next venue
+	exhausted := make(map[string]bool)
+
+	// Iceberg orders are worked in display-sized slices, so allow an attempt per slice
+	maxAttempts := maxRoutingAttempts
+	if order.DisplayQuantity > 0 {
+		maxAttempts += int(math.Ceil(order.Quantity / order.DisplayQuantity))
+		logger.Debug().
+			Float64("display_quantity", order.DisplayQuantity).
+			Int("max_attempts", maxAttempts).
+			Msg("working iceberg order in slices")
+	}
+
+	for i := 0; i < maxAttempts && remainingQty > 0; i++ {
 		logger.Debug().
 			Int("attempt", i+1).
 			Float64("remaining_quantity", remainingQty).
 			Msg("attempting execution on next exchange")
 
-		exchange := GetBestExchange()
+		exchange := selectExchange(order.Symbol, exhausted)
+		if exchange == nil {
+			logger.Warn().Msg("all exchanges exhausted before order was filled")
+			break
+		}
 
 		attemptOrder := *order
 		attemptOrder.Quantity = remainingQty
+		if order.DisplayQuantity > 0 {
+			attemptOrder.Quantity = math.Min(remainingQty, order.DisplayQuantity)
+		}
 
-		fill, err := exchange.ExecuteOrder(&attemptOrder)
+		attemptStart := time.Now()
+		fill, err := exchange.ExecuteOrder(&attemptOrder, maxSlippage, lotSize)
+		latency := time.Since(attemptStart)
 		if err != nil {
+			venueStats.record(exchange.ID, false, 0, 0, false, latency)
+			failedAttempts = append(failedAttempts, types.VenueAttempt{
+				AttemptID:    fmt.Sprintf("ATT-%s-%d", exchange.ID, rand.Int63()),
+				OrderID:      order.OrderID,
+				ExchangeID:   exchange.ID,
+				ExchangeName: exchange.Name,
+				Reason:       err.Error(),
+				CreatedAt:    now(),
+			})
 			logger.Warn().
 				Err(err).
 				Str("exchange_id", exchange.ID).
@@ -199,6 +396,17 @@ func ExecuteOrderAcrossExchanges(order *types.Order) (*types.Execution, error) {
 			continue
 		}
 
+		priced := order.Price > 0
+		slippage := 0.0
+		if priced {
+			slippage = adverseSlippage(string(order.Side), order.Price, fill.Price)
+		}
+		venueStats.record(exchange.ID, true, fill.Quantity, slippage, priced, latency)
+
+		// A fill short of the requested quantity means the venue's depth is used up
+		if fill.Quantity < attemptOrder.Quantity {
+			exhausted[exchange.ID] = true
+		}
 		fills = append(fills, fill)
 		totalExecutedQty += fill.Quantity
 		weightedPrice += fill.Price * fill.Quantity
@@ -212,11 +420,22 @@ func ExecuteOrderAcrossExchanges(order *types.Order) (*types.Execution, error) {
 
 	if len(fills) == 0 {
 		logger.Error().Msg("failed to execute order on any exchange")
-		return nil, fmt.Errorf("failed to execute order on any exchange")
+		return nil, failedAttempts, fmt.Errorf("failed to execute order on any exchange")
+	}
+
+	// Guard the average price below: venues reject empty fills individually, but an execution
+	// must never be recorded, or priced, without quantity whatever the venues return
+	if totalExecutedQty <= 0 {
+		logger.Error().Float64("total_quantity", totalExecutedQty).Int("fills", len(fills)).Msg("fills executed no quantity")
+		return nil, failedAttempts, fmt.Errorf("%w: %d fills for order %s totalled %v", ErrNoQuantityExecuted, len(fills), order.OrderID, totalExecutedQty)
 	}
 
 	// Calculate average execution price
 	averagePrice := weightedPrice / totalExecutedQty
+	if err := money.CheckFinite("average price", averagePrice); err != nil {
+		logger.Error().Err(err).Msg("rejecting execution with non-finite average price")
+		return nil, failedAttempts, err
+	}
 
 	execution := &types.Execution{
 		ExecutionID:   fmt.Sprintf("EXEC-%d", rand.Int63()),
@@ -226,8 +445,8 @@ func ExecuteOrderAcrossExchanges(order *types.Order) (*types.Execution, error) {
 		Side:          order.Side,
 		Status:        "COMPLETED",
 		Fills:         make([]types.ExchangeFill, len(fills)),
-		CreatedAt:     time.Now(),
-		UpdatedAt:     time.Now(),
+		CreatedAt:     now(),
+		UpdatedAt:     now(),
 	}
 
 	// Convert fill pointers to values and prepare fill details for logging
@@ -257,7 +476,18 @@ func ExecuteOrderAcrossExchanges(order *types.Order) (*types.Execution, error) {
 		Float64("total_fees", calculateTotalFees(fills)).
 		Msg("cross-exchange execution completed")
 
-	return execution, nil
+	return execution, failedAttempts, nil
+}
+
+// RoundDownToLot rounds quantity down to a whole number of lots of lotSize
+// The arithmetic is done in exact quantity units so a whole lot never rounds to just under itself
+func RoundDownToLot(quantity, lotSize float64) float64 {
+	lotUnits := money.ToUnits(lotSize)
+	if lotUnits <= 0 {
+		return quantity
+	}
+	units := money.ToUnits(quantity)
+	return money.FromUnits(units - units%lotUnits)
 }
 
 // Helper function to calculate total fees
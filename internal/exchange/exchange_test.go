@@ -0,0 +1,447 @@
+package exchange
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ksred/klear-api/internal/types"
+	"github.com/ksred/klear-api/pkg/common"
+	"github.com/ksred/klear-api/pkg/money"
+)
+
+// useExchanges routes orders across the given venues for the rest of the test
+func useExchanges(t *testing.T, set []*Exchange) {
+	t.Helper()
+	previous := activeExchanges()
+	SetExchanges(set)
+	t.Cleanup(func() { SetExchanges(previous) })
+}
+
+// reliableExchange returns a venue with no latency or failures and the given fixed depth
+func reliableExchange(id string, depth float64) *Exchange {
+	return &Exchange{
+		ID:              id,
+		Name:            id,
+		LiquidityFactor: 1,
+		MaxFillQuantity: depth,
+		SuccessRate:     1,
+		FeeRate:         0.001,
+	}
+}
+
+func newLimitOrder(quantity float64) *types.Order {
+	return &types.Order{
+		OrderID:   "ORDER-1",
+		ClientID:  "client-1",
+		Symbol:    "AAPL",
+		Side:      "BUY",
+		OrderType: "LIMIT",
+		Quantity:  quantity,
+		Price:     100,
+	}
+}
+
+func TestLargeOrderSweepsVenuesByDepth(t *testing.T) {
+	depths := map[string]float64{"DEEP": 300, "MID": 200, "THIN": 100}
+	useExchanges(t, []*Exchange{
+		reliableExchange("DEEP", depths["DEEP"]),
+		reliableExchange("MID", depths["MID"]),
+		reliableExchange("THIN", depths["THIN"]),
+	})
+
+	execution, _, err := ExecuteOrderAcrossExchanges(newLimitOrder(600), 0, 0)
+	if err != nil {
+		t.Fatalf("ExecuteOrderAcrossExchanges: %v", err)
+	}
+	if execution.TotalQuantity != 600 {
+		t.Errorf("executed quantity = %v, want 600", execution.TotalQuantity)
+	}
+
+	// No venue has the depth to fill the order alone, so each fills its whole depth once
+	filled := map[string]float64{}
+	for _, fill := range execution.Fills {
+		filled[fill.ExchangeID] += fill.Quantity
+	}
+	if len(execution.Fills) != len(depths) {
+		t.Errorf("fills = %d, want one per venue", len(execution.Fills))
+	}
+	for id, depth := range depths {
+		if filled[id] != depth {
+			t.Errorf("%s filled %v, want its depth %v", id, filled[id], depth)
+		}
+	}
+}
+
+func TestOrderBeyondTotalDepthFillsPartially(t *testing.T) {
+	useExchanges(t, []*Exchange{
+		reliableExchange("A", 150),
+		reliableExchange("B", 250),
+	})
+
+	execution, _, err := ExecuteOrderAcrossExchanges(newLimitOrder(1000), 0, 0)
+	if err != nil {
+		t.Fatalf("ExecuteOrderAcrossExchanges: %v", err)
+	}
+	if execution.TotalQuantity != 400 {
+		t.Errorf("executed quantity = %v, want the 400 available across venues", execution.TotalQuantity)
+	}
+}
+
+func TestExchangeFillCappedAtDepth(t *testing.T) {
+	exchange := reliableExchange("A", 250)
+
+	fill, err := exchange.ExecuteOrder(newLimitOrder(1000), 0, 0)
+	if err != nil {
+		t.Fatalf("ExecuteOrder: %v", err)
+	}
+	if fill.Quantity != 250 {
+		t.Errorf("fill quantity = %v, want the venue depth 250", fill.Quantity)
+	}
+}
+
+func TestIcebergOrderFillsInDisplaySlices(t *testing.T) {
+	useExchanges(t, []*Exchange{reliableExchange("DEEP", 10000)})
+
+	order := newLimitOrder(1000)
+	order.DisplayQuantity = 150
+	execution, _, err := ExecuteOrderAcrossExchanges(order, 0, 0)
+	if err != nil {
+		t.Fatalf("ExecuteOrderAcrossExchanges: %v", err)
+	}
+	if execution.TotalQuantity != 1000 {
+		t.Errorf("executed quantity = %v, want 1000", execution.TotalQuantity)
+	}
+
+	// Six full slices of 150 and a final slice of the 100 remaining
+	if len(execution.Fills) != 7 {
+		t.Fatalf("fills = %d, want 7 slices", len(execution.Fills))
+	}
+	for i, fill := range execution.Fills {
+		want := 150.0
+		if i == len(execution.Fills)-1 {
+			want = 100
+		}
+		if fill.Quantity != want {
+			t.Errorf("slice %d filled %v, want %v", i+1, fill.Quantity, want)
+		}
+	}
+}
+
+func TestPostOnlyOrderIsNeverFilled(t *testing.T) {
+	useExchanges(t, []*Exchange{reliableExchange("A", 1000)})
+
+	order := newLimitOrder(100)
+	order.PostOnly = true
+	execution, attempts, err := ExecuteOrderAcrossExchanges(order, 0, 0)
+	if !errors.Is(err, ErrWouldCross) {
+		t.Fatalf("ExecuteOrderAcrossExchanges error = %v, want ErrWouldCross", err)
+	}
+	if execution != nil || len(attempts) != 0 {
+		t.Errorf("execution = %v with %d venue attempts, want no venue touched", execution, len(attempts))
+	}
+}
+
+// newMarketOrder returns a market buy with an arrival price of 100 to measure slippage against
+func newMarketOrder(quantity float64) *types.Order {
+	order := newLimitOrder(quantity)
+	order.OrderType = "MARKET"
+	return order
+}
+
+func TestTightSlippageCapRejectsAdverseFills(t *testing.T) {
+	exchange := reliableExchange("A", 1000)
+	const maxSlippage = 1e-9
+
+	// Fills vary by up to 2% either way, so a cap of almost nothing rejects roughly half of them
+	filled, rejected := 0, 0
+	for i := 0; i < 200; i++ {
+		fill, err := exchange.ExecuteOrder(newMarketOrder(100), maxSlippage, 0)
+		if err != nil {
+			if !errors.Is(err, ErrSlippageExceeded) {
+				t.Fatalf("ExecuteOrder error = %v, want ErrSlippageExceeded", err)
+			}
+			rejected++
+			continue
+		}
+		if fill.Price > 100*(1+maxSlippage) {
+			t.Errorf("fill at %v accepted above the cap", fill.Price)
+		}
+		filled++
+	}
+	if filled == 0 || rejected == 0 {
+		t.Errorf("%d fills and %d rejections, want both price improvements filled and adverse fills rejected", filled, rejected)
+	}
+}
+
+func TestTightSlippageCapReroutesRejectedFills(t *testing.T) {
+	useExchanges(t, []*Exchange{reliableExchange("A", 1000), reliableExchange("B", 1000)})
+	const maxSlippage = 1e-9
+
+	rerouted := false
+	for i := 0; i < 50 && !rerouted; i++ {
+		execution, attempts, err := ExecuteOrderAcrossExchanges(newMarketOrder(100), maxSlippage, 0)
+		for _, attempt := range attempts {
+			if !strings.Contains(attempt.Reason, ErrSlippageExceeded.Error()) {
+				t.Fatalf("attempt on %s failed with %q, want the slippage cap", attempt.ExchangeID, attempt.Reason)
+			}
+		}
+		if err != nil {
+			continue
+		}
+		for _, fill := range execution.Fills {
+			if fill.Price > 100*(1+maxSlippage) {
+				t.Errorf("fill on %s at %v accepted above the cap", fill.ExchangeID, fill.Price)
+			}
+		}
+		rerouted = len(attempts) > 0
+	}
+	if !rerouted {
+		t.Error("no order was filled after a rejected venue attempt, want rejected fills routed elsewhere")
+	}
+}
+
+func TestLooseSlippageCapAcceptsFills(t *testing.T) {
+	exchange := reliableExchange("A", 1000)
+
+	// Fills never move more than 2%, inside a 5% cap
+	for i := 0; i < 100; i++ {
+		if _, err := exchange.ExecuteOrder(newMarketOrder(100), 0.05, 0); err != nil {
+			t.Fatalf("ExecuteOrder: %v", err)
+		}
+	}
+}
+
+// resetVenueStats starts the test with no venue stats recorded
+func resetVenueStats(t *testing.T) {
+	t.Helper()
+	previous := venueStats
+	venueStats = &venueTracker{venues: make(map[string]*venueCounters)}
+	t.Cleanup(func() { venueStats = previous })
+}
+
+func TestVenueStatsCountSuccessesAndFailures(t *testing.T) {
+	resetVenueStats(t)
+	// A venue with no depth fails every attempt routed to it
+	useExchanges(t, []*Exchange{reliableExchange("DOWN", 0), reliableExchange("UP", 1000)})
+
+	fills, failures, volume := 0, 0, 0.0
+	for i := 0; i < 20; i++ {
+		execution, attempts, err := ExecuteOrderAcrossExchanges(newLimitOrder(100), 0, 0)
+		for _, attempt := range attempts {
+			if attempt.ExchangeID != "DOWN" {
+				t.Fatalf("attempt on %s failed: %s", attempt.ExchangeID, attempt.Reason)
+			}
+		}
+		failures += len(attempts)
+		if err != nil {
+			continue
+		}
+		for _, fill := range execution.Fills {
+			fills++
+			volume += fill.Quantity
+		}
+	}
+	if failures == 0 || fills == 0 {
+		t.Fatalf("%d fills and %d failures routed, want both venues tried", fills, failures)
+	}
+
+	stats := map[string]VenueStats{}
+	for _, s := range GetVenueStats() {
+		stats[s.ExchangeID] = s
+	}
+	up, down := stats["UP"], stats["DOWN"]
+	if up.Attempts != fills || up.Fills != fills || up.Failures != 0 || up.FillRate != 1 || up.TotalVolume != volume {
+		t.Errorf("UP stats = %+v, want %d fills totalling %v and no failures", up, fills, volume)
+	}
+	if down.Attempts != failures || down.Fills != 0 || down.Failures != failures || down.FillRate != 0 {
+		t.Errorf("DOWN stats = %+v, want %d failures and no fills", down, failures)
+	}
+}
+
+// isWholeLots reports whether quantity is a whole number of lots, compared in exact quantity units
+func isWholeLots(quantity, lotSize float64) bool {
+	return money.ToUnits(quantity)%money.ToUnits(lotSize) == 0
+}
+
+func TestThinLiquidityFillsRoundDownToWholeLots(t *testing.T) {
+	// On a thin book only a third of the 100 depth is available: 33.33 shares, or three lots of 10
+	exchange := reliableExchange("THIN", 100)
+	exchange.LiquidityFactor = 1.0 / 3
+	const lotSize = 10
+
+	thin := false
+	for i := 0; i < 100; i++ {
+		fill, err := exchange.ExecuteOrder(newLimitOrder(100), 0, lotSize)
+		if err != nil {
+			t.Fatalf("ExecuteOrder: %v", err)
+		}
+		if !isWholeLots(fill.Quantity, lotSize) {
+			t.Fatalf("fill of %v is not a whole number of %v lots", fill.Quantity, lotSize)
+		}
+		thin = thin || fill.Quantity == 30
+	}
+	if !thin {
+		t.Error("no fill was made on the thin book, want 30 shares filled from its 33.33 depth")
+	}
+}
+
+func TestExecutionRecordsOnlyWholeLots(t *testing.T) {
+	thin := reliableExchange("THIN", 100)
+	thin.LiquidityFactor = 1.0 / 3
+	useExchanges(t, []*Exchange{thin, reliableExchange("DEEP", 1000)})
+	const lotSize = 10
+
+	for i := 0; i < 20; i++ {
+		execution, _, err := ExecuteOrderAcrossExchanges(newLimitOrder(105), 0, lotSize)
+		if err != nil {
+			t.Fatalf("ExecuteOrderAcrossExchanges: %v", err)
+		}
+		// The 5 shares beyond the last whole lot are left unfilled
+		if execution.TotalQuantity != 100 {
+			t.Errorf("executed quantity = %v, want the 100 shares in whole lots", execution.TotalQuantity)
+		}
+		for _, fill := range execution.Fills {
+			if !isWholeLots(fill.Quantity, lotSize) {
+				t.Errorf("fill on %s of %v is not a whole number of %v lots", fill.ExchangeID, fill.Quantity, lotSize)
+			}
+		}
+	}
+
+	if _, _, err := ExecuteOrderAcrossExchanges(newLimitOrder(5), 0, lotSize); !errors.Is(err, ErrBelowLotSize) {
+		t.Errorf("order below one lot error = %v, want ErrBelowLotSize", err)
+	}
+}
+
+func TestExecutionWithNoFilledQuantityIsRejected(t *testing.T) {
+	tests := []struct {
+		name    string
+		depth   float64
+		lotSize float64
+	}{
+		{"venues without depth", 0, 0},
+		{"depth short of a lot", 0.5, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useExchanges(t, []*Exchange{reliableExchange("EX1", tt.depth), reliableExchange("EX2", tt.depth)})
+
+			execution, attempts, err := ExecuteOrderAcrossExchanges(newLimitOrder(10), 0.05, tt.lotSize)
+			if err == nil {
+				t.Fatalf("execution = %+v, want a rejection when no venue fills any quantity", execution)
+			}
+			if execution != nil {
+				t.Errorf("execution = %+v, want none", execution)
+			}
+			if len(attempts) == 0 {
+				t.Fatalf("failed attempts = none, want each empty venue recorded")
+			}
+			for _, attempt := range attempts {
+				if !strings.Contains(attempt.Reason, "insufficient liquidity") {
+					t.Errorf("attempt on %s failed with %q, want insufficient liquidity", attempt.ExchangeID, attempt.Reason)
+				}
+			}
+		})
+	}
+}
+
+func TestInstantFillsAtOrderPriceWithoutLatencyOrFailures(t *testing.T) {
+	// A venue that would always fail, after a long delay, in realistic mode
+	unreliable := &Exchange{ID: "SLOW", Name: "Slow", MinLatency: 500, MaxLatency: 500, LiquidityFactor: 0.1,
+		MaxFillQuantity: 1, SuccessRate: 0, FeeRate: 0.001}
+	useExchanges(t, []*Exchange{unreliable})
+	SetInstantFills(true)
+	t.Cleanup(func() { SetInstantFills(false) })
+
+	start := time.Now()
+	execution, attempts, err := ExecuteOrderAcrossExchanges(newLimitOrder(250), 0.05, 0)
+	elapsed := time.Since(start)
+	if err != nil {
+		t.Fatalf("ExecuteOrderAcrossExchanges: %v", err)
+	}
+	if elapsed > 100*time.Millisecond {
+		t.Errorf("execution took %s, want no simulated latency", elapsed)
+	}
+	if len(attempts) != 0 {
+		t.Errorf("failed attempts = %+v, want none", attempts)
+	}
+	if execution.TotalQuantity != 250 || execution.AveragePrice != 100 || len(execution.Fills) != 1 {
+		t.Errorf("execution = %v at %v in %d fills, want all 250 in one fill at exactly 100",
+			execution.TotalQuantity, execution.AveragePrice, len(execution.Fills))
+	}
+	if fee := execution.Fills[0].FeeAmount; fee != 25 {
+		t.Errorf("fill fee = %v, want 25 charged as usual", fee)
+	}
+}
+
+func TestExecutionStampedByClock(t *testing.T) {
+	useExchanges(t, []*Exchange{reliableExchange("EXCH1", 1000)})
+	executedAt := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	SetClock(common.FixedClock{Time: executedAt})
+	t.Cleanup(func() { SetClock(common.RealClock{}) })
+
+	execution, _, err := ExecuteOrderAcrossExchanges(newLimitOrder(100), 0, 0)
+	if err != nil {
+		t.Fatalf("ExecuteOrderAcrossExchanges: %v", err)
+	}
+	if !execution.CreatedAt.Equal(executedAt) || !execution.UpdatedAt.Equal(executedAt) {
+		t.Errorf("execution created at %s, updated at %s, want the clock's %s", execution.CreatedAt, execution.UpdatedAt, executedAt)
+	}
+	for _, fill := range execution.Fills {
+		if !fill.CreatedAt.Equal(executedAt) {
+			t.Errorf("fill %s created at %s, want the clock's %s", fill.FillID, fill.CreatedAt, executedAt)
+		}
+	}
+
+	// Venue stats roll over on the clock's day
+	for _, stats := range GetVenueStats() {
+		if want := executedAt.Truncate(24 * time.Hour); !stats.Since.Equal(want) {
+			t.Errorf("venue stats since %s, want the clock's day %s", stats.Since, want)
+		}
+	}
+}
+
+func TestOrderRoutedOnlyToVenuesSupportingTheSymbol(t *testing.T) {
+	deep := reliableExchange("DEEP", 1000)
+	deep.SupportedSymbols = []string{"MSFT"}
+	only := reliableExchange("ONLY", 50)
+	only.SupportedSymbols = []string{"AAPL"}
+	// DEEP could fill the whole order, but it does not trade AAPL
+	useExchanges(t, []*Exchange{deep, only})
+
+	for i := 0; i < 20; i++ {
+		if best := GetBestExchange("AAPL"); best == nil || best.ID != "ONLY" {
+			t.Fatalf("GetBestExchange(AAPL) = %v, want ONLY", best)
+		}
+	}
+
+	execution, _, err := ExecuteOrderAcrossExchanges(newLimitOrder(200), 0, 0)
+	if err != nil {
+		t.Fatalf("ExecuteOrderAcrossExchanges: %v", err)
+	}
+	if execution.TotalQuantity != 50 {
+		t.Errorf("executed quantity = %v, want ONLY's depth of 50", execution.TotalQuantity)
+	}
+	for _, fill := range execution.Fills {
+		if fill.ExchangeID != "ONLY" {
+			t.Errorf("fill on %s, which does not trade AAPL", fill.ExchangeID)
+		}
+	}
+}
+
+func TestOrderForUnsupportedSymbolIsRejected(t *testing.T) {
+	venue := reliableExchange("MSFT-ONLY", 1000)
+	venue.SupportedSymbols = []string{"MSFT"}
+	useExchanges(t, []*Exchange{venue})
+
+	if best := GetBestExchange("AAPL"); best != nil {
+		t.Errorf("GetBestExchange(AAPL) = %s, want nil", best.ID)
+	}
+	if _, _, err := ExecuteOrderAcrossExchanges(newLimitOrder(100), 0, 0); !errors.Is(err, ErrSymbolNotSupported) {
+		t.Errorf("error = %v, want ErrSymbolNotSupported", err)
+	}
+	if _, err := BestQuote("AAPL", 100, nil); !errors.Is(err, ErrSymbolNotSupported) {
+		t.Errorf("BestQuote error = %v, want ErrSymbolNotSupported", err)
+	}
+}
@@ -0,0 +1,39 @@
+package exchange
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/ksred/klear-api/internal/types"
+)
+
+// InternalVenueID is the venue of fills matched internally against another client's order
+// instead of being routed to an exchange. Configured exchanges may not use it
+const InternalVenueID = "INTERNAL"
+
+// internalVenueName is the venue name recorded on internal fills
+const internalVenueName = "Internal Cross"
+
+// CrossesWithinSlippage reports whether the order may be crossed at price. Limit orders are only
+// matched at or through their limit, so only market orders are checked, against maxSlippage
+func CrossesWithinSlippage(order *types.Order, price, maxSlippage float64) bool {
+	if maxSlippage <= 0 || order.OrderType != "MARKET" || order.Price <= 0 {
+		return true
+	}
+	return adverseSlippage(order.Side, order.Price, price) <= maxSlippage
+}
+
+// InternalFill builds the fill of one side of an internal cross, charged at feeRate
+// Both sides of a cross get their own fill at the same price and quantity
+func InternalFill(price, quantity, feeRate float64) *types.ExchangeFill {
+	return &types.ExchangeFill{
+		FillID:       fmt.Sprintf("FILL-%s-%d", InternalVenueID, rand.Int63()),
+		ExchangeID:   InternalVenueID,
+		ExchangeName: internalVenueName,
+		Price:        price,
+		Quantity:     quantity,
+		FeeRate:      feeRate,
+		FeeAmount:    price * quantity * feeRate,
+		CreatedAt:    now(),
+	}
+}
@@ -0,0 +1,99 @@
+package exchange
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// ErrNoVenues is returned when a quote is requested but no exchanges are configured
+var ErrNoVenues = errors.New("no exchanges configured")
+
+// baseHalfSpread is the half-spread, as a fraction of the reference price, quoted by a venue
+// with full liquidity. Thinner venues quote proportionally wider
+const baseHalfSpread = 0.0005 // 5bp
+
+// VenueQuote is a simulated top-of-book quote on a single venue
+type VenueQuote struct {
+	ExchangeID   string  `json:"exchange_id"`
+	ExchangeName string  `json:"exchange_name"`
+	Bid          float64 `json:"bid"`
+	Ask          float64 `json:"ask"`
+	BidSize      float64 `json:"bid_size"`
+	AskSize      float64 `json:"ask_size"`
+}
+
+// Quote is the simulated best bid and offer for a symbol across all venues
+type Quote struct {
+	Symbol         string       `json:"symbol"`
+	ReferencePrice float64      `json:"reference_price"`
+	Bid            float64      `json:"bid"`
+	BidExchange    string       `json:"bid_exchange"`
+	Ask            float64      `json:"ask"`
+	AskExchange    string       `json:"ask_exchange"`
+	Spread         float64      `json:"spread"`
+	Venues         []VenueQuote `json:"venues"`
+	Timestamp      time.Time    `json:"timestamp"`
+}
+
+// Quote simulates the venue's top of book around the reference price
+// The half-spread widens as liquidity thins, and the mid drifts from the reference by up to half
+// the half-spread, so quotes from different venues never cross
+func (e *Exchange) Quote(referencePrice float64, rng *rand.Rand) VenueQuote {
+	halfSpread := baseHalfSpread
+	if e.LiquidityFactor > 0 {
+		halfSpread = baseHalfSpread / e.LiquidityFactor
+	}
+	drift := (rng.Float64() - 0.5) * halfSpread
+	mid := referencePrice * (1 + drift)
+	depth := e.MaxFillQuantity * e.LiquidityFactor
+
+	return VenueQuote{
+		ExchangeID:   e.ID,
+		ExchangeName: e.Name,
+		// Bids round down and asks up, so rounding never narrows the spread
+		Bid:     math.Floor(mid*(1-halfSpread)*100) / 100,
+		Ask:     math.Ceil(mid*(1+halfSpread)*100) / 100,
+		BidSize: depth,
+		AskSize: depth,
+	}
+}
+
+// BestQuote quotes the symbol on every venue that trades it and returns the highest bid and lowest ask
+// rng drives the simulated quotes, so a seeded source gives reproducible results
+func BestQuote(symbol string, referencePrice float64, rng *rand.Rand) (*Quote, error) {
+	active := activeExchanges()
+	if len(active) == 0 {
+		return nil, ErrNoVenues
+	}
+	venues := make([]*Exchange, 0, len(active))
+	for _, venue := range active {
+		if venue.Supports(symbol) {
+			venues = append(venues, venue)
+		}
+	}
+	if len(venues) == 0 {
+		return nil, fmt.Errorf("%w: %s", ErrSymbolNotSupported, symbol)
+	}
+
+	quote := &Quote{
+		Symbol:         symbol,
+		ReferencePrice: referencePrice,
+		Venues:         make([]VenueQuote, 0, len(venues)),
+		Timestamp:      now(),
+	}
+	for i, venue := range venues {
+		vq := venue.Quote(referencePrice, rng)
+		quote.Venues = append(quote.Venues, vq)
+		if i == 0 || vq.Bid > quote.Bid {
+			quote.Bid, quote.BidExchange = vq.Bid, vq.ExchangeID
+		}
+		if i == 0 || vq.Ask < quote.Ask {
+			quote.Ask, quote.AskExchange = vq.Ask, vq.ExchangeID
+		}
+	}
+	quote.Spread = math.Round((quote.Ask-quote.Bid)*100) / 100
+	return quote, nil
+}
@@ -0,0 +1,113 @@
+package exchange
+
+import (
+	"sync"
+	"time"
+)
+
+// VenueStats aggregates fill quality for a venue over the current day
+type VenueStats struct {
+	ExchangeID       string    `json:"exchange_id"`
+	ExchangeName     string    `json:"exchange_name"`
+	Attempts         int       `json:"attempts"`
+	Fills            int       `json:"fills"`
+	Failures         int       `json:"failures"`
+	FillRate         float64   `json:"fill_rate"`          // Fraction of attempts that filled
+	AverageSlippage  float64   `json:"average_slippage"`   // Mean adverse price move of priced fills; negative is improvement
+	AverageLatencyMs float64   `json:"average_latency_ms"` // Mean time per attempt, filled or not
+	TotalVolume      float64   `json:"total_volume"`       // Quantity filled
+	Since            time.Time `json:"since"`
+}
+
+// venueCounters holds the running totals behind a venue's stats
+type venueCounters struct {
+	attempts      int
+	fills         int
+	pricedFills   int
+	totalSlippage float64
+	totalLatency  time.Duration
+	totalVolume   float64
+}
+
+// venueTracker records routing attempts per venue, resetting at the start of each UTC day
+type venueTracker struct {
+	mu     sync.Mutex
+	since  time.Time
+	venues map[string]*venueCounters
+}
+
+var venueStats = &venueTracker{venues: make(map[string]*venueCounters)}
+
+// rollover resets the counters when the day has changed. Must be called with mu held
+func (t *venueTracker) rollover(now time.Time) {
+	day := now.UTC().Truncate(24 * time.Hour)
+	if !t.since.Equal(day) {
+		t.since = day
+		t.venues = make(map[string]*venueCounters)
+	}
+}
+
+// record adds the outcome of one routing attempt
+// slippage is only counted when priced is true, since market orders without a price have no reference
+func (t *venueTracker) record(exchangeID string, filled bool, quantity, slippage float64, priced bool, latency time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.rollover(now())
+	counters, ok := t.venues[exchangeID]
+	if !ok {
+		counters = &venueCounters{}
+		t.venues[exchangeID] = counters
+	}
+
+	counters.attempts++
+	counters.totalLatency += latency
+	if !filled {
+		return
+	}
+	counters.fills++
+	counters.totalVolume += quantity
+	if priced {
+		counters.pricedFills++
+		counters.totalSlippage += slippage
+	}
+}
+
+// GetVenueStats returns today's fill-quality stats for every venue
+func GetVenueStats() []VenueStats {
+	venueStats.mu.Lock()
+	defer venueStats.mu.Unlock()
+
+	venueStats.rollover(now())
+	exchanges := activeExchanges()
+	stats := make([]VenueStats, 0, len(exchanges))
+	for _, ex := range exchanges {
+		s := VenueStats{
+			ExchangeID:   ex.ID,
+			ExchangeName: ex.Name,
+			Since:        venueStats.since,
+		}
+		if c, ok := venueStats.venues[ex.ID]; ok && c.attempts > 0 {
+			s.Attempts = c.attempts
+			s.Fills = c.fills
+			s.Failures = c.attempts - c.fills
+			s.FillRate = float64(c.fills) / float64(c.attempts)
+			s.AverageLatencyMs = float64(c.totalLatency.Milliseconds()) / float64(c.attempts)
+			s.TotalVolume = c.totalVolume
+			if c.pricedFills > 0 {
+				s.AverageSlippage = c.totalSlippage / float64(c.pricedFills)
+			}
+		}
+		stats = append(stats, s)
+	}
+	return stats
+}
+
+// adverseSlippage returns how far the fill price moved against the order price, as a fraction
+func adverseSlippage(side string, orderPrice, fillPrice float64) float64 {
+	slippage := (fillPrice - orderPrice) / orderPrice
+	if side == "SELL" {
+		slippage = -slippage
+	}
+	return slippage
+}
@@ -0,0 +1,81 @@
+package market
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/klear-api/internal/exchange"
+	"github.com/ksred/klear-api/internal/pricefeed"
+	"github.com/ksred/klear-api/pkg/common"
+	"github.com/ksred/klear-api/pkg/response"
+)
+
+// Service provides simulated market data derived from the price feed and the venue set
+type Service struct {
+	feed pricefeed.PriceFeed
+
+	mu  sync.Mutex // Guards rng, which is not safe for concurrent use
+	rng *rand.Rand
+}
+
+// NewService creates a market data service quoting around the feed's last prices
+func NewService(feed pricefeed.PriceFeed) *Service {
+	return &Service{
+		feed: feed,
+		rng:  rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// SetRand replaces the source of the simulated quotes, e.g. with a seeded one for reproducible quotes
+func (s *Service) SetRand(rng *rand.Rand) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rng = rng
+}
+
+// GetQuote returns the simulated best bid and ask for a symbol across all venues
+// Returns pricefeed.ErrUnknownSymbol if the feed has no reference price for the symbol
+func (s *Service) GetQuote(symbol string) (*exchange.Quote, error) {
+	symbol = common.NormalizeSymbol(symbol)
+	referencePrice, err := s.feed.LastPrice(symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return exchange.BestQuote(symbol, referencePrice, s.rng)
+}
+
+// GinHandlers contains HTTP handlers for market data endpoints
+type GinHandlers struct {
+	service *Service
+}
+
+// NewGinHandlers creates a new set of HTTP handlers for market data endpoints
+func NewGinHandlers(service *Service) *GinHandlers {
+	return &GinHandlers{
+		service: service,
+	}
+}
+
+// GetQuoteHandler handles GET requests for a symbol's best bid and ask
+// Requires a valid JWT token
+// URL parameter: symbol
+func (h *GinHandlers) GetQuoteHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		quote, err := h.service.GetQuote(c.Param("symbol"))
+		if errors.Is(err, pricefeed.ErrUnknownSymbol) || errors.Is(err, exchange.ErrSymbolNotSupported) {
+			response.NotFound(c, err.Error())
+			return
+		}
+		if errors.Is(err, exchange.ErrNoVenues) {
+			response.ServiceUnavailable(c, err.Error())
+			return
+		}
+		response.Handle(c, quote, err)
+	}
+}
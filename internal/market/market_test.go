@@ -0,0 +1,92 @@
+package market
+
+import (
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/klear-api/internal/exchange"
+	"github.com/ksred/klear-api/internal/pricefeed"
+)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	os.Exit(m.Run())
+}
+
+// newSeededService creates a market data service over the mock feed with a seeded source
+func newSeededService(seed int64) *Service {
+	service := NewService(pricefeed.NewMockFeed())
+	service.SetRand(rand.New(rand.NewSource(seed)))
+	return service
+}
+
+func TestGetQuoteReturnsBidBelowAsk(t *testing.T) {
+	service := newSeededService(1)
+
+	quote, err := service.GetQuote(" aapl ")
+	if err != nil {
+		t.Fatalf("GetQuote: %v", err)
+	}
+	if quote.Symbol != "AAPL" || quote.ReferencePrice != 190 {
+		t.Errorf("quote = %s around %v, want AAPL around 190", quote.Symbol, quote.ReferencePrice)
+	}
+	if quote.Bid <= 0 || quote.Bid >= quote.Ask {
+		t.Errorf("quote = bid %v ask %v, want 0 < bid < ask", quote.Bid, quote.Ask)
+	}
+	if quote.Bid > quote.ReferencePrice*1.01 || quote.Ask < quote.ReferencePrice*0.99 {
+		t.Errorf("quote = bid %v ask %v, want it close to the reference price %v", quote.Bid, quote.Ask, quote.ReferencePrice)
+	}
+	if len(quote.Venues) == 0 || quote.BidExchange == "" || quote.AskExchange == "" {
+		t.Errorf("quote = %+v, want the best prices attributed to venues", quote)
+	}
+	for _, venue := range quote.Venues {
+		if venue.Bid > quote.Bid || venue.Ask < quote.Ask {
+			t.Errorf("venue %s quotes %v/%v inside the best %v/%v", venue.ExchangeID, venue.Bid, venue.Ask, quote.Bid, quote.Ask)
+		}
+	}
+}
+
+func TestGetQuoteIsReproducibleWithSeededRand(t *testing.T) {
+	first, err := newSeededService(42).GetQuote("MSFT")
+	if err != nil {
+		t.Fatalf("GetQuote: %v", err)
+	}
+	second, err := newSeededService(42).GetQuote("MSFT")
+	if err != nil {
+		t.Fatalf("GetQuote: %v", err)
+	}
+	if first.Bid != second.Bid || first.Ask != second.Ask || first.BidExchange != second.BidExchange {
+		t.Errorf("quotes = %v/%v and %v/%v, want the same quote from the same seed", first.Bid, first.Ask, second.Bid, second.Ask)
+	}
+}
+
+func TestGetQuoteHandler(t *testing.T) {
+	router := gin.New()
+	router.GET("/market/quote/:symbol", NewGinHandlers(newSeededService(7)).GetQuoteHandler())
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/market/quote/GOOGL", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", recorder.Code, recorder.Body.String())
+	}
+	var envelope struct {
+		Data exchange.Quote `json:"data"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if envelope.Data.Symbol != "GOOGL" || envelope.Data.Bid >= envelope.Data.Ask {
+		t.Errorf("quote = %+v, want a GOOGL quote with bid below ask", envelope.Data)
+	}
+
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/market/quote/UNKNOWN", nil))
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("unknown symbol status = %d, want 404", recorder.Code)
+	}
+}
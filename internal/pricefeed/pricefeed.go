@@ -0,0 +1,80 @@
+package pricefeed
+
+import (
+	"errors"
+	"strings"
+	"sync"
+)
+
+var ErrUnknownSymbol = errors.New("no market data for symbol")
+
+// PriceFeed provides market data used for mark-to-market valuation and risk
+type PriceFeed interface {
+	// LastPrice returns the latest traded price for the symbol
+	LastPrice(symbol string) (float64, error)
+	// Volatility returns the annualised volatility for the symbol as a fraction (0.15 = 15%)
+	Volatility(symbol string) (float64, error)
+}
+
+// quote holds the mock market data for a single symbol
+type quote struct {
+	price      float64
+	volatility float64
+}
+
+// MockFeed is an in-memory price feed for simulation and testing
+type MockFeed struct {
+	mu     sync.RWMutex
+	quotes map[string]quote
+}
+
+// NewMockFeed creates a mock price feed seeded with the simulation symbols
+func NewMockFeed() *MockFeed {
+	feed := &MockFeed{
+		quotes: make(map[string]quote),
+	}
+
+	// Mock reference data (in reality, this would come from a market data vendor)
+	feed.SetQuote("AAPL", 190.0, 0.22)
+	feed.SetQuote("GOOGL", 140.0, 0.26)
+	feed.SetQuote("MSFT", 410.0, 0.20)
+	feed.SetQuote("AMZN", 180.0, 0.30)
+	feed.SetQuote("META", 500.0, 0.35)
+
+	return feed
+}
+
+// SetQuote sets the last price and volatility for a symbol
+func (f *MockFeed) SetQuote(symbol string, price, volatility float64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.quotes[strings.ToUpper(symbol)] = quote{price: price, volatility: volatility}
+}
+
+// LastPrice returns the mock last price for the symbol
+func (f *MockFeed) LastPrice(symbol string) (float64, error) {
+	q, err := f.get(symbol)
+	if err != nil {
+		return 0, err
+	}
+	return q.price, nil
+}
+
+// Volatility returns the mock volatility for the symbol
+func (f *MockFeed) Volatility(symbol string) (float64, error) {
+	q, err := f.get(symbol)
+	if err != nil {
+		return 0, err
+	}
+	return q.volatility, nil
+}
+
+func (f *MockFeed) get(symbol string) (quote, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	q, exists := f.quotes[strings.ToUpper(symbol)]
+	if !exists {
+		return quote{}, ErrUnknownSymbol
+	}
+	return q, nil
+}
@@ -0,0 +1,44 @@
+package settlement
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/klear-api/pkg/response"
+)
+
+// valueDateLayout formats the value date that groups settlements into a batch
+const valueDateLayout = "2006-01-02"
+
+// saveSettlement persists a settlement of a trade on the given side that is due to settle, sweeping
+// the given deferred settlements into it
+// With batching enabled the settlement is also added to the client's batch for its currency and value date
+func (s *Service) saveSettlement(settlement *Settlement, side string, deferredIDs []string) error {
+	if s.config.BatchSettlements {
+		valueDate := settlement.SettlementDate.UTC().Format(valueDateLayout)
+		return s.db.CreateBatchedSettlement(settlement, side, deferredIDs, valueDate)
+	}
+	if len(deferredIDs) > 0 {
+		return s.db.CreateSweepingSettlement(settlement, deferredIDs)
+	}
+	return s.db.CreateSettlement(settlement)
+}
+
+// GetSettlementBatch retrieves a settlement batch with the settlements it nets
+func (s *Service) GetSettlementBatch(batchID string) (*SettlementBatch, error) {
+	return s.db.GetSettlementBatch(batchID)
+}
+
+// GetSettlementBatchHandler handles GET requests for a settlement batch
+// Requires internal authentication
+// URL parameter: batch_id
+func (h *GinHandlers) GetSettlementBatchHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		batch, err := h.service.GetSettlementBatch(c.Param("batch_id"))
+		if errors.Is(err, ErrBatchNotFound) {
+			response.NotFound(c, err.Error())
+			return
+		}
+		response.Handle(c, batch, err)
+	}
+}
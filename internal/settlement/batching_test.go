@@ -0,0 +1,59 @@
+package settlement
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/klear-api/pkg/money"
+)
+
+func TestSameCurrencySettlementsNetIntoOneBatch(t *testing.T) {
+	config := DefaultConfig()
+	config.BatchSettlements = true
+	service, _ := newTestServiceWithConfig(t, config)
+
+	sell := settleTrade(t, service, seedClearedTrade(t, service, "client-1", "SELL", 100, 150, testNow.Add(-3*time.Minute)).ExecutionID)
+	otherSell := settleTrade(t, service, seedClearedTrade(t, service, "client-1", "SELL", 50, 100, testNow.Add(-2*time.Minute)).ExecutionID)
+	buy := settleTrade(t, service, seedClearedTrade(t, service, "client-1", "BUY", 40, 100, testNow.Add(-time.Minute)).ExecutionID)
+
+	if sell.BatchID == "" || otherSell.BatchID != sell.BatchID || buy.BatchID != sell.BatchID {
+		t.Fatalf("batch IDs = %q, %q and %q, want all three in one batch", sell.BatchID, otherSell.BatchID, buy.BatchID)
+	}
+
+	router := gin.New()
+	router.GET("/batches/:batch_id", NewGinHandlers(service).GetSettlementBatchHandler())
+	recorder := performRequest(router, http.MethodGet, "/batches/"+sell.BatchID, nil)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", recorder.Code, recorder.Body.String())
+	}
+	var batch SettlementBatch
+	decodeData(t, recorder, &batch)
+
+	// Sells are received and the buy paid, so the buy nets the batch down
+	wantNet := money.Sum("USD", sell.FinalAmount, otherSell.FinalAmount, -buy.FinalAmount)
+	assertAmount(t, "batch net amount", batch.NetAmount, wantNet)
+	assertAmount(t, "batch fees", batch.SettlementFees,
+		money.Sum("USD", sell.SettlementFees, otherSell.SettlementFees, buy.SettlementFees))
+	if batch.SettlementCount != 3 || len(batch.Settlements) != 3 || batch.Currency != "USD" {
+		t.Errorf("batch = %d settlements in %s with %d listed, want 3 in USD", batch.SettlementCount, batch.Currency, len(batch.Settlements))
+	}
+
+	// Cancelling the buy takes its payment back out of the batch
+	if _, err := service.CancelSettlement(buy.SettlementID, "test"); err != nil {
+		t.Fatalf("CancelSettlement: %v", err)
+	}
+	unwound, err := service.GetSettlementBatch(sell.BatchID)
+	if err != nil {
+		t.Fatalf("GetSettlementBatch: %v", err)
+	}
+	assertAmount(t, "net amount after cancelling the buy", unwound.NetAmount, money.Sum("USD", sell.FinalAmount, otherSell.FinalAmount))
+	if unwound.SettlementCount != 2 {
+		t.Errorf("settlement count after cancel = %d, want 2", unwound.SettlementCount)
+	}
+
+	if recorder := performRequest(router, http.MethodGet, "/batches/BATCH_unknown", nil); recorder.Code != http.StatusNotFound {
+		t.Errorf("unknown batch status = %d, want 404", recorder.Code)
+	}
+}
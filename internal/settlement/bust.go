@@ -0,0 +1,119 @@
+package settlement
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/klear-api/pkg/response"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// TradeStatusBusted marks the execution and order of a busted trade
+const TradeStatusBusted = "BUSTED"
+
+var (
+	ErrTradeNotFound      = errors.New("trade not found")
+	ErrBustReasonRequired = errors.New("a reason is required to bust a trade")
+	ErrTradeSettled       = errors.New("trade is settling or has settled; it must be reversed through a settlement reversal")
+	ErrTradeBustConflict  = errors.New("trade changed while it was being busted")
+	ErrTradeAlreadyBusted = errors.New("trade has been busted")
+)
+
+// BustTradeRequest is the body accepted when busting a trade
+type BustTradeRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// TradeBust records what busting a trade reversed; it is also the payload of the TRADE_BUSTED event
+type TradeBust struct {
+	ExecutionID          string    `json:"execution_id"`
+	OrderID              string    `json:"order_id"`
+	Reason               string    `json:"reason"`
+	CancelledSettlements []string  `json:"cancelled_settlements"`
+	ReversedClearings    []string  `json:"reversed_clearings"`
+	BustedAt             time.Time `json:"busted_at"`
+}
+
+// BustTrade reverses the downstream records of an erroneous execution
+// Its settlement is cancelled, its clearing is marked REVERSED and the execution and its order are
+// marked BUSTED, all in one transaction. Only a PENDING or DEFERRED settlement is cancelled, and one
+// that was swept into a PENDING settlement is taken back out of it. A trade whose settlement is
+// SETTLING or has SETTLED, or was swept into one that is, needs a separate reversal and is rejected
+// Parameters:
+//   - executionID: ID of the execution to bust
+//   - reason: Why the trade is busted
+func (s *Service) BustTrade(executionID, reason string) (*TradeBust, error) {
+	logger := log.With().
+		Str("execution_id", executionID).
+		Str("service", "settlement").
+		Logger()
+
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, ErrBustReasonRequired
+	}
+
+	lifecycle, err := s.db.GetTradeLifecycle(executionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTradeNotFound
+		}
+		return nil, err
+	}
+	if lifecycle.Execution.Status == TradeStatusBusted {
+		return nil, ErrTradeAlreadyBusted
+	}
+
+	bust, cancelled, err := s.db.BustTrade(lifecycle.Execution, lifecycle.Order, reason, s.clock.Now())
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to bust trade")
+		return nil, err
+	}
+
+	logger.Info().
+		Str("order_id", bust.OrderID).
+		Strs("cancelled_settlements", bust.CancelledSettlements).
+		Strs("reversed_clearings", bust.ReversedClearings).
+		Str("reason", reason).
+		Msg("trade busted")
+
+	for _, settlement := range cancelled {
+		if updated, err := s.db.GetSettlement(settlement.SettlementID); err == nil {
+			s.notifier.Notify(updated, settlement.SettlementStatus)
+		}
+	}
+	return bust, nil
+}
+
+// BustTradeHandler handles POST requests to bust a trade
+// Requires internal authentication
+// URL parameter: execution_id
+// Request body should contain the reason the trade is busted
+func (h *GinHandlers) BustTradeHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req BustTradeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BindError(c, err)
+			return
+		}
+
+		bust, err := h.service.BustTrade(c.Param("execution_id"), req.Reason)
+		switch {
+		case errors.Is(err, ErrTradeNotFound):
+			response.NotFound(c, "Trade not found")
+		case errors.Is(err, ErrBustReasonRequired):
+			response.BadRequest(c, err.Error())
+		case errors.Is(err, ErrTradeSettled) || errors.Is(err, ErrTradeBustConflict) ||
+			errors.Is(err, ErrTradeAlreadyBusted):
+			response.Conflict(c, err.Error())
+		case err != nil:
+			response.InternalError(c, err.Error())
+		default:
+			response.SuccessWithStatus(c, bust, http.StatusOK)
+		}
+	}
+}
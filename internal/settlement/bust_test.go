@@ -0,0 +1,214 @@
+package settlement
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/klear-api/internal/clearing"
+	"github.com/ksred/klear-api/internal/types"
+)
+
+// bustTrade busts the trade through the bust endpoint, returning the response status and body
+func bustTrade(service *Service, executionID string) (int, string) {
+	router := gin.New()
+	router.POST("/trades/:execution_id/bust", NewGinHandlers(service).BustTradeHandler())
+	recorder := performRequest(router, http.MethodPost, "/trades/"+executionID+"/bust",
+		BustTradeRequest{Reason: "erroneous execution"})
+	return recorder.Code, recorder.Body.String()
+}
+
+// newBustService creates a test service that batches settlements, deferring those below minAmount
+func newBustService(t *testing.T, minAmount float64) *Service {
+	t.Helper()
+	config := DefaultConfig()
+	config.BatchSettlements = true
+	config.MinSettlementAmount = minAmount
+	service, _ := newTestServiceWithConfig(t, config)
+	return service
+}
+
+// assertBusted checks that the trade's execution and order are BUSTED and its clearing REVERSED
+func assertBusted(t *testing.T, service *Service, execution *types.Execution) {
+	t.Helper()
+	lifecycle, err := service.db.GetTradeLifecycle(execution.ExecutionID)
+	if err != nil {
+		t.Fatalf("GetTradeLifecycle: %v", err)
+	}
+	if lifecycle.Execution.Status != TradeStatusBusted || lifecycle.Order.Status != TradeStatusBusted {
+		t.Errorf("execution %s, order %s; want both BUSTED", lifecycle.Execution.Status, lifecycle.Order.Status)
+	}
+	var clearingRecord clearing.Clearing
+	if err := service.db.db.Where("trade_id = ?", execution.ExecutionID).First(&clearingRecord).Error; err != nil {
+		t.Fatalf("failed to load clearing: %v", err)
+	}
+	if clearingRecord.ClearingStatus != clearing.StatusReversed {
+		t.Errorf("clearing status = %s, want %s", clearingRecord.ClearingStatus, clearing.StatusReversed)
+	}
+}
+
+// assertCancelled checks that the trade's settlement was cancelled for the bust
+func assertCancelled(t *testing.T, service *Service, settlementID string) {
+	t.Helper()
+	stored, err := service.db.GetSettlement(settlementID)
+	if err != nil {
+		t.Fatalf("GetSettlement: %v", err)
+	}
+	if stored.SettlementStatus != StatusCancelled || stored.CancelReason != "erroneous execution" {
+		t.Errorf("settlement = %s with reason %q, want CANCELLED for the bust", stored.SettlementStatus, stored.CancelReason)
+	}
+}
+
+func TestBustTradeCancelsPendingSettlement(t *testing.T) {
+	service := newBustService(t, 0)
+	trade := seedClearedTrade(t, service, "client-1", "BUY", 100, 150, testNow.Add(-time.Minute))
+	settlement := settleTrade(t, service, trade.ExecutionID)
+
+	if code, body := bustTrade(service, trade.ExecutionID); code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", code, body)
+	}
+	assertCancelled(t, service, settlement.SettlementID)
+	assertBusted(t, service, trade)
+
+	batch, err := service.db.GetSettlementBatch(settlement.BatchID)
+	if err != nil {
+		t.Fatalf("GetSettlementBatch: %v", err)
+	}
+	if batch.SettlementCount != 0 || batch.NetAmount != 0 {
+		t.Errorf("batch = %d settlements netting %v, want the busted settlement taken out", batch.SettlementCount, batch.NetAmount)
+	}
+}
+
+func TestBustTradeCancelsDeferredSettlement(t *testing.T) {
+	service := newBustService(t, 10000)
+	trade := seedClearedTrade(t, service, "client-1", "BUY", 50, 150, testNow.Add(-time.Minute))
+	deferred := settleTrade(t, service, trade.ExecutionID)
+
+	if code, body := bustTrade(service, trade.ExecutionID); code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", code, body)
+	}
+	assertCancelled(t, service, deferred.SettlementID)
+	assertBusted(t, service, trade)
+
+	balances, err := service.GetDeferredBalances("client-1")
+	if err != nil {
+		t.Fatalf("GetDeferredBalances: %v", err)
+	}
+	if len(balances) != 0 {
+		t.Errorf("deferred balances = %+v, want none once the deferred trade is busted", balances)
+	}
+}
+
+func TestBustTradeTakesSweptSettlementOutOfItsSweep(t *testing.T) {
+	service := newBustService(t, 10000)
+	first := seedClearedTrade(t, service, "client-1", "BUY", 50, 150, testNow.Add(-2*time.Minute))
+	swept := settleTrade(t, service, first.ExecutionID)
+	second := seedClearedTrade(t, service, "client-1", "BUY", 50, 150, testNow.Add(-time.Minute))
+	combined := settleTrade(t, service, second.ExecutionID)
+	assertSweptInto(t, service, first.ExecutionID, combined.SettlementID)
+
+	if code, body := bustTrade(service, first.ExecutionID); code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", code, body)
+	}
+	assertCancelled(t, service, swept.SettlementID)
+	assertBusted(t, service, first)
+
+	// The combined settlement is left with only its own trade
+	stored, err := service.db.GetSettlement(combined.SettlementID)
+	if err != nil {
+		t.Fatalf("GetSettlement: %v", err)
+	}
+	if stored.SettlementStatus != StatusPending {
+		t.Errorf("combined settlement status = %s, want it still PENDING", stored.SettlementStatus)
+	}
+	assertAmount(t, "combined final amount", stored.FinalAmount, combined.FinalAmount-swept.FinalAmount)
+	assertAmount(t, "combined deferred amount", stored.DeferredAmount, 0)
+	assertAmount(t, "combined settlement fees", stored.SettlementFees, combined.SettlementFees-swept.SettlementFees)
+
+	batch, err := service.db.GetSettlementBatch(stored.BatchID)
+	if err != nil {
+		t.Fatalf("GetSettlementBatch: %v", err)
+	}
+	assertAmount(t, "batch net amount", batch.NetAmount, -stored.FinalAmount)
+	assertAmount(t, "batch settlement fees", batch.SettlementFees, stored.SettlementFees)
+	if batch.SettlementCount != 1 {
+		t.Errorf("batch settlement count = %d, want 1", batch.SettlementCount)
+	}
+}
+
+func TestBustTradeRejectsSettledTrade(t *testing.T) {
+	service := newBustService(t, 10000)
+	settled := seedClearedTrade(t, service, "client-1", "BUY", 100, 150, testNow.Add(-3*time.Minute))
+	settlement := settleTrade(t, service, settled.ExecutionID)
+	updateStatus(t, service, settlement.SettlementID, StatusSettling, StatusSettled)
+
+	// A trade swept into a settlement that has settled is settled too
+	sweptTrade := seedClearedTrade(t, service, "client-1", "BUY", 50, 150, testNow.Add(-2*time.Minute))
+	settleTrade(t, service, sweptTrade.ExecutionID)
+	combined := settleTrade(t, service, seedClearedTrade(t, service, "client-1", "BUY", 50, 150, testNow.Add(-time.Minute)).ExecutionID)
+	updateStatus(t, service, combined.SettlementID, StatusSettling, StatusSettled)
+
+	for _, trade := range []*types.Execution{settled, sweptTrade} {
+		if code, body := bustTrade(service, trade.ExecutionID); code != http.StatusConflict {
+			t.Errorf("bust %s status = %d, want 409: %s", trade.ExecutionID, code, body)
+		}
+		lifecycle, err := service.db.GetTradeLifecycle(trade.ExecutionID)
+		if err != nil {
+			t.Fatalf("GetTradeLifecycle: %v", err)
+		}
+		if lifecycle.Execution.Status == TradeStatusBusted {
+			t.Errorf("execution %s was busted, want the rejected bust to change nothing", trade.ExecutionID)
+		}
+	}
+}
+
+func TestBustTradeRejectsSettlingTrade(t *testing.T) {
+	service := newBustService(t, 10000)
+	settling := seedClearedTrade(t, service, "client-1", "BUY", 100, 150, testNow.Add(-3*time.Minute))
+	settlement := settleTrade(t, service, settling.ExecutionID)
+	updateStatus(t, service, settlement.SettlementID, StatusSettling)
+
+	// A trade swept into a settlement that is settling cannot be taken back out of it either
+	sweptTrade := seedClearedTrade(t, service, "client-1", "BUY", 50, 150, testNow.Add(-2*time.Minute))
+	swept := settleTrade(t, service, sweptTrade.ExecutionID)
+	combined := settleTrade(t, service, seedClearedTrade(t, service, "client-1", "BUY", 50, 150, testNow.Add(-time.Minute)).ExecutionID)
+	updateStatus(t, service, combined.SettlementID, StatusSettling)
+
+	for _, tc := range []struct {
+		trade      *types.Execution
+		settlement *SettlementResponse
+		into       *SettlementResponse
+	}{
+		{settling, settlement, settlement},
+		{sweptTrade, swept, combined},
+	} {
+		if code, body := bustTrade(service, tc.trade.ExecutionID); code != http.StatusConflict {
+			t.Errorf("bust %s status = %d, want 409: %s", tc.trade.ExecutionID, code, body)
+		}
+
+		lifecycle, err := service.db.GetTradeLifecycle(tc.trade.ExecutionID)
+		if err != nil {
+			t.Fatalf("GetTradeLifecycle: %v", err)
+		}
+		if lifecycle.Execution.Status == TradeStatusBusted {
+			t.Errorf("execution %s was busted, want the rejected bust to change nothing", tc.trade.ExecutionID)
+		}
+		stored, err := service.db.GetSettlement(tc.settlement.SettlementID)
+		if err != nil {
+			t.Fatalf("GetSettlement: %v", err)
+		}
+		if stored.SettlementStatus == StatusCancelled {
+			t.Errorf("settlement %s was cancelled, want it left %s", stored.SettlementID, stored.SettlementStatus)
+		}
+		into, err := service.db.GetSettlement(tc.into.SettlementID)
+		if err != nil {
+			t.Fatalf("GetSettlement: %v", err)
+		}
+		if into.SettlementStatus != StatusSettling {
+			t.Errorf("settlement %s status = %s, want it still SETTLING", into.SettlementID, into.SettlementStatus)
+		}
+		assertAmount(t, "settling final amount", into.FinalAmount, tc.into.FinalAmount)
+		assertAmount(t, "settling settlement fees", into.SettlementFees, tc.into.SettlementFees)
+	}
+}
@@ -0,0 +1,108 @@
+package settlement
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/klear-api/pkg/response"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+// CancelSettlementRequest is the body accepted when cancelling a settlement
+type CancelSettlementRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// CancelSettlement cancels a PENDING settlement before its value date, e.g. because the trade was busted
+// Deferred settlements it swept are deferred again, so their amounts are carried into the client's next
+// settlement, and it is taken out of its settlement batch. Cancelling a cancelled settlement is a no-op
+// Parameters:
+//   - settlementID: ID of the settlement to cancel
+//   - reason: Why the settlement is cancelled
+func (s *Service) CancelSettlement(settlementID, reason string) (*Settlement, error) {
+	logger := log.With().
+		Str("settlement_id", settlementID).
+		Str("service", "settlement").
+		Logger()
+
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, ErrCancelReasonRequired
+	}
+
+	settlement, err := s.db.GetSettlement(settlementID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSettlementNotFound
+		}
+		return nil, err
+	}
+
+	if settlement.SettlementStatus == StatusCancelled {
+		return settlement, nil
+	}
+	if !isAllowedTransition(settlement.SettlementStatus, StatusCancelled) {
+		logger.Warn().
+			Str("current_status", settlement.SettlementStatus).
+			Msg("rejected settlement cancellation")
+		return nil, fmt.Errorf("%w: %s to %s", ErrInvalidTransition, settlement.SettlementStatus, StatusCancelled)
+	}
+	if !s.clock.Now().Before(settlement.SettlementDate) {
+		return nil, fmt.Errorf("%w: value date %s", ErrValueDateReached, settlement.SettlementDate.Format(valueDateLayout))
+	}
+
+	cancelled, err := s.db.CancelSettlement(settlement, reason, s.clock.Now())
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to cancel settlement")
+		return nil, err
+	}
+	if !cancelled {
+		// The processor or another request moved the settlement on after it was read
+		return nil, fmt.Errorf("%w: settlement status changed concurrently", ErrInvalidTransition)
+	}
+
+	logger.Info().
+		Str("trade_id", settlement.TradeID).
+		Str("batch_id", settlement.BatchID).
+		Str("reason", reason).
+		Msg("settlement cancelled")
+
+	updated, err := s.db.GetSettlement(settlementID)
+	if err != nil {
+		return nil, err
+	}
+	s.notifier.Notify(updated, settlement.SettlementStatus)
+	return updated, nil
+}
+
+// CancelSettlementHandler handles POST requests to cancel a pending settlement
+// Requires internal authentication
+// URL parameter: settlement_id, registered under the name trade_id shared with the settle route
+// Request body should contain the cancellation reason
+func (h *GinHandlers) CancelSettlementHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req CancelSettlementRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BindError(c, err)
+			return
+		}
+
+		settlement, err := h.service.CancelSettlement(c.Param("trade_id"), req.Reason)
+		switch {
+		case errors.Is(err, ErrSettlementNotFound):
+			response.NotFound(c, "Settlement not found")
+		case errors.Is(err, ErrCancelReasonRequired):
+			response.BadRequest(c, err.Error())
+		case errors.Is(err, ErrInvalidTransition) || errors.Is(err, ErrValueDateReached):
+			response.Conflict(c, err.Error())
+		case err != nil:
+			response.InternalError(c, err.Error())
+		default:
+			response.SuccessWithStatus(c, settlement, http.StatusOK)
+		}
+	}
+}
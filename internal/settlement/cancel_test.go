@@ -0,0 +1,90 @@
+package settlement
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/klear-api/pkg/common"
+)
+
+// newCancelRouter serves the cancel endpoint under the route it is registered with
+func newCancelRouter(service *Service) *gin.Engine {
+	router := gin.New()
+	router.POST("/settlement/:trade_id/cancel", NewGinHandlers(service).CancelSettlementHandler())
+	return router
+}
+
+func TestCancelPendingSettlement(t *testing.T) {
+	service, _ := newTestService(t)
+	trade := seedClearedTrade(t, service, "client-1", "BUY", 100, 150, testNow.Add(-time.Minute))
+	settlement := settleTrade(t, service, trade.ExecutionID)
+	router := newCancelRouter(service)
+
+	recorder := performRequest(router, http.MethodPost, "/settlement/"+settlement.SettlementID+"/cancel",
+		CancelSettlementRequest{Reason: "trade busted"})
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", recorder.Code, recorder.Body.String())
+	}
+	var cancelled Settlement
+	decodeData(t, recorder, &cancelled)
+	if cancelled.SettlementStatus != StatusCancelled || cancelled.CancelReason != "trade busted" {
+		t.Errorf("settlement = %s with reason %q, want CANCELLED for trade busted", cancelled.SettlementStatus, cancelled.CancelReason)
+	}
+
+	// The processor leaves a cancelled settlement alone once its value date arrives
+	processor := NewProcessor(service.db, time.Hour)
+	processor.SetClock(common.FixedClock{Time: testNow.AddDate(0, 0, 5)})
+	if err := processor.processPendingSettlements(context.Background()); err != nil {
+		t.Fatalf("processPendingSettlements: %v", err)
+	}
+	stored, err := service.db.GetSettlement(settlement.SettlementID)
+	if err != nil {
+		t.Fatalf("GetSettlement: %v", err)
+	}
+	if stored.SettlementStatus != StatusCancelled {
+		t.Errorf("settlement status after processing = %s, want it still CANCELLED", stored.SettlementStatus)
+	}
+}
+
+func TestCancelSettlementRejected(t *testing.T) {
+	service, clock := newTestService(t)
+	router := newCancelRouter(service)
+
+	settled := settleTrade(t, service, seedClearedTrade(t, service, "client-1", "BUY", 100, 150, testNow.Add(-time.Minute)).ExecutionID)
+	updateStatus(t, service, settled.SettlementID, StatusSettling, StatusSettled)
+	pending := settleTrade(t, service, seedClearedTrade(t, service, "client-1", "BUY", 10, 150, testNow.Add(-time.Minute)).ExecutionID)
+
+	tests := []struct {
+		name         string
+		settlementID string
+		reason       string
+		want         int
+	}{
+		{"settled", settled.SettlementID, "trade busted", http.StatusConflict},
+		{"unknown", "SETT_unknown", "trade busted", http.StatusNotFound},
+		{"blank reason", pending.SettlementID, " ", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := performRequest(router, http.MethodPost, "/settlement/"+tt.settlementID+"/cancel",
+				CancelSettlementRequest{Reason: tt.reason})
+			if recorder.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", recorder.Code, tt.want, recorder.Body.String())
+			}
+		})
+	}
+
+	// A pending settlement can no longer be cancelled once its value date has arrived
+	clock.Set(pending.SettlementDate)
+	recorder := performRequest(router, http.MethodPost, "/settlement/"+pending.SettlementID+"/cancel",
+		CancelSettlementRequest{Reason: "too late"})
+	if recorder.Code != http.StatusConflict {
+		t.Errorf("cancel on the value date status = %d, want 409: %s", recorder.Code, recorder.Body.String())
+	}
+	if stored, _ := service.db.GetSettlement(settled.SettlementID); stored.SettlementStatus != StatusSettled {
+		t.Errorf("settled settlement status = %s, want it still SETTLED", stored.SettlementStatus)
+	}
+}
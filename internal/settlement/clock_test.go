@@ -0,0 +1,74 @@
+package settlement
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestWebhookDeliveryStampedByProcessorClock(t *testing.T) {
+	service, _ := newTestService(t)
+	endpoint := newWebhookEndpoint(t, 0)
+	endpoint.register(t, service, "client-1")
+
+	// startSettling processes the settlement with its clock two days after testNow
+	startSettling(t, service, "client-1", fastWebhookRetry)
+	processedAt := testNow.AddDate(0, 0, 2)
+
+	delivery := endpoint.next(t)
+	if !delivery.event.Timestamp.Equal(processedAt) {
+		t.Errorf("event timestamp = %s, want the processor clock's %s", delivery.event.Timestamp, processedAt)
+	}
+	if want := strconv.FormatInt(processedAt.Unix(), 10); delivery.timestamp != want {
+		t.Errorf("%s = %s, want %s", WebhookTimestampHeader, delivery.timestamp, want)
+	}
+}
+
+func TestCancelSettlementStampedByClock(t *testing.T) {
+	config := DefaultConfig()
+	config.BatchSettlements = true
+	service, clock := newTestServiceWithConfig(t, config)
+	settlement := settleTrade(t, service, seedClearedTrade(t, service, "client-1", "BUY", 100, 150, testNow.Add(-time.Minute)).ExecutionID)
+
+	clock.Advance(time.Hour)
+	if _, err := service.CancelSettlement(settlement.SettlementID, "booked in error"); err != nil {
+		t.Fatalf("CancelSettlement: %v", err)
+	}
+
+	cancelledAt := testNow.Add(time.Hour)
+	stored, err := service.db.GetSettlement(settlement.SettlementID)
+	if err != nil {
+		t.Fatalf("GetSettlement: %v", err)
+	}
+	if !stored.UpdatedAt.Equal(cancelledAt) {
+		t.Errorf("cancelled settlement updated at %s, want the clock's %s", stored.UpdatedAt, cancelledAt)
+	}
+	batch, err := service.GetSettlementBatch(settlement.BatchID)
+	if err != nil {
+		t.Fatalf("GetSettlementBatch: %v", err)
+	}
+	if !batch.UpdatedAt.Equal(cancelledAt) {
+		t.Errorf("batch updated at %s, want the clock's %s", batch.UpdatedAt, cancelledAt)
+	}
+}
+
+func TestSweepStampedAtSweepingSettlementCreation(t *testing.T) {
+	config := DefaultConfig()
+	config.MinSettlementAmount = 10000
+	service, clock := newTestServiceWithConfig(t, config)
+
+	deferred := seedClearedTrade(t, service, "client-1", "BUY", 50, 150, testNow.Add(-2*time.Minute))
+	settleTrade(t, service, deferred.ExecutionID)
+
+	clock.Advance(time.Hour)
+	sweeping := settleTrade(t, service, seedClearedTrade(t, service, "client-1", "BUY", 50, 150, testNow.Add(-time.Minute)).ExecutionID)
+	assertSweptInto(t, service, deferred.ExecutionID, sweeping.SettlementID)
+
+	stored, err := service.db.GetSettlementByTradeID(deferred.ExecutionID)
+	if err != nil {
+		t.Fatalf("GetSettlementByTradeID: %v", err)
+	}
+	if sweptAt := testNow.Add(time.Hour); !stored.UpdatedAt.Equal(sweptAt) {
+		t.Errorf("swept settlement updated at %s, want the clock's %s", stored.UpdatedAt, sweptAt)
+	}
+}
@@ -0,0 +1,40 @@
+package settlement
+
+import (
+	"github.com/ksred/klear-api/pkg/common"
+	"github.com/ksred/klear-api/pkg/money"
+)
+
+// Config holds the tunable settings applied by the settlement service
+type Config struct {
+	FeeSchedule FeeSchedule
+	// DefaultSettlementCycleDays is the instrument default cycle (T+N) used when
+	// the client has no counterparty agreement
+	DefaultSettlementCycleDays int
+	// DefaultCurrency is used when neither the order nor an agreement specifies a currency
+	DefaultCurrency string
+	// MinSettlementAmount defers settlements whose amount, together with the client's other
+	// deferred settlements in the same currency, falls below it (0 disables deferral)
+	MinSettlementAmount float64
+	// BatchSettlements nets each client's settlements that share a currency and value date
+	// into a single settlement batch, so one payment instruction covers them
+	BatchSettlements bool
+	// PageSize bounds the pages of the settlement listing
+	PageSize common.PageSize
+	// WebhookRetry controls how failed webhook deliveries are retried before being dead-lettered
+	WebhookRetry WebhookRetryPolicy
+	// AllowPrivateWebhooks accepts plain http webhook URLs and ones resolving to private, loopback or
+	// link-local addresses. Meant for local development only: it lets clients aim deliveries inside the network
+	AllowPrivateWebhooks bool
+}
+
+// DefaultConfig returns the settlement configuration used when nothing is overridden
+func DefaultConfig() Config {
+	return Config{
+		FeeSchedule:                DefaultFeeSchedule(),
+		DefaultSettlementCycleDays: 2, // T+2
+		DefaultCurrency:            money.DefaultCurrency,
+		PageSize:                   common.DefaultPageSize(),
+		WebhookRetry:               DefaultWebhookRetryPolicy(),
+	}
+}
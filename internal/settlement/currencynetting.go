@@ -0,0 +1,116 @@
+package settlement
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/klear-api/pkg/common"
+	"github.com/ksred/klear-api/pkg/money"
+	"github.com/ksred/klear-api/pkg/response"
+)
+
+// Directions of a net currency movement, from the client's point of view
+const (
+	DirectionReceive = "RECEIVE"
+	DirectionPay     = "PAY"
+	DirectionFlat    = "FLAT"
+)
+
+// ErrNettingNotEligible is returned when the client's counterparty agreement excludes it from netting
+var ErrNettingNotEligible = errors.New("client's counterparty agreement is not eligible for netting")
+
+// CurrencyNet is a client's net settlement obligation in one currency for a value date
+type CurrencyNet struct {
+	Currency    string  `json:"currency"`
+	Receivable  float64 `json:"receivable"`  // Proceeds of the client's sells
+	Payable     float64 `json:"payable"`     // Cost of the client's buys
+	NetAmount   float64 `json:"net_amount"`  // Receivable less payable; positive when the client receives
+	Direction   string  `json:"direction"`   // RECEIVE, PAY or FLAT
+	Settlements int     `json:"settlements"` // Trades netted, including those swept into another settlement
+}
+
+// CurrencyNetting lists a client's net settlement obligations per currency for a value date
+type CurrencyNetting struct {
+	ClientID   string        `json:"client_id"`
+	ValueDate  string        `json:"value_date"`
+	Currencies []CurrencyNet `json:"currencies"` // Ordered by currency
+}
+
+// NetByCurrency offsets a client's receivables and payables settling on a value date into
+// a single net movement per currency. Each trade's sign comes from its own order's side: sells
+// are received and buys paid. Trades of pending, settling and settled settlements count, as do
+// deferred trades swept into one of them; failed, cancelled and still-deferred ones do not settle on the date
+// Clients whose counterparty agreement is not netting eligible are rejected; clients without
+// an agreement are netted
+// Parameters:
+//   - clientID: ID of the client
+//   - valueDate: Value date (UTC day) to net
+func (s *Service) NetByCurrency(clientID string, valueDate time.Time) (*CurrencyNetting, error) {
+	clientID = common.NormalizeID(clientID)
+	agreement, err := s.db.GetCounterpartyAgreement(clientID)
+	if err != nil {
+		return nil, err
+	}
+	if agreement != nil && !agreement.NettingEligible {
+		return nil, ErrNettingNotEligible
+	}
+
+	day := time.Date(valueDate.Year(), valueDate.Month(), valueDate.Day(), 0, 0, 0, 0, time.UTC)
+	nets, err := s.db.GetCurrencyObligations(clientID, day, day.AddDate(0, 0, 1))
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range nets {
+		net := &nets[i]
+		net.Receivable = money.Round(net.Receivable, net.Currency)
+		net.Payable = money.Round(net.Payable, net.Currency)
+		net.NetAmount = money.Sum(net.Currency, net.Receivable, -net.Payable)
+		switch {
+		case net.NetAmount > 0:
+			net.Direction = DirectionReceive
+		case net.NetAmount < 0:
+			net.Direction = DirectionPay
+		default:
+			net.Direction = DirectionFlat
+		}
+	}
+
+	return &CurrencyNetting{
+		ClientID:   clientID,
+		ValueDate:  day.Format(valueDateLayout),
+		Currencies: nets,
+	}, nil
+}
+
+// GetCurrencyNettingHandler handles GET requests for a client's net settlement obligations per currency
+// Requires internal authentication
+// URL parameter: client_id
+// Query parameter: value_date (YYYY-MM-DD, optional; defaults to the current UTC day)
+func (h *GinHandlers) GetCurrencyNettingHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clientID := common.NormalizeID(c.Param("client_id"))
+		if clientID == "" {
+			response.BadRequest(c, "Client ID is required")
+			return
+		}
+
+		valueDate := h.service.clock.Now().UTC()
+		if param := c.Query("value_date"); param != "" {
+			parsed, err := time.Parse(valueDateLayout, param)
+			if err != nil {
+				response.BadRequest(c, "Invalid value_date format, expected YYYY-MM-DD")
+				return
+			}
+			valueDate = parsed
+		}
+
+		netting, err := h.service.NetByCurrency(clientID, valueDate)
+		if errors.Is(err, ErrNettingNotEligible) {
+			response.Conflict(c, err.Error())
+			return
+		}
+		response.Handle(c, netting, err)
+	}
+}
@@ -0,0 +1,64 @@
+package settlement
+
+import (
+	"testing"
+	"time"
+)
+
+// netOn nets the client's obligations for the value date, failing the test on error
+func netOn(t *testing.T, service *Service, clientID string, valueDate time.Time) *CurrencyNetting {
+	t.Helper()
+	netting, err := service.NetByCurrency(clientID, valueDate)
+	if err != nil {
+		t.Fatalf("NetByCurrency: %v", err)
+	}
+	return netting
+}
+
+func TestNetByCurrencyOffsetsBuysAndSells(t *testing.T) {
+	service, _ := newTestService(t)
+	buy := seedClearedTrade(t, service, "client-1", "BUY", 100, 150, testNow.Add(-2*time.Minute))
+	sell := seedClearedTrade(t, service, "client-1", "SELL", 40, 200, testNow.Add(-time.Minute))
+	valueDate := settleTrade(t, service, buy.ExecutionID).SettlementDate
+	settleTrade(t, service, sell.ExecutionID)
+	// Another client's trade is netted separately
+	other := seedClearedTrade(t, service, "client-2", "SELL", 100, 150, testNow.Add(-time.Minute))
+	settleTrade(t, service, other.ExecutionID)
+
+	netting := netOn(t, service, "client-1", valueDate)
+	if len(netting.Currencies) != 1 {
+		t.Fatalf("currencies = %+v, want USD only", netting.Currencies)
+	}
+	usd := netting.Currencies[0]
+	assertAmount(t, "receivable", usd.Receivable, 8000)
+	assertAmount(t, "payable", usd.Payable, 15000)
+	assertAmount(t, "net amount", usd.NetAmount, -7000)
+	if usd.Direction != DirectionPay || usd.Settlements != 2 {
+		t.Errorf("net = %s over %d settlements, want PAY over 2", usd.Direction, usd.Settlements)
+	}
+}
+
+func TestNetByCurrencyCountsSweptTradesBySide(t *testing.T) {
+	service := newDeferringService(t)
+
+	// Two 7,500 buys: the first is deferred, then swept into the second's settlement
+	first := seedClearedTrade(t, service, "client-1", "BUY", 50, 150, testNow.Add(-3*time.Minute))
+	settleTrade(t, service, first.ExecutionID)
+	second := seedClearedTrade(t, service, "client-1", "BUY", 50, 150, testNow.Add(-2*time.Minute))
+	combined := settleTrade(t, service, second.ExecutionID)
+	assertSweptInto(t, service, first.ExecutionID, combined.SettlementID)
+	sell := seedClearedTrade(t, service, "client-1", "SELL", 100, 120, testNow.Add(-time.Minute))
+	settleTrade(t, service, sell.ExecutionID)
+
+	netting := netOn(t, service, "client-1", combined.SettlementDate)
+	if len(netting.Currencies) != 1 {
+		t.Fatalf("currencies = %+v, want USD only", netting.Currencies)
+	}
+	usd := netting.Currencies[0]
+	assertAmount(t, "receivable", usd.Receivable, 12000)
+	assertAmount(t, "payable", usd.Payable, 15000)
+	assertAmount(t, "net amount", usd.NetAmount, -3000)
+	if usd.Settlements != 3 {
+		t.Errorf("netted %d trades, want the swept trade counted separately for 3", usd.Settlements)
+	}
+}
@@ -5,8 +5,12 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/ksred/klear-api/internal/clearing"
+	"github.com/ksred/klear-api/internal/events"
 	"github.com/ksred/klear-api/internal/types"
+	"github.com/ksred/klear-api/pkg/common"
+	"github.com/ksred/klear-api/pkg/money"
 	"gorm.io/gorm"
 )
 
@@ -18,8 +22,30 @@ func NewDatabase(db *gorm.DB) *Database {
 	return &Database{db: db}
 }
 
+// CreateSettlement creates a settlement and records its creation in the event log in a single transaction
 func (d *Database) CreateSettlement(settlement *Settlement) error {
-	return d.db.Create(settlement).Error
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		return createSettlement(tx, settlement)
+	})
+}
+
+// createSettlement creates a settlement using tx and records its creation in the event log
+func createSettlement(tx *gorm.DB, settlement *Settlement) error {
+	if err := tx.Create(settlement).Error; err != nil {
+		return err
+	}
+	return events.Append(tx, events.EventSettlementCreated, settlement.SettlementID, settlement, settlement.CreatedAt)
+}
+
+// appendSettlementStatusChanges records a status change in the event log for each of the settlements
+func appendSettlementStatusChanges(tx *gorm.DB, settlementIDs []string, from, to string, at time.Time) error {
+	for _, settlementID := range settlementIDs {
+		change := events.StatusChange{From: from, To: to}
+		if err := events.Append(tx, events.EventSettlementStatusChanged, settlementID, change, at); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (d *Database) GetSettlement(settlementID string) (*Settlement, error) {
@@ -38,16 +64,189 @@ func (d *Database) GetSettlementByTradeID(tradeID string) (*Settlement, error) {
 	return &settlement, nil
 }
 
+// GetActiveSettlementByTradeID retrieves the settlement for a trade that has not failed or been cancelled
+// Failed and cancelled settlements are left out so the trade can be settled again
+func (d *Database) GetActiveSettlementByTradeID(tradeID string) (*Settlement, error) {
+	var settlement Settlement
+	if err := d.db.Where("trade_id = ? AND settlement_status NOT IN ?", tradeID, []string{StatusFailed, StatusCancelled}).
+		Order("created_at DESC").
+		First(&settlement).Error; err != nil {
+		return nil, err
+	}
+	return &settlement, nil
+}
+
+// GetSettlementStatuses returns the latest settlement status for each of the client's trades in tradeIDs
+// Trades with no settlement are omitted from the result
+func (d *Database) GetSettlementStatuses(clientID string, tradeIDs []string) (map[string]string, error) {
+	var settlements []Settlement
+	if err := d.db.Select("trade_id", "settlement_status").
+		Where("trade_id IN (?) AND client_id = ?", tradeIDs, clientID).
+		Order("created_at ASC").
+		Find(&settlements).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch settlement statuses: %w", err)
+	}
+
+	statuses := make(map[string]string, len(settlements))
+	for _, settlement := range settlements {
+		statuses[settlement.TradeID] = settlement.SettlementStatus
+	}
+	return statuses, nil
+}
+
+// GetCounterpartyAgreement retrieves the agreement for a client, returning nil if the client has none
+func (d *Database) GetCounterpartyAgreement(clientID string) (*CounterpartyAgreement, error) {
+	var agreement CounterpartyAgreement
+	if err := d.db.Where("client_id = ?", clientID).First(&agreement).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to fetch counterparty agreement: %w", err)
+	}
+	return &agreement, nil
+}
+
+// SaveCounterpartyAgreement creates the client's agreement if it does not exist, otherwise updates it
+func (d *Database) SaveCounterpartyAgreement(agreement *CounterpartyAgreement) error {
+	existing, err := d.GetCounterpartyAgreement(agreement.ClientID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		agreement.ID = existing.ID
+		agreement.AgreementID = existing.AgreementID
+		agreement.CreatedAt = existing.CreatedAt
+	}
+	return d.db.Save(agreement).Error
+}
+
+// GetClientWebhook retrieves a client's settlement webhook, returning nil if the client has none
+func (d *Database) GetClientWebhook(clientID string) (*ClientWebhook, error) {
+	var webhook ClientWebhook
+	if err := d.db.Where("client_id = ?", clientID).First(&webhook).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to fetch client webhook: %w", err)
+	}
+	return &webhook, nil
+}
+
+// SaveClientWebhook creates the client's webhook if it does not exist, otherwise updates it
+func (d *Database) SaveClientWebhook(webhook *ClientWebhook) error {
+	existing, err := d.GetClientWebhook(webhook.ClientID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		webhook.ID = existing.ID
+		webhook.CreatedAt = existing.CreatedAt
+	}
+	return d.db.Save(webhook).Error
+}
+
+// DeleteClientWebhook permanently removes a client's webhook so the client can register again
+// deleted reports whether the client had a webhook
+func (d *Database) DeleteClientWebhook(clientID string) (deleted bool, err error) {
+	result := d.db.Unscoped().Where("client_id = ?", clientID).Delete(&ClientWebhook{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// CreateWebhookDeadLetter records a webhook event that could not be delivered
+func (d *Database) CreateWebhookDeadLetter(deadLetter *WebhookDeadLetter) error {
+	return d.db.Create(deadLetter).Error
+}
+
+// GetWebhookDeadLetter retrieves a dead-lettered webhook event, returning nil if there is none with the ID
+func (d *Database) GetWebhookDeadLetter(deadLetterID string) (*WebhookDeadLetter, error) {
+	var deadLetter WebhookDeadLetter
+	if err := d.db.Where("dead_letter_id = ?", deadLetterID).First(&deadLetter).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to fetch webhook dead letter: %w", err)
+	}
+	return &deadLetter, nil
+}
+
+// ListWebhookDeadLetters retrieves dead-lettered webhook events in a status, oldest first
+func (d *Database) ListWebhookDeadLetters(status string) ([]WebhookDeadLetter, error) {
+	deadLetters := []WebhookDeadLetter{}
+	err := d.db.Where("status = ?", status).Order("created_at ASC").Find(&deadLetters).Error
+	return deadLetters, err
+}
+
+// RecordRedriveFailure counts a failed re-drive of a dead letter that is still DEAD and keeps its error
+func (d *Database) RecordRedriveFailure(deadLetterID, lastError string, at time.Time) error {
+	return d.db.Model(&WebhookDeadLetter{}).
+		Where("dead_letter_id = ? AND status = ?", deadLetterID, DeadLetterStatusDead).
+		Updates(map[string]interface{}{
+			"attempts":   gorm.Expr("attempts + 1"),
+			"last_error": lastError,
+			"updated_at": at,
+		}).Error
+}
+
+// MarkDeadLetterRedriven marks a DEAD dead letter as delivered by a re-drive
+// redriven reports whether it was still DEAD, so two concurrent re-drives cannot both record delivery
+func (d *Database) MarkDeadLetterRedriven(deadLetterID string, at time.Time) (redriven bool, err error) {
+	result := d.db.Model(&WebhookDeadLetter{}).
+		Where("dead_letter_id = ? AND status = ?", deadLetterID, DeadLetterStatusDead).
+		Updates(map[string]interface{}{
+			"status":      DeadLetterStatusRedriven,
+			"attempts":    gorm.Expr("attempts + 1"),
+			"redriven_at": at,
+			"updated_at":  at,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
 func (d *Database) UpdateSettlement(settlement *Settlement) error {
 	return d.db.Save(settlement).Error
 }
 
-func (d *Database) UpdateSettlementStatus(settlementID string, status string) error {
+// TransitionSettlementStatus moves a settlement from one status to another and records the change in the event log
+// The update only applies while the settlement is still in the from status; transitioned reports whether it did
+// Moving to SETTLED also records at as when the settlement settled
+func (d *Database) TransitionSettlementStatus(settlementID, from, to string, at time.Time) (transitioned bool, err error) {
+	updates := map[string]interface{}{
+		"settlement_status": to,
+		"updated_at":        at,
+	}
+	if to == StatusSettled && from != StatusSettled {
+		updates["settled_at"] = at
+	}
+	err = d.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&Settlement{}).
+			Where("settlement_id = ? AND settlement_status = ?", settlementID, from).
+			Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		transitioned = true
+		return appendSettlementStatusChanges(tx, []string{settlementID}, from, to, at)
+	})
+	if err != nil {
+		return false, err
+	}
+	return transitioned, nil
+}
+
+func (d *Database) UpdateSettlementStatus(settlementID string, status string, at time.Time) error {
 	result := d.db.Model(&Settlement{}).
 		Where("settlement_id = ?", settlementID).
 		Updates(map[string]interface{}{
 			"settlement_status": status,
-			"updated_at":       time.Now(),
+			"updated_at":       at,
 		})
 	
 	if result.Error != nil {
@@ -77,6 +276,476 @@ func (d *Database) GetClientSettlements(clientID string) ([]Settlement, error) {
 	return settlements, nil
 }
 
+// ListClientSettlements retrieves a page of a client's settlements, newest first
+func (d *Database) ListClientSettlements(clientID string, page common.Page) ([]Settlement, error) {
+	settlements := []Settlement{}
+	err := d.db.Where("client_id = ?", clientID).Scopes(page.Apply).Find(&settlements).Error
+	return settlements, err
+}
+
+// settlementSideJoin joins each settlement to the order behind its execution, whose side
+// says whether the client pays (BUY) or receives (SELL) the settlement amount
+const settlementSideJoin = "JOIN executions ON executions.execution_id = settlements.execution_id " +
+	"JOIN orders ON orders.order_id = executions.order_id"
+
+// signedAmount signs a settlement amount by its trade's side: sells are received and buys paid
+func signedAmount(amount float64, side string) float64 {
+	if side == "BUY" {
+		return -amount
+	}
+	return amount
+}
+
+// getSettlementSide returns the side of the order behind a settlement using tx
+func getSettlementSide(tx *gorm.DB, settlementID string) (string, error) {
+	var sides []string
+	if err := tx.Model(&Settlement{}).
+		Joins(settlementSideJoin).
+		Where("settlements.settlement_id = ?", settlementID).
+		Pluck("orders.side", &sides).Error; err != nil {
+		return "", err
+	}
+	if len(sides) == 0 {
+		return "", fmt.Errorf("no order found for settlement %s", settlementID)
+	}
+	return sides[0], nil
+}
+
+// GetDeferredSettlements retrieves a client's deferred settlements in a currency for trades on one side, oldest first
+func (d *Database) GetDeferredSettlements(clientID, currency, side string) ([]Settlement, error) {
+	var settlements []Settlement
+	if err := d.db.Joins(settlementSideJoin).
+		Where("settlements.client_id = ? AND settlements.currency = ? AND settlements.settlement_status = ? AND orders.side = ?",
+			clientID, currency, StatusDeferred, side).
+		Order("settlements.created_at ASC").
+		Find(&settlements).Error; err != nil {
+		return nil, err
+	}
+	return settlements, nil
+}
+
+// CreateSweepingSettlement creates a settlement and marks the deferred settlements it absorbs as swept into it
+// Both happen in one transaction; if any of the deferred settlements is no longer deferred nothing is saved
+func (d *Database) CreateSweepingSettlement(settlement *Settlement, deferredIDs []string) error {
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		if err := createSettlement(tx, settlement); err != nil {
+			return err
+		}
+		return sweepDeferred(tx, settlement.SettlementID, deferredIDs, settlement.CreatedAt)
+	})
+}
+
+// sweepDeferred marks deferred settlements as swept into the given settlement at the given time
+// Returns ErrDeferredSweepConflict if any of them is no longer deferred
+func sweepDeferred(tx *gorm.DB, settlementID string, deferredIDs []string, now time.Time) error {
+	result := tx.Model(&Settlement{}).
+		Where("settlement_id IN ? AND settlement_status = ?", deferredIDs, StatusDeferred).
+		Updates(map[string]interface{}{
+			"settlement_status": StatusSwept,
+			"swept_into":        settlementID,
+			"updated_at":        now,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected != int64(len(deferredIDs)) {
+		return ErrDeferredSweepConflict
+	}
+	return appendSettlementStatusChanges(tx, deferredIDs, StatusDeferred, StatusSwept, now)
+}
+
+// CreateBatchedSettlement creates a settlement of a trade on the given side, sweeps any deferred
+// settlements into it and adds it to the client's batch for its currency and value date, creating
+// the batch if there is none yet. The batch nets the settlement signed by side
+// Everything happens in one transaction; a batch created concurrently for the same key is retried once
+func (d *Database) CreateBatchedSettlement(settlement *Settlement, side string, deferredIDs []string, valueDate string) error {
+	create := func() error {
+		return d.db.Transaction(func(tx *gorm.DB) error {
+			var batch SettlementBatch
+			err := tx.Where("client_id = ? AND currency = ? AND value_date = ?",
+				settlement.ClientID, settlement.Currency, valueDate).
+				First(&batch).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				batch = SettlementBatch{
+					BatchID:   "BATCH_" + uuid.New().String(),
+					ClientID:  settlement.ClientID,
+					Currency:  settlement.Currency,
+					ValueDate: valueDate,
+				}
+				err = tx.Create(&batch).Error
+			}
+			if err != nil {
+				return err
+			}
+
+			settlement.BatchID = batch.BatchID
+			if err := createSettlement(tx, settlement); err != nil {
+				return err
+			}
+			if len(deferredIDs) > 0 {
+				if err := sweepDeferred(tx, settlement.SettlementID, deferredIDs, settlement.CreatedAt); err != nil {
+					return err
+				}
+			}
+
+			return tx.Model(&batch).Updates(map[string]interface{}{
+				"net_amount":       money.Sum(batch.Currency, batch.NetAmount, signedAmount(settlement.FinalAmount, side)),
+				"settlement_fees":  money.Sum(batch.Currency, batch.SettlementFees, settlement.SettlementFees),
+				"settlement_count": batch.SettlementCount + 1,
+				"updated_at":       settlement.CreatedAt,
+			}).Error
+		})
+	}
+
+	err := create()
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		settlement.BatchID = ""
+		err = create()
+	}
+	if err != nil {
+		settlement.BatchID = ""
+	}
+	return err
+}
+
+// CancelSettlement moves a PENDING settlement to CANCELLED with the given reason, returns the deferred
+// settlements it swept to DEFERRED and removes it from its settlement batch, all in one transaction
+// cancelled reports whether the settlement was still in the status it was read with and so was cancelled
+func (d *Database) CancelSettlement(settlement *Settlement, reason string, now time.Time) (cancelled bool, err error) {
+	err = d.db.Transaction(func(tx *gorm.DB) error {
+		var cancelErr error
+		cancelled, cancelErr = cancelSettlement(tx, settlement, reason, now)
+		return cancelErr
+	})
+	if err != nil {
+		return false, err
+	}
+	return cancelled, nil
+}
+
+// cancelSettlement cancels a settlement using tx, unwinding its sweeps and batch
+// cancelled is false, with nothing changed, if the settlement's status has changed since it was read
+func cancelSettlement(tx *gorm.DB, settlement *Settlement, reason string, now time.Time) (cancelled bool, err error) {
+	result := tx.Model(&Settlement{}).
+		Where("settlement_id = ? AND settlement_status = ?", settlement.SettlementID, settlement.SettlementStatus).
+		Updates(map[string]interface{}{
+			"settlement_status": StatusCancelled,
+			"cancel_reason":     reason,
+			"updated_at":        now,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	if err := appendSettlementStatusChanges(tx, []string{settlement.SettlementID}, settlement.SettlementStatus, StatusCancelled, now); err != nil {
+		return false, err
+	}
+
+	var sweptIDs []string
+	if err := tx.Model(&Settlement{}).
+		Where("swept_into = ? AND settlement_status = ?", settlement.SettlementID, StatusSwept).
+		Pluck("settlement_id", &sweptIDs).Error; err != nil {
+		return false, err
+	}
+	if len(sweptIDs) > 0 {
+		if err := tx.Model(&Settlement{}).
+			Where("settlement_id IN ? AND settlement_status = ?", sweptIDs, StatusSwept).
+			Updates(map[string]interface{}{
+				"settlement_status": StatusDeferred,
+				"swept_into":        "",
+				"updated_at":        now,
+			}).Error; err != nil {
+			return false, err
+		}
+		if err := appendSettlementStatusChanges(tx, sweptIDs, StatusSwept, StatusDeferred, now); err != nil {
+			return false, err
+		}
+	}
+
+	if settlement.BatchID == "" {
+		return true, nil
+	}
+	var batch SettlementBatch
+	if err := tx.Where("batch_id = ?", settlement.BatchID).First(&batch).Error; err != nil {
+		return false, err
+	}
+	side, err := getSettlementSide(tx, settlement.SettlementID)
+	if err != nil {
+		return false, err
+	}
+	return true, tx.Model(&batch).Updates(map[string]interface{}{
+		"net_amount":       money.Sum(batch.Currency, batch.NetAmount, -signedAmount(settlement.FinalAmount, side)),
+		"settlement_fees":  money.Sum(batch.Currency, batch.SettlementFees, -settlement.SettlementFees),
+		"settlement_count": batch.SettlementCount - 1,
+		"updated_at":       now,
+	}).Error
+}
+
+// cancelSweptSettlement cancels a SWEPT settlement using tx and takes its amount and fees back out
+// of the settlement it was swept into, and that settlement's batch, while that settlement is PENDING
+// Returns ErrTradeSettled if the settlement it was swept into is settling or has settled
+// cancelled is false, with nothing changed, if either settlement's status has changed since it was read
+func cancelSweptSettlement(tx *gorm.DB, swept *Settlement, reason string, now time.Time) (cancelled bool, err error) {
+	var into Settlement
+	if err := tx.Where("settlement_id = ?", swept.SweptInto).First(&into).Error; err != nil {
+		return false, err
+	}
+	if into.SettlementStatus == StatusSettled || into.SettlementStatus == StatusSettling {
+		return false, fmt.Errorf("%w: settlement %s was swept into %s settlement %s",
+			ErrTradeSettled, swept.SettlementID, into.SettlementStatus, into.SettlementID)
+	}
+
+	result := tx.Model(&Settlement{}).
+		Where("settlement_id = ? AND settlement_status = ?", swept.SettlementID, StatusSwept).
+		Updates(map[string]interface{}{
+			"settlement_status": StatusCancelled,
+			"cancel_reason":     reason,
+			"updated_at":        now,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	if err := appendSettlementStatusChanges(tx, []string{swept.SettlementID}, StatusSwept, StatusCancelled, now); err != nil {
+		return false, err
+	}
+
+	// A failed settlement moves no money, so there is nothing to take back out of it
+	if into.SettlementStatus != StatusPending {
+		return true, nil
+	}
+	currency := into.Currency
+	into.FinalAmount = money.Sum(currency, into.FinalAmount, -swept.FinalAmount)
+	into.DeferredAmount = money.Sum(currency, into.DeferredAmount, -swept.FinalAmount)
+	into.GrossFees = money.Sum(currency, into.GrossFees, -swept.GrossFees)
+	into.FeeRebate = money.Sum(currency, into.FeeRebate, -swept.FeeRebate)
+	into.SettlementFees = money.Sum(currency, into.GrossFees, -into.FeeRebate)
+	into.UpdatedAt = now
+	// Save hooks keep the exact integer columns in step; the clock stamps updated_at rather than gorm's
+	result = tx.Session(&gorm.Session{NowFunc: func() time.Time { return now }}).
+		Model(&into).
+		Where("settlement_status = ?", into.SettlementStatus).
+		Select("final_amount", "final_amount_units", "deferred_amount", "gross_fees", "fee_rebate",
+			"settlement_fees", "settlement_fees_units", "updated_at").
+		Updates(&into)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+
+	if into.BatchID == "" {
+		return true, nil
+	}
+	var batch SettlementBatch
+	if err := tx.Where("batch_id = ?", into.BatchID).First(&batch).Error; err != nil {
+		return false, err
+	}
+	side, err := getSettlementSide(tx, swept.SettlementID)
+	if err != nil {
+		return false, err
+	}
+	return true, tx.Model(&batch).Updates(map[string]interface{}{
+		"net_amount":      money.Sum(batch.Currency, batch.NetAmount, -signedAmount(swept.FinalAmount, side)),
+		"settlement_fees": money.Sum(batch.Currency, batch.SettlementFees, -swept.SettlementFees),
+		"updated_at":      now,
+	}).Error
+}
+
+// SettlementTiming pairs when a trade was executed with when its settlement settled
+type SettlementTiming struct {
+	ExecutedAt time.Time
+	SettledAt  time.Time
+}
+
+// GetSettlementTimings returns the execution and settlement times of trades whose settlement
+// reached SETTLED within [start, end)
+func (d *Database) GetSettlementTimings(start, end time.Time) ([]SettlementTiming, error) {
+	var timings []SettlementTiming
+	err := d.db.Table("settlements").
+		Select("executions.created_at AS executed_at, settlements.settled_at AS settled_at").
+		Joins("JOIN executions ON executions.execution_id = settlements.trade_id").
+		Where("settlements.settlement_status = ? AND settlements.settled_at >= ? AND settlements.settled_at < ?",
+			StatusSettled, start, end).
+		Where("settlements.deleted_at IS NULL").
+		Scan(&timings).Error
+	if err != nil {
+		return nil, err
+	}
+	return timings, nil
+}
+
+// GetSettlementBatch retrieves a settlement batch and the settlements it covers, oldest first
+func (d *Database) GetSettlementBatch(batchID string) (*SettlementBatch, error) {
+	var batch SettlementBatch
+	if err := d.db.Where("batch_id = ?", batchID).First(&batch).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBatchNotFound
+		}
+		return nil, err
+	}
+	if err := d.db.Where("batch_id = ?", batchID).
+		Order("created_at ASC").
+		Find(&batch.Settlements).Error; err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+// GetDeferredBalances totals a client's deferred settlements per currency and trade side
+// Amounts are summed as exact integer units so many small settlements total without drift
+func (d *Database) GetDeferredBalances(clientID string) ([]DeferredBalance, error) {
+	var rows []struct {
+		Currency    string
+		Side        string
+		Units       int64
+		Settlements int
+	}
+	err := d.db.Model(&Settlement{}).
+		Joins(settlementSideJoin).
+		Select("settlements.currency AS currency, orders.side AS side, "+
+			"SUM(settlements.final_amount_units) AS units, COUNT(*) AS settlements").
+		Where("settlements.client_id = ? AND settlements.settlement_status = ?", clientID, StatusDeferred).
+		Group("settlements.currency, orders.side").
+		Order("settlements.currency").
+		Order("orders.side").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	balances := make([]DeferredBalance, 0, len(rows))
+	for _, row := range rows {
+		balances = append(balances, DeferredBalance{
+			Currency:    row.Currency,
+			Side:        row.Side,
+			Amount:      money.FromUnits(row.Units),
+			Settlements: row.Settlements,
+		})
+	}
+	return balances, nil
+}
+
+// GetCurrencyObligations totals a client's trades settling in [start, end) per currency, splitting sells
+// (receivable) from buys (payable) by the side of each trade's own order
+// A settlement that swept deferred settlements counts only its own trade; each swept settlement counts
+// separately on the value date of the settlement it was swept into
+func (d *Database) GetCurrencyObligations(clientID string, start, end time.Time) ([]CurrencyNet, error) {
+	var rows []struct {
+		Currency        string
+		ReceivableUnits int64
+		PayableUnits    int64
+		Settlements     int
+	}
+	query := `
+		SELECT currency,
+			COALESCE(SUM(CASE WHEN side = 'SELL' THEN units ELSE 0 END), 0) AS receivable_units,
+			COALESCE(SUM(CASE WHEN side = 'BUY' THEN units ELSE 0 END), 0) AS payable_units,
+			COUNT(*) AS settlements
+		FROM (
+			SELECT settlements.currency AS currency, orders.side AS side,
+				settlements.final_amount_units - COALESCE((
+					SELECT SUM(swept.final_amount_units) FROM settlements AS swept
+					WHERE swept.swept_into = settlements.settlement_id
+					AND swept.settlement_status = ?
+					AND swept.deleted_at IS NULL
+				), 0) AS units
+			FROM settlements
+			JOIN executions ON executions.execution_id = settlements.execution_id
+			JOIN orders ON orders.order_id = executions.order_id
+			WHERE settlements.client_id = ?
+			AND settlements.settlement_date >= ?
+			AND settlements.settlement_date < ?
+			AND settlements.settlement_status IN ?
+			AND settlements.deleted_at IS NULL
+			UNION ALL
+			SELECT swept.currency AS currency, orders.side AS side, swept.final_amount_units AS units
+			FROM settlements AS swept
+			JOIN settlements ON settlements.settlement_id = swept.swept_into
+			JOIN executions ON executions.execution_id = swept.execution_id
+			JOIN orders ON orders.order_id = executions.order_id
+			WHERE swept.client_id = ?
+			AND swept.settlement_status = ?
+			AND swept.deleted_at IS NULL
+			AND settlements.settlement_date >= ?
+			AND settlements.settlement_date < ?
+			AND settlements.settlement_status IN ?
+			AND settlements.deleted_at IS NULL
+		) AS trades
+		GROUP BY currency
+		ORDER BY currency
+	`
+	statuses := []string{StatusPending, StatusSettling, StatusSettled}
+	if err := d.db.Raw(query,
+		StatusSwept, clientID, start, end, statuses,
+		clientID, StatusSwept, start, end, statuses,
+	).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	nets := make([]CurrencyNet, 0, len(rows))
+	for _, row := range rows {
+		nets = append(nets, CurrencyNet{
+			Currency:    row.Currency,
+			Receivable:  money.FromUnits(row.ReceivableUnits),
+			Payable:     money.FromUnits(row.PayableUnits),
+			Settlements: row.Settlements,
+		})
+	}
+	return nets, nil
+}
+
+// GetExecutionFees sums the venue fees on a client's fills created in [start, end) by order symbol and currency
+func (d *Database) GetExecutionFees(clientID string, start, end time.Time) ([]feeAmount, error) {
+	fees := []feeAmount{}
+	query := `
+		SELECT orders.symbol AS symbol,
+			orders.currency AS currency,
+			COALESCE(SUM(exchange_fills.fee_amount_units), 0) AS units
+		FROM exchange_fills
+		JOIN executions ON executions.execution_id = exchange_fills.execution_id
+		JOIN orders ON orders.order_id = executions.order_id
+		WHERE orders.client_id = ?
+		AND exchange_fills.created_at >= ?
+		AND exchange_fills.created_at < ?
+		AND exchange_fills.deleted_at IS NULL
+		AND executions.deleted_at IS NULL
+		GROUP BY orders.symbol, orders.currency
+	`
+	if err := d.db.Raw(query, clientID, start, end).Scan(&fees).Error; err != nil {
+		return nil, err
+	}
+	return fees, nil
+}
+
+// GetSettlementFees sums the net fees of a client's settlements created in [start, end) by order symbol
+// and settlement currency, leaving out settlements that charge no fees of their own
+func (d *Database) GetSettlementFees(clientID string, start, end time.Time) ([]feeAmount, error) {
+	fees := []feeAmount{}
+	query := `
+		SELECT orders.symbol AS symbol,
+			settlements.currency AS currency,
+			COALESCE(SUM(settlements.settlement_fees_units), 0) AS units
+		FROM settlements
+		JOIN executions ON executions.execution_id = settlements.execution_id
+		JOIN orders ON orders.order_id = executions.order_id
+		WHERE settlements.client_id = ?
+		AND settlements.created_at >= ?
+		AND settlements.created_at < ?
+		AND settlements.settlement_status NOT IN ?
+		AND settlements.deleted_at IS NULL
+		GROUP BY orders.symbol, settlements.currency
+	`
+	excluded := []string{StatusFailed, StatusCancelled, StatusSwept}
+	if err := d.db.Raw(query, clientID, start, end, excluded).Scan(&fees).Error; err != nil {
+		return nil, err
+	}
+	return fees, nil
+}
+
 func (d *Database) GetSettlementsByDateRange(startDate, endDate time.Time) ([]Settlement, error) {
 	var settlements []Settlement
 	if err := d.db.Where("settlement_date BETWEEN ? AND ?", startDate, endDate).
@@ -91,6 +760,9 @@ func (d *Database) GetSettlementsByDateRange(startDate, endDate time.Time) ([]Se
 func (d *Database) GetExecutionByID(executionID string) (*types.Execution, error) {
 	var execution types.Execution
 	if err := d.db.Where("execution_id = ?", executionID).First(&execution).Error; err != nil {
+		if types.IsSoftDeleted(d.db, &types.Execution{}, "execution_id", executionID, err) {
+			return nil, fmt.Errorf("%w: %s", types.ErrExecutionDeleted, executionID)
+		}
 		return nil, fmt.Errorf("failed to fetch execution: %w", err)
 	}
 	return &execution, nil
@@ -100,16 +772,191 @@ func (d *Database) GetExecutionByID(executionID string) (*types.Execution, error
 func (d *Database) GetOrderByID(orderID string) (*types.Order, error) {
 	var order types.Order
 	if err := d.db.Where("order_id = ?", orderID).First(&order).Error; err != nil {
+		if types.IsSoftDeleted(d.db, &types.Order{}, "order_id", orderID, err) {
+			return nil, fmt.Errorf("%w: %s", types.ErrOrderDeleted, orderID)
+		}
 		return nil, fmt.Errorf("failed to fetch order: %w", err)
 	}
 	return &order, nil
 }
 
-// GetClearingByTradeID retrieves clearing details by trade ID
-func (d *Database) GetClearingByTradeID(tradeID string) (*clearing.Clearing, error) {
+// GetMonthToDateVolume retrieves a client's traded notional from the start of the month up to asOf
+func (d *Database) GetMonthToDateVolume(clientID string, asOf time.Time) (float64, error) {
+	var volume float64
+
+	asOf = asOf.UTC()
+	startOfMonth := time.Date(asOf.Year(), asOf.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	query := `
+		SELECT COALESCE(SUM(executions.total_quantity * executions.average_price), 0) as volume
+		FROM executions
+		JOIN orders ON orders.order_id = executions.order_id
+		WHERE orders.client_id = ?
+		AND executions.created_at >= ?
+		AND executions.created_at <= ?
+		AND executions.status = 'COMPLETED'`
+
+	if err := d.db.Raw(query, clientID, startOfMonth, asOf).Scan(&volume).Error; err != nil {
+		return 0, fmt.Errorf("failed to calculate month-to-date volume: %w", err)
+	}
+
+	return volume, nil
+}
+
+// GetClearedClearingByTradeID retrieves the successful clearing for a trade, the latest if it was
+// cleared more than once; failed and reversed attempts are ignored
+func (d *Database) GetClearedClearingByTradeID(tradeID string) (*clearing.Clearing, error) {
 	var clearingRecord clearing.Clearing
-	if err := d.db.Where("trade_id = ?", tradeID).First(&clearingRecord).Error; err != nil {
+	if err := d.db.Where("trade_id = ? AND clearing_status = ?", tradeID, clearing.StatusCleared).
+		Order("created_at DESC").
+		First(&clearingRecord).Error; err != nil {
 		return nil, fmt.Errorf("failed to fetch clearing: %w", err)
 	}
 	return &clearingRecord, nil
-} 
\ No newline at end of file
+}
+
+// GetTradeLifecycle retrieves every stage recorded for a trade, leaving stages not yet reached as nil
+func (d *Database) GetTradeLifecycle(executionID string) (*TradeLifecycle, error) {
+	lifecycle := &TradeLifecycle{}
+
+	var execution types.Execution
+	if err := d.db.Preload("Fills").Preload("Allocations").
+		Where("execution_id = ?", executionID).
+		First(&execution).Error; err != nil {
+		return nil, err
+	}
+	lifecycle.Execution = &execution
+
+	var order types.Order
+	if err := d.db.Where("order_id = ?", execution.OrderID).First(&order).Error; err == nil {
+		lifecycle.Order = &order
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to fetch order: %w", err)
+	}
+
+	lifecycle.FailedAttempts = []types.VenueAttempt{}
+	if err := d.db.Where("order_id = ?", execution.OrderID).
+		Order("created_at ASC").
+		Find(&lifecycle.FailedAttempts).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch venue attempts: %w", err)
+	}
+
+	var clearingRecord clearing.Clearing
+	if err := d.db.Where("trade_id = ?", executionID).
+		Order("created_at DESC").
+		First(&clearingRecord).Error; err == nil {
+		lifecycle.Clearing = &clearingRecord
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to fetch clearing: %w", err)
+	}
+
+	var settlement Settlement
+	if err := d.db.Where("trade_id = ?", executionID).
+		Order("created_at DESC").
+		First(&settlement).Error; err == nil {
+		lifecycle.Settlement = &settlement
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to fetch settlement: %w", err)
+	}
+
+	return lifecycle, nil
+}
+
+// BustTrade unwinds a busted trade in a single transaction: the trade's PENDING, DEFERRED and SWEPT
+// settlements are cancelled, its clearings reversed and its execution and order marked BUSTED, each
+// change recorded in the event log along with the bust itself
+// The settlements are read in the transaction; cancelled holds each as it was before cancellation
+// Returns ErrTradeSettled if a settlement of the trade is settling or has settled, and
+// ErrTradeBustConflict if the execution or a settlement changed while the trade was being busted
+func (d *Database) BustTrade(execution *types.Execution, order *types.Order, reason string, now time.Time) (bust *TradeBust, cancelled []Settlement, err error) {
+	bust = &TradeBust{
+		ExecutionID:          execution.ExecutionID,
+		OrderID:              execution.OrderID,
+		Reason:               reason,
+		CancelledSettlements: []string{},
+		ReversedClearings:    []string{},
+		BustedAt:             now,
+	}
+
+	err = d.db.Transaction(func(tx *gorm.DB) error {
+		cancelled = nil
+		result := tx.Model(&types.Execution{}).
+			Where("execution_id = ? AND status = ?", execution.ExecutionID, execution.Status).
+			Updates(map[string]interface{}{"status": TradeStatusBusted, "updated_at": now})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("%w: execution status changed concurrently", ErrTradeBustConflict)
+		}
+
+		if order != nil && order.Status != TradeStatusBusted {
+			if err := tx.Model(&types.Order{}).
+				Where("order_id = ?", order.OrderID).
+				Updates(map[string]interface{}{"status": TradeStatusBusted, "updated_at": now}).Error; err != nil {
+				return err
+			}
+			change := events.StatusChange{From: order.Status, To: TradeStatusBusted}
+			if err := events.Append(tx, events.EventOrderStatusChanged, order.OrderID, change, now); err != nil {
+				return err
+			}
+		}
+
+		var settlements []Settlement
+		if err := tx.Where("trade_id = ?", execution.ExecutionID).
+			Order("created_at ASC").
+			Find(&settlements).Error; err != nil {
+			return err
+		}
+		bust.CancelledSettlements = []string{}
+		for i := range settlements {
+			settlement := &settlements[i]
+			var ok bool
+			var err error
+			switch settlement.SettlementStatus {
+			case StatusPending, StatusDeferred:
+				ok, err = cancelSettlement(tx, settlement, reason, now)
+			case StatusSwept:
+				ok, err = cancelSweptSettlement(tx, settlement, reason, now)
+			case StatusFailed, StatusCancelled:
+				// Nothing left to unwind
+				continue
+			default:
+				// A SETTLING settlement may already be moving money, so it is treated as settled
+				return fmt.Errorf("%w: settlement %s is %s", ErrTradeSettled, settlement.SettlementID, settlement.SettlementStatus)
+			}
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return fmt.Errorf("%w: settlement %s status changed concurrently", ErrTradeBustConflict, settlement.SettlementID)
+			}
+			bust.CancelledSettlements = append(bust.CancelledSettlements, settlement.SettlementID)
+			cancelled = append(cancelled, *settlement)
+		}
+
+		var clearings []clearing.Clearing
+		if err := tx.Where("trade_id = ? AND clearing_status <> ?", execution.ExecutionID, clearing.StatusReversed).
+			Find(&clearings).Error; err != nil {
+			return err
+		}
+		for _, clearingRecord := range clearings {
+			if err := tx.Model(&clearing.Clearing{}).
+				Where("clearing_id = ?", clearingRecord.ClearingID).
+				Updates(map[string]interface{}{"clearing_status": clearing.StatusReversed, "updated_at": now}).Error; err != nil {
+				return err
+			}
+			change := events.StatusChange{From: clearingRecord.ClearingStatus, To: clearing.StatusReversed}
+			if err := events.Append(tx, events.EventClearingStatusChanged, clearingRecord.ClearingID, change, now); err != nil {
+				return err
+			}
+			bust.ReversedClearings = append(bust.ReversedClearings, clearingRecord.ClearingID)
+		}
+
+		return events.Append(tx, events.EventTradeBusted, execution.ExecutionID, bust, now)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return bust, cancelled, nil
+}
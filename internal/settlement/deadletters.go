@@ -0,0 +1,113 @@
+package settlement
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/klear-api/pkg/common"
+	"github.com/ksred/klear-api/pkg/response"
+)
+
+// ErrCodeWebhookDeliveryFailed is returned when a re-drive reaches the client's webhook but is not accepted
+const ErrCodeWebhookDeliveryFailed = "WEBHOOK_DELIVERY_FAILED"
+
+var (
+	ErrDeadLetterNotFound      = errors.New("webhook dead letter not found")
+	ErrDeadLetterRedriven      = errors.New("webhook dead letter has already been re-driven")
+	ErrInvalidDeadLetterStatus = errors.New("status must be DEAD or REDRIVEN")
+	ErrRedriveFailed           = errors.New("webhook re-drive failed")
+)
+
+// ListDeadLetters lists dead-lettered webhook events, oldest first
+// Parameters:
+//   - status: DEAD or REDRIVEN; empty lists the events still waiting to be re-driven
+func (s *Service) ListDeadLetters(status string) ([]WebhookDeadLetter, error) {
+	status = strings.ToUpper(strings.TrimSpace(status))
+	if status == "" {
+		status = DeadLetterStatusDead
+	}
+	if status != DeadLetterStatusDead && status != DeadLetterStatusRedriven {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidDeadLetterStatus, status)
+	}
+	return s.db.ListWebhookDeadLetters(status)
+}
+
+// RedriveDeadLetter makes one more attempt to deliver a dead-lettered event
+// The event is signed afresh and sent to the webhook the client has registered now, so a client
+// that fixed or moved its endpoint receives it; a failed attempt leaves the event dead-lettered
+// Parameters:
+//   - deadLetterID: ID of the dead letter to re-drive
+func (s *Service) RedriveDeadLetter(deadLetterID string) (*WebhookDeadLetter, error) {
+	deadLetterID = common.NormalizeID(deadLetterID)
+	deadLetter, err := s.db.GetWebhookDeadLetter(deadLetterID)
+	if err != nil {
+		return nil, err
+	}
+	if deadLetter == nil {
+		return nil, ErrDeadLetterNotFound
+	}
+	if deadLetter.Status != DeadLetterStatusDead {
+		return nil, ErrDeadLetterRedriven
+	}
+
+	webhook, err := s.db.GetClientWebhook(deadLetter.ClientID)
+	if err != nil {
+		return nil, err
+	}
+	if webhook == nil {
+		return nil, ErrWebhookNotFound
+	}
+
+	if err := s.notifier.post(webhook.URL, webhook.Secret, []byte(deadLetter.Payload)); err != nil {
+		if recordErr := s.db.RecordRedriveFailure(deadLetterID, err.Error(), s.clock.Now()); recordErr != nil {
+			return nil, recordErr
+		}
+		return nil, fmt.Errorf("%w: %v", ErrRedriveFailed, err)
+	}
+
+	redriven, err := s.db.MarkDeadLetterRedriven(deadLetterID, s.clock.Now())
+	if err != nil {
+		return nil, err
+	}
+	if !redriven {
+		// Another re-drive delivered it first
+		return nil, ErrDeadLetterRedriven
+	}
+	return s.db.GetWebhookDeadLetter(deadLetterID)
+}
+
+// ListDeadLettersHandler handles GET requests for dead-lettered webhook events
+// Requires a token with the admin permission
+// Query parameter: status (optional, DEAD or REDRIVEN, defaults to DEAD)
+func (h *GinHandlers) ListDeadLettersHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		deadLetters, err := h.service.ListDeadLetters(c.Query("status"))
+		if errors.Is(err, ErrInvalidDeadLetterStatus) {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		response.Handle(c, deadLetters, err)
+	}
+}
+
+// RedriveDeadLetterHandler handles POST requests to re-drive a dead-lettered webhook event
+// Requires a token with the admin permission
+// URL parameter: dead_letter_id
+func (h *GinHandlers) RedriveDeadLetterHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		deadLetter, err := h.service.RedriveDeadLetter(c.Param("dead_letter_id"))
+		switch {
+		case errors.Is(err, ErrDeadLetterNotFound):
+			response.NotFound(c, err.Error())
+		case errors.Is(err, ErrDeadLetterRedriven), errors.Is(err, ErrWebhookNotFound):
+			response.Conflict(c, err.Error())
+		case errors.Is(err, ErrRedriveFailed):
+			response.WithCode(c, http.StatusBadGateway, ErrCodeWebhookDeliveryFailed, err.Error())
+		default:
+			response.HandleWithStatus(c, deadLetter, err, http.StatusOK)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package settlement
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/klear-api/pkg/common"
+	"github.com/ksred/klear-api/pkg/money"
+	"github.com/ksred/klear-api/pkg/response"
+	"github.com/rs/zerolog/log"
+)
+
+// DeferredBalance totals a client's deferred settlements in one currency for trades on one side
+// Buys are amounts the client pays and sells amounts it receives, so the two are kept apart
+type DeferredBalance struct {
+	Currency    string  `json:"currency"`
+	Side        string  `json:"side"`
+	Amount      float64 `json:"amount"`
+	Settlements int     `json:"settlements"`
+}
+
+// createSettlement saves a validated settlement of a trade on the given side, applying the minimum
+// settlement amount. The client's deferred settlements in the same currency and on the same side
+// are swept into the new settlement once their combined amount reaches the minimum; until then the
+// new settlement is deferred too. Amounts are unsigned, so a buy is never swept into a sell or the
+// other way round
+func (s *Service) createSettlement(settlement *Settlement, side string) error {
+	if s.config.MinSettlementAmount <= 0 {
+		return s.saveSettlement(settlement, side, nil)
+	}
+
+	logger := log.With().
+		Str("settlement_id", settlement.SettlementID).
+		Str("client_id", settlement.ClientID).
+		Str("currency", settlement.Currency).
+		Str("side", side).
+		Str("service", "settlement").
+		Logger()
+
+	deferred, err := s.db.GetDeferredSettlements(settlement.ClientID, settlement.Currency, side)
+	if err != nil {
+		return err
+	}
+
+	currency := settlement.Currency
+	deferredAmount, grossFees, feeRebate := 0.0, 0.0, 0.0
+	deferredIDs := make([]string, 0, len(deferred))
+	for _, d := range deferred {
+		deferredAmount = money.Sum(currency, deferredAmount, d.FinalAmount)
+		grossFees = money.Sum(currency, grossFees, d.GrossFees)
+		feeRebate = money.Sum(currency, feeRebate, d.FeeRebate)
+		deferredIDs = append(deferredIDs, d.SettlementID)
+	}
+
+	total := money.Sum(currency, settlement.FinalAmount, deferredAmount)
+	if total < s.config.MinSettlementAmount {
+		settlement.SettlementStatus = StatusDeferred
+		logger.Info().
+			Float64("amount", settlement.FinalAmount).
+			Float64("deferred_total", total).
+			Float64("min_settlement_amount", s.config.MinSettlementAmount).
+			Msg("settlement below minimum amount, deferring")
+		return s.db.CreateSettlement(settlement)
+	}
+	if len(deferred) == 0 {
+		return s.saveSettlement(settlement, side, nil)
+	}
+
+	// The deferred settlements' amounts and fees are carried into this one
+	settlement.DeferredAmount = deferredAmount
+	settlement.FinalAmount = total
+	settlement.GrossFees = money.Sum(currency, settlement.GrossFees, grossFees)
+	settlement.FeeRebate = money.Sum(currency, settlement.FeeRebate, feeRebate)
+	settlement.SettlementFees = money.Sum(currency, settlement.GrossFees, -settlement.FeeRebate)
+
+	if err := s.saveSettlement(settlement, side, deferredIDs); err != nil {
+		return err
+	}
+
+	logger.Info().
+		Int("swept_settlements", len(deferredIDs)).
+		Float64("deferred_amount", deferredAmount).
+		Float64("final_amount", settlement.FinalAmount).
+		Msg("swept deferred settlements into settlement")
+	return nil
+}
+
+// GetDeferredBalances totals a client's deferred settlements per currency and trade side
+func (s *Service) GetDeferredBalances(clientID string) ([]DeferredBalance, error) {
+	balances, err := s.db.GetDeferredBalances(common.NormalizeID(clientID))
+	if err != nil {
+		return nil, err
+	}
+	for i := range balances {
+		balances[i].Amount = money.Round(balances[i].Amount, balances[i].Currency)
+	}
+	return balances, nil
+}
+
+// GetDeferredBalancesHandler handles GET requests for a client's deferred settlement totals
+// Requires internal authentication
+// URL parameter: client_id
+func (h *GinHandlers) GetDeferredBalancesHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clientID := common.NormalizeID(c.Param("client_id"))
+		if clientID == "" {
+			response.BadRequest(c, "Client ID is required")
+			return
+		}
+
+		balances, err := h.service.GetDeferredBalances(clientID)
+		response.Handle(c, balances, err)
+	}
+}
@@ -0,0 +1,86 @@
+package settlement
+
+import (
+	"testing"
+	"time"
+)
+
+// newDeferringService creates a test service that defers settlements below 10,000
+func newDeferringService(t *testing.T) *Service {
+	t.Helper()
+	config := DefaultConfig()
+	config.MinSettlementAmount = 10000
+	service, _ := newTestServiceWithConfig(t, config)
+	return service
+}
+
+// assertSweptInto checks that the trade's settlement was swept into the given settlement
+func assertSweptInto(t *testing.T, service *Service, tradeID, settlementID string) {
+	t.Helper()
+	stored, err := service.db.GetSettlementByTradeID(tradeID)
+	if err != nil {
+		t.Fatalf("GetSettlementByTradeID: %v", err)
+	}
+	if stored.SettlementStatus != StatusSwept || stored.SweptInto != settlementID {
+		t.Errorf("settlement of %s = %s swept into %q, want SWEPT into %s", tradeID, stored.SettlementStatus, stored.SweptInto, settlementID)
+	}
+}
+
+func TestSubThresholdSettlementsCombineIntoOne(t *testing.T) {
+	service := newDeferringService(t)
+
+	first := seedClearedTrade(t, service, "client-1", "BUY", 50, 150, testNow.Add(-2*time.Minute))
+	if deferred := settleTrade(t, service, first.ExecutionID); deferred.SettlementStatus != StatusDeferred {
+		t.Fatalf("7,500 settlement status = %s, want %s below the minimum", deferred.SettlementStatus, StatusDeferred)
+	}
+
+	second := seedClearedTrade(t, service, "client-1", "BUY", 50, 150, testNow.Add(-time.Minute))
+	combined := settleTrade(t, service, second.ExecutionID)
+	if combined.SettlementStatus != StatusPending || combined.FinalAmount != 15000 || combined.DeferredAmount != 7500 {
+		t.Errorf("combined settlement = %s for %v with %v deferred, want PENDING for 15000 carrying 7500",
+			combined.SettlementStatus, combined.FinalAmount, combined.DeferredAmount)
+	}
+	assertSweptInto(t, service, first.ExecutionID, combined.SettlementID)
+
+	balances, err := service.GetDeferredBalances("client-1")
+	if err != nil {
+		t.Fatalf("GetDeferredBalances: %v", err)
+	}
+	if len(balances) != 0 {
+		t.Errorf("deferred balances = %+v, want none once swept", balances)
+	}
+}
+
+func TestDeferredSettlementsSweptOnlyOnTheSameSide(t *testing.T) {
+	service := newDeferringService(t)
+
+	buy := seedClearedTrade(t, service, "client-1", "BUY", 50, 150, testNow.Add(-3*time.Minute))
+	settleTrade(t, service, buy.ExecutionID)
+
+	// The client receives a sell's amount, so it must not be added to the 7,500 the client owes
+	sell := seedClearedTrade(t, service, "client-1", "SELL", 50, 150, testNow.Add(-2*time.Minute))
+	if deferred := settleTrade(t, service, sell.ExecutionID); deferred.SettlementStatus != StatusDeferred || deferred.FinalAmount != 7500 {
+		t.Fatalf("sell settlement = %s for %v, want DEFERRED for its own 7500", deferred.SettlementStatus, deferred.FinalAmount)
+	}
+
+	balances, err := service.GetDeferredBalances("client-1")
+	if err != nil {
+		t.Fatalf("GetDeferredBalances: %v", err)
+	}
+	if len(balances) != 2 ||
+		balances[0] != (DeferredBalance{Currency: "USD", Side: "BUY", Amount: 7500, Settlements: 1}) ||
+		balances[1] != (DeferredBalance{Currency: "USD", Side: "SELL", Amount: 7500, Settlements: 1}) {
+		t.Errorf("deferred balances = %+v, want 7500 deferred on each side", balances)
+	}
+
+	// A second buy sweeps only the deferred buy
+	next := seedClearedTrade(t, service, "client-1", "BUY", 50, 150, testNow.Add(-time.Minute))
+	combined := settleTrade(t, service, next.ExecutionID)
+	if combined.SettlementStatus != StatusPending || combined.FinalAmount != 15000 {
+		t.Errorf("combined buy settlement = %s for %v, want PENDING for 15000", combined.SettlementStatus, combined.FinalAmount)
+	}
+	assertSweptInto(t, service, buy.ExecutionID, combined.SettlementID)
+	if stored, _ := service.db.GetSettlementByTradeID(sell.ExecutionID); stored.SettlementStatus != StatusDeferred {
+		t.Errorf("sell settlement status = %s, want it still %s", stored.SettlementStatus, StatusDeferred)
+	}
+}
@@ -0,0 +1,101 @@
+package settlement
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/ksred/klear-api/internal/breaks"
+	"github.com/ksred/klear-api/internal/clearing"
+	"github.com/ksred/klear-api/internal/database/dbtest"
+	"github.com/ksred/klear-api/internal/events"
+	"github.com/ksred/klear-api/internal/exchange"
+	"github.com/ksred/klear-api/internal/pricefeed"
+	"github.com/ksred/klear-api/internal/trading"
+	"github.com/ksred/klear-api/internal/types"
+	"github.com/ksred/klear-api/pkg/common"
+)
+
+func TestTradeLifecycleAppendsOrderedEvents(t *testing.T) {
+	db := dbtest.Open(t,
+		&types.Order{},
+		&types.Client{},
+		&types.Execution{},
+		&types.ExchangeFill{},
+		&types.Allocation{},
+		&types.VenueAttempt{},
+		&trading.IdempotencyRecord{},
+		&clearing.Clearing{},
+		&clearing.TradeNetting{},
+		&Settlement{},
+		&SettlementBatch{},
+		&CounterpartyAgreement{},
+		&ClientWebhook{},
+		&WebhookDeadLetter{},
+		&events.DomainEvent{},
+		&breaks.TradeBreak{},
+	)
+	exchange.SetInstantFills(true)
+	t.Cleanup(func() { exchange.SetInstantFills(false) })
+
+	clock := common.NewFakeClock(testNow)
+	tradingService := trading.NewService(db, trading.DefaultConfig())
+	tradingService.SetClock(clock)
+	clearingService := clearing.NewService(db, pricefeed.NewMockFeed(), clearing.DefaultConfig())
+	clearingService.SetClock(clock)
+	settlementService := NewService(db, DefaultConfig())
+	settlementService.SetClock(clock)
+
+	if err := tradingService.RegisterClient(&types.Client{ClientID: "client-1", Name: "client-1", Active: true}); err != nil {
+		t.Fatalf("RegisterClient: %v", err)
+	}
+	// Priced at the mock feed's AAPL quote, so clearing passes its price check
+	order := &types.Order{ClientID: "client-1", Symbol: "AAPL", Side: "BUY", OrderType: "LIMIT", Quantity: 10, Price: 190}
+	if _, err := tradingService.CreateOrder(order, uuid.New().String()); err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+	execution, err := tradingService.ExecuteOrder(order.OrderID, order.ClientID, uuid.New().String())
+	if err != nil {
+		t.Fatalf("ExecuteOrder: %v", err)
+	}
+	cleared, err := clearingService.ClearTrade(execution.ExecutionID)
+	if err != nil {
+		t.Fatalf("ClearTrade: %v", err)
+	}
+	settled, _, err := settlementService.SettleTrade(execution.ExecutionID)
+	if err != nil {
+		t.Fatalf("SettleTrade: %v", err)
+	}
+
+	page, err := events.NewService(db, common.DefaultPageSize()).ListEvents(0, 100)
+	if err != nil {
+		t.Fatalf("ListEvents: %v", err)
+	}
+
+	want := []struct {
+		eventType   string
+		aggregateID string
+	}{
+		{events.EventOrderCreated, order.OrderID},
+		{events.EventOrderExecuted, execution.ExecutionID},
+		{events.EventTradeCleared, cleared.ClearingID},
+		{events.EventSettlementCreated, settled.SettlementID},
+	}
+	// Status changes between the milestones are logged too; the milestones must appear in flow order
+	next := 0
+	var previous uint64
+	for _, event := range page.Events {
+		if event.Sequence <= previous {
+			t.Errorf("event %d follows sequence %d", event.Sequence, previous)
+		}
+		previous = event.Sequence
+		if next < len(want) && event.EventType == want[next].eventType {
+			if event.AggregateID != want[next].aggregateID {
+				t.Errorf("%s aggregate = %s, want %s", event.EventType, event.AggregateID, want[next].aggregateID)
+			}
+			next++
+		}
+	}
+	if next != len(want) {
+		t.Errorf("found %d of %d lifecycle events in order, log was %+v", next, len(want), page.Events)
+	}
+}
@@ -0,0 +1,40 @@
+package settlement
+
+// RebateTier defines the fee rebate applied once a client's month-to-date volume reaches MinVolume
+type RebateTier struct {
+	MinVolume  float64 // Month-to-date traded notional required for the tier
+	RebateRate float64 // Fraction of the gross fee rebated (0.25 = 25%)
+}
+
+// FeeSchedule determines settlement fees and volume-based rebates
+type FeeSchedule struct {
+	BaseRate    float64      // Fee as a fraction of trade notional
+	RebateTiers []RebateTier // Tiers in ascending order of MinVolume
+}
+
+// DefaultFeeSchedule returns the standard settlement fee schedule
+func DefaultFeeSchedule() FeeSchedule {
+	return FeeSchedule{
+		BaseRate: 0.001, // 0.1% of total value
+		RebateTiers: []RebateTier{
+			{MinVolume: 1000000, RebateRate: 0.10},  // $1M MTD: 10% rebate
+			{MinVolume: 10000000, RebateRate: 0.25}, // $10M MTD: 25% rebate
+			{MinVolume: 50000000, RebateRate: 0.40}, // $50M MTD: 40% rebate
+		},
+	}
+}
+
+// Calculate returns the gross fee and rebate for a trade's notional value
+// given the client's month-to-date traded volume
+func (f FeeSchedule) Calculate(notional, monthToDateVolume float64) (grossFee, rebate float64) {
+	grossFee = notional * f.BaseRate
+
+	rebateRate := 0.0
+	for _, tier := range f.RebateTiers {
+		if monthToDateVolume >= tier.MinVolume {
+			rebateRate = tier.RebateRate
+		}
+	}
+
+	return grossFee, grossFee * rebateRate
+}
@@ -0,0 +1,184 @@
+package settlement
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/klear-api/internal/auth"
+	"github.com/ksred/klear-api/pkg/common"
+	"github.com/ksred/klear-api/pkg/money"
+	"github.com/ksred/klear-api/pkg/response"
+)
+
+// statementDateLayout is the format of the fee statement period dates
+const statementDateLayout = "2006-01-02"
+
+// ErrInvalidStatementPeriod is returned when a fee statement period ends before it starts
+var ErrInvalidStatementPeriod = errors.New("fee statement end must not be before its start")
+
+// FeeStatementLine holds a client's fees in one symbol and currency
+type FeeStatementLine struct {
+	Symbol         string  `json:"symbol"`
+	Currency       string  `json:"currency"`
+	ExecutionFees  float64 `json:"execution_fees"`  // Venue and internal cross fees on the client's fills
+	SettlementFees float64 `json:"settlement_fees"` // Settlement fees net of rebates
+	TotalFees      float64 `json:"total_fees"`
+}
+
+// FeeStatementTotal holds a client's fees in one currency across all symbols
+type FeeStatementTotal struct {
+	Currency       string  `json:"currency"`
+	ExecutionFees  float64 `json:"execution_fees"`
+	SettlementFees float64 `json:"settlement_fees"`
+	TotalFees      float64 `json:"total_fees"`
+}
+
+// FeeStatement consolidates a client's execution and settlement fees over a period
+type FeeStatement struct {
+	ClientID string              `json:"client_id"`
+	Start    string              `json:"start"`  // First day of the period, YYYY-MM-DD
+	End      string              `json:"end"`    // Last day of the period, inclusive
+	Lines    []FeeStatementLine  `json:"lines"`  // Ordered by symbol, then currency
+	Totals   []FeeStatementTotal `json:"totals"` // Ordered by currency
+}
+
+// feeAmount is one fee sum from the database, keyed by symbol and currency
+type feeAmount struct {
+	Symbol   string
+	Currency string
+	Units    int64 // Exact integer units of 10^-8
+}
+
+// GetFeeStatement totals a client's execution and settlement fees by symbol and currency
+// Fills count towards the day they were executed and settlements towards the day they were created.
+// Failed and cancelled settlements charge no fees; swept settlements are left out because their fees
+// are carried into the settlement they were swept into
+// Parameters:
+//   - clientID: ID of the client
+//   - start: First day of the period (UTC)
+//   - end: Last day of the period (UTC), inclusive
+func (s *Service) GetFeeStatement(clientID string, start, end time.Time) (*FeeStatement, error) {
+	start = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+	end = time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.UTC)
+	if end.Before(start) {
+		return nil, ErrInvalidStatementPeriod
+	}
+	clientID = common.NormalizeID(clientID)
+	windowEnd := end.AddDate(0, 0, 1)
+
+	executionFees, err := s.db.GetExecutionFees(clientID, start, windowEnd)
+	if err != nil {
+		return nil, err
+	}
+	settlementFees, err := s.db.GetSettlementFees(clientID, start, windowEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := make(map[[2]string]*FeeStatementLine)
+	line := func(fee feeAmount) *FeeStatementLine {
+		key := [2]string{fee.Symbol, fee.Currency}
+		if lines[key] == nil {
+			lines[key] = &FeeStatementLine{Symbol: fee.Symbol, Currency: fee.Currency}
+		}
+		return lines[key]
+	}
+	for _, fee := range executionFees {
+		line(fee).ExecutionFees = money.Round(money.FromUnits(fee.Units), fee.Currency)
+	}
+	for _, fee := range settlementFees {
+		line(fee).SettlementFees = money.Round(money.FromUnits(fee.Units), fee.Currency)
+	}
+
+	statement := &FeeStatement{
+		ClientID: clientID,
+		Start:    start.Format(statementDateLayout),
+		End:      end.Format(statementDateLayout),
+		Lines:    make([]FeeStatementLine, 0, len(lines)),
+		Totals:   []FeeStatementTotal{},
+	}
+	totals := make(map[string]*FeeStatementTotal)
+	for _, l := range lines {
+		l.TotalFees = money.Sum(l.Currency, l.ExecutionFees, l.SettlementFees)
+		statement.Lines = append(statement.Lines, *l)
+
+		total := totals[l.Currency]
+		if total == nil {
+			total = &FeeStatementTotal{Currency: l.Currency}
+			totals[l.Currency] = total
+		}
+		total.ExecutionFees = money.Sum(l.Currency, total.ExecutionFees, l.ExecutionFees)
+		total.SettlementFees = money.Sum(l.Currency, total.SettlementFees, l.SettlementFees)
+		total.TotalFees = money.Sum(l.Currency, total.TotalFees, l.TotalFees)
+	}
+	for _, total := range totals {
+		statement.Totals = append(statement.Totals, *total)
+	}
+
+	sort.Slice(statement.Lines, func(i, j int) bool {
+		if statement.Lines[i].Symbol != statement.Lines[j].Symbol {
+			return statement.Lines[i].Symbol < statement.Lines[j].Symbol
+		}
+		return statement.Lines[i].Currency < statement.Lines[j].Currency
+	})
+	sort.Slice(statement.Totals, func(i, j int) bool {
+		return statement.Totals[i].Currency < statement.Totals[j].Currency
+	})
+	return statement, nil
+}
+
+// GetFeeStatementHandler handles GET requests for a client's fee statement
+// Requires a valid JWT token for the client in the path
+// URL parameter: client_id
+// Query parameters: start and end (YYYY-MM-DD, optional; default to the current UTC month to date)
+func (h *GinHandlers) GetFeeStatementHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, exists := c.Get("claims")
+		if !exists {
+			response.Unauthorized(c, "Missing authentication claims")
+			return
+		}
+
+		tokenClientID := auth.GetClientID(claims)
+		if tokenClientID == "" {
+			response.Unauthorized(c, "Invalid client ID in token")
+			return
+		}
+
+		clientID := common.NormalizeID(c.Param("client_id"))
+		if clientID != common.NormalizeID(tokenClientID) {
+			response.Forbidden(c, "Fee statements are only available for the authenticated client")
+			return
+		}
+
+		now := h.service.clock.Now().UTC()
+		end := now
+		if endParam := c.Query("end"); endParam != "" {
+			parsed, err := time.Parse(statementDateLayout, endParam)
+			if err != nil {
+				response.BadRequest(c, "Invalid end format, expected YYYY-MM-DD")
+				return
+			}
+			end = parsed
+		}
+
+		start := time.Date(end.Year(), end.Month(), 1, 0, 0, 0, 0, time.UTC)
+		if startParam := c.Query("start"); startParam != "" {
+			parsed, err := time.Parse(statementDateLayout, startParam)
+			if err != nil {
+				response.BadRequest(c, "Invalid start format, expected YYYY-MM-DD")
+				return
+			}
+			start = parsed
+		}
+
+		statement, err := h.service.GetFeeStatement(clientID, start, end)
+		if errors.Is(err, ErrInvalidStatementPeriod) {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		response.Handle(c, statement, err)
+	}
+}
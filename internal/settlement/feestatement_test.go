@@ -0,0 +1,78 @@
+package settlement
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestFeeStatementTotalsPeriodBySymbolAndCurrency(t *testing.T) {
+	service, clock := newTestService(t)
+	handlers := NewGinHandlers(service)
+	router := gin.New()
+	router.GET("/clients/:client_id/fee-statement", authenticate("client-1"), handlers.GetFeeStatementHandler())
+
+	// settle seeds a cleared trade and settles it on the same day, returning the settlement fees charged
+	settle := func(clientID, symbol, currency string, quantity, price float64, at time.Time) float64 {
+		clock.Set(at)
+		trade := seedTrade(t, service, clientID, symbol, "BUY", currency, quantity, price, at)
+		return settleTrade(t, service, trade.ExecutionID).SettlementFees
+	}
+	periodStart := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	// In the period: two AAPL trades in USD, one in EUR, and one MSFT trade in USD
+	aapl1 := settle("client-1", "AAPL", "USD", 100, 150, periodStart)
+	aapl2 := settle("client-1", "AAPL", "USD", 50, 160, periodStart.AddDate(0, 0, 9))
+	aaplEUR := settle("client-1", "AAPL", "EUR", 20, 140, periodStart.AddDate(0, 0, 10))
+	msft := settle("client-1", "MSFT", "USD", 10, 300, periodStart.AddDate(0, 0, 13))
+	// Outside the period or another client's: left off the statement
+	settle("client-1", "AAPL", "USD", 1000, 150, periodStart.AddDate(0, 0, -1))
+	settle("client-1", "AAPL", "USD", 1000, 150, periodStart.AddDate(0, 0, 14))
+	settle("client-2", "AAPL", "USD", 1000, 150, periodStart.AddDate(0, 0, 5))
+
+	recorder := performRequest(router, http.MethodGet, "/clients/client-1/fee-statement?start=2026-10-01&end=2026-10-14", nil)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", recorder.Code, recorder.Body.String())
+	}
+	var statement FeeStatement
+	decodeData(t, recorder, &statement)
+
+	if statement.Start != "2026-10-01" || statement.End != "2026-10-14" {
+		t.Errorf("period = %s to %s, want 2026-10-01 to 2026-10-14", statement.Start, statement.End)
+	}
+	// seedTrade charges a 0.1% venue fee on each fill
+	want := []FeeStatementLine{
+		{Symbol: "AAPL", Currency: "EUR", ExecutionFees: 2.8, SettlementFees: aaplEUR},
+		{Symbol: "AAPL", Currency: "USD", ExecutionFees: 15 + 8, SettlementFees: aapl1 + aapl2},
+		{Symbol: "MSFT", Currency: "USD", ExecutionFees: 3, SettlementFees: msft},
+	}
+	if len(statement.Lines) != len(want) {
+		t.Fatalf("lines = %+v, want %d", statement.Lines, len(want))
+	}
+	for i, line := range statement.Lines {
+		if line.Symbol != want[i].Symbol || line.Currency != want[i].Currency {
+			t.Errorf("line %d = %s %s, want %s %s", i, line.Symbol, line.Currency, want[i].Symbol, want[i].Currency)
+			continue
+		}
+		assertAmount(t, line.Symbol+" "+line.Currency+" execution fees", line.ExecutionFees, want[i].ExecutionFees)
+		assertAmount(t, line.Symbol+" "+line.Currency+" settlement fees", line.SettlementFees, want[i].SettlementFees)
+		assertAmount(t, line.Symbol+" "+line.Currency+" total fees", line.TotalFees, want[i].ExecutionFees+want[i].SettlementFees)
+	}
+
+	if len(statement.Totals) != 2 || statement.Totals[0].Currency != "EUR" || statement.Totals[1].Currency != "USD" {
+		t.Fatalf("totals = %+v, want EUR and USD", statement.Totals)
+	}
+	assertAmount(t, "EUR total", statement.Totals[0].TotalFees, 2.8+aaplEUR)
+	assertAmount(t, "USD execution fees", statement.Totals[1].ExecutionFees, 15+8+3)
+	assertAmount(t, "USD settlement fees", statement.Totals[1].SettlementFees, aapl1+aapl2+msft)
+	assertAmount(t, "USD total", statement.Totals[1].TotalFees, 15+8+3+aapl1+aapl2+msft)
+
+	if recorder := performRequest(router, http.MethodGet, "/clients/client-2/fee-statement", nil); recorder.Code != http.StatusForbidden {
+		t.Errorf("another client's statement status = %d, want 403", recorder.Code)
+	}
+	if recorder := performRequest(router, http.MethodGet, "/clients/client-1/fee-statement?start=2026-10-14&end=2026-10-01", nil); recorder.Code != http.StatusBadRequest {
+		t.Errorf("reversed period status = %d, want 400", recorder.Code)
+	}
+}
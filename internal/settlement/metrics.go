@@ -0,0 +1,75 @@
+package settlement
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/klear-api/pkg/response"
+)
+
+// SettlementLatency summarises how long trades that settled on a day took from execution to SETTLED
+type SettlementLatency struct {
+	Date        string  `json:"date"` // YYYY-MM-DD, in UTC
+	Count       int     `json:"count"`
+	MeanSeconds float64 `json:"mean_seconds"`
+	P95Seconds  float64 `json:"p95_seconds"`
+}
+
+// GetSettlementLatency computes the execution-to-settlement latency of the trades that settled on the given day
+// Parameters:
+//   - date: Day the settlements reached SETTLED, in UTC
+func (s *Service) GetSettlementLatency(date time.Time) (*SettlementLatency, error) {
+	date = date.UTC()
+	startOfDay := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+
+	timings, err := s.db.GetSettlementTimings(startOfDay, startOfDay.Add(24*time.Hour))
+	if err != nil {
+		return nil, err
+	}
+
+	latencies := make([]float64, 0, len(timings))
+	for _, timing := range timings {
+		latencies = append(latencies, timing.SettledAt.Sub(timing.ExecutedAt).Seconds())
+	}
+	return newSettlementLatency(startOfDay.Format(valueDateLayout), latencies), nil
+}
+
+// newSettlementLatency summarises latencies in seconds
+// The 95th percentile uses the nearest-rank method, so it is always an observed latency
+func newSettlementLatency(date string, latencies []float64) *SettlementLatency {
+	stats := &SettlementLatency{Date: date, Count: len(latencies)}
+	if len(latencies) == 0 {
+		return stats
+	}
+
+	sort.Float64s(latencies)
+	total := 0.0
+	for _, latency := range latencies {
+		total += latency
+	}
+	stats.MeanSeconds = total / float64(len(latencies))
+	stats.P95Seconds = latencies[int(math.Ceil(0.95*float64(len(latencies))))-1]
+	return stats
+}
+
+// GetSettlementLatencyHandler handles GET requests for execution-to-settlement latency metrics
+// Requires internal authentication
+// Query parameter: date (YYYY-MM-DD, defaults to today)
+func (h *GinHandlers) GetSettlementLatencyHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		date := h.service.clock.Now().UTC()
+		if dateParam := c.Query("date"); dateParam != "" {
+			parsed, err := time.Parse(valueDateLayout, dateParam)
+			if err != nil {
+				response.BadRequest(c, "Invalid date format, expected YYYY-MM-DD")
+				return
+			}
+			date = parsed
+		}
+
+		latency, err := h.service.GetSettlementLatency(date)
+		response.Handle(c, latency, err)
+	}
+}
@@ -0,0 +1,50 @@
+package settlement
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSettlementLatencyHandler(t *testing.T) {
+	service, clock := newTestService(t)
+
+	// Trades executed 1, 2, 3 and 6 hours before they settle at testNow
+	for _, hours := range []int{1, 2, 3, 6} {
+		trade := seedClearedTrade(t, service, "client-1", "BUY", 10, 150, testNow.Add(-time.Duration(hours)*time.Hour))
+		settlement := settleTrade(t, service, trade.ExecutionID)
+		updateStatus(t, service, settlement.SettlementID, StatusSettling, StatusSettled)
+	}
+	// A trade that has not settled yet is left out
+	pending := seedClearedTrade(t, service, "client-1", "BUY", 10, 150, testNow.Add(-12*time.Hour))
+	settleTrade(t, service, pending.ExecutionID)
+
+	router := gin.New()
+	router.GET("/metrics/settlement-latency", NewGinHandlers(service).GetSettlementLatencyHandler())
+
+	recorder := performRequest(router, http.MethodGet, "/metrics/settlement-latency?date="+testNow.UTC().Format("2006-01-02"), nil)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", recorder.Code, recorder.Body.String())
+	}
+	var latency SettlementLatency
+	decodeData(t, recorder, &latency)
+	if latency.Count != 4 {
+		t.Fatalf("count = %d, want the 4 settled trades", latency.Count)
+	}
+	assertAmount(t, "mean seconds", latency.MeanSeconds, 3*3600)
+	assertAmount(t, "p95 seconds", latency.P95Seconds, 6*3600)
+
+	// The day defaults to today, and a day without settlements is empty rather than an error
+	clock.Set(testNow.AddDate(0, 0, 1))
+	recorder = performRequest(router, http.MethodGet, "/metrics/settlement-latency", nil)
+	decodeData(t, recorder, &latency)
+	if latency.Count != 0 || latency.MeanSeconds != 0 {
+		t.Errorf("latency the next day = %+v, want no settlements", latency)
+	}
+
+	if recorder := performRequest(router, http.MethodGet, "/metrics/settlement-latency?date=yesterday", nil); recorder.Code != http.StatusBadRequest {
+		t.Errorf("invalid date status = %d, want 400", recorder.Code)
+	}
+}
@@ -3,50 +3,169 @@ package settlement
 import (
 	"time"
 
+	"github.com/ksred/klear-api/internal/clearing"
+	"github.com/ksred/klear-api/internal/types"
+	"github.com/ksred/klear-api/pkg/money"
 	"gorm.io/gorm"
 )
 
 type Settlement struct {
-	gorm.Model       `json:"-"`
-	SettlementID     string    `gorm:"uniqueIndex" json:"settlement_id"`
-	TradeID          string    `json:"trade_id"`
-	ClientID         string    `json:"client_id"`
-	SettlementStatus string    `json:"settlement_status"` // PENDING, SETTLING, SETTLED, FAILED
-	SettlementDate   time.Time `json:"settlement_date"`
-	FinalAmount      float64   `json:"final_amount"`
-	Currency         string    `json:"currency"`
-	SettlementAccount string   `json:"settlement_account"`
-	ClearingID       string    `json:"clearing_id"`
-	ExecutionID      string    `json:"execution_id"`
-	ExecutedPrice    float64   `json:"executed_price"`
-	ExecutedQuantity int64     `json:"executed_quantity"`
-	SettlementFees   float64   `json:"settlement_fees"`
-	CreatedAt        time.Time `json:"created_at"`
-	UpdatedAt        time.Time `json:"updated_at"`
+	gorm.Model          `json:"-"`
+	SettlementID        string     `gorm:"uniqueIndex" json:"settlement_id"`
+	TradeID             string     `gorm:"uniqueIndex:idx_settlements_active_trade,where:settlement_status <> 'FAILED' AND settlement_status <> 'CANCELLED'" json:"trade_id"` // At most one settlement per trade that has not failed or been cancelled
+	ClientID            string     `json:"client_id"`
+	SettlementStatus    string     `json:"settlement_status"` // PENDING, SETTLING, SETTLED, FAILED, DEFERRED, SWEPT, CANCELLED
+	SettlementDate      time.Time  `json:"settlement_date"`
+	FinalAmount         float64    `json:"final_amount"`
+	FinalAmountUnits    int64      `json:"-"` // Final amount as exact integer units of 10^-8
+	Currency            string     `json:"currency"`
+	SettlementAccount   string     `json:"settlement_account"`
+	ClearingID          string     `json:"clearing_id"`
+	ExecutionID         string     `json:"execution_id"`
+	ExecutedPrice       float64    `json:"executed_price"`
+	ExecutedQuantity    int64      `json:"executed_quantity"`
+	GrossFees           float64    `json:"gross_fees"`
+	FeeRebate           float64    `json:"fee_rebate"`
+	SettlementFees      float64    `json:"settlement_fees"`                   // Net of any rebate
+	SettlementFeesUnits int64      `json:"-"`                                 // Settlement fees as exact integer units of 10^-8
+	AgreementID         string     `json:"agreement_id,omitempty"`            // Counterparty agreement applied, if any
+	DeferredAmount      float64    `json:"deferred_amount,omitempty"`         // Amount of earlier deferred settlements swept into this one, included in FinalAmount
+	SweptInto           string     `gorm:"index" json:"swept_into,omitempty"` // Settlement a deferred settlement was swept into
+	BatchID             string     `gorm:"index" json:"batch_id,omitempty"`   // Settlement batch whose net instruction includes this settlement
+	CancelReason        string     `json:"cancel_reason,omitempty"`           // Why the settlement was cancelled
+	SettledAt           *time.Time `json:"settled_at,omitempty"`              // When the settlement reached SETTLED
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+}
+
+// BeforeSave stores the final amount and fees as exact scaled integers alongside the float columns,
+// so totals summed in the database are exact
+func (s *Settlement) BeforeSave(tx *gorm.DB) error {
+	s.FinalAmountUnits = money.ToUnits(s.FinalAmount)
+	s.SettlementFeesUnits = money.ToUnits(s.SettlementFees)
+	return nil
+}
+
+// AfterFind restores the final amount and fees from their exact scaled integers
+// Rows saved before the integer columns existed keep their float values
+func (s *Settlement) AfterFind(tx *gorm.DB) error {
+	if s.FinalAmountUnits != 0 {
+		s.FinalAmount = money.FromUnits(s.FinalAmountUnits)
+	}
+	if s.SettlementFeesUnits != 0 {
+		s.SettlementFees = money.FromUnits(s.SettlementFeesUnits)
+	}
+	return nil
+}
+
+// SettlementBatch nets a client's settlements in one currency and for one value date into a
+// single payment instruction. The per-trade settlements it covers reference it by BatchID
+type SettlementBatch struct {
+	gorm.Model      `json:"-"`
+	BatchID         string       `gorm:"uniqueIndex" json:"batch_id"`
+	ClientID        string       `gorm:"uniqueIndex:idx_settlement_batch_key" json:"client_id"`
+	Currency        string       `gorm:"uniqueIndex:idx_settlement_batch_key" json:"currency"`
+	ValueDate       string       `gorm:"uniqueIndex:idx_settlement_batch_key" json:"value_date"` // YYYY-MM-DD, in UTC
+	NetAmount       float64      `json:"net_amount"`                                             // Sells received less buys paid; positive when the client receives
+	SettlementFees  float64      `json:"settlement_fees"`
+	SettlementCount int          `json:"settlement_count"`
+	Settlements     []Settlement `gorm:"-" json:"settlements,omitempty"`
+	CreatedAt       time.Time    `json:"created_at"`
+	UpdatedAt       time.Time    `json:"updated_at"`
 }
 
 type SettlementResponse struct {
-	SettlementID     string    `json:"settlement_id"`
-	TradeID          string    `json:"trade_id"`
-	ClientID         string    `json:"client_id"`
-	SettlementStatus string    `json:"settlement_status"`
-	SettlementDate   time.Time `json:"settlement_date"`
-	FinalAmount      float64   `json:"final_amount"`
-	Currency         string    `json:"currency"`
-	SettlementAccount string   `json:"settlement_account"`
-	ExecutedPrice    float64   `json:"executed_price"`
-	ExecutedQuantity int64     `json:"executed_quantity"`
-	SettlementFees   float64   `json:"settlement_fees"`
-	Timestamp        time.Time `json:"timestamp"`
+	SettlementID      string    `json:"settlement_id"`
+	TradeID           string    `json:"trade_id"`
+	ClientID          string    `json:"client_id"`
+	SettlementStatus  string    `json:"settlement_status"`
+	SettlementDate    time.Time `json:"settlement_date"`
+	FinalAmount       float64   `json:"final_amount"`
+	Currency          string    `json:"currency"`
+	SettlementAccount string    `json:"settlement_account"`
+	ExecutedPrice     float64   `json:"executed_price"`
+	ExecutedQuantity  int64     `json:"executed_quantity"`
+	GrossFees         float64   `json:"gross_fees"`
+	FeeRebate         float64   `json:"fee_rebate"`
+	SettlementFees    float64   `json:"settlement_fees"`
+	DeferredAmount    float64   `json:"deferred_amount,omitempty"`
+	AgreementID       string    `json:"agreement_id,omitempty"`
+	BatchID           string    `json:"batch_id,omitempty"`
+	Timestamp         time.Time `json:"timestamp"`
+}
+
+// CounterpartyAgreement holds bespoke settlement terms negotiated with a client
+// Its terms override the instrument defaults when the client's trades are settled
+type CounterpartyAgreement struct {
+	gorm.Model          `json:"-"`
+	AgreementID         string    `gorm:"uniqueIndex" json:"agreement_id"`
+	ClientID            string    `gorm:"uniqueIndex" json:"client_id"`
+	SettlementCycleDays int       `json:"settlement_cycle_days"` // e.g. 1 for T+1
+	Currency            string    `json:"currency"`
+	NettingEligible     bool      `json:"netting_eligible"`
+	CreatedAt           time.Time `json:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at"`
+}
+
+// CounterpartyAgreementRequest sets the settlement terms agreed with a client
+// SettlementCycleDays is required and may be 0 for same-day settlement
+type CounterpartyAgreementRequest struct {
+	SettlementCycleDays *int   `json:"settlement_cycle_days" binding:"required"`
+	Currency            string `json:"currency"` // Empty settles in the order's currency; trades in another currency are rejected
+	NettingEligible     bool   `json:"netting_eligible"`
+}
+
+// ClientWebhook is the endpoint a client registered to receive settlement status changes for its own trades
+type ClientWebhook struct {
+	gorm.Model `json:"-"`
+	ClientID   string    `gorm:"uniqueIndex" json:"client_id"`
+	URL        string    `json:"url"`
+	Secret     string    `json:"-"` // Shared secret used to sign deliveries; never returned
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Dead letter statuses
+const (
+	DeadLetterStatusDead     = "DEAD"     // Undelivered, waiting to be re-driven
+	DeadLetterStatusRedriven = "REDRIVEN" // Delivered by a re-drive
+)
+
+// WebhookDeadLetter is a webhook event that was still undelivered after every retry
+type WebhookDeadLetter struct {
+	gorm.Model   `json:"-"`
+	DeadLetterID string     `gorm:"uniqueIndex" json:"dead_letter_id"`
+	ClientID     string     `gorm:"index" json:"client_id"`
+	SettlementID string     `gorm:"index" json:"settlement_id"`
+	Event        string     `json:"event"`
+	URL          string     `json:"url"`     // Webhook URL of the last failed delivery
+	Payload      string     `json:"payload"` // Event body as delivered
+	Attempts     int        `json:"attempts"`
+	LastError    string     `json:"last_error"`
+	Status       string     `gorm:"index" json:"status"` // DEAD or REDRIVEN
+	RedrivenAt   *time.Time `json:"redriven_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// TradeLifecycle is a composite view of a trade from order through settlement
+// Stages that have not been reached yet are null
+type TradeLifecycle struct {
+	Order      *types.Order       `json:"order"`
+	Execution  *types.Execution   `json:"execution"`
+	Clearing   *clearing.Clearing `json:"clearing"`
+	Settlement *Settlement        `json:"settlement"`
+	// FailedAttempts lists the venue attempts rejected while routing the order, oldest first
+	FailedAttempts []types.VenueAttempt `json:"failed_attempts"`
 }
 
 // Mock request/response structures for integration
 type ClearingDetails struct {
-	ClearingID       string    `json:"clearing_id"`
-	ClearingStatus   string    `json:"clearing_status"`
-	MarginRequired   float64   `json:"margin_required"`
-	NetPositions     float64   `json:"net_positions"`
-	SettlementAmount float64   `json:"settlement_amount"`
+	ClearingID       string  `json:"clearing_id"`
+	ClearingStatus   string  `json:"clearing_status"`
+	MarginRequired   float64 `json:"margin_required"`
+	NetPositions     float64 `json:"net_positions"`
+	SettlementAmount float64 `json:"settlement_amount"`
 }
 
 type ExecutionDetails struct {
@@ -57,3 +176,21 @@ type ExecutionDetails struct {
 	ExchangeID       string    `json:"exchange_id"`
 	ExecutionFees    float64   `json:"execution_fees"`
 }
+
+// BatchStatusRequest lists the trades whose settlement statuses are requested
+type BatchStatusRequest struct {
+	TradeIDs []string `json:"trade_ids" binding:"required"`
+}
+
+// UpdateStatusRequest holds the target status for a settlement status update
+type UpdateStatusRequest struct {
+	Status string `json:"status" binding:"required"`
+}
+
+// SettlementPage is one page of a client's settlement listing
+// NextCursor is set when more settlements follow; pass it back as the cursor to fetch them
+type SettlementPage struct {
+	Settlements []Settlement `json:"settlements"`
+	Limit       int          `json:"limit"` // Page size applied, after defaulting and clamping
+	NextCursor  string       `json:"next_cursor,omitempty"`
+}
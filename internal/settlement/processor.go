@@ -4,21 +4,39 @@ import (
 	"context"
 	"time"
 
+	"github.com/ksred/klear-api/pkg/common"
 	"github.com/rs/zerolog/log"
 )
 
 type Processor struct {
 	db           *Database
+	notifier     *WebhookNotifier
 	processDelay time.Duration // Time between settlement processing attempts
+	clock        common.Clock
 }
 
-func NewProcessor(db *Database) *Processor {
+// NewProcessor creates a settlement processor that processes pending settlements at the given interval
+func NewProcessor(db *Database, interval time.Duration) *Processor {
 	return &Processor{
 		db:           db,
-		processDelay: 5 * time.Minute, // Configurable processing interval
+		notifier:     NewWebhookNotifier(db, DefaultWebhookRetryPolicy()),
+		processDelay: interval,
+		clock:        common.RealClock{},
 	}
 }
 
+// SetClock replaces the clock used to decide whether a settlement date has been reached,
+// to stamp status changes and to time webhook deliveries
+func (p *Processor) SetClock(clock common.Clock) {
+	p.clock = clock
+	p.notifier.clock = clock
+}
+
+// SetWebhookRetry replaces the retry policy applied to webhook deliveries of status changes
+func (p *Processor) SetWebhookRetry(policy WebhookRetryPolicy) {
+	p.notifier.retry = policy
+}
+
 // Start begins the settlement processing loop
 func (p *Processor) Start(ctx context.Context) {
 	logger := log.With().Str("component", "settlement_processor").Logger()
@@ -33,14 +51,16 @@ func (p *Processor) Start(ctx context.Context) {
 			logger.Info().Msg("shutting down settlement processor")
 			return
 		case <-ticker.C:
-			if err := p.processPendingSettlements(); err != nil {
+			if err := p.processPendingSettlements(ctx); err != nil {
 				logger.Error().Err(err).Msg("failed to process pending settlements")
 			}
 		}
 	}
 }
 
-func (p *Processor) processPendingSettlements() error {
+// processPendingSettlements advances each due settlement one step
+// It checks ctx between settlements so a shutdown stops the batch promptly
+func (p *Processor) processPendingSettlements(ctx context.Context) error {
 	logger := log.With().Str("component", "settlement_processor").Logger()
 	
 	// Get all pending settlements
@@ -51,12 +71,24 @@ func (p *Processor) processPendingSettlements() error {
 
 	logger.Info().Int("pending_count", len(settlements)).Msg("processing pending settlements")
 
+	processed := 0
 	for _, settlement := range settlements {
+		if ctx.Err() != nil {
+			logger.Warn().
+				Int("processed_count", processed).
+				Int("remaining_count", len(settlements)-processed).
+				Msg("settlement batch interrupted by shutdown")
+			return nil
+		}
+		processed++
+
 		// Skip if settlement date hasn't been reached
-		if time.Now().Before(settlement.SettlementDate) {
+		if p.clock.Now().Before(settlement.SettlementDate) {
 			continue
 		}
 
+		previousStatus := settlement.SettlementStatus
+
 		// Simulate CSD processing steps
 		switch settlement.SettlementStatus {
 		case "PENDING":
@@ -80,18 +112,35 @@ func (p *Processor) processPendingSettlements() error {
 				Str("settlement_id", settlement.SettlementID).
 				Msg("settlement failed, no further processing")
 			continue
+
+		case StatusCancelled:
+			continue
 		}
 
-		settlement.UpdatedAt = time.Now()
-		if err := p.db.UpdateSettlement(&settlement); err != nil {
+		settlement.UpdatedAt = p.clock.Now()
+		// Only move the settlement on if its status is unchanged since it was read, so a settlement
+		// cancelled in the meantime stays CANCELLED
+		transitioned, err := p.db.TransitionSettlementStatus(settlement.SettlementID, previousStatus, settlement.SettlementStatus, settlement.UpdatedAt)
+		if err != nil {
 			logger.Error().
 				Err(err).
 				Str("settlement_id", settlement.SettlementID).
 				Msg("failed to update settlement status")
 			continue
 		}
+		if !transitioned {
+			logger.Info().
+				Str("settlement_id", settlement.SettlementID).
+				Msg("settlement status changed while processing, skipping")
+			continue
+		}
+
+		if settlement.SettlementStatus != previousStatus {
+			p.notifier.Notify(&settlement, previousStatus)
+		}
 	}
 
+	logger.Info().Int("processed_count", processed).Msg("finished processing pending settlements")
 	return nil
 }
 
@@ -104,5 +153,5 @@ func (p *Processor) verifySettlement(settlement *Settlement) bool {
 	// 4. Validate settlement instructions
 	
 	// For simulation, succeed 95% of the time
-	return time.Now().UnixNano()%100 < 95
+	return p.clock.Now().UnixNano()%100 < 95
 } 
\ No newline at end of file
@@ -0,0 +1,71 @@
+package settlement
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ksred/klear-api/pkg/common"
+	"gorm.io/gorm"
+)
+
+func TestProcessPendingSettlementsStopsWhenCancelled(t *testing.T) {
+	service, _ := newTestService(t)
+	const batch = 5
+	for i := 0; i < batch; i++ {
+		trade := seedClearedTrade(t, service, "client-1", "BUY", 100, 150, testNow.Add(-time.Minute))
+		settleTrade(t, service, trade.ExecutionID)
+	}
+
+	processor := NewProcessor(service.db, time.Hour)
+	processor.SetClock(common.FixedClock{Time: testNow.AddDate(0, 0, 3)})
+
+	// Shutdown arrives while the first settlement of the batch is being moved on
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	err := service.db.db.Callback().Update().After("gorm:update").Register("test:cancel", func(tx *gorm.DB) {
+		if tx.Statement.Table == "settlements" {
+			cancel()
+		}
+	})
+	if err != nil {
+		t.Fatalf("failed to register callback: %v", err)
+	}
+
+	if err := processor.processPendingSettlements(ctx); err != nil {
+		t.Fatalf("processPendingSettlements: %v", err)
+	}
+
+	var settling int64
+	service.db.db.Model(&Settlement{}).Where("settlement_status = ?", StatusSettling).Count(&settling)
+	if settling != 1 {
+		t.Errorf("%d of %d settlements moved on, want the batch to stop after the first", settling, batch)
+	}
+}
+
+func TestProcessPendingSettlementsWaitsForSettlementDate(t *testing.T) {
+	service, _ := newTestService(t)
+	trade := seedClearedTrade(t, service, "client-1", "BUY", 100, 150, testNow.Add(-time.Minute))
+	settlement := settleTrade(t, service, trade.ExecutionID)
+
+	processor := NewProcessor(service.db, time.Hour)
+	processor.SetClock(common.FixedClock{Time: testNow.AddDate(0, 0, 1)})
+	if err := processor.processPendingSettlements(context.Background()); err != nil {
+		t.Fatalf("processPendingSettlements: %v", err)
+	}
+	stored, err := service.db.GetSettlementByTradeID(trade.ExecutionID)
+	if err != nil {
+		t.Fatalf("GetSettlementByTradeID: %v", err)
+	}
+	if stored.SettlementStatus != StatusPending {
+		t.Errorf("settlement %s status before T+2 = %s, want %s", settlement.SettlementID, stored.SettlementStatus, StatusPending)
+	}
+
+	processor.SetClock(common.FixedClock{Time: testNow.AddDate(0, 0, 2)})
+	if err := processor.processPendingSettlements(context.Background()); err != nil {
+		t.Fatalf("processPendingSettlements: %v", err)
+	}
+	if stored, _ = service.db.GetSettlementByTradeID(trade.ExecutionID); stored.SettlementStatus != StatusSettling {
+		t.Errorf("settlement status on T+2 = %s, want %s", stored.SettlementStatus, StatusSettling)
+	}
+}
@@ -0,0 +1,175 @@
+package settlement
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ksred/klear-api/internal/clearing"
+	"github.com/ksred/klear-api/internal/pricefeed"
+	"github.com/ksred/klear-api/pkg/common"
+	"gorm.io/gorm"
+)
+
+// countSettlements returns how many settlements the trade has in any status
+func countSettlements(t *testing.T, service *Service, tradeID string) int64 {
+	t.Helper()
+	var count int64
+	if err := service.db.db.Model(&Settlement{}).Where("trade_id = ?", tradeID).Count(&count).Error; err != nil {
+		t.Fatalf("failed to count settlements: %v", err)
+	}
+	return count
+}
+
+func TestSettleTradeRetryReturnsExistingSettlement(t *testing.T) {
+	service, _ := newTestService(t)
+	trade := seedClearedTrade(t, service, "client-1", "BUY", 100, 150, testNow.Add(-time.Minute))
+	router := gin.New()
+	router.POST("/settlement/:trade_id", NewGinHandlers(service).SettleTradeHandler())
+
+	first := performRequest(router, http.MethodPost, "/settlement/"+trade.ExecutionID, nil)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("first settle status = %d, want 201: %s", first.Code, first.Body.String())
+	}
+	var created SettlementResponse
+	decodeData(t, first, &created)
+
+	retry := performRequest(router, http.MethodPost, "/settlement/"+trade.ExecutionID, nil)
+	if retry.Code != http.StatusOK {
+		t.Fatalf("retried settle status = %d, want 200: %s", retry.Code, retry.Body.String())
+	}
+	var existing SettlementResponse
+	decodeData(t, retry, &existing)
+	if existing.SettlementID != created.SettlementID {
+		t.Errorf("retry returned settlement %s, want the existing %s", existing.SettlementID, created.SettlementID)
+	}
+	if count := countSettlements(t, service, trade.ExecutionID); count != 1 {
+		t.Errorf("trade has %d settlements after a retry, want 1", count)
+	}
+}
+
+func TestSettleTradeAfterCancellationSettlesAgain(t *testing.T) {
+	service, _ := newTestService(t)
+	trade := seedClearedTrade(t, service, "client-1", "BUY", 100, 150, testNow.Add(-time.Minute))
+	cancelled := settleTrade(t, service, trade.ExecutionID)
+	if _, err := service.CancelSettlement(cancelled.SettlementID, "booked in error"); err != nil {
+		t.Fatalf("CancelSettlement: %v", err)
+	}
+
+	resettled, created, err := service.SettleTrade(trade.ExecutionID)
+	if err != nil {
+		t.Fatalf("SettleTrade: %v", err)
+	}
+	if !created || resettled.SettlementID == cancelled.SettlementID {
+		t.Errorf("settling a trade whose settlement was cancelled returned %s (created %v), want a new settlement",
+			resettled.SettlementID, created)
+	}
+}
+
+func TestSettleTradeUsesRetriedClearing(t *testing.T) {
+	service, _ := newTestService(t)
+	beforeOpen := time.Date(testNow.Year(), testNow.Month(), testNow.Day(), 0, 30, 0, 0, time.Local)
+	clearingClock := common.NewFakeClock(beforeOpen)
+	clearingService := clearing.NewService(service.db.db, pricefeed.NewMockFeed(), clearing.DefaultConfig())
+	clearingService.SetClock(clearingClock)
+	// Priced at the mock feed's AAPL quote, so clearing passes its price check
+	trade := seedExecutedTrade(t, service, "client-1", "AAPL", "BUY", "USD", 10, 190, beforeOpen.Add(-10*time.Minute))
+	router := gin.New()
+	router.POST("/settlement/:trade_id", NewGinHandlers(service).SettleTradeHandler())
+
+	// Clearing outside market hours fails, and a trade with only a failed clearing cannot settle
+	if _, err := clearingService.ClearTrade(trade.ExecutionID); err == nil {
+		t.Fatal("ClearTrade succeeded outside market hours")
+	}
+	if recorder := performRequest(router, http.MethodPost, "/settlement/"+trade.ExecutionID, nil); recorder.Code != http.StatusConflict {
+		t.Fatalf("settle after failed clearing status = %d, want 409: %s", recorder.Code, recorder.Body.String())
+	}
+	if count := countSettlements(t, service, trade.ExecutionID); count != 0 {
+		t.Errorf("trade has %d settlements after being rejected, want none", count)
+	}
+
+	clearingClock.Set(testNow)
+	retried, err := clearingService.RetryClearing(trade.ExecutionID)
+	if err != nil {
+		t.Fatalf("RetryClearing: %v", err)
+	}
+
+	recorder := performRequest(router, http.MethodPost, "/settlement/"+trade.ExecutionID, nil)
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("settle after retry status = %d, want 201: %s", recorder.Code, recorder.Body.String())
+	}
+	var settled SettlementResponse
+	decodeData(t, recorder, &settled)
+	stored, err := service.db.GetSettlement(settled.SettlementID)
+	if err != nil {
+		t.Fatalf("GetSettlement: %v", err)
+	}
+	if stored.ClearingID != retried.ClearingID || stored.SettlementStatus != StatusPending {
+		t.Errorf("settlement = %s against clearing %s, want PENDING against the retried clearing %s",
+			stored.SettlementStatus, stored.ClearingID, retried.ClearingID)
+	}
+}
+
+func TestActiveSettlementUniquePerTrade(t *testing.T) {
+	service, _ := newTestService(t)
+	trade := seedClearedTrade(t, service, "client-1", "BUY", 100, 150, testNow.Add(-time.Minute))
+	settleTrade(t, service, trade.ExecutionID)
+
+	newSettlement := func(status string) *Settlement {
+		return &Settlement{
+			SettlementID:     "STL_" + uuid.New().String(),
+			TradeID:          trade.ExecutionID,
+			ClientID:         "client-1",
+			SettlementStatus: status,
+			Currency:         "USD",
+			CreatedAt:        testNow,
+			UpdatedAt:        testNow,
+		}
+	}
+
+	if err := service.db.CreateSettlement(newSettlement(StatusPending)); !errors.Is(err, gorm.ErrDuplicatedKey) {
+		t.Errorf("second active settlement: err = %v, want %v", err, gorm.ErrDuplicatedKey)
+	}
+	// Failed and cancelled settlements do not count against the trade
+	for _, status := range []string{StatusFailed, StatusCancelled} {
+		if err := service.db.CreateSettlement(newSettlement(status)); err != nil {
+			t.Errorf("%s settlement alongside an active one: %v", status, err)
+		}
+	}
+}
+
+func TestConcurrentSettleTradeRetriesCreateOneSettlement(t *testing.T) {
+	service, _ := newTestService(t)
+	trade := seedClearedTrade(t, service, "client-1", "BUY", 100, 150, testNow.Add(-time.Minute))
+
+	const retries = 8
+	var wg sync.WaitGroup
+	settlementIDs := make([]string, retries)
+	for i := 0; i < retries; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			resp, _, err := service.SettleTrade(trade.ExecutionID)
+			if err != nil {
+				t.Errorf("SettleTrade: %v", err)
+				return
+			}
+			settlementIDs[i] = resp.SettlementID
+		}(i)
+	}
+	wg.Wait()
+
+	for _, id := range settlementIDs {
+		if id != settlementIDs[0] {
+			t.Errorf("concurrent retries returned settlements %v, want one settlement", settlementIDs)
+			break
+		}
+	}
+	if count := countSettlements(t, service, trade.ExecutionID); count != 1 {
+		t.Errorf("trade has %d settlements after concurrent retries, want 1", count)
+	}
+}
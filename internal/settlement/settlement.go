@@ -3,34 +3,104 @@ package settlement
 import (
 	"errors"
 	"fmt"
+	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/ksred/klear-api/internal/auth"
+	"github.com/ksred/klear-api/internal/breaks"
 	"github.com/ksred/klear-api/internal/types"
+	"github.com/ksred/klear-api/pkg/common"
+	"github.com/ksred/klear-api/pkg/money"
 	"github.com/ksred/klear-api/pkg/response"
 	"github.com/rs/zerolog/log"
 	"gorm.io/gorm"
 )
 
+// Settlement statuses
+const (
+	StatusPending   = "PENDING"
+	StatusSettling  = "SETTLING"
+	StatusSettled   = "SETTLED"
+	StatusFailed    = "FAILED"
+	StatusDeferred  = "DEFERRED"  // Below the minimum settlement amount, waiting to be swept into a later settlement
+	StatusSwept     = "SWEPT"     // Deferred settlement whose amount was carried into a later settlement
+	StatusCancelled = "CANCELLED" // Cancelled before its value date, e.g. because the trade was busted
+)
+
+// StatusNotFound is reported for trades with no settlement in batch status queries
+const StatusNotFound = "NOT_FOUND"
+
+// allowedTransitions lists the statuses each settlement status may move to
+// SETTLED, FAILED, SWEPT and CANCELLED are terminal. Deferred settlements are only swept by the
+// settlement service itself, but may be failed manually
+var allowedTransitions = map[string][]string{
+	StatusPending:   {StatusSettling, StatusFailed, StatusCancelled},
+	StatusSettling:  {StatusSettled, StatusFailed},
+	StatusSettled:   {},
+	StatusFailed:    {},
+	StatusDeferred:  {StatusFailed},
+	StatusSwept:     {},
+	StatusCancelled: {},
+}
+
+// MaxBatchStatusTradeIDs caps the number of trades accepted in a single batch status query
+const MaxBatchStatusTradeIDs = 100
+
+var (
+	ErrNoTradeIDs             = errors.New("at least one trade ID is required")
+	ErrTooManyTradeIDs        = fmt.Errorf("at most %d trade IDs may be queried at once", MaxBatchStatusTradeIDs)
+	ErrInvalidSettlementCycle = errors.New("settlement cycle must not be negative")
+	ErrInvalidAgreementCcy    = errors.New("agreement currency must be an ISO 4217 code")
+	ErrSettlementNotFound     = errors.New("settlement not found")
+	ErrInvalidStatus          = errors.New("unknown settlement status")
+	ErrInvalidTransition      = errors.New("settlement status transition not allowed")
+	ErrDeferredSweepConflict  = errors.New("deferred settlements were swept concurrently")
+	ErrBatchNotFound          = errors.New("settlement batch not found")
+	ErrCancelReasonRequired   = errors.New("a reason is required to cancel a settlement")
+	ErrValueDateReached       = errors.New("settlement has reached its value date and can no longer be cancelled")
+	ErrTradeNotCleared        = errors.New("trade has not been cleared")
+	ErrAgreementCcyMismatch   = errors.New("trade currency differs from the agreed settlement currency and cannot be converted")
+)
+
 // Service handles trade settlement operations
 type Service struct {
-	db *Database
+	db       *Database
+	breaks   *breaks.Service
+	notifier *WebhookNotifier
+	config   Config
+	clock    common.Clock
 }
 
-// NewService creates a new settlement service with the given database connection
-func NewService(gormDB *gorm.DB) *Service {
+// NewService creates a new settlement service with the given database connection and configuration
+func NewService(gormDB *gorm.DB, config Config) *Service {
+	db := NewDatabase(gormDB)
 	return &Service{
-		db: NewDatabase(gormDB),
+		db:       db,
+		breaks:   breaks.NewService(gormDB),
+		notifier: NewWebhookNotifier(db, config.WebhookRetry),
+		config:   config,
+		clock:    common.RealClock{},
 	}
 }
 
+// SetClock replaces the clock used for market-hours checks, settlement dates and record timestamps,
+// including the timestamps on webhook deliveries
+func (s *Service) SetClock(clock common.Clock) {
+	s.clock = clock
+	s.notifier.clock = clock
+	s.breaks.SetClock(clock)
+}
+
 // SettleTrade handles the settlement process for a trade
 // It validates the trade, calculates settlement amounts and fees,
 // and creates settlement records with T+2 settlement dates
+// A trade that already has a settlement returns it rather than settling twice; created reports
+// whether a new settlement was made
 // Parameters:
 //   - tradeID: ID of the trade to settle
-func (s *Service) SettleTrade(tradeID string) (*SettlementResponse, error) {
+func (s *Service) SettleTrade(tradeID string) (resp *SettlementResponse, created bool, err error) {
 	logger := log.With().
 		Str("trade_id", tradeID).
 		Str("service", "settlement").
@@ -38,46 +108,126 @@ func (s *Service) SettleTrade(tradeID string) (*SettlementResponse, error) {
 
 	logger.Info().Msg("starting settlement process for trade")
 
+	// A retried clear→settle chain returns the existing settlement rather than settling the trade twice
+	existing, err := s.db.GetActiveSettlementByTradeID(tradeID)
+	if err == nil {
+		logger.Info().
+			Str("settlement_id", existing.SettlementID).
+			Str("status", existing.SettlementStatus).
+			Msg("trade already settled, returning existing settlement")
+		return newSettlementResponse(existing, existing.UpdatedAt), false, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		logger.Error().Err(err).Msg("failed to check for an existing settlement")
+		return nil, false, fmt.Errorf("failed to check for an existing settlement: %w", err)
+	}
+
 	// Get execution details
 	execution, err := s.db.GetExecutionByID(tradeID)
 	if err != nil {
 		logger.Error().Err(err).Msg("failed to fetch execution details")
-		return nil, fmt.Errorf("failed to fetch execution details: %w", err)
+		return nil, false, fmt.Errorf("failed to fetch execution details: %w", err)
+	}
+	if execution.Status == TradeStatusBusted {
+		return nil, false, fmt.Errorf("%w: %s", ErrTradeAlreadyBusted, tradeID)
 	}
 
 	// Get order details
 	order, err := s.db.GetOrderByID(execution.OrderID)
 	if err != nil {
 		logger.Error().Err(err).Msg("failed to fetch order details")
-		return nil, fmt.Errorf("failed to fetch order details: %w", err)
+		return nil, false, fmt.Errorf("failed to fetch order details: %w", err)
 	}
 
-	// Get clearing details
-	clearingDetails, err := s.db.GetClearingByTradeID(tradeID)
+	// Get clearing details; only a successful clearing can be settled
+	clearingDetails, err := s.db.GetClearedClearingByTradeID(tradeID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		logger.Warn().Msg("trade has no successful clearing")
+		return nil, false, fmt.Errorf("%w: %s", ErrTradeNotCleared, tradeID)
+	}
 	if err != nil {
 		logger.Error().Err(err).Msg("failed to fetch clearing details")
-		return nil, fmt.Errorf("failed to fetch clearing details: %w", err)
+		return nil, false, fmt.Errorf("failed to fetch clearing details: %w", err)
 	}
 
-	// Calculate settlement fees (0.1% of total value)
-	settlementFees := execution.AveragePrice * execution.TotalQuantity * 0.001
+	// Calculate settlement fees with any volume-based rebate
+	monthToDateVolume, err := s.db.GetMonthToDateVolume(order.ClientID, s.clock.Now())
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch month-to-date volume")
+		return nil, false, fmt.Errorf("failed to fetch month-to-date volume: %w", err)
+	}
+	grossFees, feeRebate := s.config.FeeSchedule.Calculate(
+		money.Notional(execution.AveragePrice, execution.TotalQuantity, order.Currency), monthToDateVolume)
+
+	// Settle in the order's currency, with the client's negotiated terms taking precedence
+	cycleDays := s.config.DefaultSettlementCycleDays
+	currency := s.config.DefaultCurrency
+	if order.Currency != "" {
+		currency = order.Currency
+	}
+	agreementID := ""
+	agreement, err := s.db.GetCounterpartyAgreement(order.ClientID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to fetch counterparty agreement")
+		return nil, false, err
+	}
+	if agreement != nil {
+		cycleDays = agreement.SettlementCycleDays
+		// Amounts are not converted between currencies, so a trade in another currency cannot settle
+		// on the agreement rather than being relabelled into it
+		if agreement.Currency != "" && agreement.Currency != currency {
+			logger.Warn().
+				Str("agreement_currency", agreement.Currency).
+				Str("currency", currency).
+				Msg("trade currency differs from agreed settlement currency")
+			return nil, false, fmt.Errorf("%w: trade in %s, agreement in %s", ErrAgreementCcyMismatch, currency, agreement.Currency)
+		}
+		agreementID = agreement.AgreementID
+		logger.Debug().
+			Str("agreement_id", agreementID).
+			Int("settlement_cycle_days", cycleDays).
+			Str("currency", currency).
+			Msg("applying counterparty agreement")
+	}
+
+	logger.Debug().
+		Float64("month_to_date_volume", monthToDateVolume).
+		Float64("gross_fees", grossFees).
+		Float64("fee_rebate", feeRebate).
+		Msg("calculated settlement fees")
+
+	// Rounding keeps NaN as NaN, so reject non-finite inputs before anything is persisted
+	if err := errors.Join(
+		money.CheckFinite("settlement amount", clearingDetails.SettlementAmount),
+		money.CheckFinite("gross fees", grossFees),
+		money.CheckFinite("fee rebate", feeRebate),
+	); err != nil {
+		logger.Error().Err(err).Msg("settlement inputs are not finite")
+		return nil, false, err
+	}
+
+	grossFees = money.Round(grossFees, currency)
+	feeRebate = money.Round(feeRebate, currency)
 
 	settlement := &Settlement{
 		SettlementID:      "STL_" + uuid.New().String(),
 		TradeID:           tradeID,
 		ClientID:          order.ClientID,
 		SettlementStatus:  "PENDING",
-		SettlementDate:    time.Now().Add(2 * 24 * time.Hour), // T+2 settlement
-		FinalAmount:       clearingDetails.SettlementAmount,
-		Currency:          "USD", // Default currency
+		SettlementDate:    s.clock.Now().Add(time.Duration(cycleDays) * 24 * time.Hour), // T+N settlement
+		FinalAmount:       money.Round(clearingDetails.SettlementAmount, currency),
+		Currency:          currency,
 		SettlementAccount: fmt.Sprintf("ACC_%s", order.ClientID),
 		ClearingID:        clearingDetails.ClearingID,
 		ExecutionID:       execution.ExecutionID,
 		ExecutedPrice:     execution.AveragePrice,
 		ExecutedQuantity:  int64(execution.TotalQuantity),
-		SettlementFees:    settlementFees,
-		CreatedAt:         time.Now(),
-		UpdatedAt:         time.Now(),
+		GrossFees:         grossFees,
+		FeeRebate:         feeRebate,
+		SettlementFees:    money.Sum(currency, grossFees, -feeRebate),
+		AgreementID:       agreementID,
+		CreatedAt:         s.clock.Now(),
+		UpdatedAt:         s.clock.Now(),
 	}
 
 	if err := s.validateSettlement(settlement, order); err != nil {
@@ -85,14 +235,26 @@ func (s *Service) SettleTrade(tradeID string) (*SettlementResponse, error) {
 		settlement.SettlementStatus = "FAILED"
 		if err := s.db.CreateSettlement(settlement); err != nil {
 			logger.Error().Err(err).Msg("failed to save failed settlement record")
-			return nil, err
+			return nil, false, err
 		}
-		return nil, fmt.Errorf("settlement validation failed: %w", err)
+		err = fmt.Errorf("settlement validation failed: %w", err)
+		s.breaks.Record(tradeID, breaks.StageSettlement, err.Error())
+		return nil, false, err
 	}
 
-	if err := s.db.CreateSettlement(settlement); err != nil {
+	if err := s.createSettlement(settlement, order.Side); err != nil {
+		// A concurrent retry settled the trade first; the unique index on active settlements kept
+		// this one out, so return the winner's settlement
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			if existing, findErr := s.db.GetActiveSettlementByTradeID(tradeID); findErr == nil {
+				logger.Info().
+					Str("settlement_id", existing.SettlementID).
+					Msg("trade settled concurrently, returning existing settlement")
+				return newSettlementResponse(existing, existing.UpdatedAt), false, nil
+			}
+		}
 		logger.Error().Err(err).Msg("failed to create settlement record")
-		return nil, fmt.Errorf("failed to create settlement record: %w", err)
+		return nil, false, fmt.Errorf("failed to create settlement record: %w", err)
 	}
 
 	logger.Info().
@@ -102,6 +264,11 @@ func (s *Service) SettleTrade(tradeID string) (*SettlementResponse, error) {
 		Float64("final_amount", settlement.FinalAmount).
 		Msg("settlement process completed successfully")
 
+	return newSettlementResponse(settlement, s.clock.Now()), true, nil
+}
+
+// newSettlementResponse builds the response for a settlement, stamped with the given time
+func newSettlementResponse(settlement *Settlement, timestamp time.Time) *SettlementResponse {
 	return &SettlementResponse{
 		SettlementID:      settlement.SettlementID,
 		TradeID:           settlement.TradeID,
@@ -113,9 +280,14 @@ func (s *Service) SettleTrade(tradeID string) (*SettlementResponse, error) {
 		SettlementAccount: settlement.SettlementAccount,
 		ExecutedPrice:     settlement.ExecutedPrice,
 		ExecutedQuantity:  settlement.ExecutedQuantity,
+		GrossFees:         settlement.GrossFees,
+		FeeRebate:         settlement.FeeRebate,
 		SettlementFees:    settlement.SettlementFees,
-		Timestamp:         time.Now(),
-	}, nil
+		DeferredAmount:    settlement.DeferredAmount,
+		AgreementID:       settlement.AgreementID,
+		BatchID:           settlement.BatchID,
+		Timestamp:         timestamp,
+	}
 }
 
 // validateSettlement performs validation checks on the settlement
@@ -143,7 +315,7 @@ func (s *Service) validateSettlement(settlement *Settlement, order *types.Order)
 	// }
 
 	// Validate market hours. For testing we use a broad window
-	now := time.Now()
+	now := s.clock.Now()
 	marketOpen := time.Date(now.Year(), now.Month(), now.Day(), 1, 30, 0, 0, time.Local)  // 1:30 AM
 	marketClose := time.Date(now.Year(), now.Month(), now.Day(), 23, 0, 0, 0, time.Local) // 11:00 PM
 
@@ -155,9 +327,95 @@ func (s *Service) validateSettlement(settlement *Settlement, order *types.Order)
 	return nil
 }
 
-// UpdateSettlementStatus updates the status of a settlement
-func (s *Service) UpdateSettlementStatus(settlementID string, status string) error {
-	return s.db.UpdateSettlementStatus(settlementID, status)
+// SetCounterpartyAgreement creates or updates the settlement terms agreed with a client
+func (s *Service) SetCounterpartyAgreement(agreement *CounterpartyAgreement) error {
+	agreement.ClientID = common.NormalizeID(agreement.ClientID)
+	agreement.Currency = money.NormalizeCurrency(agreement.Currency)
+	if agreement.SettlementCycleDays < 0 {
+		return ErrInvalidSettlementCycle
+	}
+	if agreement.Currency != "" && !money.IsValidCurrency(agreement.Currency) {
+		return ErrInvalidAgreementCcy
+	}
+	if agreement.AgreementID == "" {
+		agreement.AgreementID = "AGR_" + uuid.New().String()
+	}
+	return s.db.SaveCounterpartyAgreement(agreement)
+}
+
+// UpdateSettlementStatus moves a settlement to a new status, enforcing the settlement state machine
+// Setting the status a settlement already has is a no-op, so retried requests succeed
+// Parameters:
+//   - settlementID: ID of the settlement to update
+//   - status: Target status (PENDING, SETTLING, SETTLED or FAILED)
+func (s *Service) UpdateSettlementStatus(settlementID string, status string) (*Settlement, error) {
+	logger := log.With().
+		Str("settlement_id", settlementID).
+		Str("target_status", status).
+		Str("service", "settlement").
+		Logger()
+
+	if _, known := allowedTransitions[status]; !known {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidStatus, status)
+	}
+	if status == StatusCancelled {
+		// Cancellation carries a reason and unwinds sweeps and batches, so it has its own endpoint
+		return nil, fmt.Errorf("%w: use the cancel endpoint to cancel a settlement", ErrInvalidStatus)
+	}
+
+	settlement, err := s.db.GetSettlement(settlementID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSettlementNotFound
+		}
+		return nil, err
+	}
+
+	if settlement.SettlementStatus == status {
+		return settlement, nil
+	}
+
+	if !isAllowedTransition(settlement.SettlementStatus, status) {
+		logger.Warn().
+			Str("current_status", settlement.SettlementStatus).
+			Msg("rejected settlement status transition")
+		return nil, fmt.Errorf("%w: %s to %s", ErrInvalidTransition, settlement.SettlementStatus, status)
+	}
+
+	transitioned, err := s.db.TransitionSettlementStatus(settlementID, settlement.SettlementStatus, status, s.clock.Now())
+	if err != nil {
+		return nil, err
+	}
+	if !transitioned {
+		// The status changed after it was read
+		return nil, fmt.Errorf("%w: settlement status changed concurrently", ErrInvalidTransition)
+	}
+
+	logger.Info().
+		Str("previous_status", settlement.SettlementStatus).
+		Msg("settlement status updated")
+
+	if status == StatusFailed {
+		s.breaks.Record(settlement.TradeID, breaks.StageSettlement,
+			fmt.Sprintf("settlement %s marked %s from %s", settlementID, StatusFailed, settlement.SettlementStatus))
+	}
+
+	updated, err := s.db.GetSettlement(settlementID)
+	if err != nil {
+		return nil, err
+	}
+	s.notifier.Notify(updated, settlement.SettlementStatus)
+	return updated, nil
+}
+
+// isAllowedTransition reports whether a settlement may move from one status to another
+func isAllowedTransition(from, to string) bool {
+	for _, allowed := range allowedTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
 }
 
 // GetSettlement retrieves a settlement by ID
@@ -170,6 +428,56 @@ func (s *Service) GetClientSettlements(clientID string) ([]Settlement, error) {
 	return s.db.GetClientSettlements(clientID)
 }
 
+// ListSettlements retrieves a page of a client's settlements, newest first
+// Parameters:
+//   - clientID: ID of the client whose settlements are listed
+//   - page: Page to return, by cursor or offset
+func (s *Service) ListSettlements(clientID string, page common.Page) (*SettlementPage, error) {
+	settlements, err := s.db.ListClientSettlements(common.NormalizeID(clientID), page)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SettlementPage{Settlements: settlements, Limit: page.Limit}
+	if page.HasMore(len(settlements)) {
+		result.Settlements = settlements[:page.Limit]
+		last := result.Settlements[page.Limit-1]
+		result.NextCursor = common.EncodeCursor(last.CreatedAt, last.ID)
+	}
+	return result, nil
+}
+
+// GetTradeLifecycle retrieves the order, execution, clearing and settlement for a trade
+func (s *Service) GetTradeLifecycle(executionID string) (*TradeLifecycle, error) {
+	return s.db.GetTradeLifecycle(executionID)
+}
+
+// GetSettlementStatuses returns the settlement status of each requested trade owned by the client
+// Trades without a settlement are reported as NOT_FOUND
+func (s *Service) GetSettlementStatuses(clientID string, tradeIDs []string) (map[string]string, error) {
+	if len(tradeIDs) == 0 {
+		return nil, ErrNoTradeIDs
+	}
+	if len(tradeIDs) > MaxBatchStatusTradeIDs {
+		return nil, ErrTooManyTradeIDs
+	}
+
+	found, err := s.db.GetSettlementStatuses(clientID, tradeIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make(map[string]string, len(tradeIDs))
+	for _, tradeID := range tradeIDs {
+		status, ok := found[tradeID]
+		if !ok {
+			status = StatusNotFound
+		}
+		statuses[tradeID] = status
+	}
+	return statuses, nil
+}
+
 // GinHandlers contains HTTP handlers for settlement endpoints
 type GinHandlers struct {
 	service *Service
@@ -187,10 +495,166 @@ func NewGinHandlers(service *Service) *GinHandlers {
 // URL parameter: trade_id
 func (h *GinHandlers) SettleTradeHandler() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		tradeID := c.Param("trade_id")
+		tradeID := common.NormalizeID(c.Param("trade_id"))
+		if tradeID == "" {
+			response.BadRequest(c, "Trade ID is required")
+			return
+		}
+		if !common.IsValidTradeID(tradeID) {
+			response.BadRequest(c, "Trade ID must be a UUID or an EXEC- execution ID")
+			return
+		}
+
+		settlementResponse, created, err := h.service.SettleTrade(tradeID)
+		if errors.Is(err, types.ErrOrderDeleted) || errors.Is(err, types.ErrExecutionDeleted) ||
+			errors.Is(err, ErrDeferredSweepConflict) || errors.Is(err, ErrTradeAlreadyBusted) ||
+			errors.Is(err, ErrTradeNotCleared) || errors.Is(err, ErrAgreementCcyMismatch) {
+			response.Conflict(c, err.Error())
+			return
+		}
+		// An existing settlement returned for a retried request was not created by it
+		status := http.StatusCreated
+		if !created {
+			status = http.StatusOK
+		}
+		response.HandleWithStatus(c, settlementResponse, err, status)
+	}
+}
+
+// GetTradeLifecycleHandler handles GET requests for a trade's full lifecycle
+// Requires internal authentication
+// URL parameter: execution_id
+func (h *GinHandlers) GetTradeLifecycleHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		executionID := c.Param("execution_id")
+
+		lifecycle, err := h.service.GetTradeLifecycle(executionID)
+		response.Handle(c, lifecycle, err)
+	}
+}
+
+// GetSettlementStatusesHandler handles POST requests for the settlement statuses of several trades
+// Requires a valid JWT token
+// Request body should contain the trade IDs to look up
+func (h *GinHandlers) GetSettlementStatusesHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, exists := c.Get("claims")
+		if !exists {
+			response.Unauthorized(c, "Missing authentication claims")
+			return
+		}
+
+		clientID := auth.GetClientID(claims)
+		if clientID == "" {
+			response.Unauthorized(c, "Invalid client ID in token")
+			return
+		}
+
+		var req BatchStatusRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BindError(c, err)
+			return
+		}
+
+		statuses, err := h.service.GetSettlementStatuses(clientID, req.TradeIDs)
+		if errors.Is(err, ErrNoTradeIDs) || errors.Is(err, ErrTooManyTradeIDs) {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		if err != nil {
+			response.InternalError(c, err.Error())
+			return
+		}
+
+		response.SuccessWithStatus(c, statuses, http.StatusOK)
+	}
+}
+
+// ListSettlementsHandler handles GET requests to list the client's settlements
+// Requires a valid JWT token
+// Query parameters: limit, cursor, offset (all optional)
+func (h *GinHandlers) ListSettlementsHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, exists := c.Get("claims")
+		if !exists {
+			response.Unauthorized(c, "Missing authentication claims")
+			return
+		}
+
+		clientID := auth.GetClientID(claims)
+		if clientID == "" {
+			response.Unauthorized(c, "Invalid client ID in token")
+			return
+		}
+
+		page, err := common.ParsePage(c.Query("limit"), c.Query("offset"), c.Query("cursor"), h.service.config.PageSize)
+		if err != nil {
+			response.BadRequest(c, err.Error())
+			return
+		}
+
+		settlements, err := h.service.ListSettlements(clientID, page)
+		if err != nil {
+			response.InternalError(c, err.Error())
+			return
+		}
+
+		response.Success(c, settlements)
+	}
+}
+
+// UpdateSettlementStatusHandler handles PUT requests to change a settlement's status
+// Requires internal authentication
+// URL parameter: settlement_id
+// Request body should contain the target status
+func (h *GinHandlers) UpdateSettlementStatusHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req UpdateStatusRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BindError(c, err)
+			return
+		}
 
-		settlementResponse, err := h.service.SettleTrade(tradeID)
-		response.Handle(c, settlementResponse, err)
+		settlement, err := h.service.UpdateSettlementStatus(c.Param("settlement_id"), req.Status)
+		switch {
+		case errors.Is(err, ErrSettlementNotFound):
+			response.NotFound(c, "Settlement not found")
+		case errors.Is(err, ErrInvalidStatus):
+			response.BadRequest(c, err.Error())
+		case errors.Is(err, ErrInvalidTransition):
+			response.Conflict(c, err.Error())
+		case err != nil:
+			response.InternalError(c, err.Error())
+		default:
+			response.Success(c, settlement)
+		}
+	}
+}
+
+// SetCounterpartyAgreementHandler handles PUT requests to set a client's counterparty agreement
+// Requires internal authentication
+// URL parameter: client_id
+// Request body should contain the agreed settlement cycle, and optionally the currency and netting eligibility
+func (h *GinHandlers) SetCounterpartyAgreementHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req CounterpartyAgreementRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BindError(c, err)
+			return
+		}
+
+		agreement := &CounterpartyAgreement{
+			ClientID:            c.Param("client_id"),
+			SettlementCycleDays: *req.SettlementCycleDays,
+			Currency:            req.Currency,
+			NettingEligible:     req.NettingEligible,
+		}
+		err := h.service.SetCounterpartyAgreement(agreement)
+		if errors.Is(err, ErrInvalidSettlementCycle) || errors.Is(err, ErrInvalidAgreementCcy) {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		response.HandleWithStatus(c, agreement, err, http.StatusOK)
 	}
 }
 
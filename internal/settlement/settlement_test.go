@@ -0,0 +1,653 @@
+package settlement
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/ksred/klear-api/internal/breaks"
+	"github.com/ksred/klear-api/internal/clearing"
+	"github.com/ksred/klear-api/internal/database/dbtest"
+	"github.com/ksred/klear-api/internal/events"
+	"github.com/ksred/klear-api/internal/types"
+	"github.com/ksred/klear-api/pkg/common"
+)
+
+// testNow is the time every test clock starts at, during market hours
+var testNow = time.Date(2026, 10, 14, 12, 0, 0, 0, time.Local)
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	os.Exit(m.Run())
+}
+
+// newTestService creates a settlement service with the default configuration over a fresh database,
+// with its clock stopped at testNow
+func newTestService(t *testing.T) (*Service, *common.FakeClock) {
+	t.Helper()
+	return newTestServiceWithConfig(t, DefaultConfig())
+}
+
+// newTestServiceWithConfig creates a settlement service over a fresh database, with its clock stopped at testNow
+func newTestServiceWithConfig(t *testing.T, config Config) (*Service, *common.FakeClock) {
+	t.Helper()
+	db := dbtest.Open(t,
+		&types.Order{},
+		&types.Execution{},
+		&types.ExchangeFill{},
+		&types.Allocation{},
+		&types.VenueAttempt{},
+		&clearing.Clearing{},
+		&clearing.TradeNetting{},
+		&Settlement{},
+		&SettlementBatch{},
+		&CounterpartyAgreement{},
+		&ClientWebhook{},
+		&WebhookDeadLetter{},
+		&events.DomainEvent{},
+		&breaks.TradeBreak{},
+	)
+	clock := common.NewFakeClock(testNow)
+	service := NewService(db, config)
+	service.SetClock(clock)
+	return service, clock
+}
+
+// seedClearedTrade records a client's filled order, its completed execution and a CLEARED
+// clearing for it, executed at the given time
+func seedClearedTrade(t *testing.T, service *Service, clientID, side string, quantity, price float64, at time.Time) *types.Execution {
+	t.Helper()
+	return seedTrade(t, service, clientID, "AAPL", side, "USD", quantity, price, at)
+}
+
+// seedTrade is seedClearedTrade for any symbol and currency
+func seedTrade(t *testing.T, service *Service, clientID, symbol, side, currency string, quantity, price float64, at time.Time) *types.Execution {
+	t.Helper()
+	execution := seedExecutedTrade(t, service, clientID, symbol, side, currency, quantity, price, at)
+	clearingRecord := &clearing.Clearing{
+		ClearingID:       "CLR_" + uuid.New().String(),
+		TradeID:          execution.ExecutionID,
+		ClearingStatus:   clearing.StatusCleared,
+		SettlementAmount: quantity * price,
+		CreatedAt:        at,
+		UpdatedAt:        at,
+	}
+	if err := service.db.db.Create(clearingRecord).Error; err != nil {
+		t.Fatalf("failed to seed clearing: %v", err)
+	}
+	return execution
+}
+
+// seedExecutedTrade records a client's filled order and its completed execution, not yet cleared
+func seedExecutedTrade(t *testing.T, service *Service, clientID, symbol, side, currency string, quantity, price float64, at time.Time) *types.Execution {
+	t.Helper()
+	order := &types.Order{
+		OrderID:   uuid.New().String(),
+		ClientID:  clientID,
+		Symbol:    symbol,
+		Side:      side,
+		OrderType: "LIMIT",
+		Quantity:  quantity,
+		Price:     price,
+		Currency:  currency,
+		Status:    "FILLED",
+		CreatedAt: at,
+		UpdatedAt: at,
+	}
+	executionID := uuid.New().String()
+	execution := &types.Execution{
+		ExecutionID:   executionID,
+		OrderID:       order.OrderID,
+		TotalQuantity: quantity,
+		AveragePrice:  price,
+		Side:          side,
+		Status:        "COMPLETED",
+		Fills: []types.ExchangeFill{{
+			FillID:       "FILL-" + executionID,
+			ExchangeID:   "EXCH1",
+			ExchangeName: "Primary Exchange",
+			Price:        price,
+			Quantity:     quantity,
+			FeeRate:      0.001,
+			FeeAmount:    price * quantity * 0.001,
+			CreatedAt:    at,
+		}},
+		CreatedAt: at,
+		UpdatedAt: at,
+	}
+	if err := service.db.db.Create(order).Error; err != nil {
+		t.Fatalf("failed to seed order: %v", err)
+	}
+	if err := service.db.db.Create(execution).Error; err != nil {
+		t.Fatalf("failed to seed execution: %v", err)
+	}
+	return execution
+}
+
+// settleTrade settles the trade, failing the test on error
+func settleTrade(t *testing.T, service *Service, tradeID string) *SettlementResponse {
+	t.Helper()
+	settlement, _, err := service.SettleTrade(tradeID)
+	if err != nil {
+		t.Fatalf("SettleTrade: %v", err)
+	}
+	return settlement
+}
+
+// updateStatus moves the settlement through the given statuses, failing the test on error
+func updateStatus(t *testing.T, service *Service, settlementID string, statuses ...string) {
+	t.Helper()
+	for _, status := range statuses {
+		if _, err := service.UpdateSettlementStatus(settlementID, status); err != nil {
+			t.Fatalf("UpdateSettlementStatus(%s): %v", status, err)
+		}
+	}
+}
+
+// authenticate stands in for the JWT middleware, authenticating every request as the client
+func authenticate(clientID string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("claims", jwt.MapClaims{"client_id": clientID})
+		c.Set("clientID", clientID)
+		c.Next()
+	}
+}
+
+// performRequest serves a request with an optional JSON body
+func performRequest(router http.Handler, method, path string, body interface{}) *httptest.ResponseRecorder {
+	var encoded []byte
+	if body != nil {
+		encoded, _ = json.Marshal(body)
+	}
+	req := httptest.NewRequest(method, path, bytes.NewReader(encoded))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+// decodeData decodes the data of a success response envelope into v
+func decodeData(t *testing.T, recorder *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	var envelope struct {
+		Success bool            `json:"success"`
+		Data    json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("failed to decode response %s: %v", recorder.Body.String(), err)
+	}
+	if !envelope.Success {
+		t.Fatalf("response was not successful: %s", recorder.Body.String())
+	}
+	if err := json.Unmarshal(envelope.Data, v); err != nil {
+		t.Fatalf("failed to decode response data %s: %v", envelope.Data, err)
+	}
+}
+
+// assertAmount fails the test if got is not want to the cent
+func assertAmount(t *testing.T, name string, got, want float64) {
+	t.Helper()
+	if math.Abs(got-want) > 0.005 {
+		t.Errorf("%s = %v, want %v", name, got, want)
+	}
+}
+
+func TestSettleTradeAppliesVolumeRebate(t *testing.T) {
+	service, _ := newTestService(t)
+
+	// Earlier this month the client traded $2M, crossing the $1M tier
+	seedClearedTrade(t, service, "client-big", "BUY", 10000, 200, testNow.Add(-72*time.Hour))
+	trade := seedClearedTrade(t, service, "client-big", "BUY", 100, 150, testNow.Add(-time.Minute))
+	// Another client has traded only this trade
+	smallTrade := seedClearedTrade(t, service, "client-small", "BUY", 100, 150, testNow.Add(-time.Minute))
+
+	settlement := settleTrade(t, service, trade.ExecutionID)
+	assertAmount(t, "gross fees", settlement.GrossFees, 15)      // 0.1% of $15,000
+	assertAmount(t, "fee rebate", settlement.FeeRebate, 1.5)     // 10% tier
+	assertAmount(t, "net fees", settlement.SettlementFees, 13.5) // Gross less rebate
+
+	small := settleTrade(t, service, smallTrade.ExecutionID)
+	assertAmount(t, "gross fees", small.GrossFees, 15)
+	assertAmount(t, "fee rebate", small.FeeRebate, 0)
+	assertAmount(t, "net fees", small.SettlementFees, 15)
+}
+
+func TestMonthToDateVolumeExcludesEarlierMonths(t *testing.T) {
+	service, _ := newTestService(t)
+	seedClearedTrade(t, service, "client-1", "BUY", 100, 150, testNow.Add(-time.Hour))
+	seedClearedTrade(t, service, "client-1", "SELL", 10, 100, testNow.Add(-time.Hour))
+	seedClearedTrade(t, service, "client-1", "BUY", 1000, 150, testNow.AddDate(0, -1, 0))
+
+	volume, err := service.db.GetMonthToDateVolume("client-1", testNow)
+	if err != nil {
+		t.Fatalf("GetMonthToDateVolume: %v", err)
+	}
+	assertAmount(t, "month-to-date volume", volume, 100*150+10*100)
+}
+
+func TestFeeScheduleTiers(t *testing.T) {
+	schedule := DefaultFeeSchedule()
+	tests := []struct {
+		volume     float64
+		wantRebate float64
+	}{
+		{999999, 0},
+		{1000000, 1.0},
+		{10000000, 2.5},
+		{50000000, 4.0},
+	}
+	for _, tt := range tests {
+		gross, rebate := schedule.Calculate(10000, tt.volume)
+		assertAmount(t, "gross fee", gross, 10)
+		assertAmount(t, "rebate", rebate, tt.wantRebate)
+	}
+}
+
+func TestSettleTradeRoundsToCurrencyPrecision(t *testing.T) {
+	service, _ := newTestService(t)
+	// $4,074.0711 notional leaves fractional cents in both the amount and the 0.1% fee
+	trade := seedClearedTrade(t, service, "client-1", "BUY", 33, 123.4567, testNow.Add(-time.Minute))
+
+	settlement := settleTrade(t, service, trade.ExecutionID)
+	if settlement.FinalAmount != 4074.07 {
+		t.Errorf("final amount = %v, want 4074.07", settlement.FinalAmount)
+	}
+	if settlement.GrossFees != 4.07 {
+		t.Errorf("gross fees = %v, want 4.07", settlement.GrossFees)
+	}
+	if settlement.SettlementFees != 4.07 {
+		t.Errorf("settlement fees = %v, want 4.07", settlement.SettlementFees)
+	}
+
+	var stored Settlement
+	if err := service.db.db.Where("settlement_id = ?", settlement.SettlementID).First(&stored).Error; err != nil {
+		t.Fatalf("failed to load settlement: %v", err)
+	}
+	if stored.FinalAmount != 4074.07 || stored.SettlementFees != 4.07 {
+		t.Errorf("stored final amount %v and fees %v, want 4074.07 and 4.07", stored.FinalAmount, stored.SettlementFees)
+	}
+}
+
+func TestGetTradeLifecycleHandler(t *testing.T) {
+	service, _ := newTestService(t)
+	settled := seedClearedTrade(t, service, "client-1", "BUY", 100, 150, testNow.Add(-time.Minute))
+	settlement := settleTrade(t, service, settled.ExecutionID)
+	updateStatus(t, service, settlement.SettlementID, StatusSettling, StatusSettled)
+	executedOnly := seedExecutedTrade(t, service, "client-1", "AAPL", "SELL", "USD", 50, 151, testNow.Add(-time.Minute))
+	// Two venues rejected the settled trade's order before it filled
+	for i, exchangeID := range []string{"EXCH2", "EXCH3"} {
+		attempt := &types.VenueAttempt{
+			AttemptID:   uuid.New().String(),
+			OrderID:     settled.OrderID,
+			ExecutionID: settled.ExecutionID,
+			ExchangeID:  exchangeID,
+			Reason:      "insufficient liquidity",
+			CreatedAt:   testNow.Add(time.Duration(i-2) * time.Minute),
+		}
+		if err := service.db.db.Create(attempt).Error; err != nil {
+			t.Fatalf("failed to seed venue attempt: %v", err)
+		}
+	}
+
+	router := gin.New()
+	router.GET("/trades/:execution_id/lifecycle", NewGinHandlers(service).GetTradeLifecycleHandler())
+	get := func(executionID string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/trades/"+executionID+"/lifecycle", nil))
+		return recorder
+	}
+
+	var full TradeLifecycle
+	decodeData(t, get(settled.ExecutionID), &full)
+	if full.Order == nil || full.Order.OrderID != settled.OrderID {
+		t.Errorf("order = %+v, want %s", full.Order, settled.OrderID)
+	}
+	if full.Execution == nil || len(full.Execution.Fills) != 1 {
+		t.Errorf("execution = %+v, want the execution with its fill", full.Execution)
+	}
+	if full.Clearing == nil || full.Clearing.ClearingStatus != clearing.StatusCleared {
+		t.Errorf("clearing = %+v, want a CLEARED clearing", full.Clearing)
+	}
+	if full.Settlement == nil || full.Settlement.SettlementStatus != StatusSettled {
+		t.Errorf("settlement = %+v, want a SETTLED settlement", full.Settlement)
+	}
+	if len(full.FailedAttempts) != 2 || full.FailedAttempts[0].ExchangeID != "EXCH2" || full.FailedAttempts[1].ExchangeID != "EXCH3" {
+		t.Errorf("failed attempts = %+v, want the EXCH2 and EXCH3 rejections oldest first", full.FailedAttempts)
+	}
+
+	var partial TradeLifecycle
+	decodeData(t, get(executedOnly.ExecutionID), &partial)
+	if partial.Order == nil || partial.Execution == nil {
+		t.Errorf("lifecycle = %+v, want the order and execution", partial)
+	}
+	if partial.Clearing != nil || partial.Settlement != nil {
+		t.Errorf("clearing = %+v, settlement = %+v, want null for stages not reached", partial.Clearing, partial.Settlement)
+	}
+	if len(partial.FailedAttempts) != 0 {
+		t.Errorf("failed attempts = %+v, want none for an order filled first time", partial.FailedAttempts)
+	}
+
+	if recorder := get(uuid.New().String()); recorder.Code != http.StatusNotFound {
+		t.Errorf("unknown execution status = %d, want 404", recorder.Code)
+	}
+}
+
+func TestGetSettlementStatusesHandler(t *testing.T) {
+	service, _ := newTestService(t)
+	pending := seedClearedTrade(t, service, "client-1", "BUY", 100, 150, testNow.Add(-time.Minute))
+	settleTrade(t, service, pending.ExecutionID)
+	settled := seedClearedTrade(t, service, "client-1", "SELL", 50, 150, testNow.Add(-time.Minute))
+	updateStatus(t, service, settleTrade(t, service, settled.ExecutionID).SettlementID, StatusSettling, StatusSettled)
+	unsettled := seedClearedTrade(t, service, "client-1", "BUY", 10, 150, testNow.Add(-time.Minute))
+	// Another client's settlement is not visible
+	otherClient := seedClearedTrade(t, service, "client-2", "BUY", 10, 150, testNow.Add(-time.Minute))
+	settleTrade(t, service, otherClient.ExecutionID)
+	unknown := uuid.New().String()
+
+	router := gin.New()
+	router.POST("/settlements/status", authenticate("client-1"), NewGinHandlers(service).GetSettlementStatusesHandler())
+	recorder := performRequest(router, http.MethodPost, "/settlements/status", BatchStatusRequest{TradeIDs: []string{
+		pending.ExecutionID, settled.ExecutionID, unsettled.ExecutionID, otherClient.ExecutionID, unknown,
+	}})
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", recorder.Code, recorder.Body.String())
+	}
+
+	var statuses map[string]string
+	decodeData(t, recorder, &statuses)
+	want := map[string]string{
+		pending.ExecutionID:     StatusPending,
+		settled.ExecutionID:     StatusSettled,
+		unsettled.ExecutionID:   StatusNotFound,
+		otherClient.ExecutionID: StatusNotFound,
+		unknown:                 StatusNotFound,
+	}
+	for tradeID, status := range want {
+		if statuses[tradeID] != status {
+			t.Errorf("status of %s = %q, want %q", tradeID, statuses[tradeID], status)
+		}
+	}
+}
+
+func TestGetSettlementStatusesCapsTradeIDs(t *testing.T) {
+	service, _ := newTestService(t)
+	router := gin.New()
+	router.POST("/settlements/status", authenticate("client-1"), NewGinHandlers(service).GetSettlementStatusesHandler())
+
+	tradeIDs := make([]string, MaxBatchStatusTradeIDs+1)
+	for i := range tradeIDs {
+		tradeIDs[i] = uuid.New().String()
+	}
+	for name, ids := range map[string][]string{"empty": {}, "over the cap": tradeIDs} {
+		recorder := performRequest(router, http.MethodPost, "/settlements/status", BatchStatusRequest{TradeIDs: ids})
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("%s status = %d, want 400", name, recorder.Code)
+		}
+	}
+}
+
+func TestSettleTradeEnforcesMarketHours(t *testing.T) {
+	service, clock := newTestService(t)
+	midnight := time.Date(testNow.Year(), testNow.Month(), testNow.Day(), 0, 0, 0, 0, time.Local)
+	clock.Set(midnight)
+	execution := seedClearedTrade(t, service, "client-1", "BUY", 100, 150, midnight.Add(-time.Minute))
+
+	if _, _, err := service.SettleTrade(execution.ExecutionID); err == nil || !strings.Contains(err.Error(), "market hours") {
+		t.Fatalf("SettleTrade at midnight error = %v, want a market hours rejection", err)
+	}
+	failed, err := service.db.GetSettlementByTradeID(execution.ExecutionID)
+	if err != nil {
+		t.Fatalf("GetSettlementByTradeID: %v", err)
+	}
+	if failed.SettlementStatus != StatusFailed {
+		t.Errorf("settlement status = %s, want %s", failed.SettlementStatus, StatusFailed)
+	}
+
+	clock.Set(testNow)
+	if settlement := settleTrade(t, service, execution.ExecutionID); settlement.SettlementStatus != StatusPending {
+		t.Errorf("mid-session settlement status = %s, want %s", settlement.SettlementStatus, StatusPending)
+	}
+}
+
+func TestCounterpartyAgreementOverridesDefaultCycle(t *testing.T) {
+	service, _ := newTestService(t)
+	router := gin.New()
+	router.PUT("/clients/:client_id/counterparty-agreement", NewGinHandlers(service).SetCounterpartyAgreementHandler())
+
+	recorder := performRequest(router, http.MethodPut, "/clients/client-t1/counterparty-agreement",
+		map[string]interface{}{"settlement_cycle_days": 1, "currency": "eur", "netting_eligible": true})
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", recorder.Code, recorder.Body.String())
+	}
+	var agreement CounterpartyAgreement
+	decodeData(t, recorder, &agreement)
+	if agreement.AgreementID == "" || agreement.Currency != "EUR" {
+		t.Fatalf("agreement = %+v, want an EUR agreement with an ID", agreement)
+	}
+
+	agreed := seedTrade(t, service, "client-t1", "AAPL", "BUY", "EUR", 100, 150, testNow.Add(-time.Minute))
+	otherCurrency := seedClearedTrade(t, service, "client-t1", "BUY", 100, 150, testNow.Add(-time.Minute))
+	standard := seedClearedTrade(t, service, "client-t2", "BUY", 100, 150, testNow.Add(-time.Minute))
+
+	settlement := settleTrade(t, service, agreed.ExecutionID)
+	if want := testNow.AddDate(0, 0, 1); !settlement.SettlementDate.Equal(want) {
+		t.Errorf("agreed settlement date = %s, want T+1 %s", settlement.SettlementDate, want)
+	}
+	if settlement.Currency != "EUR" || settlement.AgreementID != agreement.AgreementID {
+		t.Errorf("agreed settlement currency %s and agreement %q, want EUR and %s",
+			settlement.Currency, settlement.AgreementID, agreement.AgreementID)
+	}
+
+	// A USD trade is not relabelled as EUR without conversion
+	settleRouter := gin.New()
+	settleRouter.POST("/settlement/:trade_id", NewGinHandlers(service).SettleTradeHandler())
+	if recorder := performRequest(settleRouter, http.MethodPost, "/settlement/"+otherCurrency.ExecutionID, nil); recorder.Code != http.StatusConflict {
+		t.Errorf("USD trade on an EUR agreement status = %d, want 409: %s", recorder.Code, recorder.Body.String())
+	}
+	if count := countSettlements(t, service, otherCurrency.ExecutionID); count != 0 {
+		t.Errorf("USD trade on an EUR agreement has %d settlements, want none", count)
+	}
+
+	defaulted := settleTrade(t, service, standard.ExecutionID)
+	if want := testNow.AddDate(0, 0, 2); !defaulted.SettlementDate.Equal(want) {
+		t.Errorf("default settlement date = %s, want T+2 %s", defaulted.SettlementDate, want)
+	}
+	if defaulted.AgreementID != "" {
+		t.Errorf("default settlement agreement = %q, want none", defaulted.AgreementID)
+	}
+}
+
+func TestSetCounterpartyAgreementHandlerRejectsInvalidTerms(t *testing.T) {
+	service, _ := newTestService(t)
+	router := gin.New()
+	router.PUT("/clients/:client_id/counterparty-agreement", NewGinHandlers(service).SetCounterpartyAgreementHandler())
+
+	for _, body := range []map[string]interface{}{
+		{},
+		{"settlement_cycle_days": -1},
+		{"settlement_cycle_days": 1, "currency": "XYZ"},
+	} {
+		recorder := performRequest(router, http.MethodPut, "/clients/client-1/counterparty-agreement", body)
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("body %v: status = %d, want 400", body, recorder.Code)
+		}
+	}
+}
+
+func TestUpdateSettlementStatusHandlerEnforcesTransitions(t *testing.T) {
+	service, _ := newTestService(t)
+	trade := seedClearedTrade(t, service, "client-1", "BUY", 100, 150, testNow.Add(-time.Minute))
+	settlement := settleTrade(t, service, trade.ExecutionID)
+
+	router := gin.New()
+	router.PUT("/settlement/:settlement_id/status", NewGinHandlers(service).UpdateSettlementStatusHandler())
+	update := func(status string) int {
+		return performRequest(router, http.MethodPut, "/settlement/"+settlement.SettlementID+"/status",
+			UpdateStatusRequest{Status: status}).Code
+	}
+
+	tests := []struct {
+		status string
+		want   int
+	}{
+		{StatusSettling, http.StatusOK},
+		{StatusSettling, http.StatusOK}, // Retried update is a no-op
+		{StatusSettled, http.StatusOK},
+		{StatusPending, http.StatusConflict}, // Settled is final
+		{"PAID", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if got := update(tt.status); got != tt.want {
+			t.Errorf("update to %s status = %d, want %d", tt.status, got, tt.want)
+		}
+	}
+
+	stored, err := service.db.GetSettlementByTradeID(trade.ExecutionID)
+	if err != nil {
+		t.Fatalf("GetSettlementByTradeID: %v", err)
+	}
+	if stored.SettlementStatus != StatusSettled {
+		t.Errorf("settlement status = %s, want %s", stored.SettlementStatus, StatusSettled)
+	}
+
+	recorder := performRequest(router, http.MethodPut, "/settlement/STL_unknown/status", UpdateStatusRequest{Status: StatusSettling})
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("unknown settlement status = %d, want 404", recorder.Code)
+	}
+}
+
+func TestSettleTradeUsesOrderCurrency(t *testing.T) {
+	config := DefaultConfig()
+	config.DefaultCurrency = "GBP"
+	service, _ := newTestServiceWithConfig(t, config)
+	euro := seedTrade(t, service, "client-1", "SAP", "BUY", "EUR", 100, 120, testNow.Add(-time.Minute))
+	unpriced := seedTrade(t, service, "client-1", "AAPL", "BUY", "", 100, 150, testNow.Add(-time.Minute))
+
+	if settlement := settleTrade(t, service, euro.ExecutionID); settlement.Currency != "EUR" {
+		t.Errorf("settlement currency = %s, want the order's EUR", settlement.Currency)
+	}
+	if settlement := settleTrade(t, service, unpriced.ExecutionID); settlement.Currency != "GBP" {
+		t.Errorf("settlement currency = %s, want the default GBP", settlement.Currency)
+	}
+}
+
+func TestSettleTradeHandlerRejectsInvalidTradeIDs(t *testing.T) {
+	service, _ := newTestService(t)
+	router := gin.New()
+	router.POST("/settlement/:trade_id", NewGinHandlers(service).SettleTradeHandler())
+
+	for _, tradeID := range []string{"%20", "not-a-trade", "EXEC-12a"} {
+		if recorder := performRequest(router, http.MethodPost, "/settlement/"+tradeID, nil); recorder.Code != http.StatusBadRequest {
+			t.Errorf("trade ID %q: status = %d, want 400", tradeID, recorder.Code)
+		}
+	}
+}
+
+func TestDeferredBalancesSumExactly(t *testing.T) {
+	service, _ := newTestService(t)
+	for _, amount := range []float64{0.1, 0.2} {
+		execution := seedExecutedTrade(t, service, "client-1", "AAPL", "BUY", "USD", 1, amount, testNow)
+		settlement := &Settlement{
+			SettlementID:     "STL_" + uuid.New().String(),
+			TradeID:          execution.ExecutionID,
+			ExecutionID:      execution.ExecutionID,
+			ClientID:         "client-1",
+			SettlementStatus: StatusDeferred,
+			FinalAmount:      amount,
+			Currency:         "USD",
+		}
+		if err := service.db.db.Create(settlement).Error; err != nil {
+			t.Fatalf("failed to seed settlement: %v", err)
+		}
+	}
+
+	// Summed as floats, 0.1 + 0.2 is 0.30000000000000004
+	balances, err := service.db.GetDeferredBalances("client-1")
+	if err != nil {
+		t.Fatalf("GetDeferredBalances: %v", err)
+	}
+	if len(balances) != 1 || balances[0].Amount != 0.3 || balances[0].Settlements != 2 {
+		t.Errorf("balances = %+v, want exactly 0.3 USD across 2 settlements", balances)
+	}
+}
+
+func TestExecutionAmountsRoundTripAsUnits(t *testing.T) {
+	service, _ := newTestService(t)
+	execution := seedExecutedTrade(t, service, "client-1", "AAPL", "BUY", "USD", 3, 100.10, testNow)
+
+	var stored types.Execution
+	if err := service.db.db.Preload("Fills").Where("execution_id = ?", execution.ExecutionID).First(&stored).Error; err != nil {
+		t.Fatalf("failed to load execution: %v", err)
+	}
+	if stored.AveragePriceUnits != 10010000000 || stored.AveragePrice != 100.10 {
+		t.Errorf("average price = %v (%d units), want 100.10", stored.AveragePrice, stored.AveragePriceUnits)
+	}
+	if len(stored.Fills) != 1 || stored.Fills[0].PriceUnits != 10010000000 || stored.Fills[0].QuantityUnits != 300000000 {
+		t.Errorf("fills = %+v, want one fill of 3 @ 100.10 in units", stored.Fills)
+	}
+}
+
+func TestSettleTradeHandlerRejectsDeletedOrder(t *testing.T) {
+	service, _ := newTestService(t)
+	router := gin.New()
+	router.POST("/settlement/:trade_id", NewGinHandlers(service).SettleTradeHandler())
+
+	execution := seedClearedTrade(t, service, "client-1", "BUY", 100, 150, testNow.Add(-time.Minute))
+	if err := service.db.db.Where("order_id = ?", execution.OrderID).Delete(&types.Order{}).Error; err != nil {
+		t.Fatalf("failed to delete order: %v", err)
+	}
+
+	recorder := performRequest(router, http.MethodPost, "/settlement/"+execution.ExecutionID, nil)
+	if recorder.Code != http.StatusConflict || !strings.Contains(recorder.Body.String(), types.ErrOrderDeleted.Error()) {
+		t.Errorf("status = %d with %s, want 409 reporting the deleted order", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestListSettlementsCursorPagesWithoutDuplicatesOrGaps(t *testing.T) {
+	service, _ := newTestService(t)
+	created := map[string]bool{}
+	for i := 0; i < 5; i++ {
+		trade := seedClearedTrade(t, service, "client-1", "BUY", 10, 150, testNow.Add(-time.Minute))
+		created[settleTrade(t, service, trade.ExecutionID).SettlementID] = true
+	}
+
+	seen := map[string]bool{}
+	page, err := common.ParsePage("2", "", "", common.DefaultPageSize())
+	if err != nil {
+		t.Fatalf("ParsePage: %v", err)
+	}
+	for pages := 1; ; pages++ {
+		result, err := service.ListSettlements("client-1", page)
+		if err != nil {
+			t.Fatalf("ListSettlements: %v", err)
+		}
+		for _, settlement := range result.Settlements {
+			if seen[settlement.SettlementID] {
+				t.Errorf("settlement %s listed twice", settlement.SettlementID)
+			}
+			seen[settlement.SettlementID] = true
+		}
+		if result.NextCursor == "" {
+			if pages != 3 {
+				t.Errorf("listed %d pages of 2, want 3 for 5 settlements", pages)
+			}
+			break
+		}
+		if page, err = common.ParsePage("2", "", result.NextCursor, common.DefaultPageSize()); err != nil {
+			t.Fatalf("ParsePage cursor: %v", err)
+		}
+	}
+
+	if len(seen) != len(created) {
+		t.Errorf("listed %d distinct settlements, want all %d", len(seen), len(created))
+	}
+}
@@ -0,0 +1,406 @@
+package settlement
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ksred/klear-api/internal/auth"
+	"github.com/ksred/klear-api/pkg/common"
+	"github.com/ksred/klear-api/pkg/response"
+	"github.com/rs/zerolog/log"
+)
+
+// Headers sent with every webhook delivery
+const (
+	WebhookSignatureHeader = "X-Klear-Signature"
+	WebhookTimestampHeader = "X-Klear-Timestamp"
+)
+
+// EventSettlementStatusChanged is the event type delivered when a settlement changes status
+const EventSettlementStatusChanged = "settlement.status_changed"
+
+// minWebhookSecretLength is the shortest shared secret accepted for signing deliveries
+const minWebhookSecretLength = 16
+
+// webhookTimeout bounds a single delivery attempt
+const webhookTimeout = 5 * time.Second
+
+// webhookLookupTimeout bounds resolving a webhook's host when it is registered
+const webhookLookupTimeout = 2 * time.Second
+
+var (
+	ErrInvalidWebhookURL     = errors.New("webhook URL must be an absolute https URL")
+	ErrWebhookTargetPrivate  = errors.New("webhook URL must resolve only to public addresses")
+	ErrWebhookSecretTooShort = fmt.Errorf("webhook secret must be at least %d characters", minWebhookSecretLength)
+	ErrWebhookNotFound       = errors.New("no webhook registered for client")
+)
+
+// WebhookRequest is the body accepted when registering or updating a client's webhook
+type WebhookRequest struct {
+	URL    string `json:"url" binding:"required"`
+	Secret string `json:"secret" binding:"required"`
+}
+
+// SettlementEvent is the payload delivered to a client's webhook
+type SettlementEvent struct {
+	Event          string    `json:"event"`
+	SettlementID   string    `json:"settlement_id"`
+	TradeID        string    `json:"trade_id"`
+	ClientID       string    `json:"client_id"`
+	PreviousStatus string    `json:"previous_status"`
+	Status         string    `json:"status"`
+	Timestamp      time.Time `json:"timestamp"`
+}
+
+// SignWebhookPayload returns the hex-encoded HMAC-SHA256 of "<timestamp>.<body>" keyed by the secret
+// Clients recompute it to verify a delivery came from this service
+func SignWebhookPayload(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature reports whether signature is valid for the timestamp and body
+func VerifyWebhookSignature(secret, timestamp string, body []byte, signature string) bool {
+	expected := SignWebhookPayload(secret, timestamp, body)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+// WebhookRetryPolicy controls how a failed webhook delivery is retried before it is dead-lettered
+type WebhookRetryPolicy struct {
+	MaxAttempts int           // Delivery attempts, including the first, before the event is dead-lettered
+	BaseBackoff time.Duration // Delay before the first retry; doubled for each retry after it
+	MaxBackoff  time.Duration // Cap on the delay between attempts
+}
+
+// DefaultWebhookRetryPolicy returns the retry policy used when nothing is overridden
+func DefaultWebhookRetryPolicy() WebhookRetryPolicy {
+	return WebhookRetryPolicy{
+		MaxAttempts: 3,
+		BaseBackoff: 2 * time.Second,
+		MaxBackoff:  30 * time.Second,
+	}
+}
+
+// Backoff returns the delay after the given failed attempt: the base backoff doubled for each
+// earlier retry and capped at the maximum, with the upper half jittered so that deliveries
+// failing together do not retry in lockstep
+func (p WebhookRetryPolicy) Backoff(attempt int) time.Duration {
+	delay := p.BaseBackoff
+	for i := 1; i < attempt && delay < p.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	half := delay / 2
+	if half <= 0 {
+		return delay
+	}
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// WebhookNotifier delivers settlement status changes to the owning client's webhook
+type WebhookNotifier struct {
+	db     *Database
+	client *http.Client
+	retry  WebhookRetryPolicy
+	clock  common.Clock
+}
+
+// NewWebhookNotifier creates a notifier that looks up client webhooks in the given database
+// and retries failed deliveries according to the policy
+func NewWebhookNotifier(db *Database, retry WebhookRetryPolicy) *WebhookNotifier {
+	return &WebhookNotifier{
+		db:     db,
+		client: &http.Client{Timeout: webhookTimeout},
+		retry:  retry,
+		clock:  common.RealClock{},
+	}
+}
+
+// Notify sends a settlement's status change to the webhook of the client that owns it
+// Delivery happens in the background so settlement processing is never held up by a slow endpoint
+// Parameters:
+//   - settlement: The settlement after its status changed
+//   - previousStatus: The status it changed from
+func (n *WebhookNotifier) Notify(settlement *Settlement, previousStatus string) {
+	logger := log.With().
+		Str("settlement_id", settlement.SettlementID).
+		Str("client_id", settlement.ClientID).
+		Str("component", "settlement_webhooks").
+		Logger()
+
+	webhook, err := n.db.GetClientWebhook(settlement.ClientID)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to look up client webhook")
+		return
+	}
+	if webhook == nil {
+		return
+	}
+
+	body, err := json.Marshal(SettlementEvent{
+		Event:          EventSettlementStatusChanged,
+		SettlementID:   settlement.SettlementID,
+		TradeID:        settlement.TradeID,
+		ClientID:       settlement.ClientID,
+		PreviousStatus: previousStatus,
+		Status:         settlement.SettlementStatus,
+		Timestamp:      n.clock.Now(),
+	})
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to marshal settlement event")
+		return
+	}
+
+	go n.deliver(webhook, body, settlement.SettlementID)
+}
+
+// deliver posts a signed event, retrying failed attempts with backoff
+// An event that is still undelivered once the attempts run out is dead-lettered so it can be re-driven
+func (n *WebhookNotifier) deliver(webhook *ClientWebhook, body []byte, settlementID string) {
+	logger := log.With().
+		Str("settlement_id", settlementID).
+		Str("url", webhook.URL).
+		Str("component", "settlement_webhooks").
+		Logger()
+
+	maxAttempts := n.retry.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		err = n.post(webhook.URL, webhook.Secret, body)
+		if err == nil {
+			logger.Debug().Int("attempt", attempt).Msg("delivered settlement webhook")
+			return
+		}
+		logger.Warn().Err(err).Int("attempt", attempt).Msg("settlement webhook delivery failed")
+		if attempt < maxAttempts {
+			time.Sleep(n.retry.Backoff(attempt))
+		}
+	}
+
+	deadLetter := &WebhookDeadLetter{
+		DeadLetterID: "DLQ_" + uuid.New().String(),
+		ClientID:     webhook.ClientID,
+		SettlementID: settlementID,
+		Event:        EventSettlementStatusChanged,
+		URL:          webhook.URL,
+		Payload:      string(body),
+		Attempts:     maxAttempts,
+		LastError:    err.Error(),
+		Status:       DeadLetterStatusDead,
+		CreatedAt:    n.clock.Now(),
+		UpdatedAt:    n.clock.Now(),
+	}
+	if err := n.db.CreateWebhookDeadLetter(deadLetter); err != nil {
+		logger.Error().Err(err).Int("attempts", maxAttempts).Msg("failed to dead-letter undelivered settlement webhook")
+		return
+	}
+	logger.Error().
+		Str("dead_letter_id", deadLetter.DeadLetterID).
+		Int("attempts", maxAttempts).
+		Msg("giving up on settlement webhook delivery, dead-lettered")
+}
+
+// post makes a single signed delivery attempt; any non-2xx response is a failure
+func (n *WebhookNotifier) post(endpoint, secret string, body []byte) error {
+	timestamp := strconv.FormatInt(n.clock.Now().Unix(), 10)
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(secret, timestamp, body))
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// SetWebhook registers or replaces the webhook that receives a client's settlement status changes
+// The URL must be https and its host must resolve only to public addresses, so deliveries cannot be
+// aimed at the settlement worker's own network, unless the service allows private webhooks
+// Parameters:
+//   - clientID: ID of the client registering the webhook
+//   - rawURL: Absolute https URL to deliver events to
+//   - secret: Shared secret used to sign deliveries
+func (s *Service) SetWebhook(clientID, rawURL, secret string) (*ClientWebhook, error) {
+	rawURL = strings.TrimSpace(rawURL)
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Hostname() == "" {
+		return nil, ErrInvalidWebhookURL
+	}
+	if parsed.Scheme != "https" && !(s.config.AllowPrivateWebhooks && parsed.Scheme == "http") {
+		return nil, ErrInvalidWebhookURL
+	}
+	if len(secret) < minWebhookSecretLength {
+		return nil, ErrWebhookSecretTooShort
+	}
+	if !s.config.AllowPrivateWebhooks {
+		if err := checkPublicHost(parsed.Hostname()); err != nil {
+			return nil, err
+		}
+	}
+
+	webhook := &ClientWebhook{
+		ClientID: common.NormalizeID(clientID),
+		URL:      rawURL,
+		Secret:   secret,
+	}
+	if err := s.db.SaveClientWebhook(webhook); err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+// checkPublicHost resolves a webhook host and rejects it unless every address it resolves to is
+// public: loopback, private, link-local (including the cloud metadata address), unspecified and
+// multicast addresses are all refused
+func checkPublicHost(host string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), webhookLookupTimeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return fmt.Errorf("%w: %s could not be resolved", ErrWebhookTargetPrivate, host)
+	}
+	for _, addr := range addrs {
+		ip := addr.IP
+		if !ip.IsGlobalUnicast() || ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() {
+			return fmt.Errorf("%w: %s resolves to %s", ErrWebhookTargetPrivate, host, ip)
+		}
+	}
+	return nil
+}
+
+// GetWebhook retrieves a client's registered webhook
+func (s *Service) GetWebhook(clientID string) (*ClientWebhook, error) {
+	webhook, err := s.db.GetClientWebhook(common.NormalizeID(clientID))
+	if err != nil {
+		return nil, err
+	}
+	if webhook == nil {
+		return nil, ErrWebhookNotFound
+	}
+	return webhook, nil
+}
+
+// DeleteWebhook removes a client's webhook; the client stops receiving settlement events
+func (s *Service) DeleteWebhook(clientID string) error {
+	deleted, err := s.db.DeleteClientWebhook(common.NormalizeID(clientID))
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrWebhookNotFound
+	}
+	return nil
+}
+
+// SetWebhookHandler handles PUT requests to register or update the client's settlement webhook
+// Requires a valid JWT token
+// Request body should contain the webhook URL and shared secret
+func (h *GinHandlers) SetWebhookHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clientID, ok := webhookClientID(c)
+		if !ok {
+			return
+		}
+
+		var req WebhookRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BindError(c, err)
+			return
+		}
+
+		webhook, err := h.service.SetWebhook(clientID, req.URL, req.Secret)
+		if errors.Is(err, ErrInvalidWebhookURL) || errors.Is(err, ErrWebhookSecretTooShort) ||
+			errors.Is(err, ErrWebhookTargetPrivate) {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		response.Handle(c, webhook, err)
+	}
+}
+
+// GetWebhookHandler handles GET requests for the client's settlement webhook
+// Requires a valid JWT token
+func (h *GinHandlers) GetWebhookHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clientID, ok := webhookClientID(c)
+		if !ok {
+			return
+		}
+
+		webhook, err := h.service.GetWebhook(clientID)
+		if errors.Is(err, ErrWebhookNotFound) {
+			response.NotFound(c, err.Error())
+			return
+		}
+		response.Handle(c, webhook, err)
+	}
+}
+
+// DeleteWebhookHandler handles DELETE requests to remove the client's settlement webhook
+// Requires a valid JWT token
+func (h *GinHandlers) DeleteWebhookHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clientID, ok := webhookClientID(c)
+		if !ok {
+			return
+		}
+
+		err := h.service.DeleteWebhook(clientID)
+		if errors.Is(err, ErrWebhookNotFound) {
+			response.NotFound(c, err.Error())
+			return
+		}
+		response.Handle(c, gin.H{"client_id": clientID, "deleted": true}, err)
+	}
+}
+
+// webhookClientID reads the calling client from the JWT claims, responding 401 if it is missing
+func webhookClientID(c *gin.Context) (string, bool) {
+	claims, exists := c.Get("claims")
+	if !exists {
+		response.Unauthorized(c, "Missing authentication claims")
+		return "", false
+	}
+
+	clientID := auth.GetClientID(claims)
+	if clientID == "" {
+		response.Unauthorized(c, "Invalid client ID in token")
+		return "", false
+	}
+	return clientID, true
+}
@@ -0,0 +1,295 @@
+package settlement
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/klear-api/pkg/common"
+)
+
+// testWebhookSecret signs every delivery in the tests
+const testWebhookSecret = "test-webhook-secret"
+
+// publicWebhookURL is an https URL on a public address literal, so registering it needs no DNS lookup
+const publicWebhookURL = "https://93.184.216.34/hook"
+
+// fastWebhookRetry retries failed deliveries without waiting out the production backoff
+var fastWebhookRetry = WebhookRetryPolicy{MaxAttempts: 3, BaseBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+
+// webhookDelivery is a request received by a webhookEndpoint
+type webhookDelivery struct {
+	event     SettlementEvent
+	timestamp string
+	verified  bool
+}
+
+// webhookEndpoint is a client's webhook receiver. The first failures requests are answered with 500
+type webhookEndpoint struct {
+	server     *httptest.Server
+	attempts   atomic.Int32
+	failures   atomic.Int32
+	deliveries chan webhookDelivery
+}
+
+// newWebhookEndpoint starts a webhook receiver that fails its first failures requests
+func newWebhookEndpoint(t *testing.T, failures int32) *webhookEndpoint {
+	t.Helper()
+	endpoint := &webhookEndpoint{deliveries: make(chan webhookDelivery, 10)}
+	endpoint.failures.Store(failures)
+	endpoint.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if endpoint.attempts.Add(1) <= endpoint.failures.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		var delivery webhookDelivery
+		json.Unmarshal(body, &delivery.event)
+		delivery.timestamp = r.Header.Get(WebhookTimestampHeader)
+		delivery.verified = VerifyWebhookSignature(testWebhookSecret, r.Header.Get(WebhookTimestampHeader), body, r.Header.Get(WebhookSignatureHeader))
+		endpoint.deliveries <- delivery
+	}))
+	t.Cleanup(endpoint.server.Close)
+	return endpoint
+}
+
+// register registers the endpoint as the client's webhook, failing the test on error
+func (e *webhookEndpoint) register(t *testing.T, service *Service, clientID string) {
+	t.Helper()
+	// The endpoint listens on loopback over plain http, which only a development setup accepts
+	service.config.AllowPrivateWebhooks = true
+	if _, err := service.SetWebhook(clientID, e.server.URL, testWebhookSecret); err != nil {
+		t.Fatalf("SetWebhook: %v", err)
+	}
+}
+
+// heal makes the endpoint accept every request from now on
+func (e *webhookEndpoint) heal() {
+	e.failures.Store(0)
+}
+
+// next waits for the endpoint's next successful delivery
+func (e *webhookEndpoint) next(t *testing.T) webhookDelivery {
+	t.Helper()
+	select {
+	case delivery := <-e.deliveries:
+		return delivery
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for webhook delivery")
+		return webhookDelivery{}
+	}
+}
+
+// startSettling settles a new trade of the client and processes it to SETTLING, notifying its webhook
+func startSettling(t *testing.T, service *Service, clientID string, retry WebhookRetryPolicy) *SettlementResponse {
+	t.Helper()
+	trade := seedClearedTrade(t, service, clientID, "BUY", 100, 150, testNow.Add(-time.Minute))
+	settlement := settleTrade(t, service, trade.ExecutionID)
+
+	processor := NewProcessor(service.db, time.Hour)
+	processor.SetClock(common.FixedClock{Time: testNow.AddDate(0, 0, 2)})
+	processor.SetWebhookRetry(retry)
+	if err := processor.processPendingSettlements(context.Background()); err != nil {
+		t.Fatalf("processPendingSettlements: %v", err)
+	}
+	return settlement
+}
+
+func TestSettlementWebhookDeliveredOnlyToOwningClient(t *testing.T) {
+	service, _ := newTestService(t)
+	owner := newWebhookEndpoint(t, 0)
+	owner.register(t, service, "client-1")
+	other := newWebhookEndpoint(t, 0)
+	other.register(t, service, "client-2")
+
+	settlement := startSettling(t, service, "client-1", fastWebhookRetry)
+
+	delivery := owner.next(t)
+	if !delivery.verified {
+		t.Error("delivery signature did not verify with the client's secret")
+	}
+	event := delivery.event
+	if event.Event != EventSettlementStatusChanged || event.SettlementID != settlement.SettlementID ||
+		event.ClientID != "client-1" || event.PreviousStatus != StatusPending || event.Status != StatusSettling {
+		t.Errorf("event = %+v, want settlement %s moving from PENDING to SETTLING", event, settlement.SettlementID)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if attempts := other.attempts.Load(); attempts != 0 {
+		t.Errorf("another client's webhook received %d requests, want none", attempts)
+	}
+}
+
+func TestSettlementWebhookRetriesFailedDelivery(t *testing.T) {
+	service, _ := newTestService(t)
+	endpoint := newWebhookEndpoint(t, 1)
+	endpoint.register(t, service, "client-1")
+
+	startSettling(t, service, "client-1", fastWebhookRetry)
+
+	if delivery := endpoint.next(t); delivery.event.Status != StatusSettling {
+		t.Errorf("delivered status = %s, want %s", delivery.event.Status, StatusSettling)
+	}
+	if attempts := endpoint.attempts.Load(); attempts != 2 {
+		t.Errorf("webhook attempts = %d, want a retry after the failure", attempts)
+	}
+}
+
+func TestWebhookHandlersManageTheCallersWebhook(t *testing.T) {
+	service, _ := newTestService(t)
+	handlers := NewGinHandlers(service)
+	router := gin.New()
+	router.PUT("/webhook", authenticate("client-1"), handlers.SetWebhookHandler())
+	router.GET("/webhook", authenticate("client-1"), handlers.GetWebhookHandler())
+	router.DELETE("/webhook", authenticate("client-1"), handlers.DeleteWebhookHandler())
+
+	invalid := []WebhookRequest{
+		{URL: "ftp://example.com/hook", Secret: testWebhookSecret},
+		{URL: "/relative", Secret: testWebhookSecret},
+		{URL: "https://example.com/hook", Secret: "short"},
+	}
+	for _, req := range invalid {
+		if recorder := performRequest(router, http.MethodPut, "/webhook", req); recorder.Code != http.StatusBadRequest {
+			t.Errorf("PUT %+v: status = %d, want 400", req, recorder.Code)
+		}
+	}
+
+	recorder := performRequest(router, http.MethodPut, "/webhook", WebhookRequest{URL: publicWebhookURL, Secret: testWebhookSecret})
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("PUT status = %d, want 200: %s", recorder.Code, recorder.Body.String())
+	}
+	var webhook ClientWebhook
+	decodeData(t, performRequest(router, http.MethodGet, "/webhook", nil), &webhook)
+	if webhook.ClientID != "client-1" || webhook.URL != publicWebhookURL || webhook.Secret != "" {
+		t.Errorf("webhook = %+v, want client-1's URL without its secret", webhook)
+	}
+
+	if recorder := performRequest(router, http.MethodDelete, "/webhook", nil); recorder.Code != http.StatusOK {
+		t.Errorf("DELETE status = %d, want 200", recorder.Code)
+	}
+	if recorder := performRequest(router, http.MethodGet, "/webhook", nil); recorder.Code != http.StatusNotFound {
+		t.Errorf("GET after delete status = %d, want 404", recorder.Code)
+	}
+}
+
+func TestSetWebhookRejectsUnsafeURLs(t *testing.T) {
+	service, _ := newTestService(t)
+
+	invalid := []string{
+		"http://93.184.216.34/hook",
+		"ftp://93.184.216.34/hook",
+		"https:///hook",
+	}
+	for _, rawURL := range invalid {
+		if _, err := service.SetWebhook("client-1", rawURL, testWebhookSecret); !errors.Is(err, ErrInvalidWebhookURL) {
+			t.Errorf("SetWebhook(%s) error = %v, want %v", rawURL, err, ErrInvalidWebhookURL)
+		}
+	}
+
+	private := []string{
+		"https://127.0.0.1/hook",
+		"https://localhost:8443/hook",
+		"https://[::1]/hook",
+		"https://0.0.0.0/hook",
+		"https://10.0.0.5/hook",
+		"https://172.16.4.2/hook",
+		"https://192.168.1.1/hook",
+		"https://[fd00::1]/hook",
+		"https://169.254.169.254/latest/meta-data",
+		"https://[fe80::1]/hook",
+	}
+	for _, rawURL := range private {
+		if _, err := service.SetWebhook("client-1", rawURL, testWebhookSecret); !errors.Is(err, ErrWebhookTargetPrivate) {
+			t.Errorf("SetWebhook(%s) error = %v, want %v", rawURL, err, ErrWebhookTargetPrivate)
+		}
+	}
+	if _, err := service.GetWebhook("client-1"); !errors.Is(err, ErrWebhookNotFound) {
+		t.Errorf("GetWebhook error = %v, want no webhook registered for a rejected URL", err)
+	}
+
+	handlers := NewGinHandlers(service)
+	router := gin.New()
+	router.PUT("/webhook", authenticate("client-1"), handlers.SetWebhookHandler())
+	recorder := performRequest(router, http.MethodPut, "/webhook", WebhookRequest{URL: "https://169.254.169.254/latest", Secret: testWebhookSecret})
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("PUT metadata address status = %d, want 400", recorder.Code)
+	}
+
+	if _, err := service.SetWebhook("client-1", publicWebhookURL, testWebhookSecret); err != nil {
+		t.Errorf("SetWebhook(%s): %v", publicWebhookURL, err)
+	}
+}
+
+func TestDeadLetteredWebhookRedrivenAfterEndpointRecovers(t *testing.T) {
+	service, _ := newTestService(t)
+	endpoint := newWebhookEndpoint(t, math.MaxInt32)
+	endpoint.register(t, service, "client-1")
+
+	settlement := startSettling(t, service, "client-1", fastWebhookRetry)
+
+	// Delivery runs in the background, so wait for the last retry to give up
+	var deadLetter WebhookDeadLetter
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		deadLetters, err := service.ListDeadLetters("")
+		if err != nil {
+			t.Fatalf("ListDeadLetters: %v", err)
+		}
+		if len(deadLetters) == 1 {
+			deadLetter = deadLetters[0]
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("dead letters = %d, want the undelivered event", len(deadLetters))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if deadLetter.SettlementID != settlement.SettlementID || deadLetter.Status != DeadLetterStatusDead {
+		t.Errorf("dead letter = %s %s, want %s DEAD", deadLetter.SettlementID, deadLetter.Status, settlement.SettlementID)
+	}
+	if deadLetter.Attempts != fastWebhookRetry.MaxAttempts || deadLetter.LastError == "" {
+		t.Errorf("dead letter attempts = %d, last error %q; want %d attempts and the error", deadLetter.Attempts, deadLetter.LastError, fastWebhookRetry.MaxAttempts)
+	}
+	// The processor that notified the webhook runs two days after testNow
+	if want := testNow.AddDate(0, 0, 2); !deadLetter.CreatedAt.Equal(want) {
+		t.Errorf("dead letter created at %v, want the processor clock's %v", deadLetter.CreatedAt, want)
+	}
+
+	handlers := NewGinHandlers(service)
+	router := gin.New()
+	router.POST("/webhooks/dead-letters/:dead_letter_id/redrive", handlers.RedriveDeadLetterHandler())
+	path := "/webhooks/dead-letters/" + deadLetter.DeadLetterID + "/redrive"
+
+	if recorder := performRequest(router, http.MethodPost, path, nil); recorder.Code != http.StatusBadGateway {
+		t.Errorf("re-drive to the failing endpoint status = %d, want 502", recorder.Code)
+	}
+
+	endpoint.heal()
+	recorder := performRequest(router, http.MethodPost, path, nil)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("re-drive status = %d, want 200: %s", recorder.Code, recorder.Body.String())
+	}
+	var redriven WebhookDeadLetter
+	decodeData(t, recorder, &redriven)
+	if redriven.Status != DeadLetterStatusRedriven || redriven.RedrivenAt == nil || !redriven.RedrivenAt.Equal(testNow) {
+		t.Errorf("re-driven dead letter = %s at %v, want REDRIVEN at %v", redriven.Status, redriven.RedrivenAt, testNow)
+	}
+	if delivery := endpoint.next(t); delivery.event.SettlementID != settlement.SettlementID || !delivery.verified {
+		t.Errorf("re-driven delivery = %s verified %v, want a signed event for %s", delivery.event.SettlementID, delivery.verified, settlement.SettlementID)
+	}
+
+	if recorder := performRequest(router, http.MethodPost, path, nil); recorder.Code != http.StatusConflict {
+		t.Errorf("second re-drive status = %d, want 409", recorder.Code)
+	}
+	if remaining, err := service.ListDeadLetters(""); err != nil || len(remaining) != 0 {
+		t.Errorf("dead letters after re-drive = %d (%v), want none", len(remaining), err)
+	}
+}
@@ -0,0 +1,158 @@
+package trading
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ksred/klear-api/internal/auth"
+	"github.com/ksred/klear-api/internal/types"
+	"github.com/ksred/klear-api/pkg/response"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrExecutionNotFound  = errors.New("execution not found")
+	ErrAlreadyAllocated   = errors.New("execution has already been allocated")
+	ErrInvalidAllocation  = errors.New("each allocation requires a sub-account and a positive quantity")
+	ErrAllocationMismatch = errors.New("allocated quantity must equal the executed quantity")
+)
+
+// allocationTolerance absorbs float rounding when summing allocated quantities
+const allocationTolerance = 1e-6
+
+// AllocationRequest represents a request to split an execution across sub-accounts
+type AllocationRequest struct {
+	Allocations []AllocationItem `json:"allocations"`
+}
+
+// AllocationItem is a single sub-account allocation within a request
+type AllocationItem struct {
+	SubAccount string  `json:"sub_account"`
+	Quantity   float64 `json:"quantity"`
+}
+
+// AllocateExecution splits an executed block across the client's sub-accounts
+// The allocated quantities must sum to the execution's total quantity
+// Parameters:
+//   - executionID: ID of the execution to allocate
+//   - clientID: ID of the client that owns the execution's order
+//   - items: Sub-account allocations to record
+func (s *Service) AllocateExecution(executionID, clientID string, items []AllocationItem) (*types.Execution, error) {
+	logger := log.With().
+		Str("execution_id", executionID).
+		Str("client_id", clientID).
+		Str("service", "trading").
+		Logger()
+
+	execution, err := s.db.GetExecution(executionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrExecutionNotFound
+		}
+		return nil, err
+	}
+
+	// Only the owning client may allocate the execution
+	order, err := s.db.GetOrderByOrderIDAndClientID(execution.OrderID, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if order == nil {
+		return nil, ErrExecutionNotFound
+	}
+
+	count, err := s.db.CountAllocations(executionID)
+	if err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrAlreadyAllocated
+	}
+
+	if len(items) == 0 {
+		return nil, ErrInvalidAllocation
+	}
+
+	allocations := make([]types.Allocation, 0, len(items))
+	totalAllocated := 0.0
+	for _, item := range items {
+		subAccount := strings.TrimSpace(item.SubAccount)
+		if subAccount == "" || item.Quantity <= 0 {
+			return nil, ErrInvalidAllocation
+		}
+		totalAllocated += item.Quantity
+		allocations = append(allocations, types.Allocation{
+			AllocationID: "ALC_" + uuid.New().String(),
+			ExecutionID:  executionID,
+			SubAccount:   subAccount,
+			Quantity:     item.Quantity,
+			CreatedAt:    s.clock.Now(),
+		})
+	}
+
+	if math.Abs(totalAllocated-execution.TotalQuantity) > allocationTolerance {
+		logger.Warn().
+			Float64("allocated_quantity", totalAllocated).
+			Float64("executed_quantity", execution.TotalQuantity).
+			Msg("allocation quantity mismatch")
+		return nil, fmt.Errorf("%w: allocated %f, executed %f",
+			ErrAllocationMismatch, totalAllocated, execution.TotalQuantity)
+	}
+
+	if err := s.db.CreateAllocations(allocations); err != nil {
+		logger.Error().Err(err).Msg("failed to store allocations")
+		return nil, err
+	}
+
+	logger.Info().
+		Int("allocations", len(allocations)).
+		Float64("allocated_quantity", totalAllocated).
+		Msg("execution allocated to sub-accounts")
+
+	execution.Allocations = allocations
+	return execution, nil
+}
+
+// AllocateExecutionHandler handles POST requests to allocate an execution to sub-accounts
+// Requires a valid JWT token
+// URL parameter: execution_id
+func (h *GinHandlers) AllocateExecutionHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, exists := c.Get("claims")
+		if !exists {
+			response.Unauthorized(c, "Missing authentication claims")
+			return
+		}
+
+		clientID := auth.GetClientID(claims)
+		if clientID == "" {
+			response.Unauthorized(c, "Invalid client ID in token")
+			return
+		}
+
+		var req AllocationRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BindError(c, err)
+			return
+		}
+
+		execution, err := h.service.AllocateExecution(c.Param("execution_id"), clientID, req.Allocations)
+		switch {
+		case errors.Is(err, ErrExecutionNotFound):
+			response.NotFound(c, "Execution not found")
+		case errors.Is(err, ErrAlreadyAllocated):
+			response.Conflict(c, err.Error())
+		case errors.Is(err, ErrInvalidAllocation), errors.Is(err, ErrAllocationMismatch):
+			response.BadRequest(c, err.Error())
+		case err != nil:
+			response.InternalError(c, err.Error())
+		default:
+			response.Success(c, execution)
+		}
+	}
+}
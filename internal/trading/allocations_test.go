@@ -0,0 +1,77 @@
+package trading
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/klear-api/internal/types"
+)
+
+func newAllocationRouter(service *Service) *gin.Engine {
+	router := gin.New()
+	router.POST("/executions/:execution_id/allocations", authenticate(testClientID), NewGinHandlers(service).AllocateExecutionHandler())
+	return router
+}
+
+func TestAllocateExecutionSplitsBlock(t *testing.T) {
+	service := newTestService(t)
+	execution := executeTestOrder(t, service, newTestOrder())
+
+	recorder := performRequest(newAllocationRouter(service), http.MethodPost,
+		"/executions/"+execution.ExecutionID+"/allocations", AllocationRequest{Allocations: []AllocationItem{
+			{SubAccount: "fund-a", Quantity: 60},
+			{SubAccount: "fund-b", Quantity: 40},
+		}}, "")
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want 201: %s", recorder.Code, recorder.Body.String())
+	}
+
+	var envelope struct {
+		Data types.Execution `json:"data"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	allocated := map[string]float64{}
+	for _, allocation := range envelope.Data.Allocations {
+		allocated[allocation.SubAccount] = allocation.Quantity
+	}
+	if len(allocated) != 2 || allocated["fund-a"] != 60 || allocated["fund-b"] != 40 {
+		t.Errorf("allocations = %v, want fund-a 60 and fund-b 40", allocated)
+	}
+
+	stored, err := service.db.GetExecution(execution.ExecutionID)
+	if err != nil {
+		t.Fatalf("GetExecution: %v", err)
+	}
+	if len(stored.Allocations) != 2 {
+		t.Errorf("stored execution has %d allocations, want 2", len(stored.Allocations))
+	}
+}
+
+func TestAllocateExecutionRejectsMismatchedSum(t *testing.T) {
+	service := newTestService(t)
+	execution := executeTestOrder(t, service, newTestOrder())
+	router := newAllocationRouter(service)
+
+	for name, items := range map[string][]AllocationItem{
+		"under": {{SubAccount: "fund-a", Quantity: 60}, {SubAccount: "fund-b", Quantity: 30}},
+		"over":  {{SubAccount: "fund-a", Quantity: 60}, {SubAccount: "fund-b", Quantity: 50}},
+	} {
+		recorder := performRequest(router, http.MethodPost,
+			"/executions/"+execution.ExecutionID+"/allocations", AllocationRequest{Allocations: items}, "")
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("%s-allocation status = %d, want 400: %s", name, recorder.Code, recorder.Body.String())
+		}
+	}
+
+	count, err := service.db.CountAllocations(execution.ExecutionID)
+	if err != nil {
+		t.Fatalf("CountAllocations: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("stored %d allocations, want none", count)
+	}
+}
@@ -0,0 +1,27 @@
+package trading
+
+import (
+	"github.com/ksred/klear-api/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// recordFailedAttempts persists the venue attempts that failed while routing an order
+// executionID links them to the resulting execution and is empty when routing failed
+// Failures to record are logged rather than returned, since the order has already been routed
+func (s *Service) recordFailedAttempts(attempts []types.VenueAttempt, executionID string) {
+	if !s.config.RecordFailedAttempts || len(attempts) == 0 {
+		return
+	}
+	for i := range attempts {
+		attempts[i].ExecutionID = executionID
+	}
+
+	if err := s.db.CreateVenueAttempts(attempts); err != nil {
+		log.Error().
+			Err(err).
+			Str("order_id", attempts[0].OrderID).
+			Int("attempts", len(attempts)).
+			Str("service", "trading").
+			Msg("failed to record failed venue attempts")
+	}
+}
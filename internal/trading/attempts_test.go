@@ -0,0 +1,86 @@
+package trading
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/ksred/klear-api/internal/exchange"
+	"github.com/ksred/klear-api/internal/types"
+)
+
+// useSimulatedVenues routes orders across the given venues with instant fills off, so venues can fail
+func useSimulatedVenues(t *testing.T, venues []*exchange.Exchange) {
+	t.Helper()
+	previous := exchange.Exchanges()
+	exchange.SetExchanges(venues)
+	exchange.SetInstantFills(false)
+	t.Cleanup(func() {
+		exchange.SetInstantFills(true)
+		exchange.SetExchanges(previous)
+	})
+}
+
+// simulatedVenue returns a venue with no latency or random failures and the given depth
+func simulatedVenue(id string, depth float64) *exchange.Exchange {
+	return &exchange.Exchange{ID: id, Name: id, LiquidityFactor: 1, MaxFillQuantity: depth, SuccessRate: 1}
+}
+
+func TestExecuteOrderRecordsRejectedVenueAttempts(t *testing.T) {
+	service := newTestService(t)
+	// Venues without depth reject every attempt, so the order fills only once routed to DEEP
+	useSimulatedVenues(t, []*exchange.Exchange{
+		simulatedVenue("EMPTY1", 0),
+		simulatedVenue("EMPTY2", 0),
+		simulatedVenue("DEEP", 1000),
+	})
+
+	// Routing is random, so retry until an order is rejected at least twice before DEEP fills it
+	for i := 0; i < 50; i++ {
+		order := newTestOrder()
+		createTestOrder(t, service, order)
+		execution, err := service.ExecuteOrder(order.OrderID, testClientID, uuid.New().String())
+		if err != nil {
+			// Every routing attempt landed on an empty venue
+			continue
+		}
+
+		var attempts []types.VenueAttempt
+		if err := service.db.db.Where("order_id = ?", order.OrderID).Order("id").Find(&attempts).Error; err != nil {
+			t.Fatalf("failed to load venue attempts: %v", err)
+		}
+		for _, attempt := range attempts {
+			if attempt.ExchangeID == "DEEP" || attempt.Reason == "" || attempt.AttemptID == "" {
+				t.Fatalf("attempt = %+v, want a rejection on an empty venue with its reason", attempt)
+			}
+			if attempt.ExecutionID != execution.ExecutionID {
+				t.Errorf("attempt linked to execution %q, want %s", attempt.ExecutionID, execution.ExecutionID)
+			}
+		}
+		if len(attempts) >= 2 {
+			return
+		}
+	}
+	t.Fatal("no order was rejected twice before filling")
+}
+
+func TestFailedAttemptsNotRecordedWhenDisabled(t *testing.T) {
+	config := DefaultConfig()
+	config.RecordFailedAttempts = false
+	service := newTestServiceWithConfig(t, config)
+	useSimulatedVenues(t, []*exchange.Exchange{simulatedVenue("EMPTY", 0), simulatedVenue("DEEP", 1000)})
+
+	for i := 0; i < 10; i++ {
+		order := newTestOrder()
+		createTestOrder(t, service, order)
+		// Orders routed only to the empty venue fail, which records attempts just the same
+		service.ExecuteOrder(order.OrderID, testClientID, uuid.New().String())
+	}
+
+	var count int64
+	if err := service.db.db.Model(&types.VenueAttempt{}).Count(&count).Error; err != nil {
+		t.Fatalf("failed to count venue attempts: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("recorded %d venue attempts, want none with recording disabled", count)
+	}
+}
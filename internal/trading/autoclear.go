@@ -0,0 +1,38 @@
+package trading
+
+import (
+	"github.com/ksred/klear-api/internal/breaks"
+	"github.com/rs/zerolog/log"
+)
+
+// Clearer clears an executed trade, identified by its execution ID
+// It is supplied by the server so the trading service need not depend on clearing
+type Clearer func(tradeID string) error
+
+// SetClearer sets the clearer new executions are handed to when Config.AutoClear is enabled
+func (s *Service) SetClearer(clearer Clearer) {
+	s.clearer = clearer
+}
+
+// enqueueClearing clears a new execution in the background when auto-clearing is enabled
+// The execution has already succeeded, so a clearing failure opens a trade break for
+// operations to follow up rather than failing the execution
+func (s *Service) enqueueClearing(tradeID string) {
+	if !s.config.AutoClear || s.clearer == nil {
+		return
+	}
+
+	go func() {
+		logger := log.With().
+			Str("trade_id", tradeID).
+			Str("service", "trading").
+			Logger()
+
+		if err := s.clearer(tradeID); err != nil {
+			logger.Error().Err(err).Msg("auto-clearing failed")
+			s.breaks.Record(tradeID, breaks.StageClearing, "auto-clearing failed: "+err.Error())
+			return
+		}
+		logger.Info().Msg("auto-cleared execution")
+	}()
+}
@@ -0,0 +1,93 @@
+package trading
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ksred/klear-api/internal/breaks"
+	"github.com/ksred/klear-api/internal/clearing"
+	"github.com/ksred/klear-api/internal/pricefeed"
+	"github.com/ksred/klear-api/pkg/common"
+)
+
+// newAutoClearingService creates a trading service with auto-clearing enabled and clearing wired
+// to a clearing service over the same database, both on a clock during market hours
+func newAutoClearingService(t *testing.T) *Service {
+	t.Helper()
+	config := DefaultConfig()
+	config.AutoClear = true
+	service := newTestServiceWithConfig(t, config)
+	if err := service.db.db.AutoMigrate(&clearing.Clearing{}, &clearing.TradeNetting{}); err != nil {
+		t.Fatalf("failed to migrate clearing tables: %v", err)
+	}
+
+	clock := common.NewFakeClock(time.Date(2026, 10, 14, 12, 0, 0, 0, time.Local))
+	service.SetClock(clock)
+	clearingService := clearing.NewService(service.db.db, pricefeed.NewMockFeed(), clearing.DefaultConfig())
+	clearingService.SetClock(clock)
+	service.SetClearer(func(tradeID string) error {
+		_, err := clearingService.ClearTrade(tradeID)
+		return err
+	})
+	return service
+}
+
+// waitFor polls until done reports true, failing the test if it has not within a few seconds
+func waitFor(t *testing.T, what string, done func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for !done() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestAutoClearClearsExecutionInTheBackground(t *testing.T) {
+	service := newAutoClearingService(t)
+
+	execution := executeTestOrder(t, service, newTestOrder())
+
+	waitFor(t, "the execution to be cleared", func() bool {
+		var count int64
+		service.db.db.Model(&clearing.Clearing{}).Where("trade_id = ?", execution.ExecutionID).Count(&count)
+		return count == 1
+	})
+}
+
+func TestAutoClearFailureOpensTradeBreak(t *testing.T) {
+	config := DefaultConfig()
+	config.AutoClear = true
+	service := newTestServiceWithConfig(t, config)
+	service.SetClearer(func(tradeID string) error {
+		return errors.New("clearing unavailable")
+	})
+
+	// The execution succeeds even though clearing it fails
+	execution := executeTestOrder(t, service, newTestOrder())
+
+	waitFor(t, "a clearing break", func() bool {
+		var open []breaks.TradeBreak
+		service.db.db.Where("trade_id = ? AND stage = ?", execution.ExecutionID, breaks.StageClearing).Find(&open)
+		return len(open) == 1
+	})
+}
+
+func TestAutoClearDisabledByDefault(t *testing.T) {
+	service := newTestService(t)
+	cleared := make(chan string, 1)
+	service.SetClearer(func(tradeID string) error {
+		cleared <- tradeID
+		return nil
+	})
+
+	executeTestOrder(t, service, newTestOrder())
+
+	select {
+	case tradeID := <-cleared:
+		t.Errorf("execution %s was cleared, want auto-clearing off by default", tradeID)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
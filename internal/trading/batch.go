@@ -0,0 +1,168 @@
+package trading
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/klear-api/internal/types"
+	"github.com/ksred/klear-api/pkg/common"
+	"github.com/ksred/klear-api/pkg/response"
+	"github.com/rs/zerolog/log"
+)
+
+// MaxBatchExecutionOrders caps the number of orders accepted in a single batch execution
+const MaxBatchExecutionOrders = 100
+
+// batchExecutionWorkers bounds how many orders of a batch are executed at once
+const batchExecutionWorkers = 8
+
+// Batch execution result statuses
+const (
+	BatchStatusExecuted = "EXECUTED"
+	BatchStatusFailed   = "FAILED"
+)
+
+var (
+	ErrNoOrderIDs      = errors.New("at least one order ID is required")
+	ErrTooManyOrderIDs = fmt.Errorf("at most %d orders may be executed at once", MaxBatchExecutionOrders)
+)
+
+// BatchExecutionRequest lists the orders to execute in a batch
+type BatchExecutionRequest struct {
+	OrderIDs []string `json:"order_ids" binding:"required"`
+}
+
+// BatchExecutionResult reports the outcome of executing one order in a batch
+type BatchExecutionResult struct {
+	OrderID   string           `json:"order_id"`
+	Status    string           `json:"status"` // EXECUTED or FAILED
+	Execution *types.Execution `json:"execution,omitempty"`
+	Error     string           `json:"error,omitempty"`
+}
+
+// BatchExecutionResponse reports a batch execution in the order the IDs were requested
+type BatchExecutionResponse struct {
+	Executed int                    `json:"executed"`
+	Failed   int                    `json:"failed"`
+	Results  []BatchExecutionResult `json:"results"`
+}
+
+// batchIdempotencyKey derives the per-order idempotency key for an order in a batch
+// Retrying a batch with the same base key replays each order's original execution
+func batchIdempotencyKey(baseKey, orderID string) string {
+	return baseKey + ":" + orderID
+}
+
+// ExecuteOrders executes several orders concurrently with a bounded worker pool
+// A failing order does not stop the rest of the batch; each order's outcome is reported
+// Duplicate order IDs are executed once
+// Parameters:
+//   - orderIDs: IDs of the orders to execute
+//   - clientID: ID of the calling client, checked against each order owner when ownership is enforced
+//   - baseIdempotencyKey: Key from which each order's idempotency key is derived
+func (s *Service) ExecuteOrders(orderIDs []string, clientID string, baseIdempotencyKey string) (*BatchExecutionResponse, error) {
+	unique := make([]string, 0, len(orderIDs))
+	seen := make(map[string]bool, len(orderIDs))
+	for _, orderID := range orderIDs {
+		orderID = common.NormalizeID(orderID)
+		if orderID == "" || seen[orderID] {
+			continue
+		}
+		seen[orderID] = true
+		unique = append(unique, orderID)
+	}
+	if len(unique) == 0 {
+		return nil, ErrNoOrderIDs
+	}
+	if len(unique) > MaxBatchExecutionOrders {
+		return nil, ErrTooManyOrderIDs
+	}
+
+	logger := log.With().
+		Int("order_count", len(unique)).
+		Str("service", "trading").
+		Logger()
+
+	results := make([]BatchExecutionResult, len(unique))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+
+	workers := batchExecutionWorkers
+	if len(unique) < workers {
+		workers = len(unique)
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				orderID := unique[i]
+				result := BatchExecutionResult{OrderID: orderID}
+				execution, err := s.ExecuteOrder(orderID, clientID, batchIdempotencyKey(baseIdempotencyKey, orderID))
+				if err != nil {
+					result.Status = BatchStatusFailed
+					result.Error = err.Error()
+				} else {
+					result.Status = BatchStatusExecuted
+					result.Execution = execution
+				}
+				results[i] = result
+			}
+		}()
+	}
+	for i := range unique {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	resp := &BatchExecutionResponse{Results: results}
+	for _, result := range results {
+		if result.Status == BatchStatusExecuted {
+			resp.Executed++
+		} else {
+			resp.Failed++
+		}
+	}
+
+	logger.Info().
+		Int("executed", resp.Executed).
+		Int("failed", resp.Failed).
+		Msg("completed batch execution")
+
+	return resp, nil
+}
+
+// ExecuteOrdersHandler handles POST requests to execute a batch of orders
+// Requires internal authentication and idempotency key
+// Request body should contain the order IDs to execute
+func (h *GinHandlers) ExecuteOrdersHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		idempotencyKey := c.GetHeader("Idempotency-Key")
+		if idempotencyKey == "" {
+			response.BadRequest(c, "Idempotency-Key header is required")
+			return
+		}
+
+		var req BatchExecutionRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BindError(c, err)
+			return
+		}
+
+		result, err := h.service.ExecuteOrders(req.OrderIDs, c.GetString("clientID"), idempotencyKey)
+		if errors.Is(err, ErrNoOrderIDs) || errors.Is(err, ErrTooManyOrderIDs) {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		if err != nil {
+			response.InternalError(c, err.Error())
+			return
+		}
+
+		response.SuccessWithStatus(c, result, http.StatusOK)
+	}
+}
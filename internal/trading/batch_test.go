@@ -0,0 +1,77 @@
+package trading
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/klear-api/internal/types"
+)
+
+// executeBatch posts the order IDs to the batch execution handler under the idempotency key
+func executeBatch(t *testing.T, router http.Handler, idempotencyKey string, orderIDs ...string) BatchExecutionResponse {
+	t.Helper()
+	recorder := performRequest(router, http.MethodPost, "/execution/batch", BatchExecutionRequest{OrderIDs: orderIDs}, idempotencyKey)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", recorder.Code, recorder.Body.String())
+	}
+	var envelope struct {
+		Data BatchExecutionResponse `json:"data"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return envelope.Data
+}
+
+func TestExecuteOrdersHandlerReportsEachOrder(t *testing.T) {
+	service := newTestService(t)
+	router := gin.New()
+	router.POST("/execution/batch", authenticate(testClientID), NewGinHandlers(service).ExecuteOrdersHandler())
+
+	cancelled := createTestOrder(t, service, newTestOrder())
+	if _, err := service.CancelAllOrders(testClientID, ""); err != nil {
+		t.Fatalf("CancelAllOrders: %v", err)
+	}
+	valid := []*types.Order{createTestOrder(t, service, newTestOrder()), createTestOrder(t, service, newTestOrder())}
+
+	result := executeBatch(t, router, "batch-1", valid[0].OrderID, "missing-order", valid[1].OrderID, cancelled.OrderID, valid[0].OrderID)
+	if result.Executed != 2 || result.Failed != 2 || len(result.Results) != 4 {
+		t.Fatalf("result = %+v, want the two valid orders executed and two failures, duplicates executed once", result)
+	}
+	// Results follow the requested order
+	want := []struct {
+		orderID string
+		status  string
+	}{
+		{valid[0].OrderID, BatchStatusExecuted},
+		{"missing-order", BatchStatusFailed},
+		{valid[1].OrderID, BatchStatusExecuted},
+		{cancelled.OrderID, BatchStatusFailed},
+	}
+	for i, w := range want {
+		got := result.Results[i]
+		if got.OrderID != w.orderID || got.Status != w.status {
+			t.Errorf("result %d = %s %s, want %s %s", i, got.OrderID, got.Status, w.orderID, w.status)
+		}
+		if w.status == BatchStatusFailed && got.Error == "" {
+			t.Errorf("result %d failed without an error", i)
+		}
+	}
+
+	// Retrying the batch with the same key replays the executions
+	retried := executeBatch(t, router, "batch-1", valid[0].OrderID, valid[1].OrderID)
+	for i, got := range retried.Results {
+		if got.Execution == nil || got.Execution.ExecutionID != result.Results[2*i].Execution.ExecutionID {
+			t.Errorf("retried result %d = %+v, want the original execution replayed", i, got)
+		}
+	}
+
+	if recorder := performRequest(router, http.MethodPost, "/execution/batch", BatchExecutionRequest{OrderIDs: []string{" "}}, "batch-2"); recorder.Code != http.StatusBadRequest {
+		t.Errorf("empty batch status = %d, want 400", recorder.Code)
+	}
+	if recorder := performRequest(router, http.MethodPost, "/execution/batch", BatchExecutionRequest{OrderIDs: []string{valid[0].OrderID}}, ""); recorder.Code != http.StatusBadRequest {
+		t.Errorf("batch without idempotency key status = %d, want 400", recorder.Code)
+	}
+}
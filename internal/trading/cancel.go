@@ -0,0 +1,77 @@
+package trading
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/klear-api/internal/auth"
+	"github.com/ksred/klear-api/pkg/common"
+	"github.com/ksred/klear-api/pkg/response"
+	"github.com/rs/zerolog/log"
+)
+
+// CancelAllResult reports the orders cancelled by a bulk cancel
+type CancelAllResult struct {
+	CancelledCount    int      `json:"cancelled_count"`
+	CancelledOrderIDs []string `json:"cancelled_order_ids"`
+}
+
+// CancelAllOrders cancels every PENDING order for a client
+// Parameters:
+//   - clientID: ID of the client whose orders are cancelled
+//   - symbol: Optional symbol to restrict the cancel to; empty cancels across all symbols
+func (s *Service) CancelAllOrders(clientID, symbol string) (*CancelAllResult, error) {
+	clientID = common.NormalizeID(clientID)
+	if symbol != "" {
+		symbol = common.NormalizeSymbol(symbol)
+	}
+
+	logger := log.With().
+		Str("client_id", clientID).
+		Str("symbol", symbol).
+		Str("service", "trading").
+		Logger()
+
+	orderIDs, err := s.db.CancelPendingOrders(clientID, symbol, s.clock.Now())
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to cancel pending orders")
+		return nil, err
+	}
+	if orderIDs == nil {
+		orderIDs = []string{}
+	}
+
+	logger.Info().Int("cancelled_count", len(orderIDs)).Msg("cancelled pending orders")
+
+	return &CancelAllResult{
+		CancelledCount:    len(orderIDs),
+		CancelledOrderIDs: orderIDs,
+	}, nil
+}
+
+// CancelAllOrdersHandler handles POST requests to cancel all of the client's open orders
+// Requires a valid JWT token
+// Query parameter: symbol (optional)
+func (h *GinHandlers) CancelAllOrdersHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, exists := c.Get("claims")
+		if !exists {
+			response.Unauthorized(c, "Missing authentication claims")
+			return
+		}
+
+		clientID := auth.GetClientID(claims)
+		if clientID == "" {
+			response.Unauthorized(c, "Invalid client ID in token")
+			return
+		}
+
+		result, err := h.service.CancelAllOrders(clientID, c.Query("symbol"))
+		if err != nil {
+			response.InternalError(c, err.Error())
+			return
+		}
+
+		response.SuccessWithStatus(c, result, http.StatusOK)
+	}
+}
@@ -0,0 +1,97 @@
+package trading
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/klear-api/internal/types"
+)
+
+// cancelAll cancels the test client's open orders through the handler, optionally for one symbol
+func cancelAll(t *testing.T, service *Service, symbol string) CancelAllResult {
+	t.Helper()
+	router := gin.New()
+	router.POST("/orders/cancel-all", authenticate(testClientID), NewGinHandlers(service).CancelAllOrdersHandler())
+
+	path := "/orders/cancel-all"
+	if symbol != "" {
+		path += "?symbol=" + symbol
+	}
+	recorder := performRequest(router, http.MethodPost, path, nil, "")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", recorder.Code, recorder.Body.String())
+	}
+	var envelope struct {
+		Data CancelAllResult `json:"data"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return envelope.Data
+}
+
+// assertCancelled fails the test unless the result lists exactly the given orders
+func assertCancelled(t *testing.T, result CancelAllResult, orders ...*types.Order) {
+	t.Helper()
+	want := make([]string, len(orders))
+	for i, order := range orders {
+		want[i] = order.OrderID
+	}
+	got := append([]string(nil), result.CancelledOrderIDs...)
+	sort.Strings(want)
+	sort.Strings(got)
+	if result.CancelledCount != len(want) || len(got) != len(want) {
+		t.Fatalf("cancelled %d orders %v, want %v", result.CancelledCount, got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("cancelled orders %v, want %v", got, want)
+		}
+	}
+}
+
+// assertStatus fails the test unless the stored order has the status
+func assertStatus(t *testing.T, service *Service, order *types.Order, status string) {
+	t.Helper()
+	stored, err := service.GetOrder(order.OrderID)
+	if err != nil {
+		t.Fatalf("GetOrder: %v", err)
+	}
+	if stored.Status != status {
+		t.Errorf("order %s status = %s, want %s", order.OrderID, stored.Status, status)
+	}
+}
+
+func TestCancelAllOrdersCancelsOnlyPendingOrders(t *testing.T) {
+	service := newTestService(t)
+	registerClient(t, service, "client-2", true)
+
+	pendingAAPL := []*types.Order{
+		createTestOrder(t, service, newTestOrder()),
+		createTestOrder(t, service, newTestOrder()),
+	}
+	msft := newTestOrder()
+	msft.Symbol = "MSFT"
+	pendingMSFT := createTestOrder(t, service, msft)
+	filled := newTestOrder()
+	executeTestOrder(t, service, filled)
+	otherClient := newTestOrder()
+	otherClient.ClientID = "client-2"
+	createTestOrder(t, service, otherClient)
+
+	assertCancelled(t, cancelAll(t, service, "aapl"), pendingAAPL...)
+	assertStatus(t, service, pendingMSFT, "PENDING")
+
+	assertCancelled(t, cancelAll(t, service, ""), pendingMSFT)
+	for _, order := range append(pendingAAPL, pendingMSFT) {
+		assertStatus(t, service, order, "CANCELLED")
+	}
+	assertStatus(t, service, filled, "FILLED")
+	assertStatus(t, service, otherClient, "PENDING")
+
+	// Nothing is left to cancel
+	assertCancelled(t, cancelAll(t, service, ""))
+}
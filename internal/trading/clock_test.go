@@ -0,0 +1,42 @@
+package trading
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ksred/klear-api/pkg/common"
+)
+
+func TestReplaceAndCancelAllStampedByClock(t *testing.T) {
+	service := newTestService(t)
+	createdAt := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	clock := common.NewFakeClock(createdAt)
+	service.SetClock(clock)
+	replaced := createTestOrder(t, service, newTestOrder())
+	cancelled := createTestOrder(t, service, newTestOrder())
+
+	clock.Advance(time.Hour)
+	price := 151.0
+	replacement, err := service.ReplaceOrder(replaced.OrderID, testClientID, ReplaceOrderRequest{Price: &price})
+	if err != nil {
+		t.Fatalf("ReplaceOrder: %v", err)
+	}
+	replacedAt := createdAt.Add(time.Hour)
+	if stored, err := service.GetOrder(replaced.OrderID); err != nil {
+		t.Fatalf("GetOrder: %v", err)
+	} else if !stored.UpdatedAt.Equal(replacedAt) {
+		t.Errorf("replaced order updated at %s, want the clock's %s", stored.UpdatedAt, replacedAt)
+	}
+	if !replacement.CreatedAt.Equal(replacedAt) {
+		t.Errorf("replacement created at %s, want the clock's %s", replacement.CreatedAt, replacedAt)
+	}
+
+	clock.Advance(time.Hour)
+	cancelAll(t, service, "")
+	cancelledAt := createdAt.Add(2 * time.Hour)
+	if stored, err := service.GetOrder(cancelled.OrderID); err != nil {
+		t.Fatalf("GetOrder: %v", err)
+	} else if stored.Status != "CANCELLED" || !stored.UpdatedAt.Equal(cancelledAt) {
+		t.Errorf("cancelled order = %s updated at %s, want CANCELLED at the clock's %s", stored.Status, stored.UpdatedAt, cancelledAt)
+	}
+}
@@ -0,0 +1,94 @@
+package trading
+
+import (
+	"time"
+
+	"github.com/ksred/klear-api/pkg/common"
+	"github.com/ksred/klear-api/pkg/money"
+)
+
+// Config holds the tunable limits applied by the trading service
+type Config struct {
+	MaxOrderQuantity float64 // Maximum quantity accepted on a single order
+	MaxOrderPrice    float64 // Maximum price accepted on a single order
+	MaxOrderNotional float64 // Maximum price × quantity accepted on a single order (0 disables the cap)
+	MaxOpenOrders    int     // Maximum open orders per client, unless the client sets its own (0 disables the cap)
+	DefaultCurrency  string  // Currency applied to orders that do not specify one
+	// MaxMarketSlippage is the largest adverse move from the order price, as a fraction,
+	// accepted on a market order fill. Worse fills are rejected and routed to another venue
+	MaxMarketSlippage float64
+	MaxGTDHorizon     time.Duration // Furthest in the future a GTD order may expire
+	// PriceBand is the furthest a limit order price may sit from the symbol's reference price,
+	// as a fraction (0.1 = 10%). Market orders are exempt. 0 disables the check
+	PriceBand float64
+	// EnforceExecutionOwnership rejects execution of orders that belong to a
+	// different client than the caller. Disabled by default since internal
+	// systems may execute on behalf of clients
+	EnforceExecutionOwnership bool
+	// DefaultLotSize is the lot size of symbols without a specific one; executed quantities
+	// are rounded down to whole lots. 0 allows fills of any quantity
+	DefaultLotSize float64
+	// LotSizes maps symbols to their instrument lot size
+	LotSizes map[string]float64
+	// DefaultTickSize is the tick size of symbols without a specific one; limit prices must be
+	// a whole number of ticks. 0 allows any price
+	DefaultTickSize float64
+	// TickSizes maps symbols to their instrument tick size
+	TickSizes map[string]float64
+	// RecordFailedAttempts persists each venue attempt that fails while routing an order,
+	// so the venues tried and rejected can be analysed later
+	RecordFailedAttempts bool
+	// IdempotencyBackend selects where idempotency keys are stored: IdempotencyBackendDB
+	// (the default) or IdempotencyBackendRedis, which spares the database the write
+	// contention of horizontally scaled deployments
+	IdempotencyBackend string
+	// RedisAddr, RedisPassword and RedisDB locate the Redis server of the redis backend
+	RedisAddr     string
+	RedisPassword string
+	RedisDB       int
+	// AutoClear clears each new execution in the background as soon as it is recorded,
+	// instead of waiting for a separate clearing call. Clearing failures open a trade break
+	AutoClear bool
+	// PageSize bounds the pages of the order and fill listings
+	PageSize common.PageSize
+	// InternalCrossing matches an executing order against other clients' working limit orders on the
+	// opposite side before routing what remains to the venues. Internal fills are on the INTERNAL venue
+	InternalCrossing bool
+	// InternalCrossFeeRate is the fee charged to both sides of an internal cross, as a fraction of the fill value
+	InternalCrossFeeRate float64
+}
+
+// LotSizeFor returns the lot size executions of the given symbol are filled in
+func (c Config) LotSizeFor(symbol string) float64 {
+	if lotSize, ok := c.LotSizes[symbol]; ok {
+		return lotSize
+	}
+	return c.DefaultLotSize
+}
+
+// TickSizeFor returns the tick size limit prices of the given symbol must be a multiple of
+func (c Config) TickSizeFor(symbol string) float64 {
+	if tickSize, ok := c.TickSizes[symbol]; ok {
+		return tickSize
+	}
+	return c.DefaultTickSize
+}
+
+// DefaultConfig returns the trading configuration used when nothing is overridden
+func DefaultConfig() Config {
+	return Config{
+		MaxOrderQuantity:  1000000,  // 1M shares
+		MaxOrderPrice:     1000000,  // $1M per share
+		MaxOrderNotional:  10000000, // $10M per order
+		DefaultCurrency:   money.DefaultCurrency,
+		MaxMarketSlippage: 0.015,               // 1.5%
+		MaxGTDHorizon:     90 * 24 * time.Hour, // 90 days
+		// Failed attempts are rare, so recording them costs little
+		RecordFailedAttempts: true,
+		IdempotencyBackend:   IdempotencyBackendDB,
+		RedisAddr:            "localhost:6379",
+		PageSize:             common.DefaultPageSize(),
+		// Below every built-in venue, so both sides of a cross pay less than on an exchange
+		InternalCrossFeeRate: 0.0001, // 0.01%
+	}
+}
@@ -0,0 +1,143 @@
+package trading
+
+import (
+	"errors"
+	"math"
+
+	"github.com/google/uuid"
+	"github.com/ksred/klear-api/internal/exchange"
+	"github.com/ksred/klear-api/internal/types"
+	"github.com/ksred/klear-api/pkg/money"
+	"github.com/rs/zerolog/log"
+)
+
+// ErrCrossedOrderChanged is returned when a resting order matched in an internal cross was filled,
+// cancelled or otherwise changed before the cross was recorded. Executing again matches afresh
+var ErrCrossedOrderChanged = errors.New("order crossed internally changed during execution")
+
+// internalCross is one match of an executing order against a resting order of another client
+type internalCross struct {
+	fill           types.ExchangeFill // The executing order's side of the cross
+	order          *types.Order       // The resting order, updated with the cross
+	execution      *types.Execution   // The resting order's side of the cross
+	previousStatus string             // Status of the resting order when it was matched
+	previousFilled float64            // Filled quantity of the resting order when it was matched
+}
+
+// executeWithCrossing crosses the order internally as far as it can, when internal crossing is
+// enabled, then routes what remains to the venues. The execution holds the internal fills first,
+// then the venue fills. Once something has crossed, a failure to route the rest leaves it open
+// on the order rather than failing the execution
+func (s *Service) executeWithCrossing(order *types.Order, lotSize float64) (*types.Execution, []types.VenueAttempt, []internalCross, error) {
+	crosses, err := s.crossInternally(order, lotSize)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if len(crosses) == 0 {
+		execution, failedAttempts, err := exchange.ExecuteOrderAcrossExchanges(order, s.config.MaxMarketSlippage, lotSize)
+		return execution, failedAttempts, nil, err
+	}
+
+	execution := &types.Execution{
+		OrderID:   order.OrderID,
+		Side:      order.Side,
+		Status:    "COMPLETED",
+		CreatedAt: s.clock.Now(),
+		UpdatedAt: s.clock.Now(),
+	}
+	crossedUnits := int64(0)
+	for _, cross := range crosses {
+		execution.Fills = append(execution.Fills, cross.fill)
+		crossedUnits += money.ToUnits(cross.fill.Quantity)
+	}
+
+	var failedAttempts []types.VenueAttempt
+	remainder := *order
+	remainder.Quantity = money.FromUnits(money.ToUnits(order.Quantity) - crossedUnits)
+	if exchange.RoundDownToLot(remainder.Quantity, lotSize) > 0 {
+		routed, failed, err := exchange.ExecuteOrderAcrossExchanges(&remainder, s.config.MaxMarketSlippage, lotSize)
+		failedAttempts = failed
+		if err != nil {
+			log.Warn().Err(err).
+				Str("order_id", order.OrderID).
+				Float64("remaining_quantity", remainder.Quantity).
+				Msg("failed to route the rest of an internally crossed order")
+		} else {
+			execution.Fills = append(execution.Fills, routed.Fills...)
+		}
+	}
+
+	totalUnits := int64(0)
+	weightedPrice := 0.0
+	for _, fill := range execution.Fills {
+		totalUnits += money.ToUnits(fill.Quantity)
+		weightedPrice += fill.Price * fill.Quantity
+	}
+	execution.TotalQuantity = money.FromUnits(totalUnits)
+	execution.AveragePrice = weightedPrice / execution.TotalQuantity
+	return execution, failedAttempts, crosses, nil
+}
+
+// crossInternally matches the order against other clients' working limit orders on the opposite side,
+// best price first, at the resting orders' prices. Both sides are charged the internal cross fee rate.
+// Post-only orders never cross, since crossing takes liquidity. Returns the matches, not yet recorded
+func (s *Service) crossInternally(order *types.Order, lotSize float64) ([]internalCross, error) {
+	if !s.config.InternalCrossing || order.PostOnly {
+		return nil, nil
+	}
+	now := s.clock.Now()
+	resting, err := s.db.GetCrossableOrders(order, now)
+	if err != nil {
+		return nil, err
+	}
+
+	var crosses []internalCross
+	remaining := exchange.RoundDownToLot(order.Quantity, lotSize)
+	for i := range resting {
+		contra := &resting[i]
+		if remaining <= 0 {
+			break
+		}
+		// Resting orders come best price first, so none further on is within the cap either
+		if !exchange.CrossesWithinSlippage(order, contra.Price, s.config.MaxMarketSlippage) {
+			break
+		}
+		if s.checkClientActive(contra.ClientID) != nil {
+			continue
+		}
+		quantity := exchange.RoundDownToLot(math.Min(remaining, remainingQuantity(contra)), lotSize)
+		if quantity <= 0 {
+			continue
+		}
+
+		cross := internalCross{
+			fill:           *exchange.InternalFill(contra.Price, quantity, s.config.InternalCrossFeeRate),
+			order:          contra,
+			previousStatus: contra.Status,
+			previousFilled: contra.FilledQuantity,
+		}
+		contraFill := exchange.InternalFill(contra.Price, quantity, s.config.InternalCrossFeeRate)
+		cross.execution = &types.Execution{
+			ExecutionID:   uuid.New().String(),
+			OrderID:       contra.OrderID,
+			TotalQuantity: quantity,
+			AveragePrice:  contra.Price,
+			Side:          contra.Side,
+			Status:        "COMPLETED",
+			Fills:         []types.ExchangeFill{*contraFill},
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		}
+		s.fillOrder(contra, quantity, lotSize)
+		crosses = append(crosses, cross)
+		remaining = money.FromUnits(money.ToUnits(remaining) - money.ToUnits(quantity))
+
+		log.Info().
+			Str("order_id", order.OrderID).
+			Str("contra_order_id", contra.OrderID).
+			Float64("quantity", quantity).
+			Float64("price", contra.Price).
+			Msg("order crossed internally")
+	}
+	return crosses, nil
+}
@@ -0,0 +1,92 @@
+package trading
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ksred/klear-api/internal/exchange"
+	"github.com/ksred/klear-api/internal/types"
+	"github.com/ksred/klear-api/pkg/common"
+)
+
+func TestInternalCrossRecordsFillsAtInternalRate(t *testing.T) {
+	config := DefaultConfig()
+	config.InternalCrossing = true
+	config.InternalCrossFeeRate = 0.0002
+	service := newTestServiceWithConfig(t, config)
+	registerClient(t, service, "client-2", true)
+
+	restingOrder := func(clientID string, quantity, price float64) *types.Order {
+		order := newTestOrder()
+		order.ClientID = clientID
+		order.Side = "SELL"
+		order.Quantity = quantity
+		order.Price = price
+		return createTestOrder(t, service, order)
+	}
+	resting := restingOrder("client-2", 60, 149)
+	// Priced above the buy limit, and the buyer's own order, neither of which may cross
+	tooExpensive := restingOrder("client-2", 100, 151)
+	own := restingOrder(testClientID, 100, 148)
+
+	order := createTestOrder(t, service, newTestOrder())
+	execution, err := service.ExecuteOrder(order.OrderID, testClientID, uuid.New().String())
+	if err != nil {
+		t.Fatalf("ExecuteOrder: %v", err)
+	}
+	if execution.TotalQuantity != 100 || len(execution.Fills) != 2 {
+		t.Fatalf("execution = %v in %d fills, want 100 in an internal and a venue fill", execution.TotalQuantity, len(execution.Fills))
+	}
+	internal, venue := execution.Fills[0], execution.Fills[1]
+	if internal.ExchangeID != exchange.InternalVenueID || internal.Quantity != 60 || internal.Price != 149 {
+		t.Errorf("first fill = %v @ %v on %s, want 60 @ 149 on INTERNAL", internal.Quantity, internal.Price, internal.ExchangeID)
+	}
+	if internal.FeeRate != 0.0002 || math.Abs(internal.FeeAmount-149*60*0.0002) > 1e-9 {
+		t.Errorf("internal fill fee = %v at %v, want %v at the internal rate", internal.FeeAmount, internal.FeeRate, 149*60*0.0002)
+	}
+	if venue.ExchangeID == exchange.InternalVenueID || venue.Quantity != 40 || venue.FeeRate == 0.0002 {
+		t.Errorf("second fill = %v on %s at %v, want the other 40 routed to a venue at its own rate", venue.Quantity, venue.ExchangeID, venue.FeeRate)
+	}
+	if want := (149*60 + 150*40) / 100.0; math.Abs(execution.AveragePrice-want) > 1e-9 {
+		t.Errorf("average price = %v, want %v", execution.AveragePrice, want)
+	}
+
+	// The resting order is filled by an execution of its own
+	stored, err := service.db.GetOrder(resting.OrderID)
+	if err != nil {
+		t.Fatalf("GetOrder: %v", err)
+	}
+	if stored.Status != "FILLED" || stored.FilledQuantity != 60 {
+		t.Errorf("resting order = %s with %v filled, want FILLED with 60", stored.Status, stored.FilledQuantity)
+	}
+	for _, untouched := range []*types.Order{tooExpensive, own} {
+		if stored, err := service.db.GetOrder(untouched.OrderID); err != nil || stored.Status != "PENDING" {
+			t.Errorf("order %s crossed, want it left PENDING", untouched.OrderID)
+		}
+	}
+
+	// Both sides of the cross are reported on the INTERNAL venue, with their fees
+	now := time.Now()
+	report, err := service.GetFillsForExchange(exchange.InternalVenueID, now.Add(-time.Hour), now.Add(time.Hour), common.Page{Limit: 10})
+	if err != nil {
+		t.Fatalf("GetFillsForExchange: %v", err)
+	}
+	if report.FillCount != 2 || report.TotalQuantity != 120 || math.Abs(report.TotalFees-2*149*60*0.0002) > 1e-9 {
+		t.Errorf("internal fills = %d totalling %v with %v fees, want both sides of the 60 cross", report.FillCount, report.TotalQuantity, report.TotalFees)
+	}
+	contraExecutionID := ""
+	for _, fill := range report.Fills {
+		if fill.ExecutionID != execution.ExecutionID {
+			contraExecutionID = fill.ExecutionID
+		}
+	}
+	contra, err := service.db.GetExecution(contraExecutionID)
+	if err != nil {
+		t.Fatalf("GetExecution: %v", err)
+	}
+	if contra.OrderID != resting.OrderID || contra.Side != "SELL" || contra.TotalQuantity != 60 {
+		t.Errorf("contra execution = %s %s %v, want the resting order selling 60", contra.OrderID, contra.Side, contra.TotalQuantity)
+	}
+}
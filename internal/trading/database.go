@@ -2,9 +2,14 @@ package trading
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
+	"github.com/ksred/klear-api/internal/database/retry"
+	"github.com/ksred/klear-api/internal/events"
 	"github.com/ksred/klear-api/internal/types"
+	"github.com/ksred/klear-api/pkg/common"
+	"github.com/ksred/klear-api/pkg/money"
 	"gorm.io/gorm"
 )
 
@@ -16,8 +21,23 @@ func NewDatabase(db *gorm.DB) *Database {
 	return &Database{db: db}
 }
 
-func (d *Database) CreateOrder(order *types.Order) error {
-	return d.db.Create(order).Error
+// CreateOrder creates an order and records its creation in the event log in a single transaction
+// CreateOrder saves a new order and its creation event
+// record, if set, runs in the same transaction, so the order is only created if it succeeds
+func (d *Database) CreateOrder(order *types.Order, record func(tx *gorm.DB) error) error {
+	return retry.Do("create_order", func() error {
+		return d.db.Transaction(func(tx *gorm.DB) error {
+			if record != nil {
+				if err := record(tx); err != nil {
+					return err
+				}
+			}
+			if err := tx.Create(order).Error; err != nil {
+				return err
+			}
+			return events.Append(tx, events.EventOrderCreated, order.OrderID, order, order.CreatedAt)
+		})
+	})
 }
 
 func (d *Database) GetOrder(orderID string) (*types.Order, error) {
@@ -42,6 +62,43 @@ func (d *Database) GetOrderByOrderIDAndClientID(orderID, clientID string) (*type
 	return &order, nil
 }
 
+// GetClient retrieves a client by its ID, returning nil if the client does not exist
+func (d *Database) GetClient(clientID string) (*types.Client, error) {
+	var client types.Client
+	if err := d.db.Where("client_id = ?", clientID).First(&client).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &client, nil
+}
+
+// countOpenOrders returns the number of the client's orders that are still working, read through tx
+// It is served by the orders (client_id, status) index
+func countOpenOrders(tx *gorm.DB, clientID string) (int64, error) {
+	var count int64
+	if err := tx.Model(&types.Order{}).
+		Where("client_id = ? AND status IN ?", clientID, openOrderStatuses).
+		Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// SaveClient creates the client if it does not exist, otherwise updates it
+func (d *Database) SaveClient(client *types.Client) error {
+	existing, err := d.GetClient(client.ClientID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		client.ID = existing.ID
+		client.CreatedAt = existing.CreatedAt
+	}
+	return d.db.Save(client).Error
+}
+
 func (d *Database) UpdateOrder(order *types.Order) error {
 	return d.db.Save(order).Error
 }
@@ -52,92 +109,306 @@ func (d *Database) CreateExecution(execution *types.Execution) error {
 
 func (d *Database) GetExecution(executionID string) (*types.Execution, error) {
 	var execution types.Execution
-	if err := d.db.Where("execution_id = ?", executionID).First(&execution).Error; err != nil {
+	if err := d.db.Preload("Allocations").
+		Where("execution_id = ?", executionID).
+		First(&execution).Error; err != nil {
 		return nil, err
 	}
 	return &execution, nil
 }
 
+// CreateAllocations stores all allocations for an execution in a single transaction
+func (d *Database) CreateAllocations(allocations []types.Allocation) error {
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		return tx.Create(&allocations).Error
+	})
+}
+
+// CountAllocations returns the number of allocations recorded against an execution
+func (d *Database) CountAllocations(executionID string) (int64, error) {
+	var count int64
+	if err := d.db.Model(&types.Allocation{}).
+		Where("execution_id = ?", executionID).
+		Count(&count).Error; err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
 func (d *Database) UpdateExecution(execution *types.Execution) error {
 	return d.db.Save(execution).Error
 }
 
-// CreateOrderWithIdempotency creates a new order and idempotency record in a transaction
-func (d *Database) CreateOrderWithIdempotency(order *types.Order, idempotencyKey string) error {
-	// Begin transaction
-	tx := d.db.Begin()
-	if err := tx.Error; err != nil {
-		return err
-	}
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
+// RecordExecution creates an execution, saves the order it filled and records the execution,
+// and the order's move from previousStatus, in the event log in a single transaction
+// record, if not nil, runs first in the same transaction, e.g. to commit the idempotency key
+func (d *Database) RecordExecution(execution *types.Execution, order *types.Order, previousStatus string, record func(tx *gorm.DB) error) error {
+	return d.db.Transaction(func(tx *gorm.DB) error {
+		if record != nil {
+			if err := record(tx); err != nil {
+				return err
+			}
 		}
-	}()
+		return recordExecution(tx, execution, order, previousStatus)
+	})
+}
 
-	if err := tx.Create(order).Error; err != nil {
-		tx.Rollback()
+// recordExecution saves the execution and its filled order, and records them in the event log
+func recordExecution(tx *gorm.DB, execution *types.Execution, order *types.Order, previousStatus string) error {
+	if err := tx.Create(execution).Error; err != nil {
 		return err
 	}
-
-	// Create idempotency record
-	record := IdempotencyRecord{
-		IdempotencyKey: idempotencyKey,
-		ResourceID:     order.OrderID,
-		ResourceType:   "order",
-		ExpiresAt:      time.Now().Add(24 * time.Hour),
+	// Save stamps updated_at with gorm's clock; keep the fill time the service set instead
+	filledAt := order.UpdatedAt
+	if err := tx.Session(&gorm.Session{NowFunc: func() time.Time { return filledAt }}).Save(order).Error; err != nil {
+		return err
 	}
-
-	if err := tx.Create(&record).Error; err != nil {
-		tx.Rollback()
+	if err := events.Append(tx, events.EventOrderExecuted, execution.ExecutionID, execution, execution.CreatedAt); err != nil {
 		return err
 	}
+	if previousStatus == order.Status {
+		return nil
+	}
+	return appendOrderStatusChanges(tx, []string{order.OrderID}, previousStatus, order.Status, order.UpdatedAt)
+}
+
+// GetCrossableOrders retrieves the working limit orders of other clients the order can be crossed
+// against: the opposite side of the same symbol and currency, priced at or through the order's limit.
+// Orders come best price first, then oldest first. Reduce-only orders are left out, since crossing
+// them is not checked against the position
+func (d *Database) GetCrossableOrders(order *types.Order, now time.Time) ([]types.Order, error) {
+	contraSide, priceCondition, priceOrder := "SELL", "price_units <= ?", "price_units ASC"
+	if order.Side == "SELL" {
+		contraSide, priceCondition, priceOrder = "BUY", "price_units >= ?", "price_units DESC"
+	}
+	query := d.db.Where("symbol = ? AND currency = ? AND side = ? AND client_id <> ? AND order_type = ?",
+		order.Symbol, order.Currency, contraSide, order.ClientID, "LIMIT").
+		Where("status IN ? AND reduce_only = ?", openOrderStatuses, false).
+		Where("expires_at IS NULL OR expires_at > ?", now)
+	if order.OrderType == "LIMIT" {
+		query = query.Where(priceCondition, money.ToUnits(order.Price))
+	}
 
-	return tx.Commit().Error
+	orders := []types.Order{}
+	err := query.Order(priceOrder).Order("created_at ASC").Order("id ASC").Find(&orders).Error
+	return orders, err
 }
 
-// GetIdempotencyRecord retrieves an idempotency record by key
-func (d *Database) GetIdempotencyRecord(key string) (*IdempotencyRecord, error) {
-	var record IdempotencyRecord
-	if err := d.db.Where("idempotency_key = ?", key).First(&record).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return &record, nil
+// recordCrossedOrders records the executions of the resting orders an order was crossed against
+// A resting order that changed since it was matched fails the cross with ErrCrossedOrderChanged
+func recordCrossedOrders(tx *gorm.DB, crosses []internalCross) error {
+	for _, cross := range crosses {
+		var current types.Order
+		if err := tx.Where("order_id = ?", cross.order.OrderID).First(&current).Error; err != nil {
+			return err
+		}
+		if current.Status != cross.previousStatus || current.FilledQuantity != cross.previousFilled {
+			return fmt.Errorf("%w: %s", ErrCrossedOrderChanged, cross.order.OrderID)
+		}
+		if err := recordExecution(tx, cross.execution, cross.order, cross.previousStatus); err != nil {
+			return err
 		}
-		return nil, err
 	}
-	return &record, nil
+	return nil
 }
 
-// CreateExecutionWithIdempotency creates a new execution and idempotency record in a transaction
-func (d *Database) CreateExecutionWithIdempotency(execution *types.Execution, idempotencyKey string) error {
-	// Begin transaction
-	tx := d.db.Begin()
-	if err := tx.Error; err != nil {
-		return err
+// GetOrderEvents retrieves the creation and status change events of an order, in sequence order
+func (d *Database) GetOrderEvents(orderID string) ([]events.DomainEvent, error) {
+	orderEvents := []events.DomainEvent{}
+	err := d.db.Where("aggregate_id = ? AND event_type IN ?", orderID,
+		[]string{events.EventOrderCreated, events.EventOrderStatusChanged}).
+		Order("sequence ASC").
+		Find(&orderEvents).Error
+	return orderEvents, err
+}
+
+// appendOrderStatusChanges records a status change in the event log for each of the orders
+func appendOrderStatusChanges(tx *gorm.DB, orderIDs []string, from, to string, at time.Time) error {
+	for _, orderID := range orderIDs {
+		change := events.StatusChange{From: from, To: to}
+		if err := events.Append(tx, events.EventOrderStatusChanged, orderID, change, at); err != nil {
+			return err
+		}
 	}
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
+	return nil
+}
+
+// ReplaceOrder cancels the original order and creates its replacement in a single transaction
+// The original is cancelled as of the replacement's creation time
+// The cancel only applies while the original is still PENDING; replaced reports whether it did
+func (d *Database) ReplaceOrder(originalOrderID string, replacement *types.Order) (replaced bool, err error) {
+	err = d.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&types.Order{}).
+			Where("order_id = ? AND status = ?", originalOrderID, "PENDING").
+			Updates(map[string]interface{}{"status": "CANCELLED", "updated_at": replacement.CreatedAt})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
 		}
-	}()
 
-	if err := tx.Create(execution).Error; err != nil {
-		tx.Rollback()
-		return err
+		if err := tx.Create(replacement).Error; err != nil {
+			return err
+		}
+		if err := appendOrderStatusChanges(tx, []string{originalOrderID}, "PENDING", "CANCELLED", replacement.CreatedAt); err != nil {
+			return err
+		}
+		if err := events.Append(tx, events.EventOrderCreated, replacement.OrderID, replacement, replacement.CreatedAt); err != nil {
+			return err
+		}
+		replaced = true
+		return nil
+	})
+	return replaced, err
+}
+
+// ListOrders retrieves a page of a client's orders, newest first,
+// restricted to the given client tag when one is provided
+func (d *Database) ListOrders(clientID, clientTag string, page common.Page) ([]types.Order, error) {
+	orders := []types.Order{}
+	query := d.db.Where("client_id = ?", clientID)
+	if clientTag != "" {
+		query = query.Where("client_tag = ?", clientTag)
 	}
+	err := query.Scopes(page.Apply).Find(&orders).Error
+	return orders, err
+}
+
+// CreateVenueAttempts records failed venue attempts
+func (d *Database) CreateVenueAttempts(attempts []types.VenueAttempt) error {
+	return d.db.Create(&attempts).Error
+}
+
+// GetFillsForExchange retrieves one page of a venue's fills created in the window [start, end), newest first
+func (d *Database) GetFillsForExchange(exchangeID string, start, end time.Time, page common.Page) ([]types.ExchangeFill, error) {
+	fills := []types.ExchangeFill{}
+	err := d.db.Where("exchange_id = ? AND created_at >= ? AND created_at < ?", exchangeID, start, end).
+		Scopes(page.Apply).
+		Find(&fills).Error
+	return fills, err
+}
 
-	// Create idempotency record
-	record := IdempotencyRecord{
-		IdempotencyKey: idempotencyKey,
-		ResourceID:     execution.ExecutionID,
-		ResourceType:   "execution",
-		ExpiresAt:      time.Now().Add(24 * time.Hour),
+// GetFillTotals counts a venue's fills created in the window [start, end) and sums their quantity and fees
+func (d *Database) GetFillTotals(exchangeID string, start, end time.Time) (count int, quantity, fees float64, err error) {
+	var totals struct {
+		Count    int
+		Quantity float64
+		Fees     float64
 	}
+	err = d.db.Model(&types.ExchangeFill{}).
+		Select("COUNT(*) AS count, COALESCE(SUM(quantity), 0) AS quantity, COALESCE(SUM(fee_amount), 0) AS fees").
+		Where("exchange_id = ? AND created_at >= ? AND created_at < ?", exchangeID, start, end).
+		Scan(&totals).Error
+	return totals.Count, totals.Quantity, totals.Fees, err
+}
 
-	if err := tx.Create(&record).Error; err != nil {
-		tx.Rollback()
-		return err
+// GetNetPosition returns a client's net executed quantity in a symbol: bought minus sold
+func (d *Database) GetNetPosition(clientID, symbol string) (float64, error) {
+	var position float64
+	query := `
+		SELECT COALESCE(SUM(CASE WHEN orders.side = 'BUY' THEN executions.total_quantity ELSE -executions.total_quantity END), 0)
+		FROM executions
+		JOIN orders ON orders.order_id = executions.order_id
+		WHERE orders.client_id = ?
+		AND orders.symbol = ?
+		AND executions.status = 'COMPLETED'
+		AND executions.deleted_at IS NULL
+	`
+	if err := d.db.Raw(query, clientID, symbol).Scan(&position).Error; err != nil {
+		return 0, err
 	}
+	return position, nil
+}
 
-	return tx.Commit().Error
+// GetHouseExposure sums the completed executions of every client before asOf into a net
+// quantity and notional per symbol and currency, ordered by symbol
+func (d *Database) GetHouseExposure(asOf time.Time) ([]SymbolExposure, error) {
+	exposures := []SymbolExposure{}
+	query := `
+		SELECT orders.symbol AS symbol,
+			orders.currency AS currency,
+			COALESCE(SUM(CASE WHEN orders.side = 'BUY' THEN executions.total_quantity ELSE -executions.total_quantity END), 0) AS net_quantity,
+			COALESCE(SUM(CASE WHEN orders.side = 'BUY' THEN 1 ELSE -1 END * executions.total_quantity * executions.average_price), 0) AS net_notional,
+			COUNT(DISTINCT orders.client_id) AS client_count
+		FROM executions
+		JOIN orders ON orders.order_id = executions.order_id
+		WHERE executions.status = 'COMPLETED'
+		AND executions.deleted_at IS NULL
+		AND executions.created_at < ?
+		GROUP BY orders.symbol, orders.currency
+		ORDER BY orders.symbol, orders.currency
+	`
+	if err := d.db.Raw(query, asOf).Scan(&exposures).Error; err != nil {
+		return nil, err
+	}
+	return exposures, nil
+}
+
+// FindOrphanedExecutions retrieves executions whose order ID matches no order row, oldest first
+// Soft-deleted orders still count as existing, so only executions with no order at all are returned
+func (d *Database) FindOrphanedExecutions() ([]types.Execution, error) {
+	executions := []types.Execution{}
+	err := d.db.
+		Joins("LEFT JOIN orders ON orders.order_id = executions.order_id").
+		Where("orders.id IS NULL").
+		Order("executions.created_at ASC").
+		Find(&executions).Error
+	return executions, err
+}
+
+// ExpireGTDOrders marks PENDING GTD orders whose expiry is at or before now as EXPIRED
+// and returns the IDs of the expired orders
+func (d *Database) ExpireGTDOrders(now time.Time) ([]string, error) {
+	var orderIDs []string
+	err := d.db.Transaction(func(tx *gorm.DB) error {
+		query := tx.Model(&types.Order{}).
+			Where("status = ? AND time_in_force = ? AND expires_at <= ?", "PENDING", "GTD", now)
+		if err := query.Pluck("order_id", &orderIDs).Error; err != nil {
+			return err
+		}
+		if len(orderIDs) == 0 {
+			return nil
+		}
+
+		if err := tx.Model(&types.Order{}).
+			Where("order_id IN (?) AND status = ?", orderIDs, "PENDING").
+			Updates(map[string]interface{}{"status": "EXPIRED", "updated_at": now}).Error; err != nil {
+			return err
+		}
+		return appendOrderStatusChanges(tx, orderIDs, "PENDING", "EXPIRED", now)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return orderIDs, nil
+}
+
+// CancelPendingOrders cancels all of a client's PENDING orders as of now in a single transaction,
+// optionally restricted to one symbol, and returns the IDs of the cancelled orders
+func (d *Database) CancelPendingOrders(clientID, symbol string, now time.Time) ([]string, error) {
+	var orderIDs []string
+	err := d.db.Transaction(func(tx *gorm.DB) error {
+		query := tx.Model(&types.Order{}).Where("client_id = ? AND status = ?", clientID, "PENDING")
+		if symbol != "" {
+			query = query.Where("symbol = ?", symbol)
+		}
+		if err := query.Pluck("order_id", &orderIDs).Error; err != nil {
+			return err
+		}
+		if len(orderIDs) == 0 {
+			return nil
+		}
+
+		if err := tx.Model(&types.Order{}).
+			Where("order_id IN (?) AND status = ?", orderIDs, "PENDING").
+			Updates(map[string]interface{}{"status": "CANCELLED", "updated_at": now}).Error; err != nil {
+			return err
+		}
+		return appendOrderStatusChanges(tx, orderIDs, "PENDING", "CANCELLED", now)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return orderIDs, nil
 }
@@ -0,0 +1,114 @@
+package trading
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ksred/klear-api/internal/types"
+	"github.com/rs/zerolog/log"
+)
+
+// Time-in-force values supported on orders
+const (
+	TimeInForceGTC = "GTC" // Good-till-cancelled, the default
+	TimeInForceGTD = "GTD" // Good-till-date, expires at the order's ExpiresAt
+)
+
+var (
+	ErrInvalidTimeInForce = errors.New("time in force must be GTC or GTD")
+	ErrExpiryRequired     = errors.New("GTD orders require expires_at")
+	ErrExpiryNotAllowed   = errors.New("expires_at is only supported on GTD orders")
+	ErrExpiryInPast       = errors.New("expires_at must be in the future")
+	ErrExpiryTooFar       = errors.New("expires_at exceeds the maximum GTD horizon")
+	ErrOrderExpired       = errors.New("order has expired")
+)
+
+// validateTimeInForce normalizes the order's time in force and checks its expiry against now
+func (s *Service) validateTimeInForce(order *types.Order, now time.Time) error {
+	order.TimeInForce = strings.ToUpper(strings.TrimSpace(order.TimeInForce))
+	if order.TimeInForce == "" {
+		order.TimeInForce = TimeInForceGTC
+	}
+
+	switch order.TimeInForce {
+	case TimeInForceGTC:
+		if order.ExpiresAt != nil {
+			return ErrExpiryNotAllowed
+		}
+	case TimeInForceGTD:
+		if order.ExpiresAt == nil || order.ExpiresAt.IsZero() {
+			return ErrExpiryRequired
+		}
+		if !order.ExpiresAt.After(now) {
+			return ErrExpiryInPast
+		}
+		if order.ExpiresAt.After(now.Add(s.config.MaxGTDHorizon)) {
+			return fmt.Errorf("%w of %s", ErrExpiryTooFar, s.config.MaxGTDHorizon)
+		}
+	default:
+		return ErrInvalidTimeInForce
+	}
+	return nil
+}
+
+// isExpired reports whether the order has expired, whether or not the sweeper has marked it yet
+func isExpired(order *types.Order, now time.Time) bool {
+	if order.Status == "EXPIRED" {
+		return true
+	}
+	return order.TimeInForce == TimeInForceGTD && order.ExpiresAt != nil && !order.ExpiresAt.After(now)
+}
+
+// ExpireOrders marks PENDING GTD orders whose expiry has passed as EXPIRED
+// Returns the IDs of the expired orders
+func (s *Service) ExpireOrders(now time.Time) ([]string, error) {
+	orderIDs, err := s.db.ExpireGTDOrders(now)
+	if err != nil {
+		return nil, err
+	}
+	if len(orderIDs) > 0 {
+		log.Info().
+			Str("service", "trading").
+			Int("expired_count", len(orderIDs)).
+			Msg("expired GTD orders")
+	}
+	return orderIDs, nil
+}
+
+// OrderExpirer periodically expires GTD orders that have reached their date
+type OrderExpirer struct {
+	service  *Service
+	interval time.Duration // Time between sweeps
+}
+
+// NewOrderExpirer creates an order expirer that sweeps at the given interval
+func NewOrderExpirer(service *Service, interval time.Duration) *OrderExpirer {
+	return &OrderExpirer{
+		service:  service,
+		interval: interval,
+	}
+}
+
+// Start sweeps for expired orders until ctx is cancelled
+func (e *OrderExpirer) Start(ctx context.Context) {
+	logger := log.With().Str("component", "order_expirer").Logger()
+	logger.Info().Dur("interval", e.interval).Msg("starting order expirer")
+
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info().Msg("shutting down order expirer")
+			return
+		case <-ticker.C:
+			if _, err := e.service.ExpireOrders(e.service.clock.Now()); err != nil {
+				logger.Error().Err(err).Msg("failed to expire orders")
+			}
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package trading
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ksred/klear-api/internal/types"
+	"github.com/ksred/klear-api/pkg/common"
+)
+
+// newGTDOrder returns a test order good until expiresAt
+func newGTDOrder(expiresAt time.Time) *types.Order {
+	order := newTestOrder()
+	order.TimeInForce = TimeInForceGTD
+	order.ExpiresAt = &expiresAt
+	return order
+}
+
+func TestGTDOrderExpiresAtItsDate(t *testing.T) {
+	service := newTestService(t)
+	clock := common.NewFakeClock(time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC))
+	service.SetClock(clock)
+
+	order := createTestOrder(t, service, newGTDOrder(clock.Now().Add(time.Hour)))
+
+	// Nothing expires before the order's date
+	expired, err := service.ExpireOrders(clock.Now().Add(59 * time.Minute))
+	if err != nil {
+		t.Fatalf("ExpireOrders: %v", err)
+	}
+	if len(expired) != 0 {
+		t.Fatalf("expired %v before the order's date, want none", expired)
+	}
+
+	clock.Advance(time.Hour)
+	expired, err = service.ExpireOrders(clock.Now())
+	if err != nil {
+		t.Fatalf("ExpireOrders: %v", err)
+	}
+	if len(expired) != 1 || expired[0] != order.OrderID {
+		t.Fatalf("expired %v, want the GTD order %s", expired, order.OrderID)
+	}
+	assertStatus(t, service, order, "EXPIRED")
+
+	if _, err := service.ExecuteOrder(order.OrderID, testClientID, uuid.New().String()); !errors.Is(err, ErrOrderExpired) {
+		t.Errorf("ExecuteOrder error = %v, want ErrOrderExpired", err)
+	}
+}
+
+func TestCreateOrderRejectsInvalidGTDExpiry(t *testing.T) {
+	service := newTestService(t)
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	service.SetClock(common.FixedClock{Time: now})
+
+	tests := []struct {
+		name  string
+		order func() *types.Order
+		want  error
+	}{
+		{"past date", func() *types.Order { return newGTDOrder(now.Add(-time.Minute)) }, ErrExpiryInPast},
+		{"missing date", func() *types.Order {
+			order := newTestOrder()
+			order.TimeInForce = TimeInForceGTD
+			return order
+		}, ErrExpiryRequired},
+		{"beyond horizon", func() *types.Order { return newGTDOrder(now.Add(DefaultConfig().MaxGTDHorizon + time.Hour)) }, ErrExpiryTooFar},
+		{"date on GTC", func() *types.Order {
+			order := newGTDOrder(now.Add(time.Hour))
+			order.TimeInForce = TimeInForceGTC
+			return order
+		}, ErrExpiryNotAllowed},
+	}
+	for _, tt := range tests {
+		if _, err := service.CreateOrder(tt.order(), uuid.New().String()); !errors.Is(err, tt.want) {
+			t.Errorf("%s: CreateOrder error = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
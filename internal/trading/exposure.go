@@ -0,0 +1,66 @@
+package trading
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/klear-api/pkg/money"
+	"github.com/ksred/klear-api/pkg/response"
+	"github.com/rs/zerolog/log"
+)
+
+// SymbolExposure is the house's net position in a symbol, summed across every client
+// Notional is signed like the quantity: positive when the house is net long
+type SymbolExposure struct {
+	Symbol      string  `json:"symbol"`
+	Currency    string  `json:"currency"`
+	NetQuantity float64 `json:"net_quantity"`
+	NetNotional float64 `json:"net_notional"`
+	ClientCount int     `json:"client_count"`
+}
+
+// HouseExposure is the firm-wide exposure per symbol from completed executions up to AsOf
+type HouseExposure struct {
+	AsOf    time.Time        `json:"as_of"`
+	Symbols []SymbolExposure `json:"symbols"`
+}
+
+// GetHouseExposure sums every client's completed executions into a net position per symbol
+// Parameters:
+//   - asOf: Only executions before this time are included
+func (s *Service) GetHouseExposure(asOf time.Time) (*HouseExposure, error) {
+	exposures, err := s.db.GetHouseExposure(asOf)
+	if err != nil {
+		log.Error().Err(err).Time("as_of", asOf).Msg("failed to calculate house exposure")
+		return nil, err
+	}
+
+	for i := range exposures {
+		exposures[i].NetNotional = money.Round(exposures[i].NetNotional, exposures[i].Currency)
+	}
+
+	return &HouseExposure{
+		AsOf:    asOf,
+		Symbols: exposures,
+	}, nil
+}
+
+// GetHouseExposureHandler handles GET requests for firm-wide exposure per symbol
+// Requires internal authentication
+// Query parameter: as_of (YYYY-MM-DD, includes executions up to the end of that UTC day; defaults to now)
+func (h *GinHandlers) GetHouseExposureHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		asOf := h.service.clock.Now().UTC()
+		if asOfParam := c.Query("as_of"); asOfParam != "" {
+			parsed, err := time.Parse("2006-01-02", asOfParam)
+			if err != nil {
+				response.BadRequest(c, "Invalid as_of format, expected YYYY-MM-DD")
+				return
+			}
+			asOf = parsed.Add(24 * time.Hour)
+		}
+
+		exposure, err := h.service.GetHouseExposure(asOf)
+		response.Handle(c, exposure, err)
+	}
+}
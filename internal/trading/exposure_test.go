@@ -0,0 +1,89 @@
+package trading
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/klear-api/internal/types"
+)
+
+// getHouseExposure fetches the house exposure through the handler, with an optional as_of date
+func getHouseExposure(t *testing.T, service *Service, asOf string) HouseExposure {
+	t.Helper()
+	router := gin.New()
+	router.GET("/risk/house-exposure", NewGinHandlers(service).GetHouseExposureHandler())
+
+	path := "/risk/house-exposure"
+	if asOf != "" {
+		path += "?as_of=" + asOf
+	}
+	recorder := performRequest(router, http.MethodGet, path, nil, "")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", recorder.Code, recorder.Body.String())
+	}
+	var envelope struct {
+		Data HouseExposure `json:"data"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return envelope.Data
+}
+
+func TestHouseExposureCombinesClients(t *testing.T) {
+	service := newTestService(t)
+	registerClient(t, service, "client-2", true)
+
+	executeTestOrder(t, service, newTestOrder())
+	sell := newTestOrder()
+	sell.ClientID = "client-2"
+	sell.Side = "SELL"
+	sell.Quantity = 40
+	executeTestOrder(t, service, sell)
+	msft := &types.Order{ClientID: testClientID, Symbol: "MSFT", Side: "BUY", OrderType: "LIMIT", Quantity: 10, Price: 300}
+	executeTestOrder(t, service, msft)
+
+	exposure := getHouseExposure(t, service, "")
+	if len(exposure.Symbols) != 2 {
+		t.Fatalf("symbols = %+v, want AAPL and MSFT", exposure.Symbols)
+	}
+	aapl := exposure.Symbols[0]
+	if aapl.Symbol != "AAPL" || aapl.NetQuantity != 60 || aapl.NetNotional != 9000 || aapl.ClientCount != 2 {
+		t.Errorf("AAPL exposure = %+v, want client-1's 100 bought net of client-2's 40 sold: 60 for 9000 across 2 clients", aapl)
+	}
+	msftExposure := exposure.Symbols[1]
+	if msftExposure.Symbol != "MSFT" || msftExposure.NetQuantity != 10 || msftExposure.NetNotional != 3000 || msftExposure.ClientCount != 1 {
+		t.Errorf("MSFT exposure = %+v, want 10 for 3000 from one client", msftExposure)
+	}
+}
+
+func TestHouseExposureAsOf(t *testing.T) {
+	service := newTestService(t)
+	earlier := executeTestOrder(t, service, newTestOrder())
+	executeTestOrder(t, service, newTestOrder())
+
+	// The first execution happened three days ago
+	executedAt := time.Now().UTC().AddDate(0, 0, -3)
+	if err := service.db.db.Model(&types.Execution{}).
+		Where("execution_id = ?", earlier.ExecutionID).
+		Update("created_at", executedAt).Error; err != nil {
+		t.Fatalf("failed to backdate execution: %v", err)
+	}
+
+	asOf := getHouseExposure(t, service, executedAt.Format("2006-01-02"))
+	if len(asOf.Symbols) != 1 || asOf.Symbols[0].NetQuantity != 100 {
+		t.Errorf("exposure as of %s = %+v, want only the earlier 100", executedAt.Format("2006-01-02"), asOf.Symbols)
+	}
+	if now := getHouseExposure(t, service, ""); len(now.Symbols) != 1 || now.Symbols[0].NetQuantity != 200 {
+		t.Errorf("exposure now = %+v, want both executions for 200", now.Symbols)
+	}
+
+	router := gin.New()
+	router.GET("/risk/house-exposure", NewGinHandlers(service).GetHouseExposureHandler())
+	if recorder := performRequest(router, http.MethodGet, "/risk/house-exposure?as_of=14-10-2026", nil, ""); recorder.Code != http.StatusBadRequest {
+		t.Errorf("invalid as_of status = %d, want 400", recorder.Code)
+	}
+}
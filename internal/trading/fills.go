@@ -0,0 +1,119 @@
+package trading
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/klear-api/internal/types"
+	"github.com/ksred/klear-api/pkg/common"
+	"github.com/ksred/klear-api/pkg/response"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrExchangeIDRequired = errors.New("exchange ID is required")
+	ErrInvalidFillWindow  = errors.New("fill window end must be after its start")
+)
+
+// FillReport lists the fills on a venue within a time window, for venue reconciliation
+// The count and totals cover the whole window; Fills holds one page of it, newest first.
+// NextCursor is set when more fills follow; pass it back as the cursor to fetch them
+type FillReport struct {
+	ExchangeID    string               `json:"exchange_id"`
+	WindowStart   time.Time            `json:"window_start"`
+	WindowEnd     time.Time            `json:"window_end"`
+	FillCount     int                  `json:"fill_count"`
+	TotalQuantity float64              `json:"total_quantity"`
+	TotalFees     float64              `json:"total_fees"`
+	Fills         []types.ExchangeFill `json:"fills"`
+	Limit         int                  `json:"limit"` // Page size applied, after defaulting and clamping
+	NextCursor    string               `json:"next_cursor,omitempty"`
+}
+
+// GetFillsForExchange retrieves the fills on a venue in the window [start, end)
+// Parameters:
+//   - exchangeID: ID of the venue, e.g. EXCH1
+//   - start: Inclusive start of the window
+//   - end: Exclusive end of the window
+//   - page: Page of fills to return, by cursor or offset
+func (s *Service) GetFillsForExchange(exchangeID string, start, end time.Time, page common.Page) (*FillReport, error) {
+	exchangeID = common.NormalizeSymbol(exchangeID)
+	if exchangeID == "" {
+		return nil, ErrExchangeIDRequired
+	}
+	if !end.After(start) {
+		return nil, ErrInvalidFillWindow
+	}
+
+	fills, err := s.db.GetFillsForExchange(exchangeID, start, end, page)
+	if err != nil {
+		log.Error().Err(err).Str("exchange_id", exchangeID).Msg("failed to fetch exchange fills")
+		return nil, err
+	}
+	count, quantity, fees, err := s.db.GetFillTotals(exchangeID, start, end)
+	if err != nil {
+		log.Error().Err(err).Str("exchange_id", exchangeID).Msg("failed to total exchange fills")
+		return nil, err
+	}
+
+	report := &FillReport{
+		ExchangeID:    exchangeID,
+		WindowStart:   start,
+		WindowEnd:     end,
+		FillCount:     count,
+		TotalQuantity: quantity,
+		TotalFees:     fees,
+		Fills:         fills,
+		Limit:         page.Limit,
+	}
+	if page.HasMore(len(fills)) {
+		report.Fills = fills[:page.Limit]
+		last := report.Fills[page.Limit-1]
+		report.NextCursor = common.EncodeCursor(last.CreatedAt, last.ID)
+	}
+
+	return report, nil
+}
+
+// GetFillsHandler handles GET requests for the fills on a venue
+// Requires internal authentication
+// Query parameters: exchange_id (required), start and end (RFC 3339, default to the current UTC day),
+// limit, cursor, offset (optional)
+func (h *GinHandlers) GetFillsHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		now := h.service.clock.Now().UTC()
+		start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		if startParam := c.Query("start"); startParam != "" {
+			parsed, err := time.Parse(time.RFC3339, startParam)
+			if err != nil {
+				response.BadRequest(c, "Invalid start format, expected RFC 3339")
+				return
+			}
+			start = parsed
+		}
+
+		end := start.Add(24 * time.Hour)
+		if endParam := c.Query("end"); endParam != "" {
+			parsed, err := time.Parse(time.RFC3339, endParam)
+			if err != nil {
+				response.BadRequest(c, "Invalid end format, expected RFC 3339")
+				return
+			}
+			end = parsed
+		}
+
+		page, err := common.ParsePage(c.Query("limit"), c.Query("offset"), c.Query("cursor"), h.service.config.PageSize)
+		if err != nil {
+			response.BadRequest(c, err.Error())
+			return
+		}
+
+		report, err := h.service.GetFillsForExchange(c.Query("exchange_id"), start, end, page)
+		if errors.Is(err, ErrExchangeIDRequired) || errors.Is(err, ErrInvalidFillWindow) {
+			response.BadRequest(c, err.Error())
+			return
+		}
+		response.Handle(c, report, err)
+	}
+}
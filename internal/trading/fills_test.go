@@ -0,0 +1,99 @@
+package trading
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/klear-api/internal/types"
+)
+
+func TestGetFillsHandlerFiltersByVenueAndWindow(t *testing.T) {
+	service := newTestService(t)
+	day := time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC)
+
+	seed := []struct {
+		id       string
+		exchange string
+		at       time.Time
+		quantity float64
+		fee      float64
+	}{
+		{"F1", "EXCH1", day.Add(9 * time.Hour), 100, 15},
+		{"F2", "EXCH1", day.Add(10 * time.Hour), 50, 7.5},
+		{"F3", "EXCH1", day.Add(11 * time.Hour), 25, 3.75},
+		{"F4", "EXCH2", day.Add(10 * time.Hour), 200, 40},
+		{"F5", "EXCH1", day.Add(-time.Hour), 300, 45}, // Previous day
+	}
+	for _, fill := range seed {
+		record := types.ExchangeFill{
+			FillID:      fill.id,
+			ExecutionID: "EXEC-" + fill.id,
+			ExchangeID:  fill.exchange,
+			Price:       150,
+			Quantity:    fill.quantity,
+			FeeAmount:   fill.fee,
+			CreatedAt:   fill.at,
+		}
+		if err := service.db.db.Create(&record).Error; err != nil {
+			t.Fatalf("failed to seed fill: %v", err)
+		}
+	}
+
+	router := gin.New()
+	router.GET("/fills", NewGinHandlers(service).GetFillsHandler())
+	query := url.Values{
+		"exchange_id": {"exch1"},
+		"start":       {day.Format(time.RFC3339)},
+		"end":         {day.Add(24 * time.Hour).Format(time.RFC3339)},
+		"limit":       {"2"},
+	}
+	fetch := func(query url.Values) FillReport {
+		t.Helper()
+		recorder := performRequest(router, http.MethodGet, "/fills?"+query.Encode(), nil, "")
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200: %s", recorder.Code, recorder.Body.String())
+		}
+		var envelope struct {
+			Data FillReport `json:"data"`
+		}
+		if err := json.Unmarshal(recorder.Body.Bytes(), &envelope); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return envelope.Data
+	}
+
+	report := fetch(query)
+	if report.ExchangeID != "EXCH1" || report.FillCount != 3 || report.TotalQuantity != 175 || report.TotalFees != 26.25 {
+		t.Errorf("report = %d fills of %v with %v fees on %s, want 3 fills of 175 with 26.25 fees on EXCH1",
+			report.FillCount, report.TotalQuantity, report.TotalFees, report.ExchangeID)
+	}
+	if len(report.Fills) != 2 || report.Fills[0].FillID != "F3" || report.Fills[1].FillID != "F2" || report.NextCursor == "" {
+		t.Fatalf("first page = %+v, want F3 and F2 with a cursor", report.Fills)
+	}
+
+	query.Set("cursor", report.NextCursor)
+	next := fetch(query)
+	if len(next.Fills) != 1 || next.Fills[0].FillID != "F1" || next.NextCursor != "" {
+		t.Errorf("second page = %+v, want only F1", next.Fills)
+	}
+}
+
+func TestGetFillsHandlerRejectsInvalidQueries(t *testing.T) {
+	service := newTestService(t)
+	router := gin.New()
+	router.GET("/fills", NewGinHandlers(service).GetFillsHandler())
+
+	for _, query := range []string{
+		"",
+		"exchange_id=EXCH1&start=yesterday",
+		"exchange_id=EXCH1&start=2026-10-14T00:00:00Z&end=2026-10-13T00:00:00Z",
+	} {
+		if recorder := performRequest(router, http.MethodGet, "/fills?"+query, nil, ""); recorder.Code != http.StatusBadRequest {
+			t.Errorf("query %q: status = %d, want 400", query, recorder.Code)
+		}
+	}
+}
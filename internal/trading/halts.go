@@ -0,0 +1,111 @@
+package trading
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/klear-api/pkg/common"
+	"github.com/ksred/klear-api/pkg/response"
+	"github.com/rs/zerolog/log"
+)
+
+var ErrSymbolHalted = errors.New("trading is halted for symbol")
+
+// HaltRegistry tracks symbols whose trading has been halted, e.g. by a LULD circuit breaker
+type HaltRegistry struct {
+	mu     sync.RWMutex
+	halted map[string]bool
+}
+
+// NewHaltRegistry creates an empty halt registry
+func NewHaltRegistry() *HaltRegistry {
+	return &HaltRegistry{
+		halted: make(map[string]bool),
+	}
+}
+
+// Halt stops trading in a symbol
+func (r *HaltRegistry) Halt(symbol string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.halted[common.NormalizeSymbol(symbol)] = true
+}
+
+// Resume re-opens trading in a halted symbol
+func (r *HaltRegistry) Resume(symbol string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.halted, common.NormalizeSymbol(symbol))
+}
+
+// IsHalted reports whether trading in a symbol is halted
+func (r *HaltRegistry) IsHalted(symbol string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.halted[common.NormalizeSymbol(symbol)]
+}
+
+// List returns the halted symbols in alphabetical order
+func (r *HaltRegistry) List() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	symbols := make([]string, 0, len(r.halted))
+	for symbol := range r.halted {
+		symbols = append(symbols, symbol)
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// Halts returns the service's symbol halt registry
+func (s *Service) Halts() *HaltRegistry {
+	return s.halts
+}
+
+// HaltSymbolHandler handles POST requests to halt trading in a symbol
+// Requires a token with the admin permission
+// URL parameter: symbol
+func (h *GinHandlers) HaltSymbolHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		symbol := common.NormalizeSymbol(c.Param("symbol"))
+		if symbol == "" {
+			response.BadRequest(c, "Symbol is required")
+			return
+		}
+
+		h.service.halts.Halt(symbol)
+		log.Warn().Str("symbol", symbol).Msg("trading halted for symbol")
+
+		response.SuccessWithStatus(c, gin.H{"symbol": symbol, "halted": true}, http.StatusOK)
+	}
+}
+
+// ResumeSymbolHandler handles DELETE requests to resume trading in a halted symbol
+// Requires a token with the admin permission
+// URL parameter: symbol
+func (h *GinHandlers) ResumeSymbolHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		symbol := common.NormalizeSymbol(c.Param("symbol"))
+		if symbol == "" {
+			response.BadRequest(c, "Symbol is required")
+			return
+		}
+
+		h.service.halts.Resume(symbol)
+		log.Info().Str("symbol", symbol).Msg("trading resumed for symbol")
+
+		response.Success(c, gin.H{"symbol": symbol, "halted": false})
+	}
+}
+
+// ListHaltedSymbolsHandler handles GET requests for the currently halted symbols
+// Requires internal authentication
+func (h *GinHandlers) ListHaltedSymbolsHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		response.Success(c, gin.H{"halted_symbols": h.service.halts.List()})
+	}
+}
@@ -0,0 +1,75 @@
+package trading
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func newHaltRouter(service *Service) *gin.Engine {
+	handlers := NewGinHandlers(service)
+	router := gin.New()
+	router.POST("/orders", authenticate(testClientID, "trade"), handlers.CreateOrderHandler())
+	router.GET("/orders/:order_id", authenticate(testClientID), handlers.GetOrderStatusHandler())
+	router.GET("/symbols/halted", handlers.ListHaltedSymbolsHandler())
+	router.POST("/symbols/:symbol/halt", handlers.HaltSymbolHandler())
+	router.DELETE("/symbols/:symbol/halt", handlers.ResumeSymbolHandler())
+	return router
+}
+
+// placeOrder submits a test order for the symbol through the handler
+func placeOrder(router http.Handler, symbol string) int {
+	order := newTestOrder()
+	order.Symbol = symbol
+	return performRequest(router, http.MethodPost, "/orders", order, uuid.New().String()).Code
+}
+
+func TestHaltedSymbolRejectsOrders(t *testing.T) {
+	service := newTestService(t)
+	router := newHaltRouter(service)
+	resting := createTestOrder(t, service, newTestOrder())
+
+	if recorder := performRequest(router, http.MethodPost, "/symbols/aapl/halt", nil, ""); recorder.Code != http.StatusOK {
+		t.Fatalf("halt status = %d, want 200: %s", recorder.Code, recorder.Body.String())
+	}
+
+	order := newTestOrder()
+	recorder := performRequest(router, http.MethodPost, "/orders", order, uuid.New().String())
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Fatalf("halted order status = %d, want 503: %s", recorder.Code, recorder.Body.String())
+	}
+	if code := errorCode(t, recorder); code != RejectCodeSymbolHalted {
+		t.Errorf("halted order code = %s, want %s", code, RejectCodeSymbolHalted)
+	}
+	if status := placeOrder(router, "MSFT"); status != http.StatusCreated {
+		t.Errorf("order for a trading symbol status = %d, want 201", status)
+	}
+
+	// Status of existing orders in the halted symbol is still served
+	if recorder := performRequest(router, http.MethodGet, "/orders/"+resting.OrderID, nil, ""); recorder.Code != http.StatusOK {
+		t.Errorf("order status during halt = %d, want 200", recorder.Code)
+	}
+
+	recorder = performRequest(router, http.MethodGet, "/symbols/halted", nil, "")
+	var listed struct {
+		Data struct {
+			HaltedSymbols []string `json:"halted_symbols"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &listed); err != nil {
+		t.Fatalf("failed to decode halted symbols: %v", err)
+	}
+	if len(listed.Data.HaltedSymbols) != 1 || listed.Data.HaltedSymbols[0] != "AAPL" {
+		t.Errorf("halted symbols = %v, want [AAPL]", listed.Data.HaltedSymbols)
+	}
+
+	if recorder := performRequest(router, http.MethodDelete, "/symbols/AAPL/halt", nil, ""); recorder.Code != http.StatusOK {
+		t.Fatalf("resume status = %d, want 200", recorder.Code)
+	}
+	if status := placeOrder(router, "AAPL"); status != http.StatusCreated {
+		t.Errorf("order after resume status = %d, want 201", status)
+	}
+}
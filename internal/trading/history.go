@@ -0,0 +1,102 @@
+package trading
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/klear-api/internal/auth"
+	"github.com/ksred/klear-api/internal/events"
+	"github.com/ksred/klear-api/pkg/response"
+)
+
+// OrderTransition is one status change in an order's history
+type OrderTransition struct {
+	Sequence uint64    `json:"sequence"`       // Event log sequence the transition was recorded at
+	From     string    `json:"from,omitempty"` // Empty for the order's creation
+	To       string    `json:"to"`
+	At       time.Time `json:"at"`
+}
+
+// OrderHistory is the timeline of an order's status changes, oldest first
+type OrderHistory struct {
+	OrderID     string            `json:"order_id"`
+	Status      string            `json:"status"` // Current status of the order
+	Transitions []OrderTransition `json:"transitions"`
+}
+
+// GetOrderHistory returns the status transitions of a client's order, read from the event log
+// Returns ErrOrderNotFound if the order does not exist or belongs to another client
+// Parameters:
+//   - orderID: ID of the order
+//   - clientID: ID of the client the order must belong to
+func (s *Service) GetOrderHistory(orderID, clientID string) (*OrderHistory, error) {
+	order, err := s.GetOrderByOrderIDAndClientID(orderID, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if order == nil {
+		return nil, ErrOrderNotFound
+	}
+
+	orderEvents, err := s.db.GetOrderEvents(order.OrderID)
+	if err != nil {
+		return nil, err
+	}
+
+	history := &OrderHistory{
+		OrderID:     order.OrderID,
+		Status:      order.Status,
+		Transitions: make([]OrderTransition, 0, len(orderEvents)),
+	}
+	for _, event := range orderEvents {
+		transition := OrderTransition{Sequence: event.Sequence, At: event.OccurredAt}
+		switch event.EventType {
+		case events.EventOrderCreated:
+			// The creation event carries the whole order; only its initial status is needed
+			var created struct {
+				Status string `json:"status"`
+			}
+			if err := json.Unmarshal(event.Payload, &created); err != nil {
+				return nil, fmt.Errorf("failed to decode event %d: %w", event.Sequence, err)
+			}
+			transition.To = created.Status
+		case events.EventOrderStatusChanged:
+			var change events.StatusChange
+			if err := json.Unmarshal(event.Payload, &change); err != nil {
+				return nil, fmt.Errorf("failed to decode event %d: %w", event.Sequence, err)
+			}
+			transition.From, transition.To = change.From, change.To
+		}
+		history.Transitions = append(history.Transitions, transition)
+	}
+	return history, nil
+}
+
+// GetOrderHistoryHandler handles GET requests for the status history of the client's order
+// Requires a valid JWT token
+// URL parameter: order_id
+func (h *GinHandlers) GetOrderHistoryHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, exists := c.Get("claims")
+		if !exists {
+			response.Unauthorized(c, "Missing authentication claims")
+			return
+		}
+
+		clientID := auth.GetClientID(claims)
+		if clientID == "" {
+			response.Unauthorized(c, "Invalid client ID in token")
+			return
+		}
+
+		history, err := h.service.GetOrderHistory(c.Param("order_id"), clientID)
+		if errors.Is(err, ErrOrderNotFound) {
+			response.NotFound(c, "Order not found")
+			return
+		}
+		response.Handle(c, history, err)
+	}
+}
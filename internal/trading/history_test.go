@@ -0,0 +1,66 @@
+package trading
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/klear-api/pkg/common"
+)
+
+func TestOrderHistoryListsTransitionsInOrder(t *testing.T) {
+	service := newTestService(t)
+	clock := common.NewFakeClock(time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC))
+	service.SetClock(clock)
+	router := gin.New()
+	router.GET("/orders/:order_id/history", authenticate(testClientID), NewGinHandlers(service).GetOrderHistoryHandler())
+
+	order := createTestOrder(t, service, newTestOrder())
+	createdAt := clock.Now()
+	clock.Advance(time.Minute)
+	if _, err := service.ExecuteOrder(order.OrderID, testClientID, "history-execution"); err != nil {
+		t.Fatalf("ExecuteOrder: %v", err)
+	}
+	filledAt := clock.Now()
+
+	recorder := performRequest(router, http.MethodGet, "/orders/"+order.OrderID+"/history", nil, "")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", recorder.Code, recorder.Body.String())
+	}
+	var envelope struct {
+		Data OrderHistory `json:"data"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	history := envelope.Data
+
+	if history.OrderID != order.OrderID || history.Status != "FILLED" {
+		t.Errorf("history = %s %s, want %s FILLED", history.OrderID, history.Status, order.OrderID)
+	}
+	want := []OrderTransition{
+		{From: "", To: "PENDING", At: createdAt},
+		{From: "PENDING", To: "FILLED", At: filledAt},
+	}
+	if len(history.Transitions) != len(want) {
+		t.Fatalf("transitions = %+v, want %d", history.Transitions, len(want))
+	}
+	for i, transition := range history.Transitions {
+		if transition.From != want[i].From || transition.To != want[i].To || !transition.At.Equal(want[i].At) {
+			t.Errorf("transition %d = %q→%s at %v, want %q→%s at %v",
+				i, transition.From, transition.To, transition.At, want[i].From, want[i].To, want[i].At)
+		}
+		if i > 0 && transition.Sequence <= history.Transitions[i-1].Sequence {
+			t.Errorf("transition %d sequence %d does not follow %d", i, transition.Sequence, history.Transitions[i-1].Sequence)
+		}
+	}
+
+	// Another client's order is not found
+	other := gin.New()
+	other.GET("/orders/:order_id/history", authenticate("client-2"), NewGinHandlers(service).GetOrderHistoryHandler())
+	if recorder := performRequest(other, http.MethodGet, "/orders/"+order.OrderID+"/history", nil, ""); recorder.Code != http.StatusNotFound {
+		t.Errorf("another client's history status = %d, want 404", recorder.Code)
+	}
+}
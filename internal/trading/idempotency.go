@@ -0,0 +1,182 @@
+package trading
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ksred/klear-api/internal/database/retry"
+	"github.com/ksred/klear-api/pkg/common"
+	"github.com/rs/zerolog/log"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrIdempotencyKeyInProgress  = errors.New("a request with this idempotency key is already in progress")
+	ErrIdempotencyKeyNotReserved = errors.New("idempotency key is not reserved")
+)
+
+// Idempotency backends selectable through Config.IdempotencyBackend
+const (
+	IdempotencyBackendDB    = "db"
+	IdempotencyBackendRedis = "redis"
+)
+
+const (
+	// idempotencyKeyTTL is how long a committed key replays the resource it created
+	idempotencyKeyTTL = 24 * time.Hour
+	// idempotencyReservationTTL bounds how long a reservation blocks its key, so a request
+	// that dies between Reserve and Commit does not lock the key out until it would have expired
+	idempotencyReservationTTL = time.Minute
+)
+
+// IdempotencyStore records the resource created for each idempotency key
+// A request first reserves its key, creates the resource and then commits the resource ID
+// against the key; a request that cannot complete releases its reservation. Keys are scoped
+// per resource type, so the same key used for an order and an execution are distinct
+type IdempotencyStore interface {
+	// Get returns the ID of the resource committed under the key, or "" if the key is
+	// unknown, expired or only reserved
+	Get(key, resourceType string) (string, error)
+	// Reserve claims the key for a new request
+	// Returns ErrIdempotencyKeyInProgress if the key is already reserved or committed
+	Reserve(key, resourceType string) error
+	// Commit records the resource created under a reserved key
+	// Returns ErrIdempotencyKeyNotReserved if the reservation was lost
+	Commit(key, resourceType, resourceID string) error
+	// Release drops a reservation that was not committed, so the key can be retried
+	Release(key, resourceType string) error
+}
+
+// txIdempotencyStore is implemented by stores kept in the application database, which record
+// a key in the same transaction that creates its resource instead of reserving it beforehand
+// A crash can then never leave a resource without its key, or a key reserved for a resource
+// that was never created
+type txIdempotencyStore interface {
+	// RecordTx records the resource created under the key within the transaction
+	// Returns ErrIdempotencyKeyInProgress if the key is already reserved or committed
+	RecordTx(tx *gorm.DB, key, resourceType, resourceID string) error
+	// CommitTx records the resource created under a reserved key within the transaction
+	// Returns ErrIdempotencyKeyNotReserved if the reservation was lost
+	CommitTx(tx *gorm.DB, key, resourceType, resourceID string) error
+}
+
+// dbIdempotencyStore keeps idempotency keys as IdempotencyRecord rows
+// The unique index on key and resource type makes Reserve atomic
+type dbIdempotencyStore struct {
+	db    *gorm.DB
+	clock common.Clock
+}
+
+// NewDBIdempotencyStore creates an idempotency store backed by the application database
+func NewDBIdempotencyStore(db *gorm.DB) IdempotencyStore {
+	return &dbIdempotencyStore{db: db, clock: common.RealClock{}}
+}
+
+func (s *dbIdempotencyStore) Get(key, resourceType string) (string, error) {
+	var record IdempotencyRecord
+	err := s.db.Where("idempotency_key = ? AND resource_type = ? AND expires_at > ?", key, resourceType, s.clock.Now()).
+		First(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return record.ResourceID, nil
+}
+
+func (s *dbIdempotencyStore) Reserve(key, resourceType string) error {
+	return retry.Do("reserve_idempotency_key", func() error {
+		return s.db.Transaction(func(tx *gorm.DB) error {
+			return s.insertRecord(tx, key, resourceType, "", s.clock.Now().Add(idempotencyReservationTTL))
+		})
+	})
+}
+
+func (s *dbIdempotencyStore) RecordTx(tx *gorm.DB, key, resourceType, resourceID string) error {
+	return s.insertRecord(tx, key, resourceType, resourceID, s.clock.Now().Add(idempotencyKeyTTL))
+}
+
+// insertRecord claims the key with a record expiring at expiresAt, committed to resourceID
+// or, if it is empty, only reserved
+func (s *dbIdempotencyStore) insertRecord(tx *gorm.DB, key, resourceType, resourceID string, expiresAt time.Time) error {
+	// An expired record no longer guards its key, so it gives way to the new one
+	if err := tx.Unscoped().
+		Where("idempotency_key = ? AND resource_type = ? AND expires_at <= ?", key, resourceType, s.clock.Now()).
+		Delete(&IdempotencyRecord{}).Error; err != nil {
+		return err
+	}
+
+	record := IdempotencyRecord{
+		IdempotencyKey: key,
+		ResourceType:   resourceType,
+		ResourceID:     resourceID,
+		ExpiresAt:      expiresAt,
+	}
+	err := tx.Create(&record).Error
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrIdempotencyKeyInProgress
+	}
+	return err
+}
+
+func (s *dbIdempotencyStore) Commit(key, resourceType, resourceID string) error {
+	return s.CommitTx(s.db, key, resourceType, resourceID)
+}
+
+func (s *dbIdempotencyStore) CommitTx(tx *gorm.DB, key, resourceType, resourceID string) error {
+	result := tx.Model(&IdempotencyRecord{}).
+		Where("idempotency_key = ? AND resource_type = ? AND resource_id = ''", key, resourceType).
+		Updates(map[string]interface{}{
+			"resource_id": resourceID,
+			"expires_at":  s.clock.Now().Add(idempotencyKeyTTL),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrIdempotencyKeyNotReserved
+	}
+	return nil
+}
+
+func (s *dbIdempotencyStore) Release(key, resourceType string) error {
+	return s.db.Unscoped().
+		Where("idempotency_key = ? AND resource_type = ? AND resource_id = ''", key, resourceType).
+		Delete(&IdempotencyRecord{}).Error
+}
+
+// newIdempotencyStore creates the idempotency store selected by the configuration,
+// defaulting to the database
+func newIdempotencyStore(db *gorm.DB, config Config) IdempotencyStore {
+	if config.IdempotencyBackend == IdempotencyBackendRedis {
+		return NewRedisIdempotencyStore(config.RedisAddr, config.RedisPassword, config.RedisDB)
+	}
+	return NewDBIdempotencyStore(db)
+}
+
+// commitIdempotencyKey records the resource created under a reserved key
+// Failures are logged rather than returned, since the resource already exists; the key stays
+// reserved until the reservation expires, so retries are rejected rather than duplicated meanwhile
+func (s *Service) commitIdempotencyKey(key, resourceType, resourceID string) {
+	if err := s.idempotency.Commit(key, resourceType, resourceID); err != nil {
+		log.Error().
+			Err(err).
+			Str("resource_type", resourceType).
+			Str("resource_id", resourceID).
+			Str("service", "trading").
+			Msg("failed to commit idempotency key")
+	}
+}
+
+// releaseIdempotencyKey drops the reservation of a request that failed, so the key can be retried
+// Failures are logged; the reservation then lapses when it expires
+func (s *Service) releaseIdempotencyKey(key, resourceType string) {
+	if err := s.idempotency.Release(key, resourceType); err != nil {
+		log.Error().
+			Err(err).
+			Str("resource_type", resourceType).
+			Str("service", "trading").
+			Msg("failed to release idempotency key")
+	}
+}
@@ -0,0 +1,76 @@
+package trading
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/ksred/klear-api/pkg/redis"
+)
+
+// redisCommandTimeout bounds dialling Redis and each idempotency command
+const redisCommandTimeout = 2 * time.Second
+
+// redisReservedValue marks a key that is reserved but not yet committed
+// Resource IDs are never empty, so it cannot collide with a committed value
+const redisReservedValue = ""
+
+// redisIdempotencyStore keeps idempotency keys in Redis so horizontally scaled instances
+// share them without contending on the database
+// Reserve is an atomic SET NX and Commit and Release are scripts that only act on a key still
+// reserved, with key expiry handled by Redis
+type redisIdempotencyStore struct {
+	client *redis.Client
+}
+
+// NewRedisIdempotencyStore creates an idempotency store backed by the Redis server at addr
+// The connection is made on first use
+func NewRedisIdempotencyStore(addr, password string, db int) IdempotencyStore {
+	return &redisIdempotencyStore{client: redis.NewClient(addr, password, db, redisCommandTimeout)}
+}
+
+// redisIdempotencyKey namespaces a key by resource type
+func redisIdempotencyKey(key, resourceType string) string {
+	return "idempotency:" + resourceType + ":" + key
+}
+
+func (s *redisIdempotencyStore) Get(key, resourceType string) (string, error) {
+	reply, err := s.client.Do("GET", redisIdempotencyKey(key, resourceType))
+	if errors.Is(err, redis.ErrNil) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	resourceID, _ := reply.(string)
+	return resourceID, nil
+}
+
+func (s *redisIdempotencyStore) Reserve(key, resourceType string) error {
+	_, err := s.client.Do("SET", redisIdempotencyKey(key, resourceType), redisReservedValue,
+		"NX", "PX", strconv.FormatInt(idempotencyReservationTTL.Milliseconds(), 10))
+	if errors.Is(err, redis.ErrNil) {
+		return ErrIdempotencyKeyInProgress
+	}
+	return err
+}
+
+func (s *redisIdempotencyStore) Commit(key, resourceType, resourceID string) error {
+	// Only a reservation may be committed; a committed key keeps the resource it replays
+	_, err := s.client.Do("EVAL",
+		`if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("SET", KEYS[1], ARGV[2], "PX", ARGV[3]) end return false`,
+		"1", redisIdempotencyKey(key, resourceType), redisReservedValue, resourceID,
+		strconv.FormatInt(idempotencyKeyTTL.Milliseconds(), 10))
+	if errors.Is(err, redis.ErrNil) {
+		return ErrIdempotencyKeyNotReserved
+	}
+	return err
+}
+
+func (s *redisIdempotencyStore) Release(key, resourceType string) error {
+	// Only an uncommitted reservation may be released; a committed key must keep replaying
+	_, err := s.client.Do("EVAL",
+		`if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("DEL", KEYS[1]) end return 0`,
+		"1", redisIdempotencyKey(key, resourceType), redisReservedValue)
+	return err
+}
@@ -0,0 +1,270 @@
+package trading
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/ksred/klear-api/internal/types"
+	"github.com/ksred/klear-api/pkg/common"
+	"gorm.io/gorm"
+)
+
+// testIdempotencyStore runs the reserve, commit and replay contract every store must keep
+func testIdempotencyStore(t *testing.T, store IdempotencyStore) {
+	t.Helper()
+	const key = "store-key"
+
+	if id, err := store.Get(key, ResourceTypeOrder); err != nil || id != "" {
+		t.Fatalf("Get of an unknown key = %q, %v, want nothing", id, err)
+	}
+	if err := store.Commit(key, ResourceTypeOrder, "order-1"); !errors.Is(err, ErrIdempotencyKeyNotReserved) {
+		t.Errorf("Commit before Reserve error = %v, want ErrIdempotencyKeyNotReserved", err)
+	}
+
+	if err := store.Reserve(key, ResourceTypeOrder); err != nil {
+		t.Fatalf("Reserve: %v", err)
+	}
+	if err := store.Reserve(key, ResourceTypeOrder); !errors.Is(err, ErrIdempotencyKeyInProgress) {
+		t.Errorf("second Reserve error = %v, want ErrIdempotencyKeyInProgress", err)
+	}
+	if id, err := store.Get(key, ResourceTypeOrder); err != nil || id != "" {
+		t.Errorf("Get of a reserved key = %q, %v, want nothing to replay yet", id, err)
+	}
+
+	if err := store.Commit(key, ResourceTypeOrder, "order-1"); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	if id, err := store.Get(key, ResourceTypeOrder); err != nil || id != "order-1" {
+		t.Errorf("Get of a committed key = %q, %v, want order-1 replayed", id, err)
+	}
+	if err := store.Reserve(key, ResourceTypeOrder); !errors.Is(err, ErrIdempotencyKeyInProgress) {
+		t.Errorf("Reserve of a committed key error = %v, want ErrIdempotencyKeyInProgress", err)
+	}
+	if err := store.Commit(key, ResourceTypeOrder, "order-2"); !errors.Is(err, ErrIdempotencyKeyNotReserved) {
+		t.Errorf("second Commit error = %v, want ErrIdempotencyKeyNotReserved", err)
+	}
+
+	// Releasing a committed key must not let it be reused
+	if err := store.Release(key, ResourceTypeOrder); err != nil {
+		t.Fatalf("Release of a committed key: %v", err)
+	}
+	if id, _ := store.Get(key, ResourceTypeOrder); id != "order-1" {
+		t.Errorf("Get after Release of a committed key = %q, want order-1 still replayed", id)
+	}
+
+	// A released reservation frees the key, and keys are scoped by resource type
+	if err := store.Reserve(key, ResourceTypeExecution); err != nil {
+		t.Fatalf("Reserve of the key for executions: %v", err)
+	}
+	if err := store.Release(key, ResourceTypeExecution); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if err := store.Reserve(key, ResourceTypeExecution); err != nil {
+		t.Errorf("Reserve after Release error = %v, want the key free again", err)
+	}
+}
+
+func TestDBIdempotencyStore(t *testing.T) {
+	service := newTestService(t)
+	testIdempotencyStore(t, NewDBIdempotencyStore(service.db.db))
+}
+
+func TestDBIdempotencyStoreReservationExpires(t *testing.T) {
+	service := newTestService(t)
+	clock := common.NewFakeClock(time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC))
+	store := &dbIdempotencyStore{db: service.db.db, clock: clock}
+
+	if err := store.Reserve("abandoned", ResourceTypeOrder); err != nil {
+		t.Fatalf("Reserve: %v", err)
+	}
+	// A request that died after reserving does not lock the key out for long
+	clock.Advance(idempotencyReservationTTL + time.Second)
+	if err := store.Reserve("abandoned", ResourceTypeOrder); err != nil {
+		t.Errorf("Reserve after the reservation expired error = %v, want the key reclaimed", err)
+	}
+}
+
+func TestRedisIdempotencyStore(t *testing.T) {
+	addr := newFakeRedis(t)
+	store := NewRedisIdempotencyStore(addr, "", 0)
+	testIdempotencyStore(t, store)
+}
+
+func TestCreateAndExecuteOrderWithRedisIdempotency(t *testing.T) {
+	config := DefaultConfig()
+	config.IdempotencyBackend = IdempotencyBackendRedis
+	config.RedisAddr = newFakeRedis(t)
+	service := newTestServiceWithConfig(t, config)
+
+	order := newTestOrder()
+	if created, err := service.CreateOrder(order, "redis-key"); err != nil || !created {
+		t.Fatalf("CreateOrder = %v, %v, want the order created", created, err)
+	}
+	replay := newTestOrder()
+	if created, err := service.CreateOrder(replay, "redis-key"); err != nil || created || replay.OrderID != order.OrderID {
+		t.Errorf("replayed CreateOrder = %v, %v for %q, want %q replayed", created, err, replay.OrderID, order.OrderID)
+	}
+
+	execution, err := service.ExecuteOrder(order.OrderID, testClientID, "redis-key")
+	if err != nil {
+		t.Fatalf("ExecuteOrder: %v", err)
+	}
+	replayed, err := service.ExecuteOrder(order.OrderID, testClientID, "redis-key")
+	if err != nil || replayed.ExecutionID != execution.ExecutionID {
+		t.Errorf("replayed ExecuteOrder = %v, %v, want execution %s replayed", replayed, err, execution.ExecutionID)
+	}
+}
+
+func TestExecuteOrderCommitsKeyWithTheExecution(t *testing.T) {
+	service := newTestService(t)
+	order := createTestOrder(t, service, newTestOrder())
+
+	execution, err := service.ExecuteOrder(order.OrderID, testClientID, "exec-key")
+	if err != nil {
+		t.Fatalf("ExecuteOrder: %v", err)
+	}
+	var record IdempotencyRecord
+	if err := service.db.db.Where("idempotency_key = ? AND resource_type = ?", "exec-key", ResourceTypeExecution).
+		First(&record).Error; err != nil {
+		t.Fatalf("failed to load idempotency record: %v", err)
+	}
+	if record.ResourceID != execution.ExecutionID {
+		t.Errorf("key committed to %q, want execution %s", record.ResourceID, execution.ExecutionID)
+	}
+}
+
+func TestExecutionRolledBackWhenKeyCannotBeCommitted(t *testing.T) {
+	service := newTestService(t)
+	order := createTestOrder(t, service, newTestOrder())
+	store := service.idempotency.(txIdempotencyStore)
+
+	// The key was never reserved, as if the reservation had been lost while routing
+	_, err := service.executeOrder(order.OrderID, testClientID, func(tx *gorm.DB, executionID string) error {
+		return store.CommitTx(tx, uuid.New().String(), ResourceTypeExecution, executionID)
+	})
+	if !errors.Is(err, ErrIdempotencyKeyNotReserved) {
+		t.Fatalf("executeOrder error = %v, want ErrIdempotencyKeyNotReserved", err)
+	}
+
+	var count int64
+	service.db.db.Model(&types.Execution{}).Count(&count)
+	if count != 0 {
+		t.Errorf("stored %d executions, want the execution rolled back with the key", count)
+	}
+	if stored, _ := service.db.GetOrder(order.OrderID); stored.Status != "PENDING" {
+		t.Errorf("order status = %s, want it still PENDING", stored.Status)
+	}
+}
+
+// newFakeRedis starts an in-memory server speaking the subset of Redis the idempotency store
+// uses, GET, SET with NX and the commit and release scripts, and returns its address
+// Expiry is not modelled
+func newFakeRedis(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	var (
+		mu   sync.Mutex
+		data = map[string]string{}
+	)
+	handle := func(args []string) string {
+		mu.Lock()
+		defer mu.Unlock()
+		switch strings.ToUpper(args[0]) {
+		case "GET":
+			value, ok := data[args[1]]
+			if !ok {
+				return "$-1\r\n"
+			}
+			return "$" + strconv.Itoa(len(value)) + "\r\n" + value + "\r\n"
+		case "SET":
+			if _, exists := data[args[1]]; exists && len(args) > 3 && args[3] == "NX" {
+				return "$-1\r\n"
+			}
+			data[args[1]] = args[2]
+			return "+OK\r\n"
+		case "EVAL":
+			// Both scripts act on KEYS[1] only while it holds ARGV[1]: the commit script,
+			// which has a value to set, sets it and the release script deletes the key
+			value, ok := data[args[3]]
+			held := ok && value == args[4]
+			if len(args) > 5 {
+				if !held {
+					return "$-1\r\n"
+				}
+				data[args[3]] = args[5]
+				return "+OK\r\n"
+			}
+			if held {
+				delete(data, args[3])
+				return ":1\r\n"
+			}
+			return ":0\r\n"
+		}
+		return "-ERR unknown command\r\n"
+	}
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				for {
+					args, err := readRedisCommand(reader)
+					if err != nil {
+						return
+					}
+					if _, err := io.WriteString(conn, handle(args)); err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+// readRedisCommand reads a command sent as an array of bulk strings
+func readRedisCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		header, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(header[1:]))
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
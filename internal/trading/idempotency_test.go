@@ -0,0 +1,163 @@
+package trading
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/klear-api/internal/types"
+	"github.com/ksred/klear-api/pkg/common"
+)
+
+func TestCreateOrderConcurrentSameKeyReturnsWinner(t *testing.T) {
+	service := newTestService(t)
+
+	const requests = 2
+	var (
+		wg      sync.WaitGroup
+		orders  [requests]*types.Order
+		created [requests]bool
+		errs    [requests]error
+	)
+	for i := 0; i < requests; i++ {
+		orders[i] = newTestOrder()
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			created[i], errs[i] = service.CreateOrder(orders[i], "same-key")
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("request %d failed: %v", i, err)
+		}
+	}
+	if orders[0].OrderID == "" || orders[0].OrderID != orders[1].OrderID {
+		t.Errorf("order IDs = %q and %q, want the same order", orders[0].OrderID, orders[1].OrderID)
+	}
+	if created[0] == created[1] {
+		t.Errorf("created = %v and %v, want exactly one request to create the order", created[0], created[1])
+	}
+
+	var count int64
+	if err := service.db.db.Model(&types.Order{}).Count(&count).Error; err != nil {
+		t.Fatalf("failed to count orders: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("stored %d orders, want 1", count)
+	}
+}
+
+func TestIdempotencyKeyScopedByResourceType(t *testing.T) {
+	service := newTestService(t)
+	const key = "shared-key"
+
+	order := newTestOrder()
+	if _, err := service.CreateOrder(order, key); err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+	// The same key on an execute does not replay the order creation
+	execution, err := service.ExecuteOrder(order.OrderID, testClientID, key)
+	if err != nil {
+		t.Fatalf("ExecuteOrder: %v", err)
+	}
+	if execution.OrderID != order.OrderID || execution.ExecutionID == "" {
+		t.Fatalf("execution = %+v, want a new execution of order %s", execution, order.OrderID)
+	}
+
+	// Each scope still replays its own resource
+	replayedExecution, err := service.ExecuteOrder(order.OrderID, testClientID, key)
+	if err != nil {
+		t.Fatalf("replayed ExecuteOrder: %v", err)
+	}
+	if replayedExecution.ExecutionID != execution.ExecutionID {
+		t.Errorf("replayed execution %s, want %s", replayedExecution.ExecutionID, execution.ExecutionID)
+	}
+	replayedOrder := newTestOrder()
+	created, err := service.CreateOrder(replayedOrder, key)
+	if err != nil {
+		t.Fatalf("replayed CreateOrder: %v", err)
+	}
+	if created || replayedOrder.OrderID != order.OrderID {
+		t.Errorf("replayed create made order %s (created %v), want the original %s", replayedOrder.OrderID, created, order.OrderID)
+	}
+}
+
+func TestCreateOrderHandlerReturns201ThenReplays200(t *testing.T) {
+	service := newTestService(t)
+	router := gin.New()
+	router.POST("/orders", authenticate(testClientID, "trade"), NewGinHandlers(service).CreateOrderHandler())
+
+	// decode returns the ID of the order in the response envelope
+	decode := func(body []byte) string {
+		var envelope struct {
+			Data types.Order `json:"data"`
+		}
+		if err := json.Unmarshal(body, &envelope); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		return envelope.Data.OrderID
+	}
+
+	created := performRequest(router, http.MethodPost, "/orders", newTestOrder(), "replay-key")
+	if created.Code != http.StatusCreated {
+		t.Fatalf("new order status = %d, want %d: %s", created.Code, http.StatusCreated, created.Body.String())
+	}
+	replayed := performRequest(router, http.MethodPost, "/orders", newTestOrder(), "replay-key")
+	if replayed.Code != http.StatusOK {
+		t.Fatalf("replayed order status = %d, want %d: %s", replayed.Code, http.StatusOK, replayed.Body.String())
+	}
+	if createdID, replayedID := decode(created.Body.Bytes()), decode(replayed.Body.Bytes()); createdID != replayedID {
+		t.Errorf("replay returned order %s, want the original %s", replayedID, createdID)
+	}
+}
+
+func TestGetOrderByIdempotencyKeyHandlerRecoversOrder(t *testing.T) {
+	service := newTestService(t)
+	clock := common.NewFakeClock(time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC))
+	service.SetClock(clock)
+	registerClient(t, service, "client-2", true)
+
+	order := newTestOrder()
+	if _, err := service.CreateOrder(order, "lost-response"); err != nil {
+		t.Fatalf("CreateOrder: %v", err)
+	}
+
+	lookup := func(clientID, key string) *httptest.ResponseRecorder {
+		router := gin.New()
+		router.GET("/orders/by-idempotency-key/:key", authenticate(clientID), NewGinHandlers(service).GetOrderByIdempotencyKeyHandler())
+		return performRequest(router, http.MethodGet, "/orders/by-idempotency-key/"+key, nil, "")
+	}
+
+	recorder := lookup(testClientID, "lost-response")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", recorder.Code, recorder.Body.String())
+	}
+	var envelope struct {
+		Data types.Order `json:"data"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if envelope.Data.OrderID != order.OrderID {
+		t.Errorf("recovered order %s, want %s", envelope.Data.OrderID, order.OrderID)
+	}
+
+	if recorder := lookup(testClientID, "never-used"); recorder.Code != http.StatusNotFound {
+		t.Errorf("unknown key status = %d, want 404", recorder.Code)
+	}
+	// Another client's key does not reveal its order
+	if recorder := lookup("client-2", "lost-response"); recorder.Code != http.StatusNotFound {
+		t.Errorf("another client's key status = %d, want 404", recorder.Code)
+	}
+	clock.Advance(25 * time.Hour)
+	if recorder := lookup(testClientID, "lost-response"); recorder.Code != http.StatusNotFound {
+		t.Errorf("expired key status = %d, want 404", recorder.Code)
+	}
+}
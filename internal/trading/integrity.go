@@ -0,0 +1,46 @@
+package trading
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/klear-api/internal/types"
+	"github.com/ksred/klear-api/pkg/response"
+	"github.com/rs/zerolog/log"
+)
+
+// OrphanedExecutionsReport lists executions that reference an order that does not exist
+type OrphanedExecutionsReport struct {
+	Count      int               `json:"count"`
+	Executions []types.Execution `json:"executions"`
+}
+
+// FindOrphanedExecutions finds executions whose order ID does not resolve to an order,
+// which can be left behind by partial failures
+func (s *Service) FindOrphanedExecutions() (*OrphanedExecutionsReport, error) {
+	executions, err := s.db.FindOrphanedExecutions()
+	if err != nil {
+		log.Error().Err(err).Msg("failed to find orphaned executions")
+		return nil, err
+	}
+	if len(executions) > 0 {
+		log.Warn().Int("count", len(executions)).Msg("found orphaned executions")
+	}
+
+	return &OrphanedExecutionsReport{
+		Count:      len(executions),
+		Executions: executions,
+	}, nil
+}
+
+// GetOrphanedExecutionsHandler handles GET requests for executions without an order
+// Requires internal authentication
+func (h *GinHandlers) GetOrphanedExecutionsHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		report, err := h.service.FindOrphanedExecutions()
+		if err != nil {
+			response.InternalError(c, err.Error())
+			return
+		}
+
+		response.Success(c, report)
+	}
+}
@@ -0,0 +1,54 @@
+package trading
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/klear-api/internal/types"
+)
+
+func TestGetOrphanedExecutionsHandlerFindsDanglingOrderIDs(t *testing.T) {
+	service := newTestService(t)
+	executeTestOrder(t, service, newTestOrder())
+
+	// An execution of a soft-deleted order still has its order
+	deletedOrder := newTestOrder()
+	executeTestOrder(t, service, deletedOrder)
+	if err := service.db.db.Where("order_id = ?", deletedOrder.OrderID).Delete(&types.Order{}).Error; err != nil {
+		t.Fatalf("failed to delete order: %v", err)
+	}
+
+	orphan := &types.Execution{
+		ExecutionID:   "EXEC-orphan",
+		OrderID:       "missing-order",
+		TotalQuantity: 10,
+		AveragePrice:  150,
+		Side:          "BUY",
+		Status:        "COMPLETED",
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+	}
+	if err := service.db.db.Create(orphan).Error; err != nil {
+		t.Fatalf("failed to seed execution: %v", err)
+	}
+
+	router := gin.New()
+	router.GET("/integrity/orphaned-executions", NewGinHandlers(service).GetOrphanedExecutionsHandler())
+	recorder := performRequest(router, http.MethodGet, "/integrity/orphaned-executions", nil, "")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", recorder.Code, recorder.Body.String())
+	}
+	var envelope struct {
+		Data OrphanedExecutionsReport `json:"data"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	report := envelope.Data
+	if report.Count != 1 || len(report.Executions) != 1 || report.Executions[0].ExecutionID != orphan.ExecutionID {
+		t.Errorf("report = %+v, want only the execution of the missing order", report)
+	}
+}
@@ -0,0 +1,160 @@
+package trading
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ksred/klear-api/internal/types"
+	"github.com/ksred/klear-api/pkg/common"
+)
+
+func newListRouter(service *Service) *gin.Engine {
+	handlers := NewGinHandlers(service)
+	router := gin.New()
+	router.POST("/orders", authenticate(testClientID, "trade"), handlers.CreateOrderHandler())
+	router.GET("/orders", authenticate(testClientID), handlers.ListOrdersHandler())
+	router.GET("/orders/:order_id", authenticate(testClientID), handlers.GetOrderStatusHandler())
+	return router
+}
+
+// listOrders fetches a page of the test client's orders from the given path, failing the test on error
+func listOrders(t *testing.T, router http.Handler, path string) OrderPage {
+	t.Helper()
+	recorder := performRequest(router, http.MethodGet, path, nil, "")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("GET %s status = %d, want 200: %s", path, recorder.Code, recorder.Body.String())
+	}
+	var envelope struct {
+		Data OrderPage `json:"data"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return envelope.Data
+}
+
+func TestListOrdersFiltersByClientTag(t *testing.T) {
+	service := newTestService(t)
+	router := newListRouter(service)
+
+	tagged := make([]*types.Order, 0, 2)
+	for _, tag := range []string{" momentum ", "momentum", "mean-reversion", ""} {
+		order := newTestOrder()
+		order.ClientTag = tag
+		createTestOrder(t, service, order)
+		if order.ClientTag == "momentum" {
+			tagged = append(tagged, order)
+		}
+	}
+
+	page := listOrders(t, router, "/orders?tag=momentum")
+	if len(page.Orders) != len(tagged) {
+		t.Fatalf("listed %d momentum orders, want %d", len(page.Orders), len(tagged))
+	}
+	for _, order := range page.Orders {
+		if order.ClientTag != "momentum" {
+			t.Errorf("listed order tagged %q, want momentum", order.ClientTag)
+		}
+	}
+	if all := listOrders(t, router, "/orders"); len(all.Orders) != 4 {
+		t.Errorf("listed %d orders without a tag filter, want 4", len(all.Orders))
+	}
+
+	// The status response echoes the tag
+	recorder := performRequest(router, http.MethodGet, "/orders/"+tagged[0].OrderID, nil, "")
+	if !strings.Contains(recorder.Body.String(), `"client_tag":"momentum"`) {
+		t.Errorf("order status = %s, want the client tag echoed", recorder.Body.String())
+	}
+}
+
+func TestCreateOrderRejectsOverlongClientTag(t *testing.T) {
+	service := newTestService(t)
+	router := newListRouter(service)
+
+	order := newTestOrder()
+	order.ClientTag = strings.Repeat("x", maxClientTagLength+1)
+	recorder := performRequest(router, http.MethodPost, "/orders", order, uuid.New().String())
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400: %s", recorder.Code, recorder.Body.String())
+	}
+
+	order = newTestOrder()
+	order.ClientTag = strings.Repeat("x", maxClientTagLength)
+	if recorder := performRequest(router, http.MethodPost, "/orders", order, uuid.New().String()); recorder.Code != http.StatusCreated {
+		t.Errorf("tag at the cap: status = %d, want 201: %s", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestListOrdersCursorPagesWithoutDuplicatesOrGaps(t *testing.T) {
+	service := newTestService(t)
+	router := newListRouter(service)
+
+	// Pairs of orders share a creation time, so pages must break ties on the row ID
+	clock := common.NewFakeClock(time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC))
+	service.SetClock(clock)
+	created := map[string]bool{}
+	for i := 0; i < 7; i++ {
+		if i%2 == 0 {
+			clock.Advance(time.Second)
+		}
+		created[createTestOrder(t, service, newTestOrder()).OrderID] = true
+	}
+
+	seen := map[string]bool{}
+	var previous *types.Order
+	path, pages := "/orders?limit=3", 0
+	for {
+		page := listOrders(t, router, path)
+		pages++
+		for i := range page.Orders {
+			order := &page.Orders[i]
+			if seen[order.OrderID] {
+				t.Errorf("order %s listed twice", order.OrderID)
+			}
+			seen[order.OrderID] = true
+			if previous != nil && (order.CreatedAt.After(previous.CreatedAt) ||
+				(order.CreatedAt.Equal(previous.CreatedAt) && order.ID > previous.ID)) {
+				t.Errorf("order %s listed after the older order %s", order.OrderID, previous.OrderID)
+			}
+			previous = order
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		path = "/orders?limit=3&cursor=" + page.NextCursor
+	}
+
+	if pages != 3 {
+		t.Errorf("listed %d pages of 3, want 3 for 7 orders", pages)
+	}
+	if len(seen) != len(created) {
+		t.Errorf("listed %d distinct orders, want all %d", len(seen), len(created))
+	}
+	for id := range created {
+		if !seen[id] {
+			t.Errorf("order %s never listed", id)
+		}
+	}
+
+	if recorder := performRequest(router, http.MethodGet, "/orders?cursor=not-a-cursor", nil, ""); recorder.Code != http.StatusBadRequest {
+		t.Errorf("invalid cursor status = %d, want 400", recorder.Code)
+	}
+}
+
+func TestListOrdersDefaultsTo50AndClampsLimit(t *testing.T) {
+	service := newTestService(t)
+	router := newListRouter(service)
+	createTestOrder(t, service, newTestOrder())
+
+	if page := listOrders(t, router, "/orders"); page.Limit != 50 {
+		t.Errorf("default limit = %d, want 50", page.Limit)
+	}
+	if page := listOrders(t, router, "/orders?limit=100000"); page.Limit != 500 {
+		t.Errorf("limit=100000 served %d, want it clamped to 500", page.Limit)
+	}
+}
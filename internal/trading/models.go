@@ -3,21 +3,32 @@ package trading
 import (
 	"time"
 
+	"github.com/ksred/klear-api/internal/types"
 	"gorm.io/gorm"
 )
 
 type Order struct {
-	gorm.Model `json:"-"`
-	OrderID    string    `gorm:"uniqueIndex" json:"order_id"`
-	ClientID   string    `json:"client_id"`
-	Symbol     string    `json:"symbol"`
-	Side       string    `json:"side"`       // BUY or SELL
-	OrderType  string    `json:"order_type"` // MARKET or LIMIT
-	Quantity   float64   `json:"quantity"`
-	Price      float64   `json:"price"`
-	Status     string    `json:"status"` // PENDING, FILLED, CANCELLED
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	gorm.Model      `json:"-"`
+	OrderID         string     `gorm:"uniqueIndex" json:"order_id"`
+	ClientID        string     `json:"client_id"`
+	Symbol          string     `json:"symbol"`
+	Side            string     `json:"side"`       // BUY or SELL
+	OrderType       string     `json:"order_type"` // MARKET or LIMIT
+	Quantity        float64    `json:"quantity"`
+	Price           float64    `json:"price"`
+	QuantityUnits   int64      `json:"-"`                                        // Quantity as exact integer units of 10^-8
+	PriceUnits      int64      `json:"-"`                                        // Price as exact integer units of 10^-8
+	Currency        string     `json:"currency"`                                 // ISO 4217 code, defaults to the configured currency
+	PostOnly        bool       `json:"post_only,omitempty"`                      // Limit order that must never take liquidity
+	ReduceOnly      bool       `json:"reduce_only,omitempty"`                    // Order that may only reduce the client's position in the symbol
+	DisplayQuantity float64    `json:"display_quantity,omitempty"`               // Iceberg slice size; 0 exposes the full quantity
+	TimeInForce     string     `json:"time_in_force"`                            // GTC or GTD
+	ExpiresAt       *time.Time `gorm:"index" json:"expires_at,omitempty"`        // Expiry of GTD orders
+	Status          string     `json:"status"`                                   // PENDING, PARTIALLY_FILLED, FILLED, CANCELLED, EXPIRED
+	ReplacesOrderID string     `gorm:"index" json:"replaces_order_id,omitempty"` // Order replaced via cancel/replace
+	ClientTag       string     `gorm:"index" json:"client_tag,omitempty"`        // Free-form client label for correlation, e.g. strategy name
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
 }
 
 type Execution struct {
@@ -32,10 +43,24 @@ type Execution struct {
 	UpdatedAt   time.Time `json:"updated_at"`
 }
 
+// Resource types an idempotency key can be scoped to. A key may be reused once per type
+const (
+	ResourceTypeOrder     = "order"
+	ResourceTypeExecution = "execution"
+)
+
 type IdempotencyRecord struct {
 	gorm.Model
-	IdempotencyKey string    `gorm:"uniqueIndex" json:"idempotency_key"`
+	IdempotencyKey string    `gorm:"uniqueIndex:idx_idempotency_key_resource_type" json:"idempotency_key"`
 	ResourceID     string    `json:"resource_id"`
-	ResourceType   string    `json:"resource_type"`
+	ResourceType   string    `gorm:"uniqueIndex:idx_idempotency_key_resource_type" json:"resource_type"`
 	ExpiresAt      time.Time `json:"expires_at"`
 }
+
+// OrderPage is one page of a client's order listing
+// NextCursor is set when more orders follow; pass it back as the cursor to fetch them
+type OrderPage struct {
+	Orders     []types.Order `json:"orders"`
+	Limit      int           `json:"limit"` // Page size applied, after defaulting and clamping
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
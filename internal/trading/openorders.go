@@ -0,0 +1,48 @@
+package trading
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+var ErrTooManyOpenOrders = errors.New("client has too many open orders")
+
+// openOrderStatuses are the statuses of orders still working, which count towards the open order cap
+var openOrderStatuses = []string{"PENDING", "PARTIALLY_FILLED"}
+
+// maxOpenOrdersFor returns the open order cap of the client: its own limit when set,
+// otherwise the configured default. 0 means no cap
+func (s *Service) maxOpenOrdersFor(clientID string) (int, error) {
+	client, err := s.db.GetClient(clientID)
+	if err != nil {
+		return 0, err
+	}
+	if client != nil && client.MaxOpenOrders > 0 {
+		return client.MaxOpenOrders, nil
+	}
+	return s.config.MaxOpenOrders, nil
+}
+
+// openOrderCheck returns the check that the client has room for another open order, to run in the
+// new order's transaction so that concurrent orders cannot both take the client's last open slot
+func (s *Service) openOrderCheck(clientID string) (func(tx *gorm.DB) error, error) {
+	limit, err := s.maxOpenOrdersFor(clientID)
+	if err != nil {
+		return nil, err
+	}
+	return func(tx *gorm.DB) error {
+		if limit <= 0 {
+			return nil
+		}
+		open, err := countOpenOrders(tx, clientID)
+		if err != nil {
+			return err
+		}
+		if open >= int64(limit) {
+			return fmt.Errorf("%w: %d open orders, the limit is %d", ErrTooManyOpenOrders, open, limit)
+		}
+		return nil
+	}, nil
+}
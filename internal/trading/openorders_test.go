@@ -0,0 +1,96 @@
+package trading
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ksred/klear-api/internal/types"
+)
+
+func TestOpenOrdersUpToCapThenNextRejected(t *testing.T) {
+	config := DefaultConfig()
+	config.MaxOpenOrders = 3
+	service := newTestServiceWithConfig(t, config)
+	registerClient(t, service, "client-2", true)
+	router := gin.New()
+	router.POST("/orders", authenticate(testClientID, "trade"), NewGinHandlers(service).CreateOrderHandler())
+
+	// The body names another client, but orders count against the authenticated one
+	order := newTestOrder()
+	order.ClientID = "client-2"
+	for i := 0; i < config.MaxOpenOrders; i++ {
+		recorder := performRequest(router, http.MethodPost, "/orders", order, uuid.New().String())
+		if recorder.Code != http.StatusCreated {
+			t.Fatalf("order %d status = %d, want 201: %s", i+1, recorder.Code, recorder.Body.String())
+		}
+	}
+	recorder := performRequest(router, http.MethodPost, "/orders", order, uuid.New().String())
+	if recorder.Code != http.StatusTooManyRequests || errorCode(t, recorder) != RejectCodeOpenOrders {
+		t.Fatalf("order over the cap = %d %s, want 429 %s", recorder.Code, recorder.Body.String(), RejectCodeOpenOrders)
+	}
+
+	// Other clients have their own allowance
+	other := newTestOrder()
+	other.ClientID = "client-2"
+	createTestOrder(t, service, other)
+
+	// Filling an open order frees its slot
+	var open types.Order
+	if err := service.db.db.Where("client_id = ? AND status = ?", testClientID, "PENDING").First(&open).Error; err != nil {
+		t.Fatalf("failed to load an open order: %v", err)
+	}
+	if _, err := service.ExecuteOrder(open.OrderID, testClientID, uuid.New().String()); err != nil {
+		t.Fatalf("ExecuteOrder: %v", err)
+	}
+	createTestOrder(t, service, newTestOrder())
+}
+
+func TestClientOpenOrderLimitOverridesDefault(t *testing.T) {
+	config := DefaultConfig()
+	config.MaxOpenOrders = 5
+	service := newTestServiceWithConfig(t, config)
+	if err := service.RegisterClient(&types.Client{ClientID: testClientID, Name: testClientID, Active: true, MaxOpenOrders: 1}); err != nil {
+		t.Fatalf("RegisterClient: %v", err)
+	}
+
+	createTestOrder(t, service, newTestOrder())
+	if _, err := service.CreateOrder(newTestOrder(), uuid.New().String()); !errors.Is(err, ErrTooManyOpenOrders) {
+		t.Errorf("second order error = %v, want ErrTooManyOpenOrders at the client's own limit of 1", err)
+	}
+}
+
+func TestConcurrentOrdersNeverExceedOpenOrderCap(t *testing.T) {
+	config := DefaultConfig()
+	config.MaxOpenOrders = 3
+	service := newTestServiceWithConfig(t, config)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := service.CreateOrder(newTestOrder(), uuid.New().String())
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	created := 0
+	for err := range errs {
+		switch {
+		case err == nil:
+			created++
+		case !errors.Is(err, ErrTooManyOpenOrders):
+			t.Errorf("CreateOrder error = %v, want success or ErrTooManyOpenOrders", err)
+		}
+	}
+	if created != config.MaxOpenOrders {
+		t.Errorf("created %d orders concurrently, want exactly the cap of %d", created, config.MaxOpenOrders)
+	}
+}
@@ -0,0 +1,68 @@
+package trading
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/ksred/klear-api/internal/exchange"
+)
+
+func TestThinLiquidityLeavesOrderPartiallyFilled(t *testing.T) {
+	service := newTestService(t)
+	// A single venue with 40 of depth can fill only part of a 100 share order
+	useSimulatedVenues(t, []*exchange.Exchange{simulatedVenue("THIN", 40)})
+
+	order := createTestOrder(t, service, newTestOrder())
+	execution, err := service.ExecuteOrder(order.OrderID, testClientID, uuid.New().String())
+	if err != nil {
+		t.Fatalf("ExecuteOrder: %v", err)
+	}
+	if execution.TotalQuantity != 40 {
+		t.Errorf("execution quantity = %v, want the venue's 40 of depth", execution.TotalQuantity)
+	}
+	stored, err := service.db.GetOrder(order.OrderID)
+	if err != nil {
+		t.Fatalf("GetOrder: %v", err)
+	}
+	if stored.Status != "PARTIALLY_FILLED" || stored.FilledQuantity != 40 || stored.Quantity != 100 {
+		t.Fatalf("order = %s with %v of %v filled, want PARTIALLY_FILLED with 40 of 100", stored.Status, stored.FilledQuantity, stored.Quantity)
+	}
+	open, err := countOpenOrders(service.db.db, testClientID)
+	if err != nil {
+		t.Fatalf("countOpenOrders: %v", err)
+	}
+	if open != 1 {
+		t.Errorf("open orders = %d, want the partially filled order counted", open)
+	}
+
+	// Executing again routes only the remaining 60, which fills the order
+	exchange.SetExchanges([]*exchange.Exchange{simulatedVenue("DEEP", 1000)})
+	rest, err := service.ExecuteOrder(order.OrderID, testClientID, uuid.New().String())
+	if err != nil {
+		t.Fatalf("ExecuteOrder of the remainder: %v", err)
+	}
+	if rest.TotalQuantity != 60 {
+		t.Errorf("remainder execution quantity = %v, want 60", rest.TotalQuantity)
+	}
+	stored, err = service.db.GetOrder(order.OrderID)
+	if err != nil {
+		t.Fatalf("GetOrder: %v", err)
+	}
+	if stored.Status != "FILLED" || stored.FilledQuantity != 100 {
+		t.Errorf("order = %s with %v filled, want FILLED with 100", stored.Status, stored.FilledQuantity)
+	}
+
+	history, err := service.GetOrderHistory(order.OrderID, testClientID)
+	if err != nil {
+		t.Fatalf("GetOrderHistory: %v", err)
+	}
+	var statuses []string
+	for _, transition := range history.Transitions {
+		statuses = append(statuses, transition.To)
+	}
+	want := []string{"PENDING", "PARTIALLY_FILLED", "FILLED"}
+	if strings.Join(statuses, ",") != strings.Join(want, ",") {
+		t.Errorf("history = %v, want %v", statuses, want)
+	}
+}
@@ -0,0 +1,38 @@
+package trading
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/ksred/klear-api/internal/pricefeed"
+	"github.com/ksred/klear-api/internal/types"
+)
+
+var ErrPriceOutOfBand = errors.New("order price is outside the price band")
+
+// SetPriceFeed sets the feed limit order prices are checked against when Config.PriceBand is set
+func (s *Service) SetPriceFeed(feed pricefeed.PriceFeed) {
+	s.priceFeed = feed
+}
+
+// checkPriceBand rejects a limit order priced further from the symbol's reference price than
+// the configured band, catching fat-finger prices before they reach a venue
+// Market orders take the market price and are exempt. Symbols without a reference price
+// cannot be banded and are let through
+func (s *Service) checkPriceBand(order *types.Order) error {
+	if s.config.PriceBand <= 0 || s.priceFeed == nil || order.OrderType != "LIMIT" {
+		return nil
+	}
+	reference, err := s.priceFeed.LastPrice(order.Symbol)
+	if err != nil || reference <= 0 {
+		return nil
+	}
+
+	deviation := math.Abs(order.Price-reference) / reference
+	if deviation > s.config.PriceBand {
+		return fmt.Errorf("%w: price %v is more than %v%% from the reference price %v of %s",
+			ErrPriceOutOfBand, order.Price, s.config.PriceBand*100, reference, order.Symbol)
+	}
+	return nil
+}
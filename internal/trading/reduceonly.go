@@ -0,0 +1,43 @@
+package trading
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/ksred/klear-api/internal/types"
+	"github.com/ksred/klear-api/pkg/money"
+)
+
+var (
+	ErrReduceOnlyIncreases = errors.New("reduce-only order would increase the position")
+	ErrReduceOnlyFlips     = errors.New("reduce-only order would flip the position")
+)
+
+// checkReduceOnly rejects a reduce-only order that would increase the client's net position
+// in the symbol, or take it through zero to the other side. Orders without the flag pass
+func (s *Service) checkReduceOnly(order *types.Order) error {
+	if !order.ReduceOnly || (order.Side != "BUY" && order.Side != "SELL") {
+		return nil
+	}
+
+	position, err := s.db.GetNetPosition(order.ClientID, order.Symbol)
+	if err != nil {
+		return fmt.Errorf("failed to fetch net position: %w", err)
+	}
+
+	// A reducing order trades against the position: a SELL against a long, a BUY against a short
+	reduces := (order.Side == "SELL" && position > 0) || (order.Side == "BUY" && position < 0)
+	if !reduces {
+		return fmt.Errorf("%w: %s position is %g", ErrReduceOnlyIncreases, order.Symbol, position)
+	}
+	if money.ToUnits(order.Quantity) > money.ToUnits(math.Abs(position)) {
+		return fmt.Errorf("%w: quantity %g exceeds %s position of %g", ErrReduceOnlyFlips, order.Quantity, order.Symbol, position)
+	}
+	return nil
+}
+
+// isReduceOnlyViolation reports whether the error is a reduce-only order rejected against the current position
+func isReduceOnlyViolation(err error) bool {
+	return errors.Is(err, ErrReduceOnlyIncreases) || errors.Is(err, ErrReduceOnlyFlips)
+}
@@ -0,0 +1,97 @@
+package trading
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ksred/klear-api/internal/types"
+)
+
+// newReduceOnlySell returns a reduce-only sell of the test client
+func newReduceOnlySell(quantity float64) *types.Order {
+	order := newTestOrder()
+	order.Side = "SELL"
+	order.Quantity = quantity
+	order.ReduceOnly = true
+	return order
+}
+
+func TestReduceOnlyOrderClosesThePosition(t *testing.T) {
+	service := newTestService(t)
+	executeTestOrder(t, service, newTestOrder())
+
+	executeTestOrder(t, service, newReduceOnlySell(100))
+
+	position, err := service.db.GetNetPosition(testClientID, "AAPL")
+	if err != nil {
+		t.Fatalf("GetNetPosition: %v", err)
+	}
+	if position != 0 {
+		t.Errorf("net position = %v, want the reduce-only sell to close it to 0", position)
+	}
+}
+
+func TestReduceOnlyOrderRejectedWhenItWouldFlipOrIncrease(t *testing.T) {
+	service := newTestService(t)
+	executeTestOrder(t, service, newTestOrder())
+
+	increase := newTestOrder()
+	increase.ReduceOnly = true
+	tests := []struct {
+		name    string
+		order   *types.Order
+		wantErr error
+	}{
+		{"sell through zero", newReduceOnlySell(150), ErrReduceOnlyFlips},
+		{"buy against a long", increase, ErrReduceOnlyIncreases},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := service.CreateOrder(tt.order, uuid.New().String()); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CreateOrder error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	position, err := service.db.GetNetPosition(testClientID, "AAPL")
+	if err != nil {
+		t.Fatalf("GetNetPosition: %v", err)
+	}
+	if position != 100 {
+		t.Errorf("net position = %v, want the rejected orders to leave it at 100", position)
+	}
+}
+
+func TestCreateOrderHandlerPlacesOrderForAuthenticatedClient(t *testing.T) {
+	service := newTestService(t)
+	registerClient(t, service, "client-2", true)
+
+	router := gin.New()
+	router.POST("/orders", authenticate(testClientID, "trade"), NewGinHandlers(service).CreateOrderHandler())
+
+	// A body naming another client must not place an order on that client's book
+	order := newTestOrder()
+	order.ClientID = "client-2"
+	recorder := performRequest(router, http.MethodPost, "/orders", order, uuid.New().String())
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d: %s", recorder.Code, http.StatusCreated, recorder.Body.String())
+	}
+
+	var envelope struct {
+		Data types.Order `json:"data"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	stored, err := service.db.GetOrder(envelope.Data.OrderID)
+	if err != nil {
+		t.Fatalf("GetOrder: %v", err)
+	}
+	if stored.ClientID != testClientID {
+		t.Errorf("order placed for %q, want the authenticated client %q", stored.ClientID, testClientID)
+	}
+}
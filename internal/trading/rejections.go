@@ -0,0 +1,91 @@
+package trading
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/klear-api/internal/exchange"
+	"github.com/ksred/klear-api/internal/types"
+	"github.com/ksred/klear-api/pkg/response"
+)
+
+// Error codes reported when an order is rejected on creation or replacement
+const (
+	RejectCodeInvalidOrder  = "INVALID_ORDER"           // Malformed or inconsistent order details
+	RejectCodeRiskLimit     = "RISK_LIMIT_EXCEEDED"     // Quantity or price beyond the configured risk limits
+	RejectCodeNotionalLimit = "NOTIONAL_LIMIT_EXCEEDED" // Price × quantity beyond the configured notional limit
+	RejectCodeLotSize       = "BELOW_LOT_SIZE"          // Quantity smaller than the symbol's lot size
+	RejectCodeTickSize      = "OFF_TICK_SIZE"           // Limit price not a whole number of the symbol's ticks
+	RejectCodeSymbolHalted  = "SYMBOL_HALTED"           // Trading in the symbol is halted
+	RejectCodeReduceOnly    = "REDUCE_ONLY_VIOLATION"   // A reduce-only order would increase or flip the position
+	RejectCodePriceBand     = "PRICE_OUT_OF_BAND"       // A limit price too far from the symbol's reference price
+	RejectCodeOpenOrders    = "TOO_MANY_OPEN_ORDERS"    // The client already has its maximum number of open orders
+)
+
+// checkLotSize rejects an order too small to ever fill a single lot of its symbol
+func (s *Service) checkLotSize(order *types.Order) error {
+	lotSize := s.config.LotSizeFor(order.Symbol)
+	if lotSize > 0 && order.Quantity < lotSize {
+		return fmt.Errorf("%w: quantity %v is smaller than the lot size %v of %s",
+			exchange.ErrBelowLotSize, order.Quantity, lotSize, order.Symbol)
+	}
+	return nil
+}
+
+// tickTolerance absorbs the float error of dividing a price by its tick size
+const tickTolerance = 1e-9
+
+// checkTickSize rejects a limit order whose price is not a whole number of ticks of its symbol
+// Market orders carry no price and are exempt
+func (s *Service) checkTickSize(order *types.Order) error {
+	tickSize := s.config.TickSizeFor(order.Symbol)
+	if tickSize <= 0 || order.OrderType != "LIMIT" {
+		return nil
+	}
+	ticks := order.Price / tickSize
+	if math.Abs(ticks-math.Round(ticks)) > tickTolerance*math.Max(1, math.Abs(ticks)) {
+		return fmt.Errorf("%w: price %v is not a multiple of the tick size %v of %s",
+			ErrPriceOffTick, order.Price, tickSize, order.Symbol)
+	}
+	return nil
+}
+
+// orderRejection returns the status and error code an order rejection is reported with
+// Invalid order details are a 400; well-formed orders that breach a limit are a 422.
+// ok is false for errors that are not order rejections
+func orderRejection(err error) (status int, code string, ok bool) {
+	switch {
+	case errors.Is(err, ErrSymbolHalted):
+		return http.StatusServiceUnavailable, RejectCodeSymbolHalted, true
+	case isReduceOnlyViolation(err):
+		return http.StatusConflict, RejectCodeReduceOnly, true
+	case errors.Is(err, ErrNotionalOutOfBounds):
+		return http.StatusUnprocessableEntity, RejectCodeNotionalLimit, true
+	case errors.Is(err, ErrQuantityOutOfBounds) || errors.Is(err, ErrPriceOutOfBounds):
+		return http.StatusUnprocessableEntity, RejectCodeRiskLimit, true
+	case errors.Is(err, exchange.ErrBelowLotSize):
+		return http.StatusUnprocessableEntity, RejectCodeLotSize, true
+	case errors.Is(err, ErrPriceOffTick):
+		return http.StatusUnprocessableEntity, RejectCodeTickSize, true
+	case errors.Is(err, ErrTooManyOpenOrders):
+		return http.StatusTooManyRequests, RejectCodeOpenOrders, true
+	case errors.Is(err, ErrPriceOutOfBand):
+		return http.StatusBadRequest, RejectCodePriceBand, true
+	case isOrderValidationError(err):
+		return http.StatusBadRequest, RejectCodeInvalidOrder, true
+	}
+	return 0, "", false
+}
+
+// respondOrderRejection reports an order rejection with its status and code
+// Returns false, sending nothing, if the error is not an order rejection
+func respondOrderRejection(c *gin.Context, err error) bool {
+	status, code, ok := orderRejection(err)
+	if ok {
+		response.WithCode(c, status, code, err.Error())
+	}
+	return ok
+}
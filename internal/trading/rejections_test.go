@@ -0,0 +1,187 @@
+package trading
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ksred/klear-api/internal/pricefeed"
+	"github.com/ksred/klear-api/internal/types"
+	"github.com/ksred/klear-api/pkg/response"
+)
+
+func TestCreateOrderRejectionStatusAndCode(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     func(*Config)
+		setup      func(*testing.T, *Service)
+		order      func(*types.Order)
+		wantStatus int
+		wantCode   string
+	}{
+		{
+			name:       "invalid side",
+			order:      func(o *types.Order) { o.Side = "HOLD" },
+			wantStatus: http.StatusBadRequest,
+			wantCode:   RejectCodeInvalidOrder,
+		},
+		{
+			name:       "quantity over risk limit",
+			config:     func(c *Config) { c.MaxOrderQuantity = 50 },
+			wantStatus: http.StatusUnprocessableEntity,
+			wantCode:   RejectCodeRiskLimit,
+		},
+		{
+			name:       "notional over limit",
+			config:     func(c *Config) { c.MaxOrderNotional = 1000 },
+			wantStatus: http.StatusUnprocessableEntity,
+			wantCode:   RejectCodeNotionalLimit,
+		},
+		{
+			name:       "below lot size",
+			config:     func(c *Config) { c.LotSizes = map[string]float64{"AAPL": 500} },
+			wantStatus: http.StatusUnprocessableEntity,
+			wantCode:   RejectCodeLotSize,
+		},
+		{
+			name:       "off tick size",
+			config:     func(c *Config) { c.TickSizes = map[string]float64{"AAPL": 0.05} },
+			order:      func(o *types.Order) { o.Price = 150.03 },
+			wantStatus: http.StatusUnprocessableEntity,
+			wantCode:   RejectCodeTickSize,
+		},
+		{
+			name:       "outside price band",
+			config:     func(c *Config) { c.PriceBand = 0.1 },
+			setup:      func(t *testing.T, s *Service) { s.SetPriceFeed(pricefeed.NewMockFeed()) },
+			wantStatus: http.StatusBadRequest,
+			wantCode:   RejectCodePriceBand,
+		},
+		{
+			name:       "symbol halted",
+			setup:      func(t *testing.T, s *Service) { s.halts.Halt("AAPL") },
+			wantStatus: http.StatusServiceUnavailable,
+			wantCode:   RejectCodeSymbolHalted,
+		},
+		{
+			name:       "reduce-only without a position",
+			order:      func(o *types.Order) { o.ReduceOnly = true },
+			wantStatus: http.StatusConflict,
+			wantCode:   RejectCodeReduceOnly,
+		},
+		{
+			name:       "too many open orders",
+			config:     func(c *Config) { c.MaxOpenOrders = 1 },
+			setup:      func(t *testing.T, s *Service) { createTestOrder(t, s, newTestOrder()) },
+			wantStatus: http.StatusTooManyRequests,
+			wantCode:   RejectCodeOpenOrders,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := DefaultConfig()
+			if tt.config != nil {
+				tt.config(&config)
+			}
+			service := newTestServiceWithConfig(t, config)
+			if tt.setup != nil {
+				tt.setup(t, service)
+			}
+			router := gin.New()
+			router.POST("/orders", authenticate(testClientID, "trade"), NewGinHandlers(service).CreateOrderHandler())
+
+			order := newTestOrder()
+			if tt.order != nil {
+				tt.order(order)
+			}
+			recorder := performRequest(router, http.MethodPost, "/orders", order, uuid.New().String())
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, tt.wantStatus, recorder.Body.String())
+			}
+			if code := errorCode(t, recorder); code != tt.wantCode {
+				t.Errorf("code = %s, want %s", code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestTickSizeAcceptsWholeTicksAndMarketOrders(t *testing.T) {
+	config := DefaultConfig()
+	config.DefaultTickSize = 0.01
+	config.TickSizes = map[string]float64{"AAPL": 0.05}
+	service := newTestServiceWithConfig(t, config)
+
+	onTick := newTestOrder()
+	onTick.Price = 150.05
+	createTestOrder(t, service, onTick)
+
+	// Other symbols use the default tick size
+	cents := newTestOrder()
+	cents.Symbol = "MSFT"
+	cents.Price = 410.37
+	createTestOrder(t, service, cents)
+
+	market := newTestOrder()
+	market.OrderType = "MARKET"
+	market.Price = 0
+	createTestOrder(t, service, market)
+}
+
+func TestPriceBandAcceptsOrdersWithinBandAndMarketOrders(t *testing.T) {
+	config := DefaultConfig()
+	config.PriceBand = 0.1
+	service := newTestServiceWithConfig(t, config)
+	service.SetPriceFeed(pricefeed.NewMockFeed())
+
+	// The mock feed's AAPL reference price is 190, so the band runs from 171 to 209
+	for _, price := range []float64{171, 190, 209} {
+		order := newTestOrder()
+		order.Price = price
+		createTestOrder(t, service, order)
+	}
+	for _, price := range []float64{170, 210} {
+		order := newTestOrder()
+		order.Price = price
+		if _, err := service.CreateOrder(order, uuid.New().String()); !errors.Is(err, ErrPriceOutOfBand) {
+			t.Errorf("limit order at %v error = %v, want ErrPriceOutOfBand", price, err)
+		}
+	}
+
+	market := newTestOrder()
+	market.OrderType = "MARKET"
+	market.Price = 0
+	createTestOrder(t, service, market)
+}
+
+func TestCreateOrderRejectsMalformedJSON(t *testing.T) {
+	service := newTestService(t)
+	router := gin.New()
+	router.POST("/orders", authenticate(testClientID, "trade"), NewGinHandlers(service).CreateOrderHandler())
+
+	for name, body := range map[string]string{
+		"truncated":         `{"client_id":"client-1","symbol":"AAPL","side":"BUY",`,
+		"wrong-typed field": `{"client_id":"client-1","symbol":"AAPL","side":"BUY","order_type":"LIMIT","quantity":"100","price":150}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Idempotency-Key", uuid.New().String())
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, req)
+
+			if recorder.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400: %s", recorder.Code, recorder.Body.String())
+			}
+			if code := errorCode(t, recorder); code != response.ErrCodeMalformedJSON {
+				t.Errorf("code = %s, want %s", code, response.ErrCodeMalformedJSON)
+			}
+			if strings.Contains(recorder.Body.String(), "json:") || strings.Contains(recorder.Body.String(), "Go struct") {
+				t.Errorf("response leaks decoder internals: %s", recorder.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package trading
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ksred/klear-api/internal/auth"
+	"github.com/ksred/klear-api/internal/types"
+	"github.com/ksred/klear-api/pkg/common"
+	"github.com/ksred/klear-api/pkg/response"
+	"github.com/rs/zerolog/log"
+)
+
+var (
+	ErrOrderNotReplaceable = errors.New("only pending orders can be replaced")
+	ErrOrderCancelled      = errors.New("order has been cancelled")
+)
+
+// ReplaceOrderRequest holds the modified parameters for a cancel/replace
+// Fields left unset keep the value from the original order
+type ReplaceOrderRequest struct {
+	Quantity        *float64 `json:"quantity"`
+	Price           *float64 `json:"price"`
+	OrderType       *string  `json:"order_type"`
+	DisplayQuantity *float64 `json:"display_quantity"`
+}
+
+// ReplaceOrder cancels a pending order and creates a new order with the modified parameters
+// The new order records the original in ReplacesOrderID
+// Parameters:
+//   - orderID: ID of the order to replace
+//   - clientID: ID of the client that owns the order
+//   - req: Parameters to change on the replacement order
+func (s *Service) ReplaceOrder(orderID, clientID string, req ReplaceOrderRequest) (*types.Order, error) {
+	logger := log.With().
+		Str("order_id", orderID).
+		Str("client_id", clientID).
+		Str("service", "trading").
+		Logger()
+
+	original, err := s.db.GetOrderByOrderIDAndClientID(common.NormalizeID(orderID), common.NormalizeID(clientID))
+	if err != nil {
+		return nil, err
+	}
+	if original == nil {
+		return nil, ErrOrderNotFound
+	}
+	if original.Status != "PENDING" {
+		logger.Warn().Str("status", original.Status).Msg("replace rejected, order is not pending")
+		return nil, ErrOrderNotReplaceable
+	}
+
+	replacement := &types.Order{
+		OrderID:         uuid.New().String(),
+		ClientID:        original.ClientID,
+		Symbol:          original.Symbol,
+		Side:            original.Side,
+		OrderType:       original.OrderType,
+		Quantity:        original.Quantity,
+		Price:           original.Price,
+		Currency:        original.Currency,
+		DisplayQuantity: original.DisplayQuantity,
+		PostOnly:        original.PostOnly,
+		ReduceOnly:      original.ReduceOnly,
+		ClientTag:       original.ClientTag,
+		TimeInForce:     original.TimeInForce,
+		ExpiresAt:       original.ExpiresAt,
+		Status:          "PENDING",
+		ReplacesOrderID: original.OrderID,
+		CreatedAt:       s.clock.Now(),
+		UpdatedAt:       s.clock.Now(),
+	}
+	if req.Quantity != nil {
+		replacement.Quantity = *req.Quantity
+	}
+	if req.Price != nil {
+		replacement.Price = *req.Price
+	}
+	if req.OrderType != nil {
+		replacement.OrderType = *req.OrderType
+	}
+	if req.DisplayQuantity != nil {
+		replacement.DisplayQuantity = *req.DisplayQuantity
+	}
+
+	if err := s.validateOrderBounds(replacement); err != nil {
+		return nil, err
+	}
+	if err := s.checkLotSize(replacement); err != nil {
+		return nil, err
+	}
+	if err := s.checkPriceBand(replacement); err != nil {
+		return nil, err
+	}
+	if isExpired(original, s.clock.Now()) {
+		return nil, ErrOrderExpired
+	}
+
+	if s.halts.IsHalted(replacement.Symbol) {
+		return nil, fmt.Errorf("%w: %s", ErrSymbolHalted, replacement.Symbol)
+	}
+	if err := s.checkReduceOnly(replacement); err != nil {
+		return nil, err
+	}
+
+	replaced, err := s.db.ReplaceOrder(original.OrderID, replacement)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to replace order")
+		return nil, err
+	}
+	if !replaced {
+		// The order was filled or cancelled after it was read
+		logger.Warn().Msg("replace rejected, order is no longer pending")
+		return nil, ErrOrderNotReplaceable
+	}
+
+	logger.Info().
+		Str("new_order_id", replacement.OrderID).
+		Float64("quantity", replacement.Quantity).
+		Float64("price", replacement.Price).
+		Msg("order replaced")
+
+	return replacement, nil
+}
+
+// ReplaceOrderHandler handles POST requests to cancel/replace an order
+// Requires a valid JWT token
+// URL parameter: order_id
+// Request body should contain the parameters to change
+func (h *GinHandlers) ReplaceOrderHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, exists := c.Get("claims")
+		if !exists {
+			response.Unauthorized(c, "Missing authentication claims")
+			return
+		}
+
+		clientID := auth.GetClientID(claims)
+		if clientID == "" {
+			response.Unauthorized(c, "Invalid client ID in token")
+			return
+		}
+
+		var req ReplaceOrderRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BindError(c, err)
+			return
+		}
+
+		order, err := h.service.ReplaceOrder(c.Param("order_id"), clientID, req)
+		if err != nil && respondOrderRejection(c, err) {
+			return
+		}
+		switch {
+		case errors.Is(err, ErrOrderNotFound):
+			response.NotFound(c, "Order not found")
+		case errors.Is(err, ErrOrderNotReplaceable) || errors.Is(err, ErrOrderExpired):
+			response.Conflict(c, err.Error())
+		case err != nil:
+			response.InternalError(c, err.Error())
+		default:
+			response.Success(c, order)
+		}
+	}
+}
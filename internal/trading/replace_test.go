@@ -0,0 +1,80 @@
+package trading
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/klear-api/internal/types"
+)
+
+func newReplaceRouter(service *Service) *gin.Engine {
+	router := gin.New()
+	router.POST("/orders/:order_id/replace", authenticate(testClientID), NewGinHandlers(service).ReplaceOrderHandler())
+	return router
+}
+
+func TestReplaceOrder(t *testing.T) {
+	service := newTestService(t)
+	original := createTestOrder(t, service, newTestOrder())
+
+	price := 151.0
+	recorder := performRequest(newReplaceRouter(service), http.MethodPost, "/orders/"+original.OrderID+"/replace",
+		ReplaceOrderRequest{Price: &price}, "")
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want 201: %s", recorder.Code, recorder.Body.String())
+	}
+	var envelope struct {
+		Data types.Order `json:"data"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	replacement := envelope.Data
+	if replacement.ReplacesOrderID != original.OrderID || replacement.Price != price ||
+		replacement.Quantity != original.Quantity || replacement.Status != "PENDING" {
+		t.Errorf("replacement = %+v, want a pending order at %v replacing %s", replacement, price, original.OrderID)
+	}
+
+	stored, err := service.GetOrder(original.OrderID)
+	if err != nil {
+		t.Fatalf("GetOrder: %v", err)
+	}
+	if stored.Status != "CANCELLED" {
+		t.Errorf("original status = %s, want CANCELLED", stored.Status)
+	}
+}
+
+func TestReplaceOrderRejectsFilledOrder(t *testing.T) {
+	service := newTestService(t)
+	order := newTestOrder()
+	executeTestOrder(t, service, order)
+
+	price := 151.0
+	recorder := performRequest(newReplaceRouter(service), http.MethodPost, "/orders/"+order.OrderID+"/replace",
+		ReplaceOrderRequest{Price: &price}, "")
+	if recorder.Code != http.StatusConflict {
+		t.Errorf("status = %d, want 409: %s", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestReplaceOrderRejectsInvalidOrderType(t *testing.T) {
+	service := newTestService(t)
+	original := createTestOrder(t, service, newTestOrder())
+
+	orderType := "STOP"
+	recorder := performRequest(newReplaceRouter(service), http.MethodPost, "/orders/"+original.OrderID+"/replace",
+		ReplaceOrderRequest{OrderType: &orderType}, "")
+	if recorder.Code != http.StatusBadRequest || errorCode(t, recorder) != RejectCodeInvalidOrder {
+		t.Errorf("response = %d %s, want 400 %s", recorder.Code, recorder.Body.String(), RejectCodeInvalidOrder)
+	}
+
+	stored, err := service.GetOrder(original.OrderID)
+	if err != nil {
+		t.Fatalf("GetOrder: %v", err)
+	}
+	if stored.Status != "PENDING" {
+		t.Errorf("original status = %s, want it left PENDING", stored.Status)
+	}
+}
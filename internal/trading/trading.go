@@ -2,26 +2,78 @@ package trading
 
 import (
 	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/ksred/klear-api/internal/auth"
+	"github.com/ksred/klear-api/internal/breaks"
 	"github.com/ksred/klear-api/internal/exchange"
+	"github.com/ksred/klear-api/internal/pricefeed"
 	"github.com/ksred/klear-api/internal/types"
+	"github.com/ksred/klear-api/pkg/common"
+	"github.com/ksred/klear-api/pkg/money"
 	"github.com/ksred/klear-api/pkg/response"
 	"gorm.io/gorm"
 )
 
+var (
+	ErrOrderNotFound       = errors.New("order not found")
+	ErrClientNotFound      = errors.New("client not found")
+	ErrClientInactive      = errors.New("client is inactive")
+	ErrInvalidQuantity     = errors.New("order quantity must be positive")
+	ErrInvalidPrice        = errors.New("order price must not be negative")
+	ErrQuantityOutOfBounds = errors.New("order quantity exceeds maximum allowed")
+	ErrPriceOutOfBounds    = errors.New("order price exceeds maximum allowed")
+	ErrNotionalOutOfBounds = errors.New("order notional exceeds maximum allowed")
+	ErrOrderNotOwned       = errors.New("order belongs to a different client")
+	ErrInvalidDisplayQty   = errors.New("display quantity must be between zero and the order quantity")
+	ErrInvalidCurrency     = errors.New("currency is not a valid ISO 4217 code")
+	ErrPostOnlyNotLimit    = errors.New("post-only is only supported on limit orders")
+	ErrClientTagTooLong    = errors.New("client tag exceeds maximum length")
+	ErrInvalidSide         = errors.New("order side must be BUY or SELL")
+	ErrInvalidOrderType    = errors.New("order type must be MARKET or LIMIT")
+	ErrPriceOffTick        = errors.New("order price is not a multiple of the tick size")
+)
+
+// maxClientTagLength caps the free-form tag clients may attach to an order
+const maxClientTagLength = 64
+
 // Service handles trading operations and order management
 type Service struct {
-	db *Database
+	db          *Database
+	config      Config
+	halts       *HaltRegistry
+	idempotency IdempotencyStore
+	breaks      *breaks.Service
+	clearer     Clearer             // Clears new executions when Config.AutoClear is enabled
+	priceFeed   pricefeed.PriceFeed // Reference prices for the price band check
+	clock       common.Clock
 }
 
-// NewService creates a new trading service with the given database connection
-func NewService(gormDB *gorm.DB) *Service {
+// NewService creates a new trading service with the given database connection and configuration
+func NewService(gormDB *gorm.DB, config Config) *Service {
 	return &Service{
-		db: NewDatabase(gormDB),
+		db:          NewDatabase(gormDB),
+		config:      config,
+		halts:       NewHaltRegistry(),
+		idempotency: newIdempotencyStore(gormDB, config),
+		breaks:      breaks.NewService(gormDB),
+		clock:       common.RealClock{},
+	}
+}
+
+// SetClock replaces the clock used for order and trade break timestamps, expiry and idempotency key expiry
+// Keys held in Redis expire on the Redis server's clock and are unaffected
+func (s *Service) SetClock(clock common.Clock) {
+	s.clock = clock
+	s.breaks.SetClock(clock)
+	if store, ok := s.idempotency.(*dbIdempotencyStore); ok {
+		store.clock = clock
 	}
 }
 
@@ -30,92 +82,379 @@ func NewService(gormDB *gorm.DB) *Service {
 // Parameters:
 //   - order: The order to create
 //   - idempotencyKey: Unique key to prevent duplicate order creation
-func (s *Service) CreateOrder(order *types.Order, idempotencyKey string) error {
-	// Check for existing idempotency record
-	record, err := s.db.GetIdempotencyRecord(idempotencyKey)
-
-	// If record exists and hasn't expired
-	if err == nil && record.ExpiresAt.After(time.Now()) && record != nil {
-		// Return existing order
-		existingOrder, err := s.db.GetOrder(record.ResourceID)
-		if err != nil {
-			return err
-		}
-		if existingOrder == nil {
-			return errors.New("order not found")
-		}
-		*order = *existingOrder
-		return nil
+//
+// created reports whether a new order was created; it is false when the key replayed an existing order
+func (s *Service) CreateOrder(order *types.Order, idempotencyKey string) (created bool, err error) {
+	// Replay the order an earlier request created with this key
+	replayed, err := s.replayOrder(order, idempotencyKey)
+	if err != nil || replayed {
+		return false, err
+	}
+
+	s.normalizeOrder(order)
+	if errs := s.checkOrder(order, s.clock.Now()); len(errs) > 0 {
+		return false, errs[0]
+	}
+	// The handler places the order for the authenticated client, so the cap is that client's
+	checkOpenOrders, err := s.openOrderCheck(order.ClientID)
+	if err != nil {
+		return false, err
 	}
 
 	// Prepare new order
 	order.OrderID = uuid.New().String()
 	order.Status = "PENDING"
-	order.CreatedAt = time.Now()
-	order.UpdatedAt = time.Now()
+	order.CreatedAt = s.clock.Now()
+	order.UpdatedAt = s.clock.Now()
+
+	// A database-backed key is recorded in the order's own transaction
+	if store, ok := s.idempotency.(txIdempotencyStore); ok {
+		err := s.db.CreateOrder(order, func(tx *gorm.DB) error {
+			if err := checkOpenOrders(tx); err != nil {
+				return err
+			}
+			return store.RecordTx(tx, idempotencyKey, ResourceTypeOrder, order.OrderID)
+		})
+		if err != nil {
+			return s.replayConcurrentOrder(order, idempotencyKey, err)
+		}
+		return true, nil
+	}
+
+	if err := s.idempotency.Reserve(idempotencyKey, ResourceTypeOrder); err != nil {
+		return s.replayConcurrentOrder(order, idempotencyKey, err)
+	}
+
+	if err := s.db.CreateOrder(order, checkOpenOrders); err != nil {
+		s.releaseIdempotencyKey(idempotencyKey, ResourceTypeOrder)
+		return false, err
+	}
+	s.commitIdempotencyKey(idempotencyKey, ResourceTypeOrder, order.OrderID)
+	return true, nil
+}
 
-	return s.db.CreateOrderWithIdempotency(order, idempotencyKey)
+// replayConcurrentOrder handles err from claiming the idempotency key of a new order
+// A concurrent request with the same key may have won the race and finished, in which case the
+// order it created is loaded into order instead of failing
+func (s *Service) replayConcurrentOrder(order *types.Order, idempotencyKey string, err error) (bool, error) {
+	if errors.Is(err, ErrIdempotencyKeyInProgress) {
+		if replayed, replayErr := s.replayOrder(order, idempotencyKey); replayErr != nil || replayed {
+			return false, replayErr
+		}
+	}
+	return false, err
+}
+
+// replayOrder loads into order the order committed under the idempotency key, reporting
+// whether there was one
+func (s *Service) replayOrder(order *types.Order, idempotencyKey string) (bool, error) {
+	orderID, err := s.idempotency.Get(idempotencyKey, ResourceTypeOrder)
+	if err != nil || orderID == "" {
+		return false, err
+	}
+	existingOrder, err := s.db.GetOrder(orderID)
+	if err != nil {
+		return false, err
+	}
+	if existingOrder == nil {
+		return false, ErrOrderNotFound
+	}
+	*order = *existingOrder
+	return true, nil
 }
 
 // GetOrder retrieves an order by its ID
 func (s *Service) GetOrder(orderID string) (*types.Order, error) {
-	return s.db.GetOrder(orderID)
+	return s.db.GetOrder(common.NormalizeID(orderID))
 }
 
 // GetOrderByOrderIDAndClientID retrieves an order by its ID and client ID
 func (s *Service) GetOrderByOrderIDAndClientID(orderID, clientID string) (*types.Order, error) {
-	return s.db.GetOrderByOrderIDAndClientID(orderID, clientID)
+	return s.db.GetOrderByOrderIDAndClientID(common.NormalizeID(orderID), common.NormalizeID(clientID))
+}
+
+// GetOrderByIdempotencyKey retrieves the order a client created with the given idempotency key,
+// so a client that lost the create response can recover it
+// Returns ErrOrderNotFound if the key is unknown, has expired or belongs to another client's order
+func (s *Service) GetOrderByIdempotencyKey(idempotencyKey, clientID string) (*types.Order, error) {
+	orderID, err := s.idempotency.Get(idempotencyKey, ResourceTypeOrder)
+	if err != nil {
+		return nil, err
+	}
+	if orderID == "" {
+		return nil, ErrOrderNotFound
+	}
+
+	order, err := s.GetOrderByOrderIDAndClientID(orderID, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if order == nil {
+		return nil, ErrOrderNotFound
+	}
+	return order, nil
+}
+
+// ListOrders retrieves a page of a client's orders, newest first, optionally filtered by client tag
+// Parameters:
+//   - clientID: ID of the client whose orders are listed
+//   - clientTag: Optional tag to match exactly; empty lists orders regardless of tag
+//   - page: Page to return, by cursor or offset
+func (s *Service) ListOrders(clientID, clientTag string, page common.Page) (*OrderPage, error) {
+	orders, err := s.db.ListOrders(common.NormalizeID(clientID), strings.TrimSpace(clientTag), page)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &OrderPage{Orders: orders, Limit: page.Limit}
+	if page.HasMore(len(orders)) {
+		result.Orders = orders[:page.Limit]
+		last := result.Orders[page.Limit-1]
+		result.NextCursor = common.EncodeCursor(last.CreatedAt, last.ID)
+	}
+	return result, nil
 }
 
 // ExecuteOrder executes an existing order with idempotency support
 // It routes the order to available exchanges and records the execution results
 // Parameters:
 //   - orderID: ID of the order to execute
+//   - clientID: ID of the calling client, checked against the order owner when ownership is enforced
 //   - idempotencyKey: Unique key to prevent duplicate execution
-func (s *Service) ExecuteOrder(orderID string, idempotencyKey string) (*types.Execution, error) {
-	// Check for existing idempotency record
-	record, err := s.db.GetIdempotencyRecord(idempotencyKey)
-
-	// If record exists and hasn't expired
-	if err == nil && record.ExpiresAt.After(time.Now()) && record != nil {
-		// Return existing execution
-		existingExecution, err := s.db.GetExecution(record.ResourceID)
-		if err != nil {
-			return nil, err
+func (s *Service) ExecuteOrder(orderID string, clientID string, idempotencyKey string) (*types.Execution, error) {
+	// Replay the execution an earlier request recorded with this key
+	executionID, err := s.idempotency.Get(idempotencyKey, ResourceTypeExecution)
+	if err != nil {
+		return nil, err
+	}
+	if executionID != "" {
+		return s.db.GetExecution(executionID)
+	}
+
+	// Reserve the key before routing, so a concurrent duplicate cannot execute the order twice
+	if err := s.idempotency.Reserve(idempotencyKey, ResourceTypeExecution); err != nil {
+		if errors.Is(err, ErrIdempotencyKeyInProgress) {
+			if executionID, getErr := s.idempotency.Get(idempotencyKey, ResourceTypeExecution); getErr == nil && executionID != "" {
+				return s.db.GetExecution(executionID)
+			}
+		}
+		return nil, err
+	}
+
+	// A database-backed key is committed in the execution's own transaction
+	store, inTx := s.idempotency.(txIdempotencyStore)
+	var record func(tx *gorm.DB, executionID string) error
+	if inTx {
+		record = func(tx *gorm.DB, executionID string) error {
+			return store.CommitTx(tx, idempotencyKey, ResourceTypeExecution, executionID)
 		}
-		return existingExecution, nil
 	}
 
-	order, err := s.db.GetOrder(orderID)
-	if err != nil || order == nil {
+	execution, err := s.executeOrder(orderID, clientID, record)
+	if err != nil {
+		s.releaseIdempotencyKey(idempotencyKey, ResourceTypeExecution)
 		return nil, err
 	}
+	if !inTx {
+		s.commitIdempotencyKey(idempotencyKey, ResourceTypeExecution, execution.ExecutionID)
+	}
+	s.enqueueClearing(execution.ExecutionID)
+	return execution, nil
+}
 
-	// Use the mock exchange system to execute the order
-	execution, err := exchange.ExecuteOrderAcrossExchanges(order)
+// executeOrder routes the order to the exchanges and records the execution
+// record, if not nil, runs in the transaction that records the execution
+func (s *Service) executeOrder(orderID string, clientID string, record func(tx *gorm.DB, executionID string) error) (*types.Execution, error) {
+	order, err := s.db.GetOrder(common.NormalizeID(orderID))
 	if err != nil {
 		return nil, err
 	}
+	if order == nil {
+		return nil, ErrOrderNotFound
+	}
 
-	// Set execution ID
-	execution.ExecutionID = uuid.New().String()
+	if order.Status == "CANCELLED" {
+		return nil, ErrOrderCancelled
+	}
+	if isExpired(order, s.clock.Now()) {
+		return nil, ErrOrderExpired
+	}
+
+	if s.config.EnforceExecutionOwnership && order.ClientID != common.NormalizeID(clientID) {
+		return nil, ErrOrderNotOwned
+	}
 
-	// Save the execution to database with idempotency
-	if err := s.db.CreateExecutionWithIdempotency(execution, idempotencyKey); err != nil {
+	// Only execute orders for known, active clients
+	if err := s.checkClientActive(order.ClientID); err != nil {
 		return nil, err
 	}
 
-	// Update order status
-	order.Status = "FILLED"
-	order.UpdatedAt = time.Now()
+	// Re-check sanity bounds in case limits changed or the order bypassed creation checks
+	if err := s.validateOrderBounds(order); err != nil {
+		return nil, err
+	}
+
+	// The position may have moved since the order was created
+	if err := s.checkReduceOnly(order); err != nil {
+		return nil, err
+	}
+
+	// Use the mock exchange system to execute the order, or what remains of a partially filled one,
+	// after crossing what it can internally
+	lotSize := s.config.LotSizeFor(order.Symbol)
+	toExecute := *order
+	if order.Status == "PARTIALLY_FILLED" {
+		toExecute.Quantity = remainingQuantity(order)
+	}
+	execution, failedAttempts, crosses, err := s.executeWithCrossing(&toExecute, lotSize)
+	if err != nil {
+		s.recordFailedAttempts(failedAttempts, "")
+		return nil, err
+	}
+
+	// Set execution ID, and record the execution at the service's time so it lines up with the order
+	execution.ExecutionID = uuid.New().String()
+	execution.CreatedAt = s.clock.Now()
+	execution.UpdatedAt = execution.CreatedAt
+	s.recordFailedAttempts(failedAttempts, execution.ExecutionID)
+
+	// Update order status; quantity the venues could not fill stays open on the order
+	previousStatus := order.Status
+	s.fillOrder(order, execution.TotalQuantity, lotSize)
 	// QUESTION: do we need toupdate the order fill price?
-	if err := s.db.UpdateOrder(order); err != nil {
+
+	// Save the execution, the filled order and the resting orders it crossed together
+	recordTx := func(tx *gorm.DB) error {
+		if record != nil {
+			if err := record(tx, execution.ExecutionID); err != nil {
+				return err
+			}
+		}
+		return recordCrossedOrders(tx, crosses)
+	}
+	if err := s.db.RecordExecution(execution, order, previousStatus, recordTx); err != nil {
 		return nil, err
 	}
 
+	// The resting side of each cross is a new execution of its own
+	for _, cross := range crosses {
+		s.enqueueClearing(cross.execution.ExecutionID)
+	}
 	return execution, nil
 }
 
+// fillOrder adds an executed quantity to the order. The order is FILLED unless at least a lot
+// remains, in which case it stays PARTIALLY_FILLED for the rest to be executed
+func (s *Service) fillOrder(order *types.Order, quantity, lotSize float64) {
+	order.FilledQuantity = money.FromUnits(money.ToUnits(order.FilledQuantity) + money.ToUnits(quantity))
+	order.Status = "FILLED"
+	if remaining := remainingQuantity(order); remaining > 0 && (lotSize <= 0 || remaining >= lotSize) {
+		order.Status = "PARTIALLY_FILLED"
+	}
+	order.UpdatedAt = s.clock.Now()
+}
+
+// remainingQuantity returns the quantity of the order not yet executed
+func remainingQuantity(order *types.Order) float64 {
+	return money.FromUnits(money.ToUnits(order.Quantity) - money.ToUnits(order.FilledQuantity))
+}
+
+// normalizeOrder trims identifiers and applies defaults so lookups and netting match
+// regardless of client formatting
+func (s *Service) normalizeOrder(order *types.Order) {
+	order.Symbol = common.NormalizeSymbol(order.Symbol)
+	order.ClientID = common.NormalizeID(order.ClientID)
+	order.ClientTag = strings.TrimSpace(order.ClientTag)
+
+	order.Currency = money.NormalizeCurrency(order.Currency)
+	if order.Currency == "" {
+		order.Currency = s.config.DefaultCurrency
+	}
+}
+
+// checkOrder runs the creation-time checks on a normalized order and returns every failure
+func (s *Service) checkOrder(order *types.Order, now time.Time) []error {
+	var errs []error
+	if !money.IsValidCurrency(order.Currency) {
+		errs = append(errs, fmt.Errorf("%w: %s", ErrInvalidCurrency, order.Currency))
+	}
+	if err := s.validateOrderBounds(order); err != nil {
+		errs = append(errs, err)
+	} else if err := s.checkLotSize(order); err != nil {
+		errs = append(errs, err)
+	} else if err := s.checkTickSize(order); err != nil {
+		errs = append(errs, err)
+	} else if err := s.checkPriceBand(order); err != nil {
+		errs = append(errs, err)
+	}
+	if err := s.validateTimeInForce(order, now); err != nil {
+		errs = append(errs, err)
+	}
+	if s.halts.IsHalted(order.Symbol) {
+		errs = append(errs, fmt.Errorf("%w: %s", ErrSymbolHalted, order.Symbol))
+	}
+	if err := s.checkReduceOnly(order); err != nil {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// validateOrderBounds checks that the order quantity and price are within the configured sanity bounds
+func (s *Service) validateOrderBounds(order *types.Order) error {
+	if order.Side != "BUY" && order.Side != "SELL" {
+		return ErrInvalidSide
+	}
+	if order.OrderType != "MARKET" && order.OrderType != "LIMIT" {
+		return ErrInvalidOrderType
+	}
+	// NaN fails every comparison, so it is rejected explicitly
+	if math.IsNaN(order.Quantity) || order.Quantity <= 0 {
+		return ErrInvalidQuantity
+	}
+	if math.IsNaN(order.Price) || order.Price < 0 {
+		return ErrInvalidPrice
+	}
+	if order.Quantity > s.config.MaxOrderQuantity {
+		return ErrQuantityOutOfBounds
+	}
+	if order.Price > s.config.MaxOrderPrice {
+		return ErrPriceOutOfBounds
+	}
+	if s.config.MaxOrderNotional > 0 && money.Notional(order.Price, order.Quantity, order.Currency) > s.config.MaxOrderNotional {
+		return ErrNotionalOutOfBounds
+	}
+	if math.IsNaN(order.DisplayQuantity) || order.DisplayQuantity < 0 || order.DisplayQuantity > order.Quantity {
+		return ErrInvalidDisplayQty
+	}
+	if order.PostOnly && order.OrderType != "LIMIT" {
+		return ErrPostOnlyNotLimit
+	}
+	if len(order.ClientTag) > maxClientTagLength {
+		return ErrClientTagTooLong
+	}
+	return nil
+}
+
+// checkClientActive verifies that the client exists and has not been deactivated
+func (s *Service) checkClientActive(clientID string) error {
+	client, err := s.db.GetClient(clientID)
+	if err != nil {
+		return err
+	}
+	if client == nil {
+		return ErrClientNotFound
+	}
+	if !client.Active {
+		return ErrClientInactive
+	}
+	return nil
+}
+
+// RegisterClient creates or updates a client record (for testing/demo purposes)
+func (s *Service) RegisterClient(client *types.Client) error {
+	return s.db.SaveClient(client)
+}
+
 // GinHandlers contains HTTP handlers for trading endpoints
 type GinHandlers struct {
 	service *Service
@@ -140,17 +479,39 @@ func (h *GinHandlers) CreateOrderHandler() gin.HandlerFunc {
 			return
 		}
 
+		claims, exists := c.Get("claims")
+		if !exists {
+			response.Unauthorized(c, "Missing authentication claims")
+			return
+		}
+
 		var order types.Order
 		if err := c.ShouldBindJSON(&order); err != nil {
-			response.BadRequest(c, err.Error())
+			response.BindError(c, err)
 			return
 		}
 
-		if err := h.service.CreateOrder(&order, idempotencyKey); err != nil {
+		// Orders are always placed for the authenticated client, whatever the body claims
+		order.ClientID = auth.GetClientID(claims)
+
+		created, err := h.service.CreateOrder(&order, idempotencyKey)
+		if err != nil {
+			if respondOrderRejection(c, err) {
+				return
+			}
+			if errors.Is(err, ErrIdempotencyKeyInProgress) {
+				response.Conflict(c, err.Error())
+				return
+			}
 			response.InternalError(c, err.Error())
 			return
 		}
 
+		// Replays of an earlier request return the existing order rather than creating one
+		if !created {
+			response.SuccessWithStatus(c, order, http.StatusOK)
+			return
+		}
 		response.Success(c, order)
 	}
 }
@@ -190,6 +551,65 @@ func (h *GinHandlers) GetOrderStatusHandler() gin.HandlerFunc {
 	}
 }
 
+// GetOrderByIdempotencyKeyHandler handles GET requests to recover an order by the idempotency key it was created with
+// Requires a valid JWT token
+// URL parameter: key
+func (h *GinHandlers) GetOrderByIdempotencyKeyHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, exists := c.Get("claims")
+		if !exists {
+			response.Unauthorized(c, "Missing authentication claims")
+			return
+		}
+
+		clientID := auth.GetClientID(claims)
+		if clientID == "" {
+			response.Unauthorized(c, "Invalid client ID in token")
+			return
+		}
+
+		order, err := h.service.GetOrderByIdempotencyKey(c.Param("key"), clientID)
+		if errors.Is(err, ErrOrderNotFound) {
+			response.NotFound(c, "Order not found")
+			return
+		}
+		response.Handle(c, order, err)
+	}
+}
+
+// ListOrdersHandler handles GET requests to list the client's orders
+// Requires a valid JWT token
+// Query parameters: tag, limit, cursor, offset (all optional)
+func (h *GinHandlers) ListOrdersHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, exists := c.Get("claims")
+		if !exists {
+			response.Unauthorized(c, "Missing authentication claims")
+			return
+		}
+
+		clientID := auth.GetClientID(claims)
+		if clientID == "" {
+			response.Unauthorized(c, "Invalid client ID in token")
+			return
+		}
+
+		page, err := common.ParsePage(c.Query("limit"), c.Query("offset"), c.Query("cursor"), h.service.config.PageSize)
+		if err != nil {
+			response.BadRequest(c, err.Error())
+			return
+		}
+
+		orders, err := h.service.ListOrders(clientID, c.Query("tag"), page)
+		if err != nil {
+			response.InternalError(c, err.Error())
+			return
+		}
+
+		response.Success(c, orders)
+	}
+}
+
 // ExecuteOrderHandler handles POST requests to execute orders
 // Requires internal authentication and idempotency key
 // URL parameter: order_id
@@ -203,13 +623,53 @@ func (h *GinHandlers) ExecuteOrderHandler() gin.HandlerFunc {
 		}
 
 		orderID := c.Param("order_id")
+		clientID := c.GetString("clientID")
 
-		execution, err := h.service.ExecuteOrder(orderID, idempotencyKey)
+		execution, err := h.service.ExecuteOrder(orderID, clientID, idempotencyKey)
+		if errors.Is(err, ErrOrderNotFound) {
+			response.NotFound(c, "Order not found")
+			return
+		}
+		if errors.Is(err, ErrClientNotFound) || errors.Is(err, ErrClientInactive) || errors.Is(err, ErrOrderNotOwned) {
+			response.Forbidden(c, err.Error())
+			return
+		}
+		if errors.Is(err, ErrOrderCancelled) || errors.Is(err, ErrOrderExpired) || errors.Is(err, exchange.ErrWouldCross) ||
+			errors.Is(err, exchange.ErrBelowLotSize) || errors.Is(err, exchange.ErrNoQuantityExecuted) ||
+			errors.Is(err, exchange.ErrSymbolNotSupported) || isReduceOnlyViolation(err) ||
+			errors.Is(err, ErrIdempotencyKeyInProgress) || errors.Is(err, ErrCrossedOrderChanged) {
+			response.Conflict(c, err.Error())
+			return
+		}
+		if isOrderValidationError(err) {
+			response.BadRequest(c, err.Error())
+			return
+		}
 		if err != nil {
 			response.InternalError(c, err.Error())
 			return
 		}
 
-		response.Success(c, execution)
+		response.SuccessWithStatus(c, execution, http.StatusOK)
 	}
 }
+
+// isOrderValidationError reports whether the error is caused by invalid order details
+func isOrderValidationError(err error) bool {
+	return errors.Is(err, ErrInvalidQuantity) ||
+		errors.Is(err, ErrInvalidPrice) ||
+		errors.Is(err, ErrQuantityOutOfBounds) ||
+		errors.Is(err, ErrPriceOutOfBounds) ||
+		errors.Is(err, ErrInvalidDisplayQty) ||
+		errors.Is(err, ErrInvalidCurrency) ||
+		errors.Is(err, ErrPostOnlyNotLimit) ||
+		errors.Is(err, ErrClientTagTooLong) ||
+		errors.Is(err, ErrInvalidSide) ||
+		errors.Is(err, ErrInvalidOrderType) ||
+		errors.Is(err, ErrNotionalOutOfBounds) ||
+		errors.Is(err, ErrInvalidTimeInForce) ||
+		errors.Is(err, ErrExpiryRequired) ||
+		errors.Is(err, ErrExpiryNotAllowed) ||
+		errors.Is(err, ErrExpiryInPast) ||
+		errors.Is(err, ErrExpiryTooFar)
+}
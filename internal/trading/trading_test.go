@@ -0,0 +1,493 @@
+package trading
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/ksred/klear-api/internal/breaks"
+	"github.com/ksred/klear-api/internal/database/dbtest"
+	"github.com/ksred/klear-api/internal/events"
+	"github.com/ksred/klear-api/internal/exchange"
+	"github.com/ksred/klear-api/internal/types"
+	"gorm.io/gorm"
+)
+
+// testClientID is the active client every test service is created with
+const testClientID = "client-1"
+
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	// Venues fill instantly and in full at the order price, so executions are deterministic
+	exchange.SetInstantFills(true)
+	os.Exit(m.Run())
+}
+
+// newTestService creates a trading service with the default configuration over a fresh database
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+	return newTestServiceWithConfig(t, DefaultConfig())
+}
+
+// newTestServiceWithConfig creates a trading service over a fresh database holding the active client testClientID
+func newTestServiceWithConfig(t *testing.T, config Config) *Service {
+	t.Helper()
+	db := dbtest.Open(t,
+		&types.Order{},
+		&types.Client{},
+		&types.Execution{},
+		&types.ExchangeFill{},
+		&types.Allocation{},
+		&types.VenueAttempt{},
+		&IdempotencyRecord{},
+		&events.DomainEvent{},
+		&breaks.TradeBreak{},
+	)
+	service := NewService(db, config)
+	registerClient(t, service, testClientID, true)
+	return service
+}
+
+// registerClient creates or updates a client with the given status
+func registerClient(t *testing.T, service *Service, clientID string, active bool) {
+	t.Helper()
+	if err := service.RegisterClient(&types.Client{ClientID: clientID, Name: clientID, Active: active}); err != nil {
+		t.Fatalf("failed to register client %s: %v", clientID, err)
+	}
+}
+
+// newTestOrder returns a valid limit order of the test client
+func newTestOrder() *types.Order {
+	return &types.Order{
+		ClientID:  testClientID,
+		Symbol:    "AAPL",
+		Side:      "BUY",
+		OrderType: "LIMIT",
+		Quantity:  100,
+		Price:     150,
+	}
+}
+
+// createTestOrder creates the order under a fresh idempotency key, failing the test on error
+func createTestOrder(t *testing.T, service *Service, order *types.Order) *types.Order {
+	t.Helper()
+	if _, err := service.CreateOrder(order, uuid.New().String()); err != nil {
+		t.Fatalf("failed to create order: %v", err)
+	}
+	return order
+}
+
+// executeTestOrder creates the order and executes it in full, failing the test on error
+func executeTestOrder(t *testing.T, service *Service, order *types.Order) *types.Execution {
+	t.Helper()
+	createTestOrder(t, service, order)
+	execution, err := service.ExecuteOrder(order.OrderID, order.ClientID, uuid.New().String())
+	if err != nil {
+		t.Fatalf("failed to execute order: %v", err)
+	}
+	return execution
+}
+
+// authenticate stands in for the JWT middleware, authenticating every request as the client
+func authenticate(clientID string, permissions ...string) gin.HandlerFunc {
+	granted := make([]interface{}, len(permissions))
+	for i, permission := range permissions {
+		granted[i] = permission
+	}
+	return func(c *gin.Context) {
+		c.Set("claims", jwt.MapClaims{"client_id": clientID, "permissions": granted})
+		c.Set("clientID", clientID)
+		c.Next()
+	}
+}
+
+// performRequest serves a request with an optional JSON body and idempotency key
+func performRequest(router http.Handler, method, path string, body interface{}, idempotencyKey string) *httptest.ResponseRecorder {
+	var encoded []byte
+	if body != nil {
+		encoded, _ = json.Marshal(body)
+	}
+	req := httptest.NewRequest(method, path, bytes.NewReader(encoded))
+	req.Header.Set("Content-Type", "application/json")
+	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+// errorCode returns the error code of an error response envelope
+func errorCode(t *testing.T, recorder *httptest.ResponseRecorder) string {
+	t.Helper()
+	var envelope struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("failed to decode response %s: %v", recorder.Body.String(), err)
+	}
+	return envelope.Error.Code
+}
+
+func TestExecuteOrderForActiveClient(t *testing.T) {
+	service := newTestService(t)
+	order := createTestOrder(t, service, newTestOrder())
+
+	execution, err := service.ExecuteOrder(order.OrderID, testClientID, "exec-active")
+	if err != nil {
+		t.Fatalf("ExecuteOrder: %v", err)
+	}
+	if execution.TotalQuantity != order.Quantity {
+		t.Errorf("executed quantity = %v, want %v", execution.TotalQuantity, order.Quantity)
+	}
+}
+
+func TestExecuteOrderRejectsInactiveClient(t *testing.T) {
+	service := newTestService(t)
+	order := createTestOrder(t, service, newTestOrder())
+
+	// The client is deactivated after placing the order
+	registerClient(t, service, testClientID, false)
+
+	_, err := service.ExecuteOrder(order.OrderID, testClientID, "exec-inactive")
+	if !errors.Is(err, ErrClientInactive) {
+		t.Fatalf("ExecuteOrder error = %v, want ErrClientInactive", err)
+	}
+
+	stored, err := service.GetOrder(order.OrderID)
+	if err != nil {
+		t.Fatalf("GetOrder: %v", err)
+	}
+	if stored.Status != "PENDING" {
+		t.Errorf("order status = %s, want PENDING", stored.Status)
+	}
+}
+
+func TestExecuteOrderRejectsUnknownClient(t *testing.T) {
+	service := newTestService(t)
+	order := newTestOrder()
+	order.ClientID = "client-unknown"
+	createTestOrder(t, service, order)
+
+	_, err := service.ExecuteOrder(order.OrderID, order.ClientID, "exec-unknown")
+	if !errors.Is(err, ErrClientNotFound) {
+		t.Fatalf("ExecuteOrder error = %v, want ErrClientNotFound", err)
+	}
+}
+
+func TestCreateOrderRejectsOutOfBoundsOrders(t *testing.T) {
+	config := DefaultConfig()
+	config.MaxOrderQuantity = 1000
+	config.MaxOrderPrice = 500
+	config.MaxOrderNotional = 0
+	service := newTestServiceWithConfig(t, config)
+
+	router := gin.New()
+	router.POST("/orders", authenticate(testClientID), NewGinHandlers(service).CreateOrderHandler())
+
+	tests := []struct {
+		name     string
+		quantity float64
+		price    float64
+		wantErr  error
+	}{
+		{"quantity over limit", 1e18, 150, ErrQuantityOutOfBounds},
+		{"price over limit", 100, 501, ErrPriceOutOfBounds},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := newTestOrder()
+			order.Quantity = tt.quantity
+			order.Price = tt.price
+
+			if _, err := service.CreateOrder(order, uuid.New().String()); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CreateOrder error = %v, want %v", err, tt.wantErr)
+			}
+
+			order = newTestOrder()
+			order.Quantity = tt.quantity
+			order.Price = tt.price
+			recorder := performRequest(router, http.MethodPost, "/orders", order, uuid.New().String())
+			if recorder.Code != http.StatusUnprocessableEntity || errorCode(t, recorder) != RejectCodeRiskLimit {
+				t.Errorf("response = %d %s, want 422 %s", recorder.Code, recorder.Body.String(), RejectCodeRiskLimit)
+			}
+		})
+	}
+
+	var count int64
+	service.db.db.Model(&types.Order{}).Count(&count)
+	if count != 0 {
+		t.Errorf("stored %d orders, want none", count)
+	}
+}
+
+func TestCreateOrderRejectsNonFiniteAmounts(t *testing.T) {
+	service := newTestService(t)
+
+	tests := []struct {
+		name    string
+		order   func(*types.Order)
+		wantErr error
+	}{
+		{"NaN quantity", func(o *types.Order) { o.Quantity = math.NaN() }, ErrInvalidQuantity},
+		{"NaN price", func(o *types.Order) { o.Price = math.NaN() }, ErrInvalidPrice},
+		{"NaN display quantity", func(o *types.Order) { o.DisplayQuantity = math.NaN() }, ErrInvalidDisplayQty},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := newTestOrder()
+			tt.order(order)
+			if _, err := service.CreateOrder(order, uuid.New().String()); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CreateOrder error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	var count int64
+	service.db.db.Model(&types.Order{}).Count(&count)
+	if count != 0 {
+		t.Errorf("stored %d orders, want none", count)
+	}
+}
+
+func TestExecuteOrderRechecksBounds(t *testing.T) {
+	service := newTestService(t)
+	order := createTestOrder(t, service, newTestOrder())
+
+	// The limit is lowered after the order was accepted
+	service.config.MaxOrderQuantity = order.Quantity / 2
+
+	if _, err := service.ExecuteOrder(order.OrderID, testClientID, "exec-bounds"); !errors.Is(err, ErrQuantityOutOfBounds) {
+		t.Fatalf("ExecuteOrder error = %v, want ErrQuantityOutOfBounds", err)
+	}
+	var count int64
+	service.db.db.Model(&types.Execution{}).Count(&count)
+	if count != 0 {
+		t.Errorf("stored %d executions, want none", count)
+	}
+}
+
+func TestExecuteOrderHandlerUnknownOrder(t *testing.T) {
+	service := newTestService(t)
+
+	if _, err := service.ExecuteOrder(uuid.New().String(), testClientID, "exec-missing"); !errors.Is(err, ErrOrderNotFound) {
+		t.Errorf("ExecuteOrder error = %v, want ErrOrderNotFound", err)
+	}
+
+	router := gin.New()
+	router.POST("/orders/:order_id/execute", authenticate(testClientID), NewGinHandlers(service).ExecuteOrderHandler())
+	recorder := performRequest(router, http.MethodPost, "/orders/"+uuid.New().String()+"/execute", nil, "exec-missing-http")
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404: %s", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestCreateOrderNormalizesSymbolsSoTheyNetTogether(t *testing.T) {
+	service := newTestService(t)
+
+	for _, symbol := range []string{" aapl ", "Aapl", "AAPL\t"} {
+		order := newTestOrder()
+		order.Symbol = symbol
+		order.ClientID = " " + testClientID + " "
+		order.Quantity = 10
+		executeTestOrder(t, service, order)
+
+		if order.Symbol != "AAPL" || order.ClientID != testClientID {
+			t.Errorf("order stored as %q for %q, want AAPL for %q", order.Symbol, order.ClientID, testClientID)
+		}
+	}
+
+	position, err := service.db.GetNetPosition(testClientID, "AAPL")
+	if err != nil {
+		t.Fatalf("GetNetPosition: %v", err)
+	}
+	if position != 30 {
+		t.Errorf("net position = %v, want all three orders netted to 30", position)
+	}
+}
+
+func TestExecuteOrderEnforcesOwnership(t *testing.T) {
+	config := DefaultConfig()
+	config.EnforceExecutionOwnership = true
+	service := newTestServiceWithConfig(t, config)
+	registerClient(t, service, "client-2", true)
+
+	router := gin.New()
+	router.POST("/orders/:order_id/execute", func(c *gin.Context) {
+		// InternalAuth sets the caller's client ID from its token
+		c.Set("clientID", c.GetHeader("X-Client-ID"))
+	}, NewGinHandlers(service).ExecuteOrderHandler())
+	execute := func(orderID, clientID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/orders/"+orderID+"/execute", nil)
+		req.Header.Set("Idempotency-Key", uuid.New().String())
+		req.Header.Set("X-Client-ID", clientID)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	order := createTestOrder(t, service, newTestOrder())
+	if recorder := execute(order.OrderID, "client-2"); recorder.Code != http.StatusForbidden {
+		t.Errorf("mismatched client status = %d, want 403: %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder := execute(order.OrderID, testClientID); recorder.Code != http.StatusOK {
+		t.Errorf("owning client status = %d, want 200: %s", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestExecuteOrderOwnershipCheckDisabledByDefault(t *testing.T) {
+	service := newTestService(t)
+	order := createTestOrder(t, service, newTestOrder())
+
+	if _, err := service.ExecuteOrder(order.OrderID, "internal-system", uuid.New().String()); err != nil {
+		t.Errorf("ExecuteOrder on behalf of the client: %v", err)
+	}
+}
+
+func TestCreateOrderRejectsInvalidOrderType(t *testing.T) {
+	service := newTestService(t)
+	order := newTestOrder()
+	order.OrderType = "STOP"
+
+	if _, err := service.CreateOrder(order, uuid.New().String()); !errors.Is(err, ErrInvalidOrderType) {
+		t.Errorf("CreateOrder error = %v, want ErrInvalidOrderType", err)
+	}
+}
+
+func TestCreateOrderRetriesTransientDatabaseError(t *testing.T) {
+	service := newTestService(t)
+
+	// The first order insert fails as if SQLite were busy; the retried transaction succeeds
+	failures := 1
+	err := service.db.db.Callback().Create().Before("gorm:create").Register("test:inject_busy", func(tx *gorm.DB) {
+		if tx.Statement.Table == "orders" && failures > 0 {
+			failures--
+			tx.AddError(errors.New("database is locked"))
+		}
+	})
+	if err != nil {
+		t.Fatalf("failed to register callback: %v", err)
+	}
+
+	order := createTestOrder(t, service, newTestOrder())
+	if failures != 0 {
+		t.Fatal("transient failure was not injected")
+	}
+	if _, err := service.GetOrder(order.OrderID); err != nil {
+		t.Errorf("GetOrder after retry: %v", err)
+	}
+}
+
+func TestCreateOrderValidatesDisplayQuantity(t *testing.T) {
+	service := newTestService(t)
+
+	for _, display := range []float64{-1, 101} {
+		order := newTestOrder()
+		order.DisplayQuantity = display
+		if _, err := service.CreateOrder(order, uuid.New().String()); !errors.Is(err, ErrInvalidDisplayQty) {
+			t.Errorf("display quantity %v: CreateOrder error = %v, want ErrInvalidDisplayQty", display, err)
+		}
+	}
+
+	iceberg := newTestOrder()
+	iceberg.DisplayQuantity = 100
+	createTestOrder(t, service, iceberg)
+}
+
+func TestCreateOrderValidatesCurrency(t *testing.T) {
+	config := DefaultConfig()
+	config.DefaultCurrency = "GBP"
+	service := newTestServiceWithConfig(t, config)
+	router := gin.New()
+	router.POST("/orders", authenticate(testClientID, "trade"), NewGinHandlers(service).CreateOrderHandler())
+
+	tests := []struct {
+		currency     string
+		wantStatus   int
+		wantCurrency string
+	}{
+		{"eur", http.StatusCreated, "EUR"},
+		{"", http.StatusCreated, "GBP"}, // Omitted currency takes the configured default
+		{"XYZ", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		order := newTestOrder()
+		order.Currency = tt.currency
+		recorder := performRequest(router, http.MethodPost, "/orders", order, uuid.New().String())
+		if recorder.Code != tt.wantStatus {
+			t.Errorf("currency %q: status = %d, want %d: %s", tt.currency, recorder.Code, tt.wantStatus, recorder.Body.String())
+			continue
+		}
+		if tt.wantStatus != http.StatusCreated {
+			if code := errorCode(t, recorder); code != RejectCodeInvalidOrder {
+				t.Errorf("currency %q: code = %s, want %s", tt.currency, code, RejectCodeInvalidOrder)
+			}
+			continue
+		}
+		var envelope struct {
+			Data types.Order `json:"data"`
+		}
+		if err := json.Unmarshal(recorder.Body.Bytes(), &envelope); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if envelope.Data.Currency != tt.wantCurrency {
+			t.Errorf("currency %q: order currency = %s, want %s", tt.currency, envelope.Data.Currency, tt.wantCurrency)
+		}
+	}
+}
+
+func TestPostOnlyOrderRestsInsteadOfCrossing(t *testing.T) {
+	service := newTestService(t)
+	router := gin.New()
+	router.POST("/execution/:order_id", authenticate(testClientID), NewGinHandlers(service).ExecuteOrderHandler())
+
+	postOnly := newTestOrder()
+	postOnly.PostOnly = true
+	createTestOrder(t, service, postOnly)
+
+	recorder := performRequest(router, http.MethodPost, "/execution/"+postOnly.OrderID, nil, uuid.New().String())
+	if recorder.Code != http.StatusConflict {
+		t.Fatalf("post-only execution status = %d, want 409: %s", recorder.Code, recorder.Body.String())
+	}
+	stored, err := service.GetOrder(postOnly.OrderID)
+	if err != nil {
+		t.Fatalf("GetOrder: %v", err)
+	}
+	if stored.Status != "PENDING" {
+		t.Errorf("post-only order status = %s, want it resting as PENDING", stored.Status)
+	}
+
+	// The same order without the flag takes liquidity and fills
+	taker := createTestOrder(t, service, newTestOrder())
+	if recorder := performRequest(router, http.MethodPost, "/execution/"+taker.OrderID, nil, uuid.New().String()); recorder.Code != http.StatusOK {
+		t.Errorf("taker execution status = %d, want 200: %s", recorder.Code, recorder.Body.String())
+	}
+
+	market := newTestOrder()
+	market.OrderType = "MARKET"
+	market.PostOnly = true
+	if _, err := service.CreateOrder(market, uuid.New().String()); !errors.Is(err, ErrPostOnlyNotLimit) {
+		t.Errorf("post-only market order error = %v, want ErrPostOnlyNotLimit", err)
+	}
+}
+
+func TestCreateOrderRejectsInvalidSide(t *testing.T) {
+	service := newTestService(t)
+	for _, side := range []string{"", "HOLD"} {
+		order := newTestOrder()
+		order.Side = side
+		if _, err := service.CreateOrder(order, uuid.New().String()); !errors.Is(err, ErrInvalidSide) {
+			t.Errorf("side %q: CreateOrder error = %v, want ErrInvalidSide", side, err)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package trading
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/klear-api/internal/types"
+	"github.com/ksred/klear-api/pkg/response"
+)
+
+// OrderValidation reports whether an order would be accepted, and why not
+type OrderValidation struct {
+	Valid   bool         `json:"valid"`
+	Reasons []string     `json:"reasons"`
+	Order   *types.Order `json:"order"` // The order as it would be submitted, after normalization and defaults
+}
+
+// ValidateOrder runs the checks applied when an order is created without submitting it
+// Every failing check is reported, not just the first. Nothing is persisted
+// Parameters:
+//   - order: The order to check
+func (s *Service) ValidateOrder(order types.Order) *OrderValidation {
+	s.normalizeOrder(&order)
+	errs := s.checkOrder(&order, s.clock.Now())
+
+	reasons := make([]string, 0, len(errs))
+	for _, err := range errs {
+		reasons = append(reasons, err.Error())
+	}
+
+	return &OrderValidation{
+		Valid:   len(errs) == 0,
+		Reasons: reasons,
+		Order:   &order,
+	}
+}
+
+// ValidateOrderHandler handles POST requests for a pre-trade check of an order
+// Requires a valid JWT token
+// Request body has the same shape as order creation
+func (h *GinHandlers) ValidateOrderHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var order types.Order
+		if err := c.ShouldBindJSON(&order); err != nil {
+			response.BindError(c, err)
+			return
+		}
+
+		response.SuccessWithStatus(c, h.service.ValidateOrder(order), http.StatusOK)
+	}
+}
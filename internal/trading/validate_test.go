@@ -0,0 +1,68 @@
+package trading
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/klear-api/internal/types"
+)
+
+// validateOrder posts the order to the pre-trade check and decodes the result
+func validateOrder(t *testing.T, service *Service, order *types.Order) *OrderValidation {
+	t.Helper()
+	router := gin.New()
+	router.POST("/orders/validate", authenticate(testClientID), NewGinHandlers(service).ValidateOrderHandler())
+
+	recorder := performRequest(router, http.MethodPost, "/orders/validate", order, "")
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", recorder.Code, recorder.Body.String())
+	}
+	var envelope struct {
+		Data OrderValidation `json:"data"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	return &envelope.Data
+}
+
+func TestValidateOrderHandlerPassesValidOrder(t *testing.T) {
+	service := newTestService(t)
+
+	validation := validateOrder(t, service, newTestOrder())
+	if !validation.Valid || len(validation.Reasons) != 0 {
+		t.Errorf("validation = %+v, want a pass with no reasons", validation)
+	}
+	assertNoOrders(t, service)
+}
+
+func TestValidateOrderHandlerReportsNotionalCap(t *testing.T) {
+	config := DefaultConfig()
+	config.MaxOrderNotional = 10000
+	service := newTestServiceWithConfig(t, config)
+
+	// 100 @ 150 is 15,000, above the cap
+	validation := validateOrder(t, service, newTestOrder())
+	if validation.Valid {
+		t.Fatal("validation passed, want the notional cap to fail it")
+	}
+	if len(validation.Reasons) != 1 || !strings.Contains(validation.Reasons[0], ErrNotionalOutOfBounds.Error()) {
+		t.Errorf("reasons = %v, want only the notional cap", validation.Reasons)
+	}
+	assertNoOrders(t, service)
+}
+
+// assertNoOrders fails the test if any order was stored
+func assertNoOrders(t *testing.T, service *Service) {
+	t.Helper()
+	var count int64
+	if err := service.db.db.Model(&types.Order{}).Count(&count).Error; err != nil {
+		t.Fatalf("failed to count orders: %v", err)
+	}
+	if count != 0 {
+		t.Errorf("stored %d orders, want none", count)
+	}
+}
@@ -0,0 +1,20 @@
+package trading
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/klear-api/internal/exchange"
+	"github.com/ksred/klear-api/pkg/response"
+)
+
+// GetVenueStats returns today's fill-quality stats for each execution venue
+func (s *Service) GetVenueStats() []exchange.VenueStats {
+	return exchange.GetVenueStats()
+}
+
+// GetVenueStatsHandler handles GET requests for per-venue execution stats
+// Requires internal authentication
+func (h *GinHandlers) GetVenueStatsHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		response.Success(c, h.service.GetVenueStats())
+	}
+}
@@ -0,0 +1,20 @@
+package types
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Client represents a trading client and its account status
+type Client struct {
+	gorm.Model `json:"-"`
+	ClientID   string    `gorm:"uniqueIndex" json:"client_id"`
+	Name       string    `json:"name"`
+	Active     bool      `json:"active"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+
+	// MaxOpenOrders caps the client's open orders, overriding the configured default; 0 applies the default
+	MaxOpenOrders int `json:"max_open_orders,omitempty"`
+}
@@ -0,0 +1,28 @@
+package types
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// Errors returned when a trade references an order or execution that has been soft-deleted
+var (
+	ErrOrderDeleted     = errors.New("referenced order has been deleted")
+	ErrExecutionDeleted = errors.New("referenced execution has been deleted")
+)
+
+// IsSoftDeleted reports whether a lookup of model by column = id that failed with err missed
+// a soft-deleted row, which GORM excludes from queries by default
+func IsSoftDeleted(db *gorm.DB, model interface{}, column, id string, err error) bool {
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return false
+	}
+	var count int64
+	if err := db.Unscoped().Model(model).
+		Where(column+" = ? AND deleted_at IS NOT NULL", id).
+		Count(&count).Error; err != nil {
+		return false
+	}
+	return count > 0
+}
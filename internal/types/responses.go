@@ -23,6 +23,9 @@ type SettlementResponse struct {
 	SettlementDate    time.Time `json:"settlement_date"`
 	SettlementAccount string    `json:"settlement_account"`
 	Currency          string    `json:"currency"`
+	GrossFees         float64   `json:"gross_fees"`
+	FeeRebate         float64   `json:"fee_rebate"`
+	SettlementFees    float64   `json:"settlement_fees"`
 	CreatedAt         time.Time `json:"created_at"`
 	UpdatedAt         time.Time `json:"updated_at"`
 } 
\ No newline at end of file
@@ -3,45 +3,147 @@ package types
 import (
 	"time"
 
+	"github.com/ksred/klear-api/pkg/money"
 	"gorm.io/gorm"
 )
 
 type Order struct {
-	gorm.Model `json:"-"`
-	OrderID    string    `gorm:"uniqueIndex" json:"order_id"`
-	ClientID   string    `json:"client_id"`
-	Symbol     string    `json:"symbol"`
-	Side       string    `json:"side"`       // BUY or SELL
-	OrderType  string    `json:"order_type"` // MARKET or LIMIT
-	Quantity   float64   `json:"quantity"`
-	Price      float64   `json:"price"`
-	Status     string    `json:"status"` // PENDING, FILLED, CANCELLED
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
+	gorm.Model      `json:"-"`
+	OrderID         string     `gorm:"uniqueIndex" json:"order_id"`
+	ClientID        string     `json:"client_id"`
+	Symbol          string     `json:"symbol"`
+	Side            string     `json:"side"`       // BUY or SELL
+	OrderType       string     `json:"order_type"` // MARKET or LIMIT
+	Quantity        float64    `json:"quantity"`
+	Price           float64    `json:"price"`
+	QuantityUnits   int64      `json:"-"`                                        // Quantity as exact integer units of 10^-8
+	PriceUnits      int64      `json:"-"`                                        // Price as exact integer units of 10^-8
+	Currency        string     `json:"currency"`                                 // ISO 4217 code, defaults to the configured currency
+	PostOnly        bool       `json:"post_only,omitempty"`                      // Limit order that must never take liquidity
+	ReduceOnly      bool       `json:"reduce_only,omitempty"`                    // Order that may only reduce the client's position in the symbol
+	DisplayQuantity float64    `json:"display_quantity,omitempty"`               // Iceberg slice size; 0 exposes the full quantity
+	TimeInForce     string     `json:"time_in_force"`                            // GTC or GTD
+	ExpiresAt       *time.Time `gorm:"index" json:"expires_at,omitempty"`        // Expiry of GTD orders
+	FilledQuantity  float64    `json:"filled_quantity"`                          // Quantity executed so far; the rest remains to be executed
+	Status          string     `json:"status"`                                   // PENDING, PARTIALLY_FILLED, FILLED, CANCELLED, EXPIRED, BUSTED
+	ReplacesOrderID string     `gorm:"index" json:"replaces_order_id,omitempty"` // Order replaced via cancel/replace
+	ClientTag       string     `gorm:"index" json:"client_tag,omitempty"`        // Free-form client label for correlation, e.g. strategy name
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// BeforeSave stores the price and quantity as exact scaled integers alongside the float columns
+func (o *Order) BeforeSave(tx *gorm.DB) error {
+	o.QuantityUnits = money.ToUnits(o.Quantity)
+	o.PriceUnits = money.ToUnits(o.Price)
+	return nil
+}
+
+// AfterFind restores the price and quantity from their exact scaled integers, so a price
+// like 100.10 is read back as 100.10 rather than a neighbouring binary value
+// Rows saved before the integer columns existed keep their float values
+func (o *Order) AfterFind(tx *gorm.DB) error {
+	if o.QuantityUnits != 0 {
+		o.Quantity = money.FromUnits(o.QuantityUnits)
+	}
+	if o.PriceUnits != 0 {
+		o.Price = money.FromUnits(o.PriceUnits)
+	}
+	return nil
 }
 
 type ExchangeFill struct {
+	gorm.Model     `json:"-"`
+	FillID         string    `gorm:"uniqueIndex" json:"fill_id"`
+	ExecutionID    string    `json:"execution_id"`
+	ExchangeID     string    `json:"exchange_id"`
+	ExchangeName   string    `json:"exchange_name"`
+	Price          float64   `json:"price"`
+	Quantity       float64   `json:"quantity"`
+	PriceUnits     int64     `json:"-"` // Price as exact integer units of 10^-8
+	QuantityUnits  int64     `json:"-"` // Quantity as exact integer units of 10^-8
+	FeeRate        float64   `json:"fee_rate"`
+	FeeAmount      float64   `json:"fee_amount"`
+	FeeAmountUnits int64     `json:"-"` // Fee as exact integer units of 10^-8
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// BeforeSave stores the price, quantity and fee as exact scaled integers alongside the float columns
+func (f *ExchangeFill) BeforeSave(tx *gorm.DB) error {
+	f.PriceUnits = money.ToUnits(f.Price)
+	f.QuantityUnits = money.ToUnits(f.Quantity)
+	f.FeeAmountUnits = money.ToUnits(f.FeeAmount)
+	return nil
+}
+
+// AfterFind restores the price, quantity and fee from their exact scaled integers
+// Rows saved before the integer columns existed keep their float values
+func (f *ExchangeFill) AfterFind(tx *gorm.DB) error {
+	if f.PriceUnits != 0 {
+		f.Price = money.FromUnits(f.PriceUnits)
+	}
+	if f.QuantityUnits != 0 {
+		f.Quantity = money.FromUnits(f.QuantityUnits)
+	}
+	if f.FeeAmountUnits != 0 {
+		f.FeeAmount = money.FromUnits(f.FeeAmountUnits)
+	}
+	return nil
+}
+
+// VenueAttempt records a venue attempt that failed while routing an order, for routing analysis
+type VenueAttempt struct {
 	gorm.Model   `json:"-"`
-	FillID       string    `gorm:"uniqueIndex" json:"fill_id"`
-	ExecutionID  string    `json:"execution_id"`
+	AttemptID    string    `gorm:"uniqueIndex" json:"attempt_id"`
+	OrderID      string    `gorm:"index" json:"order_id"`
+	ExecutionID  string    `json:"execution_id,omitempty"` // Execution the order ended up with; empty if routing failed
 	ExchangeID   string    `json:"exchange_id"`
 	ExchangeName string    `json:"exchange_name"`
-	Price        float64   `json:"price"`
-	Quantity     float64   `json:"quantity"`
-	FeeRate      float64   `json:"fee_rate"`
-	FeeAmount    float64   `json:"fee_amount"`
+	Reason       string    `json:"reason"`
 	CreatedAt    time.Time `json:"created_at"`
 }
 
 type Execution struct {
-	gorm.Model    `json:"-"`
-	ExecutionID   string         `gorm:"uniqueIndex" json:"execution_id"`
-	OrderID       string         `json:"order_id"`
-	TotalQuantity float64       `json:"total_quantity"`
-	AveragePrice  float64       `json:"average_price"`
-	Side          string        `json:"side"`
-	Status        string        `json:"status"` // PENDING, COMPLETED, FAILED
-	Fills         []ExchangeFill `json:"fills,omitempty" gorm:"foreignKey:ExecutionID"`
-	CreatedAt     time.Time     `json:"created_at"`
-	UpdatedAt     time.Time     `json:"updated_at"`
-} 
\ No newline at end of file
+	gorm.Model         `json:"-"`
+	ExecutionID        string         `gorm:"uniqueIndex" json:"execution_id"`
+	OrderID            string         `json:"order_id"`
+	TotalQuantity      float64        `json:"total_quantity"`
+	AveragePrice       float64        `json:"average_price"`
+	TotalQuantityUnits int64          `json:"-"` // Total quantity as exact integer units of 10^-8
+	AveragePriceUnits  int64          `json:"-"` // Average price as exact integer units of 10^-8
+	Side               string         `json:"side"`
+	Status             string         `json:"status"` // PENDING, COMPLETED, FAILED, BUSTED
+	Fills              []ExchangeFill `json:"fills,omitempty" gorm:"foreignKey:ExecutionID;references:ExecutionID"`
+	Allocations        []Allocation   `json:"allocations,omitempty" gorm:"foreignKey:ExecutionID;references:ExecutionID"`
+	CreatedAt          time.Time      `json:"created_at"`
+	UpdatedAt          time.Time      `json:"updated_at"`
+}
+
+// BeforeSave stores the total quantity and average price as exact scaled integers alongside the float columns
+func (e *Execution) BeforeSave(tx *gorm.DB) error {
+	e.TotalQuantityUnits = money.ToUnits(e.TotalQuantity)
+	e.AveragePriceUnits = money.ToUnits(e.AveragePrice)
+	return nil
+}
+
+// AfterFind restores the total quantity and average price from their exact scaled integers
+// Rows saved before the integer columns existed keep their float values
+func (e *Execution) AfterFind(tx *gorm.DB) error {
+	if e.TotalQuantityUnits != 0 {
+		e.TotalQuantity = money.FromUnits(e.TotalQuantityUnits)
+	}
+	if e.AveragePriceUnits != 0 {
+		e.AveragePrice = money.FromUnits(e.AveragePriceUnits)
+	}
+	return nil
+}
+
+// Allocation assigns part of an executed block to a client sub-account
+type Allocation struct {
+	gorm.Model   `json:"-"`
+	AllocationID string    `gorm:"uniqueIndex" json:"allocation_id"`
+	ExecutionID  string    `gorm:"index" json:"execution_id"`
+	SubAccount   string    `json:"sub_account"`
+	Quantity     float64   `json:"quantity"`
+	CreatedAt    time.Time `json:"created_at"`
+}
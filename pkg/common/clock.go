@@ -0,0 +1,63 @@
+package common
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock provides the current time, allowing time-dependent rules such as
+// market hours to be evaluated against a controlled time
+type Clock interface {
+	Now() time.Time
+}
+
+// RealClock is a Clock backed by the system wall clock
+type RealClock struct{}
+
+// Now returns the current system time
+func (RealClock) Now() time.Time {
+	return time.Now()
+}
+
+// FixedClock is a Clock that always reports the same time
+type FixedClock struct {
+	Time time.Time
+}
+
+// Now returns the fixed time
+func (c FixedClock) Now() time.Time {
+	return c.Time
+}
+
+// FakeClock is a Clock for tests whose time only moves when it is set or advanced
+// It is safe for concurrent use, so it can drive background workers such as the settlement processor
+type FakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewFakeClock creates a FakeClock reporting the given time
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+// Now returns the fake clock's current time
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the fake clock forward by d
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Set moves the fake clock to the given time
+func (c *FakeClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = now
+}
@@ -0,0 +1,29 @@
+package common
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// executionIDPrefix prefixes the IDs assigned to executions, which double as trade IDs
+const executionIDPrefix = "EXEC-"
+
+// IsValidTradeID reports whether id is a well-formed trade ID, either a UUID
+// or an execution ID of the form EXEC-<digits>
+func IsValidTradeID(id string) bool {
+	if _, err := uuid.Parse(id); err == nil {
+		return true
+	}
+
+	digits, ok := strings.CutPrefix(id, executionIDPrefix)
+	if !ok || digits == "" {
+		return false
+	}
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,24 @@
+package common
+
+import "testing"
+
+func TestIsValidTradeID(t *testing.T) {
+	tests := []struct {
+		id   string
+		want bool
+	}{
+		{"0b9f4c1e-6a53-4a0e-9c1a-3f1d2e4b5a6c", true},
+		{"EXEC-4885918251643617117", true},
+		{"", false},
+		{"EXEC-", false},
+		{"EXEC-12a", false},
+		{"exec-123", false},
+		{"not-a-trade", false},
+		{"../settlement", false},
+	}
+	for _, tt := range tests {
+		if got := IsValidTradeID(tt.id); got != tt.want {
+			t.Errorf("IsValidTradeID(%q) = %v, want %v", tt.id, got, tt.want)
+		}
+	}
+}
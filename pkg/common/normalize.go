@@ -0,0 +1,15 @@
+package common
+
+import "strings"
+
+// NormalizeSymbol trims whitespace and upper-cases a ticker symbol so that
+// " aapl " and "AAPL" refer to the same instrument
+func NormalizeSymbol(symbol string) string {
+	return strings.ToUpper(strings.TrimSpace(symbol))
+}
+
+// NormalizeID trims surrounding whitespace from an identifier
+// IDs are case-sensitive, so casing is preserved
+func NormalizeID(id string) string {
+	return strings.TrimSpace(id)
+}
@@ -0,0 +1,121 @@
+package common
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid pagination cursor")
+	ErrInvalidLimit  = errors.New("limit must be a positive integer")
+	ErrInvalidOffset = errors.New("offset must be a non-negative integer")
+)
+
+// Cursor marks the last row of a page in a listing ordered by creation time, then row ID
+// The next page starts strictly after it, so rows are neither repeated nor skipped however deep the listing goes
+type Cursor struct {
+	CreatedAt time.Time
+	ID        uint
+}
+
+// EncodeCursor returns the opaque cursor string for the row with the given creation time and ID
+func EncodeCursor(createdAt time.Time, id uint) string {
+	raw := fmt.Sprintf("%d:%d", createdAt.UnixNano(), id)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeCursor parses a cursor string produced by EncodeCursor
+func DecodeCursor(s string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	nanos, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	i, err := strconv.ParseUint(id, 10, 64)
+	if err != nil || i == 0 {
+		return nil, ErrInvalidCursor
+	}
+	return &Cursor{CreatedAt: time.Unix(0, n), ID: uint(i)}, nil
+}
+
+// PageSize bounds the pages of a listing
+type PageSize struct {
+	Default int // Page size used when the request gives no limit
+	Max     int // Largest page size served; larger limits are clamped to it
+}
+
+// DefaultPageSize returns the page size bounds used when nothing is overridden
+func DefaultPageSize() PageSize {
+	return PageSize{Default: 50, Max: 500}
+}
+
+// Page selects one page of a listing
+// When Cursor is set the page starts after it and Offset is ignored; otherwise Offset rows are skipped
+type Page struct {
+	Limit  int
+	Offset int
+	Cursor *Cursor
+}
+
+// ParsePage builds a Page from the raw limit, offset and cursor query values
+// An empty limit uses the default page size and larger limits are clamped to the maximum
+func ParsePage(limit, offset, cursor string, size PageSize) (Page, error) {
+	page := Page{Limit: size.Default}
+
+	if limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			return Page{}, ErrInvalidLimit
+		}
+		page.Limit = n
+	}
+	if page.Limit > size.Max {
+		page.Limit = size.Max
+	}
+	if offset != "" {
+		n, err := strconv.Atoi(offset)
+		if err != nil || n < 0 {
+			return Page{}, ErrInvalidOffset
+		}
+		page.Offset = n
+	}
+	if cursor != "" {
+		c, err := DecodeCursor(cursor)
+		if err != nil {
+			return Page{}, err
+		}
+		page.Cursor = c
+	}
+	return page, nil
+}
+
+// Apply is a GORM scope that orders a query newest first by created_at, then id, and selects the page
+// One row more than the limit is fetched so callers can tell whether another page follows
+func (p Page) Apply(db *gorm.DB) *gorm.DB {
+	if p.Cursor != nil {
+		db = db.Where("(created_at < ? OR (created_at = ? AND id < ?))",
+			p.Cursor.CreatedAt, p.Cursor.CreatedAt, p.Cursor.ID)
+	} else if p.Offset > 0 {
+		db = db.Offset(p.Offset)
+	}
+	return db.Order("created_at DESC").Order("id DESC").Limit(p.Limit + 1)
+}
+
+// HasMore reports whether a result fetched with Apply holds more rows than the page, in which
+// case the extra row should be dropped and a next cursor returned
+func (p Page) HasMore(rows int) bool {
+	return rows > p.Limit
+}
@@ -0,0 +1,41 @@
+package common
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParsePageDefaultsAndClamps(t *testing.T) {
+	size := DefaultPageSize()
+	tests := []struct {
+		limit string
+		want  int
+	}{
+		{"", 50},
+		{"10", 10},
+		{"500", 500},
+		{"100000", 500},
+	}
+	for _, tt := range tests {
+		page, err := ParsePage(tt.limit, "", "", size)
+		if err != nil {
+			t.Fatalf("ParsePage(%q): %v", tt.limit, err)
+		}
+		if page.Limit != tt.want {
+			t.Errorf("ParsePage(%q).Limit = %d, want %d", tt.limit, page.Limit, tt.want)
+		}
+	}
+}
+
+func TestParsePageRejectsInvalidValues(t *testing.T) {
+	size := DefaultPageSize()
+	if _, err := ParsePage("0", "", "", size); !errors.Is(err, ErrInvalidLimit) {
+		t.Errorf("limit 0 error = %v, want ErrInvalidLimit", err)
+	}
+	if _, err := ParsePage("", "-1", "", size); !errors.Is(err, ErrInvalidOffset) {
+		t.Errorf("offset -1 error = %v, want ErrInvalidOffset", err)
+	}
+	if _, err := ParsePage("", "", "not a cursor", size); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("garbage cursor error = %v, want ErrInvalidCursor", err)
+	}
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/klear-api/pkg/response"
+)
+
+// DatabaseHealth reports whether the database is reachable
+type DatabaseHealth interface {
+	// Healthy reports whether the database was reachable at its most recent check
+	Healthy() bool
+	// Check pings the database now, records the result and returns the error if it is unreachable
+	Check(ctx context.Context) error
+}
+
+// RequireDatabase rejects writes with 503 SERVICE_UNAVAILABLE while the database is down,
+// so clients see why the request failed instead of an opaque 500
+// While the last check passed, each write pings the database first, so an outage that began
+// since that check is caught before the handler runs; once a check fails, writes are turned away
+// without a ping until a background check finds the database up again
+// Reads pass through and fail on their own, and middleware ahead of it such as the rate limiter
+// keeps working since it does not depend on the database
+func RequireDatabase(health DatabaseHealth) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		if !health.Healthy() || health.Check(c.Request.Context()) != nil {
+			response.ServiceUnavailable(c, "Database is unavailable; writes are suspended until it recovers, please retry later")
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// stubHealth is a DatabaseHealth whose checks return err, recording the result as a monitor would
+type stubHealth struct {
+	healthy bool
+	err     error
+	checks  int
+}
+
+func (h *stubHealth) Healthy() bool { return h.healthy }
+
+func (h *stubHealth) Check(ctx context.Context) error {
+	h.checks++
+	h.healthy = h.err == nil
+	return h.err
+}
+
+func TestRequireDatabaseChecksWritesOnly(t *testing.T) {
+	health := &stubHealth{healthy: true, err: errors.New("database is closed")}
+	router := gin.New()
+	router.Use(RequireDatabase(health))
+	router.Any("/orders", func(c *gin.Context) { c.Status(http.StatusOK) })
+	serve := func(method string) int {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(method, "/orders", nil))
+		return recorder.Code
+	}
+
+	if code := serve(http.MethodGet); code != http.StatusOK || health.checks != 0 {
+		t.Errorf("read = %d after %d checks, want 200 without a check", code, health.checks)
+	}
+	// The outage started after the last check passed; the write's own check catches it
+	if code := serve(http.MethodPost); code != http.StatusServiceUnavailable {
+		t.Errorf("write during an outage status = %d, want 503", code)
+	}
+	// Once the database is known down, writes are turned away without another check
+	if code := serve(http.MethodDelete); code != http.StatusServiceUnavailable || health.checks != 1 {
+		t.Errorf("write while down = %d after %d checks, want 503 after the first check only", code, health.checks)
+	}
+
+	health.healthy, health.err = true, nil
+	if code := serve(http.MethodPost); code != http.StatusOK {
+		t.Errorf("write after recovery status = %d, want 200", code)
+	}
+}
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -14,77 +15,113 @@ import (
 
 type visitor struct {
 	limiter  *rate.Limiter
+	clientIP string
+	path     string
 	lastSeen time.Time
 }
 
-var (
-	visitors = make(map[string]*visitor)
-	mu       sync.RWMutex
+// RateLimitConfig holds the request rate allowed per client for each endpoint type
+type RateLimitConfig struct {
+	Auth    rate.Limit
+	Trading rate.Limit
+	Status  rate.Limit
+}
 
-	// Configure limits per endpoint type
-	authLimit    = rate.Limit(10.0 / 60.0)   // 10 requests per minute
-	tradingLimit = rate.Limit(100.0 / 60.0)  // 100 requests per minute
-	statusLimit  = rate.Limit(1000.0 / 60.0) // 1000 requests per minute
-)
+// DefaultRateLimitConfig returns the rate limits used when nothing is overridden
+func DefaultRateLimitConfig() RateLimitConfig {
+	return RateLimitConfig{
+		Auth:    rate.Limit(10.0 / 60.0),   // 10 requests per minute
+		Trading: rate.Limit(100.0 / 60.0),  // 100 requests per minute
+		Status:  rate.Limit(1000.0 / 60.0), // 1000 requests per minute
+	}
+}
+
+// ParseRateLimit parses a limit written as requests/seconds, e.g. "100/60" for 100 requests per minute
+func ParseRateLimit(value string) (rate.Limit, error) {
+	requestsPart, secondsPart, ok := strings.Cut(strings.TrimSpace(value), "/")
+	if !ok {
+		return 0, fmt.Errorf("invalid rate limit %q: expected requests/seconds", value)
+	}
+	requests, err := strconv.ParseFloat(strings.TrimSpace(requestsPart), 64)
+	if err != nil || requests <= 0 {
+		return 0, fmt.Errorf("invalid rate limit %q: requests must be a positive number", value)
+	}
+	seconds, err := strconv.ParseFloat(strings.TrimSpace(secondsPart), 64)
+	if err != nil || seconds <= 0 {
+		return 0, fmt.Errorf("invalid rate limit %q: seconds must be a positive number", value)
+	}
+	return rate.Limit(requests / seconds), nil
+}
 
-// Cleanup old visitors periodically
-func init() {
-	go cleanupVisitors()
+// RateLimiter enforces per-client request rates for each endpoint type
+type RateLimiter struct {
+	config   RateLimitConfig
+	visitors map[string]*visitor
+	mu       sync.Mutex
 }
 
-func getLimiter(path, clientIP string) *rate.Limiter {
-	mu.Lock()
-	defer mu.Unlock()
+// NewRateLimiter creates a rate limiter with the given limits and starts its cleanup of idle clients
+func NewRateLimiter(config RateLimitConfig) *RateLimiter {
+	l := &RateLimiter{
+		config:   config,
+		visitors: make(map[string]*visitor),
+	}
+	go l.cleanupVisitors()
+	return l
+}
+
+func (l *RateLimiter) getLimiter(path, clientIP string) *rate.Limiter {
+	l.mu.Lock()
+	defer l.mu.Unlock()
 
 	key := clientIP + ":" + path
-	v, exists := visitors[key]
+	v, exists := l.visitors[key]
 
 	if !exists {
 		var limit rate.Limit
 		switch {
 		case strings.HasPrefix(path, "/api/v1/auth"):
-			limit = authLimit
+			limit = l.config.Auth
 		case strings.HasPrefix(path, "/api/v1/orders"):
-			limit = tradingLimit
+			limit = l.config.Trading
 		case strings.HasPrefix(path, "/api/v1/status"):
-			limit = statusLimit
+			limit = l.config.Status
 		default:
 			limit = rate.Inf // No limit for other paths
 		}
 
 		v = &visitor{
 			limiter:  rate.NewLimiter(limit, 1), // burst of 1
+			clientIP: clientIP,
+			path:     path,
 			lastSeen: time.Now(),
 		}
-		visitors[key] = v
+		l.visitors[key] = v
 	}
 
 	v.lastSeen = time.Now()
 	return v.limiter
 }
 
-func cleanupVisitors() {
+func (l *RateLimiter) cleanupVisitors() {
 	for {
 		time.Sleep(time.Minute)
 
-		mu.Lock()
-		for ip, v := range visitors {
+		l.mu.Lock()
+		for ip, v := range l.visitors {
 			if time.Since(v.lastSeen) > 3*time.Minute {
-				delete(visitors, ip)
+				delete(l.visitors, ip)
 			}
 		}
-		mu.Unlock()
+		l.mu.Unlock()
 	}
 }
 
-func RateLimit() gin.HandlerFunc {
+// Middleware returns the handler that rejects requests over the client's rate
+func (l *RateLimiter) Middleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		clientID := c.GetString("clientID")
-		if clientID == "" {
-			clientID = c.ClientIP()
-		}
-
-		limiter := getLimiter(c.FullPath(), clientID)
+		// The limiter runs ahead of authentication, so requests are keyed on the client IP
+		limiter := l.getLimiter(c.FullPath(), c.ClientIP())
 		if !limiter.Allow() {
 			response.BadRequest(c, "Rate limit exceeded. Please try again later.")
 			c.Abort()
@@ -95,7 +132,14 @@ func RateLimit() gin.HandlerFunc {
 	}
 }
 
-func JWTAuth() gin.HandlerFunc {
+// RevocationList reports whether a token has been revoked, by the token's ID (jti claim)
+type RevocationList interface {
+	IsRevoked(tokenID string) bool
+}
+
+// JWTAuth verifies the bearer token against the JWT signing secret and sets its claims in the context
+// Tokens in revocations are rejected; revocations may be nil when tokens cannot be revoked
+func JWTAuth(secret string, revocations RevocationList) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		bearerToken := strings.Split(c.GetHeader("Authorization"), " ")
 		if len(bearerToken) != 2 {
@@ -109,7 +153,7 @@ func JWTAuth() gin.HandlerFunc {
 			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 			}
-			return []byte("klear-secret-key"), nil
+			return []byte(secret), nil
 		})
 
 		if err != nil {
@@ -135,6 +179,12 @@ func JWTAuth() gin.HandlerFunc {
 			}
 		}
 
+		if isRevoked(revocations, claims) {
+			response.Unauthorized(c, "Token has been revoked")
+			c.Abort()
+			return
+		}
+
 		// Set individual claims in the context
 		for key, value := range claims {
 			c.Set(key, value)
@@ -150,14 +200,37 @@ func JWTAuth() gin.HandlerFunc {
 	}
 }
 
-func InternalAuth() gin.HandlerFunc {
+// RequirePermission rejects requests whose token does not grant the permission with 403
+// It must run after JWTAuth, which puts the token claims in the context
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, _ := c.Get("claims")
+		if jwtClaims, ok := claims.(jwt.MapClaims); ok {
+			if granted, ok := jwtClaims["permissions"].([]interface{}); ok {
+				for _, value := range granted {
+					if value == permission {
+						c.Next()
+						return
+					}
+				}
+			}
+		}
+
+		response.Forbidden(c, fmt.Sprintf("API key lacks the %s permission", permission))
+		c.Abort()
+	}
+}
+
+// InternalAuth verifies the bearer token of internal requests against the JWT signing secret
+// Tokens in revocations are rejected as by JWTAuth; revocations may be nil
+func InternalAuth(secret string, revocations RevocationList) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// For internal requests, we could use several possibilities depending on the implementation:
 		// - IP whitelisting
 		// - API key
 		// - JWT token
 		// For now, we will use a simple API key, the same as for the public API
-		clientID, err := validateAndExtractToken(c)
+		clientID, err := validateAndExtractToken(c, secret, revocations)
 		if err != nil {
 			return
 		}
@@ -167,7 +240,16 @@ func InternalAuth() gin.HandlerFunc {
 	}
 }
 
-func validateAndExtractToken(c *gin.Context) (string, error) {
+// isRevoked reports whether the token with the given claims is in revocations
+func isRevoked(revocations RevocationList, claims jwt.MapClaims) bool {
+	if revocations == nil {
+		return false
+	}
+	tokenID, _ := claims["jti"].(string)
+	return revocations.IsRevoked(tokenID)
+}
+
+func validateAndExtractToken(c *gin.Context, secret string, revocations RevocationList) (string, error) {
 	authHeader := c.GetHeader("Authorization")
 	if authHeader == "" {
 		response.Unauthorized(c, "Authorization header required")
@@ -187,7 +269,7 @@ func validateAndExtractToken(c *gin.Context) (string, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return []byte("klear-secret-key"), nil
+		return []byte(secret), nil
 	})
 
 	if err != nil {
@@ -203,6 +285,12 @@ func validateAndExtractToken(c *gin.Context) (string, error) {
 		return "", fmt.Errorf("invalid token claims")
 	}
 
+	if isRevoked(revocations, claims) {
+		response.Unauthorized(c, "Token has been revoked")
+		c.Abort()
+		return "", fmt.Errorf("token has been revoked")
+	}
+
 	clientID, ok := claims["client_id"].(string)
 	if !ok {
 		response.Unauthorized(c, "Invalid client ID in token")
@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/klear-api/pkg/response"
+	"golang.org/x/time/rate"
+)
+
+// VisitorInfo describes the rate-limit state tracked for one client IP on one endpoint
+type VisitorInfo struct {
+	Key               string    `json:"key"` // Identifies the entry when purging
+	ClientIP          string    `json:"client_ip"`
+	Path              string    `json:"path"`
+	LastSeen          time.Time `json:"last_seen"`
+	Unlimited         bool      `json:"unlimited"`           // Path has no rate limit
+	RequestsPerMinute float64   `json:"requests_per_minute"` // 0 when unlimited
+	Burst             int       `json:"burst"`
+	TokensAvailable   float64   `json:"tokens_available"` // Requests the client could make right now
+}
+
+// Visitors returns the rate-limit state of every tracked client IP and endpoint, ordered by key
+func (l *RateLimiter) Visitors() []VisitorInfo {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	visitors := make([]VisitorInfo, 0, len(l.visitors))
+	for key, v := range l.visitors {
+		info := VisitorInfo{
+			Key:       key,
+			ClientIP:  v.clientIP,
+			Path:      v.path,
+			LastSeen:  v.lastSeen,
+			Unlimited: v.limiter.Limit() == rate.Inf,
+			Burst:     v.limiter.Burst(),
+		}
+		if !info.Unlimited {
+			info.RequestsPerMinute = float64(v.limiter.Limit()) * 60
+			info.TokensAvailable = math.Max(v.limiter.TokensAt(now), 0)
+		} else {
+			info.TokensAvailable = float64(info.Burst)
+		}
+		visitors = append(visitors, info)
+	}
+	sort.Slice(visitors, func(i, j int) bool { return visitors[i].Key < visitors[j].Key })
+	return visitors
+}
+
+// Purge forgets the rate-limit state of one client IP and endpoint, so its next request starts afresh
+// Reports whether the key was tracked
+func (l *RateLimiter) Purge(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	_, exists := l.visitors[key]
+	delete(l.visitors, key)
+	return exists
+}
+
+// PurgeAll forgets the rate-limit state of every client, returning how many entries were removed
+func (l *RateLimiter) PurgeAll() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	purged := len(l.visitors)
+	l.visitors = make(map[string]*visitor)
+	return purged
+}
+
+// ListVisitorsHandler handles GET requests for the rate limiter's tracked clients
+// Requires a token with the admin permission
+func (l *RateLimiter) ListVisitorsHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		response.Success(c, l.Visitors())
+	}
+}
+
+// PurgeVisitorsHandler handles DELETE requests to purge rate limiter state
+// Requires a token with the admin permission
+// Query parameter: key (optional); without it every entry is purged
+func (l *RateLimiter) PurgeVisitorsHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key, ok := c.GetQuery("key")
+		if !ok {
+			response.SuccessWithStatus(c, gin.H{"purged": l.PurgeAll()}, http.StatusOK)
+			return
+		}
+
+		if !l.Purge(key) {
+			response.NotFound(c, "no rate limit state tracked for key")
+			return
+		}
+		response.SuccessWithStatus(c, gin.H{"purged": 1}, http.StatusOK)
+	}
+}
@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testSecret = "test-secret"
+
+// serveOrdersFrom sends a request to the trading endpoint from the given client IP
+func serveOrdersFrom(router http.Handler, clientIP string) {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/orders", nil)
+	req.RemoteAddr = clientIP + ":1234"
+	router.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func TestRateLimiterListsAndPurgesVisitors(t *testing.T) {
+	limiter := NewRateLimiter(DefaultRateLimitConfig())
+	router := newOrdersRouter(limiter)
+	serveOrdersFrom(router, "10.0.0.1")
+	serveOrdersFrom(router, "10.0.0.2")
+
+	visitors := limiter.Visitors()
+	if len(visitors) != 2 {
+		t.Fatalf("visitors = %+v, want one per client IP", visitors)
+	}
+	first := visitors[0]
+	if first.Key != "10.0.0.1:/api/v1/orders" || first.ClientIP != "10.0.0.1" || first.Path != "/api/v1/orders" {
+		t.Errorf("first visitor = %+v, want 10.0.0.1 on /api/v1/orders", first)
+	}
+	// The single request allowed in the burst has been spent
+	if first.Unlimited || first.RequestsPerMinute != 100 || first.Burst != 1 || first.TokensAvailable >= 1 {
+		t.Errorf("first visitor limiter = %+v, want 100 per minute with its burst spent", first)
+	}
+
+	if !limiter.Purge(first.Key) {
+		t.Fatalf("Purge(%q) = false, want the tracked key purged", first.Key)
+	}
+	if limiter.Purge(first.Key) {
+		t.Errorf("Purge(%q) twice = true, want the key already gone", first.Key)
+	}
+	if visitors := limiter.Visitors(); len(visitors) != 1 || visitors[0].ClientIP != "10.0.0.2" {
+		t.Errorf("visitors after purge = %+v, want only 10.0.0.2", visitors)
+	}
+	if purged := limiter.PurgeAll(); purged != 1 || len(limiter.Visitors()) != 0 {
+		t.Errorf("PurgeAll = %d, want the remaining visitor purged", purged)
+	}
+}
+
+// signToken returns a token signed with testSecret for the client with the given permissions
+func signToken(t *testing.T, clientID string, permissions ...string) string {
+	t.Helper()
+	granted := make([]interface{}, len(permissions))
+	for i, permission := range permissions {
+		granted[i] = permission
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"client_id":   clientID,
+		"permissions": granted,
+		"exp":         time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte(testSecret))
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return token
+}
+
+func TestAdminRoutesRequireAdminPermission(t *testing.T) {
+	limiter := NewRateLimiter(DefaultRateLimitConfig())
+	router := gin.New()
+	admin := router.Group("/api/v1/admin")
+	admin.Use(JWTAuth(testSecret, nil), RequirePermission("admin"))
+	admin.GET("/ratelimit", limiter.ListVisitorsHandler())
+	admin.DELETE("/ratelimit", limiter.PurgeVisitorsHandler())
+
+	serve := func(method, path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	// A client token, even one that may trade, is not an operator credential
+	client := signToken(t, "client-1", "read", "trade")
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		if recorder := serve(method, "/api/v1/admin/ratelimit", client); recorder.Code != http.StatusForbidden {
+			t.Errorf("%s with a client token status = %d, want 403", method, recorder.Code)
+		}
+	}
+
+	operator := signToken(t, "operator", "read", "admin")
+	recorder := serve(http.MethodGet, "/api/v1/admin/ratelimit", operator)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("GET with an operator token status = %d, want 200: %s", recorder.Code, recorder.Body.String())
+	}
+	var envelope struct {
+		Data []VisitorInfo `json:"data"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if recorder := serve(http.MethodDelete, "/api/v1/admin/ratelimit?key=unknown", operator); recorder.Code != http.StatusNotFound {
+		t.Errorf("purging an untracked key status = %d, want 404", recorder.Code)
+	}
+	if recorder := serve(http.MethodDelete, "/api/v1/admin/ratelimit", operator); recorder.Code != http.StatusOK {
+		t.Errorf("purging every key status = %d, want 200", recorder.Code)
+	}
+}
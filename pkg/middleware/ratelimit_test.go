@@ -0,0 +1,129 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+)
+
+// serveOrders sends a request to the trading endpoint through the limiter
+func serveOrders(router http.Handler) int {
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/api/v1/orders", nil))
+	return recorder.Code
+}
+
+// newOrdersRouter serves the trading endpoint behind the limiter
+func newOrdersRouter(limiter *RateLimiter) *gin.Engine {
+	router := gin.New()
+	router.GET("/api/v1/orders", limiter.Middleware(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+func TestRateLimiterEnforcesCustomTradingLimit(t *testing.T) {
+	config := DefaultRateLimitConfig()
+	config.Trading = rate.Limit(50) // One request every 20ms
+	router := newOrdersRouter(NewRateLimiter(config))
+
+	if code := serveOrders(router); code != http.StatusOK {
+		t.Fatalf("first request status = %d, want 200", code)
+	}
+	if code := serveOrders(router); code != http.StatusBadRequest {
+		t.Fatalf("immediate second request status = %d, want 400", code)
+	}
+	time.Sleep(30 * time.Millisecond)
+	if code := serveOrders(router); code != http.StatusOK {
+		t.Errorf("request after one interval status = %d, want 200 at the custom rate", code)
+	}
+
+	// At the default 100 requests per minute the same wait is not enough
+	defaults := newOrdersRouter(NewRateLimiter(DefaultRateLimitConfig()))
+	serveOrders(defaults)
+	time.Sleep(30 * time.Millisecond)
+	if code := serveOrders(defaults); code != http.StatusBadRequest {
+		t.Errorf("default limiter status = %d, want 400", code)
+	}
+}
+
+func TestParseRateLimit(t *testing.T) {
+	limit, err := ParseRateLimit(" 100 / 60 ")
+	if err != nil {
+		t.Fatalf("ParseRateLimit: %v", err)
+	}
+	if want := rate.Limit(100.0 / 60.0); limit != want {
+		t.Errorf("limit = %v, want %v", limit, want)
+	}
+
+	for _, value := range []string{"100", "abc/60", "100/0", "-5/60", "0/60", "100/x"} {
+		if _, err := ParseRateLimit(value); err == nil {
+			t.Errorf("ParseRateLimit(%q) succeeded, want an error", value)
+		}
+	}
+}
+
+// requestToken sends a token request through the limiter from the peer address, with an optional X-Forwarded-For header
+func requestToken(router http.Handler, remoteAddr, forwardedFor string) int {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/token", nil)
+	req.RemoteAddr = remoteAddr
+	if forwardedFor != "" {
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder.Code
+}
+
+// newTokenRouter serves the token endpoint behind the limiter, trusting X-Forwarded-For only from proxies
+func newTokenRouter(t *testing.T, proxies []string) *gin.Engine {
+	t.Helper()
+	router := gin.New()
+	if err := router.SetTrustedProxies(proxies); err != nil {
+		t.Fatalf("SetTrustedProxies: %v", err)
+	}
+	router.POST("/api/v1/auth/token", NewRateLimiter(DefaultRateLimitConfig()).Middleware(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+func TestRateLimiterKeysOnForwardedClientBehindTrustedProxy(t *testing.T) {
+	router := newTokenRouter(t, []string{"10.0.0.0/8"})
+
+	// Two clients behind the same proxy each get their own allowance
+	if code := requestToken(router, "10.0.0.1:40000", "203.0.113.1"); code != http.StatusOK {
+		t.Fatalf("first client status = %d, want 200", code)
+	}
+	if code := requestToken(router, "10.0.0.1:40001", "203.0.113.2"); code != http.StatusOK {
+		t.Errorf("second client behind the proxy status = %d, want 200", code)
+	}
+	if code := requestToken(router, "10.0.0.1:40002", "203.0.113.1"); code != http.StatusBadRequest {
+		t.Errorf("first client's second request status = %d, want 400", code)
+	}
+}
+
+func TestRateLimiterIgnoresForwardedHeaderFromUntrustedPeer(t *testing.T) {
+	router := newTokenRouter(t, []string{"10.0.0.0/8"})
+
+	// A client outside the trusted range cannot pick a fresh key by sending the header
+	if code := requestToken(router, "198.51.100.7:40000", "203.0.113.1"); code != http.StatusOK {
+		t.Fatalf("first request status = %d, want 200", code)
+	}
+	if code := requestToken(router, "198.51.100.7:40001", "203.0.113.2"); code != http.StatusBadRequest {
+		t.Errorf("spoofed header request status = %d, want 400 on the peer's own key", code)
+	}
+
+	// With no proxies trusted, requests through a proxy all share its address
+	untrusting := newTokenRouter(t, nil)
+	if code := requestToken(untrusting, "10.0.0.1:40000", "203.0.113.1"); code != http.StatusOK {
+		t.Fatalf("first proxied request status = %d, want 200", code)
+	}
+	if code := requestToken(untrusting, "10.0.0.1:40001", "203.0.113.2"); code != http.StatusBadRequest {
+		t.Errorf("second proxied request status = %d, want 400 without a trusted proxy", code)
+	}
+}
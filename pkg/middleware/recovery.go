@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"runtime/debug"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/ksred/klear-api/pkg/response"
+	"github.com/rs/zerolog/log"
+)
+
+// RequestIDHeader carries the ID used to correlate a request across logs and responses
+const RequestIDHeader = "X-Request-ID"
+
+// RequestID tags each request with an ID, reusing the caller's X-Request-ID when provided
+// The ID is stored in the context as "requestID" and echoed in the response header
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if requestID == "" {
+			requestID = uuid.New().String()
+		}
+
+		c.Set("requestID", requestID)
+		c.Header(RequestIDHeader, requestID)
+		c.Next()
+	}
+}
+
+// Recovery converts a panic in a handler into a logged 500 with the standard JSON error envelope
+// It replaces gin's default recovery, which responds with plain text
+func Recovery() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Later middleware may swap in a buffering writer, so keep the original to respond on
+		writer := c.Writer
+
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				log.Error().
+					Str("request_id", c.GetString("requestID")).
+					Str("method", c.Request.Method).
+					Str("path", c.Request.URL.Path).
+					Interface("panic", recovered).
+					Bytes("stack", debug.Stack()).
+					Msg("recovered from panic in request handler")
+
+				c.Writer = writer
+				if !writer.Written() {
+					response.InternalError(c, "An unexpected error occurred")
+				}
+				c.Abort()
+			}
+		}()
+
+		c.Next()
+	}
+}
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/klear-api/pkg/response"
+)
+
+func TestRecoveryReturnsJSONEnvelope(t *testing.T) {
+	router := gin.New()
+	router.Use(RequestID(), Recovery())
+	router.GET("/", func(c *gin.Context) {
+		var m map[string]int
+		m["boom"]++
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(RequestIDHeader, "req-123")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	if recorder.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", recorder.Code)
+	}
+	var envelope response.Response
+	if err := json.Unmarshal(recorder.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("body %q is not a JSON envelope: %v", recorder.Body.String(), err)
+	}
+	if envelope.Success || envelope.Error == nil || envelope.Error.Code != response.ErrCodeInternalError {
+		t.Errorf("envelope = %s, want an internal error", recorder.Body.String())
+	}
+	if got := recorder.Header().Get(RequestIDHeader); got != "req-123" {
+		t.Errorf("request ID header = %q, want the caller's req-123", got)
+	}
+}
+
+func TestRequestIDGeneratedWhenMissing(t *testing.T) {
+	router := gin.New()
+	router.Use(RequestID())
+	router.GET("/", func(c *gin.Context) { c.String(http.StatusOK, c.GetString("requestID")) })
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	if id := recorder.Header().Get(RequestIDHeader); id == "" || id != recorder.Body.String() {
+		t.Errorf("request ID header %q and context %q, want the same generated ID", id, recorder.Body.String())
+	}
+}
@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/klear-api/pkg/response"
+)
+
+// bufferedWriter holds the handler's response until the request is known to have met its deadline
+type bufferedWriter struct {
+	gin.ResponseWriter
+	body   bytes.Buffer
+	status int
+}
+
+func (w *bufferedWriter) WriteHeader(code int) {
+	w.status = code
+}
+
+func (w *bufferedWriter) WriteHeaderNow() {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+}
+
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	w.WriteHeaderNow()
+	return w.body.Write(data)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	w.WriteHeaderNow()
+	return w.body.WriteString(s)
+}
+
+func (w *bufferedWriter) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+func (w *bufferedWriter) Size() int {
+	return w.body.Len()
+}
+
+func (w *bufferedWriter) Written() bool {
+	return w.status != 0
+}
+
+// Flush is a no-op as the response is only sent once the handler has completed
+func (w *bufferedWriter) Flush() {}
+
+// Timeout bounds each request with a deadline on its context
+// Services that honour the request context stop work once the deadline passes.
+// If the deadline is exceeded, the handler's response is discarded and a 504 is returned instead.
+// The handler is not interrupted: it runs to completion and any changes it made are kept, so a
+// 504 does not mean the request had no effect. Clients should retry writes with the same
+// Idempotency-Key, which replays the result of a request that completed after its deadline
+func Timeout(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		writer := c.Writer
+		buffered := &bufferedWriter{ResponseWriter: writer}
+		c.Writer = buffered
+
+		c.Next()
+
+		c.Writer = writer
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			response.GatewayTimeout(c, "Request timed out. Please try again later.")
+			c.Abort()
+			return
+		}
+
+		if buffered.Written() {
+			writer.WriteHeader(buffered.Status())
+			if _, err := writer.Write(buffered.body.Bytes()); err != nil {
+				c.Error(err)
+			}
+		}
+	}
+}
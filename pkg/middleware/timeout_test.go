@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ksred/klear-api/pkg/response"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func serveTimeout(timeout time.Duration, handler gin.HandlerFunc) *httptest.ResponseRecorder {
+	router := gin.New()
+	router.GET("/", Timeout(timeout), handler)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	return recorder
+}
+
+func TestTimeoutReturns504ForSlowHandler(t *testing.T) {
+	var completed atomic.Bool
+	recorder := serveTimeout(5*time.Millisecond, func(c *gin.Context) {
+		time.Sleep(50 * time.Millisecond)
+		completed.Store(true)
+		c.JSON(http.StatusOK, gin.H{"late": true})
+	})
+
+	if recorder.Code != http.StatusGatewayTimeout {
+		t.Fatalf("status = %d, want 504", recorder.Code)
+	}
+	if !strings.Contains(recorder.Body.String(), response.ErrCodeGatewayTimeout) || strings.Contains(recorder.Body.String(), `"late":true`) {
+		t.Errorf("body = %s, want only the gateway timeout error", recorder.Body.String())
+	}
+	// The handler is not interrupted, so its side effects stand despite the 504
+	if !completed.Load() {
+		t.Error("handler did not run to completion")
+	}
+}
+
+func TestTimeoutCancelsRequestContext(t *testing.T) {
+	start := time.Now()
+	recorder := serveTimeout(5*time.Millisecond, func(c *gin.Context) {
+		select {
+		case <-c.Request.Context().Done():
+		case <-time.After(time.Second):
+		}
+		c.Status(http.StatusOK)
+	})
+
+	if recorder.Code != http.StatusGatewayTimeout {
+		t.Errorf("status = %d, want 504", recorder.Code)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("handler honouring the context took %s, want it to stop at the deadline", elapsed)
+	}
+}
+
+func TestTimeoutPassesThroughFastHandler(t *testing.T) {
+	recorder := serveTimeout(time.Second, func(c *gin.Context) {
+		c.JSON(http.StatusCreated, gin.H{"ok": true})
+	})
+
+	if recorder.Code != http.StatusCreated {
+		t.Errorf("status = %d, want 201", recorder.Code)
+	}
+	if recorder.Body.String() != `{"ok":true}` {
+		t.Errorf("body = %s, want the handler's response", recorder.Body.String())
+	}
+}
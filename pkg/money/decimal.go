@@ -0,0 +1,77 @@
+package money
+
+import (
+	"math/big"
+	"strconv"
+)
+
+// UnitPlaces is the number of decimal places kept when prices and quantities are stored as scaled integers
+const UnitPlaces = 8
+
+// unitScale is 10^UnitPlaces, the factor between a value and its scaled integer units
+var unitScale = new(big.Int).Exp(big.NewInt(10), big.NewInt(UnitPlaces), nil)
+
+// ToUnits converts a price or quantity to exact integer units of 10^-UnitPlaces
+// The value is read as its shortest decimal form, so 100.10 becomes exactly 10010000000
+func ToUnits(value float64) int64 {
+	return roundRat(new(big.Rat).Mul(exactRat(value), new(big.Rat).SetInt(unitScale))).Int64()
+}
+
+// FromUnits converts integer units of 10^-UnitPlaces back to the nearest float64
+func FromUnits(units int64) float64 {
+	return ratToFloat(new(big.Rat).SetFrac(big.NewInt(units), unitScale))
+}
+
+// Notional returns price multiplied by quantity, computed exactly and rounded once to the
+// currency's minor units
+func Notional(price, quantity float64, currency string) float64 {
+	product := new(big.Rat).Mul(exactRat(price), exactRat(quantity))
+	return roundToPlaces(product, MinorUnits(currency))
+}
+
+// Sum adds amounts exactly and rounds the total once to the currency's minor units
+// Pass negative amounts to subtract
+func Sum(currency string, amounts ...float64) float64 {
+	total := new(big.Rat)
+	for _, amount := range amounts {
+		total.Add(total, exactRat(amount))
+	}
+	return roundToPlaces(total, MinorUnits(currency))
+}
+
+// exactRat returns the exact decimal value a float64 was written as, e.g. 0.1 rather than
+// 0.1000000000000000055511151231257827
+func exactRat(value float64) *big.Rat {
+	r, ok := new(big.Rat).SetString(strconv.FormatFloat(value, 'f', -1, 64))
+	if !ok {
+		// Only NaN and infinities fail to parse; treat them as zero
+		return new(big.Rat)
+	}
+	return r
+}
+
+// roundToPlaces rounds r to the given decimal places, half away from zero, and returns the nearest float64
+func roundToPlaces(r *big.Rat, places int) float64 {
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(places)), nil)
+	scaled := roundRat(new(big.Rat).Mul(r, new(big.Rat).SetInt(scale)))
+	return ratToFloat(new(big.Rat).SetFrac(scaled, scale))
+}
+
+// roundRat rounds r to an integer, half away from zero
+func roundRat(r *big.Rat) *big.Int {
+	num := new(big.Int).Abs(r.Num())
+	quo, rem := new(big.Int).QuoRem(num, r.Denom(), new(big.Int))
+	if new(big.Int).Mul(rem, big.NewInt(2)).Cmp(r.Denom()) >= 0 {
+		quo.Add(quo, big.NewInt(1))
+	}
+	if r.Sign() < 0 {
+		quo.Neg(quo)
+	}
+	return quo
+}
+
+// ratToFloat returns the float64 nearest to r
+func ratToFloat(r *big.Rat) float64 {
+	f, _ := r.Float64()
+	return f
+}
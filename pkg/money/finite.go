@@ -0,0 +1,19 @@
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrNonFinite is returned when a calculation produces NaN or an infinity
+var ErrNonFinite = errors.New("calculation produced a non-finite value")
+
+// CheckFinite returns an error wrapping ErrNonFinite if value is NaN or infinite
+// name identifies the value in the error, e.g. "average price"
+func CheckFinite(name string, value float64) error {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return fmt.Errorf("%w: %s is %v", ErrNonFinite, name, value)
+	}
+	return nil
+}
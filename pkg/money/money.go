@@ -0,0 +1,89 @@
+package money
+
+import (
+	"strings"
+	"sync"
+)
+
+// DefaultCurrency is the currency used when none is specified
+const DefaultCurrency = "USD"
+
+// defaultMinorUnits is the precision used for currencies without an explicit entry
+const defaultMinorUnits = 2
+
+var (
+	mu         sync.RWMutex
+	minorUnits = map[string]int{
+		"USD": 2,
+		"EUR": 2,
+		"GBP": 2,
+		"JPY": 0,
+		"KRW": 0,
+		"CLP": 0,
+		"ISK": 0,
+		"BHD": 3,
+		"KWD": 3,
+		"OMR": 3,
+		"JOD": 3,
+		"TND": 3,
+	}
+)
+
+// isoCurrencies is the allowlist of active ISO 4217 currency codes
+var isoCurrencies = map[string]bool{
+	"AED": true, "AFN": true, "ALL": true, "AMD": true, "ANG": true, "AOA": true, "ARS": true, "AUD": true,
+	"AWG": true, "AZN": true, "BAM": true, "BBD": true, "BDT": true, "BGN": true, "BHD": true, "BIF": true,
+	"BMD": true, "BND": true, "BOB": true, "BRL": true, "BSD": true, "BTN": true, "BWP": true, "BYN": true,
+	"BZD": true, "CAD": true, "CDF": true, "CHF": true, "CLP": true, "CNY": true, "COP": true, "CRC": true,
+	"CUP": true, "CVE": true, "CZK": true, "DJF": true, "DKK": true, "DOP": true, "DZD": true, "EGP": true,
+	"ERN": true, "ETB": true, "EUR": true, "FJD": true, "FKP": true, "GBP": true, "GEL": true, "GHS": true,
+	"GIP": true, "GMD": true, "GNF": true, "GTQ": true, "GYD": true, "HKD": true, "HNL": true, "HTG": true,
+	"HUF": true, "IDR": true, "ILS": true, "INR": true, "IQD": true, "IRR": true, "ISK": true, "JMD": true,
+	"JOD": true, "JPY": true, "KES": true, "KGS": true, "KHR": true, "KMF": true, "KPW": true, "KRW": true,
+	"KWD": true, "KYD": true, "KZT": true, "LAK": true, "LBP": true, "LKR": true, "LRD": true, "LSL": true,
+	"LYD": true, "MAD": true, "MDL": true, "MGA": true, "MKD": true, "MMK": true, "MNT": true, "MOP": true,
+	"MRU": true, "MUR": true, "MVR": true, "MWK": true, "MXN": true, "MYR": true, "MZN": true, "NAD": true,
+	"NGN": true, "NIO": true, "NOK": true, "NPR": true, "NZD": true, "OMR": true, "PAB": true, "PEN": true,
+	"PGK": true, "PHP": true, "PKR": true, "PLN": true, "PYG": true, "QAR": true, "RON": true, "RSD": true,
+	"RUB": true, "RWF": true, "SAR": true, "SBD": true, "SCR": true, "SDG": true, "SEK": true, "SGD": true,
+	"SHP": true, "SLE": true, "SOS": true, "SRD": true, "SSP": true, "STN": true, "SVC": true, "SYP": true,
+	"SZL": true, "THB": true, "TJS": true, "TMT": true, "TND": true, "TOP": true, "TRY": true, "TTD": true,
+	"TWD": true, "TZS": true, "UAH": true, "UGX": true, "USD": true, "UYU": true, "UZS": true, "VES": true,
+	"VND": true, "VUV": true, "WST": true, "XAF": true, "XCD": true, "XOF": true, "XPF": true, "YER": true,
+	"ZAR": true, "ZMW": true, "ZWL": true,
+}
+
+// IsValidCurrency reports whether code is an active ISO 4217 currency code
+func IsValidCurrency(code string) bool {
+	return isoCurrencies[strings.ToUpper(strings.TrimSpace(code))]
+}
+
+// NormalizeCurrency trims and upper-cases a currency code
+func NormalizeCurrency(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// MinorUnits returns the number of decimal places used by the currency
+func MinorUnits(currency string) int {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	if units, ok := minorUnits[strings.ToUpper(currency)]; ok {
+		return units
+	}
+	return defaultMinorUnits
+}
+
+// SetMinorUnits overrides the number of decimal places used by a currency
+func SetMinorUnits(currency string, units int) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	minorUnits[strings.ToUpper(currency)] = units
+}
+
+// Round rounds an amount to the currency's minor-unit precision, half away from zero
+// The amount is rounded as the decimal it was written as, so 1.005 rounds to 1.01
+func Round(amount float64, currency string) float64 {
+	return roundToPlaces(exactRat(amount), MinorUnits(currency))
+}
@@ -0,0 +1,57 @@
+package money
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestRoundToCurrencyPrecision(t *testing.T) {
+	tests := []struct {
+		amount   float64
+		currency string
+		want     float64
+	}{
+		{123.4567 * 33 * 0.001, "USD", 4.07},
+		{1.005, "USD", 1.01},
+		{-1.005, "USD", -1.01},
+		{1234.5, "JPY", 1235},
+		{1.0005, "BHD", 1.001},
+		{2.345, "XYZ", 2.35}, // Unknown currencies use two places
+	}
+	for _, tt := range tests {
+		if got := Round(tt.amount, tt.currency); got != tt.want {
+			t.Errorf("Round(%v, %s) = %v, want %v", tt.amount, tt.currency, got, tt.want)
+		}
+	}
+}
+
+func TestSetMinorUnitsOverridesPrecision(t *testing.T) {
+	previous := MinorUnits("usd")
+	SetMinorUnits("usd", 4)
+	t.Cleanup(func() { SetMinorUnits("USD", previous) })
+
+	if got := MinorUnits("USD"); got != 4 {
+		t.Fatalf("MinorUnits(USD) = %d, want 4", got)
+	}
+	if got := Round(1.23456, "USD"); got != 1.2346 {
+		t.Errorf("Round(1.23456, USD) = %v, want 1.2346", got)
+	}
+}
+
+func TestCheckFinite(t *testing.T) {
+	for _, value := range []float64{0, -1.5, 1e300} {
+		if err := CheckFinite("amount", value); err != nil {
+			t.Errorf("CheckFinite(%v) = %v, want nil", value, err)
+		}
+	}
+	for _, value := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		err := CheckFinite("amount", value)
+		if !errors.Is(err, ErrNonFinite) {
+			t.Errorf("CheckFinite(%v) = %v, want ErrNonFinite", value, err)
+		} else if !strings.Contains(err.Error(), "amount") {
+			t.Errorf("CheckFinite(%v) = %q, want the value named", value, err)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package redis
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrNil is returned by Do when Redis replies with a null value, e.g. GET of a missing key
+var ErrNil = errors.New("redis: nil reply")
+
+// Error is an error reply sent by the Redis server
+type Error string
+
+func (e Error) Error() string { return "redis: " + string(e) }
+
+// defaultMaxIdle is how many idle connections a client keeps for reuse
+const defaultMaxIdle = 8
+
+// Client is a minimal Redis client speaking RESP over a pool of connections
+// Each command borrows a connection, dialling one when none is idle, and returns it to the pool
+// once the reply has been read in full; a connection that saw a network or protocol error is
+// closed instead, since its position in the reply stream is unknown
+type Client struct {
+	addr     string
+	password string
+	db       int
+	timeout  time.Duration
+	maxIdle  int
+
+	mu   sync.Mutex
+	idle []*conn
+}
+
+// conn is a single connection to the server
+type conn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+// NewClient creates a client for the Redis server at addr
+// password may be empty; db selects the logical database; timeout bounds dialling and each command
+func NewClient(addr, password string, db int, timeout time.Duration) *Client {
+	return &Client{addr: addr, password: password, db: db, timeout: timeout, maxIdle: defaultMaxIdle}
+}
+
+// Do sends a command and returns its reply: a string for simple and bulk strings,
+// an int64 for integers and a []interface{} for arrays
+// Null replies are returned as ErrNil and server errors as Error; within an array they are
+// returned as nil and Error elements
+func (c *Client) Do(args ...string) (interface{}, error) {
+	cn, err := c.get()
+	if err != nil {
+		return nil, err
+	}
+	reply, err := cn.roundTrip(args, c.timeout)
+	var redisErr Error
+	if err != nil && !errors.Is(err, ErrNil) && !errors.As(err, &redisErr) {
+		cn.Close()
+		return nil, err
+	}
+	c.put(cn)
+	return reply, err
+}
+
+// Close closes the idle connections
+// Connections in use are closed when they are returned
+func (c *Client) Close() error {
+	c.mu.Lock()
+	idle := c.idle
+	c.idle = nil
+	c.maxIdle = 0
+	c.mu.Unlock()
+
+	var firstErr error
+	for _, cn := range idle {
+		if err := cn.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// get takes an idle connection, or dials a new one when none is left
+func (c *Client) get() (*conn, error) {
+	c.mu.Lock()
+	if n := len(c.idle); n > 0 {
+		cn := c.idle[n-1]
+		c.idle = c.idle[:n-1]
+		c.mu.Unlock()
+		return cn, nil
+	}
+	c.mu.Unlock()
+	return c.connect()
+}
+
+// put returns a connection to the pool, closing it when the pool is full
+func (c *Client) put(cn *conn) {
+	c.mu.Lock()
+	if len(c.idle) < c.maxIdle {
+		c.idle = append(c.idle, cn)
+		c.mu.Unlock()
+		return
+	}
+	c.mu.Unlock()
+	cn.Close()
+}
+
+// connect dials the server and authenticates and selects the database when configured
+func (c *Client) connect() (*conn, error) {
+	netConn, err := net.DialTimeout("tcp", c.addr, c.timeout)
+	if err != nil {
+		return nil, fmt.Errorf("redis: failed to connect to %s: %w", c.addr, err)
+	}
+	cn := &conn{Conn: netConn, reader: bufio.NewReader(netConn)}
+
+	if c.password != "" {
+		if _, err := cn.roundTrip([]string{"AUTH", c.password}, c.timeout); err != nil {
+			cn.Close()
+			return nil, err
+		}
+	}
+	if c.db != 0 {
+		if _, err := cn.roundTrip([]string{"SELECT", strconv.Itoa(c.db)}, c.timeout); err != nil {
+			cn.Close()
+			return nil, err
+		}
+	}
+	return cn, nil
+}
+
+func (cn *conn) roundTrip(args []string, timeout time.Duration) (interface{}, error) {
+	if timeout > 0 {
+		if err := cn.SetDeadline(time.Now().Add(timeout)); err != nil {
+			return nil, err
+		}
+	}
+
+	buf := []byte("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		buf = append(buf, "$"+strconv.Itoa(len(arg))+"\r\n"...)
+		buf = append(buf, arg...)
+		buf = append(buf, "\r\n"...)
+	}
+	if _, err := cn.Write(buf); err != nil {
+		return nil, err
+	}
+	return cn.readReply()
+}
+
+func (cn *conn) readReply() (interface{}, error) {
+	line, err := cn.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("redis: malformed reply %q", line)
+	}
+	kind, payload := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return payload, nil
+	case '-':
+		return nil, Error(payload)
+	case ':':
+		return strconv.ParseInt(payload, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, fmt.Errorf("redis: malformed bulk length %q", payload)
+		}
+		if n < 0 {
+			return nil, ErrNil
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(cn.reader, data); err != nil {
+			return nil, err
+		}
+		return string(data[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, fmt.Errorf("redis: malformed array length %q", payload)
+		}
+		if n < 0 {
+			return nil, ErrNil
+		}
+		// Every element is read, even after an error element, so the next reply starts cleanly
+		items := make([]interface{}, n)
+		for i := range items {
+			item, err := cn.readReply()
+			var redisErr Error
+			switch {
+			case errors.As(err, &redisErr):
+				items[i] = redisErr
+			case errors.Is(err, ErrNil):
+				items[i] = nil
+			case err != nil:
+				return nil, err
+			default:
+				items[i] = item
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("redis: unknown reply type %q", kind)
+	}
+}
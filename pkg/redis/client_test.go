@@ -0,0 +1,187 @@
+package redis
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeServer is a RESP server answering each command with the raw reply its handler returns
+type fakeServer struct {
+	listener net.Listener
+	handler  func(args []string) string
+	accepted atomic.Int32
+}
+
+func newFakeServer(t *testing.T, handler func(args []string) string) *fakeServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	server := &fakeServer{listener: listener, handler: handler}
+	t.Cleanup(func() { listener.Close() })
+	go server.serve()
+	return server
+}
+
+func (s *fakeServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.accepted.Add(1)
+		go s.serveConn(conn)
+	}
+}
+
+func (s *fakeServer) serveConn(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+		if _, err := io.WriteString(conn, s.handler(args)); err != nil {
+			return
+		}
+	}
+}
+
+// readCommand reads a command sent as an array of bulk strings
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line[1:]))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		if _, err := reader.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		arg, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		args[i] = strings.TrimSuffix(arg, "\r\n")
+	}
+	return args, nil
+}
+
+func TestDoDrainsArrayWithErrorElement(t *testing.T) {
+	server := newFakeServer(t, func(args []string) string {
+		if args[0] == "EXEC" {
+			return "*4\r\n:1\r\n-ERR wrong type\r\n$-1\r\n$2\r\nok\r\n"
+		}
+		return "+PONG\r\n"
+	})
+	client := NewClient(server.listener.Addr().String(), "", 0, time.Second)
+	defer client.Close()
+
+	reply, err := client.Do("EXEC")
+	if err != nil {
+		t.Fatalf("Do(EXEC) error = %v, want the errors returned as elements", err)
+	}
+	items, ok := reply.([]interface{})
+	if !ok || len(items) != 4 {
+		t.Fatalf("Do(EXEC) = %#v, want 4 elements", reply)
+	}
+	if items[0] != int64(1) || items[1] != Error("ERR wrong type") || items[2] != nil || items[3] != "ok" {
+		t.Errorf("Do(EXEC) = %#v, want [1, ERR wrong type, nil, ok]", items)
+	}
+
+	// The next command on the same connection reads its own reply, not the rest of the array
+	if reply, err := client.Do("PING"); err != nil || reply != "PONG" {
+		t.Errorf("Do(PING) = %v, %v, want PONG", reply, err)
+	}
+	if accepted := server.accepted.Load(); accepted != 1 {
+		t.Errorf("server accepted %d connections, want the connection reused", accepted)
+	}
+}
+
+func TestDoReturnsServerErrorsAndNil(t *testing.T) {
+	server := newFakeServer(t, func(args []string) string {
+		switch args[0] {
+		case "GET":
+			return "$-1\r\n"
+		case "BAD":
+			return "-ERR unknown command\r\n"
+		}
+		return "+OK\r\n"
+	})
+	client := NewClient(server.listener.Addr().String(), "", 0, time.Second)
+	defer client.Close()
+
+	if _, err := client.Do("GET", "missing"); !errors.Is(err, ErrNil) {
+		t.Errorf("Do(GET) error = %v, want ErrNil", err)
+	}
+	var redisErr Error
+	if _, err := client.Do("BAD"); !errors.As(err, &redisErr) || redisErr != "ERR unknown command" {
+		t.Errorf("Do(BAD) error = %v, want the server error", err)
+	}
+	if reply, err := client.Do("SET", "key", "value"); err != nil || reply != "OK" {
+		t.Errorf("Do(SET) = %v, %v, want OK", reply, err)
+	}
+	if accepted := server.accepted.Load(); accepted != 1 {
+		t.Errorf("server accepted %d connections, want server errors to keep the connection", accepted)
+	}
+}
+
+func TestDoPoolsConnectionsForConcurrentCommands(t *testing.T) {
+	release := make(chan struct{})
+	var waiting atomic.Int32
+	server := newFakeServer(t, func(args []string) string {
+		if args[0] == "BLOCK" {
+			waiting.Add(1)
+			<-release
+		}
+		return "+OK\r\n"
+	})
+	client := NewClient(server.listener.Addr().String(), "", 0, 5*time.Second)
+	defer client.Close()
+
+	// Commands in flight together each get their own connection rather than queueing
+	const concurrent = 3
+	var wg sync.WaitGroup
+	for i := 0; i < concurrent; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.Do("BLOCK"); err != nil {
+				t.Errorf("Do(BLOCK): %v", err)
+			}
+		}()
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for waiting.Load() < concurrent && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := waiting.Load(); got != concurrent {
+		t.Fatalf("%d commands in flight, want %d served at once", got, concurrent)
+	}
+	close(release)
+	wg.Wait()
+
+	// The connections are then reused
+	for i := 0; i < concurrent; i++ {
+		if _, err := client.Do("PING"); err != nil {
+			t.Fatalf("Do(PING): %v", err)
+		}
+	}
+	if accepted := server.accepted.Load(); accepted != concurrent {
+		t.Errorf("server accepted %d connections, want %d reused from the pool", accepted, concurrent)
+	}
+}
@@ -0,0 +1,94 @@
+package response
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// ErrCodeMalformedJSON is returned when a request body cannot be decoded as JSON
+const ErrCodeMalformedJSON = "MALFORMED_JSON"
+
+func init() {
+	// Report validation failures by their JSON field names rather than Go struct field names
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+			if name == "-" {
+				return ""
+			}
+			return name
+		})
+	}
+}
+
+// BindError sends a 400 response for a request body that failed to bind, telling
+// malformed JSON apart from validation failures without exposing decoder or validator internals
+func BindError(c *gin.Context, err error) {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var validationErrs validator.ValidationErrors
+
+	switch {
+	case errors.Is(err, io.EOF):
+		WithCode(c, http.StatusBadRequest, ErrCodeMalformedJSON, "Request body is empty")
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		WithCode(c, http.StatusBadRequest, ErrCodeMalformedJSON, "Request body is not valid JSON: unexpected end of input")
+	case errors.As(err, &syntaxErr):
+		WithCode(c, http.StatusBadRequest, ErrCodeMalformedJSON,
+			fmt.Sprintf("Request body is not valid JSON: syntax error at offset %d", syntaxErr.Offset))
+	case errors.As(err, &typeErr):
+		WithCode(c, http.StatusBadRequest, ErrCodeMalformedJSON, typeErrorMessage(typeErr))
+	case errors.As(err, &validationErrs):
+		WithCode(c, http.StatusBadRequest, ErrCodeValidationFailed, validationMessage(validationErrs))
+	default:
+		BadRequest(c, "Invalid request body")
+	}
+}
+
+// typeErrorMessage describes a JSON value of the wrong type in terms of the expected JSON type
+func typeErrorMessage(err *json.UnmarshalTypeError) string {
+	if err.Field == "" {
+		return fmt.Sprintf("Request body must be %s", jsonKind(err.Type))
+	}
+	return fmt.Sprintf("Field %s must be %s", err.Field, jsonKind(err.Type))
+}
+
+// jsonKind names the JSON type a Go type is decoded from
+func jsonKind(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "a string"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
+
+// validationMessage lists the fields that failed validation
+func validationMessage(errs validator.ValidationErrors) string {
+	msgs := make([]string, 0, len(errs))
+	for _, fe := range errs {
+		if fe.Tag() == "required" {
+			msgs = append(msgs, fmt.Sprintf("Field %s is required", fe.Field()))
+		} else {
+			msgs = append(msgs, fmt.Sprintf("Field %s is invalid", fe.Field()))
+		}
+	}
+	return strings.Join(msgs, "; ")
+}
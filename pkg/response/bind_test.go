@@ -0,0 +1,67 @@
+package response
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type bindRequest struct {
+	Symbol   string  `json:"symbol" binding:"required"`
+	Quantity float64 `json:"quantity"`
+}
+
+// bind posts body to a handler that binds it into a bindRequest and reports failures with BindError
+func bind(body string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/", func(c *gin.Context) {
+		var req bindRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			BindError(c, err)
+			return
+		}
+		Success(c, req)
+	})
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestBindError(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		wantCode    string
+		wantMessage string
+	}{
+		{"empty body", ``, ErrCodeMalformedJSON, "Request body is empty"},
+		{"truncated", `{"symbol":"AAPL","quantity":`, ErrCodeMalformedJSON, "Request body is not valid JSON: unexpected end of input"},
+		{"syntax error", `{"symbol":"AAPL",}`, ErrCodeMalformedJSON, "Request body is not valid JSON: syntax error at offset 18"},
+		{"wrong-typed field", `{"symbol":"AAPL","quantity":"100"}`, ErrCodeMalformedJSON, "Field quantity must be a number"},
+		{"wrong-typed body", `["AAPL"]`, ErrCodeMalformedJSON, "Request body must be an object"},
+		{"missing field", `{"quantity":100}`, ErrCodeValidationFailed, "Field symbol is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := bind(tt.body)
+			if recorder.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, want 400", recorder.Code)
+			}
+			var resp Response
+			if err := json.Unmarshal(recorder.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Error == nil || resp.Error.Code != tt.wantCode || resp.Error.Message != tt.wantMessage {
+				t.Errorf("error = %+v, want %s %q", resp.Error, tt.wantCode, tt.wantMessage)
+			}
+		})
+	}
+}
@@ -30,6 +30,8 @@ const (
 	ErrCodeInternalError     = "INTERNAL_ERROR"
 	ErrCodeValidationFailed  = "VALIDATION_FAILED"
 	ErrCodeDuplicateResource = "DUPLICATE_RESOURCE"
+	ErrCodeGatewayTimeout    = "GATEWAY_TIMEOUT"
+	ErrCodeUnavailable       = "SERVICE_UNAVAILABLE"
 )
 
 // Handle processes the error and returns appropriate response
@@ -49,13 +51,29 @@ func Handle(c *gin.Context, data interface{}, err error) {
 	}
 }
 
+// HandleWithStatus is Handle with an explicit status code for the success response
+func HandleWithStatus(c *gin.Context, data interface{}, err error, status int) {
+	if err == nil {
+		SuccessWithStatus(c, data, status)
+		return
+	}
+	Handle(c, nil, err)
+}
+
 // Success sends a successful response
+// POST requests get 201; use SuccessWithStatus for POSTs that do not create a resource
 func Success(c *gin.Context, data interface{}) {
 	status := http.StatusOK
 	if c.Request.Method == "POST" {
 		status = http.StatusCreated
 	}
 
+	SuccessWithStatus(c, data, status)
+}
+
+// SuccessWithStatus sends a successful response with an explicit status code,
+// e.g. 200 for a POST that performs an action or replays an idempotent request
+func SuccessWithStatus(c *gin.Context, data interface{}, status int) {
 	c.JSON(status, Response{
 		Success: true,
 		Data:    data,
@@ -128,6 +146,40 @@ func Conflict(c *gin.Context, message string) {
 	})
 }
 
+// GatewayTimeout sends a 504 response
+func GatewayTimeout(c *gin.Context, message string) {
+	c.JSON(http.StatusGatewayTimeout, Response{
+		Success: false,
+		Error: &Error{
+			Code:    ErrCodeGatewayTimeout,
+			Message: message,
+		},
+	})
+}
+
+// ServiceUnavailable sends a 503 response
+func ServiceUnavailable(c *gin.Context, message string) {
+	c.JSON(http.StatusServiceUnavailable, Response{
+		Success: false,
+		Error: &Error{
+			Code:    ErrCodeUnavailable,
+			Message: message,
+		},
+	})
+}
+
+// WithCode sends an error response with an explicit status and a domain-specific error code,
+// for errors the caller should be able to tell apart, e.g. the reason an order was rejected
+func WithCode(c *gin.Context, status int, code, message string) {
+	c.JSON(status, Response{
+		Success: false,
+		Error: &Error{
+			Code:    code,
+			Message: message,
+		},
+	})
+}
+
 // handleError determines the appropriate error response
 func handleError(c *gin.Context, err error) {
 	// Add custom error type checks here